@@ -36,14 +36,15 @@ func setupCloseHandler(database *database.Database) {
 	go func() {
 		<-c
 		fmt.Println("closehandler invoked")
-		database.Close()
+		database.ForceClose()
 		os.Exit(0)
 	}()
 }
 
 // [CONCURRENCY]
 // // Start listening for connections at port `port`.
-func startServer(repl *repl.REPL, tm *concurrency.TransactionManager, prompt string, port int) {
+// If `rateLimit` is positive, each connection may run at most that many commands per second.
+func startServer(r *repl.REPL, tm *concurrency.TransactionManager, prompt string, port int, rateLimit int) {
 	// Handle a connection by running the repl on it.
 	handleConn := func(c net.Conn) {
 		clientId := uuid.New()
@@ -51,7 +52,11 @@ func startServer(repl *repl.REPL, tm *concurrency.TransactionManager, prompt str
 		if tm != nil {
 			defer tm.Commit(clientId)
 		}
-		repl.Run(clientId, prompt, c, c)
+		var limiter *repl.RateLimiter
+		if rateLimit > 0 {
+			limiter = repl.NewRateLimiter(rateLimit)
+		}
+		r.RunWithLimiter(clientId, prompt, c, c, limiter)
 	}
 	// Start listening for new connections.
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%v", port))
@@ -83,6 +88,11 @@ func main() {
 
 	// [CONCURRENCY]
 	var portFlag = flag.Int("p", DEFAULT_PORT, "port number")
+	var adminFlag = flag.Bool("admin", false, "enable admin commands (e.g. killall, buffer_size) for all clients")
+	var rateLimitFlag = flag.Int("ratelimit", 0, "max commands per second per connection (0 for no limit)")
+
+	// [RECOVERY]
+	var checkpointFlag = flag.Duration("checkpoint", 0, "interval between automatic checkpoints (0 to disable)")
 
 	flag.Parse()
 
@@ -135,6 +145,9 @@ func main() {
 	case "hash", "b+tree":
 		server = false
 		repls = append(repls, database.DatabaseRepl(db))
+		if *adminFlag {
+			repls = append(repls, database.AdminREPL(db))
+		}
 
 	// [CONCURRENCY]
 	case "concurrency":
@@ -142,6 +155,9 @@ func main() {
 		lm := concurrency.NewResourceLockManager()
 		tm = concurrency.NewTransactionManager(lm)
 		repls = append(repls, concurrency.TransactionREPL(db, tm))
+		if *adminFlag {
+			repls = append(repls, concurrency.AdminREPL(tm), database.AdminREPL(db))
+		}
 
 	// [RECOVERY]
 	case "recovery":
@@ -153,10 +169,23 @@ func main() {
 			fmt.Println(err)
 			return
 		}
-		recovery.Prime(strings.TrimSuffix(db.GetBasePath(), "/"))
+		if _, err = recovery.Prime(strings.TrimSuffix(db.GetBasePath(), "/")); err != nil {
+			fmt.Println(err)
+			return
+		}
 		repls = append(repls, recovery.RecoveryREPL(db, tm, rm))
+		if *adminFlag {
+			repls = append(repls, recovery.AdminREPL(rm), database.AdminREPL(db))
+		}
 		// Recover in this case!
 		rm.Recover()
+		if *checkpointFlag > 0 {
+			if err = rm.StartCheckpointScheduler(*checkpointFlag); err != nil {
+				fmt.Println(err)
+				return
+			}
+			defer rm.StopCheckpointScheduler()
+		}
 
 	default:
 		fmt.Println("must specify -project [go,pager,hash,b+tree,concurrency,recovery]")
@@ -173,7 +202,7 @@ func main() {
 	// Start server if server (concurrency or recovery), else run REPL here.
 	if server {
 		// 	[CONCURRENCY]
-		startServer(r, tm, prompt, *portFlag)
+		startServer(r, tm, prompt, *portFlag, *rateLimitFlag)
 	} else {
 		r.Run(uuid.New(), prompt, nil, nil)
 	}
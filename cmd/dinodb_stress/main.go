@@ -7,28 +7,28 @@ import (
 	"math/rand"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"dinodb/pkg/btree"
 	"dinodb/pkg/database"
-	"dinodb/pkg/hash"
 
 	"github.com/google/uuid"
 )
 
-var STARTUP = 100 * time.Millisecond
 var MAX_DELAY int64 = 10
 
-// Listens for SIGINT or SIGTERM and calls table.CloseDB().
-func setupCloseHandler(db *database.Database) {
+// Listens for SIGINT or SIGTERM and calls db.ForceClose(). Waits for verification to finish first,
+// so that a table is never closed while it is being verified.
+func setupCloseHandler(db *database.Database, verifying *sync.Mutex) {
 	c := make(chan os.Signal)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		fmt.Println("closehandler invoked")
-		db.Close()
+		verifying.Lock()
+		db.ForceClose()
 		os.Exit(0)
 	}()
 }
@@ -55,16 +55,6 @@ func parseWorkload(path string) ([]string, error) {
 	return workload, scanner.Err()
 }
 
-// Handle workload
-func handleWorkload(c chan string, wg *sync.WaitGroup, workload []string, idx int, n int) {
-	// Iterate!
-	defer wg.Done()
-	for i := idx; i < len(workload); i += n {
-		time.Sleep(jitter())
-		c <- workload[i]
-	}
-}
-
 // Start the database.
 func main() {
 	// Set up flags.
@@ -86,26 +76,24 @@ func main() {
 	}
 	// Setup close conditions.
 	defer db.Close()
-	setupCloseHandler(db)
+	var verifying sync.Mutex
+	setupCloseHandler(db, &verifying)
 	// Clean up old db resources.
 	os.Remove("./data/t")
 	os.Remove("./data/t.meta")
-	// Run REPL.
 	r := database.DatabaseRepl(db)
-	c := make(chan string)
-	go r.RunChan(c, uuid.New(), "")
-	// Some time to wake up...
-	time.Sleep(STARTUP)
+	clientId := uuid.New()
 	// Initialize the db.
-	switch *indexFlag {
-	case "btree":
-		c <- "create btree table t"
-	case "hash":
-		c <- "create hash table t"
-	default:
-		fmt.Println("must specify -index [btree,hash]")
+	indexType, err := database.ParseIndexType(*indexFlag)
+	if err != nil {
+		fmt.Println("must specify -index [btree,hash]:", err)
 		return
 	}
+	if *nFlag < 1 {
+		fmt.Println("must run at least 1 thread")
+		return
+	}
+	r.RunWorkload([]string{fmt.Sprintf("create %s table t", indexType)}, 1, clientId, nil)
 	// Parse and run workload.
 	if *workloadFlag == "" {
 		fmt.Println("no workload file given")
@@ -116,28 +104,34 @@ func main() {
 		fmt.Println(err)
 		return
 	}
-	// Some time to wake up...
-	time.Sleep(STARTUP)
-	var wg sync.WaitGroup
-	for i := 0; i < *nFlag; i++ {
-		wg.Add(1)
-		go handleWorkload(c, &wg, workload, i, *nFlag)
+	// Check the whole workload before running any of it.
+	if errs := r.ValidateScript(strings.NewReader(strings.Join(workload, "\n"))); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Println(err)
+		}
+		return
 	}
-	wg.Wait()
+	// Returns once every command has finished, so the table is quiescent for verification.
+	r.RunWorkload(workload, *nFlag, clientId, jitter)
 	// Verify the structure of the index.
 	if *verifyFlag {
-		index, err := db.GetTable("t")
+		verifying.Lock()
+		err = verify(db)
+		verifying.Unlock()
 		if err != nil {
-			fmt.Println("error getting table t")
-			return
-		}
-		switch *indexFlag {
-		case "btree":
-			index := index.(*btree.BTreeIndex)
-			btree.IsBTree(index)
-		case "hash":
-			index := index.(*hash.HashIndex)
-			hash.IsHash(index)
+			fmt.Println("verification failed:", err)
+			db.Close()
+			os.Exit(1)
 		}
+		fmt.Println("verification passed")
+	}
+}
+
+// Verify the structure of table t.
+func verify(db *database.Database) error {
+	index, err := db.GetTable("t")
+	if err != nil {
+		return fmt.Errorf("error getting table t: %w", err)
 	}
+	return database.VerifyIndex(index)
 }
@@ -0,0 +1,39 @@
+package btree
+
+// LevelStats summarizes the nodes on one level of a B+Tree.
+type LevelStats struct {
+	Level    int64 // The level, counting the root as level 1.
+	NumNodes int64 // The number of nodes on the level.
+	NumKeys  int64 // The total number of keys in the level's nodes.
+	// The average fraction of each node's capacity its keys fill, from 0 to 1.
+	// A node is full when it holds as many keys as it can without splitting.
+	FillFactor float64
+}
+
+// LevelStats describes the tree and summarizes each of its levels from the root down, in level order.
+// The index must not be modified while this runs.
+func (index *BTreeIndex) LevelStats() ([]LevelStats, error) {
+	desc, err := index.Describe()
+	if err != nil {
+		return nil, err
+	}
+	pagesize := index.pager.GetPageSize()
+	stats := make([]LevelStats, 0, desc.Height)
+	level := []NodeDescription{desc.Root}
+	for len(level) > 0 {
+		s := LevelStats{Level: int64(len(stats)) + 1, NumNodes: int64(len(level))}
+		capacity := keysPerInternalNode(pagesize) - 1
+		if level[0].NodeType == LEAF_NODE {
+			capacity = entriesPerLeafNode(pagesize) - 1
+		}
+		next := make([]NodeDescription, 0)
+		for _, node := range level {
+			s.NumKeys += int64(len(node.Keys))
+			next = append(next, node.Children...)
+		}
+		s.FillFactor = float64(s.NumKeys) / float64(s.NumNodes*capacity)
+		stats = append(stats, s)
+		level = next
+	}
+	return stats, nil
+}
@@ -5,15 +5,22 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 
+	"dinodb/pkg/cursor"
 	"dinodb/pkg/entry"
 	"dinodb/pkg/pager"
 )
 
 // BTreeIndex is an index that uses a B+Tree as it's underlying data structure
 type BTreeIndex struct {
-	pager  *pager.Pager // The pager used to store the B+Tree's data.
-	rootPN int64        // The pagenum of this B+Tree's root node.
+	pager    *pager.Pager // The pager used to store the B+Tree's data.
+	rootPN   int64        // The pagenum of this B+Tree's root node.
+	height   atomic.Int64 // The number of levels in the B+Tree, used to bound how many pages an insert pins.
+	compare  Comparator   // The order of the B+Tree's keys, as recorded in its file.
+	mergeGap atomic.Int64 // The gap between the split and merge thresholds, in percent (see SetMergeGap).
+	ops      opTracker    // The lookups and writes running on the B+Tree, which Close waits for.
 }
 
 // OpenIndex returns a BTreeIndex that stores its data in a file with the given name.
@@ -24,18 +31,96 @@ func OpenIndex(filename string) (*BTreeIndex, error) {
 	if err != nil {
 		return nil, err
 	}
+	return openIndex(pager)
+}
+
+// OpenIndexWithPageSize is like OpenIndex, but stores the B+Tree's nodes in pages of the given size.
+// Larger pages increase the fanout of the B+Tree's nodes.
+func OpenIndexWithPageSize(filename string, pagesize int64) (*BTreeIndex, error) {
+	// Create a pager for the B+Tree
+	pager, err := pager.NewWithPageSize(filename, pagesize)
+	if err != nil {
+		return nil, err
+	}
+	return openIndex(pager)
+}
+
+// OpenIndexWithComparator is like OpenIndex, but orders the B+Tree's keys with the comparator registered
+// under the given id instead of in ascending order. The id is recorded in a new file, and an existing file
+// must have been created with the same comparator; OpenIndex uses whichever comparator a file was created with.
+func OpenIndexWithComparator(filename string, id ComparatorID) (*BTreeIndex, error) {
+	// Create a pager for the B+Tree
+	indexPager, err := pager.New(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireComparator(indexPager, id); err != nil {
+		indexPager.Close()
+		return nil, err
+	}
+	return openIndex(indexPager)
+}
+
+// openIndex returns a BTreeIndex backed by the given pager.
+func openIndex(indexPager *pager.Pager) (*BTreeIndex, error) {
+	// Check that the file's entries have versions, marking a new file as having them
+	if err := indexPager.RequireFeature(pager.VERSIONED_ENTRIES_FLAG); err != nil {
+		indexPager.Close()
+		return nil, err
+	}
+	compare, err := lookupComparator(ComparatorID(indexPager.GetComparatorID()))
+	if err != nil {
+		indexPager.Close()
+		return nil, err
+	}
 	// Initialize the pager if it's new, creating a leaf root node
-	if pager.GetNumPages() == 0 {
-		rootPage, err := pager.GetNewPage()
+	if indexPager.GetNumPages() == 0 {
+		rootPage, err := indexPager.GetNewPage()
 		if err != nil {
 			return nil, err
 		}
-		defer pager.PutPage(rootPage)
+		defer indexPager.PutPage(rootPage)
 		initPage(rootPage, LEAF_NODE)
 		rootNode := pageToLeafNode(rootPage)
 		rootNode.setRightSibling(-1)
+		rootNode.setLeftSibling(-1)
+	}
+	index := &BTreeIndex{pager: indexPager, rootPN: ROOT_PN, compare: compare}
+	index.ops.finished = sync.NewCond(&index.ops.mtx)
+	index.mergeGap.Store(DEFAULT_MERGE_GAP)
+	height, err := index.measureHeight()
+	if err != nil {
+		return nil, err
+	}
+	index.height.Store(height)
+	return index, nil
+}
+
+// measureHeight returns the number of levels in the B+Tree by following the leftmost path from the root.
+func (index *BTreeIndex) measureHeight() (int64, error) {
+	height := int64(1)
+	pn := index.rootPN
+	for {
+		page, err := index.pager.GetPage(pn)
+		if err != nil {
+			return 0, err
+		}
+		node, isInternal := pageToNode(page).(*InternalNode)
+		if isInternal {
+			pn = node.getPNAt(0)
+		}
+		index.pager.PutPage(page)
+		if !isInternal {
+			return height, nil
+		}
+		height++
 	}
-	return &BTreeIndex{pager: pager, rootPN: ROOT_PN}, nil
+}
+
+// insertPinBudget returns the most pages an insert may pin at once: every node on the path
+// from the root to a leaf, a new sibling for each of them if they all split, and a new page for the old root.
+func (index *BTreeIndex) insertPinBudget() int64 {
+	return 2*index.height.Load() + 1
 }
 
 // GetName returns the base file name of the file backing this index's pager.
@@ -48,37 +133,158 @@ func (index *BTreeIndex) GetPager() *pager.Pager {
 	return index.pager
 }
 
-// Close flushes all changes to disk.
+// Close flushes all changes to disk. Lookups, scans, writes and new cursors that start once Close is called get
+// pager.ErrClosing, and Close waits for those already running, and any cursors still open (see Pager.Close), to finish.
 func (index *BTreeIndex) Close() (err error) {
+	index.waitForOps()
 	err = index.pager.Close()
+	if err != nil && !errors.Is(err, pager.ErrClosing) {
+		index.reopenOps()
+	}
 	return err
 }
 
 // Find returns the entry associated with the given key, or an error if
 // no entry with that key is found.
 func (index *BTreeIndex) Find(key int64) (entry.Entry, error) {
-	// Get the root node.
-	rootPage, err := index.pager.GetPage(index.rootPN)
+	if err := index.beginOp(); err != nil {
+		return entry.Entry{}, err
+	}
+	defer index.endOp()
+	return index.find(key)
+}
+
+// find carries out a Find once it is registered with beginOp, or while operations are paused.
+func (index *BTreeIndex) find(key int64) (entry.Entry, error) {
+	// [CONCURRENCY] Lookups only ever read lock nodes, so they can run alongside each other and non-splitting inserts.
+	leaf, err := index.lockLeaf(key, false)
 	if err != nil {
 		return entry.Entry{}, err
 	}
-	// [CONCURRENCY] Lock and eventually unlock the root node.
-	lockRoot(rootPage)
-	rootNode := pageToNode(rootPage)
-	initRootNode(rootNode)
-	defer unsafeUnlockRoot(rootNode)
-	defer index.pager.PutPage(rootPage)
-	// Start the lookup process on the root node
-	value, found := rootNode.get(key)
-	if found {
-		return entry.New(key, value), nil
+	defer index.pager.PutPage(leaf.page)
+	defer leaf.page.RUnlock()
+	i := leaf.search(key)
+	if i < leaf.numKeys && leaf.getKeyAt(i) == key {
+		return leaf.getEntry(i), nil
 	}
 	return entry.Entry{}, fmt.Errorf("no entry with key %d was found", key)
 }
 
+// lockLeaf returns the leaf that would hold the given key, write locked if write is set and read locked otherwise.
+// [CONCURRENCY] Internal nodes are read locked, each only until its child is locked, so at most two pages
+// are locked at once and the SUPER_NODE is never taken. The caller must unlock and put the leaf's page.
+func (index *BTreeIndex) lockLeaf(key int64, write bool) (*LeafNode, error) {
+	page, err := index.pager.GetPage(index.rootPN)
+	if err != nil {
+		return nil, err
+	}
+	page.RLock()
+	// Let go of the page in hand if the descent panics, say in the comparator.
+	defer onPanic(func() {
+		page.RUnlock()
+		index.pager.PutPage(page)
+	})
+	// The root is the only node whose type can change, so recheck it after trading the read lock for a write lock.
+	for write && pageToNodeHeader(page).nodeType == LEAF_NODE {
+		page.RUnlock()
+		page.WLock()
+		if pageToNodeHeader(page).nodeType == LEAF_NODE {
+			return lockedLeaf(page, write), nil
+		}
+		// The root split in between, though a delete may have collapsed it back into a leaf since.
+		page.WUnlock()
+		page.RLock()
+	}
+	for {
+		internal, isInternal := pageToNode(page).(*InternalNode)
+		if !isInternal {
+			return lockedLeaf(page, write), nil
+		}
+		child, err := index.pager.GetPage(internal.getPNAt(internal.search(key)))
+		if err != nil {
+			page.RUnlock()
+			index.pager.PutPage(page)
+			return nil, err
+		}
+		child.RLock()
+		// Upgrading a leaf while its parent is still read locked is safe, since splitting it would need the parent's write lock.
+		if write && pageToNodeHeader(child).nodeType == LEAF_NODE {
+			child.RUnlock()
+			child.WLock()
+		}
+		page.RUnlock()
+		index.pager.PutPage(page)
+		page = child
+	}
+}
+
+// lockedLeaf returns the leaf on the given page, which lockLeaf has locked, marking it as locked if it is write locked.
+func lockedLeaf(page *pager.Page, write bool) *LeafNode {
+	leaf := pageToLeafNode(page)
+	leaf.locked = write
+	return leaf
+}
+
 // Insert inserts a key-value entry into the B+Tree,
 // returning an error if there is a problem with the insertion or splitting process.
 func (index *BTreeIndex) Insert(key int64, value int64) error {
+	defer index.checkRootInvariant("insert", key)
+	return index.insert(key, value, false, false, ANY_VERSION)
+}
+
+// Update modifies the value associated with an existing key.
+func (index *BTreeIndex) Update(key int64, value int64) error {
+	defer index.checkRootInvariant("update", key)
+	return index.insert(key, value, true, false, ANY_VERSION)
+}
+
+// Upsert modifies the value associated with the given key, inserting a new entry if the key doesn't exist.
+func (index *BTreeIndex) Upsert(key int64, value int64) error {
+	defer index.checkRootInvariant("upsert", key)
+	return index.insert(key, value, true, true, ANY_VERSION)
+}
+
+// CompareAndSwap updates the value associated with an existing key if the entry is at expectedVersion,
+// returning the entry's new version. Returns an entry.ErrVersionConflict if it is at a different version.
+func (index *BTreeIndex) CompareAndSwap(key int64, expectedVersion int64, newValue int64) (newVersion int64, err error) {
+	if expectedVersion < 0 {
+		return 0, fmt.Errorf("%w: versions are never negative", entry.ErrVersionConflict)
+	}
+	defer index.checkRootInvariant("compare and swap", key)
+	if err = index.insert(key, newValue, true, false, expectedVersion); err != nil {
+		return 0, err
+	}
+	return expectedVersion + 1, nil
+}
+
+// insert inserts or updates an entry depending on the update and upsert flags (see LeafNode.insert),
+// splitting the root node if necessary.
+func (index *BTreeIndex) insert(key int64, value int64, update bool, upsert bool, expectedVersion int64) error {
+	if err := index.beginOp(); err != nil {
+		return err
+	}
+	defer index.endOp()
+	return index.insertEntry(key, value, update, upsert, expectedVersion)
+}
+
+// insertEntry carries out an insert once it is registered with beginOp, or while operations are paused.
+func (index *BTreeIndex) insertEntry(key int64, value int64, update bool, upsert bool, expectedVersion int64) error {
+	// Fail early if the buffer may not have room for every page the insert could pin.
+	if err := index.pager.CheckPinBudget(index.insertPinBudget()); err != nil {
+		return err
+	}
+	// [CONCURRENCY] Optimistically write lock only the leaf; if it might split, retry write locking from the root.
+	leaf, err := index.lockLeaf(key, true)
+	if err != nil {
+		return err
+	}
+	if !leaf.canSplit() {
+		defer index.pager.PutPage(leaf.page)
+		_, err = leaf.insert(key, value, update, upsert, expectedVersion)
+		return err
+	}
+	leaf.unlock()
+	index.pager.PutPage(leaf.page)
 	// Get the root node.
 	rootPage, err := index.pager.GetPage(index.rootPN)
 	if err != nil {
@@ -91,7 +297,7 @@ func (index *BTreeIndex) Insert(key int64, value int64) error {
 	defer unsafeUnlockRoot(rootNode)
 	defer index.pager.PutPage(rootPage)
 	// Insert the entry into the root node.
-	result, err := rootNode.insert(key, value, false)
+	result, err := rootNode.insert(key, value, update, upsert, expectedVersion)
 	if err != nil || !result.isSplit {
 		return err
 	}
@@ -121,6 +327,10 @@ func (index *BTreeIndex) Insert(key int64, value int64) error {
 		leafyRoot := pageToLeafNode(rootNode.getPage())
 		newNode.copy(leafyRoot)
 		newNodePN = newNode.page.GetPageNum()
+		// The split pointed the right leaf back at the root's page, which is about to become internal.
+		if err := relinkLeftSibling(index.pager, result.rightPN, newNodePN); err != nil {
+			return err
+		}
 	} else {
 		// Create a new internal node.
 		newNode, err := createInternalNode(index.pager)
@@ -141,42 +351,119 @@ func (index *BTreeIndex) Insert(key int64, value int64) error {
 	newRoot.updatePNAt(0, newNodePN)
 	newRoot.updatePNAt(1, result.rightPN)
 	newRoot.updateNumKeys(1)
+	index.height.Add(1)
 	return nil
 }
 
-// Update modifies the value associated with an existing key.
-func (index *BTreeIndex) Update(key int64, value int64) error {
-	// Get the root node.
-	rootPage, err := index.pager.GetPage(index.rootPN)
+// Rekey moves the entry with oldKey to newKey, keeping its value. Returns an error
+// if there is no entry with oldKey, or if there is already an entry with newKey.
+// Like the hash table's Rekey, which write locks the whole table, it pauses every other lookup, scan and write
+// on the B+Tree while it moves the entry, so none sees both entries at once, or neither. Cursors that were
+// already open aren't paused, so they may see either.
+func (index *BTreeIndex) Rekey(oldKey int64, newKey int64) error {
+	if err := index.pauseOps(); err != nil {
+		return err
+	}
+	defer index.resumeOps()
+	defer index.checkRootInvariant("rekey", newKey)
+	e, err := index.find(oldKey)
 	if err != nil {
 		return err
 	}
-	// [CONCURRENCY] Lock and eventually unlock the root node.
-	lockRoot(rootPage)
-	rootNode := pageToNode(rootPage)
-	initRootNode(rootNode)
-	defer unsafeUnlockRoot(rootNode)
-	defer index.pager.PutPage(rootPage)
-	// Update the entry.
-	_, err = rootNode.insert(key, value, true)
-	return err
+	if err := index.insertEntry(newKey, e.Value, false, false, ANY_VERSION); err != nil {
+		return err
+	}
+	return index.delete(oldKey)
 }
 
 // Delete removes the entry with the given key from the B+Tree.
 func (index *BTreeIndex) Delete(key int64) error {
-	// Get the root node.
+	if err := index.beginOp(); err != nil {
+		return err
+	}
+	defer index.endOp()
+	defer index.checkRootInvariant("delete", key)
+	return index.delete(key)
+}
+
+// delete carries out a Delete once it is registered with beginOp, or while operations are paused.
+func (index *BTreeIndex) delete(key int64) error {
+	// [CONCURRENCY] Optimistically write lock only the leaf; if the delete could make it underflow,
+	// start over write locking from the root so that its parents can be rebalanced.
+	leaf, err := index.lockLeaf(key, true)
+	if err != nil {
+		return err
+	}
+	gap := index.mergeGap.Load()
+	if !leaf.canUnderflow(gap) {
+		defer index.pager.PutPage(leaf.page)
+		leaf.delete(key, gap)
+		return nil
+	}
+	leaf.unlock()
+	index.pager.PutPage(leaf.page)
 	rootPage, err := index.pager.GetPage(index.rootPN)
 	if err != nil {
 		return err
 	}
-	// [CONCURRENCY] Lock and eventually unlock the root node.
+	defer index.pager.PutPage(rootPage)
 	lockRoot(rootPage)
 	rootNode := pageToNode(rootPage)
 	initRootNode(rootNode)
 	defer unsafeUnlockRoot(rootNode)
+	if !rootNode.delete(key, gap) {
+		return nil
+	}
+	// The root was left with a single child, so replace it with that child.
+	root := rootNode.(*InternalNode)
+	err = index.collapseRoot(root)
+	root.unlockParents()
+	root.unlock()
+	return err
+}
+
+// collapseRoot copies the root's only child into the root page, keeping the root at its pagenumber
+// and making the B+Tree one level shorter. The child's page is freed afterwards.
+// Expects the root to be write locked.
+func (index *BTreeIndex) collapseRoot(root *InternalNode) error {
+	child, err := root.getAndLockChildAt(0)
+	if err != nil {
+		return err
+	}
+	root.page.Update(child.getPage().GetData(), 0, index.pager.GetPageSize())
+	index.height.Add(-1)
+	// The only child has no siblings, so nothing else points at its page.
+	child.getPage().WUnlock()
+	index.pager.FreePage(child.getPage())
+	index.pager.PutPage(child.getPage())
+	return nil
+}
+
+// Truncate removes every entry, resetting the B+Tree to an empty root leaf and shrinking its file to that page.
+// Lookups and writes that start meanwhile wait for it. Returns an error if a page is still pinned, e.g. by an open cursor.
+func (index *BTreeIndex) Truncate() error {
+	if err := index.pauseOps(); err != nil {
+		return err
+	}
+	defer index.resumeOps()
+	if n := index.pager.GetNumPinned(); n > 0 {
+		return fmt.Errorf("cannot truncate while %d pages are pinned", n)
+	}
+	if err := index.pager.Truncate(index.rootPN + 1); err != nil {
+		return err
+	}
+	rootPage, err := index.pager.GetPage(index.rootPN)
+	if err != nil {
+		return err
+	}
 	defer index.pager.PutPage(rootPage)
-	// Delete the key.
-	rootNode.delete(key)
+	rootPage.WLock()
+	defer rootPage.WUnlock()
+	initPage(rootPage, LEAF_NODE)
+	rootNode := pageToLeafNode(rootPage)
+	rootNode.setRightSibling(-1)
+	rootNode.setLeftSibling(-1)
+	index.height.Store(1)
 	return nil
 }
 
@@ -184,17 +471,24 @@ func (index *BTreeIndex) Delete(key int64) error {
 // ordered by their keys.
 func (index *BTreeIndex) Select() ([]entry.Entry, error) {
 	/* SOLUTION {{{ */
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
 	// Use a cursor to traverse the B+Tree from start to end
 	entries := make([]entry.Entry, 0)
 	// Get a cursor pointing to the first entry
 	// Cursor returns locked
-	cursor, err := index.CursorAtStart()
-	
+	cursor, err := index.cursorAtStart()
 
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close()
+	// An empty B+Tree has no entries to select
+	if !cursor.Valid() {
+		return entries, nil
+	}
 
 	// Traverse over all entries.
 	for {
@@ -212,20 +506,57 @@ func (index *BTreeIndex) Select() ([]entry.Entry, error) {
 	/* SOLUTION }}} */
 }
 
+// SelectBatched returns a slice of all the entries in the B+Tree ordered by their keys, like Select,
+// but copies each leaf's entries out at once rather than stepping a cursor through it entry by entry.
+func (index *BTreeIndex) SelectBatched() ([]entry.Entry, error) {
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
+	entries := make([]entry.Entry, 0)
+	cursor, err := index.cursorAtStart()
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+	// [CONCURRENCY] The cursor locks the next leaf before letting go of the current one, so a leaf can't be merged
+	// away and its page freed between reading its right sibling's page number and reaching that page.
+	for cursor.Valid() {
+		leaf := cursor.curNode
+		for i := cursor.curIndex; i < leaf.numKeys; i++ {
+			entries = append(entries, leaf.getEntry(i))
+		}
+		// Skip to the start of the next leaf
+		cursor.curIndex = leaf.numKeys - 1
+		if cursor.Next() {
+			break
+		}
+	}
+	return entries, nil
+}
+
 // SelectRange returns a slice of entries with keys between the startKey and endKey.
-// startKey is inclusive, and endKey is exclusive --> [startKey, endKey).
-// return an error if startKey >= endKey or some other error occurs
+// startKey is inclusive, and endKey is exclusive --> [startKey, endKey), in the order of the index's comparator.
+// return an error if startKey doesn't sort before endKey or some other error occurs
 func (index *BTreeIndex) SelectRange(startKey int64, endKey int64) ([]entry.Entry, error) {
 	/* SOLUTION {{{ */
-	if startKey >= endKey {
+	if index.compare(startKey, endKey) >= 0 {
 		return nil, errors.New("startKey is not smaller than endKey")
 	}
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
 	ret := make([]entry.Entry, 0)
-	c, err := index.CursorAt(startKey)
+	c, err := index.cursorAt(startKey)
 	if err != nil {
 		return nil, err
 	}
 	defer c.Close()
+	// There are no entries at or after startKey
+	if !c.Valid() {
+		return ret, nil
+	}
 	// Get the first entry that the cursor is pointing at
 	checkEntry, err := c.GetEntry()
 	if err != nil {
@@ -233,7 +564,7 @@ func (index *BTreeIndex) SelectRange(startKey int64, endKey int64) ([]entry.Entr
 	}
 	// Get all the desired entries by looping until endKey is reached/exceeded
 	// or until we don't have any more entries
-	for endKey > checkEntry.Key {
+	for index.compare(checkEntry.Key, endKey) < 0 {
 		ret = append(ret, checkEntry)
 		if c.Next() {
 			return ret, nil
@@ -247,6 +578,195 @@ func (index *BTreeIndex) SelectRange(startKey int64, endKey int64) ([]entry.Entr
 	/* SOLUTION }}} */
 }
 
+// RangeCount returns the number of entries with keys between the startKey and endKey.
+// startKey is inclusive, and endKey is exclusive --> [startKey, endKey), in the order of the index's comparator.
+// Leaves that fall entirely within the range are counted without reading their entries.
+// return an error if startKey doesn't sort before endKey or some other error occurs
+func (index *BTreeIndex) RangeCount(startKey int64, endKey int64) (int64, error) {
+	if index.compare(startKey, endKey) >= 0 {
+		return 0, errors.New("startKey is not smaller than endKey")
+	}
+	if err := index.beginOp(); err != nil {
+		return 0, err
+	}
+	defer index.endOp()
+	cursor, err := index.cursorAt(startKey)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close()
+	count := int64(0)
+	for cursor.Valid() {
+		leaf := cursor.curNode
+		// The range ends within this leaf
+		if index.compare(leaf.getKeyAt(leaf.numKeys-1), endKey) >= 0 {
+			return count + leaf.search(endKey) - cursor.curIndex, nil
+		}
+		count += leaf.numKeys - cursor.curIndex
+		// Skip to the start of the next leaf
+		cursor.curIndex = leaf.numKeys - 1
+		if cursor.Next() {
+			break
+		}
+	}
+	return count, nil
+}
+
+// Count returns the number of entries in the B+Tree, summing the key counts in the leaves' headers
+// along the sibling chain without reading their entries.
+func (index *BTreeIndex) Count() (int64, error) {
+	if err := index.beginOp(); err != nil {
+		return 0, err
+	}
+	defer index.endOp()
+	cursor, err := index.cursorAtStart()
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close()
+	count := int64(0)
+	for cursor.Valid() {
+		count += cursor.curNode.numKeys
+		// Skip to the start of the next leaf
+		cursor.curIndex = cursor.curNode.numKeys - 1
+		if cursor.Next() {
+			break
+		}
+	}
+	return count, nil
+}
+
+// SelectChunks passes every entry in the B+Tree, ordered by key, to fn in slices of at most chunkSize entries.
+// No pages are held while fn runs; the next chunk resumes from the key after the last entry passed to fn.
+func (index *BTreeIndex) SelectChunks(chunkSize int, fn func([]entry.Entry) error) error {
+	if chunkSize <= 0 {
+		return errors.New("chunk size must be positive")
+	}
+	chunk, atEnd, err := index.nextChunk(chunkSize, 0, true)
+	for {
+		if err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := fn(chunk); err != nil {
+			return err
+		}
+		if atEnd {
+			return nil
+		}
+		chunk, atEnd, err = index.nextChunk(chunkSize, chunk[len(chunk)-1].Key, false)
+	}
+}
+
+// nextChunk reads up to chunkSize entries starting at the first entry that sorts after the given key,
+// or at the first entry if fromStart is set, reporting whether it ran out of entries.
+// Each chunk is read as an operation of its own, so the caller may use the index between chunks.
+func (index *BTreeIndex) nextChunk(chunkSize int, key int64, fromStart bool) (chunk []entry.Entry, atEnd bool, err error) {
+	if err := index.beginOp(); err != nil {
+		return nil, false, err
+	}
+	defer index.endOp()
+	var c *BTreeCursor
+	if fromStart {
+		c, err = index.cursorAtStart()
+	} else {
+		c, err = index.cursorAfter(key)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer c.Close()
+	return readChunk(c, chunkSize)
+}
+
+// cursorAfter returns a cursor pointing to the first entry that sorts after the given key.
+// If there is no such entry, the returned cursor is not valid.
+func (index *BTreeIndex) cursorAfter(key int64) (*BTreeCursor, error) {
+	cursor, err := index.cursorAt(key)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Valid() && cursor.curNode.getKeyAt(cursor.curIndex) == key && cursor.Next() {
+		// The key's entry was the last one.
+		cursor.curIndex = cursor.curNode.numKeys
+	}
+	return cursor, nil
+}
+
+// readChunk reads up to chunkSize entries starting at the cursor's position,
+// reporting whether the cursor ran out of entries.
+func readChunk(c cursor.Cursor, chunkSize int) (chunk []entry.Entry, atEnd bool, err error) {
+	chunk = make([]entry.Entry, 0, chunkSize)
+	if !c.Valid() {
+		return chunk, true, nil
+	}
+	for len(chunk) < chunkSize {
+		e, err := c.GetEntry()
+		if err != nil {
+			return nil, false, err
+		}
+		chunk = append(chunk, e)
+		if c.Next() {
+			return chunk, true, nil
+		}
+	}
+	return chunk, false, nil
+}
+
+// Metadata returns the B+Tree's metadata, counting its entries.
+func (index *BTreeIndex) Metadata() (pager.TableMetadata, error) {
+	count, err := index.Count()
+	if err != nil {
+		return pager.TableMetadata{}, err
+	}
+	return index.pager.Metadata("btree", count), nil
+}
+
+// Warmup reads the B+Tree's pages into the pager's buffer, stopping once the buffer is full.
+// Pages are read breadth-first from the root so that internal nodes, which every lookup
+// passes through, are cached before leaves. Pages are not left pinned after being loaded.
+// Lookups and writes that start meanwhile wait for it, since a delete could free a queued page before it is read.
+func (index *BTreeIndex) Warmup() error {
+	if err := index.pauseOps(); err != nil {
+		return err
+	}
+	defer index.resumeOps()
+	internalPNs := make([]int64, 0)
+	queue := []int64{index.rootPN}
+	bufSize := index.pager.GetBufferSize()
+	for numLoaded := int64(0); len(queue) > 0 && numLoaded < bufSize; numLoaded++ {
+		pn := queue[0]
+		queue = queue[1:]
+		page, err := index.pager.GetPage(pn)
+		if errors.Is(err, pager.ErrRanOutOfPages) {
+			break
+		} else if err != nil {
+			return err
+		}
+		page.RLock()
+		if node, ok := pageToNode(page).(*InternalNode); ok {
+			internalPNs = append(internalPNs, pn)
+			for i := int64(0); i <= node.numKeys; i++ {
+				queue = append(queue, node.getPNAt(i))
+			}
+		}
+		page.RUnlock()
+		index.pager.PutPage(page)
+	}
+	// Touch the internal nodes again from the bottom up, so that they're the last
+	// pages to be evicted, with the root being evicted last of all.
+	for i := len(internalPNs) - 1; i >= 0; i-- {
+		page, err := index.pager.GetPage(internalPNs[i])
+		if err != nil {
+			return err
+		}
+		index.pager.PutPage(page)
+	}
+	return nil
+}
+
 // Print will pretty-print all nodes in the B+Tree.
 func (index *BTreeIndex) Print(w io.Writer) {
 	rootPage, err := index.pager.GetPage(index.rootPN)
@@ -0,0 +1,145 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+
+	"dinodb/pkg/entry"
+)
+
+// Error for when a bulk load is given entries out of order, or is run on a B+Tree that isn't empty
+var ErrBulkLoad = errors.New("cannot bulk load")
+
+// bulkChild is a node built by a bulk load, waiting to be given a parent.
+type bulkChild struct {
+	minKey int64 // The smallest key under the node.
+	pn     int64 // The node's pagenumber.
+}
+
+// BulkLoad fills an empty B+Tree with the given entries, which must be sorted in the B+Tree's key order
+// with no duplicate keys. Rather than inserting the entries one at a time, it writes the leaves from left
+// to right, then each level of internal nodes above them, filling nodes as full as they can be without
+// splitting and spreading the entries evenly so that none is left underfull. Entries keep their versions.
+// Lookups and writes that start meanwhile wait for it. Returns an ErrBulkLoad if the B+Tree isn't empty.
+func (index *BTreeIndex) BulkLoad(entries []entry.Entry) error {
+	for i := 1; i < len(entries); i++ {
+		if index.compare(entries[i-1].Key, entries[i].Key) >= 0 {
+			return fmt.Errorf("%w: key %d is not in order after key %d", ErrBulkLoad, entries[i].Key, entries[i-1].Key)
+		}
+	}
+	if err := index.pauseOps(); err != nil {
+		return err
+	}
+	defer index.resumeOps()
+	if n := index.pager.GetNumPages(); n != index.rootPN+1 {
+		return fmt.Errorf("%w: B+Tree already has %d pages", ErrBulkLoad, n)
+	}
+	rootPage, err := index.pager.GetPage(index.rootPN)
+	if err != nil {
+		return err
+	}
+	defer index.pager.PutPage(rootPage)
+	rootPage.WLock()
+	defer rootPage.WUnlock()
+	root := pageToLeafNode(rootPage)
+	if root.numKeys != 0 {
+		return fmt.Errorf("%w: B+Tree already has entries", ErrBulkLoad)
+	}
+	// A single leaf's worth of entries stays in the root leaf.
+	if int64(len(entries)) < root.maxEntries() {
+		root.setEntries(entries)
+		return nil
+	}
+	level, err := index.bulkLoadLeaves(entries, root.maxEntries()-1)
+	if err != nil {
+		return err
+	}
+	height := int64(2)
+	maxChildren := pageToInternalNode(rootPage).maxKeys()
+	for ; int64(len(level)) > maxChildren; height++ {
+		if level, err = index.bulkLoadInternals(level, maxChildren); err != nil {
+			return err
+		}
+	}
+	// The top level fits in a single node, which becomes the root.
+	initPage(rootPage, INTERNAL_NODE)
+	fillBulkInternal(pageToInternalNode(rootPage), level)
+	index.height.Store(height)
+	return nil
+}
+
+// bulkLoadLeaves writes the entries into a chain of new leaves holding up to perLeaf entries each,
+// returning the leaves in order.
+func (index *BTreeIndex) bulkLoadLeaves(entries []entry.Entry, perLeaf int64) ([]bulkChild, error) {
+	groups := evenGroups(int64(len(entries)), perLeaf)
+	leaves := make([]bulkChild, 0, len(groups))
+	var prev *LeafNode
+	start := int64(0)
+	for _, size := range groups {
+		leaf, err := createLeafNode(index.pager)
+		if err != nil {
+			if prev != nil {
+				index.pager.PutPage(prev.page)
+			}
+			return nil, err
+		}
+		leaf.setEntries(entries[start : start+size])
+		leaf.setRightSibling(-1)
+		leaf.setLeftSibling(-1)
+		if prev != nil {
+			prev.setRightSibling(leaf.page.GetPageNum())
+			leaf.setLeftSibling(prev.page.GetPageNum())
+			index.pager.PutPage(prev.page)
+		}
+		leaves = append(leaves, bulkChild{minKey: entries[start].Key, pn: leaf.page.GetPageNum()})
+		prev = leaf
+		start += size
+	}
+	index.pager.PutPage(prev.page)
+	return leaves, nil
+}
+
+// bulkLoadInternals writes a level of new internal nodes with up to maxChildren children each over the given
+// nodes, returning the new nodes in order.
+func (index *BTreeIndex) bulkLoadInternals(children []bulkChild, maxChildren int64) ([]bulkChild, error) {
+	groups := evenGroups(int64(len(children)), maxChildren)
+	parents := make([]bulkChild, 0, len(groups))
+	start := int64(0)
+	for _, size := range groups {
+		node, err := createInternalNode(index.pager)
+		if err != nil {
+			return nil, err
+		}
+		fillBulkInternal(node, children[start:start+size])
+		index.pager.PutPage(node.page)
+		parents = append(parents, bulkChild{minKey: children[start].minKey, pn: node.page.GetPageNum()})
+		start += size
+	}
+	return parents, nil
+}
+
+// fillBulkInternal points the internal node at the given children, separating each from the one before it by its smallest key.
+func fillBulkInternal(node *InternalNode, children []bulkChild) {
+	keys := make([]int64, 0, len(children)-1)
+	pns := make([]int64, 0, len(children))
+	for i, child := range children {
+		if i > 0 {
+			keys = append(keys, child.minKey)
+		}
+		pns = append(pns, child.pn)
+	}
+	node.setKeysAndPNs(keys, pns)
+}
+
+// evenGroups splits n items into as few groups of at most size items as possible, with sizes that differ by at most one.
+func evenGroups(n int64, size int64) []int64 {
+	numGroups := (n + size - 1) / size
+	groups := make([]int64, numGroups)
+	for i := range groups {
+		groups[i] = n / numGroups
+		if int64(i) < n%numGroups {
+			groups[i]++
+		}
+	}
+	return groups
+}
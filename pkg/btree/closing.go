@@ -0,0 +1,84 @@
+package btree
+
+import (
+	"sync"
+
+	"dinodb/pkg/pager"
+)
+
+// opTracker counts the operations running on a B+Tree, so that Close can wait for them to finish.
+type opTracker struct {
+	mtx      sync.Mutex
+	finished *sync.Cond // Signalled on mtx when the last running operation finishes, or when operations resume.
+	numOps   int64      // The number of operations running.
+	closing  bool       // Whether Close has been called, so that new operations are rejected.
+	paused   bool       // Whether the whole B+Tree is being changed, such as by Truncate, so that new operations wait.
+}
+
+// beginOp registers an operation on the B+Tree, returning pager.ErrClosing if the B+Tree is closing.
+// Operations that get pages after beginOp succeeds must call endOp once they've put them.
+func (index *BTreeIndex) beginOp() error {
+	index.ops.mtx.Lock()
+	defer index.ops.mtx.Unlock()
+	for index.ops.paused && !index.ops.closing {
+		index.ops.finished.Wait()
+	}
+	if index.ops.closing {
+		return pager.ErrClosing
+	}
+	index.ops.numOps++
+	return nil
+}
+
+// endOp marks an operation registered with beginOp as finished.
+func (index *BTreeIndex) endOp() {
+	index.ops.mtx.Lock()
+	defer index.ops.mtx.Unlock()
+	index.ops.numOps--
+	if index.ops.numOps == 0 {
+		index.ops.finished.Broadcast()
+	}
+}
+
+// waitForOps marks the B+Tree as closing and waits for the operations already running to finish.
+func (index *BTreeIndex) waitForOps() {
+	index.ops.mtx.Lock()
+	defer index.ops.mtx.Unlock()
+	index.ops.closing = true
+	for index.ops.numOps > 0 || index.ops.paused {
+		index.ops.finished.Wait()
+	}
+}
+
+// reopenOps lets operations run on the B+Tree again after a failed close.
+func (index *BTreeIndex) reopenOps() {
+	index.ops.mtx.Lock()
+	defer index.ops.mtx.Unlock()
+	index.ops.closing = false
+}
+
+// pauseOps waits for the operations already running on the B+Tree to finish, making new ones wait until resumeOps.
+// Returns pager.ErrClosing if the B+Tree is closing.
+func (index *BTreeIndex) pauseOps() error {
+	index.ops.mtx.Lock()
+	defer index.ops.mtx.Unlock()
+	for index.ops.paused && !index.ops.closing {
+		index.ops.finished.Wait()
+	}
+	if index.ops.closing {
+		return pager.ErrClosing
+	}
+	index.ops.paused = true
+	for index.ops.numOps > 0 {
+		index.ops.finished.Wait()
+	}
+	return nil
+}
+
+// resumeOps lets the operations waiting since pauseOps run.
+func (index *BTreeIndex) resumeOps() {
+	index.ops.mtx.Lock()
+	defer index.ops.mtx.Unlock()
+	index.ops.paused = false
+	index.ops.finished.Broadcast()
+}
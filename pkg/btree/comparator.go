@@ -0,0 +1,86 @@
+package btree
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"sync"
+
+	"dinodb/pkg/pager"
+)
+
+// Comparator orders keys, returning a negative number if a sorts before b, zero if they are equal,
+// and a positive number if a sorts after b. It must return zero only for equal keys.
+type Comparator func(a int64, b int64) int
+
+// ComparatorID identifies a comparator in a B+Tree file's superblock, so that the file's keys
+// are ordered the same way whenever it is reopened.
+type ComparatorID int64
+
+const (
+	ASCENDING_COMPARATOR  ComparatorID = 0 // Orders keys from smallest to largest. The default.
+	DESCENDING_COMPARATOR ComparatorID = 1 // Orders keys from largest to smallest.
+)
+
+// Error for when a B+Tree file records a comparator id that hasn't been registered
+var ErrUnknownComparator = errors.New("unknown comparator")
+
+// Error for when a B+Tree file is opened with a different comparator than the one it was created with
+var ErrComparatorMismatch = errors.New("comparator does not match the one the B+Tree was created with")
+
+var (
+	comparatorsMtx sync.RWMutex
+	comparators    = map[ComparatorID]Comparator{
+		ASCENDING_COMPARATOR:  cmp.Compare[int64],
+		DESCENDING_COMPARATOR: func(a int64, b int64) int { return cmp.Compare(b, a) },
+	}
+)
+
+// RegisterComparator makes a custom comparator available under the given id, for OpenIndexWithComparator
+// and for reopening files created with it. Returns an error if the id is already taken.
+func RegisterComparator(id ComparatorID, compare Comparator) error {
+	comparatorsMtx.Lock()
+	defer comparatorsMtx.Unlock()
+	if _, ok := comparators[id]; ok {
+		return fmt.Errorf("comparator %d is already registered", id)
+	}
+	comparators[id] = compare
+	return nil
+}
+
+// lookupComparator returns the comparator registered under the given id.
+func lookupComparator(id ComparatorID) (Comparator, error) {
+	comparatorsMtx.RLock()
+	defer comparatorsMtx.RUnlock()
+	compare, ok := comparators[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownComparator, id)
+	}
+	return compare, nil
+}
+
+// comparatorOf returns the comparator recorded in the given pager's superblock,
+// falling back to ascending order if it isn't registered (openIndex rejects such files).
+func comparatorOf(p *pager.Pager) Comparator {
+	compare, err := lookupComparator(ComparatorID(p.GetComparatorID()))
+	if err != nil {
+		return cmp.Compare[int64]
+	}
+	return compare
+}
+
+// requireComparator records the given comparator in a new file's superblock,
+// or returns an ErrComparatorMismatch if an existing file uses a different one.
+func requireComparator(p *pager.Pager, id ComparatorID) error {
+	if _, err := lookupComparator(id); err != nil {
+		return err
+	}
+	if p.GetNumPages() == 0 {
+		p.SetComparatorID(int64(id))
+		return nil
+	}
+	if recorded := ComparatorID(p.GetComparatorID()); recorded != id {
+		return fmt.Errorf("%w: file uses comparator %d, not %d", ErrComparatorMismatch, recorded, id)
+	}
+	return nil
+}
@@ -11,7 +11,10 @@ import (
 var ROOT_PN int64 = 0
 
 // Entry constants.
-const ENTRYSIZE int64 = binary.MaxVarintLen64 * 2
+const ENTRYSIZE int64 = binary.MaxVarintLen64 * 3
+
+// Passed as the expected version of an update that should apply whatever the entry's version is.
+const ANY_VERSION int64 = -1
 
 // Node header constants.
 const (
@@ -23,14 +26,19 @@ const (
 )
 
 // Leaf node header constants.
+// ENTRIES_PER_LEAF_NODE is for the default page size; see entriesPerLeafNode.
 const (
 	RIGHT_SIBLING_PN_OFFSET int64 = NODE_HEADER_SIZE
 	RIGHT_SIBLING_PN_SIZE   int64 = binary.MaxVarintLen64
-	LEAF_NODE_HEADER_SIZE   int64 = NODE_HEADER_SIZE + RIGHT_SIBLING_PN_SIZE
+	LEFT_SIBLING_PN_OFFSET  int64 = RIGHT_SIBLING_PN_OFFSET + RIGHT_SIBLING_PN_SIZE
+	LEFT_SIBLING_PN_SIZE    int64 = binary.MaxVarintLen64
+	LEAF_NODE_HEADER_SIZE   int64 = NODE_HEADER_SIZE + RIGHT_SIBLING_PN_SIZE + LEFT_SIBLING_PN_SIZE
 	ENTRIES_PER_LEAF_NODE   int64 = ((pager.Pagesize - LEAF_NODE_HEADER_SIZE) / ENTRYSIZE) - 1
 )
 
 // Internal node header constants.
+// KEYS_PER_INTERNAL_NODE, KEYS_SIZE, and PNS_OFFSET are for the default page size;
+// see keysPerInternalNode and pnsOffset.
 const (
 	KEY_SIZE                  int64 = binary.MaxVarintLen64
 	PN_SIZE                   int64 = binary.MaxVarintLen64
@@ -42,5 +50,20 @@ const (
 	PNS_OFFSET                int64 = KEYS_OFFSET + KEYS_SIZE
 )
 
+// entriesPerLeafNode returns the maximum number of entries in a leaf node with the given page size.
+func entriesPerLeafNode(pagesize int64) int64 {
+	return ((pagesize - LEAF_NODE_HEADER_SIZE) / ENTRYSIZE) - 1
+}
+
+// keysPerInternalNode returns the maximum number of keys in an internal node with the given page size.
+func keysPerInternalNode(pagesize int64) int64 {
+	return ((pagesize - INTERNAL_NODE_HEADER_SIZE - KEY_SIZE) / (KEY_SIZE + PN_SIZE)) - 1
+}
+
+// pnsOffset returns the offset of an internal node's pagenumbers with the given page size.
+func pnsOffset(pagesize int64) int64 {
+	return KEYS_OFFSET + KEY_SIZE*(keysPerInternalNode(pagesize)+1)
+}
+
 // [CONCURRENCY]
 var SUPER_NODE = &InternalNode{NodeHeader: NodeHeader{INTERNAL_NODE, 0, &pager.Page{}}}
@@ -5,6 +5,7 @@ import (
 
 	"dinodb/pkg/cursor"
 	"dinodb/pkg/entry"
+	"dinodb/pkg/pager"
 )
 
 // BTreeCursor is a data structure that allows for easy iteration through
@@ -13,16 +14,37 @@ type BTreeCursor struct {
 	index    *BTreeIndex // The B+Tree index that this cursor iterates through.
 	curNode  *LeafNode   // Current leaf node we are pointing at
 	curIndex int64       // The current index within curNode that we are pointing at.
+	lastPN   int64       // The page number of the last leaf to visit, or -1 to visit every leaf.
 }
 
 // CursorAtStart returns a cursor pointing to the first entry of the B+Tree.
+// If the B+Tree is empty, the returned cursor is not valid.
 // Cursor's node should be locked upon leaving, and the page should not have been put
 func (index *BTreeIndex) CursorAtStart() (cursor.Cursor, error) {
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
+	c, err := index.cursorAtStart()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// cursorAtStart carries out a CursorAtStart once it is registered with beginOp.
+func (index *BTreeIndex) cursorAtStart() (*BTreeCursor, error) {
 	// Get the root page.
 	curPage, err := index.pager.GetPage(index.rootPN)
 	if err != nil {
 		return nil, err
 	}
+	// [CONCURRENCY] Nodes are read while locked, since deletes can merge them or turn the root back into a leaf.
+	curPage.RLock()
+	defer onPanic(func() {
+		curPage.RUnlock()
+		index.pager.PutPage(curPage)
+	})
 	curHeader := pageToNodeHeader(curPage)
 	// Traverse down the leftmost children until we reach a leaf node.
 	for curHeader.nodeType != LEAF_NODE {
@@ -30,31 +52,89 @@ func (index *BTreeIndex) CursorAtStart() (cursor.Cursor, error) {
 		leftmostPN := curNode.getPNAt(0)
 		curPage, err = index.pager.GetPage(leftmostPN)
 		if err != nil {
+			curNode.page.RUnlock()
 			index.pager.PutPage(curNode.page)
 			return nil, err
 		}
+		// [CONCURRENCY] lock-crabbing: get child lock, then release parent lock and put its page
+		curPage.RLock()
+		curNode.page.RUnlock()
 		index.pager.PutPage(curNode.page)
 		curHeader = pageToNodeHeader(curPage)
 	}
 	// Set the cursor to point to the first entry in the leftmost leaf node.
 	leftmostNode := pageToLeafNode(curPage)
-	leftmostNode.page.RLock()
 	// Initialize cursor
-	cursor := &BTreeCursor{index: index, curIndex: 0, curNode: leftmostNode}
+	cursor := &BTreeCursor{index: index, curIndex: 0, curNode: leftmostNode, lastPN: -1}
 	// Account for the edge case where the leftmostNode is empty
 	// By adding a call to Next() here if the first node is empty,
 	// we can guarantee that the cursor won't be stuck in an
 	// empty node
+	// If Next() reaches the end, all our leaf nodes are empty and the cursor stays invalid
 	if cursor.curNode.numKeys == 0 {
-		noEntries := cursor.Next()
-		//if noEntries is true, then all our leaf nodes are empty
-		if noEntries {
-			return nil, errors.New("all leaf nodes are empty")
-		}
+		cursor.Next()
+	}
+	return cursor, nil
+}
+
+// CursorAtEnd returns a cursor pointing to the last entry of the B+Tree, for walking it backward with Prev.
+// If the B+Tree is empty, the returned cursor is not valid.
+// Cursor's node should be locked upon leaving, and the page should not have been put
+func (index *BTreeIndex) CursorAtEnd() (*BTreeCursor, error) {
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
+	lastNode, _, _, err := index.rlockLeafBefore(0, true)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &BTreeCursor{index: index, curIndex: max(lastNode.numKeys-1, 0), curNode: lastNode, lastPN: -1}
+	defer onPanic(cursor.Close)
+	// As in CursorAtStart, step off an empty last node; if every node is empty, the cursor stays invalid
+	if cursor.curNode.numKeys == 0 {
+		cursor.Prev()
 	}
 	return cursor, nil
 }
 
+// rlockLeafBefore crabs down from the root to the leaf whose range holds the last keys that sort before the given key,
+// or to the last leaf if toEnd is set, and returns it read-locked. Unless it is the first leaf (hasLow is false),
+// also returns the separator its range starts at: any entries before the key that aren't in the leaf sort before low.
+func (index *BTreeIndex) rlockLeafBefore(key int64, toEnd bool) (leaf *LeafNode, low int64, hasLow bool, err error) {
+	curPage, err := index.pager.GetPage(index.rootPN)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	curPage.RLock()
+	defer onPanic(func() {
+		curPage.RUnlock()
+		index.pager.PutPage(curPage)
+	})
+	for pageToNodeHeader(curPage).nodeType != LEAF_NODE {
+		curNode := pageToInternalNode(curPage)
+		childIdx := curNode.numKeys
+		if !toEnd {
+			childIdx = curNode.searchBefore(key)
+		}
+		if childIdx > 0 {
+			low, hasLow = curNode.getKeyAt(childIdx-1), true
+		}
+		childPage, err := index.pager.GetPage(curNode.getPNAt(childIdx))
+		if err != nil {
+			curPage.RUnlock()
+			index.pager.PutPage(curPage)
+			return nil, 0, false, err
+		}
+		// [CONCURRENCY] lock-crabbing: get child lock, then release parent lock and put its page
+		childPage.RLock()
+		curPage.RUnlock()
+		index.pager.PutPage(curPage)
+		curPage = childPage
+	}
+	return pageToLeafNode(curPage), low, hasLow, nil
+}
+
 // CursorAt returns a cursor pointing to the given key.
 // If the key is not found, calls Next() to reach the next entry
 // after the position of where key would be.
@@ -62,6 +142,19 @@ func (index *BTreeIndex) CursorAtStart() (cursor.Cursor, error) {
 // Hint: use keyToNodeEntry
 // Cursor's node should leave locked, and its page should not have been put
 func (index *BTreeIndex) CursorAt(key int64) (cursor.Cursor, error) {
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
+	c, err := index.cursorAt(key)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// cursorAt carries out a CursorAt once it is registered with beginOp.
+func (index *BTreeIndex) cursorAt(key int64) (*BTreeCursor, error) {
 	// Get the root page.
 	rootPage, err := index.pager.GetPage(index.rootPN)
 	if err != nil {
@@ -72,6 +165,10 @@ func (index *BTreeIndex) CursorAt(key int64) (cursor.Cursor, error) {
 	rootNode := pageToNode(rootPage)
 	// Traverse down the B+Tree to find where the entry with the given key is found
 	curNode := rootNode
+	defer onPanic(func() {
+		curNode.getPage().RUnlock()
+		index.pager.PutPage(curNode.getPage())
+	})
 	for {
 		iNode, ok := curNode.(*InternalNode)
 		if !ok {
@@ -81,6 +178,8 @@ func (index *BTreeIndex) CursorAt(key int64) (cursor.Cursor, error) {
 		i := iNode.search(key)
 		child, err := iNode.getChildAt(i)
 		if err != nil {
+			iNode.page.RUnlock()
+			index.pager.PutPage(iNode.page)
 			return nil, err
 		}
 
@@ -94,7 +193,7 @@ func (index *BTreeIndex) CursorAt(key int64) (cursor.Cursor, error) {
 	}
 
 	// Initialize cursor
-	cursor := &BTreeCursor{index: index, curIndex: curNode.search(key), curNode: curNode.(*LeafNode)}
+	cursor := &BTreeCursor{index: index, curIndex: curNode.search(key), curNode: curNode.(*LeafNode), lastPN: -1}
 	// If the cursor is not pointing at an entry, call Next()
 	// This can happen if the entry associated 'key' was previously deleted
 	// we can do this because CursorAt() is used only for SelectRange()
@@ -104,12 +203,16 @@ func (index *BTreeIndex) CursorAt(key int64) (cursor.Cursor, error) {
 	return cursor, nil
 }
 
-// Next() moves the cursor ahead by one entry. Returns true at the end of the BTree.
+// Next() moves the cursor ahead by one entry. Returns true at the end of the BTree,
+// or at the end of the cursor's last leaf if it has one.
 // Cursor's node should enter and leave locked.
 // The node the cursor is in upon return's page should not have been put
 func (cursor *BTreeCursor) Next() (atEnd bool) {
 	// If the cursor is at the end of the node, go to the next node.
 	if cursor.curIndex+1 >= cursor.curNode.numKeys {
+		if cursor.curNode.page.GetPageNum() == cursor.lastPN {
+			return true
+		}
 		// Get the next node's page number.
 		nextPN := cursor.curNode.rightSiblingPN
 		if nextPN < 0 {
@@ -120,16 +223,17 @@ func (cursor *BTreeCursor) Next() (atEnd bool) {
 		if err != nil {
 			return true
 		}
-		cursor.index.pager.PutPage(cursor.curNode.page)
+		prevPage := cursor.curNode.page
+		cursor.index.pager.PutPage(prevPage)
 
+		// Lock the next node before reading it, since merges and refills move entries between leaves.
+		nextPage.RLock()
 		nextNode := pageToLeafNode(nextPage)
 		// Reinitialize the cursor.
 		cursor.curIndex = 0
 		cursor.curNode = nextNode
-		// Lock the next node.
-		nextNode.page.RLock()
 		//Unlock the previous node
-		cursor.curNode.page.RUnlock()
+		prevPage.RUnlock()
 		
 		// If the next node is empty, step to the next node.
 		// If no deletes are called, then this should never happen
@@ -143,6 +247,92 @@ func (cursor *BTreeCursor) Next() (atEnd bool) {
 	return false
 }
 
+// Prev moves the cursor back by one entry. Returns true at the start of the BTree.
+// Cursor's node should enter and leave locked.
+// The node the cursor is in upon return's page should not have been put
+func (cursor *BTreeCursor) Prev() (atStart bool) {
+	if cursor.curIndex > 0 {
+		cursor.curIndex--
+		return false
+	}
+	// The entries left to visit sort before the current node's first entry;
+	// if the node is empty, they are the rest of the tree.
+	bound, toEnd := int64(0), true
+	if cursor.curNode.numKeys > 0 {
+		bound, toEnd = cursor.curNode.getKeyAt(0), false
+	}
+	// [CONCURRENCY] The node let go of to search from the root, if any; it is relocked if that search panics,
+	// so that the cursor can still be closed.
+	var released *pager.Page
+	defer onPanic(func() {
+		if released != nil {
+			released.RLock()
+		}
+	})
+	for {
+		prevPN := cursor.curNode.leftSiblingPN
+		if prevPN < 0 {
+			return true
+		}
+		prevPage, err := cursor.index.pager.GetPage(prevPN)
+		if err != nil {
+			return true
+		}
+		curPage := cursor.curNode.page
+		// [CONCURRENCY] Writers lock sibling leaves from left to right, so waiting for the previous node's lock
+		// while holding this one could deadlock. If it can't be taken right away, let go of this node and
+		// find the entries before the bound again from the root.
+		if prevPage.TryRLock() {
+			cursor.curNode = pageToLeafNode(prevPage)
+			curPage.RUnlock()
+			cursor.index.pager.PutPage(curPage)
+		} else {
+			cursor.index.pager.PutPage(prevPage)
+			curPage.RUnlock()
+			released = curPage
+			prevNode, low, hasLow, err := cursor.index.rlockLeafBefore(bound, toEnd)
+			released = nil
+			if err != nil {
+				// Stay where we were; nothing else is locked, so waiting for the lock is safe.
+				curPage.RLock()
+				cursor.curNode = pageToLeafNode(curPage)
+				cursor.curIndex = 0
+				return true
+			}
+			cursor.index.pager.PutPage(curPage)
+			cursor.curNode = prevNode
+			// Entries may have moved into this node meanwhile, so look for the last one before the bound.
+			i := cursor.curNode.numKeys
+			if !toEnd {
+				i = cursor.curNode.search(bound)
+			}
+			if i > 0 {
+				cursor.curIndex = i - 1
+				return false
+			}
+			cursor.curIndex = 0
+			// Nothing here sorts before the bound, so the entries left to visit sort before this node's range.
+			if !hasLow {
+				return true
+			}
+			bound, toEnd = low, false
+			continue
+		}
+		// If the previous node is empty, step to the node before it.
+		if cursor.curNode.numKeys > 0 {
+			cursor.curIndex = cursor.curNode.numKeys - 1
+			return false
+		}
+		cursor.curIndex = 0
+	}
+}
+
+// Valid returns whether the cursor is pointing at an entry.
+// Cursors are only invalid if there were no entries at or after where they were created.
+func (cursor *BTreeCursor) Valid() bool {
+	return cursor.curIndex < cursor.curNode.numKeys
+}
+
 // GetEntry returns the entry currently pointed to by the cursor.
 func (cursor *BTreeCursor) GetEntry() (entry.Entry, error) {
 	// Check if we're retrieving a non-existent entry.
@@ -161,5 +351,6 @@ func (cursor *BTreeCursor) GetEntry() (entry.Entry, error) {
 func (cursor *BTreeCursor) Close() {
 	// Unlock the Cursor's node node once we are done with the cursor
 	// and put the page of the node the cursor was in
+	cursor.curNode.page.RUnlock()
 	cursor.index.pager.PutPage(cursor.curNode.page)
 }
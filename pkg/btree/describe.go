@@ -0,0 +1,88 @@
+package btree
+
+// NodeDescription describes a single node of a B+Tree and, for internal nodes, the subtree under it.
+type NodeDescription struct {
+	PN       int64    // The page number the node is stored on.
+	NodeType NodeType // Whether the node is a leaf or an internal node.
+	Keys     []int64  // The node's keys, in order.
+	// For internal nodes, the page numbers of the node's children, and their descriptions, in order.
+	ChildPNs []int64
+	Children []NodeDescription
+	// For leaves, the page number of the right sibling, or -1 for the last leaf.
+	RightSiblingPN int64
+	// For leaves, the page number of the left sibling, or -1 for the first leaf.
+	LeftSiblingPN int64
+}
+
+// TreeDescription describes the structure of a whole B+Tree, for tests and tools to inspect without parsing Print's output.
+type TreeDescription struct {
+	Root   NodeDescription
+	Height int64 // The number of levels in the tree, counting the root and the leaves.
+}
+
+// Leaves returns the descriptions of the tree's leaves, ordered from left to right.
+func (desc TreeDescription) Leaves() []NodeDescription {
+	leaves := make([]NodeDescription, 0)
+	var visit func(node NodeDescription)
+	visit = func(node NodeDescription) {
+		if node.NodeType == LEAF_NODE {
+			leaves = append(leaves, node)
+			return
+		}
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	visit(desc.Root)
+	return leaves
+}
+
+// Describe returns the structure of the B+Tree: every node's page number, type, keys, children, and siblings.
+// Entries' values aren't included. The index must not be modified while this runs.
+func (index *BTreeIndex) Describe() (TreeDescription, error) {
+	root, height, err := index.describeNode(index.rootPN)
+	if err != nil {
+		return TreeDescription{}, err
+	}
+	return TreeDescription{Root: root, Height: height}, nil
+}
+
+// describeNode describes the node on the given page and its subtree, returning the subtree's height.
+func (index *BTreeIndex) describeNode(pn int64) (desc NodeDescription, height int64, err error) {
+	page, err := index.pager.GetPage(pn)
+	if err != nil {
+		return NodeDescription{}, 0, err
+	}
+	defer index.pager.PutPage(page)
+	switch node := pageToNode(page).(type) {
+	case *LeafNode:
+		desc = NodeDescription{PN: pn, NodeType: LEAF_NODE, Keys: make([]int64, node.numKeys),
+			RightSiblingPN: node.rightSiblingPN, LeftSiblingPN: node.leftSiblingPN}
+		for i := range desc.Keys {
+			desc.Keys[i] = node.getKeyAt(int64(i))
+		}
+		return desc, 1, nil
+	case *InternalNode:
+		desc = NodeDescription{
+			PN:       pn,
+			NodeType: INTERNAL_NODE,
+			Keys:     make([]int64, node.numKeys),
+			ChildPNs: make([]int64, node.numKeys+1),
+			Children: make([]NodeDescription, node.numKeys+1),
+		}
+		for i := range desc.Keys {
+			desc.Keys[i] = node.getKeyAt(int64(i))
+		}
+		for i := range desc.ChildPNs {
+			desc.ChildPNs[i] = node.getPNAt(int64(i))
+			var childHeight int64
+			desc.Children[i], childHeight, err = index.describeNode(desc.ChildPNs[i])
+			if err != nil {
+				return NodeDescription{}, 0, err
+			}
+			height = max(height, childHeight)
+		}
+		return desc, height + 1, nil
+	}
+	return NodeDescription{}, 0, nil
+}
@@ -0,0 +1,50 @@
+package btree
+
+// FindPlan describes how Find looks up a key, without reading the entry's value.
+type FindPlan struct {
+	Path  []int64 // The page numbers of the nodes visited, from the root to the leaf.
+	Found bool    // Whether the leaf holds the key.
+	Slot  int64   // The key's position in the leaf, or where it would be inserted if it isn't there.
+}
+
+// ExplainFind traverses the B+Tree as Find would for the given key. Each node on the path
+// is one page access, so a lookup makes one page access per level of the B+Tree.
+func (index *BTreeIndex) ExplainFind(key int64) (FindPlan, error) {
+	var plan FindPlan
+	if err := index.beginOp(); err != nil {
+		return plan, err
+	}
+	defer index.endOp()
+	page, err := index.pager.GetPage(index.rootPN)
+	if err != nil {
+		return plan, err
+	}
+	page.RLock()
+	defer onPanic(func() {
+		page.RUnlock()
+		index.pager.PutPage(page)
+	})
+	for {
+		plan.Path = append(plan.Path, page.GetPageNum())
+		internal, isInternal := pageToNode(page).(*InternalNode)
+		if !isInternal {
+			leaf := pageToLeafNode(page)
+			plan.Slot = leaf.search(key)
+			plan.Found = plan.Slot < leaf.numKeys && leaf.getKeyAt(plan.Slot) == key
+			page.RUnlock()
+			index.pager.PutPage(page)
+			return plan, nil
+		}
+		// Lock the child before releasing its parent, so that it can't be split out from under the lookup.
+		child, err := index.pager.GetPage(internal.getPNAt(internal.search(key)))
+		if err == nil {
+			child.RLock()
+		}
+		page.RUnlock()
+		index.pager.PutPage(page)
+		if err != nil {
+			return plan, err
+		}
+		page = child
+	}
+}
@@ -1,6 +1,7 @@
 package btree
 
 import (
+	"dinodb/pkg/entry"
 	"dinodb/pkg/pager"
 	"encoding/binary"
 	"fmt"
@@ -14,13 +15,15 @@ import (
 type InternalNode struct {
 	NodeHeader      // Embeds all NodeHeader fields.
 	parent     Node // A pointer to the parent node (only used in CONCURRENCY for unlocking)
+	locked     bool // Whether an insert or delete holds this node's lock (only used in CONCURRENCY for unlocking after a panic)
 }
 
 // insert finds the appropriate place in a leaf node to insert a new tuple.
 // [CONCURRENCY]
 // - Unlock parents if it is impossible to split in this operation
 // - Continue with hand-over-hand locking with child node
-func (node *InternalNode) insert(key int64, value int64, update bool) (Split, error) {
+func (node *InternalNode) insert(key int64, value int64, update bool, upsert bool, expectedVersion int64) (Split, error) {
+	defer onPanic(node.unlockAll)
 	// Insert the entry into the appropriate child node.
 	// [CONCURRENCY] Unlock parents if it is impossible to split in this operation
 	if !node.canSplit() {
@@ -28,16 +31,17 @@ func (node *InternalNode) insert(key int64, value int64, update bool) (Split, er
 	}
 	childIdx := node.search(key)
 	child, childErr := node.getAndLockChildAt(childIdx)
-	node.initChild(child)
 	if childErr != nil {
+		node.unlockAll()
 		return Split{}, childErr
 	}
+	node.initChild(child)
 
 	pager := child.getPage().GetPager()
 	defer pager.PutPage(child.getPage())
 	// Insert value into the child.
 
-	result, childErr := child.insert(key, value, update)
+	result, childErr := child.insert(key, value, update, upsert, expectedVersion)
 	if childErr != nil {
 		node.unlockParents()
 		return Split{}, childErr
@@ -74,7 +78,7 @@ func (node *InternalNode) insertSplit(split Split) (Split, error) {
 	node.updatePNAt(insertPos+1, split.rightPN)
 	node.updateNumKeys(node.numKeys + 1)
 	// Check if we need to split.
-	if node.numKeys >= KEYS_PER_INTERNAL_NODE {
+	if node.numKeys >= node.maxKeys() {
 		return node.split()
 	}
 	return Split{}, nil
@@ -113,53 +117,199 @@ func (node *InternalNode) split() (Split, error) {
 	/* SOLUTION }}} */
 }
 
-// delete removes a given tuple from the leaf node, if the given key exists.
-func (node *InternalNode) delete(key int64) {
-	// [CONCURRENCY] Unlock all parent nodes
-	node.unlockParents()
+// delete removes a given tuple from the leaf node, if the given key exists, then rebalances the child
+// it was removed from if that child underflowed. Returns whether this node underflowed in turn:
+// a root underflows once it is left with a single child, and other nodes once they hold fewer than minKeys keys.
+// [CONCURRENCY]
+// - Unlock parents if it is impossible to underflow in this operation
+// - Continue with hand-over-hand locking with child node
+// - An underflowing node is left locked, along with its parents, for its parent to rebalance
+func (node *InternalNode) delete(key int64, gap int64) (underflow bool) {
+	defer onPanic(node.unlockAll)
+	// [CONCURRENCY] Unlock parents if it is impossible to underflow in this operation
+	if !node.canUnderflow(gap) {
+		node.unlockParents()
+	}
 	// Get the next child node where the key would be located under
 	childIdx := node.search(key)
 	child, err := node.getAndLockChildAt(childIdx)
 	if err != nil {
-		return
+		node.unlockParents()
+		node.unlock()
+		return false
 	}
 	// [CONCURRENCY] initialize child node's parent pointer
 	node.initChild(child)
 	pager := child.getPage().GetPager()
 	defer pager.PutPage(child.getPage())
-	// Delete from child
-	child.delete(key)
+	// Delete from child; if it didn't underflow, it has unlocked this node and its parents.
+	if !child.delete(key, gap) {
+		return false
+	}
+	node.rebalanceChild(childIdx, child, gap)
+	if node.underflows(gap) {
+		return true
+	}
+	node.unlockParents()
+	node.unlock()
+	return false
 }
 
-// get returns the value associated with a given key from the leaf node.
-func (node *InternalNode) get(key int64) (value int64, found bool) {
-	// [CONCURRENCY] Unlock parents.
-	node.unlockParents()
-	// Find the child.
-	childIdx := node.search(key)
-	child, err := node.getAndLockChildAt(childIdx)
+// rebalanceChild fixes the underflowing child at the given index by merging it with an adjacent sibling
+// if their entries fit in one node with the merge gap to spare, or by evening out their entries otherwise,
+// then unlocks the child.
+// [CONCURRENCY] Cursors lock leaves from left to right, so the child is relocked along with its sibling in that order.
+func (node *InternalNode) rebalanceChild(childIdx int64, child Node, gap int64) {
+	switch castedChild := child.(type) {
+	case *InternalNode:
+		castedChild.unlock()
+	case *LeafNode:
+		castedChild.unlock()
+	}
+	if node.numKeys == 0 {
+		// There is no sibling to rebalance with.
+		return
+	}
+	// Pair the child with its right sibling, or with its left sibling if it is the last child.
+	leftIdx := min(childIdx, node.numKeys-1)
+	pager := node.page.GetPager()
+	left, err := node.getAndLockChildAt(leftIdx)
 	if err != nil {
-		return 0, false
+		return
 	}
-	// [CONCURRENCY] initialize child's parent pointer
-	node.initChild(child)
-	pager := child.getPage().GetPager()
-	defer pager.PutPage(child.getPage())
-	return child.get(key)
+	defer pager.PutPage(left.getPage())
+	defer left.getPage().WUnlock()
+	right, err := node.getAndLockChildAt(leftIdx + 1)
+	if err != nil {
+		return
+	}
+	var merged bool
+	// [CONCURRENCY] Readers only step onto a node's page while holding its parent or, for a leaf, a sibling pointing at it
+	// (see BTreeCursor.Next and Prev). Those are all locked or relinked here, so a merged away node's page can be freed
+	// once it is unlocked.
+	defer func() {
+		right.getPage().WUnlock()
+		if merged {
+			pager.FreePage(right.getPage())
+		}
+		pager.PutPage(right.getPage())
+	}()
+	switch castedLeft := left.(type) {
+	case *LeafNode:
+		merged = node.rebalanceLeaves(leftIdx, castedLeft, right.(*LeafNode), gap)
+	case *InternalNode:
+		merged = node.rebalanceInternals(leftIdx, castedLeft, right.(*InternalNode), gap)
+	}
+}
+
+// rebalanceLeaves merges the right leaf into the left one if their entries fit in one leaf (see mergedFits),
+// or splits their entries evenly between them otherwise. leftIdx is the left leaf's index in this node.
+// Merging into the left leaf keeps every leaf still in use at its page, so the leaf before the left one
+// needn't be relinked, while the leaf after the right one is pointed back at the left one. Returns whether
+// the leaves were merged, leaving the right leaf's page unreachable. If that relink fails, they are left unmerged.
+func (node *InternalNode) rebalanceLeaves(leftIdx int64, left *LeafNode, right *LeafNode, gap int64) (merged bool) {
+	entries := make([]entry.Entry, 0, left.numKeys+right.numKeys)
+	for i := int64(0); i < left.numKeys; i++ {
+		entries = append(entries, left.getEntry(i))
+	}
+	for i := int64(0); i < right.numKeys; i++ {
+		entries = append(entries, right.getEntry(i))
+	}
+	if mergedFits(int64(len(entries)), left.maxEntries(), gap) {
+		if err := relinkLeftSibling(node.page.GetPager(), right.rightSiblingPN, left.page.GetPageNum()); err != nil {
+			return false
+		}
+		left.setEntries(entries)
+		left.setRightSibling(right.rightSiblingPN)
+		node.removeChildAt(leftIdx + 1)
+		return true
+	}
+	mid := len(entries) / 2
+	left.setEntries(entries[:mid])
+	right.setEntries(entries[mid:])
+	node.updateKeyAt(leftIdx, entries[mid].Key)
+	return false
+}
+
+// rebalanceInternals merges the right internal node into the left one, pulling down the key between them,
+// if their keys fit in one node (see mergedFits), or splits their keys evenly between them otherwise, rotating
+// the middle key up into this node. leftIdx is the left node's index in this node. Returns whether they were merged.
+func (node *InternalNode) rebalanceInternals(leftIdx int64, left *InternalNode, right *InternalNode, gap int64) (merged bool) {
+	keys := make([]int64, 0, left.numKeys+right.numKeys+1)
+	pns := make([]int64, 0, left.numKeys+right.numKeys+2)
+	for i := int64(0); i < left.numKeys; i++ {
+		keys = append(keys, left.getKeyAt(i))
+	}
+	keys = append(keys, node.getKeyAt(leftIdx))
+	for i := int64(0); i < right.numKeys; i++ {
+		keys = append(keys, right.getKeyAt(i))
+	}
+	for i := int64(0); i <= left.numKeys; i++ {
+		pns = append(pns, left.getPNAt(i))
+	}
+	for i := int64(0); i <= right.numKeys; i++ {
+		pns = append(pns, right.getPNAt(i))
+	}
+	if mergedFits(int64(len(keys)), left.maxKeys(), gap) {
+		left.setKeysAndPNs(keys, pns)
+		node.removeChildAt(leftIdx + 1)
+		return true
+	}
+	mid := (len(keys) - 1) / 2
+	left.setKeysAndPNs(keys[:mid], pns[:mid+1])
+	right.setKeysAndPNs(keys[mid+1:], pns[mid+1:])
+	node.updateKeyAt(leftIdx, keys[mid])
+	return false
+}
+
+// removeChildAt removes the child at the given index, which must not be the first child,
+// along with the key that separates it from the child before it.
+func (node *InternalNode) removeChildAt(childIdx int64) {
+	for i := childIdx - 1; i < node.numKeys-1; i++ {
+		node.updateKeyAt(i, node.getKeyAt(i+1))
+	}
+	for i := childIdx; i < node.numKeys; i++ {
+		node.updatePNAt(i, node.getPNAt(i+1))
+	}
+	node.updateNumKeys(node.numKeys - 1)
+}
+
+// setKeysAndPNs replaces the node's keys and child pagenumbers, of which there must be one more than keys.
+func (node *InternalNode) setKeysAndPNs(keys []int64, pns []int64) {
+	for i, key := range keys {
+		node.updateKeyAt(int64(i), key)
+	}
+	for i, pn := range pns {
+		node.updatePNAt(int64(i), pn)
+	}
+	node.updateNumKeys(int64(len(keys)))
 }
 
 /////////////////////////////////////////////////////////////////////////////
 ///////////////////// Internal Node  Helper Functions ///////////////////////
 /////////////////////////////////////////////////////////////////////////////
 
-// search returns the first index where key > given key.
+// search returns the first index where key > given key, in the order of the B+Tree's comparator.
 // If no such index exists, it returns numKeys.
 func (node *InternalNode) search(key int64) int64 {
+	compare := node.comparator()
 	// Binary search for the key.
 	minIndex := sort.Search(
 		int(node.numKeys),
 		func(idx int) bool {
-			return node.getKeyAt(int64(idx)) > key
+			return compare(node.getKeyAt(int64(idx)), key) > 0
+		},
+	)
+	return int64(minIndex)
+}
+
+// searchBefore returns the index of the child whose range holds the last keys that sort before the given key.
+func (node *InternalNode) searchBefore(key int64) int64 {
+	compare := node.comparator()
+	minIndex := sort.Search(
+		int(node.numKeys),
+		func(idx int) bool {
+			return compare(node.getKeyAt(int64(idx)), key) >= 0
 		},
 	)
 	return int64(minIndex)
@@ -199,7 +349,7 @@ func (node *InternalNode) printNode(w io.Writer, firstPrefix string, prefix stri
 // Concurrency note: the given page must at least be read-locked before calling.
 func pageToInternalNode(page *pager.Page) *InternalNode {
 	nodeHeader := pageToNodeHeader(page)
-	return &InternalNode{nodeHeader, nil}
+	return &InternalNode{nodeHeader, nil, false}
 }
 
 // createInternalNode creates and returns a new internal node.
@@ -226,10 +376,29 @@ func (node *InternalNode) getNodeType() NodeType {
 // copy copies the metadata and data of the passed in InternalNode to this InternalNode.
 // Concurrency note: the toCopy node's page must at least be read-locked before calling.
 func (node *InternalNode) copy(toCopy *InternalNode) {
-	node.page.Update(toCopy.page.GetData(), 0, pager.Pagesize)
+	node.page.Update(toCopy.page.GetData(), 0, node.page.GetPager().GetPageSize())
 	node.updateNumKeys(toCopy.numKeys)
 }
 
+// maxKeys returns the maximum number of keys this internal node can hold, based on its page size.
+func (node *InternalNode) maxKeys() int64 {
+	return keysPerInternalNode(node.page.GetPager().GetPageSize())
+}
+
+// minKeys returns the fewest keys an internal node other than the root may hold before it is merged or refilled,
+// with a merge gap of gap percent.
+func (node *InternalNode) minKeys(gap int64) int64 {
+	return minKeysWithGap((node.maxKeys()-1)/2, node.maxKeys(), gap)
+}
+
+// underflows returns whether the node has too few keys: none for the root, or fewer than minKeys for other nodes.
+func (node *InternalNode) underflows(gap int64) bool {
+	if node.isRoot() {
+		return node.numKeys == 0
+	}
+	return node.numKeys < node.minKeys(gap)
+}
+
 // isRoot returns true if the current node is the root node.
 func (node *InternalNode) isRoot() bool {
 	return node.page.GetPageNum() == ROOT_PN
@@ -241,8 +410,8 @@ func keyPos(index int64) int64 {
 }
 
 // pnPos returns the page offset to the internal node's ith child's pagenumber
-func pnPos(index int64) int64 {
-	return PNS_OFFSET + index*PN_SIZE
+func (node *InternalNode) pnPos(index int64) int64 {
+	return pnsOffset(node.page.GetPager().GetPageSize()) + index*PN_SIZE
 }
 
 // getKeyAt returns the key stored at the given index of the internal node.
@@ -265,7 +434,7 @@ func (node *InternalNode) updateKeyAt(index int64, newKey int64) {
 // getPNAt returns the pagenumber stored at the given index of the internal node.
 // Concurrency note: this InternalNode's page should at least be read-locked before calling.
 func (node *InternalNode) getPNAt(index int64) int64 {
-	startPos := pnPos(index)
+	startPos := node.pnPos(index)
 	pagenum, _ := binary.Varint(node.page.GetData()[startPos : startPos+PN_SIZE])
 	return pagenum
 }
@@ -275,7 +444,7 @@ func (node *InternalNode) updatePNAt(index int64, newPagenum int64) {
 	// Serialize the pagenum data
 	data := make([]byte, PN_SIZE)
 	binary.PutVarint(data, newPagenum)
-	startPos := pnPos(index)
+	startPos := node.pnPos(index)
 	node.page.Update(data, startPos, PN_SIZE)
 }
 
@@ -325,14 +494,24 @@ func (node *InternalNode) initChild(child Node) {
 	switch castedChild := child.(type) {
 	case *InternalNode:
 		castedChild.parent = node
+		castedChild.locked = true
 	case *LeafNode:
 		castedChild.parent = node
+		castedChild.locked = true
 	}
 }
 
 // canSplit returns whether this node has the capability to split in the next insert operation.
 func (node *InternalNode) canSplit() bool {
-	return node.numKeys == KEYS_PER_INTERNAL_NODE-1
+	return node.numKeys == node.maxKeys()-1
+}
+
+// canUnderflow returns whether this node has the capability to underflow in the next delete operation.
+func (node *InternalNode) canUnderflow(gap int64) bool {
+	if node.isRoot() {
+		return node.numKeys <= 1
+	}
+	return node.numKeys <= node.minKeys(gap)
 }
 
 // unlockParents unlocks all of this node's locked parents.
@@ -356,5 +535,15 @@ func (node *InternalNode) unlockParents() {
 // unlock unlocks this internal node.
 func (node *InternalNode) unlock() {
 	node.parent = nil
+	node.locked = false
 	node.page.WUnlock()
 }
+
+// unlockAll unlocks this node, if it is still locked, along with the parents it still holds.
+// [CONCURRENCY] Inserts and deletes defer it through onPanic, so a panic partway down leaves nothing locked.
+func (node *InternalNode) unlockAll() {
+	if node.locked {
+		node.unlockParents()
+		node.unlock()
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !btreedebug
+
+package btree
+
+// checkRootInvariant does nothing unless built with the btreedebug tag (see invariant_debug.go).
+func (index *BTreeIndex) checkRootInvariant(op string, key int64) {}
@@ -0,0 +1,73 @@
+//go:build btreedebug
+
+package btree
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+
+	"dinodb/pkg/pager"
+)
+
+// Called with a report naming the operation when a mutating operation leaves the B+Tree's root
+// anywhere but ROOT_PN, or leaves a page at ROOT_PN that isn't a valid root node. Logs the report by default.
+// Only exists when built with the btreedebug tag.
+var RootInvariantHandler = func(report string) {
+	log.Print(report)
+}
+
+// checkRootInvariant checks that the root is still a valid node at ROOT_PN after op on key,
+// reporting any violation to RootInvariantHandler.
+func (index *BTreeIndex) checkRootInvariant(op string, key int64) {
+	if violation := index.rootViolation(); violation != "" {
+		RootInvariantHandler(fmt.Sprintf("btree %s: %s(%d) broke the root invariant: %s",
+			index.GetName(), op, key, violation))
+	}
+}
+
+// rootViolation describes how the root breaks its invariant, or returns "" if it doesn't.
+func (index *BTreeIndex) rootViolation() string {
+	if index.rootPN != ROOT_PN {
+		return fmt.Sprintf("root is at page %d instead of page %d", index.rootPN, ROOT_PN)
+	}
+	rootPage, err := index.pager.GetPage(ROOT_PN)
+	if errors.Is(err, pager.ErrClosing) {
+		// The B+Tree was closed as the operation finished, so there's nothing left to check.
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("failed to get the root page: %v", err)
+	}
+	defer index.pager.PutPage(rootPage)
+	rootPage.RLock()
+	defer rootPage.RUnlock()
+	data := rootPage.GetData()
+	numKeys, n := binary.Varint(data[NUM_KEYS_OFFSET : NUM_KEYS_OFFSET+NUM_KEYS_SIZE])
+	if n <= 0 {
+		return "root's key count is not a valid varint"
+	}
+	pagesize := index.pager.GetPageSize()
+	switch data[NODETYPE_OFFSET] {
+	case 0:
+		// An internal root always has two children, since it is only made by splitting the old root.
+		if numKeys < 1 || numKeys > keysPerInternalNode(pagesize) {
+			return fmt.Sprintf("internal root has %d keys", numKeys)
+		}
+	case 1:
+		if numKeys < 0 || numKeys > entriesPerLeafNode(pagesize) {
+			return fmt.Sprintf("leaf root has %d entries", numKeys)
+		}
+		// A leaf root is the only leaf, so it has no siblings.
+		if sibling, _ := binary.Varint(data[RIGHT_SIBLING_PN_OFFSET : RIGHT_SIBLING_PN_OFFSET+RIGHT_SIBLING_PN_SIZE]); sibling != -1 {
+			return fmt.Sprintf("leaf root has right sibling %d", sibling)
+		}
+		if sibling, _ := binary.Varint(data[LEFT_SIBLING_PN_OFFSET : LEFT_SIBLING_PN_OFFSET+LEFT_SIBLING_PN_SIZE]); sibling != -1 {
+			return fmt.Sprintf("leaf root has left sibling %d", sibling)
+		}
+	default:
+		return fmt.Sprintf("root has node type %d", data[NODETYPE_OFFSET])
+	}
+	return ""
+}
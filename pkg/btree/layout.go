@@ -0,0 +1,80 @@
+package btree
+
+// PageRole describes what a page in a B+Tree's file is used for.
+type PageRole string
+
+const (
+	ROOT_PAGE     PageRole = "root"
+	INTERNAL_PAGE PageRole = "internal"
+	LEAF_PAGE     PageRole = "leaf"
+	FREE_PAGE     PageRole = "free"
+)
+
+// PageInfo summarizes a single page of a B+Tree's file.
+type PageInfo struct {
+	PN       int64    // The page number.
+	Role     PageRole // What the page is used for. Pages unreachable from the root are free.
+	NodeType NodeType // The type of node stored on the page, if it isn't free.
+	NumKeys  int64    // The number of keys in the node.
+	// For leaves with at least one key, the smallest and largest keys in the leaf.
+	MinKey, MaxKey int64
+	// For leaves, the page number of the right sibling, or -1 for the last leaf.
+	RightSiblingPN int64
+}
+
+// Layout classifies every page of the B+Tree's file in page number order, without reading the nodes' entries.
+// The index must not be modified while this runs.
+func (index *BTreeIndex) Layout() ([]PageInfo, error) {
+	reachable := make(map[int64]bool)
+	if err := index.markReachable(index.rootPN, reachable); err != nil {
+		return nil, err
+	}
+	layout := make([]PageInfo, 0, index.pager.GetNumPages())
+	for pn := int64(0); pn < index.pager.GetNumPages(); pn++ {
+		if !reachable[pn] {
+			layout = append(layout, PageInfo{PN: pn, Role: FREE_PAGE})
+			continue
+		}
+		page, err := index.pager.GetPage(pn)
+		if err != nil {
+			return nil, err
+		}
+		header := pageToNodeHeader(page)
+		info := PageInfo{PN: pn, Role: INTERNAL_PAGE, NodeType: header.nodeType, NumKeys: header.numKeys}
+		if header.nodeType == LEAF_NODE {
+			info.Role = LEAF_PAGE
+			leaf := pageToLeafNode(page)
+			if leaf.numKeys > 0 {
+				info.MinKey = leaf.getKeyAt(0)
+				info.MaxKey = leaf.getKeyAt(leaf.numKeys - 1)
+			}
+			info.RightSiblingPN = leaf.rightSiblingPN
+		}
+		if pn == index.rootPN {
+			info.Role = ROOT_PAGE
+		}
+		index.pager.PutPage(page)
+		layout = append(layout, info)
+	}
+	return layout, nil
+}
+
+// markReachable records the page numbers of the node on the given page and all of its descendants.
+func (index *BTreeIndex) markReachable(pn int64, reachable map[int64]bool) error {
+	reachable[pn] = true
+	page, err := index.pager.GetPage(pn)
+	if err != nil {
+		return err
+	}
+	defer index.pager.PutPage(page)
+	node, isInternal := pageToNode(page).(*InternalNode)
+	if !isInternal {
+		return nil
+	}
+	for i := int64(0); i <= node.numKeys; i++ {
+		if err := index.markReachable(node.getPNAt(i), reachable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
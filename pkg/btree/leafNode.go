@@ -16,7 +16,9 @@ import (
 type LeafNode struct {
 	NodeHeader           // Embeds all NodeHeader fields.
 	rightSiblingPN int64 // The page number of the right sibling node.
+	leftSiblingPN  int64 // The page number of the left sibling node.
 	parent         Node  // A pointer to the parent node (only used in CONCURRENCY for unlocking).
+	locked         bool  // Whether an insert or delete holds this node's lock (only used in CONCURRENCY for unlocking after a panic).
 }
 
 // insert finds the appropriate place in the leaf node to insert a new key-value pair.
@@ -25,14 +27,18 @@ type LeafNode struct {
 //
 // If the update flag is true, then insert will update the value of an existing key instead,
 // returning an error if an existing entry to overwrite is not found.
+// If the upsert flag is also true, a missing entry is inserted instead of returning an error.
+// Updating an entry increments its version. Unless expectedVersion is ANY_VERSION, an update
+// returns an entry.ErrVersionConflict instead if the entry's version isn't expectedVersion.
 // CONCURRENCY:
 // - Unlock parents if it is impossible to split
 // - The insert should fully complete at the leaf node, so make sure to unlock accordingly
-func (node *LeafNode) insert(key int64, value int64, update bool) (Split, error) {
+func (node *LeafNode) insert(key int64, value int64, update bool, upsert bool, expectedVersion int64) (Split, error) {
 	/* SOLUTION {{{ */
+	defer node.unlock()
+	defer onPanic(node.unlockParents)
 	// Get insert position.
 	insertPos := node.search(key)
-	defer node.unlock()
 	if(!node.canSplit()){
 		node.unlockParents()
 	}
@@ -40,27 +46,31 @@ func (node *LeafNode) insert(key int64, value int64, update bool) (Split, error)
 	if insertPos < node.numKeys && node.getKeyAt(insertPos) == key {
 		node.unlockParents()
 		if update {
-			node.updateValueAt(insertPos, value)
+			existing := node.getEntry(insertPos)
+			if expectedVersion != ANY_VERSION && existing.Version != expectedVersion {
+				return Split{}, fmt.Errorf("%w: key %d is at version %d, not %d",
+					entry.ErrVersionConflict, key, existing.Version, expectedVersion)
+			}
+			node.modifyEntry(insertPos, entry.Entry{Key: key, Value: value, Version: existing.Version + 1})
 			return Split{}, nil
 		} else {
 			return Split{}, errors.New("cannot insert duplicate key")
 		}
 	}
 	// Return an error if we're updating a non-existent entry.
-	if update {
+	if update && !upsert {
 		node.unlockParents()
 		return Split{}, errors.New("cannot update non-existent entry")
 	}
 	// Shift entries to the right if needed.
 	for i := node.numKeys - 1; i >= insertPos; i-- {
-		node.updateKeyAt(i+1, node.getKeyAt(i))
-		node.updateValueAt(i+1, node.getValueAt(i))
+		node.modifyEntry(i+1, node.getEntry(i))
 	}
 	node.updateNumKeys(node.numKeys + 1)
 	// Modify the Entry at this position.
 	node.modifyEntry(insertPos, entry.New(key, value))
 	// Check if we need to split the node.
-	if node.numKeys >= ENTRIES_PER_LEAF_NODE {
+	if node.numKeys >= node.maxEntries() {
 		return node.split()
 	}
 	return Split{}, nil
@@ -77,14 +87,22 @@ func (node *LeafNode) split() (Split, error) {
 		return Split{}, err
 	}
 	defer pager.PutPage(newNode.getPage())
-	// Set the right sibling for our two nodes.
+	// [CONCURRENCY] A cursor walking back from the old right sibling can reach the new node once it is relinked,
+	// so keep it locked until it is filled in.
+	newNode.page.WLock()
+	defer newNode.page.WUnlock()
+	// Point the old right sibling back at the new node before linking it in, so a failure leaves the node as it was.
+	if err := relinkLeftSibling(pager, node.rightSiblingPN, newNode.page.GetPageNum()); err != nil {
+		return Split{}, err
+	}
+	// Set the siblings for our two nodes.
 	prevSiblingPN := node.setRightSibling(newNode.page.GetPageNum())
 	newNode.setRightSibling(prevSiblingPN)
+	newNode.setLeftSibling(node.page.GetPageNum())
 	// Transfer entries to the new node (plus the new entry) accordingly.
 	midpoint := node.numKeys / 2
 	for i := midpoint; i < node.numKeys; i++ {
-		newNode.updateKeyAt(newNode.numKeys, node.getKeyAt(i))
-		newNode.updateValueAt(newNode.numKeys, node.getValueAt(i))
+		newNode.modifyEntry(newNode.numKeys, node.getEntry(i))
 		newNode.updateNumKeys(newNode.numKeys + 1)
 	}
 	node.updateNumKeys(midpoint)
@@ -98,52 +116,45 @@ func (node *LeafNode) split() (Split, error) {
 }
 
 // delete removes a given key-value pair from the leaf node, if the given key exists.
-func (node *LeafNode) delete(key int64) {
-	// [CONCURRENCY] Unlock parents, eventually unlock this node
-	node.unlockParents()
-	defer node.unlock()
+// Returns whether the leaf underflowed, i.e. it isn't the root and holds fewer than minEntries entries.
+// [CONCURRENCY] An underflowing leaf is left locked, along with its parents, for its parent to rebalance;
+// otherwise this node and its parents are unlocked.
+func (node *LeafNode) delete(key int64, gap int64) (underflow bool) {
+	defer onPanic(node.unlockAll)
+	// [CONCURRENCY] Unlock parents if it is impossible to underflow
+	if !node.canUnderflow(gap) {
+		node.unlockParents()
+	}
 	// Find index of the specified key
 	deletePos := node.search(key)
-	if deletePos >= node.numKeys || node.getKeyAt(deletePos) != key {
-		// Key was not found, so nothing to delete
-		return
+	if deletePos < node.numKeys && node.getKeyAt(deletePos) == key {
+		// Shift entries to the left, overwriting the key-value pair to be deleted
+		for i := deletePos; i < node.numKeys-1; i++ {
+			node.modifyEntry(i, node.getEntry(i+1))
+		}
+		node.updateNumKeys(node.numKeys - 1)
 	}
-	// Shift entries to the left, overwriting the key-value pair to be deleted
-	for i := deletePos; i < node.numKeys-1; i++ {
-		node.updateKeyAt(i, node.getKeyAt(i+1))
-		node.updateValueAt(i, node.getValueAt(i+1))
+	if !node.isRoot() && node.numKeys < node.minEntries(gap) {
+		return true
 	}
-	node.updateNumKeys(node.numKeys - 1)
-}
-
-// get returns a boolean indicating whether the specified key was found,
-// and if it was found, also returns the key's associated value.
-func (node *LeafNode) get(key int64) (value int64, found bool) {
-	// [CONCURRENCY] Unlock parents and eventually unlock this node
 	node.unlockParents()
-	defer node.unlock()
-	// Find index of key
-	index := node.search(key)
-	if index >= node.numKeys || node.getKeyAt(index) != key {
-		// Key was not found, so return false
-		return 0, false
-	}
-	entry := node.getEntry(index)
-	return entry.Value, true
+	node.unlock()
+	return false
 }
 
 /////////////////////////////////////////////////////////////////////////////
 ////////////////////////// Leaf Node  Helper Functions //////////////////////
 /////////////////////////////////////////////////////////////////////////////
 
-// search returns the first index where key >= given key.
+// search returns the first index where key >= given key, in the order of the B+Tree's comparator.
 // If no key satisfies this condition, returns numKeys.
 func (node *LeafNode) search(key int64) int64 {
+	compare := node.comparator()
 	// Binary search for the key.
 	minIndex := sort.Search(
 		int(node.numKeys),
 		func(idx int) bool {
-			return node.getKeyAt(int64(idx)) >= key
+			return compare(node.getKeyAt(int64(idx)), key) >= 0
 		},
 	)
 	return int64(minIndex)
@@ -182,10 +193,15 @@ func pageToLeafNode(page *pager.Page) *LeafNode {
 	rightSiblingPN, _ := binary.Varint(
 		page.GetData()[RIGHT_SIBLING_PN_OFFSET : RIGHT_SIBLING_PN_OFFSET+RIGHT_SIBLING_PN_SIZE],
 	)
+	leftSiblingPN, _ := binary.Varint(
+		page.GetData()[LEFT_SIBLING_PN_OFFSET : LEFT_SIBLING_PN_OFFSET+LEFT_SIBLING_PN_SIZE],
+	)
 	return &LeafNode{
 		nodeHeader,
 		rightSiblingPN,
+		leftSiblingPN,
 		nil,
+		false,
 	}
 }
 
@@ -214,9 +230,21 @@ func (node *LeafNode) getNodeType() NodeType {
 // copy copies the metadata and data of the passed in LeafNode to this LeafNode.
 // Concurrency note: the toCopy node's page must at least be read-locked before calling.
 func (node *LeafNode) copy(toCopy *LeafNode) {
-	node.page.Update(toCopy.page.GetData(), 0, pager.Pagesize)
+	node.page.Update(toCopy.page.GetData(), 0, node.page.GetPager().GetPageSize())
 	node.updateNumKeys(toCopy.numKeys)
 	node.setRightSibling(toCopy.rightSiblingPN)
+	node.setLeftSibling(toCopy.leftSiblingPN)
+}
+
+// maxEntries returns the maximum number of entries this leaf node can hold, based on its page size.
+func (node *LeafNode) maxEntries() int64 {
+	return entriesPerLeafNode(node.page.GetPager().GetPageSize())
+}
+
+// minEntries returns the fewest entries a leaf other than the root may hold before it is merged or refilled,
+// with a merge gap of gap percent.
+func (node *LeafNode) minEntries(gap int64) int64 {
+	return minKeysWithGap(node.maxEntries()/2, node.maxEntries(), gap)
 }
 
 // isRoot returns true if the current node is the root node.
@@ -241,11 +269,50 @@ func (node *LeafNode) setRightSibling(siblingPN int64) int64 {
 	return oldSiblingPN
 }
 
+// setLeftSibling sets the left sibling pagenumber field of the leaf node
+// and updates the leaf node's page accordingly.
+func (node *LeafNode) setLeftSibling(siblingPN int64) {
+	node.leftSiblingPN = siblingPN
+	siblingData := make([]byte, LEFT_SIBLING_PN_SIZE)
+	binary.PutVarint(siblingData, node.leftSiblingPN)
+	node.page.Update(
+		siblingData,
+		LEFT_SIBLING_PN_OFFSET,
+		LEFT_SIBLING_PN_SIZE,
+	)
+}
+
+// relinkLeftSibling points the left sibling pointer of the leaf on page pn, if there is one, at siblingPN.
+// [CONCURRENCY] Locks that leaf while updating it, so the leaf on page siblingPN, which is to its left,
+// must already be write-locked.
+func relinkLeftSibling(p *pager.Pager, pn int64, siblingPN int64) error {
+	if pn < 0 {
+		return nil
+	}
+	page, err := p.GetPage(pn)
+	if err != nil {
+		return err
+	}
+	defer p.PutPage(page)
+	page.WLock()
+	defer page.WUnlock()
+	pageToLeafNode(page).setLeftSibling(siblingPN)
+	return nil
+}
+
 // entryPos returns the page offset to the entry at the given index.
 func (node *LeafNode) entryPos(index int64) int64 {
 	return LEAF_NODE_HEADER_SIZE + index*ENTRYSIZE
 }
 
+// setEntries replaces the leaf's entries with the given ones, keeping its right sibling.
+func (node *LeafNode) setEntries(entries []entry.Entry) {
+	for i, e := range entries {
+		node.modifyEntry(int64(i), e)
+	}
+	node.updateNumKeys(int64(len(entries)))
+}
+
 // modifyEntry updates the data stored in the entry at the given index.
 func (node *LeafNode) modifyEntry(index int64, entry entry.Entry) {
 	newdata := entry.Marshal()
@@ -268,10 +335,11 @@ func (node *LeafNode) getKeyAt(index int64) int64 {
 	return node.getEntry(index).Key
 }
 
-// updateKeyAt updates the key at the given index of the leaf node.
+// updateKeyAt updates the key at the given index of the leaf node, keeping its value and version.
 func (node *LeafNode) updateKeyAt(index int64, newKey int64) {
-	existingVal := node.getValueAt(index)
-	node.modifyEntry(index, entry.New(newKey, existingVal))
+	e := node.getEntry(index)
+	e.Key = newKey
+	node.modifyEntry(index, e)
 }
 
 // getValueAt returns the value stored at the given index of the leaf node.
@@ -280,10 +348,11 @@ func (node *LeafNode) getValueAt(index int64) int64 {
 	return node.getEntry(index).Value
 }
 
-// updateValueAt updates the value at the given index of the leaf node.
+// updateValueAt updates the value at the given index of the leaf node, keeping its key and version.
 func (node *LeafNode) updateValueAt(index int64, newVal int64) {
-	existingKey := node.getKeyAt(index)
-	node.modifyEntry(index, entry.New(existingKey, newVal))
+	e := node.getEntry(index)
+	e.Value = newVal
+	node.modifyEntry(index, e)
 }
 
 // updateNumKeys updates the numKeys field in the node struct and the page.
@@ -301,7 +370,12 @@ func (node *LeafNode) updateNumKeys(newNumKeys int64) {
 
 // canSplit returns whether this node has the capability to split in the next insert operation.
 func (node *LeafNode) canSplit() bool {
-	return node.numKeys == ENTRIES_PER_LEAF_NODE-1
+	return node.numKeys == node.maxEntries()-1
+}
+
+// canUnderflow returns whether this node has the capability to underflow in the next delete operation.
+func (node *LeafNode) canUnderflow(gap int64) bool {
+	return !node.isRoot() && node.numKeys <= node.minEntries(gap)
 }
 
 // unlockParents unlocks all of this node's locked parents.
@@ -325,5 +399,15 @@ func (node *LeafNode) unlockParents() {
 // unlock unlocks this leaf node.
 func (node *LeafNode) unlock() {
 	node.parent = nil
+	node.locked = false
 	node.page.WUnlock()
 }
+
+// unlockAll unlocks this node, if it is still locked, along with the parents it still holds.
+// [CONCURRENCY] Deletes defer it through onPanic, so a panic partway down leaves nothing locked.
+func (node *LeafNode) unlockAll() {
+	if node.locked {
+		node.unlockParents()
+		node.unlock()
+	}
+}
@@ -0,0 +1,40 @@
+package btree
+
+import "fmt"
+
+// DEFAULT_MERGE_GAP is the default gap between the split and merge thresholds, as a percentage of a node's capacity.
+// At 25%, a node underflows below a quarter full, like a sparse hash bucket, and two nodes are only merged
+// if the result is under three quarters full, so a merged node can take a quarter of its capacity in inserts
+// before it splits again, and a split node a quarter of its capacity in deletes before it merges again.
+const DEFAULT_MERGE_GAP int64 = 25
+
+// SetMergeGap sets how far apart the split and merge thresholds of the B+Tree's nodes are, as a percentage
+// of a node's capacity from 0 to 50. A node underflows once it holds fewer keys than half its capacity less
+// the gap, and is then merged with a sibling if their keys fit in a node with the gap to spare, or else evened
+// out with it. With no gap, a node that was just merged can split again on the next insert, and vice versa.
+// The gap isn't recorded in the B+Tree's file, so a reopened B+Tree uses DEFAULT_MERGE_GAP again.
+// Nodes that are already underfull under the new gap are only fixed by the next delete from them.
+func (index *BTreeIndex) SetMergeGap(percent int64) error {
+	if percent < 0 || percent > 50 {
+		return fmt.Errorf("merge gap must be between 0 and 50 percent, not %d", percent)
+	}
+	index.mergeGap.Store(percent)
+	return nil
+}
+
+// GetMergeGap returns the B+Tree's merge gap, as a percentage of a node's capacity.
+func (index *BTreeIndex) GetMergeGap() int64 {
+	return index.mergeGap.Load()
+}
+
+// minKeysWithGap returns the fewest keys a node other than the root may hold before it is merged or refilled,
+// given that a split leaves it with half keys and that the merge gap is gap percent of its capacity.
+func minKeysWithGap(half int64, capacity int64, gap int64) int64 {
+	return max(half-capacity*gap/100, 1)
+}
+
+// mergedFits returns whether numKeys keys can be merged into a node that holds up to capacity keys,
+// leaving it the merge gap's worth of room before it would split.
+func mergedFits(numKeys int64, capacity int64, gap int64) bool {
+	return numKeys < capacity-capacity*gap/100
+}
@@ -0,0 +1,74 @@
+package btree
+
+import (
+	"dinodb/pkg/entry"
+	"dinodb/pkg/pager"
+)
+
+// Floor returns the entry with the largest key at most the given key, or false if every key is larger.
+// Keys are compared with the index's comparator, so in a descending index the floor is the smallest key at least the given key.
+func (index *BTreeIndex) Floor(key int64) (entry.Entry, bool, error) {
+	if err := index.beginOp(); err != nil {
+		return entry.Entry{}, false, err
+	}
+	defer index.endOp()
+	rootPage, err := index.pager.GetPage(index.rootPN)
+	if err != nil {
+		return entry.Entry{}, false, err
+	}
+	return index.floorIn(rootPage, key)
+}
+
+// floorIn returns the floor of key within the subtree rooted at the node on the given page, read locking the page
+// before reading the node and putting it once done. If the child that key falls in holds no key at most key, which only
+// happens when deletes have emptied its leaves, the children to its left are searched in turn.
+func (index *BTreeIndex) floorIn(page *pager.Page, key int64) (entry.Entry, bool, error) {
+	// [CONCURRENCY] The node is read while locked, since deletes can merge it or turn the root back into a leaf.
+	page.RLock()
+	defer index.pager.PutPage(page)
+	defer page.RUnlock()
+	switch node := pageToNode(page).(type) {
+	case *LeafNode:
+		pos := node.search(key)
+		if pos < node.numKeys && node.getKeyAt(pos) == key {
+			return node.getEntry(pos), true, nil
+		}
+		if pos > 0 {
+			return node.getEntry(pos - 1), true, nil
+		}
+	case *InternalNode:
+		for i := node.search(key); i >= 0; i-- {
+			childPage, err := index.pager.GetPage(node.getPNAt(i))
+			if err != nil {
+				return entry.Entry{}, false, err
+			}
+			e, found, err := index.floorIn(childPage, key)
+			if err != nil || found {
+				return e, found, err
+			}
+		}
+	}
+	return entry.Entry{}, false, nil
+}
+
+// Ceiling returns the entry with the smallest key at least the given key, or false if every key is smaller.
+// Like Floor, keys are compared with the index's comparator.
+func (index *BTreeIndex) Ceiling(key int64) (entry.Entry, bool, error) {
+	if err := index.beginOp(); err != nil {
+		return entry.Entry{}, false, err
+	}
+	defer index.endOp()
+	c, err := index.cursorAt(key)
+	if err != nil {
+		return entry.Entry{}, false, err
+	}
+	defer c.Close()
+	if !c.Valid() {
+		return entry.Entry{}, false, nil
+	}
+	e, err := c.GetEntry()
+	if err != nil {
+		return entry.Entry{}, false, err
+	}
+	return e, true, nil
+}
@@ -30,17 +30,17 @@ type Node interface {
 	//
 	// If the update flag is true, then insert will perform an update instead,
 	// returning an error if an existing entry to overwrite is not found.
-	insert(key int64, value int64, update bool) (Split, error)
+	// If the upsert flag is also true, a missing entry is inserted instead.
+	// Unless expectedVersion is ANY_VERSION, an update returns an entry.ErrVersionConflict
+	// if the existing entry's version isn't expectedVersion.
+	insert(key int64, value int64, update bool, upsert bool, expectedVersion int64) (Split, error)
 
 	// delete traverses down the B+Tree and removes the entry with the given key
-	// from the leaf nodes if it exists.
-	// Note that delete does not implement merging of node (see handout for more details).
-	delete(key int64)
-
-	// get tries to find the value associated with the given key in the B+Tree,
-	// traversing down to the leaf nodes. It returns a boolean indicating whether
-	// the key was found in the node and the associated value if found.
-	get(key int64) (value int64, found bool)
+	// from the leaf nodes if it exists, merging or refilling children that underflow
+	// under a merge gap of gap percent (see BTreeIndex.SetMergeGap).
+	// Returns whether the node itself underflowed, in which case it is left locked
+	// for its parent to rebalance with a sibling.
+	delete(key int64, gap int64) (underflow bool)
 
 	// Helper methods added for convenience
 	search(searchKey int64) int64
@@ -72,12 +72,18 @@ type NodeHeader struct {
 
 // initPage resets the page's data then sets the nodeType bit.
 func initPage(page *pager.Page, nodeType NodeType) {
-	newData := make([]byte, pager.Pagesize)
+	pagesize := page.GetPager().GetPageSize()
+	newData := make([]byte, pagesize)
 	// Set the nodeType bit for leaf nodes (don't need to set InternalNode bit since it is 0)
 	if nodeType == LEAF_NODE {
 		newData[NODETYPE_OFFSET] = 1
 	}
-	page.Update(newData, 0, pager.Pagesize)
+	page.Update(newData, 0, pagesize)
+}
+
+// comparator returns the order of the keys in the node's B+Tree.
+func (header NodeHeader) comparator() Comparator {
+	return comparatorOf(header.page.GetPager())
 }
 
 // pageToNode returns the node corresponding to the given page.
@@ -109,13 +115,26 @@ func pageToNodeHeader(page *pager.Page) NodeHeader {
 	}
 }
 
-// [CONCURRENCY] Sets the root node's parent pointer to the SUPER_NODE.
+// [CONCURRENCY] Sets the root node's parent pointer to the SUPER_NODE, and marks the root as locked.
 func initRootNode(root Node) {
 	switch castedRootNode := root.(type) {
 	case *InternalNode:
 		castedRootNode.parent = SUPER_NODE
+		castedRootNode.locked = true
 	case *LeafNode:
 		castedRootNode.parent = SUPER_NODE
+		castedRootNode.locked = true
+	}
+}
+
+// [CONCURRENCY]
+// onPanic calls release if the function deferring it is panicking, then carries on panicking.
+// Lock crabbing defers it to let go of the pages it holds, since a command's panic is recovered
+// (see repl.callCommand) and a page left locked would stall every later operation on the B+Tree.
+func onPanic(release func()) {
+	if p := recover(); p != nil {
+		release()
+		panic(p)
 	}
 }
 
@@ -0,0 +1,85 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+
+	"dinodb/pkg/cursor"
+)
+
+// LeafPartitions splits the B+Tree's leaves, in key order, into at most n runs of roughly equal length so that
+// they can be scanned in parallel. Each run is given by the page numbers of its first and last leaves, and is
+// scanned with CursorAtLeaves. Leaves are found through the internal nodes, so no leaf pages are read.
+// The B+Tree must not be modified between partitioning it and scanning the partitions.
+func (index *BTreeIndex) LeafPartitions(n int) ([][2]int64, error) {
+	if n <= 0 {
+		return nil, errors.New("number of partitions must be positive")
+	}
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
+	leafPNs := make([]int64, 0)
+	if err := index.collectLeafPNs(index.rootPN, index.height.Load(), &leafPNs); err != nil {
+		return nil, err
+	}
+	numParts := min(n, len(leafPNs))
+	partitions := make([][2]int64, numParts)
+	for i := range partitions {
+		first := i * len(leafPNs) / numParts
+		last := (i+1)*len(leafPNs)/numParts - 1
+		partitions[i] = [2]int64{leafPNs[first], leafPNs[last]}
+	}
+	return partitions, nil
+}
+
+// collectLeafPNs appends the page numbers of the leaves under the node on the given page, in key order.
+// The node is height levels above the bottom of the B+Tree, counting itself.
+func (index *BTreeIndex) collectLeafPNs(pn int64, height int64, leafPNs *[]int64) error {
+	if height <= 1 {
+		*leafPNs = append(*leafPNs, pn)
+		return nil
+	}
+	page, err := index.pager.GetPage(pn)
+	if err != nil {
+		return err
+	}
+	defer index.pager.PutPage(page)
+	page.RLock()
+	defer page.RUnlock()
+	node, ok := pageToNode(page).(*InternalNode)
+	if !ok {
+		return fmt.Errorf("expected page %d to hold an internal node", pn)
+	}
+	for i := int64(0); i <= node.numKeys; i++ {
+		if err := index.collectLeafPNs(node.getPNAt(i), height-1, leafPNs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CursorAtLeaves returns a cursor over the entries in the leaves from firstPN through lastPN, following
+// the leaves' right siblings, such as a partition from LeafPartitions. If those leaves are empty,
+// the returned cursor is not valid. Cursor's node should be locked upon leaving, and the page should not have been put
+func (index *BTreeIndex) CursorAtLeaves(firstPN int64, lastPN int64) (cursor.Cursor, error) {
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
+	page, err := index.pager.GetPage(firstPN)
+	if err != nil {
+		return nil, err
+	}
+	if pageToNodeHeader(page).nodeType != LEAF_NODE {
+		index.pager.PutPage(page)
+		return nil, fmt.Errorf("expected page %d to hold a leaf node", firstPN)
+	}
+	leaf := pageToLeafNode(page)
+	leaf.page.RLock()
+	cursor := &BTreeCursor{index: index, curIndex: 0, curNode: leaf, lastPN: lastPN}
+	if cursor.curNode.numKeys == 0 {
+		cursor.Next()
+	}
+	return cursor, nil
+}
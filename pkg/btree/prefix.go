@@ -0,0 +1,64 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+
+	"dinodb/pkg/entry"
+)
+
+// prefixRange returns the smallest and largest keys whose top prefixBits bits are prefix, i.e. the keys in
+// [prefix<<k, (prefix+1)<<k) for k = 64-prefixBits. The prefix is signed, like the keys, so it must be in
+// [-2^(prefixBits-1), 2^(prefixBits-1)).
+func prefixRange(prefix int64, prefixBits int64) (first int64, last int64, err error) {
+	if prefixBits < 1 || prefixBits > 64 {
+		return 0, 0, errors.New("prefix bits must be between 1 and 64")
+	}
+	if high := prefix >> (prefixBits - 1); high != 0 && high != -1 {
+		return 0, 0, fmt.Errorf("prefix %d does not fit in %d bits", prefix, prefixBits)
+	}
+	k := 64 - prefixBits
+	first = prefix << k
+	// The last key fills the k low bits, so it doesn't overflow for the largest prefix like (prefix+1)<<k would.
+	last = first | int64(uint64(1)<<k-1)
+	return first, last, nil
+}
+
+// SelectPrefix returns the entries whose keys' top prefixBits bits are prefix, ordered by their keys.
+// This is a range scan over [prefix<<k, (prefix+1)<<k) for k = 64-prefixBits; see SelectRange.
+// The scan assumes the index's comparator keeps that range's keys together, as ascending and descending order do.
+func (index *BTreeIndex) SelectPrefix(prefix int64, prefixBits int64) ([]entry.Entry, error) {
+	first, last, err := prefixRange(prefix, prefixBits)
+	if err != nil {
+		return nil, err
+	}
+	if index.compare(first, last) > 0 {
+		first, last = last, first
+	}
+	if err := index.beginOp(); err != nil {
+		return nil, err
+	}
+	defer index.endOp()
+	ret := make([]entry.Entry, 0)
+	c, err := index.cursorAt(first)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if !c.Valid() {
+		return ret, nil
+	}
+	for {
+		e, err := c.GetEntry()
+		if err != nil {
+			return nil, err
+		}
+		if index.compare(e.Key, last) > 0 {
+			return ret, nil
+		}
+		ret = append(ret, e)
+		if c.Next() {
+			return ret, nil
+		}
+	}
+}
@@ -2,14 +2,19 @@ package btree
 
 import (
 	"errors"
+	"fmt"
 )
 
+// ErrSiblingChainMismatch is returned when following leaves' sibling pointers doesn't visit the leaves in tree order.
+var ErrSiblingChainMismatch = errors.New("leaf sibling chain does not match tree order")
+
 func IsBTree(index *BTreeIndex) (l int64, r int64, isbtree bool, err error) {
 	// Get the node from the page
 	rootPage, err := index.pager.GetPage(index.rootPN)
 	if err != nil {
 		return 0, 0, false, err
 	}
+	defer index.pager.PutPage(rootPage)
 	n := pageToNode(rootPage)
 	return isBTree(n)
 }
@@ -18,6 +23,7 @@ func isBTree(n Node) (l int64, r int64, isbtree bool, err error) {
 	// Depending on the node type...
 	switch n := n.(type) {
 	case *InternalNode:
+		compare := n.comparator()
 		// Check that each key is less than the bounds of the node it goes around.
 		var lowest, highest int64
 		for i := int64(0); i < n.numKeys+1; i++ {
@@ -28,6 +34,7 @@ func isBTree(n Node) (l int64, r int64, isbtree bool, err error) {
 			}
 			// Check if child is BTree
 			cl, cr, cisbtree, err := isBTree(c)
+			n.page.GetPager().PutPage(c.getPage())
 			if err != nil {
 				return -1, -1, false, err
 			} else if !cisbtree {
@@ -43,13 +50,13 @@ func isBTree(n Node) (l int64, r int64, isbtree bool, err error) {
 			// If it is, check that the key bounds work out.
 			if i-1 >= 0 {
 				k := n.getKeyAt(i - 1)
-				if k > cl {
+				if compare(k, cl) > 0 {
 					return -1, -1, false, nil
 				}
 			}
 			if i < n.numKeys {
 				k := n.getKeyAt(i)
-				if k < cr {
+				if compare(k, cr) < 0 {
 					return -1, -1, false, nil
 				}
 			}
@@ -57,9 +64,13 @@ func isBTree(n Node) (l int64, r int64, isbtree bool, err error) {
 		// Return bounds.
 		return lowest, highest, true, nil
 	case *LeafNode:
+		// An empty leaf, e.g. the root of an empty tree, has no keys to bound it.
+		if n.numKeys == 0 {
+			return 0, 0, true, nil
+		}
 		// Check that each key is less than the one after it.
 		for i := int64(0); i < n.numKeys-1; i++ {
-			if n.getKeyAt(i) > n.getKeyAt(i+1) {
+			if n.comparator()(n.getKeyAt(i), n.getKeyAt(i+1)) > 0 {
 				return -1, -1, false, nil
 			}
 		}
@@ -69,3 +80,63 @@ func isBTree(n Node) (l int64, r int64, isbtree bool, err error) {
 		return -1, -1, false, errors.New("should not have gotten here")
 	}
 }
+
+// VerifyChains checks that following right sibling pointers from the leftmost leaf visits exactly
+// the leaves that the tree's internal nodes order from left to right, and that the chain ends after the last leaf.
+// It also checks that each leaf's left sibling pointer leads back to the leaf before it, or is -1 for the first leaf.
+// The index must not be modified while this runs.
+func VerifyChains(index *BTreeIndex) error {
+	leaves, err := index.leafPNs(index.rootPN)
+	if err != nil {
+		return err
+	}
+	pn := leaves[0]
+	for i, expected := range leaves {
+		if pn != expected {
+			return fmt.Errorf("%w: leaf %d should be page %d, but the chain reached page %d",
+				ErrSiblingChainMismatch, i, expected, pn)
+		}
+		page, err := index.pager.GetPage(pn)
+		if err != nil {
+			return err
+		}
+		leaf := pageToLeafNode(page)
+		index.pager.PutPage(page)
+		prevPN := int64(-1)
+		if i > 0 {
+			prevPN = leaves[i-1]
+		}
+		if leaf.leftSiblingPN != prevPN {
+			return fmt.Errorf("%w: leaf %d (page %d) points back to page %d instead of page %d",
+				ErrSiblingChainMismatch, i, expected, leaf.leftSiblingPN, prevPN)
+		}
+		pn = leaf.rightSiblingPN
+	}
+	if pn != -1 {
+		return fmt.Errorf("%w: the last leaf (page %d) points to page %d instead of ending the chain",
+			ErrSiblingChainMismatch, leaves[len(leaves)-1], pn)
+	}
+	return nil
+}
+
+// leafPNs returns the page numbers of the leaves under the given page, ordered from left to right.
+func (index *BTreeIndex) leafPNs(pn int64) ([]int64, error) {
+	page, err := index.pager.GetPage(pn)
+	if err != nil {
+		return nil, err
+	}
+	defer index.pager.PutPage(page)
+	node, isInternal := pageToNode(page).(*InternalNode)
+	if !isInternal {
+		return []int64{pn}, nil
+	}
+	leaves := make([]int64, 0)
+	for i := int64(0); i <= node.numKeys; i++ {
+		childLeaves, err := index.leafPNs(node.getPNAt(i))
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, childLeaves...)
+	}
+	return leaves, nil
+}
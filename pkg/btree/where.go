@@ -0,0 +1,53 @@
+package btree
+
+import (
+	"errors"
+
+	"dinodb/pkg/entry"
+)
+
+// ErrStopScan can be returned by a SelectRangeWhere callback to end the scan early without an error.
+var ErrStopScan = errors.New("stop scan")
+
+// SelectRangeWhere passes each entry with a key in [startKey, endKey), in the order of the index's comparator,
+// that pred accepts to fn as the scan reaches it, without collecting the entries. The scan stops at the first
+// error fn returns and returns it, or returns nil if the error is ErrStopScan, so leaves past that entry aren't read.
+// fn runs while the scan's leaf is read locked, so it must not modify the index.
+// Returns an error if startKey doesn't sort before endKey.
+func (index *BTreeIndex) SelectRangeWhere(startKey int64, endKey int64, pred func(entry.Entry) bool, fn func(entry.Entry) error) error {
+	if index.compare(startKey, endKey) >= 0 {
+		return errors.New("startKey is not smaller than endKey")
+	}
+	if err := index.beginOp(); err != nil {
+		return err
+	}
+	defer index.endOp()
+	c, err := index.cursorAt(startKey)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	// There are no entries at or after startKey
+	if !c.Valid() {
+		return nil
+	}
+	for {
+		e, err := c.GetEntry()
+		if err != nil {
+			return err
+		}
+		if index.compare(e.Key, endKey) >= 0 {
+			return nil
+		}
+		if pred(e) {
+			if err := fn(e); errors.Is(err, ErrStopScan) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if c.Next() {
+			return nil
+		}
+	}
+}
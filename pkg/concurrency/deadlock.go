@@ -45,6 +45,20 @@ func (g *WaitsForGraph) RemoveEdge(from *Transaction, to *Transaction) error {
 	return errors.New("edge not found")
 }
 
+// Remove all edges from the graph.
+func (g *WaitsForGraph) Clear() {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.edges = make([]Edge, 0)
+}
+
+// Return the number of edges in the graph.
+func (g *WaitsForGraph) NumEdges() int {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	return len(g.edges)
+}
+
 // Remove the element at index `i` from `list`.
 func removeHelper(list []Edge, i int) []Edge {
 	list[i] = list[len(list)-1]
@@ -8,6 +8,17 @@ const (
 	W_LOCK LockType = 1
 )
 
+// Indicates how long a transaction holds its read locks.
+type IsolationLevel int
+
+const (
+	// Read locks are held until the transaction commits (strict two-phase locking).
+	SERIALIZABLE IsolationLevel = 0
+	// Read locks are released as soon as the read finishes, so other transactions may change
+	// an entry between two reads of it. Write locks are still held until the transaction commits.
+	READ_COMMITTED IsolationLevel = 1
+)
+
 // A Resource refers to an entry in our database,
 // uniquely identified by tableName and key
 type Resource struct {
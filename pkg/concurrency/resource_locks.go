@@ -7,13 +7,20 @@ import (
 
 // ResourceLockManager handles the locking of database resources.
 type ResourceLockManager struct {
-	locks map[Resource]*sync.RWMutex
+	locks map[Resource]*resourceLock
 	mtx   sync.Mutex
 }
 
+// resourceLock is the mutex guarding a resource, counting the transactions holding or waiting on it
+// so that it can be dropped from the lock manager once none are left.
+type resourceLock struct {
+	sync.RWMutex
+	refs int
+}
+
 func NewResourceLockManager() *ResourceLockManager {
 	return &ResourceLockManager{
-		locks: make(map[Resource]*sync.RWMutex),
+		locks: make(map[Resource]*resourceLock),
 	}
 }
 
@@ -23,9 +30,10 @@ func (lm *ResourceLockManager) Lock(r Resource, lType LockType) error {
 	lm.mtx.Lock()
 	lock, found := lm.locks[r]
 	if !found {
-		lm.locks[r] = &sync.RWMutex{}
-		lock = lm.locks[r]
+		lock = &resourceLock{}
+		lm.locks[r] = lock
 	}
+	lock.refs++
 	lm.mtx.Unlock()
 	// Lock accordingly
 	switch lType {
@@ -41,11 +49,11 @@ func (lm *ResourceLockManager) Lock(r Resource, lType LockType) error {
 func (lm *ResourceLockManager) Unlock(r Resource, lType LockType) error {
 	// Safely acquire the mutex guarding the Resource
 	lm.mtx.Lock()
+	defer lm.mtx.Unlock()
 	lock, found := lm.locks[r]
 	if !found {
 		return errors.New("tried to unlock nonexistent resource")
 	}
-	lm.mtx.Unlock()
 	// Unlock accordingly
 	switch lType {
 	case R_LOCK:
@@ -53,5 +61,17 @@ func (lm *ResourceLockManager) Unlock(r Resource, lType LockType) error {
 	case W_LOCK:
 		lock.Unlock()
 	}
+	// Drop the mutex once no transaction holds or waits on it
+	lock.refs--
+	if lock.refs == 0 {
+		delete(lm.locks, r)
+	}
 	return nil
 }
+
+// Return the number of resources with a mutex, i.e. that some transaction holds or waits on.
+func (lm *ResourceLockManager) NumResources() int {
+	lm.mtx.Lock()
+	defer lm.mtx.Unlock()
+	return len(lm.locks)
+}
@@ -0,0 +1,27 @@
+package concurrency
+
+// TMState summarizes a TransactionManager's bookkeeping, so that tests can check
+// that it returns to where it started once a workload finishes, i.e. that nothing leaked.
+type TMState struct {
+	NumTransactions int // The number of running transactions.
+	NumHeldLocks    int // The number of locks held across all running transactions.
+	NumResources    int // The number of resources the lock manager has a mutex for.
+	NumEdges        int // The number of edges in the waits-for graph.
+}
+
+// StateSnapshot returns a summary of the transaction manager's running transactions, locks, and waits-for graph.
+// Each part is read under its own lock, so the snapshot is only consistent once the transaction manager is idle.
+func (tm *TransactionManager) StateSnapshot() TMState {
+	var state TMState
+	tm.mtx.RLock()
+	state.NumTransactions = len(tm.transactions)
+	for _, t := range tm.transactions {
+		t.RLock()
+		state.NumHeldLocks += len(t.lockedResources)
+		t.RUnlock()
+	}
+	tm.mtx.RUnlock()
+	state.NumResources = tm.resourceLockManager.NumResources()
+	state.NumEdges = tm.waitsForGraph.NumEdges()
+	return state
+}
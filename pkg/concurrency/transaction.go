@@ -10,7 +10,8 @@ import (
 // Therefore, the clientID is a unique identifier for both the Transaction and its Client
 type Transaction struct {
 	clientId        uuid.UUID
-	lockedResources map[Resource]LockType 	// tracks currently locked resources and LockType. Useful for error handling when Locking
+	lockedResources map[Resource]LockType // tracks currently locked resources and LockType. Useful for error handling when Locking
+	isolation       IsolationLevel        // how long read locks are held for
 	mtx             sync.RWMutex
 }
 
@@ -37,3 +38,7 @@ func (t *Transaction) GetClientID() (clientId uuid.UUID) {
 func (t *Transaction) GetResources() (resources map[Resource]LockType) {
 	return t.lockedResources
 }
+
+func (t *Transaction) GetIsolation() (level IsolationLevel) {
+	return t.isolation
+}
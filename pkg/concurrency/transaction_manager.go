@@ -9,6 +9,15 @@ import (
 	"github.com/google/uuid"
 )
 
+// Error for when a client's transaction was aborted by an admin. Returned on the client's next call.
+var ErrTransactionAborted = errors.New("transaction was aborted")
+
+// Error for when a table can't be changed as a whole because a running transaction holds locks on it.
+var ErrTableInUse = errors.New("table is in use by a transaction")
+
+// Error for when a client begins a transaction while already running one, and the manager doesn't resume transactions.
+var ErrTransactionAlreadyBegan = errors.New("transaction already began")
+
 // Transaction Manager manages all of the transactions on a server.
 // Every client runs 1 transaction at a time, so uuid (clientID) can be used to uniquely identify a Transaction.
 // Resources are like Entries that can be uniquely identified across tables
@@ -16,6 +25,9 @@ type TransactionManager struct {
 	resourceLockManager *ResourceLockManager       // Maps every resource to it's corresponding mutex
 	waitsForGraph       *WaitsForGraph             // Identifies deadlocks through cycle detection
 	transactions        map[uuid.UUID]*Transaction // Identifies the Transaction for a particular client
+	aborted             map[uuid.UUID]bool         // Clients whose transactions were aborted, but who haven't been told yet
+	resumable           bool                       // Whether beginning a client's running transaction resumes it instead of erroring
+	reservedTables      map[string]bool            // Tables being changed as a whole, which no transaction may lock
 	mtx                 sync.RWMutex
 }
 
@@ -24,6 +36,8 @@ func NewTransactionManager(lm *ResourceLockManager) *TransactionManager {
 		resourceLockManager: lm,
 		waitsForGraph:       NewGraph(),
 		transactions:        make(map[uuid.UUID]*Transaction),
+		aborted:             make(map[uuid.UUID]bool),
+		reservedTables:      make(map[string]bool),
 	}
 }
 
@@ -31,6 +45,10 @@ func (tm *TransactionManager) GetResourceLockManager() (lm *ResourceLockManager)
 	return tm.resourceLockManager
 }
 
+func (tm *TransactionManager) GetWaitsForGraph() (g *WaitsForGraph) {
+	return tm.waitsForGraph
+}
+
 func (tm *TransactionManager) GetTransactions() (txs map[uuid.UUID]*Transaction) {
 	return tm.transactions
 }
@@ -43,16 +61,35 @@ func (tm *TransactionManager) GetTransaction(clientId uuid.UUID) (tx *Transactio
 	return tx, found
 }
 
-// Begin a transaction for the given client; error if already began.
+// Set whether beginning a transaction for a client that is already running one resumes it,
+// e.g. for a client reconnecting with the same id, instead of returning ErrTransactionAlreadyBegan.
+func (tm *TransactionManager) SetResumable(resumable bool) {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+	tm.resumable = resumable
+}
+
+// Begin a transaction for the given client; see BeginOrResume for a client that already began one.
 func (tm *TransactionManager) Begin(clientId uuid.UUID) error {
+	_, _, err := tm.BeginOrResume(clientId)
+	return err
+}
+
+// Begin a transaction for the given client, returning it. If the client already began one, returns
+// ErrTransactionAlreadyBegan, or if the manager is resumable, returns the running transaction with resumed set.
+func (tm *TransactionManager) BeginOrResume(clientId uuid.UUID) (tx *Transaction, resumed bool, err error) {
 	tm.mtx.Lock()
 	defer tm.mtx.Unlock()
-	_, found := tm.transactions[clientId]
-	if found {
-		return errors.New("transaction already began")
+	if tx, found := tm.transactions[clientId]; found {
+		if !tm.resumable {
+			return nil, false, ErrTransactionAlreadyBegan
+		}
+		return tx, true, nil
 	}
-	tm.transactions[clientId] = &Transaction{clientId: clientId, lockedResources: make(map[Resource]LockType)}
-	return nil
+	delete(tm.aborted, clientId)
+	tx = &Transaction{clientId: clientId, lockedResources: make(map[Resource]LockType)}
+	tm.transactions[clientId] = tx
+	return tx, false, nil
 }
 
 // Locks the requested resource. Will return an error if deadlock is created by locking.
@@ -70,7 +107,7 @@ func (tm *TransactionManager) Lock(clientId uuid.UUID, table database.Index, res
 
 	transaction, status := tm.GetTransaction(clientId)
 	if !status {
-		return errors.New("no such transaction")
+		return tm.missingTransactionErr(clientId)
 	}
 	newResource := Resource{tableName: table.GetName(), key: resourceKey}
 	possibleConflicts := tm.conflictingTransactions(newResource, lType)
@@ -90,12 +127,25 @@ func (tm *TransactionManager) Lock(clientId uuid.UUID, table database.Index, res
 		defer tm.waitsForGraph.RemoveEdge(transaction, t)
 	}
 	if tm.waitsForGraph.DetectCycle() {
+		transaction.RUnlock()
 		return errors.New("tm.lock: deadlock detected")
 	}
 	transaction.RUnlock()
 	if err := tm.resourceLockManager.Lock(newResource, lType); err != nil {
 		return err
 	}
+	// If the transaction was aborted while waiting for the lock, give the lock back
+	tm.mtx.RLock()
+	defer tm.mtx.RUnlock()
+	if tm.transactions[clientId] != transaction {
+		tm.resourceLockManager.Unlock(newResource, lType)
+		return ErrTransactionAborted
+	}
+	// Likewise if the table is being changed as a whole
+	if tm.reservedTables[newResource.tableName] {
+		tm.resourceLockManager.Unlock(newResource, lType)
+		return ErrTableInUse
+	}
 	transaction.WLock()
 	defer transaction.WUnlock()
 	// Set the lock in transaction.lockedResources
@@ -111,7 +161,7 @@ func (tm *TransactionManager) Lock(clientId uuid.UUID, table database.Index, res
 func (tm *TransactionManager) Unlock(clientId uuid.UUID, table database.Index, resourceKey int64, lType LockType) error {
 	transaction, status := tm.GetTransaction(clientId)
 	if(!status) {
-		return errors.New("no such transaction")
+		return tm.missingTransactionErr(clientId)
 	}
 	transaction.WLock()
 	defer transaction.WUnlock()
@@ -128,6 +178,85 @@ func (tm *TransactionManager) Unlock(clientId uuid.UUID, table database.Index, r
 	return nil
 }
 
+// Releases the requested resource regardless of the type of lock held on it,
+// without committing the transaction.
+// 1) Get the transaction we want, and construct the resource.
+// 2) Remove resource from the transaction's currently locked resources if it is held.
+// 3) Unlock resource's mutex with the lock type it was recorded with
+func (tm *TransactionManager) ReleaseLock(clientId uuid.UUID, table database.Index, resourceKey int64) error {
+	transaction, found := tm.GetTransaction(clientId)
+	if !found {
+		return tm.missingTransactionErr(clientId)
+	}
+	transaction.WLock()
+	defer transaction.WUnlock()
+	resource := Resource{tableName: table.GetName(), key: resourceKey}
+	lType, held := transaction.lockedResources[resource]
+	if !held {
+		return errors.New("tm.release: resource is not locked by this transaction")
+	}
+	delete(transaction.lockedResources, resource)
+	return tm.resourceLockManager.Unlock(resource, lType)
+}
+
+// Releases all of the read locks held by the given transaction without committing it.
+// Write locks are kept until the transaction commits.
+func (tm *TransactionManager) ReleaseAllReadLocks(clientId uuid.UUID) error {
+	transaction, found := tm.GetTransaction(clientId)
+	if !found {
+		return tm.missingTransactionErr(clientId)
+	}
+	transaction.WLock()
+	defer transaction.WUnlock()
+	for r, lType := range transaction.lockedResources {
+		if lType != R_LOCK {
+			continue
+		}
+		delete(transaction.lockedResources, r)
+		if err := tm.resourceLockManager.Unlock(r, lType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sets the isolation level of the client's running transaction, which starts out SERIALIZABLE.
+// Read locks already held are kept regardless of the new level.
+func (tm *TransactionManager) SetIsolation(clientId uuid.UUID, level IsolationLevel) error {
+	if level != SERIALIZABLE && level != READ_COMMITTED {
+		return errors.New("tm.isolation: unknown isolation level")
+	}
+	transaction, found := tm.GetTransaction(clientId)
+	if !found {
+		return tm.missingTransactionErr(clientId)
+	}
+	transaction.WLock()
+	defer transaction.WUnlock()
+	transaction.isolation = level
+	return nil
+}
+
+// Called once a read of the given resource finishes. Under READ_COMMITTED, releases the
+// transaction's read lock on the resource; under SERIALIZABLE, or if the transaction
+// holds a write lock on the resource, does nothing.
+func (tm *TransactionManager) EndRead(clientId uuid.UUID, table database.Index, resourceKey int64) error {
+	transaction, found := tm.GetTransaction(clientId)
+	if !found {
+		return tm.missingTransactionErr(clientId)
+	}
+	transaction.WLock()
+	defer transaction.WUnlock()
+	if transaction.isolation != READ_COMMITTED {
+		return nil
+	}
+	resource := Resource{tableName: table.GetName(), key: resourceKey}
+	if lType, held := transaction.lockedResources[resource]; !held || lType != R_LOCK {
+		return nil
+	}
+	delete(transaction.lockedResources, resource)
+	return tm.resourceLockManager.Unlock(resource, R_LOCK)
+}
+
 // Commits the given transaction and removes it from the running transactions list.
 func (tm *TransactionManager) Commit(clientId uuid.UUID) error {
 	tm.mtx.Lock()
@@ -135,6 +264,10 @@ func (tm *TransactionManager) Commit(clientId uuid.UUID) error {
 	// Get the transaction we want.
 	t, found := tm.transactions[clientId]
 	if !found {
+		if tm.aborted[clientId] {
+			delete(tm.aborted, clientId)
+			return ErrTransactionAborted
+		}
 		return errors.New("no transactions running")
 	}
 	// Unlock all resources.
@@ -151,10 +284,112 @@ func (tm *TransactionManager) Commit(clientId uuid.UUID) error {
 	return nil
 }
 
+// Aborts the given transaction, releasing all of its locks and removing it from the running transactions list.
+// The client's next call will return ErrTransactionAborted.
+func (tm *TransactionManager) Abort(clientId uuid.UUID) error {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+	t, found := tm.transactions[clientId]
+	if !found {
+		return errors.New("no transactions running")
+	}
+	return tm.abort(t)
+}
+
+// Aborts every running transaction, releasing all locks and clearing the waits-for graph.
+// Safe to call while clients are mid-operation; each client's next call will return ErrTransactionAborted.
+func (tm *TransactionManager) AbortAll() (err error) {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+	for _, t := range tm.transactions {
+		if curErr := tm.abort(t); err == nil {
+			err = curErr
+		}
+	}
+	tm.waitsForGraph.Clear()
+	return err
+}
+
+// Releases all of the transaction's locks, removes it from the running transactions list,
+// and marks it as aborted. Expects tm.mtx to be locked.
+func (tm *TransactionManager) abort(t *Transaction) (err error) {
+	t.WLock()
+	defer t.WUnlock()
+	for r, lType := range t.lockedResources {
+		if curErr := tm.resourceLockManager.Unlock(r, lType); err == nil {
+			err = curErr
+		}
+	}
+	clear(t.lockedResources)
+	delete(tm.transactions, t.clientId)
+	tm.aborted[t.clientId] = true
+	return err
+}
+
+// Returns ErrTransactionAborted if the client's transaction was aborted and they haven't been told yet,
+// or a generic error otherwise.
+func (tm *TransactionManager) missingTransactionErr(clientId uuid.UUID) error {
+	tm.mtx.Lock()
+	defer tm.mtx.Unlock()
+	if tm.aborted[clientId] {
+		delete(tm.aborted, clientId)
+		return ErrTransactionAborted
+	}
+	return errors.New("no such transaction")
+}
+
+// Returns whether any running transaction holds a lock on a resource in the given table.
+func (tm *TransactionManager) TableInUse(table database.Index) bool {
+	tm.mtx.RLock()
+	defer tm.mtx.RUnlock()
+	return tm.tableInUse(table.GetName())
+}
+
+// Returns whether any running transaction holds a lock on a resource in the named table. Expects tm.mtx to be locked.
+func (tm *TransactionManager) tableInUse(tableName string) bool {
+	for _, t := range tm.transactions {
+		t.RLock()
+		for r := range t.lockedResources {
+			if r.tableName == tableName {
+				t.RUnlock()
+				return true
+			}
+		}
+		t.RUnlock()
+	}
+	return false
+}
+
+// WithTableReserved runs fn while no transaction can lock a resource in the given table, for changing the table
+// as a whole. Returns an ErrTableInUse without running fn if a running transaction already holds a lock on the table,
+// or if the table is already reserved. Transactions that try to lock a resource in the table meanwhile fail with an ErrTableInUse.
+func (tm *TransactionManager) WithTableReserved(table database.Index, fn func() error) error {
+	tableName := table.GetName()
+	tm.mtx.Lock()
+	if tm.reservedTables[tableName] || tm.tableInUse(tableName) {
+		tm.mtx.Unlock()
+		return ErrTableInUse
+	}
+	tm.reservedTables[tableName] = true
+	tm.mtx.Unlock()
+	defer func() {
+		tm.mtx.Lock()
+		delete(tm.reservedTables, tableName)
+		tm.mtx.Unlock()
+	}()
+	return fn()
+}
+
 // Returns a slice of all transactions that conflict w/ the given resource and locktype.
 func (tm *TransactionManager) conflictingTransactions(r Resource, lType LockType) []*Transaction {
 	txs := make([]*Transaction, 0)
+	tm.mtx.RLock()
+	running := make([]*Transaction, 0, len(tm.transactions))
 	for _, t := range tm.transactions {
+		running = append(running, t)
+	}
+	tm.mtx.RUnlock()
+	for _, t := range running {
 		t.RLock()
 		for storedResource, storedType := range t.lockedResources {
 			if storedResource == r && (storedType == W_LOCK || lType == W_LOCK) {
@@ -3,10 +3,10 @@ package concurrency
 import (
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
 	"dinodb/pkg/repl"
 
 	"github.com/google/uuid"
@@ -19,26 +19,36 @@ func TransactionREPL(db *database.Database, tm *TransactionManager) *repl.REPL {
 		return HandleCreateTable(db, tm, payload, replConfig.GetAddr())
 	}, "Create a table. usage: create table <table>")
 
-	r.AddCommand("find", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return HandleFind(db, tm, payload, replConfig.GetAddr())
+	r.AddResultCommand("find", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return FindResult(db, tm, database.WithDefaultTable(payload, replConfig), replConfig.GetAddr())
 	}, "Find an element. usage: find <key> from <table>")
 
 	r.AddCommand("insert", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return "", HandleInsert(db, tm, payload, replConfig.GetAddr())
-	}, "Insert an element. usage: insert <key> <value> into <table>")
+		return database.HandleInsertPairs(database.WithDefaultTable(payload, replConfig), func(payload string) error {
+			return HandleInsert(db, tm, payload, replConfig.GetAddr())
+		})
+	}, "Insert elements. usage: insert <key> <value> into <table> | insert (<key> <value>)... into <table>")
 
 	r.AddCommand("update", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return "", HandleUpdate(db, tm, payload, replConfig.GetAddr())
 	}, "Update en element. usage: update <table> <key> <value>")
 
 	r.AddCommand("delete", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return "", HandleDelete(db, tm, payload, replConfig.GetAddr())
+		return "", HandleDelete(db, tm, database.WithDefaultTable(payload, replConfig), replConfig.GetAddr())
 	}, "Delete an element. usage: delete <key> from <table>")
 
-	r.AddCommand("select", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return HandleSelect(db, tm, payload, replConfig.GetAddr())
+	r.AddCommand("rekey", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleRekey(db, tm, payload, replConfig.GetAddr())
+	}, "Change an element's key. usage: rekey <table> <old key> <new key>")
+
+	r.AddResultCommand("select", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return SelectResult(db, tm, database.WithDefaultTable(payload, replConfig), replConfig.GetAddr())
 	}, "Select elements from a table. usage: select from <table>")
 
+	r.AddCommand("use", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return database.HandleUse(db, payload, replConfig)
+	}, "Set the table that find, insert, delete, and select use when they aren't given one. usage: use <table>")
+
 	r.AddCommand("transaction", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return "", HandleTransaction(db, tm, payload, replConfig.GetAddr())
 	}, "Handle transactions. usage: transaction <begin|commit>")
@@ -47,10 +57,39 @@ func TransactionREPL(db *database.Database, tm *TransactionManager) *repl.REPL {
 		return "", HandleLock(db, tm, payload, replConfig.GetAddr())
 	}, "Grabs a write lock on a resource. usage: lock <table> <key>")
 
+	r.AddCommand("isolation", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleIsolation(db, tm, payload, replConfig.GetAddr())
+	}, "Set the current transaction's isolation level. usage: isolation <serializable|read_committed>")
+
 	r.AddCommand("pretty", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return HandlePretty(db, payload)
 	}, "Print out the internal data representation. usage: pretty")
 
+	r.AddCommand("convert", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleConvert(db, tm, payload)
+	}, "Convert a table to another index type. usage: convert <table> to <btree|hash>")
+
+	r.AddCommand("truncate", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleTruncate(db, tm, payload)
+	}, "Remove every element from a table, keeping the table. usage: truncate <table>")
+
+	// Abort the transaction of a client whose command panicked, so its locks don't block other clients.
+	r.AddPanicHandler(func(clientId uuid.UUID) {
+		tm.Abort(clientId)
+	})
+
+	// Trace the pages each command accesses for clients that turn tracing on.
+	r.SetTracer(db.Trace)
+
+	return r
+}
+
+// Admin REPL, with commands that affect every client's transactions.
+func AdminREPL(tm *TransactionManager) *repl.REPL {
+	r := repl.NewRepl()
+	r.AddCommand("killall", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleKillAll(tm, payload)
+	}, "Abort every running transaction. usage: killall")
 	return r
 }
 
@@ -79,29 +118,42 @@ func HandleCreateTable(db *database.Database, tm *TransactionManager, payload st
 
 // Handle find.
 func HandleFind(db *database.Database, tm *TransactionManager, payload string, clientId uuid.UUID) (output string, err error) {
+	result, err := FindResult(db, tm, payload, clientId)
+	if err != nil {
+		return "", err
+	}
+	return result.Format(repl.HUMAN_FORMAT)
+}
+
+// Handle find, returning the found entry as the result's only row.
+func FindResult(db *database.Database, tm *TransactionManager, payload string, clientId uuid.UUID) (result repl.Result, err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: find <key> from <table>
-	var key int
+	var key int64
 	var table database.Index
 	if numFields != 4 || fields[2] != "from" {
-		return "", fmt.Errorf("usage: find <key> from <table>")
+		return result, fmt.Errorf("usage: find <key> from <table>")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
-		return "", fmt.Errorf("find error: %v", err)
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
+		return result, fmt.Errorf("find error: %v", err)
 	}
 	if table, err = db.GetTable(fields[3]); err != nil {
-		return "", fmt.Errorf("find error: %v", err)
+		return result, fmt.Errorf("find error: %v", err)
 	}
 	// Get the transaction, run the find, release lock and rollback if error.
-	if err = tm.Lock(clientId, table, int64(key), R_LOCK); err != nil {
-		return "", fmt.Errorf("find error: %v", err)
+	if err = tm.Lock(clientId, table, key, R_LOCK); err != nil {
+		return result, fmt.Errorf("find error: %v", err)
+	}
+	result, err = database.FindResult(db, payload)
+	// Under read committed, the read lock is given back as soon as the read is done.
+	if unlockErr := tm.EndRead(clientId, table, key); err == nil {
+		err = unlockErr
 	}
-	output, err = database.HandleFind(db, payload)
 	if err != nil {
-		return "", fmt.Errorf("find error: %v", err)
+		return repl.Result{}, fmt.Errorf("find error: %v", err)
 	}
-	return
+	return result, nil
 }
 
 // Handle inserts.
@@ -109,23 +161,23 @@ func HandleInsert(db *database.Database, tm *TransactionManager, payload string,
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: insert <key> <value> into <table>
-	var key int
+	var key int64
 	var table database.Index
 	if numFields != 5 || fields[3] != "into" {
 		return fmt.Errorf("usage: insert <key> <value> into <table>")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
 	if table, err = db.GetTable(fields[4]); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
 	// Get the transaction, run the find, release lock and rollback if error.
-	if err = tm.Lock(clientId, table, int64(key), W_LOCK); err != nil {
+	if err = tm.Lock(clientId, table, key, W_LOCK); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
 	if err = database.HandleInsert(db, payload); err != nil {
-		return fmt.Errorf("insert error: %v", err)
+		return fmt.Errorf("insert error: %w", err)
 	}
 	return nil
 }
@@ -135,19 +187,19 @@ func HandleUpdate(db *database.Database, tm *TransactionManager, payload string,
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: update <table> <key> <value>
-	var key int
+	var key int64
 	var table database.Index
 	if numFields != 4 {
 		return fmt.Errorf("usage: update <table> <key> <value>")
 	}
-	if key, err = strconv.Atoi(fields[2]); err != nil {
+	if key, err = entry.ParseInt64(fields[2]); err != nil {
 		return fmt.Errorf("update error: %v", err)
 	}
 	if table, err = db.GetTable(fields[1]); err != nil {
 		return fmt.Errorf("update error: %v", err)
 	}
 	// Get the transaction, run the find, release lock and rollback if error.
-	if err = tm.Lock(clientId, table, int64(key), W_LOCK); err != nil {
+	if err = tm.Lock(clientId, table, key, W_LOCK); err != nil {
 		return fmt.Errorf("update error: %v", err)
 	}
 	if err = database.HandleUpdate(db, payload); err != nil {
@@ -161,19 +213,19 @@ func HandleDelete(db *database.Database, tm *TransactionManager, payload string,
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: delete <key> from <table>
-	var key int
+	var key int64
 	var table database.Index
 	if numFields != 4 || fields[2] != "from" {
 		return fmt.Errorf("usage: delete <key> from <table>")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
 		return fmt.Errorf("delete error: %v", err)
 	}
 	if table, err = db.GetTable(fields[3]); err != nil {
 		return fmt.Errorf("delete error: %v", err)
 	}
 	// Get the transaction, run the find, release lock and rollback if error.
-	if err = tm.Lock(clientId, table, int64(key), W_LOCK); err != nil {
+	if err = tm.Lock(clientId, table, key, W_LOCK); err != nil {
 		return fmt.Errorf("delete error: %v", err)
 	}
 	if err = database.HandleDelete(db, payload); err != nil {
@@ -182,19 +234,59 @@ func HandleDelete(db *database.Database, tm *TransactionManager, payload string,
 	return nil
 }
 
+// Handle rekey. Write locks both the old and new keys.
+func HandleRekey(db *database.Database, tm *TransactionManager, payload string, clientId uuid.UUID) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: rekey <table> <old key> <new key>
+	var oldKey, newKey int64
+	var table database.Index
+	if numFields != 4 {
+		return fmt.Errorf("usage: rekey <table> <old key> <new key>")
+	}
+	if oldKey, err = entry.ParseInt64(fields[2]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	if newKey, err = entry.ParseInt64(fields[3]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	if table, err = db.GetTable(fields[1]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	// Get the transaction, lock both keys, then run the rekey.
+	for _, key := range []int64{oldKey, newKey} {
+		if err = tm.Lock(clientId, table, key, W_LOCK); err != nil {
+			return fmt.Errorf("rekey error: %v", err)
+		}
+	}
+	if err = database.HandleRekey(db, payload); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	return nil
+}
+
 // Handle select.
 func HandleSelect(db *database.Database, tm *TransactionManager, payload string, clientId uuid.UUID) (output string, err error) {
+	result, err := SelectResult(db, tm, payload, clientId)
+	if err != nil {
+		return "", err
+	}
+	return result.Format(repl.HUMAN_FORMAT)
+}
+
+// Handle select, returning the selected entries as the result's rows.
+func SelectResult(db *database.Database, tm *TransactionManager, payload string, clientId uuid.UUID) (result repl.Result, err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: select from <table>
 	if numFields != 3 || fields[1] != "from" {
-		return "", fmt.Errorf("usage: select from <table>")
+		return result, fmt.Errorf("usage: select from <table>")
 	}
 	// NOTE: Select is unsafe; not locking anything. May provide an inconsistent view of the database.
-	if output, err = database.HandleSelect(db, payload); err != nil {
-		return "", fmt.Errorf("select error: %v", err)
+	if result, err = database.SelectResult(db, payload); err != nil {
+		return repl.Result{}, fmt.Errorf("select error: %v", err)
 	}
-	return
+	return result, nil
 }
 
 // Handle write lock requests.
@@ -202,7 +294,7 @@ func HandleLock(db *database.Database, tm *TransactionManager, payload string, c
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: lock <table> <key>
-	var key int
+	var key int64
 	var table database.Index
 	if numFields != 3 {
 		return fmt.Errorf("usage: lock <table> <key>")
@@ -210,16 +302,92 @@ func HandleLock(db *database.Database, tm *TransactionManager, payload string, c
 	if table, err = db.GetTable(fields[1]); err != nil {
 		return fmt.Errorf("lock error: %v", err)
 	}
-	if key, err = strconv.Atoi(fields[2]); err != nil {
+	if key, err = entry.ParseInt64(fields[2]); err != nil {
 		return fmt.Errorf("lock error: %v", err)
 	}
-	if err = tm.Lock(clientId, table, int64(key), W_LOCK); err != nil {
+	if err = tm.Lock(clientId, table, key, W_LOCK); err != nil {
 		return fmt.Errorf("lock error: %v", err)
 	}
 	return nil
 }
 
+// Handle isolation.
+func HandleIsolation(db *database.Database, tm *TransactionManager, payload string, clientId uuid.UUID) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: isolation <serializable|read_committed>
+	if numFields != 2 {
+		return errors.New("usage: isolation <serializable|read_committed>")
+	}
+	var level IsolationLevel
+	switch fields[1] {
+	case "serializable":
+		level = SERIALIZABLE
+	case "read_committed":
+		level = READ_COMMITTED
+	default:
+		return errors.New("usage: isolation <serializable|read_committed>")
+	}
+	if err = tm.SetIsolation(clientId, level); err != nil {
+		return fmt.Errorf("isolation error: %v", err)
+	}
+	return nil
+}
+
 // Handle pretty printing.
 func HandlePretty(db *database.Database, payload string) (output string, err error) {
 	return database.HandlePretty(db, payload)
 }
+
+// Handle aborting all transactions.
+func HandleKillAll(tm *TransactionManager, payload string) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: killall
+	if numFields != 1 {
+		return fmt.Errorf("usage: killall")
+	}
+	return tm.AbortAll()
+}
+
+// Handle convert. Rejected if any transaction holds a lock on the table, and no transaction can lock it until the conversion is done.
+func HandleConvert(db *database.Database, tm *TransactionManager, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	// Usage: convert <table> to <btree|hash>
+	if len(fields) < 2 {
+		return database.HandleConvert(db, payload)
+	}
+	table, err := db.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("convert error: %v", err)
+	}
+	err = tm.WithTableReserved(table, func() (err error) {
+		output, err = database.HandleConvert(db, payload)
+		return err
+	})
+	if errors.Is(err, ErrTableInUse) {
+		return "", fmt.Errorf("convert error: %w", err)
+	}
+	return output, err
+}
+
+// Handle truncate. Rejected if any transaction holds a lock on the table, and no transaction can lock it until the truncate is done.
+func HandleTruncate(db *database.Database, tm *TransactionManager, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	// Usage: truncate <table>
+	if len(fields) != 2 {
+		return database.HandleTruncate(db, payload)
+	}
+	table, err := db.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("truncate error: %v", err)
+	}
+	err = tm.WithTableReserved(table, func() (err error) {
+		output, err = database.HandleTruncate(db, payload)
+		return err
+	})
+	if errors.Is(err, ErrTableInUse) {
+		return "", fmt.Errorf("truncate error: %w", err)
+	}
+	return output, err
+}
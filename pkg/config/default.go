@@ -1,6 +1,11 @@
 // Global database config.
 package config
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 // Name of the database.
 const DBName = "dinodb"
 
@@ -13,6 +18,16 @@ const MaxPagesInBuffer = 32
 // Name of log file.
 const LogFileName = "db.log"
 
+// Names that tables can't use, since they clash (or would clash) with the database's internal files:
+// the log file's base name, the database's own name, and the catalog and recovery folders.
+// Compared case-insensitively, since some filesystems are case-insensitive.
+var ReservedTableNames = []string{
+	strings.TrimSuffix(LogFileName, filepath.Ext(LogFileName)),
+	DBName,
+	"catalog",
+	"recovery",
+}
+
 // Return prompt if requested, else "".
 func GetPrompt(flag bool) string {
 	if flag {
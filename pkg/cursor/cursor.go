@@ -9,4 +9,25 @@ type Cursor interface {
 	Next() bool                     //Moves the cursor to the next entry in the index
 	GetEntry() (entry.Entry, error) //Returns the entry at the position of the cursor
 	Close()                         //Called to indicate that the cursor is done being used
+	Valid() bool                    //Returns false if the cursor isn't pointing at an entry (e.g. the index is empty)
+}
+
+// ForEach calls fn on the entry the cursor points at and on each entry after it, until the cursor reaches the end.
+// Stops and returns the error if fn returns an error. The caller still has to close the cursor.
+func ForEach(c Cursor, fn func(entry.Entry) error) error {
+	if !c.Valid() {
+		return nil
+	}
+	for {
+		e, err := c.GetEntry()
+		if err != nil {
+			return err
+		}
+		if err = fn(e); err != nil {
+			return err
+		}
+		if c.Next() {
+			return nil
+		}
+	}
 }
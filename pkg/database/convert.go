@@ -0,0 +1,177 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+)
+
+// Number of entries copied at a time when converting a table.
+const convertChunkSize = 1024
+
+// Suffixes of the files a conversion stages next to a table's own files. Table names can't contain a dot,
+// so these never clash with another table's files.
+const (
+	convertSuffix       = ".convert"        // The new index's data file.
+	convertMetaSuffix   = ".convert.meta"   // The new index's .meta file, if it is a hash table.
+	convertCommitSuffix = ".convert.commit" // Written last to commit a conversion.
+)
+
+// Convert a table to the given index type, keeping its name and entries.
+// The entries are copied into a new index in temporary files. Once those are synced, a commit file is written,
+// and the temporary files then replace the table's files. A crash before the commit file is written leaves the
+// table as it was; a crash after it is written is rolled forward the next time the table is opened.
+// A B+Tree is bulk loaded, so every entry is held in memory while it is built.
+// The caller must make sure nothing else uses the table while it is converted.
+func (db *Database) ConvertTable(name string, newType IndexType) (err error) {
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	table, err := db.getTable(name)
+	if err != nil {
+		return err
+	}
+	oldType, err := GetIndexType(table)
+	if err != nil {
+		return err
+	}
+	if oldType == newType {
+		return fmt.Errorf("table is already a %s table", newType)
+	}
+	// Build the new index in temporary files, cleaning them up if anything fails before the commit.
+	path := filepath.Join(db.basepath, name)
+	removeStaged := func() {
+		os.Remove(path + convertSuffix)
+		os.Remove(path + convertMetaSuffix)
+		os.Remove(path + convertCommitSuffix)
+	}
+	removeStaged()
+	if err = stageConversion(table, path, newType); err != nil {
+		removeStaged()
+		return err
+	}
+	if err = table.Close(); err != nil {
+		removeStaged()
+		return err
+	}
+	delete(db.tables, name)
+	// The commit file is the commit point: from here on the conversion is finished, even after a crash.
+	if err = writeSynced(path+convertCommitSuffix, []byte(newType.String())); err != nil {
+		removeStaged()
+		if _, reopenErr := db.getTable(name); reopenErr != nil {
+			return errors.Join(err, reopenErr)
+		}
+		return err
+	}
+	if err = finishConversion(path); err != nil {
+		return err
+	}
+	newTable, err := db.getTable(name)
+	if err != nil {
+		return err
+	}
+	return newTable.GetPager().SetCachePolicy(table.GetPager().GetCachePolicy())
+}
+
+// stageConversion copies every entry of the table into a new index of the given type in the conversion's
+// temporary files, and syncs them to disk.
+func stageConversion(table Index, path string, newType IndexType) (err error) {
+	newTable, err := openIndex(path+convertSuffix, newType)
+	if err != nil {
+		return err
+	}
+	if bt, ok := newTable.(*btree.BTreeIndex); ok {
+		var entries []entry.Entry
+		err = table.SelectChunks(convertChunkSize, func(chunk []entry.Entry) error {
+			entries = append(entries, chunk...)
+			return nil
+		})
+		if err == nil {
+			sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+			err = bt.BulkLoad(entries)
+		}
+	} else {
+		err = table.SelectChunks(convertChunkSize, func(chunk []entry.Entry) error {
+			for _, e := range chunk {
+				if err := newTable.Insert(e.Key, e.Value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if closeErr := newTable.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = syncFile(path + convertSuffix); err != nil {
+		return err
+	}
+	if newType == HashIndexType {
+		return syncFile(path + convertMetaSuffix)
+	}
+	return nil
+}
+
+// finishConversion completes the conversion of the table stored at the given path if its commit file was written,
+// moving the new index's files into place, or otherwise removes whatever a conversion staged. Every step can be
+// repeated, so a crash partway through is finished the next time this runs.
+func finishConversion(path string) error {
+	newType, err := os.ReadFile(path + convertCommitSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		os.Remove(path + convertSuffix)
+		os.Remove(path + convertMetaSuffix)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// Swap in the .meta file first: the data file's rename is the last step, and shows the swap is done.
+	if string(newType) == HashIndexType.String() {
+		err = os.Rename(path+convertMetaSuffix, path+".meta")
+	} else {
+		err = os.Remove(path + ".meta")
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err = os.Rename(path+convertSuffix, path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Remove(path + convertCommitSuffix)
+}
+
+// writeSynced creates the file at the given path holding the given data, and syncs it to disk.
+func writeSynced(path string, data []byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// syncFile commits the file at the given path to stable storage.
+func syncFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = file.Sync()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
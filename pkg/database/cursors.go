@@ -0,0 +1,216 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/cursor"
+	"dinodb/pkg/entry"
+	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
+)
+
+// Error for when a client refers to a cursor it doesn't have open.
+var ErrCursorNotFound = errors.New("cursor not found")
+
+// Error for when a client opens a cursor under a name it already has open.
+var ErrCursorExists = errors.New("cursor already open")
+
+// A cursor a client has opened over a table.
+// A B+Tree cursor read locks the leaf it is on, so rather than being held between fetches,
+// it is reopened by each fetch just past the last key fetched.
+type namedCursor struct {
+	table   Index
+	cursor  cursor.Cursor // The open cursor, unless the table is a B+Tree.
+	lastKey int64         // The last key fetched from a B+Tree, once started is set.
+	started bool          // Whether an entry has been fetched from a B+Tree.
+	done    bool          // Whether every entry has been fetched.
+}
+
+// CursorSessions holds the named cursors each client has open.
+// Cursors don't hold any page between fetches, so a client can write to a table it has a cursor open on.
+// A B+Tree cursor then fetches the entries after the last one it fetched as they are at the time of the fetch.
+type CursorSessions struct {
+	mtx     sync.Mutex
+	cursors map[uuid.UUID]map[string]*namedCursor
+}
+
+// Construct an empty set of cursor sessions.
+func NewCursorSessions() *CursorSessions {
+	return &CursorSessions{cursors: make(map[uuid.UUID]map[string]*namedCursor)}
+}
+
+// Open a cursor named name at the start of the given table for the client.
+// Returns whether it is the client's only open cursor.
+func (s *CursorSessions) Open(clientId uuid.UUID, name string, table Index) (first bool, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	clientCursors, ok := s.cursors[clientId]
+	if _, exists := clientCursors[name]; exists {
+		return false, fmt.Errorf("%w: %s", ErrCursorExists, name)
+	}
+	nc := &namedCursor{table: table}
+	if _, isBTree := table.(*btree.BTreeIndex); !isBTree {
+		c, err := table.CursorAtStart()
+		if err != nil {
+			return false, err
+		}
+		nc.cursor, nc.done = c, !c.Valid()
+	}
+	if !ok {
+		clientCursors = make(map[string]*namedCursor)
+		s.cursors[clientId] = clientCursors
+	}
+	clientCursors[name] = nc
+	return !ok, nil
+}
+
+// Fetch the next n entries from the client's cursor named name.
+// Returns fewer than n entries once the cursor reaches the end of its table.
+func (s *CursorSessions) Fetch(clientId uuid.UUID, name string, n int) ([]entry.Entry, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	nc, ok := s.cursors[clientId][name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCursorNotFound, name)
+	}
+	entries := make([]entry.Entry, 0)
+	if nc.done {
+		return entries, nil
+	}
+	c, err := nc.resume()
+	if err != nil {
+		return nil, err
+	}
+	if c != nc.cursor {
+		defer c.Close()
+	}
+	for len(entries) < n && !nc.done {
+		e, err := c.GetEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+		nc.lastKey, nc.started = e.Key, true
+		nc.done = c.Next()
+	}
+	return entries, nil
+}
+
+// resume returns the cursor to fetch the next entries from. For a B+Tree, that is a new cursor
+// past the last key fetched, which the caller must close.
+func (nc *namedCursor) resume() (cursor.Cursor, error) {
+	bt, ok := nc.table.(*btree.BTreeIndex)
+	if !ok {
+		return nc.cursor, nil
+	}
+	if !nc.started {
+		c, err := bt.CursorAtStart()
+		if err != nil {
+			return nil, err
+		}
+		nc.done = !c.Valid()
+		return c, nil
+	}
+	c, err := bt.CursorAt(nc.lastKey)
+	if err != nil {
+		return nil, err
+	}
+	nc.done = !c.Valid()
+	// Step past the last key fetched, unless it has been deleted since.
+	if !nc.done {
+		e, err := c.GetEntry()
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		if e.Key == nc.lastKey {
+			nc.done = c.Next()
+		}
+	}
+	return c, nil
+}
+
+// Close the client's cursor named name.
+func (s *CursorSessions) Close(clientId uuid.UUID, name string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	nc, ok := s.cursors[clientId][name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCursorNotFound, name)
+	}
+	if nc.cursor != nil {
+		nc.cursor.Close()
+	}
+	delete(s.cursors[clientId], name)
+	if len(s.cursors[clientId]) == 0 {
+		delete(s.cursors, clientId)
+	}
+	return nil
+}
+
+// Close every cursor the client has open.
+func (s *CursorSessions) CloseAll(clientId uuid.UUID) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, nc := range s.cursors[clientId] {
+		if nc.cursor != nil {
+			nc.cursor.Close()
+		}
+	}
+	delete(s.cursors, clientId)
+}
+
+// Get the number of cursors the client has open.
+func (s *CursorSessions) NumOpen(clientId uuid.UUID) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.cursors[clientId])
+}
+
+// Handle cursor. Cursors opened through the REPL are closed when the client's session ends.
+func HandleCursor(d *Database, s *CursorSessions, payload string, replConfig *repl.REPLConfig) (result repl.Result, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	clientId := replConfig.GetAddr()
+	switch {
+	// Usage: cursor open <name> on <table>
+	case numFields == 5 && fields[1] == "open" && fields[3] == "on":
+		table, err := d.GetTable(fields[4])
+		if err != nil {
+			return repl.Result{}, fmt.Errorf("cursor error: %v", err)
+		}
+		first, err := s.Open(clientId, fields[2], table)
+		if err != nil {
+			return repl.Result{}, fmt.Errorf("cursor error: %w", err)
+		}
+		if first {
+			replConfig.OnClose(func() { s.CloseAll(clientId) })
+		}
+		return repl.Result{Message: fmt.Sprintf("cursor %s opened on %s", fields[2], fields[4])}, nil
+	// Usage: cursor fetch <name> <n>
+	case numFields == 4 && fields[1] == "fetch":
+		n, err := strconv.Atoi(fields[3])
+		if err != nil || n <= 0 {
+			return repl.Result{}, errors.New("cursor error: n must be a positive integer")
+		}
+		entries, err := s.Fetch(clientId, fields[2], n)
+		if err != nil {
+			return repl.Result{}, fmt.Errorf("cursor error: %w", err)
+		}
+		return repl.Result{Rows: entries}, nil
+	// Usage: cursor close <name>
+	case numFields == 3 && fields[1] == "close":
+		if err := s.Close(clientId, fields[2]); err != nil {
+			return repl.Result{}, fmt.Errorf("cursor error: %w", err)
+		}
+		return repl.Result{Message: fmt.Sprintf("cursor %s closed", fields[2])}, nil
+	default:
+		return repl.Result{}, errors.New("usage: cursor open <name> on <table> | cursor fetch <name> <n> | cursor close <name>")
+	}
+}
@@ -2,19 +2,56 @@ package database
 
 import (
 	"errors"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"dinodb/pkg/btree"
+	"dinodb/pkg/config"
+	"dinodb/pkg/entry"
 	"dinodb/pkg/hash"
+	"dinodb/pkg/pager"
 )
 
+// Error for when a table name is reserved for one of the database's internal files.
+var ErrReservedTableName = errors.New("table name is reserved")
+
+// Error for when an entry is inserted under a key that is already in the table.
+var ErrKeyExists = errors.New("key already in table")
+
+// Error for when a table's index structure is broken, e.g. a key is out of order or in the wrong bucket.
+var ErrInvalidIndex = errors.New("index structure is invalid")
+
+// Error for when an entry is updated or deleted under a key that isn't in the table.
+var ErrKeyNotFound = errors.New("key not in table")
+
+// KeyExistsError is an ErrKeyExists that carries the entry already in the table,
+// so that a client doesn't need another find to see what it conflicted with.
+type KeyExistsError struct {
+	Existing entry.Entry // The entry already under the key.
+}
+
+// Error names the key and the value already under it.
+func (e *KeyExistsError) Error() string {
+	return fmt.Sprintf("%v: %d has value %d", ErrKeyExists, e.Existing.Key, e.Existing.Value)
+}
+
+// Unwrap returns ErrKeyExists, so that errors.Is matches it.
+func (e *KeyExistsError) Unwrap() error {
+	return ErrKeyExists
+}
+
 // Database interface.
 type Database struct {
-	basepath string
-	tables   map[string]Index
+	basepath   string
+	tables     map[string]Index
+	bufferSize int64      // The buffer size of every table's pager, or 0 if it hasn't been changed from the default.
+	tablesMtx  sync.Mutex // Guards tables and bufferSize, and the checks for a table's files when creating or opening it.
+	traceMtx   sync.Mutex // Held while a command is traced, so that traces are taken one at a time.
 }
 
 // Opens a database given a data folder.
@@ -37,6 +74,8 @@ func Open(folder string) (*Database, error) {
 
 // Close each table in the database, then close the database.
 func (db *Database) Close() (err error) {
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
 	for _, table := range db.tables {
 		curErr := table.Close()
 		if err == nil {
@@ -46,6 +85,24 @@ func (db *Database) Close() (err error) {
 	return err
 }
 
+// Close each table in the database, force closing the pager of any table that
+// can't be closed cleanly (e.g. because it still has pinned pages). For use on shutdown paths.
+func (db *Database) ForceClose() (err error) {
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	for name, table := range db.tables {
+		curErr := table.Close()
+		if curErr != nil {
+			log.Printf("failed to close table %s, force closing: %v\n", name, curErr)
+			curErr = table.GetPager().ForceClose()
+		}
+		if err == nil {
+			err = curErr
+		}
+	}
+	return err
+}
+
 // Create a log file for the database.
 func (db *Database) CreateLogFile(filename string) error {
 	if _, err := os.Stat(filename); err == nil {
@@ -65,38 +122,140 @@ func (db *Database) CreateTable(name string, indexType IndexType) (index Index,
 	if alphanumeric.MatchString(name) {
 		return nil, errors.New("table name must be alphanumeric")
 	}
+	if isReservedTableName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrReservedTableName, name)
+	}
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
 	// Create the file, if not exists.
 	path := filepath.Join(db.basepath, name)
 	if _, err := os.Stat(path); err == nil {
 		return nil, errors.New("table already exists")
 	}
 	// Open the right type of index.
+	index, err = openIndex(path, indexType)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.addTable(name, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Add a newly opened table to the database, resizing its pager's buffer if the database's buffer size has been changed.
+func (db *Database) addTable(name string, index Index) error {
+	if db.bufferSize != 0 {
+		if err := index.GetPager().ResizeBuffer(db.bufferSize); err != nil {
+			index.Close()
+			return err
+		}
+	}
+	db.tables[name] = index
+	return nil
+}
+
+// Returns whether the given table name is reserved (see config.ReservedTableNames).
+func isReservedTableName(name string) bool {
+	for _, reserved := range config.ReservedTableNames {
+		if strings.EqualFold(name, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open an index of the given type, stored at the given path.
+func openIndex(path string, indexType IndexType) (index Index, err error) {
 	switch indexType {
 	case BTreeIndexType:
-		index, err = btree.OpenIndex(path)
-		if err != nil {
-			return nil, err
-		}
+		return btree.OpenIndex(path)
 	case HashIndexType:
-		index, err = hash.OpenTable(path)
-		if err != nil {
+		return hash.OpenTable(path)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownIndexType, indexType)
+	}
+}
+
+// Get the type of the given index.
+func GetIndexType(index Index) (IndexType, error) {
+	switch index.(type) {
+	case *btree.BTreeIndex:
+		return BTreeIndexType, nil
+	case *hash.HashIndex:
+		return HashIndexType, nil
+	default:
+		return "", errors.New("unknown index type")
+	}
+}
+
+// VerifyIndex checks the structure of the index: that a B+Tree's keys are ordered within and across its nodes,
+// or that each of a hash table's entries is in the bucket its key hashes to. Returns an ErrInvalidIndex if not.
+// The index must not be modified while this runs.
+func VerifyIndex(index Index) error {
+	var valid bool
+	var err error
+	switch index := index.(type) {
+	case *btree.BTreeIndex:
+		_, _, valid, err = btree.IsBTree(index)
+	case *hash.HashIndex:
+		valid, err = hash.IsHash(index)
+	default:
+		return errors.New("unknown index type")
+	}
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("%w: %s", ErrInvalidIndex, index.GetName())
+	}
+	return nil
+}
+
+// RepairHashTable rebuilds a hash table's directory from its bucket pages and writes it to a new .meta file,
+// for when the .meta file is lost or corrupted. The table is closed first if it is open.
+func (db *Database) RepairHashTable(name string) (table *hash.HashIndex, err error) {
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	path := filepath.Join(db.basepath, name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.New("table not found")
+	}
+	if open, ok := db.tables[name]; ok {
+		if err = open.Close(); err != nil {
 			return nil, err
 		}
-	default:
-		return nil, errors.New("invalid index type")
+		delete(db.tables, name)
 	}
-	db.tables[name] = index
-	return index, nil
+	table, err = hash.RepairTable(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.addTable(name, table); err != nil {
+		return nil, err
+	}
+	return table, nil
 }
 
 // Get a table by its name, either from existing tables, or by creating a new one.
 func (db *Database) GetTable(name string) (index Index, err error) {
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	return db.getTable(name)
+}
+
+// Get a table by its name, opening it from disk if it isn't open yet. The caller must hold tablesMtx.
+func (db *Database) getTable(name string) (index Index, err error) {
 	// Check existing set of tables.
 	if idx, ok := db.tables[name]; ok {
 		return idx, nil
 	}
-	// Check if file exists; if not, error.
+	// Finish or discard a conversion that was interrupted, so the table's files match.
 	path := filepath.Join(db.basepath, name)
+	if err := finishConversion(path); err != nil {
+		return nil, err
+	}
+	// Check if file exists; if not, error.
 	if _, err := os.Stat(path); err != nil {
 		return nil, errors.New("table not found")
 	}
@@ -114,13 +273,67 @@ func (db *Database) GetTable(name string) (index Index, err error) {
 			return nil, err
 		}
 	}
-	db.tables[name] = index
+	if err = db.addTable(name, index); err != nil {
+		return nil, err
+	}
 	return index, nil
 }
 
-// Get a database's tables.
+// Returns whether the table with the given name is open, without opening it from disk.
+func (db *Database) IsTableOpen(name string) bool {
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	_, ok := db.tables[name]
+	return ok
+}
+
+// Get the number of pages each table's pager can hold in its buffer.
+func (db *Database) GetBufferSize() int64 {
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	if db.bufferSize == 0 {
+		return config.MaxPagesInBuffer
+	}
+	return db.bufferSize
+}
+
+// Resize the buffer of every open table's pager to hold size pages, and use that size for tables opened later.
+// Tables that were resized before an error occurred keep their new size.
+func (db *Database) SetBufferSize(size int64) error {
+	if size <= 0 {
+		return errors.New("buffer size must be positive")
+	}
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	for name, table := range db.tables {
+		if err := table.GetPager().ResizeBuffer(size); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+	db.bufferSize = size
+	return nil
+}
+
+// Set the cache policy of the given table's pager. Policies aren't recorded on disk,
+// so a table uses pager.WRITE_BACK again once it is reopened.
+// Write-through only covers the table's pages: a hash table's directory is still written on close or checkpoint.
+func (db *Database) SetCachePolicy(name string, policy pager.CachePolicy) error {
+	table, err := db.GetTable(name)
+	if err != nil {
+		return err
+	}
+	return table.GetPager().SetCachePolicy(policy)
+}
+
+// Get a copy of a database's open tables.
 func (db *Database) GetTables() map[string]Index {
-	return db.tables
+	db.tablesMtx.Lock()
+	defer db.tablesMtx.Unlock()
+	tables := make(map[string]Index, len(db.tables))
+	for name, table := range db.tables {
+		tables[name] = table
+	}
+	return tables
 }
 
 // Returns the basepath of the database.
@@ -4,10 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math/rand"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"dinodb/pkg/btree"
 	"dinodb/pkg/entry"
+	"dinodb/pkg/hash"
+	"dinodb/pkg/pager"
 	"dinodb/pkg/repl"
 )
 
@@ -18,30 +26,165 @@ func DatabaseRepl(db *Database) *repl.REPL {
 		return HandleCreateTable(db, payload)
 	}, "Create a table. usage: create <btree|hash> table <table>")
 
-	r.AddCommand("find", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return HandleFind(db, payload)
+	r.AddResultCommand("find", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return FindResult(db, WithDefaultTable(payload, replConfig))
 	}, "Find an element. usage: find <key> from <table>")
 
+	r.AddResultCommand("floor", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return NearestResult(db, payload)
+	}, "Find the element with the largest key at most the given key. usage: floor <key> from <table>")
+
+	r.AddResultCommand("ceiling", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return NearestResult(db, payload)
+	}, "Find the element with the smallest key at least the given key. usage: ceiling <key> from <table>")
+
 	r.AddCommand("insert", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return "", HandleInsert(db, payload)
-	}, "Insert an element. usage: insert <key> <value> into <table>")
+		return HandleInsertPairs(WithDefaultTable(payload, replConfig), func(payload string) error {
+			return HandleInsert(db, payload)
+		})
+	}, "Insert elements. usage: insert <key> <value> into <table> | insert (<key> <value>)... into <table>")
+
+	r.AddResultCommand("update", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return UpdateResult(db, payload)
+	}, "Update en element. usage: update <table> <key> <value> [if exists]")
+
+	r.AddCommand("cas", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleCompareAndSwap(db, payload)
+	}, "Update an element if it is at the expected version. usage: cas <table> <key> <expected version> <value>")
 
-	r.AddCommand("update", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return "", HandleUpdate(db, payload)
-	}, "Update en element. usage: update <table> <key> <value>")
+	r.AddResultCommand("delete", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return DeleteResult(db, WithDefaultTable(payload, replConfig))
+	}, "Delete an element. usage: delete <key> from <table> [if exists]")
 
-	r.AddCommand("delete", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return "", HandleDelete(db, payload)
-	}, "Delete an element. usage: delete <key> from <table>")
+	r.AddCommand("rekey", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleRekey(db, payload)
+	}, "Change an element's key. usage: rekey <table> <old key> <new key>")
 
-	r.AddCommand("select", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return HandleSelect(db, payload)
-	}, "Select elements from a table. usage: select from <table>")
+	r.AddResultCommand("select", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return SelectResult(db, WithDefaultTable(payload, replConfig))
+	}, "Select elements from a table. usage: select [distinct value | sample <n> | histogram <bucketCount> | prefix <prefix> <bits> | <expression>, ...] from <table>"+
+		" | select range <start> <end> from <table> [where <expression> <comparison> <expression>] [limit <n>]")
+
+	r.AddCommand("use", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleUse(db, payload, replConfig)
+	}, "Set the table that find, insert, delete, and select use when they aren't given one. usage: use <table>")
+
+	cursors := NewCursorSessions()
+	r.AddResultCommand("cursor", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return HandleCursor(db, cursors, payload, replConfig)
+	}, "Page through a table with a named cursor. usage: cursor open <name> on <table> | cursor fetch <name> <n> | cursor close <name>")
 
 	r.AddCommand("pretty", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return HandlePretty(db, payload)
 	}, "Print out the internal data representation. usage: pretty")
 
+	r.AddCommand("digest", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleDigest(db, payload)
+	}, "Count and checksum a table's entries. usage: digest <table>")
+
+	r.AddCommand("count", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleCount(db, payload)
+	}, "Count the elements in a table. usage: count from <table>")
+
+	r.AddCommand("range", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleRangeCount(db, payload)
+	}, "Count the elements with keys in [start, end). usage: range count <start> <end> from <table>")
+
+	r.AddCommand("warmup", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleWarmup(db, payload)
+	}, "Load a table's pages into the buffer. usage: warmup <table>")
+
+	r.AddCommand("cache_policy", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleCachePolicy(db, payload)
+	}, "Get or set when a table's modified pages are written to disk. usage: cache_policy <table> [<write_back|write_through|write_through_sync>]")
+
+	r.AddCommand("convert", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleConvert(db, payload)
+	}, "Convert a table to another index type. usage: convert <table> to <btree|hash>")
+
+	r.AddCommand("truncate", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleTruncate(db, payload)
+	}, "Remove every element from a table, keeping the table. usage: truncate <table>")
+
+	r.AddCommand("export", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleExport(db, payload)
+	}, "Write a script of commands that recreates the database. usage: export <path>")
+
+	r.AddCommand("import", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleImport(db, payload)
+	}, "Insert the <key>,<value> rows of a CSV file into a table. usage: import <path> into <table> [on error skip|abort]")
+
+	r.AddCommand("layout", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleLayout(db, payload)
+	}, "Print the role of each page in a table's file. usage: layout <table>")
+
+	r.AddCommand("describe", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleDescribe(db, payload)
+	}, "Print a table's type, creation time, format version, and size. usage: describe <table>")
+
+	r.AddCommand("height", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleHeight(db, payload)
+	}, "Print a B+Tree's height, or a hash table's bucket depths. usage: height <table> [levels]")
+
+	r.AddCommand("explain", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleExplain(db, payload)
+	}, "Describe how a find would look up a key, without reading its value. usage: explain find <key> from <table>")
+
+	r.AddCommand("verify", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleVerify(db, payload)
+	}, "Check a B+Tree table's leaf sibling chain. usage: verify chains <table>")
+
+	r.AddCommand("repair", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleRepair(db, payload)
+	}, "Rebuild a hash table's lost or corrupted directory from its buckets. usage: repair hash <table>")
+
+	// Argument checks for ValidateScript, mostly by the number of fields in each command's usage.
+	r.AddValidator("create", repl.NumFields(4))
+	// Find, insert, delete, and select may leave out their table, using the one set by use.
+	r.AddValidator("find", repl.NumFields(2, 4))
+	r.AddValidator("insert", func(payload string) error {
+		if !slices.Contains(strings.Fields(payload), "into") {
+			payload += " into default"
+		}
+		_, _, _, err := ParseInsertPairs(payload)
+		return err
+	})
+	r.AddValidator("floor", repl.NumFields(4))
+	r.AddValidator("ceiling", repl.NumFields(4))
+	r.AddValidator("update", repl.NumFields(4, 6))
+	r.AddValidator("cas", repl.NumFields(5))
+	r.AddValidator("delete", repl.NumFields(2, 4, 6))
+	r.AddValidator("use", repl.NumFields(2))
+	r.AddValidator("rekey", repl.NumFields(4))
+	r.AddValidator("digest", repl.NumFields(2))
+	r.AddValidator("count", repl.NumFields(3))
+	r.AddValidator("range", repl.NumFields(6))
+	r.AddValidator("warmup", repl.NumFields(2))
+	r.AddValidator("cache_policy", repl.NumFields(2, 3))
+	r.AddValidator("convert", repl.NumFields(4))
+	r.AddValidator("truncate", repl.NumFields(2))
+	r.AddValidator("export", repl.NumFields(2))
+	r.AddValidator("import", repl.NumFields(4, 7))
+	r.AddValidator("layout", repl.NumFields(2))
+	r.AddValidator("describe", repl.NumFields(2))
+	r.AddValidator("height", repl.NumFields(2, 3))
+	r.AddValidator("explain", repl.NumFields(5))
+	r.AddValidator("verify", repl.NumFields(3))
+	r.AddValidator("repair", repl.NumFields(3))
+
+	// Trace the pages each command accesses for clients that turn tracing on.
+	r.SetTracer(db.Trace)
+
+	return r
+}
+
+// Admin REPL, with commands that affect every client's tables.
+func AdminREPL(db *Database) *repl.REPL {
+	r := repl.NewRepl()
+	r.AddCommand("buffer_size", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleBufferSize(db, payload)
+	}, "Get or set the number of pages each table's buffer holds. usage: buffer_size [<n>]")
+	r.AddValidator("buffer_size", repl.NumFields(1, 2))
 	return r
 }
 
@@ -50,17 +193,12 @@ func HandleCreateTable(d *Database, payload string) (output string, err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: create <type> table <table>
-	if numFields != 4 || fields[2] != "table" || (fields[1] != "btree" && fields[1] != "hash") {
+	if numFields != 4 || fields[2] != "table" {
 		return "", fmt.Errorf("usage: create <btree|hash> table <table>")
 	}
-	var tableType IndexType
-	switch fields[1] {
-	case "btree":
-		tableType = BTreeIndexType
-	case "hash":
-		tableType = HashIndexType
-	default:
-		return "", errors.New("create error: internal error")
+	tableType, err := ParseIndexType(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("create error: %w", err)
 	}
 	tableName := fields[3]
 	_, err = d.CreateTable(tableName, tableType)
@@ -68,32 +206,77 @@ func HandleCreateTable(d *Database, payload string) (output string, err error) {
 		return "", err
 	}
 
-	return fmt.Sprintf("%s table %s created.\n", fields[1], tableName), nil
+	return fmt.Sprintf("%s table %s created.\n", tableType, tableName), nil
 }
 
 // Handle find.
 func HandleFind(d *Database, payload string) (output string, err error) {
+	result, err := FindResult(d, payload)
+	if err != nil {
+		return "", err
+	}
+	return result.Format(repl.HUMAN_FORMAT)
+}
+
+// Handle find, returning the found entry as the result's only row.
+func FindResult(d *Database, payload string) (result repl.Result, err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: find <key> from <table>
-	var key int
+	var key int64
 	if numFields != 4 || fields[2] != "from" {
-		return "", fmt.Errorf("usage: find <key> from <table>")
+		return result, fmt.Errorf("usage: find <key> from <table>")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
-		return "", fmt.Errorf("find error: %v", err)
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
+		return result, fmt.Errorf("find error: %v", err)
 	}
 	tableName := fields[3]
 	table, err := d.GetTable(tableName)
 	if err != nil {
-		return "", fmt.Errorf("find error: %v", err)
+		return result, fmt.Errorf("find error: %v", err)
 	}
-	entry, err := table.Find(int64(key))
+	e, err := table.Find(key)
 	if err != nil {
-		return "", fmt.Errorf("find error: %v", err)
+		return result, fmt.Errorf("find error: %v", err)
 	}
 
-	return fmt.Sprintf("found entry: (%d, %d)\n", entry.Key, entry.Value), nil
+	result.Rows = []entry.Entry{e}
+	result.Message = fmt.Sprintf("found entry: (%d, %d)\n", e.Key, e.Value)
+	return result, nil
+}
+
+// Handle floor and ceiling, returning the nearest entry as the result's only row, or no rows if there isn't one.
+func NearestResult(d *Database, payload string) (result repl.Result, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: <floor|ceiling> <key> from <table>
+	var key int64
+	if numFields != 4 || (fields[0] != "floor" && fields[0] != "ceiling") || fields[2] != "from" {
+		return result, fmt.Errorf("usage: <floor|ceiling> <key> from <table>")
+	}
+	op := fields[0]
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
+		return result, fmt.Errorf("%s error: %v", op, err)
+	}
+	table, err := d.GetTable(fields[3])
+	if err != nil {
+		return result, fmt.Errorf("%s error: %v", op, err)
+	}
+	nearest, bound := table.Floor, "at most"
+	if op == "ceiling" {
+		nearest, bound = table.Ceiling, "at least"
+	}
+	e, found, err := nearest(key)
+	if err != nil {
+		return result, fmt.Errorf("%s error: %v", op, err)
+	}
+	if !found {
+		result.Message = fmt.Sprintf("no key %s %d\n", bound, key)
+		return result, nil
+	}
+	result.Rows = []entry.Entry{e}
+	result.Message = fmt.Sprintf("%s of %d: (%d, %d)\n", op, key, e.Key, e.Value)
+	return result, nil
 }
 
 // Handle insert.
@@ -101,14 +284,14 @@ func HandleInsert(d *Database, payload string) (err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: insert <key> <value> into <table>
-	var key, value int
+	var key, value int64
 	if numFields != 5 || fields[3] != "into" {
 		return fmt.Errorf("usage: insert <key> <value> into <table>")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
-	if value, err = strconv.Atoi(fields[2]); err != nil {
+	if value, err = entry.ParseInt64(fields[2]); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
 	tableName := fields[4]
@@ -116,88 +299,472 @@ func HandleInsert(d *Database, payload string) (err error) {
 	if err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
-	_, err = table.Find(int64(key))
+	existing, err := table.Find(key)
 	if err == nil {
-		return fmt.Errorf("insert error: key already in table")
+		return fmt.Errorf("insert error: %w", &KeyExistsError{existing})
 	}
-	err = table.Insert(int64(key), int64(value))
+	err = table.Insert(key, value)
 	if err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
 	return nil
 }
 
+// InsertOutcome is the outcome of inserting one pair of a multi-pair insert.
+type InsertOutcome struct {
+	Entry entry.Entry
+	Err   error // Why the pair wasn't inserted, or nil if it was.
+}
+
+// Handle an insert of one or more pairs, inserting each with insert.
+// A single pair, given as "insert <key> <value> into <table>", is passed to insert as is.
+// Each pair of "insert (<key> <value>)... into <table>" is passed to insert as a single pair insert, in order.
+// Pairs whose key is already in the table (ErrKeyExists) are reported as failed and skipped;
+// any other error stops the insert and is returned.
+func HandleInsertPairs(payload string, insert func(payload string) error) (output string, err error) {
+	pairs, tableName, multi, err := ParseInsertPairs(payload)
+	if err != nil {
+		return "", err
+	}
+	if !multi {
+		return "", insert(payload)
+	}
+	outcomes := make([]InsertOutcome, 0, len(pairs))
+	for _, pair := range pairs {
+		err := insert(fmt.Sprintf("insert %d %d into %s", pair.Key, pair.Value, tableName))
+		if err != nil && !errors.Is(err, ErrKeyExists) {
+			return "", err
+		}
+		outcomes = append(outcomes, InsertOutcome{pair, err})
+	}
+	return FormatInsertOutcomes(outcomes), nil
+}
+
+// ParseInsertPairs parses the pairs and table name out of an insert command. multi reports
+// whether the pairs were given in the parenthesized form, "insert (<key> <value>)... into <table>".
+func ParseInsertPairs(payload string) (pairs []entry.Entry, tableName string, multi bool, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	usage := fmt.Errorf("usage: insert <key> <value> into <table> | insert (<key> <value>)... into <table>")
+	if numFields < 4 || fields[numFields-2] != "into" {
+		return nil, "", false, usage
+	}
+	tableName = fields[numFields-1]
+	pairsText := strings.Join(fields[1:numFields-2], " ")
+	multi = strings.HasPrefix(pairsText, "(")
+	if !multi {
+		pairsText = "(" + pairsText + ")"
+	}
+	for pairsText != "" {
+		end := strings.Index(pairsText, ")")
+		if !strings.HasPrefix(pairsText, "(") || end < 0 {
+			return nil, "", false, usage
+		}
+		pair := strings.Fields(pairsText[1:end])
+		if len(pair) != 2 {
+			return nil, "", false, usage
+		}
+		key, err := entry.ParseInt64(pair[0])
+		if err != nil {
+			return nil, "", false, fmt.Errorf("insert error: %v", err)
+		}
+		value, err := entry.ParseInt64(pair[1])
+		if err != nil {
+			return nil, "", false, fmt.Errorf("insert error: %v", err)
+		}
+		pairs = append(pairs, entry.New(key, value))
+		pairsText = strings.TrimSpace(pairsText[end+1:])
+	}
+	return pairs, tableName, multi, nil
+}
+
+// FormatInsertOutcomes reports how many pairs of a multi-pair insert were inserted, followed by each pair that failed and why.
+func FormatInsertOutcomes(outcomes []InsertOutcome) string {
+	w := new(strings.Builder)
+	numInserted := 0
+	for _, outcome := range outcomes {
+		if outcome.Err == nil {
+			numInserted++
+		}
+	}
+	fmt.Fprintf(w, "inserted %d of %d entries\n", numInserted, len(outcomes))
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			fmt.Fprintf(w, "failed (%d, %d): %v\n", outcome.Entry.Key, outcome.Entry.Value, outcome.Err)
+		}
+	}
+	return w.String()
+}
+
 // Handle update.
 func HandleUpdate(d *Database, payload string) (err error) {
-	fields := strings.Fields(payload)
+	_, err = UpdateResult(d, payload)
+	return err
+}
+
+// Handle update, returning the number of entries updated as the result's affected count.
+// With "if exists", updating a key that isn't in the table updates nothing rather than failing.
+func UpdateResult(d *Database, payload string) (result repl.Result, err error) {
+	fields, ifExists := cutIfExists(strings.Fields(payload))
 	numFields := len(fields)
-	// Usage: update <table> <key> <value>
-	var key, value int
+	// Usage: update <table> <key> <value> [if exists]
+	var key, value int64
 	if numFields != 4 {
-		return fmt.Errorf("usage: update <table> <key> <value>")
+		return result, fmt.Errorf("usage: update <table> <key> <value> [if exists]")
 	}
-	if key, err = strconv.Atoi(fields[2]); err != nil {
-		return fmt.Errorf("update error: %v", err)
+	if key, err = entry.ParseInt64(fields[2]); err != nil {
+		return result, fmt.Errorf("update error: %v", err)
 	}
-	if value, err = strconv.Atoi(fields[3]); err != nil {
-		return fmt.Errorf("update error: %v", err)
+	if value, err = entry.ParseInt64(fields[3]); err != nil {
+		return result, fmt.Errorf("update error: %v", err)
 	}
 	tableName := fields[1]
 	table, err := d.GetTable(tableName)
 	if err != nil {
-		return fmt.Errorf("update error: %v", err)
+		return result, fmt.Errorf("update error: %v", err)
+	}
+	if _, err = table.Find(key); err != nil {
+		if ifExists {
+			return result, nil
+		}
+		return result, fmt.Errorf("update error: %w: %d", ErrKeyNotFound, key)
 	}
-	err = table.Update(int64(key), int64(value))
+	err = table.Update(key, value)
 	if err != nil {
-		return fmt.Errorf("update error: %v", err)
+		return result, fmt.Errorf("update error: %v", err)
 	}
-	return nil
+	result.Affected = 1
+	return result, nil
+}
+
+// Handle compare-and-swap.
+func HandleCompareAndSwap(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: cas <table> <key> <expected version> <value>
+	var key, expectedVersion, value int64
+	if numFields != 5 {
+		return "", fmt.Errorf("usage: cas <table> <key> <expected version> <value>")
+	}
+	if key, err = entry.ParseInt64(fields[2]); err != nil {
+		return "", fmt.Errorf("cas error: %v", err)
+	}
+	if expectedVersion, err = entry.ParseInt64(fields[3]); err != nil {
+		return "", fmt.Errorf("cas error: %v", err)
+	}
+	if value, err = entry.ParseInt64(fields[4]); err != nil {
+		return "", fmt.Errorf("cas error: %v", err)
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("cas error: %v", err)
+	}
+	newVersion, err := table.CompareAndSwap(key, expectedVersion, value)
+	if err != nil {
+		return "", fmt.Errorf("cas error: %w", err)
+	}
+	return fmt.Sprintf("key %d is now at version %d", key, newVersion), nil
 }
 
 // Handle delete.
 func HandleDelete(d *Database, payload string) (err error) {
-	fields := strings.Fields(payload)
+	_, err = DeleteResult(d, payload)
+	return err
+}
+
+// Handle delete, returning the number of entries deleted as the result's affected count.
+// With "if exists", deleting a key that isn't in the table deletes nothing rather than failing.
+func DeleteResult(d *Database, payload string) (result repl.Result, err error) {
+	fields, ifExists := cutIfExists(strings.Fields(payload))
 	numFields := len(fields)
-	// Usage: delete <key> from <table>
-	var key int
+	// Usage: delete <key> from <table> [if exists]
+	var key int64
 	if numFields != 4 || fields[2] != "from" {
-		return fmt.Errorf("usage: delete <key> from <table>")
+		return result, fmt.Errorf("usage: delete <key> from <table> [if exists]")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
-		return fmt.Errorf("delete error: %v", err)
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
+		return result, fmt.Errorf("delete error: %v", err)
 	}
 	tableName := fields[3]
 	table, err := d.GetTable(tableName)
 	if err != nil {
-		return fmt.Errorf("delete error: %v", err)
+		return result, fmt.Errorf("delete error: %v", err)
+	}
+	// B+Trees delete missing keys silently, so check for the key first.
+	if _, err = table.Find(key); err != nil {
+		if ifExists {
+			return result, nil
+		}
+		return result, fmt.Errorf("delete error: %w: %d", ErrKeyNotFound, key)
+	}
+	err = table.Delete(key)
+	if err != nil {
+		return result, fmt.Errorf("delete error: %v", err)
+	}
+	result.Affected = 1
+	return result, nil
+}
+
+// cutIfExists removes a trailing "if exists" from a command's fields, reporting whether it was there.
+func cutIfExists(fields []string) ([]string, bool) {
+	if n := len(fields); n >= 2 && fields[n-2] == "if" && fields[n-1] == "exists" {
+		return fields[:n-2], true
+	}
+	return fields, false
+}
+
+// Handle rekey.
+func HandleRekey(d *Database, payload string) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: rekey <table> <old key> <new key>
+	var oldKey, newKey int64
+	if numFields != 4 {
+		return fmt.Errorf("usage: rekey <table> <old key> <new key>")
+	}
+	if oldKey, err = entry.ParseInt64(fields[2]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	if newKey, err = entry.ParseInt64(fields[3]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return fmt.Errorf("rekey error: %v", err)
 	}
-	err = table.Delete(int64(key))
+	err = table.Rekey(oldKey, newKey)
 	if err != nil {
-		return fmt.Errorf("delete error: %v", err)
+		return fmt.Errorf("rekey error: %v", err)
 	}
 	return nil
 }
 
 // Handle select.
 func HandleSelect(d *Database, payload string) (output string, err error) {
+	result, err := SelectResult(d, payload)
+	if err != nil {
+		return "", err
+	}
+	return result.Format(repl.HUMAN_FORMAT)
+}
+
+// Handle select, returning the selected entries as the result's rows.
+// Rows of select distinct value pair each distinct value (as the key) with its count (as the value).
+func SelectResult(d *Database, payload string) (result repl.Result, err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
-	w := new(strings.Builder)
+	// Usage: select distinct value from <table>
+	if numFields == 5 && fields[1] == "distinct" && fields[2] == "value" && fields[3] == "from" {
+		return handleSelectDistinct(d, fields[4])
+	}
+	// Usage: select sample <n> from <table>
+	if numFields == 5 && fields[1] == "sample" && fields[3] == "from" {
+		return handleSelectSample(d, fields[2], fields[4])
+	}
+	// Usage: select histogram <bucketCount> from <table>
+	if numFields == 5 && fields[1] == "histogram" && fields[3] == "from" {
+		return handleSelectHistogram(d, fields[2], fields[4])
+	}
+	// Usage: select prefix <prefix> <bits> from <table>
+	if numFields == 6 && fields[1] == "prefix" && fields[4] == "from" {
+		return handleSelectPrefix(d, fields[2], fields[3], fields[5])
+	}
+	// Usage: select range <start> <end> from <table> [where <expression> <comparison> <expression>] [limit <n>]
+	if numFields >= 6 && fields[1] == "range" && fields[4] == "from" {
+		return handleSelectRange(d, fields)
+	}
+	// Usage: select <expression>, ... from <table>
+	if numFields > 3 && fields[numFields-2] == "from" {
+		return handleSelectProjection(d, payload, fields[numFields-1])
+	}
 	// Usage: select from <table>
 	if numFields != 3 || fields[1] != "from" {
-		return "", fmt.Errorf("usage: select from <table>")
+		return result, fmt.Errorf("usage: select [distinct value | sample <n> | histogram <bucketCount> | prefix <prefix> <bits> | <expression>, ...] from <table>" +
+			" | select range <start> <end> from <table> [where <expression> <comparison> <expression>] [limit <n>]")
 	}
 	tableName := fields[2]
 	table, err := d.GetTable(tableName)
 	if err != nil {
-		return "", fmt.Errorf("select error: %v", err)
+		return result, fmt.Errorf("select error: %v", err)
 	}
-	var results []entry.Entry
-	if results, err = table.Select(); err != nil {
-		return "", err
+	if result.Rows, err = table.Select(); err != nil {
+		return result, err
 	}
-	printResults(results, w)
-	return w.String(), nil
+	return result, nil
+}
+
+// Handle select distinct value.
+func handleSelectDistinct(d *Database, tableName string) (result repl.Result, err error) {
+	table, err := d.GetTable(tableName)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	distinct, err := SelectDistinctValues(table)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	result.Rows = make([]entry.Entry, 0, len(distinct))
+	for _, vc := range distinct {
+		result.Rows = append(result.Rows, entry.New(vc.Value, vc.Count))
+	}
+	return result, nil
+}
+
+// Handle select sample.
+func handleSelectSample(d *Database, n string, tableName string) (result repl.Result, err error) {
+	size, err := strconv.Atoi(n)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	table, err := d.GetTable(tableName)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	sample, err := SelectSample(table, size, rand.New(rand.NewSource(time.Now().UnixNano())))
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	result.Rows = sample
+	return result, nil
+}
+
+// Handle select histogram, with one (low, high, count) tuple per bucket.
+func handleSelectHistogram(d *Database, n string, tableName string) (result repl.Result, err error) {
+	bucketCount, err := strconv.Atoi(n)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	table, err := d.GetTable(tableName)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	buckets, err := SelectHistogram(table, bucketCount)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	result.Columns = []string{"low", "high", "count"}
+	result.Tuples = make([][]int64, 0, len(buckets))
+	for _, bucket := range buckets {
+		result.Tuples = append(result.Tuples, []int64{bucket.Low, bucket.High, bucket.Count})
+	}
+	return result, nil
+}
+
+// Handle select prefix, which only B+Tree tables support.
+func handleSelectPrefix(d *Database, prefixStr string, bitsStr string, tableName string) (result repl.Result, err error) {
+	prefix, err := entry.ParseInt64(prefixStr)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	bits, err := strconv.ParseInt(bitsStr, 10, 64)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	table, err := d.GetTable(tableName)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	btreeTable, ok := table.(*btree.BTreeIndex)
+	if !ok {
+		return result, fmt.Errorf("select error: only B+Tree tables support prefix scans")
+	}
+	if result.Rows, err = btreeTable.SelectPrefix(prefix, bits); err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	return result, nil
+}
+
+// Handle select range, which only B+Tree tables support. Matching entries are streamed from the scan,
+// which stops reading leaves once the limit is reached.
+func handleSelectRange(d *Database, fields []string) (result repl.Result, err error) {
+	startKey, err := entry.ParseInt64(fields[2])
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	endKey, err := entry.ParseInt64(fields[3])
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	rest := fields[6:]
+	limit := -1
+	if n := len(rest); n >= 2 && rest[n-2] == "limit" {
+		if limit, err = strconv.Atoi(rest[n-1]); err != nil || limit <= 0 {
+			return result, fmt.Errorf("select error: limit must be a positive integer, not %q", rest[n-1])
+		}
+		rest = rest[:n-2]
+	}
+	pred := func(entry.Entry) bool { return true }
+	// An error evaluating the predicate lets the entry through so that the callback can stop the scan with it.
+	var predErr error
+	if len(rest) > 0 {
+		if rest[0] != "where" || len(rest) == 1 {
+			return result, fmt.Errorf("usage: select range <start> <end> from <table> [where <expression> <comparison> <expression>] [limit <n>]")
+		}
+		predicate, err := ParsePredicate(strings.Join(rest[1:], " "))
+		if err != nil {
+			return result, fmt.Errorf("select error: %v", err)
+		}
+		pred = func(e entry.Entry) bool {
+			match, err := predicate.Match(e)
+			if err != nil {
+				predErr = err
+				return true
+			}
+			return match
+		}
+	}
+	table, err := d.GetTable(fields[5])
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	btreeTable, ok := table.(*btree.BTreeIndex)
+	if !ok {
+		return result, fmt.Errorf("select error: only B+Tree tables support range scans")
+	}
+	result.Rows = make([]entry.Entry, 0)
+	err = btreeTable.SelectRangeWhere(startKey, endKey, pred, func(e entry.Entry) error {
+		if predErr != nil {
+			return predErr
+		}
+		result.Rows = append(result.Rows, e)
+		if len(result.Rows) == limit {
+			return btree.ErrStopScan
+		}
+		return nil
+	})
+	if err != nil {
+		return repl.Result{}, fmt.Errorf("select error: %w", err)
+	}
+	return result, nil
+}
+
+// Handle select with a projection, such as "select key, value*2 from <table>".
+// Each expression becomes one column of the result's tuples.
+func handleSelectProjection(d *Database, payload string, tableName string) (result repl.Result, err error) {
+	text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(payload), "select"))
+	text = text[:strings.LastIndex(text, "from")]
+	projection, err := ParseProjection(text)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	table, err := d.GetTable(tableName)
+	if err != nil {
+		return result, fmt.Errorf("select error: %v", err)
+	}
+	result.Columns = projection.Columns()
+	result.Tuples = make([][]int64, 0)
+	err = ForEach(table, func(e entry.Entry) error {
+		tuple, err := projection.Apply(e)
+		if err != nil {
+			return err
+		}
+		result.Tuples = append(result.Tuples, tuple)
+		return nil
+	})
+	if err != nil {
+		return repl.Result{}, fmt.Errorf("select error: %w", err)
+	}
+	return result, nil
 }
 
 // Handle pretty printing.
@@ -230,10 +797,453 @@ func HandlePretty(d *Database, payload string) (output string, err error) {
 	return w.String(), nil
 }
 
-// printResults prints all given entries in a standard format.
-func printResults(entries []entry.Entry, w io.Writer) {
-	for _, entry := range entries {
-		io.WriteString(w, fmt.Sprintf("(%v, %v)\n",
-			entry.Key, entry.Value))
+// Handle digest.
+func HandleDigest(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: digest <table>
+	if numFields != 2 {
+		return "", fmt.Errorf("usage: digest <table>")
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("digest error: %v", err)
+	}
+	count, checksum, err := Digest(table)
+	if err != nil {
+		return "", fmt.Errorf("digest error: %v", err)
+	}
+	return fmt.Sprintf("count: %d, checksum: %016x\n", count, checksum), nil
+}
+
+// Handle count.
+func HandleCount(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: count from <table>
+	if numFields != 3 || fields[1] != "from" {
+		return "", fmt.Errorf("usage: count from <table>")
+	}
+	table, err := d.GetTable(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("count error: %v", err)
+	}
+	count, err := table.Count()
+	if err != nil {
+		return "", fmt.Errorf("count error: %v", err)
+	}
+	return fmt.Sprintf("%d\n", count), nil
+}
+
+// Handle range count.
+func HandleRangeCount(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: range count <start> <end> from <table>
+	if numFields != 6 || fields[1] != "count" || fields[4] != "from" {
+		return "", fmt.Errorf("usage: range count <start> <end> from <table>")
+	}
+	startKey, err := entry.ParseInt64(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("range count error: %v", err)
+	}
+	endKey, err := entry.ParseInt64(fields[3])
+	if err != nil {
+		return "", fmt.Errorf("range count error: %v", err)
+	}
+	table, err := d.GetTable(fields[5])
+	if err != nil {
+		return "", fmt.Errorf("range count error: %v", err)
+	}
+	count, err := table.RangeCount(startKey, endKey)
+	if err != nil {
+		return "", fmt.Errorf("range count error: %v", err)
+	}
+	return fmt.Sprintf("%d\n", count), nil
+}
+
+// Handle buffer size.
+func HandleBufferSize(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: buffer_size
+	if numFields == 1 {
+		return fmt.Sprintf("buffer size: %d pages\n", d.GetBufferSize()), nil
+	}
+	// Usage: buffer_size <n>
+	if numFields != 2 {
+		return "", fmt.Errorf("usage: buffer_size [<n>]")
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("buffer_size error: %v", err)
+	}
+	if err = d.SetBufferSize(size); err != nil {
+		return "", fmt.Errorf("buffer_size error: %v", err)
+	}
+	return "", nil
+}
+
+// Handle cache policy.
+func HandleCachePolicy(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: cache_policy <table> [<policy>]
+	if numFields != 2 && numFields != 3 {
+		return "", fmt.Errorf("usage: cache_policy <table> [<write_back|write_through|write_through_sync>]")
+	}
+	if numFields == 2 {
+		table, err := d.GetTable(fields[1])
+		if err != nil {
+			return "", fmt.Errorf("cache_policy error: %v", err)
+		}
+		return fmt.Sprintf("cache policy: %s\n", table.GetPager().GetCachePolicy()), nil
+	}
+	policy, err := pager.ParseCachePolicy(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("cache_policy error: %v", err)
+	}
+	if err = d.SetCachePolicy(fields[1], policy); err != nil {
+		return "", fmt.Errorf("cache_policy error: %v", err)
+	}
+	return "", nil
+}
+
+// Handle export.
+func HandleExport(d *Database, payload string) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: export <path>
+	if numFields != 2 {
+		return fmt.Errorf("usage: export <path>")
+	}
+	file, err := os.Create(fields[1])
+	if err != nil {
+		return fmt.Errorf("export error: %v", err)
+	}
+	err = d.Export(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("export error: %v", err)
+	}
+	return nil
+}
+
+// Handle import.
+func HandleImport(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: import <path> into <table> [on error skip|abort]
+	if (numFields != 4 && numFields != 7) || fields[2] != "into" ||
+		(numFields == 7 && (fields[4] != "on" || fields[5] != "error")) {
+		return "", fmt.Errorf("usage: import <path> into <table> [on error skip|abort]")
+	}
+	mode := IMPORT_ABORT
+	if numFields == 7 {
+		if mode, err = ParseImportMode(fields[6]); err != nil {
+			return "", fmt.Errorf("import error: %v", err)
+		}
+	}
+	table, err := d.GetTable(fields[3])
+	if err != nil {
+		return "", fmt.Errorf("import error: %v", err)
+	}
+	file, err := os.Open(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("import error: %v", err)
+	}
+	defer file.Close()
+	summary, err := ImportCSV(table, file, mode)
+	if err != nil {
+		return FormatImportSummary(summary), fmt.Errorf("import error: %v", err)
+	}
+	return FormatImportSummary(summary), nil
+}
+
+// Handle warmup.
+func HandleWarmup(d *Database, payload string) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: warmup <table>
+	if numFields != 2 {
+		return fmt.Errorf("usage: warmup <table>")
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return fmt.Errorf("warmup error: %v", err)
+	}
+	if err = table.Warmup(); err != nil {
+		return fmt.Errorf("warmup error: %v", err)
+	}
+	return nil
+}
+
+// Handle convert.
+func HandleConvert(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: convert <table> to <btree|hash>
+	if numFields != 4 || fields[2] != "to" {
+		return "", fmt.Errorf("usage: convert <table> to <btree|hash>")
+	}
+	newType, err := ParseIndexType(fields[3])
+	if err != nil {
+		return "", fmt.Errorf("convert error: %w", err)
+	}
+	tableName := fields[1]
+	if err = d.ConvertTable(tableName, newType); err != nil {
+		return "", fmt.Errorf("convert error: %v", err)
+	}
+	return fmt.Sprintf("table %s converted to %s.\n", tableName, newType), nil
+}
+
+// Handle truncate.
+func HandleTruncate(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: truncate <table>
+	if numFields != 2 {
+		return "", fmt.Errorf("usage: truncate <table>")
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("truncate error: %v", err)
+	}
+	if err = table.Truncate(); err != nil {
+		return "", fmt.Errorf("truncate error: %v", err)
+	}
+	return fmt.Sprintf("table %s truncated.\n", fields[1]), nil
+}
+
+// Handle verify.
+func HandleVerify(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: verify chains <table>
+	if numFields != 3 || fields[1] != "chains" {
+		return "", fmt.Errorf("usage: verify chains <table>")
+	}
+	table, err := d.GetTable(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("verify error: %v", err)
+	}
+	btreeTable, ok := table.(*btree.BTreeIndex)
+	if !ok {
+		return "", fmt.Errorf("verify error: only B+Tree tables have sibling chains")
+	}
+	if err = btree.VerifyChains(btreeTable); err != nil {
+		return "", fmt.Errorf("verify error: %w", err)
+	}
+	return "sibling chain ok\n", nil
+}
+
+// Handle repair.
+func HandleRepair(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: repair hash <table>
+	if numFields != 3 || fields[1] != "hash" {
+		return "", fmt.Errorf("usage: repair hash <table>")
+	}
+	table, err := d.RepairHashTable(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("repair error: %w", err)
+	}
+	hashTable := table.GetTable()
+	numBuckets := len(slices.Compact(slices.Sorted(slices.Values(hashTable.GetBuckets()))))
+	return fmt.Sprintf("rebuilt the directory of %s: global depth %d, %d buckets\n", fields[2], hashTable.GetDepth(), numBuckets), nil
+}
+
+// Handle explain.
+func HandleExplain(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: explain find <key> from <table>
+	if numFields != 5 || fields[1] != "find" || fields[3] != "from" {
+		return "", fmt.Errorf("usage: explain find <key> from <table>")
+	}
+	key, err := entry.ParseInt64(fields[2])
+	if err != nil {
+		return "", fmt.Errorf("explain error: %v", err)
+	}
+	table, err := d.GetTable(fields[4])
+	if err != nil {
+		return "", fmt.Errorf("explain error: %v", err)
+	}
+	switch table := table.(type) {
+	case *btree.BTreeIndex:
+		plan, err := table.ExplainFind(key)
+		if err != nil {
+			return "", fmt.Errorf("explain error: %v", err)
+		}
+		path := make([]string, len(plan.Path))
+		for i, pn := range plan.Path {
+			path[i] = strconv.FormatInt(pn, 10)
+		}
+		leafPN := plan.Path[len(plan.Path)-1]
+		return fmt.Sprintf("btree traversal: %d page accesses (pages %s)\n%s\n",
+			len(plan.Path), strings.Join(path, " -> "), explainLocation(plan.Found, "leaf", leafPN, plan.Slot)), nil
+	case *hash.HashIndex:
+		plan, err := table.ExplainFind(key)
+		if err != nil {
+			return "", fmt.Errorf("explain error: %v", err)
+		}
+		return fmt.Sprintf("hash bucket lookup: 1 page access (hash %d at global depth %d, bucket page %d at local depth %d)\n%s\n",
+			plan.Hash, plan.GlobalDepth, plan.BucketPN, plan.LocalDepth, explainLocation(plan.Found, "bucket", plan.BucketPN, plan.Slot)), nil
+	default:
+		return "", fmt.Errorf("explain error: unsupported index type")
+	}
+}
+
+// explainLocation describes where a find ends up.
+func explainLocation(found bool, container string, pn int64, slot int64) string {
+	if found {
+		return fmt.Sprintf("found at slot %d of %s %d", slot, container, pn)
+	}
+	return fmt.Sprintf("not found in %s %d", container, pn)
+}
+
+// Handle layout.
+func HandleLayout(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: layout <table>
+	if numFields != 2 {
+		return "", fmt.Errorf("usage: layout <table>")
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("layout error: %v", err)
+	}
+	w := new(strings.Builder)
+	switch table := table.(type) {
+	case *btree.BTreeIndex:
+		layout, err := table.Layout()
+		if err != nil {
+			return "", fmt.Errorf("layout error: %v", err)
+		}
+		for _, info := range layout {
+			printBTreePageInfo(info, w)
+		}
+	case *hash.HashIndex:
+		layout, err := table.Layout()
+		if err != nil {
+			return "", fmt.Errorf("layout error: %v", err)
+		}
+		for _, info := range layout {
+			printHashPageInfo(info, w)
+		}
+	default:
+		return "", fmt.Errorf("layout error: unsupported index type")
+	}
+	return w.String(), nil
+}
+
+// Handle describe.
+func HandleDescribe(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: describe <table>
+	if numFields != 2 {
+		return "", fmt.Errorf("usage: describe <table>")
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("describe error: %v", err)
+	}
+	metadata, err := table.Metadata()
+	if err != nil {
+		return "", fmt.Errorf("describe error: %v", err)
+	}
+	createdAt := "unknown"
+	if !metadata.CreatedAt.IsZero() {
+		createdAt = metadata.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	w := new(strings.Builder)
+	fmt.Fprintf(w, "table: %s\n", fields[1])
+	fmt.Fprintf(w, "type: %s\n", metadata.IndexType)
+	fmt.Fprintf(w, "created at: %s\n", createdAt)
+	fmt.Fprintf(w, "format version: %d\n", metadata.FormatVersion)
+	fmt.Fprintf(w, "entries: %d\n", metadata.NumEntries)
+	fmt.Fprintf(w, "pages: %d\n", metadata.NumPages)
+	return w.String(), nil
+}
+
+// Handle height.
+func HandleHeight(d *Database, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: height <table> [levels]
+	if numFields != 2 && (numFields != 3 || fields[2] != "levels") {
+		return "", fmt.Errorf("usage: height <table> [levels]")
+	}
+	table, err := d.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("height error: %v", err)
+	}
+	w := new(strings.Builder)
+	switch table := table.(type) {
+	case *btree.BTreeIndex:
+		levels, err := table.LevelStats()
+		if err != nil {
+			return "", fmt.Errorf("height error: %v", err)
+		}
+		fmt.Fprintf(w, "height: %d\n", len(levels))
+		if numFields == 3 {
+			for _, level := range levels {
+				fmt.Fprintf(w, "level %d: %d nodes, %d keys, %.1f%% full\n",
+					level.Level, level.NumNodes, level.NumKeys, 100*level.FillFactor)
+			}
+		}
+	case *hash.HashIndex:
+		globalDepth, counts, err := table.GetTable().DepthCounts()
+		if err != nil {
+			return "", fmt.Errorf("height error: %v", err)
+		}
+		fmt.Fprintf(w, "global depth: %d\n", globalDepth)
+		for _, depth := range slices.Sorted(maps.Keys(counts)) {
+			fmt.Fprintf(w, "local depth %d: %d buckets\n", depth, counts[depth])
+		}
+	default:
+		return "", fmt.Errorf("height error: unsupported index type")
+	}
+	return w.String(), nil
+}
+
+// printBTreePageInfo prints a line describing a B+Tree page.
+func printBTreePageInfo(info btree.PageInfo, w io.Writer) {
+	line := fmt.Sprintf("page %d: %s", info.PN, info.Role)
+	if info.Role != btree.FREE_PAGE {
+		if info.Role == btree.ROOT_PAGE {
+			if info.NodeType == btree.LEAF_NODE {
+				line += " (leaf)"
+			} else {
+				line += " (internal)"
+			}
+		}
+		line += fmt.Sprintf(", %d keys", info.NumKeys)
+		if info.NodeType == btree.LEAF_NODE {
+			if info.NumKeys > 0 {
+				line += fmt.Sprintf(" [%d, %d]", info.MinKey, info.MaxKey)
+			}
+			if info.RightSiblingPN >= 0 {
+				line += fmt.Sprintf(", right sibling %d", info.RightSiblingPN)
+			} else {
+				line += ", no right sibling"
+			}
+		}
+	}
+	io.WriteString(w, line+"\n")
+}
+
+// printHashPageInfo prints a line describing a hash table page.
+func printHashPageInfo(info hash.PageInfo, w io.Writer) {
+	line := fmt.Sprintf("page %d: %s", info.PN, info.Role)
+	if info.Role == hash.BUCKET_PAGE {
+		line += fmt.Sprintf(", local depth %d, %d keys, %d directory slots",
+			info.LocalDepth, info.NumKeys, info.NumSlots)
 	}
+	io.WriteString(w, line+"\n")
 }
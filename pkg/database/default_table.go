@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"dinodb/pkg/repl"
+)
+
+// Handle use, setting the client's default table for find, insert, delete, and select.
+func HandleUse(d *Database, payload string, replConfig *repl.REPLConfig) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: use <table>
+	if numFields != 2 {
+		return "", fmt.Errorf("usage: use <table>")
+	}
+	if _, err = d.GetTable(fields[1]); err != nil {
+		return "", fmt.Errorf("use error: %v", err)
+	}
+	replConfig.SetDefaultTable(fields[1])
+	return "", nil
+}
+
+// WithDefaultTable adds the client's default table to a find, insert, delete, or select payload that isn't given
+// a table, e.g. turning "find 5" into "find 5 from <table>". Other payloads are returned unchanged,
+// as are all payloads if the client has no default table.
+func WithDefaultTable(payload string, replConfig *repl.REPLConfig) string {
+	table := replConfig.GetDefaultTable()
+	fields := strings.Fields(payload)
+	if table == "" || len(fields) == 0 {
+		return payload
+	}
+	switch fields[0] {
+	case "find", "delete":
+		if len(fields) >= 2 && !slices.Contains(fields, "from") {
+			// The table goes straight after the key, ahead of a delete's "if exists".
+			return strings.Join(slices.Insert(fields, 2, "from", table), " ")
+		}
+	case "insert":
+		if !slices.Contains(fields, "into") {
+			return payload + " into " + table
+		}
+	case "select":
+		if !slices.Contains(fields, "from") {
+			// A range select's table goes ahead of its where and limit clauses.
+			if len(fields) > 1 && fields[1] == "range" {
+				if i := slices.IndexFunc(fields, func(f string) bool { return f == "where" || f == "limit" }); i >= 0 {
+					return strings.Join(slices.Insert(fields, i, "from", table), " ")
+				}
+			}
+			return payload + " from " + table
+		}
+	}
+	return payload
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"sort"
+
+	"dinodb/pkg/entry"
+)
+
+// ValueCount is a distinct value in a table and the number of entries that have it.
+type ValueCount struct {
+	Value int64
+	Count int64
+}
+
+// SelectDistinctValues returns each distinct value in the index once along with
+// its number of occurrences, sorted by value. Entries are streamed rather than
+// materialized, so memory use is proportional to the number of distinct values.
+func SelectDistinctValues(index Index) ([]ValueCount, error) {
+	counts := make(map[int64]int64)
+	err := ForEach(index, func(e entry.Entry) error {
+		counts[e.Value]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	distinct := make([]ValueCount, 0, len(counts))
+	for value, count := range counts {
+		distinct = append(distinct, ValueCount{Value: value, Count: count})
+	}
+	sort.Slice(distinct, func(i, j int) bool {
+		return distinct[i].Value < distinct[j].Value
+	})
+	return distinct, nil
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"dinodb/pkg/entry"
+)
+
+// Number of entries read at a time when exporting a table.
+const exportChunkSize = 1024
+
+// ListTables returns the names of every table in the database, sorted, whether or not they have been opened yet.
+func (db *Database) ListTables() ([]string, error) {
+	files, err := os.ReadDir(db.basepath)
+	if err != nil {
+		return nil, err
+	}
+	// Table files are named after their table; other files (the log, .meta files) have an extension.
+	nonAlphanumeric := regexp.MustCompile(`\W`)
+	seen := make(map[string]bool)
+	for name := range db.GetTables() {
+		seen[name] = true
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.Type().IsRegular() && !nonAlphanumeric.MatchString(name) && !isReservedTableName(name) {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Export writes a script of REPL commands that recreates the database: a create command for each table,
+// followed by an insert command for each of the table's entries. Tables are written in name order.
+func (db *Database) Export(w io.Writer) error {
+	names, err := db.ListTables()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		table, err := db.GetTable(name)
+		if err != nil {
+			return err
+		}
+		indexType, err := GetIndexType(table)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "create %s table %s\n", indexType, name); err != nil {
+			return err
+		}
+		err = table.SelectChunks(exportChunkSize, func(entries []entry.Entry) error {
+			for _, e := range entries {
+				if _, err := fmt.Fprintf(w, "insert %d %d into %s\n", e.Key, e.Value, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
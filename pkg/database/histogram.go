@@ -0,0 +1,75 @@
+package database
+
+import (
+	"errors"
+	"math/bits"
+
+	"dinodb/pkg/entry"
+)
+
+// HistogramBucket counts the entries whose keys fall between Low and High, inclusive.
+type HistogramBucket struct {
+	Low   int64
+	High  int64
+	Count int64
+}
+
+// SelectHistogram divides the range between the index's smallest and largest keys into
+// bucketCount equal-width buckets and counts the entries in each, in order of key.
+// Fewer buckets are returned if the range holds fewer than bucketCount keys, and none if the index is empty.
+// The index is scanned once, keeping only each entry's key, so the counts always add up to the entries scanned.
+func SelectHistogram(index Index, bucketCount int) ([]HistogramBucket, error) {
+	if bucketCount <= 0 {
+		return nil, errors.New("bucket count must be positive")
+	}
+	keys := make([]int64, 0)
+	err := ForEach(index, func(e entry.Entry) error {
+		keys = append(keys, e.Key)
+		return nil
+	})
+	if err != nil || len(keys) == 0 {
+		return []HistogramBucket{}, err
+	}
+	minKey, maxKey := keys[0], keys[0]
+	for _, key := range keys {
+		minKey = min(minKey, key)
+		maxKey = max(maxKey, key)
+	}
+	// The number of keys in the range; 0 stands for 2^64, when the range spans every int64.
+	span := uint64(maxKey-minKey) + 1
+	numBuckets := uint64(bucketCount)
+	if span != 0 && span < numBuckets {
+		numBuckets = span
+	}
+	// bucketStart returns the offset of bucket i's first key from minKey, i.e. ceil(i*span/numBuckets).
+	bucketStart := func(i uint64) uint64 {
+		hi, lo := bits.Mul64(i, span)
+		if span == 0 {
+			hi, lo = i, 0
+		}
+		quo, rem := bits.Div64(hi, lo, numBuckets)
+		if rem != 0 {
+			quo++
+		}
+		return quo
+	}
+	buckets := make([]HistogramBucket, numBuckets)
+	for i := range buckets {
+		buckets[i].Low = minKey + int64(bucketStart(uint64(i)))
+		if i > 0 {
+			buckets[i-1].High = buckets[i].Low - 1
+		}
+	}
+	buckets[numBuckets-1].High = maxKey
+	for _, key := range keys {
+		// A key belongs to bucket floor(offset*numBuckets/span).
+		offset := uint64(key - minKey)
+		hi, lo := bits.Mul64(offset, numBuckets)
+		i := hi
+		if span != 0 {
+			i, _ = bits.Div64(hi, lo, span)
+		}
+		buckets[i].Count++
+	}
+	return buckets, nil
+}
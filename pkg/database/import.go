@@ -0,0 +1,121 @@
+package database
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ImportMode controls what an import does with a row it can't insert.
+type ImportMode int
+
+const (
+	// Stop at the first bad row, keeping the rows imported before it.
+	IMPORT_ABORT ImportMode = iota
+	// Skip bad rows and keep importing.
+	IMPORT_SKIP
+)
+
+// Error for when a string doesn't name an import mode.
+var ErrUnknownImportMode = errors.New("unknown import mode")
+
+// ParseImportMode returns the import mode with the given name.
+func ParseImportMode(s string) (ImportMode, error) {
+	for _, mode := range []ImportMode{IMPORT_ABORT, IMPORT_SKIP} {
+		if mode.String() == s {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q, expected skip or abort", ErrUnknownImportMode, s)
+}
+
+// String returns the import mode's name, as accepted by ParseImportMode.
+func (mode ImportMode) String() string {
+	switch mode {
+	case IMPORT_ABORT:
+		return "abort"
+	case IMPORT_SKIP:
+		return "skip"
+	default:
+		return fmt.Sprintf("ImportMode(%d)", int(mode))
+	}
+}
+
+// RejectedRow is a row of an import that wasn't inserted.
+type RejectedRow struct {
+	Line   int   // The row's line number, starting at 1.
+	Reason error // Why the row wasn't inserted.
+}
+
+// ImportSummary reports the outcome of an import.
+type ImportSummary struct {
+	Imported int           // The number of rows inserted.
+	Rejected []RejectedRow // The rows that weren't inserted, in order.
+	Aborted  bool          // Whether the import stopped at its last rejected row.
+}
+
+// ImportCSV inserts the rows of a CSV file of "<key>,<value>" rows into the table. Blank lines are skipped.
+// Rows that can't be parsed or whose key is already in the table (ErrKeyExists) are rejected, and are
+// handled as the mode says. Errors reading the file or inserting a row stop the import and are returned
+// along with the summary so far.
+func ImportCSV(table Index, r io.Reader, mode ImportMode) (ImportSummary, error) {
+	summary := ImportSummary{Rejected: make([]RejectedRow, 0)}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		row := strings.TrimSpace(scanner.Text())
+		if row == "" {
+			continue
+		}
+		key, value, err := parseImportRow(row)
+		if err == nil {
+			if existing, findErr := table.Find(key); findErr == nil {
+				err = &KeyExistsError{existing}
+			}
+		}
+		if err != nil {
+			summary.Rejected = append(summary.Rejected, RejectedRow{line, err})
+			if mode == IMPORT_ABORT {
+				summary.Aborted = true
+				return summary, nil
+			}
+			continue
+		}
+		if err := table.Insert(key, value); err != nil {
+			return summary, fmt.Errorf("error inserting line %d: %v", line, err)
+		}
+		summary.Imported++
+	}
+	return summary, scanner.Err()
+}
+
+// parseImportRow parses a "<key>,<value>" row of an import.
+func parseImportRow(row string) (key int64, value int64, err error) {
+	fields := strings.Split(row, ",")
+	if len(fields) != 2 {
+		return 0, 0, fmt.Errorf("expected 2 fields, but got %d", len(fields))
+	}
+	if key, err = strconv.ParseInt(strings.TrimSpace(fields[0]), 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("bad key: %v", err)
+	}
+	if value, err = strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64); err != nil {
+		return 0, 0, fmt.Errorf("bad value: %v", err)
+	}
+	return key, value, nil
+}
+
+// FormatImportSummary formats an import summary as lines of text.
+func FormatImportSummary(summary ImportSummary) string {
+	w := new(strings.Builder)
+	fmt.Fprintf(w, "imported: %d\n", summary.Imported)
+	fmt.Fprintf(w, "rejected: %d\n", len(summary.Rejected))
+	for _, row := range summary.Rejected {
+		fmt.Fprintf(w, "line %d: %v\n", row.Line, row.Reason)
+	}
+	if summary.Aborted {
+		fmt.Fprintf(w, "aborted at line %d\n", summary.Rejected[len(summary.Rejected)-1].Line)
+	}
+	return w.String()
+}
@@ -4,6 +4,8 @@ import (
 	"dinodb/pkg/cursor"
 	"dinodb/pkg/entry"
 	"dinodb/pkg/pager"
+	"errors"
+	"fmt"
 	"io"
 )
 
@@ -15,17 +17,72 @@ const (
 	HashIndexType  IndexType = "hash"
 )
 
+// Error for when a string doesn't name an index type.
+var ErrUnknownIndexType = errors.New("unknown index type")
+
+// ParseIndexType returns the index type with the given name.
+func ParseIndexType(s string) (IndexType, error) {
+	switch indexType := IndexType(s); indexType {
+	case BTreeIndexType, HashIndexType:
+		return indexType, nil
+	default:
+		return "", fmt.Errorf("%w %q, expected btree or hash", ErrUnknownIndexType, s)
+	}
+}
+
+// String returns the index type's name, as accepted by ParseIndexType.
+func (indexType IndexType) String() string {
+	return string(indexType)
+}
+
 // Index interface.
 type Index interface {
 	Close() error
 	GetName() string
 	GetPager() *pager.Pager
 	Find(int64) (entry.Entry, error)
+	Floor(int64) (entry.Entry, bool, error)
+	Ceiling(int64) (entry.Entry, bool, error)
 	Insert(int64, int64) error
 	Update(int64, int64) error
+	Upsert(int64, int64) error
+	CompareAndSwap(key int64, expectedVersion int64, newValue int64) (newVersion int64, err error)
+	Rekey(oldKey int64, newKey int64) error
 	Delete(int64) error
+	Truncate() error
 	Select() ([]entry.Entry, error)
+	SelectChunks(chunkSize int, fn func([]entry.Entry) error) error
 	Print(io.Writer)
 	PrintPN(int, io.Writer)
 	CursorAtStart() (cursor.Cursor, error)
+	Warmup() error
+	Count() (int64, error)
+	RangeCount(startKey int64, endKey int64) (int64, error)
+	Metadata() (pager.TableMetadata, error)
+}
+
+// ForEach calls fn on every entry in the index, in the order the index's cursor visits them.
+// Stops and returns the error if fn returns an error.
+func ForEach(index Index, fn func(entry.Entry) error) error {
+	c, err := index.CursorAtStart()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return cursor.ForEach(c, fn)
+}
+
+// Digest returns the number of entries in the index and an order-independent checksum of them
+// (the XOR of each entry's hash), so that indexes with the same entries produce the same digest
+// regardless of their type or layout.
+func Digest(index Index) (count int64, checksum uint64, err error) {
+	err = ForEach(index, func(e entry.Entry) error {
+		count++
+		checksum ^= e.Hash()
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, checksum, nil
 }
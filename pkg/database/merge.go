@@ -0,0 +1,125 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"dinodb/pkg/entry"
+)
+
+// ConflictPolicy controls what a merge does with an entry whose key is already in the destination table.
+type ConflictPolicy int
+
+const (
+	// Stop the merge at the first key already in the destination table.
+	CONFLICT_ERROR ConflictPolicy = iota
+	// Keep the destination's entry.
+	CONFLICT_SKIP
+	// Replace the destination's entry with the source's.
+	CONFLICT_REPLACE
+)
+
+// Error for when a string doesn't name a conflict policy.
+var ErrUnknownConflictPolicy = errors.New("unknown conflict policy")
+
+// Error for when a table has a different index type in each of the databases being merged.
+var ErrTableTypeMismatch = errors.New("table has a different index type in each database")
+
+// ParseConflictPolicy returns the conflict policy with the given name.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	for _, policy := range []ConflictPolicy{CONFLICT_ERROR, CONFLICT_SKIP, CONFLICT_REPLACE} {
+		if policy.String() == s {
+			return policy, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q, expected error, skip, or replace", ErrUnknownConflictPolicy, s)
+}
+
+// String returns the conflict policy's name, as accepted by ParseConflictPolicy.
+func (policy ConflictPolicy) String() string {
+	switch policy {
+	case CONFLICT_ERROR:
+		return "error"
+	case CONFLICT_SKIP:
+		return "skip"
+	case CONFLICT_REPLACE:
+		return "replace"
+	default:
+		return fmt.Sprintf("ConflictPolicy(%d)", int(policy))
+	}
+}
+
+// Merge copies every table of src into dst, in name order. A table dst doesn't have is created with src's
+// index type; the entries of a table both have are inserted into dst's table, with keys already in it handled
+// as onConflict says. Under CONFLICT_ERROR, the merge stops with a KeyExistsError at the first such key,
+// keeping the entries merged before it. Every table both have must have the same index type in each, which is
+// checked before anything is merged. The caller must make sure nothing else uses either database meanwhile.
+func Merge(dst *Database, src *Database, onConflict ConflictPolicy) error {
+	if dst == src {
+		return errors.New("merge error: cannot merge a database into itself")
+	}
+	srcNames, err := src.ListTables()
+	if err != nil {
+		return fmt.Errorf("merge error: %v", err)
+	}
+	dstNames, err := dst.ListTables()
+	if err != nil {
+		return fmt.Errorf("merge error: %v", err)
+	}
+	// Open every source table and check the shared ones' types up front, so a mismatch changes nothing.
+	srcTables := make([]Index, len(srcNames))
+	for i, name := range srcNames {
+		if srcTables[i], err = src.GetTable(name); err != nil {
+			return fmt.Errorf("merge error: %v", err)
+		}
+		if !slices.Contains(dstNames, name) {
+			continue
+		}
+		dstTable, err := dst.GetTable(name)
+		if err != nil {
+			return fmt.Errorf("merge error: %v", err)
+		}
+		srcType, _ := GetIndexType(srcTables[i])
+		dstType, _ := GetIndexType(dstTable)
+		if srcType != dstType {
+			return fmt.Errorf("merge error: %w: %s is %s in the source and %s in the destination",
+				ErrTableTypeMismatch, name, srcType, dstType)
+		}
+	}
+	for i, name := range srcNames {
+		if err := mergeTable(dst, srcTables[i], slices.Contains(dstNames, name), onConflict); err != nil {
+			return fmt.Errorf("merge error: table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// mergeTable inserts every entry of the source table into the destination's table of the same name,
+// creating it first if the destination doesn't have it.
+func mergeTable(dst *Database, srcTable Index, exists bool, onConflict ConflictPolicy) (err error) {
+	var dstTable Index
+	if exists {
+		dstTable, err = dst.GetTable(srcTable.GetName())
+	} else {
+		indexType, _ := GetIndexType(srcTable)
+		dstTable, err = dst.CreateTable(srcTable.GetName(), indexType)
+	}
+	if err != nil {
+		return err
+	}
+	return ForEach(srcTable, func(e entry.Entry) error {
+		existing, findErr := dstTable.Find(e.Key)
+		if findErr != nil {
+			return dstTable.Insert(e.Key, e.Value)
+		}
+		switch onConflict {
+		case CONFLICT_SKIP:
+			return nil
+		case CONFLICT_REPLACE:
+			return dstTable.Update(e.Key, e.Value)
+		default:
+			return &KeyExistsError{existing}
+		}
+	})
+}
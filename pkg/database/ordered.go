@@ -0,0 +1,67 @@
+package database
+
+import (
+	"container/heap"
+	"errors"
+	"sort"
+
+	"dinodb/pkg/entry"
+)
+
+// valueOrderLess reports whether a comes before b when ordering by value, then by key.
+func valueOrderLess(a entry.Entry, b entry.Entry) bool {
+	if a.Value != b.Value {
+		return a.Value < b.Value
+	}
+	return a.Key < b.Key
+}
+
+// topEntries is a max-heap of entries in value-then-key order, holding the smallest entries seen so far.
+type topEntries []entry.Entry
+
+func (h topEntries) Len() int           { return len(h) }
+func (h topEntries) Less(i, j int) bool { return valueOrderLess(h[j], h[i]) }
+func (h topEntries) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *topEntries) Push(x any)        { *h = append(*h, x.(entry.Entry)) }
+func (h *topEntries) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// SelectOrderedByValue returns the first limit entries of the index ordered by value, then by key,
+// or every entry if limit is 0. Tables have no reverse index to read this order from, so the
+// entries are streamed through a heap of size limit rather than selected and sorted in full.
+func SelectOrderedByValue(index Index, limit int) ([]entry.Entry, error) {
+	if limit < 0 {
+		return nil, errors.New("limit must not be negative")
+	}
+	if limit == 0 {
+		entries, err := index.Select()
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return valueOrderLess(entries[i], entries[j])
+		})
+		return entries, nil
+	}
+	top := make(topEntries, 0, limit)
+	err := ForEach(index, func(e entry.Entry) error {
+		if len(top) < limit {
+			heap.Push(&top, e)
+		} else if valueOrderLess(e, top[0]) {
+			top[0] = e
+			heap.Fix(&top, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return valueOrderLess(top[i], top[j])
+	})
+	return top, nil
+}
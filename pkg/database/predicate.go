@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"dinodb/pkg/entry"
+)
+
+// Predicate compares two expressions over an entry's key and value, such as "value*2 > key".
+type Predicate struct {
+	left, right expr
+	op          string
+}
+
+// The comparisons a predicate can make, with each operator ahead of its prefixes so that "<=" isn't read as "<".
+var comparisonOps = []string{"<=", ">=", "!=", "=", "<", ">"}
+
+// ParsePredicate parses two expressions, as in ParseProjection, compared with one of = != < <= > >=.
+func ParsePredicate(text string) (*Predicate, error) {
+	for i := range len(text) {
+		for _, op := range comparisonOps {
+			if !strings.HasPrefix(text[i:], op) {
+				continue
+			}
+			left, _, err := parseExpr(text[:i])
+			if err != nil {
+				return nil, err
+			}
+			right, _, err := parseExpr(text[i+len(op):])
+			if err != nil {
+				return nil, err
+			}
+			return &Predicate{left: left, right: right, op: op}, nil
+		}
+	}
+	return nil, fmt.Errorf("no comparison in predicate %q", strings.TrimSpace(text))
+}
+
+// Match evaluates the predicate on the given entry.
+func (p *Predicate) Match(e entry.Entry) (bool, error) {
+	left, err := p.left.eval(e)
+	if err != nil {
+		return false, fmt.Errorf("%w in predicate for key %d", err, e.Key)
+	}
+	right, err := p.right.eval(e)
+	if err != nil {
+		return false, fmt.Errorf("%w in predicate for key %d", err, e.Key)
+	}
+	switch p.op {
+	case "<=":
+		return left <= right, nil
+	case ">=":
+		return left >= right, nil
+	case "!=":
+		return left != right, nil
+	case "=":
+		return left == right, nil
+	case "<":
+		return left < right, nil
+	default:
+		return left > right, nil
+	}
+}
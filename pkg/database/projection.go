@@ -0,0 +1,239 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"dinodb/pkg/entry"
+)
+
+// Error for when a projection divides by zero.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Projection is a list of arithmetic expressions over an entry's key and value,
+// each of which computes one column of a projected row.
+type Projection struct {
+	columns []string
+	exprs   []expr
+}
+
+// expr is a node of a parsed arithmetic expression.
+type expr interface {
+	eval(e entry.Entry) (int64, error)
+}
+
+// fieldExpr evaluates to the entry's key or value.
+type fieldExpr struct {
+	key bool
+}
+
+// constExpr evaluates to a constant.
+type constExpr struct {
+	val int64
+}
+
+// binaryExpr applies one of + - * / to the values of two expressions.
+type binaryExpr struct {
+	op          byte
+	left, right expr
+}
+
+func (f fieldExpr) eval(e entry.Entry) (int64, error) {
+	if f.key {
+		return e.Key, nil
+	}
+	return e.Value, nil
+}
+
+func (c constExpr) eval(e entry.Entry) (int64, error) {
+	return c.val, nil
+}
+
+func (b binaryExpr) eval(e entry.Entry) (int64, error) {
+	left, err := b.left.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	right, err := b.right.eval(e)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	default:
+		if right == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return left / right, nil
+	}
+}
+
+// ParseProjection parses a comma-separated list of expressions over key and value, such as "key, value*2".
+// Expressions are made of key, value, integer constants, + - * / (with the usual precedence,
+// and integer division), unary minus, and parentheses.
+func ParseProjection(text string) (*Projection, error) {
+	p := &Projection{}
+	for _, column := range strings.Split(text, ",") {
+		e, canonical, err := parseExpr(column)
+		if err != nil {
+			return nil, err
+		}
+		p.columns = append(p.columns, canonical)
+		p.exprs = append(p.exprs, e)
+	}
+	return p, nil
+}
+
+// parseExpr parses a single expression, also returning its text with whitespace removed.
+func parseExpr(text string) (e expr, canonical string, err error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tokens) == 0 {
+		return nil, "", errors.New("empty expression in projection")
+	}
+	parser := &exprParser{tokens: tokens}
+	e, err = parser.parseSum()
+	if err != nil {
+		return nil, "", err
+	}
+	if parser.pos < len(tokens) {
+		return nil, "", fmt.Errorf("unexpected %q in expression %q", tokens[parser.pos], strings.TrimSpace(text))
+	}
+	return e, strings.Join(tokens, ""), nil
+}
+
+// Columns returns the text of each of the projection's expressions, with whitespace removed.
+func (p *Projection) Columns() []string {
+	return p.columns
+}
+
+// Apply evaluates the projection's expressions on the given entry, returning one value per column.
+func (p *Projection) Apply(e entry.Entry) ([]int64, error) {
+	row := make([]int64, len(p.exprs))
+	for i, ex := range p.exprs {
+		val, err := ex.eval(e)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %s for key %d", err, p.columns[i], e.Key)
+		}
+		row[i] = val
+	}
+	return row, nil
+}
+
+// tokenize splits an expression into identifiers, integer constants, operators and parentheses.
+func tokenize(text string) ([]string, error) {
+	tokens := make([]string, 0)
+	for i := 0; i < len(text); {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*/()", c):
+			tokens = append(tokens, string(c))
+			i++
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			j := i
+			for j < len(text) && (unicode.IsLetter(rune(text[j])) || unicode.IsDigit(rune(text[j]))) {
+				j++
+			}
+			tokens = append(tokens, text[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q in projection", c)
+		}
+	}
+	return tokens, nil
+}
+
+// exprParser is a recursive descent parser over an expression's tokens.
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or the empty string at the end of the expression.
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// parseSum parses terms separated by + or -.
+func (p *exprParser) parseSum() (expr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op[0], left, right}
+	}
+	return left, nil
+}
+
+// parseProduct parses factors separated by * or /.
+func (p *exprParser) parseProduct() (expr, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "*" || op == "/"; op = p.peek() {
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op[0], left, right}
+	}
+	return left, nil
+}
+
+// parseFactor parses a field, a constant, a negated factor, or a parenthesized expression.
+func (p *exprParser) parseFactor() (expr, error) {
+	token := p.peek()
+	p.pos++
+	switch {
+	case token == "":
+		return nil, errors.New("unexpected end of expression in projection")
+	case token == "key":
+		return fieldExpr{key: true}, nil
+	case token == "value":
+		return fieldExpr{key: false}, nil
+	case token == "-":
+		inner, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return binaryExpr{'-', constExpr{0}, inner}, nil
+	case token == "(":
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing ) in projection")
+		}
+		p.pos++
+		return inner, nil
+	}
+	val, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unknown operand %q in projection", token)
+	}
+	return constExpr{val}, nil
+}
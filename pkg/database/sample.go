@@ -0,0 +1,36 @@
+package database
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+
+	"dinodb/pkg/entry"
+)
+
+// SelectSample returns min(n, number of entries) entries chosen uniformly at random from the index,
+// sorted by key. Uses reservoir sampling over a single scan, so memory use is proportional to n.
+// Randomness comes from rng, so passing a seeded source makes the sample reproducible.
+func SelectSample(index Index, n int, rng *rand.Rand) ([]entry.Entry, error) {
+	if n < 0 {
+		return nil, errors.New("sample size must not be negative")
+	}
+	reservoir := make([]entry.Entry, 0, n)
+	seen := 0
+	err := ForEach(index, func(e entry.Entry) error {
+		seen++
+		if len(reservoir) < n {
+			reservoir = append(reservoir, e)
+		} else if i := rng.Intn(seen); i < n {
+			reservoir[i] = e
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(reservoir, func(i, j int) bool {
+		return reservoir[i].Key < reservoir[j].Key
+	})
+	return reservoir, nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"dinodb/pkg/pager"
+)
+
+// Trace runs a command, returning every page access it made to the database's tables, in order.
+// Traces are taken one at a time, and the accesses of other clients' commands running alongside
+// a traced command are recorded with it.
+func (db *Database) Trace(run func()) string {
+	db.traceMtx.Lock()
+	defer db.traceMtx.Unlock()
+	trace := pager.NewPageTrace()
+	tables := db.GetTables()
+	for _, table := range tables {
+		table.GetPager().SetTrace(trace)
+	}
+	run()
+	for _, table := range tables {
+		table.GetPager().SetTrace(nil)
+	}
+	return FormatPageTrace(trace.Accesses())
+}
+
+// FormatPageTrace renders page accesses one per line, after a count of them.
+func FormatPageTrace(accesses []pager.PageAccess) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "trace: %d page accesses\n", len(accesses))
+	for _, access := range accesses {
+		fmt.Fprintf(&sb, "  %s\n", access)
+	}
+	return sb.String()
+}
@@ -2,44 +2,53 @@ package entry
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+
+	"github.com/cespare/xxhash"
 )
 
+// Error for when a compare-and-swap expects a different version than the entry has.
+var ErrVersionConflict = errors.New("version conflict")
+
 // Entry is a key-value pair that is usually used to represent an entry in a BTree or Hash table.
+// Version starts at 0 when the entry is inserted and increments every time its value is updated.
 type Entry struct {
-	Key   int64
-	Value int64
+	Key     int64
+	Value   int64
+	Version int64
 }
 
-// New constructs and returns a new Entry with the specified key and value.
+// New constructs and returns a new Entry with the specified key and value, at version 0.
 func New(key int64, value int64) Entry {
-	return Entry{key, value}
+	return Entry{Key: key, Value: value}
 }
 
 // Marshal serializes a given entry into a byte array.
 func (entry Entry) Marshal() []byte {
-	// Marshall the key field.
-	var newdata []byte
-	bin := make([]byte, binary.MaxVarintLen64)
-	binary.PutVarint(bin, entry.Key)
-	newdata = bin
-	// Marshall the value field.
-	bin = make([]byte, binary.MaxVarintLen64)
-	binary.PutVarint(bin, entry.Value)
-	newdata = append(newdata, bin...)
-	// Return the combined byte array.
+	newdata := make([]byte, binary.MaxVarintLen64*3)
+	binary.PutVarint(newdata, entry.Key)
+	binary.PutVarint(newdata[binary.MaxVarintLen64:], entry.Value)
+	binary.PutVarint(newdata[binary.MaxVarintLen64*2:], entry.Version)
 	return newdata
 }
 
 // UnmarshalEntry deserializes a byte array into an entry.
 func UnmarshalEntry(data []byte) Entry {
-	k, _ := binary.Varint(data[:len(data)/2])
-	v, _ := binary.Varint(data[len(data)/2:])
-	return Entry{Key: k, Value: v}
+	k, _ := binary.Varint(data[:binary.MaxVarintLen64])
+	v, _ := binary.Varint(data[binary.MaxVarintLen64 : binary.MaxVarintLen64*2])
+	version, _ := binary.Varint(data[binary.MaxVarintLen64*2 : binary.MaxVarintLen64*3])
+	return Entry{Key: k, Value: v, Version: version}
 }
 
 // Print writes the entry to the specified writer in the following format: (<key>, <value>)
 func (entry Entry) Print(w io.Writer) {
 	fmt.Fprintf(w, "(%d, %d), ", entry.Key, entry.Value)
 }
+
+// Hash returns a hash of the entry's key and value, for use in checksums. The version is not hashed,
+// so entries with the same contents hash the same regardless of their update history.
+func (entry Entry) Hash() uint64 {
+	return xxhash.Sum64(New(entry.Key, entry.Value).Marshal())
+}
@@ -0,0 +1,23 @@
+package entry
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Error for when a key or value being parsed doesn't fit in an int64.
+var ErrOutOfRange = errors.New("value out of int64 range")
+
+// ParseInt64 parses a key or value written in base 10, returning an ErrOutOfRange
+// if it doesn't fit in an int64, regardless of the platform's int size.
+func ParseInt64(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, fmt.Errorf("%w: %s", ErrOutOfRange, s)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer: %q", s)
+	}
+	return n, nil
+}
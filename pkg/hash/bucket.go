@@ -49,6 +49,17 @@ func (bucket *HashBucket) GetPage() *pager.Page {
 	return bucket.page
 }
 
+// maxSize returns the max number of entries that can live in this bucket, based on its page size.
+func (bucket *HashBucket) maxSize() int64 {
+	return maxBucketSize(bucket.page.GetPager().GetPageSize())
+}
+
+// sparse returns whether the bucket is under a quarter full, so that merging it with a sparse split image
+// leaves a bucket at most half full, which won't need to split again soon.
+func (bucket *HashBucket) sparse() bool {
+	return bucket.numKeys < bucket.maxSize()/4
+}
+
 // Find returns an entry in the bucket with the given key.
 func (bucket *HashBucket) Find(key int64) (entry.Entry, bool) {
 	for i := int64(0); i < bucket.numKeys; i++ {
@@ -66,12 +77,12 @@ func (bucket *HashBucket) Insert(key int64, value int64) bool {
 	bucket.modifyEntry(bucket.numKeys, entry.New(key, value))
 	bucket.updateNumKeys(bucket.numKeys + 1)
 	// If we reach the max number of keys a Hash Bucket can store, we must split
-	return bucket.numKeys >= MAX_BUCKET_SIZE
+	return bucket.numKeys >= bucket.maxSize()
 	/* SOLUTION }}} */
 }
 
-// Update modifies the value associated with a given key, or returns an error
-// if no entry with that key is found.
+// Update modifies the value associated with a given key and increments its version,
+// or returns an error if no entry with that key is found.
 // This method should never split the bucket.
 func (bucket *HashBucket) Update(key int64, newValue int64) error {
 	// Get the index to update.
@@ -86,13 +97,31 @@ func (bucket *HashBucket) Update(key int64, newValue int64) error {
 		return errors.New("key not found, update aborted")
 	}
 	// Update the value.
-	bucket.updateValueAt(index, newValue)
+	e := bucket.getEntry(index)
+	bucket.modifyEntry(index, entry.Entry{Key: key, Value: newValue, Version: e.Version + 1})
 	return nil
 }
 
+// CompareAndSwap updates the entry with the given key like Update, but only if it is at expectedVersion.
+// Returns the entry's new version, or an entry.ErrVersionConflict if it is at a different version.
+func (bucket *HashBucket) CompareAndSwap(key int64, expectedVersion int64, newValue int64) (int64, error) {
+	e, found := bucket.Find(key)
+	if !found {
+		return 0, errors.New("key not found, update aborted")
+	}
+	if e.Version != expectedVersion {
+		return 0, fmt.Errorf("%w: key %d is at version %d, not %d",
+			entry.ErrVersionConflict, key, e.Version, expectedVersion)
+	}
+	if err := bucket.Update(key, newValue); err != nil {
+		return 0, err
+	}
+	return expectedVersion + 1, nil
+}
+
 // Delete deletes the key-value entry with the specified key, or returns an error
 // if no entry with that key is found.
-// NOTE: does not coalesce (ie doesn't merge buckets when they become empty)
+// NOTE: does not coalesce; HashTable.Delete merges the bucket with its split image afterwards if both are sparse
 func (bucket *HashBucket) Delete(key int64) error {
 	// Get the index to delete.
 	index := int64(-1)
@@ -180,10 +209,11 @@ func (bucket *HashBucket) getKeyAt(index int64) int64 {
 	return bucket.getEntry(index).Key
 }
 
-// updateKeyAt updates the key of the entry at the given index.
+// updateKeyAt updates the key of the entry at the given index, keeping its value and version.
 func (bucket *HashBucket) updateKeyAt(index int64, newKey int64) {
-	existingVal := bucket.getValueAt(index)
-	bucket.modifyEntry(index, entry.New(newKey, existingVal))
+	e := bucket.getEntry(index)
+	e.Key = newKey
+	bucket.modifyEntry(index, e)
 }
 
 // Get the value at the given index.
@@ -191,10 +221,11 @@ func (bucket *HashBucket) getValueAt(index int64) int64 {
 	return bucket.getEntry(index).Value
 }
 
-// updateValueAt updates the value of the entry at the given index.
+// updateValueAt updates the value of the entry at the given index, keeping its key and version.
 func (bucket *HashBucket) updateValueAt(index int64, newValue int64) {
-	existingKey := bucket.getKeyAt(index)
-	bucket.modifyEntry(index, entry.New(existingKey, newValue))
+	e := bucket.getEntry(index)
+	e.Value = newValue
+	bucket.modifyEntry(index, e)
 }
 
 // updateDepth updates this bucket's depth and writes the new depth to the bucket's page.
@@ -16,5 +16,15 @@ const DEPTH_SIZE int64 = binary.MaxVarintLen64
 const NUM_KEYS_OFFSET int64 = DEPTH_OFFSET + DEPTH_SIZE
 const NUM_KEYS_SIZE int64 = binary.MaxVarintLen64
 const BUCKET_HEADER_SIZE int64 = DEPTH_SIZE + NUM_KEYS_SIZE
-const ENTRYSIZE int64 = binary.MaxVarintLen64 * 2                         // int64 key, int64 value
-const MAX_BUCKET_SIZE int64 = (PAGESIZE - BUCKET_HEADER_SIZE) / ENTRYSIZE // max number of entries that can live in a bucket
+const ENTRYSIZE int64 = binary.MaxVarintLen64 * 3                         // int64 key, int64 value, int64 version
+const MAX_BUCKET_SIZE int64 = (PAGESIZE - BUCKET_HEADER_SIZE) / ENTRYSIZE // max number of entries that can live in a bucket with the default page size
+const HASHER_ID_OFFSET int64 = DEPTH_OFFSET + DEPTH_SIZE                  // offset of the hasher id in the meta file
+const HASHER_ID_SIZE int64 = binary.MaxVarintLen64
+const META_HEADER_SIZE int64 = DEPTH_SIZE + HASHER_ID_SIZE
+const INITIAL_DEPTH int64 = 2      // the global depth of a new table, below which merging buckets never shrinks it
+const FREE_BUCKET_DEPTH int64 = -1 // the local depth written to a bucket page merged into its split image, marking it free
+
+// maxBucketSize returns the max number of entries that can live in a bucket with the given page size.
+func maxBucketSize(pagesize int64) int64 {
+	return (pagesize - BUCKET_HEADER_SIZE) / ENTRYSIZE
+}
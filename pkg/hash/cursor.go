@@ -15,6 +15,7 @@ type HashCursor struct {
 }
 
 // CursorAtStart returns a cursor to the first entry in the hash table.
+// If the hash table is empty, the returned cursor is not valid.
 func (table *HashIndex) CursorAtStart() (cursor.Cursor, error) {
 	cursor := HashCursor{table: table, cellnum: 0}
 
@@ -25,12 +26,9 @@ func (table *HashIndex) CursorAtStart() (cursor.Cursor, error) {
 	defer table.pager.PutPage(curPage)
 	cursor.curBucket = pageToBucket(curPage)
 	//if we are in an empty bucket, move to the leftmost non-empty bucket
+	//if Next() reaches the end, then all our buckets are empty and the cursor stays invalid
 	if cursor.curBucket.numKeys == 0 {
-		noEntries := cursor.Next()
-		//if noEntries is true, then all our buckets are empty
-		if noEntries {
-			return nil, errors.New("all buckets are empty")
-		}
+		cursor.Next()
 	}
 
 	return &cursor, nil
@@ -67,6 +65,11 @@ func (cursor *HashCursor) Next() bool {
 	return false
 }
 
+// Valid returns whether the cursor is pointing at an entry.
+func (cursor *HashCursor) Valid() bool {
+	return cursor.cellnum < cursor.curBucket.numKeys
+}
+
 // GetEntry returns the entry currently pointed to by the cursor.
 func (cursor *HashCursor) GetEntry() (entry.Entry, error) {
 	if cursor.cellnum > cursor.curBucket.numKeys {
@@ -0,0 +1,40 @@
+package hash
+
+import "fmt"
+
+// FindPlan describes how Find looks up a key, without reading the entry's value.
+// The directory is kept in memory, so the bucket's page is the only page a lookup accesses.
+type FindPlan struct {
+	Hash        int64 // The key's hash, which is its slot in the directory.
+	GlobalDepth int64 // The table's global depth, which the key is hashed at.
+	BucketPN    int64 // The page number of the bucket that the directory slot points to.
+	LocalDepth  int64 // The bucket's local depth.
+	Found       bool  // Whether the bucket holds the key.
+	Slot        int64 // The key's position in the bucket, if it is there.
+}
+
+// ExplainFind hashes the given key and finds its bucket as Find would.
+func (table *HashTable) ExplainFind(key int64) (FindPlan, error) {
+	table.RLock()
+	plan := FindPlan{Hash: table.hasher(key, table.globalDepth), GlobalDepth: table.globalDepth}
+	if plan.Hash < 0 || int(plan.Hash) >= len(table.buckets) {
+		table.RUnlock()
+		return plan, fmt.Errorf("hash %d is outside the directory", plan.Hash)
+	}
+	plan.BucketPN = table.buckets[plan.Hash]
+	bucket, err := table.GetAndLockBucketByPN(plan.BucketPN, READ_LOCK)
+	table.RUnlock()
+	if err != nil {
+		return plan, err
+	}
+	defer table.pager.PutPage(bucket.page)
+	defer bucket.RUnlock()
+	plan.LocalDepth = bucket.localDepth
+	for i := int64(0); i < bucket.numKeys; i++ {
+		if bucket.getKeyAt(i) == key {
+			plan.Found, plan.Slot = true, i
+			break
+		}
+	}
+	return plan, nil
+}
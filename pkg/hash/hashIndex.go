@@ -1,9 +1,12 @@
 package hash
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"path/filepath"
 
+	"dinodb/pkg/cursor"
 	"dinodb/pkg/entry"
 	"dinodb/pkg/pager"
 )
@@ -15,23 +18,39 @@ type HashIndex struct {
 }
 
 // Opens the pager with the given table name.
+// New tables use the default Hasher; existing tables use the hasher they were created with.
 func OpenTable(filename string) (*HashIndex, error) {
+	return OpenTableWithHasher(filename, nil)
+}
+
+// Opens the pager with the given table name, routing keys with the given hasher.
+// If the table already exists, returns ErrHasherMismatch if it was created with a different hasher.
+// A nil hasher behaves like OpenTable.
+func OpenTableWithHasher(filename string, hasher HasherFunc) (*HashIndex, error) {
 	// Create a pager for the table.
-	pager, err := pager.New(filename)
+	bucketPager, err := pager.New(filename)
 	if err != nil {
 		return nil, err
 	}
+	// Check that the table's entries have versions, marking a new table as having them.
+	if err = bucketPager.RequireFeature(pager.VERSIONED_ENTRIES_FLAG); err != nil {
+		bucketPager.Close()
+		return nil, err
+	}
 	// Return index.
 	var table *HashTable
-	if pager.GetNumPages() == 0 {
-		table, err = NewHashTable(pager)
+	if bucketPager.GetNumPages() == 0 {
+		if hasher == nil {
+			hasher = Hasher
+		}
+		table, err = NewHashTable(bucketPager, hasher)
 	} else {
-		table, err = ReadHashTable(pager)
+		table, err = ReadHashTable(bucketPager, hasher)
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &HashIndex{table: table, pager: pager}, nil
+	return &HashIndex{table: table, pager: bucketPager}, nil
 }
 
 // GetName returns the base file name of the file backing this index's pager.
@@ -51,9 +70,44 @@ func (index *HashIndex) GetTable() *HashTable {
 
 // Closes the table by closing the pager.
 func (index *HashIndex) Close() error {
+	// Wait for in-flight operations to finish, so that none is splitting the directory as it is written.
+	// Operations that start afterwards get pager.ErrClosing.
+	index.table.WLock()
+	defer index.table.WUnlock()
 	return WriteHashTable(index.pager, index.table)
 }
 
+// Flush writes the table's dirty pages and its directory to disk without closing it,
+// so that a copy of its files can be opened as the table is now.
+func (index *HashIndex) Flush() error {
+	// Keep splits from changing the directory while it is written.
+	index.table.RLock()
+	defer index.table.RUnlock()
+	index.pager.LockAllPages()
+	index.pager.FlushAllPages()
+	index.pager.UnlockAllPages()
+	return writeHashTableMeta(index.pager, index.table)
+}
+
+// Truncate removes every entry, resetting the table to a new directory of the default depth
+// and shrinking its bucket file to the new buckets. Returns an error if a bucket is still pinned, e.g. by an open cursor.
+func (index *HashIndex) Truncate() error {
+	index.table.WLock()
+	defer index.table.WUnlock()
+	if n := index.pager.GetNumPinned(); n > 0 {
+		return fmt.Errorf("cannot truncate while %d pages are pinned", n)
+	}
+	if err := index.pager.Truncate(0); err != nil {
+		return err
+	}
+	table, err := NewHashTable(index.pager, index.table.hasher)
+	if err != nil {
+		return err
+	}
+	index.table.globalDepth, index.table.buckets, index.table.freeBuckets = table.globalDepth, table.buckets, nil
+	return writeHashTableMeta(index.pager, index.table)
+}
+
 // Find element by key.
 func (index *HashIndex) Find(key int64) (entry.Entry, error) {
 	return index.table.Find(key)
@@ -69,16 +123,137 @@ func (index *HashIndex) Update(key int64, value int64) error {
 	return index.table.Update(key, value)
 }
 
+// Update given element if it is at expectedVersion, returning its new version.
+func (index *HashIndex) CompareAndSwap(key int64, expectedVersion int64, newValue int64) (int64, error) {
+	return index.table.CompareAndSwap(key, expectedVersion, newValue)
+}
+
+// Update given element, inserting it if it doesn't exist.
+func (index *HashIndex) Upsert(key int64, value int64) error {
+	return index.table.Upsert(key, value)
+}
+
+// Move the element with oldKey to newKey.
+func (index *HashIndex) Rekey(oldKey int64, newKey int64) error {
+	return index.table.Rekey(oldKey, newKey)
+}
+
 // Delete given element.
 func (index *HashIndex) Delete(key int64) error {
 	return index.table.Delete(key)
 }
 
-// Select all elements.
+// Select all elements, in no particular order.
 func (index *HashIndex) Select() ([]entry.Entry, error) {
 	return index.table.Select()
 }
 
+// Select all elements, ordered by their keys.
+func (index *HashIndex) SelectSorted() ([]entry.Entry, error) {
+	return index.table.SelectSorted()
+}
+
+// Select up to limit elements ordered by their keys, after afterKey if hasAfter is set, reporting whether there are more.
+func (index *HashIndex) SelectSortedPage(afterKey int64, hasAfter bool, limit int) ([]entry.Entry, bool, error) {
+	return index.table.SelectSortedPage(afterKey, hasAfter, limit)
+}
+
+// Select all elements, passing them to fn in chunks.
+func (index *HashIndex) SelectChunks(chunkSize int, fn func([]entry.Entry) error) error {
+	return index.table.SelectChunks(chunkSize, fn)
+}
+
+// Count the table's entries without reading them.
+func (index *HashIndex) Count() (int64, error) {
+	return index.table.Count()
+}
+
+// Metadata returns the table's metadata, counting its entries. Pages are counted in the table's bucket file.
+func (index *HashIndex) Metadata() (pager.TableMetadata, error) {
+	count, err := index.Count()
+	if err != nil {
+		return pager.TableMetadata{}, err
+	}
+	return index.pager.Metadata("hash", count), nil
+}
+
+// Count the table's entries with keys in [startKey, endKey), scanning every bucket.
+func (index *HashIndex) RangeCount(startKey int64, endKey int64) (count int64, err error) {
+	if startKey >= endKey {
+		return 0, errors.New("startKey is not smaller than endKey")
+	}
+	c, err := index.CursorAtStart()
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+	err = cursor.ForEach(c, func(e entry.Entry) error {
+		if e.Key >= startKey && e.Key < endKey {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Floor returns the entry with the largest key at most the given key, or false if every key is larger,
+// scanning every bucket.
+func (index *HashIndex) Floor(key int64) (entry.Entry, bool, error) {
+	return index.nearest(func(e entry.Entry, best entry.Entry, found bool) bool {
+		return e.Key <= key && (!found || e.Key > best.Key)
+	})
+}
+
+// Ceiling returns the entry with the smallest key at least the given key, or false if every key is smaller,
+// scanning every bucket.
+func (index *HashIndex) Ceiling(key int64) (entry.Entry, bool, error) {
+	return index.nearest(func(e entry.Entry, best entry.Entry, found bool) bool {
+		return e.Key >= key && (!found || e.Key < best.Key)
+	})
+}
+
+// nearest scans every bucket for the best entry, where better reports whether an entry beats the best so far.
+func (index *HashIndex) nearest(better func(e entry.Entry, best entry.Entry, found bool) bool) (best entry.Entry, found bool, err error) {
+	c, err := index.CursorAtStart()
+	if err != nil {
+		return entry.Entry{}, false, err
+	}
+	defer c.Close()
+	if !c.Valid() {
+		return entry.Entry{}, false, nil
+	}
+	for {
+		e, err := c.GetEntry()
+		if err != nil {
+			return entry.Entry{}, false, err
+		}
+		if better(e, best, found) {
+			best, found = e, true
+		}
+		if c.Next() {
+			return best, found, nil
+		}
+	}
+}
+
+// Load the table's buckets into the buffer.
+func (index *HashIndex) Warmup() error {
+	return index.table.Warmup()
+}
+
+// Layout classifies every page of the table's file.
+func (index *HashIndex) Layout() ([]PageInfo, error) {
+	return index.table.Layout()
+}
+
+// ExplainFind describes how Find looks up the given key.
+func (index *HashIndex) ExplainFind(key int64) (FindPlan, error) {
+	return index.table.ExplainFind(key)
+}
+
 // Print all elements.
 func (index *HashIndex) Print(w io.Writer) {
 	index.table.Print(w)
@@ -1,11 +1,13 @@
 package hash
 
 import (
+	"cmp"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"slices"
 	"sync"
 
 	"dinodb/pkg/entry"
@@ -17,12 +19,17 @@ type HashTable struct {
 	globalDepth int64        // The **global** depth of the Hash Table
 	buckets     []int64      // Slice of bucket's page numbers. The indices (in binary) correspond to buckets' search keys in the HashTable
 	pager       *pager.Pager // The pager associated with the Hash Table
+	hasher      HasherFunc   // The hash function used to route keys to buckets
+	freeBuckets []int64      // Page numbers of buckets merged into their split images, reused by splits
 	rwlock      sync.RWMutex // Lock on the Hash Table
 }
 
-// Returns a new HashTable.
-func NewHashTable(pager *pager.Pager) (*HashTable, error) {
-	depth := int64(2)
+// ErrHasherMismatch is returned when a table is reopened with a different hasher than it was created with.
+var ErrHasherMismatch = errors.New("hasher does not match the one the table was created with")
+
+// Returns a new HashTable that routes keys using the given hasher.
+func NewHashTable(pager *pager.Pager, hasher HasherFunc) (*HashTable, error) {
+	depth := INITIAL_DEPTH
 	buckets := make([]int64, powInt(2, depth))
 	for i := range buckets {
 		bucket, err := newHashBucket(pager, depth)
@@ -32,7 +39,7 @@ func NewHashTable(pager *pager.Pager) (*HashTable, error) {
 		buckets[i] = bucket.page.GetPageNum()
 		pager.PutPage(bucket.page)
 	}
-	return &HashTable{globalDepth: depth, buckets: buckets, pager: pager}, nil
+	return &HashTable{globalDepth: depth, buckets: buckets, pager: pager, hasher: hasher}, nil
 }
 
 // Get depth.
@@ -50,11 +57,16 @@ func (table *HashTable) GetPager() *pager.Pager {
 	return table.pager
 }
 
+// GetHasher returns the hash function used by this table.
+func (table *HashTable) GetHasher() HasherFunc {
+	return table.hasher
+}
+
 // Finds the entry with the given key.
 func (table *HashTable) Find(key int64) (entry.Entry, error) {
 	table.RLock()
 	// Hash the key.
-	hash := Hasher(key, table.globalDepth)
+	hash := table.hasher(key, table.globalDepth)
 	if hash < 0 || int(hash) >= len(table.buckets) {
 		table.RUnlock()
 		return entry.Entry{}, errors.New("not found")
@@ -87,24 +99,45 @@ func (table *HashTable) ExtendTable() {
 }
 
 // Insert a key / value pair into the Hash Table.
-// Make sure to lock both table and buckets
+// An insert that leaves room in its bucket only read locks the table while it finds and locks the bucket,
+// so inserts into different buckets can run at the same time. An insert that fills its bucket has to split it,
+// which may change the directory, so it is retried with the table write locked.
 func (table *HashTable) Insert(key int64, value int64) error {
-	/* SOLUTION {{{ */
+	table.RLock()
+	hash := table.hasher(key, table.globalDepth)
+	bucket, err := table.GetAndLockBucket(hash, WRITE_LOCK)
+	table.RUnlock()
+	if err != nil {
+		return err
+	}
+	fits := bucket.numKeys+1 < bucket.maxSize()
+	if fits {
+		bucket.Insert(key, value)
+	}
+	bucket.WUnlock()
+	table.pager.PutPage(bucket.page)
+	if fits {
+		return nil
+	}
+	return table.insertAndSplit(key, value)
+}
+
+// Insert a key / value pair with the table write locked, splitting its bucket if it fills up.
+func (table *HashTable) insertAndSplit(key int64, value int64) error {
 	table.WLock()
 	defer table.WUnlock()
-	hash := Hasher(key, table.globalDepth)
+	hash := table.hasher(key, table.globalDepth)
 	bucket, err := table.GetAndLockBucket(hash, WRITE_LOCK)
-	defer bucket.WUnlock()
 	if err != nil {
 		return err
 	}
+	defer bucket.WUnlock()
 	defer table.pager.PutPage(bucket.page)
 	split := bucket.Insert(key, value)
 	if !split {
 		return nil
 	}
 	return table.split(bucket, hash)
-	/* SOLUTION }}} */
 }
 
 // Split the given bucket into two, extending the table if necessary.
@@ -125,13 +158,12 @@ func (table *HashTable) split(bucket *HashBucket, hash int64) error {
 	// Next, make a new bucket
 
 	bucket.updateLocalDepth(bucket.localDepth + 1)
-	newBucket, err := newHashBucket(table.pager, bucket.localDepth)
-
-	newBucket.WLock()
-	defer newBucket.WUnlock()
+	newBucket, err := table.newBucket(bucket.localDepth)
 	if err != nil {
 		return err
 	}
+	newBucket.WLock()
+	defer newBucket.WUnlock()
 	defer table.pager.PutPage(newBucket.page)
 
 	// Move entries over to it.
@@ -142,7 +174,7 @@ func (table *HashTable) split(bucket *HashBucket, hash int64) error {
 	oldNKeys := int64(0)
 	newNKeys := int64(0)
 	for _, entry := range tmpEntries {
-		if Hasher(entry.Key, bucket.localDepth) == newHash {
+		if table.hasher(entry.Key, bucket.localDepth) == newHash {
 			newBucket.modifyEntry(newNKeys, entry)
 			newNKeys++
 		} else {
@@ -159,10 +191,10 @@ func (table *HashTable) split(bucket *HashBucket, hash int64) error {
 		table.buckets[i] = newBucket.page.GetPageNum()
 	}
 	// Check if recursive splitting is required
-	if oldNKeys >= MAX_BUCKET_SIZE {
+	if oldNKeys >= bucket.maxSize() {
 		return table.split(bucket, oldHash)
 	}
-	if newNKeys >= MAX_BUCKET_SIZE {
+	if newNKeys >= newBucket.maxSize() {
 		return table.split(newBucket, newHash)
 	}
 	return nil
@@ -172,7 +204,7 @@ func (table *HashTable) split(bucket *HashBucket, hash int64) error {
 // Update the given key-value pair.
 func (table *HashTable) Update(key int64, value int64) error {
 	table.RLock()
-	hash := Hasher(key, table.globalDepth)
+	hash := table.hasher(key, table.globalDepth)
 	// [CONCURRENCY]: Using GetAndLockBucket instead of GetBucket
 	bucket, err := table.GetAndLockBucket(hash, WRITE_LOCK)
 	if err != nil {
@@ -186,28 +218,202 @@ func (table *HashTable) Update(key int64, value int64) error {
 	return err2
 }
 
-// Delete the given key-value pair, does not coalesce.
-func (table *HashTable) Delete(key int64) error {
+// Update the value of the given key if its entry is at expectedVersion, returning the entry's new version.
+func (table *HashTable) CompareAndSwap(key int64, expectedVersion int64, newValue int64) (int64, error) {
 	table.RLock()
-	hash := Hasher(key, table.globalDepth)
-	// [CONCURRENCY]: Using GetAndLockBucket instead of GetBucket
+	hash := table.hasher(key, table.globalDepth)
 	bucket, err := table.GetAndLockBucket(hash, WRITE_LOCK)
 	if err != nil {
 		table.RUnlock()
+		return 0, err
+	}
+	defer table.pager.PutPage(bucket.page)
+	table.RUnlock()
+	defer bucket.WUnlock()
+	return bucket.CompareAndSwap(key, expectedVersion, newValue)
+}
+
+// Update the given key-value pair, inserting it if the key doesn't exist.
+func (table *HashTable) Upsert(key int64, value int64) error {
+	table.WLock()
+	defer table.WUnlock()
+	hash := table.hasher(key, table.globalDepth)
+	bucket, err := table.GetAndLockBucket(hash, WRITE_LOCK)
+	if err != nil {
 		return err
 	}
+	defer bucket.WUnlock()
 	defer table.pager.PutPage(bucket.page)
+	if _, found := bucket.Find(key); found {
+		return bucket.Update(key, value)
+	}
+	if split := bucket.Insert(key, value); !split {
+		return nil
+	}
+	return table.split(bucket, hash)
+}
+
+// Move the entry with oldKey to newKey, keeping its value. Returns an error
+// if oldKey doesn't exist or newKey already does. Holds the table's write lock throughout.
+func (table *HashTable) Rekey(oldKey int64, newKey int64) error {
+	table.WLock()
+	defer table.WUnlock()
+	oldHash := table.hasher(oldKey, table.globalDepth)
+	oldBucket, err := table.GetAndLockBucket(oldHash, WRITE_LOCK)
+	if err != nil {
+		return err
+	}
+	e, found := oldBucket.Find(oldKey)
+	if !found {
+		oldBucket.WUnlock()
+		table.pager.PutPage(oldBucket.page)
+		return errors.New("key not found, rekey aborted")
+	}
+	// Check that the new key doesn't exist, without locking the old key's bucket twice.
+	newHash := table.hasher(newKey, table.globalDepth)
+	_, exists := oldBucket.Find(newKey)
+	if table.buckets[newHash] != table.buckets[oldHash] {
+		newBucket, err := table.GetAndLockBucket(newHash, READ_LOCK)
+		if err != nil {
+			oldBucket.WUnlock()
+			table.pager.PutPage(oldBucket.page)
+			return err
+		}
+		_, exists = newBucket.Find(newKey)
+		newBucket.RUnlock()
+		table.pager.PutPage(newBucket.page)
+	}
+	if exists {
+		oldBucket.WUnlock()
+		table.pager.PutPage(oldBucket.page)
+		return errors.New("key already exists, rekey aborted")
+	}
+	err = oldBucket.Delete(oldKey)
+	oldBucket.WUnlock()
+	table.pager.PutPage(oldBucket.page)
+	if err != nil {
+		return err
+	}
+	// Insert the entry under its new key, splitting if necessary.
+	newBucket, err := table.GetAndLockBucket(newHash, WRITE_LOCK)
+	if err != nil {
+		return err
+	}
+	defer newBucket.WUnlock()
+	defer table.pager.PutPage(newBucket.page)
+	if split := newBucket.Insert(newKey, e.Value); !split {
+		return nil
+	}
+	return table.split(newBucket, newHash)
+}
+
+// Delete the given key-value pair.
+// A delete only read locks the table while it finds and locks the bucket. A delete that leaves its bucket sparse
+// then merges it with its split image, which may change the directory, so that is done with the table write locked.
+func (table *HashTable) Delete(key int64) error {
+	table.RLock()
+	hash := table.hasher(key, table.globalDepth)
+	// [CONCURRENCY]: Using GetAndLockBucket instead of GetBucket
+	bucket, err := table.GetAndLockBucket(hash, WRITE_LOCK)
 	table.RUnlock()
+	if err != nil {
+		return err
+	}
+	err = bucket.Delete(key)
+	mergeable := err == nil && bucket.localDepth > INITIAL_DEPTH && bucket.sparse()
+	bucket.WUnlock()
+	table.pager.PutPage(bucket.page)
+	if !mergeable {
+		return err
+	}
+	return table.coalesce(key)
+}
+
+// coalesce merges the bucket the key hashes to with its split image for as long as both are sparse,
+// then halves the directory for as long as no bucket's local depth reaches the global depth.
+func (table *HashTable) coalesce(key int64) error {
+	table.WLock()
+	defer table.WUnlock()
+	for {
+		merged, err := table.merge(table.hasher(key, table.globalDepth))
+		if err != nil {
+			return err
+		}
+		if !merged {
+			break
+		}
+	}
+	table.shrink()
+	return nil
+}
+
+// merge moves the entries of the bucket at the given hash and its split image into whichever of the two
+// split would have kept, decrementing its local depth and freeing the other's page.
+// Returns false without merging if either bucket isn't sparse, or if they don't have the same local depth.
+func (table *HashTable) merge(hash int64) (bool, error) {
+	bucket, err := table.GetAndLockBucket(hash, WRITE_LOCK)
+	if err != nil {
+		return false, err
+	}
+	defer table.pager.PutPage(bucket.page)
 	defer bucket.WUnlock()
-	err2 := bucket.Delete(key)
-	return err2
+	if bucket.localDepth <= INITIAL_DEPTH || !bucket.sparse() {
+		return false, nil
+	}
+	// The split image's slots differ from this bucket's in the highest bit the local depth covers.
+	imageBit := powInt(2, bucket.localDepth-1)
+	image, err := table.GetAndLockBucket(hash^imageBit, WRITE_LOCK)
+	if err != nil {
+		return false, err
+	}
+	defer table.pager.PutPage(image.page)
+	defer image.WUnlock()
+	if image.localDepth != bucket.localDepth || !image.sparse() {
+		return false, nil
+	}
+	kept, freed := bucket, image
+	if hash&imageBit != 0 {
+		kept, freed = image, bucket
+	}
+	for i := int64(0); i < freed.numKeys; i++ {
+		kept.modifyEntry(kept.numKeys+i, freed.getEntry(i))
+	}
+	kept.updateNumKeys(kept.numKeys + freed.numKeys)
+	kept.updateLocalDepth(kept.localDepth - 1)
+	freed.updateNumKeys(0)
+	freed.updateLocalDepth(FREE_BUCKET_DEPTH)
+	keptPN, freedPN := kept.page.GetPageNum(), freed.page.GetPageNum()
+	for i, pn := range table.buckets {
+		if pn == freedPN {
+			table.buckets[i] = keptPN
+		}
+	}
+	table.freeBuckets = append(table.freeBuckets, freedPN)
+	return true, nil
+}
+
+// shrink halves the table while its halves point to the same buckets, i.e. while every bucket's local depth
+// is below the global depth, without going below the initial depth.
+func (table *HashTable) shrink() {
+	for table.globalDepth > INITIAL_DEPTH {
+		half := len(table.buckets) / 2
+		if !slices.Equal(table.buckets[:half], table.buckets[half:]) {
+			return
+		}
+		table.globalDepth = table.globalDepth - 1
+		table.buckets = table.buckets[:half]
+	}
 }
 
 // Select all entries in this table.
+// Entries are returned bucket by bucket, in the order of the buckets' pages, so their order
+// depends on the table's split history; callers should only rely on the set of entries returned.
+// Use SelectSorted for entries in a deterministic order.
 func (table *HashTable) Select() ([]entry.Entry, error) {
 	/* SOLUTION {{{ */
 	ret := make([]entry.Entry, 0)
 	table.RLock()
+	defer table.RUnlock()
 	for i := int64(0); i < table.pager.GetNumPages(); i++ {
 		bucket, err := table.GetAndLockBucketByPN(i, READ_LOCK)
 		if err != nil {
@@ -225,6 +431,106 @@ func (table *HashTable) Select() ([]entry.Entry, error) {
 	/* SOLUTION }}} */
 }
 
+// Count returns the number of entries in this table, summing the key counts in its bucket pages' headers.
+func (table *HashTable) Count() (int64, error) {
+	table.RLock()
+	defer table.RUnlock()
+	count := int64(0)
+	for pn := int64(0); pn < table.pager.GetNumPages(); pn++ {
+		bucket, err := table.GetAndLockBucketByPN(pn, READ_LOCK)
+		if err != nil {
+			return 0, err
+		}
+		count += bucket.numKeys
+		bucket.RUnlock()
+		table.pager.PutPage(bucket.page)
+	}
+	return count, nil
+}
+
+// Select all entries in this table, ordered by their keys.
+// The same set of entries is always returned in the same order, however the table was built.
+func (table *HashTable) SelectSorted() ([]entry.Entry, error) {
+	ret, err := table.Select()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(ret, func(a, b entry.Entry) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return ret, nil
+}
+
+// SelectSortedPage returns up to limit entries ordered by their keys, starting after afterKey if hasAfter is set
+// or at the smallest key otherwise, and whether there are more entries after the page. Pass the last key of
+// one page as the next page's afterKey to paginate through SelectSorted.
+//
+// Each page is read from one snapshot, since Select holds the table's read lock for its whole scan, which keeps
+// splits and merges from moving entries between buckets while it runs. Pages are read from separate snapshots, but
+// since they resume by key rather than by position, a pass sees every key present throughout it exactly once,
+// in order, however the buckets split in between. Keys inserted or deleted during the pass may or may not be seen.
+func (table *HashTable) SelectSortedPage(afterKey int64, hasAfter bool, limit int) (page []entry.Entry, more bool, err error) {
+	if limit <= 0 {
+		return nil, false, errors.New("limit must be positive")
+	}
+	entries, err := table.Select()
+	if err != nil {
+		return nil, false, err
+	}
+	if hasAfter {
+		entries = slices.DeleteFunc(entries, func(e entry.Entry) bool {
+			return e.Key <= afterKey
+		})
+	}
+	slices.SortFunc(entries, func(a, b entry.Entry) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	if len(entries) > limit {
+		return entries[:limit], true, nil
+	}
+	return entries, false, nil
+}
+
+// Pass every entry in this table to fn in slices of chunkSize entries (the last may be smaller).
+// The table and its buckets are only locked while being read, never while fn runs,
+// so changes made between chunks may or may not be reflected in later chunks.
+func (table *HashTable) SelectChunks(chunkSize int, fn func([]entry.Entry) error) error {
+	if chunkSize <= 0 {
+		return errors.New("chunk size must be positive")
+	}
+	pending := make([]entry.Entry, 0, chunkSize)
+	for pn := int64(0); ; pn++ {
+		table.RLock()
+		if pn >= table.pager.GetNumPages() {
+			table.RUnlock()
+			break
+		}
+		bucket, err := table.GetAndLockBucketByPN(pn, READ_LOCK)
+		if err != nil {
+			table.RUnlock()
+			return err
+		}
+		entries, err := bucket.Select()
+		bucket.RUnlock()
+		table.pager.PutPage(bucket.GetPage())
+		table.RUnlock()
+		if err != nil {
+			return err
+		}
+		pending = append(pending, entries...)
+		for len(pending) >= chunkSize {
+			if err := fn(pending[:chunkSize]); err != nil {
+				return err
+			}
+			pending = append(make([]entry.Entry, 0, chunkSize), pending[chunkSize:]...)
+		}
+	}
+	if len(pending) > 0 {
+		return fn(pending)
+	}
+	return nil
+}
+
 // Print writes a string representation of this entire table (including it's buckets) to the specified writer.
 func (table *HashTable) Print(w io.Writer) {
 	table.RLock()
@@ -272,6 +578,24 @@ func (table *HashTable) WUnlock() {
 	table.rwlock.Unlock()
 }
 
+// Warmup reads the table's buckets into the pager's buffer in page order, stopping once
+// the buffer is full. Buckets are not left pinned after being loaded.
+func (table *HashTable) Warmup() error {
+	table.RLock()
+	defer table.RUnlock()
+	numPages := min(table.pager.GetNumPages(), table.pager.GetBufferSize())
+	for pn := int64(0); pn < numPages; pn++ {
+		page, err := table.pager.GetPage(pn)
+		if errors.Is(err, pager.ErrRanOutOfPages) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		table.pager.PutPage(page)
+	}
+	return nil
+}
+
 // [CONCURRENCY] Grab a read lock on the hash table index
 func (table *HashTable) RLock() {
 	table.rwlock.RLock()
@@ -310,6 +634,37 @@ func (table *HashTable) GetAndLockBucketByPN(pn int64, lock BucketLockType) (*Ha
 	return pageToBucket(page), nil
 }
 
+// newBucket returns a new bucket with the given local depth, reusing a page freed by merging if there is one.
+func (table *HashTable) newBucket(depth int64) (*HashBucket, error) {
+	n := len(table.freeBuckets)
+	if n == 0 {
+		return newHashBucket(table.pager, depth)
+	}
+	bucket, err := table.GetBucketByPN(table.freeBuckets[n-1])
+	if err != nil {
+		return nil, err
+	}
+	table.freeBuckets = table.freeBuckets[:n-1]
+	bucket.updateNumKeys(0)
+	bucket.updateLocalDepth(depth)
+	return bucket, nil
+}
+
+// unusedPages returns the page numbers of the bucket pages that no slot in the directory points to.
+func (table *HashTable) unusedPages() []int64 {
+	used := make(map[int64]bool, len(table.buckets))
+	for _, pn := range table.buckets {
+		used[pn] = true
+	}
+	unused := make([]int64, 0)
+	for pn := int64(0); pn < table.pager.GetNumPages(); pn++ {
+		if !used[pn] {
+			unused = append(unused, pn)
+		}
+	}
+	return unused
+}
+
 // Returns the bucket in the hash table, and increments the bucket ref count.
 func (table *HashTable) GetBucket(hash int64) (*HashBucket, error) {
 	pagenum := table.buckets[hash]
@@ -331,7 +686,9 @@ func (table *HashTable) GetAndLockBucket(hash int64, lock BucketLockType) (*Hash
 }
 
 // Read hash table in from memory.
-func ReadHashTable(bucketPager *pager.Pager) (*HashTable, error) {
+// If hasher is nil, the registered hasher the table was created with is used;
+// otherwise, returns ErrHasherMismatch if the given hasher isn't the one the table was created with.
+func ReadHashTable(bucketPager *pager.Pager, hasher HasherFunc) (*HashTable, error) {
 	backingFilename := bucketPager.GetFileName() + ".meta"
 	indexPager, err := pager.New(backingFilename)
 	if err != nil {
@@ -344,13 +701,27 @@ func ReadHashTable(bucketPager *pager.Pager) (*HashTable, error) {
 	}
 	// Read the gobal depth
 	depth, _ := binary.Varint(metaPage.GetData()[:DEPTH_SIZE])
-	bytesRead := DEPTH_SIZE
+	// Read the hasher id and resolve/check the hasher
+	hasherId, _ := binary.Varint(metaPage.GetData()[HASHER_ID_OFFSET : HASHER_ID_OFFSET+HASHER_ID_SIZE])
+	if hasher == nil {
+		var found bool
+		if hasher, found = lookupHasher(hasherId); !found {
+			indexPager.PutPage(metaPage)
+			indexPager.Close()
+			return nil, errors.New("table was created with an unregistered hasher")
+		}
+	} else if hasherID(hasher) != hasherId {
+		indexPager.PutPage(metaPage)
+		indexPager.Close()
+		return nil, ErrHasherMismatch
+	}
+	bytesRead := META_HEADER_SIZE
 	// Read the bucket index
 	pnSize := int64(binary.MaxVarintLen64)
 	numHashes := powInt(2, depth)
 	buckets := make([]int64, numHashes)
 	for i := int64(0); i < numHashes; i++ {
-		if bytesRead+pnSize > PAGESIZE {
+		if bytesRead+pnSize > indexPager.GetPageSize() {
 			indexPager.PutPage(metaPage)
 			metaPN++
 			metaPage, err = indexPager.GetPage(metaPN)
@@ -365,17 +736,35 @@ func ReadHashTable(bucketPager *pager.Pager) (*HashTable, error) {
 	}
 	indexPager.PutPage(metaPage)
 	indexPager.Close()
-	return &HashTable{globalDepth: depth, buckets: buckets, pager: bucketPager}, nil
+	table := &HashTable{globalDepth: depth, buckets: buckets, pager: bucketPager, hasher: hasher}
+	table.freeBuckets = table.unusedPages()
+	return table, nil
 }
 
 // Write hash table out to memory.
 func WriteHashTable(bucketPager *pager.Pager, table *HashTable) error {
+	if err := writeHashTableMeta(bucketPager, table); err != nil {
+		return err
+	}
+	return bucketPager.Close()
+}
+
+// writeHashTableMeta writes the table's global depth, hasher id, and bucket directory to its .meta file.
+func writeHashTableMeta(bucketPager *pager.Pager, table *HashTable) error {
 	backingFilename := bucketPager.GetFileName() + ".meta"
 	indexPager, err := pager.New(backingFilename)
 	if err != nil {
 		return err
 	}
-	metaPage, err := indexPager.GetNewPage()
+	// Overwrite the meta file's pages in place, only allocating new ones if the directory grew
+	metaPN := int64(0)
+	getMetaPage := func() (*pager.Page, error) {
+		if metaPN < indexPager.GetNumPages() {
+			return indexPager.GetPage(metaPN)
+		}
+		return indexPager.GetNewPage()
+	}
+	metaPage, err := getMetaPage()
 	if err != nil {
 		return err
 	}
@@ -384,14 +773,19 @@ func WriteHashTable(bucketPager *pager.Pager, table *HashTable) error {
 	depthData := make([]byte, DEPTH_SIZE)
 	binary.PutVarint(depthData, table.globalDepth)
 	metaPage.Update(depthData, DEPTH_OFFSET, DEPTH_SIZE)
-	bytesWritten := DEPTH_SIZE
+	// Write the hasher id to meta file
+	hasherIdData := make([]byte, HASHER_ID_SIZE)
+	binary.PutVarint(hasherIdData, hasherID(table.hasher))
+	metaPage.Update(hasherIdData, HASHER_ID_OFFSET, HASHER_ID_SIZE)
+	bytesWritten := META_HEADER_SIZE
 	// Write bucket index to meta file
 	pnSize := int64(binary.MaxVarintLen64)
 	pnData := make([]byte, pnSize)
 	for _, pn := range table.buckets {
-		if bytesWritten+pnSize > PAGESIZE {
+		if bytesWritten+pnSize > indexPager.GetPageSize() {
 			indexPager.PutPage(metaPage)
-			metaPage, err = indexPager.GetNewPage()
+			metaPN++
+			metaPage, err = getMetaPage()
 			if err != nil {
 				return err
 			}
@@ -403,8 +797,7 @@ func WriteHashTable(bucketPager *pager.Pager, table *HashTable) error {
 		bytesWritten += pnSize
 	}
 	indexPager.PutPage(metaPage)
-	indexPager.Close()
-	return bucketPager.Close()
+	return indexPager.Close()
 }
 
 // x^y
@@ -2,11 +2,25 @@ package hash
 
 import (
 	"encoding/binary"
+	"sync"
 
 	"github.com/cespare/xxhash"
 	"github.com/spaolacci/murmur3"
 )
 
+// HasherFunc maps a key to a bucket index in the range [0, 2^depth).
+type HasherFunc func(key int64, depth int64) int64
+
+// registeredHashers maps hasher ids to hashers, so that a table can be reopened
+// with the hasher it was created with without the caller passing it back in.
+var registeredHashers = map[int64]HasherFunc{}
+var registeredHashersMtx sync.RWMutex
+
+func init() {
+	RegisterHasher(Hasher)
+	RegisterHasher(MurmurDepthHasher)
+}
+
 // getHash uses the given hasher function to calculate and return
 // the hash of a key modded by the size.
 func getHash(hasher func(b []byte) uint64, key int64, size int64) uint {
@@ -30,6 +44,43 @@ func MurmurHasher(key int64, size int64) uint {
 }
 
 // Hasher returns the hash of a key, modded by 2^depth.
+// This is the default hasher used by hash tables.
 func Hasher(key int64, depth int64) int64 {
 	return int64(XxHasher(key, powInt(2, depth)))
 }
+
+// MurmurDepthHasher returns the MurmurHash3 hash of a key, modded by 2^depth.
+func MurmurDepthHasher(key int64, depth int64) int64 {
+	return int64(MurmurHasher(key, powInt(2, depth)))
+}
+
+// RegisterHasher makes the given hasher available when reopening tables that
+// were created with it, and returns the id that the hasher is persisted under.
+func RegisterHasher(hasher HasherFunc) int64 {
+	id := hasherID(hasher)
+	registeredHashersMtx.Lock()
+	defer registeredHashersMtx.Unlock()
+	registeredHashers[id] = hasher
+	return id
+}
+
+// lookupHasher returns the registered hasher with the given id, if any.
+func lookupHasher(id int64) (HasherFunc, bool) {
+	registeredHashersMtx.RLock()
+	defer registeredHashersMtx.RUnlock()
+	hasher, ok := registeredHashers[id]
+	return hasher, ok
+}
+
+// hasherID fingerprints a hasher by the buckets it assigns to a fixed set of probe keys.
+// Two hashers that route keys differently will (with overwhelming probability) get different ids.
+func hasherID(hasher HasherFunc) int64 {
+	const numProbes = 64
+	const probeDepth = 16
+	buf := make([]byte, 0, numProbes*binary.MaxVarintLen64)
+	for i := int64(0); i < numProbes; i++ {
+		probe := i * 0x3C6EF372FE94F82B
+		buf = binary.AppendVarint(buf, hasher(probe, probeDepth))
+	}
+	return int64(xxhash.Sum64(buf) >> 1)
+}
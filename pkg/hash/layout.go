@@ -0,0 +1,67 @@
+package hash
+
+// PageRole describes what a page in a hash table's file is used for.
+type PageRole string
+
+const (
+	BUCKET_PAGE PageRole = "bucket"
+	FREE_PAGE   PageRole = "free"
+)
+
+// PageInfo summarizes a single page of a hash table's file.
+// The directory isn't included, since it lives in the separate .meta file.
+type PageInfo struct {
+	PN         int64    // The page number.
+	Role       PageRole // What the page is used for. Pages no directory slot points to are free.
+	LocalDepth int64    // For buckets, the bucket's local depth.
+	NumKeys    int64    // For buckets, the number of entries in the bucket.
+	NumSlots   int64    // For buckets, the number of directory slots pointing to the bucket.
+}
+
+// Layout classifies every page of the table's file in page number order, without reading the buckets' entries.
+func (table *HashTable) Layout() ([]PageInfo, error) {
+	table.RLock()
+	defer table.RUnlock()
+	slots := make(map[int64]int64)
+	for _, pn := range table.buckets {
+		slots[pn]++
+	}
+	layout := make([]PageInfo, 0, table.pager.GetNumPages())
+	for pn := int64(0); pn < table.pager.GetNumPages(); pn++ {
+		if slots[pn] == 0 {
+			layout = append(layout, PageInfo{PN: pn, Role: FREE_PAGE})
+			continue
+		}
+		bucket, err := table.GetAndLockBucketByPN(pn, READ_LOCK)
+		if err != nil {
+			return nil, err
+		}
+		layout = append(layout, PageInfo{
+			PN:         pn,
+			Role:       BUCKET_PAGE,
+			LocalDepth: bucket.localDepth,
+			NumKeys:    bucket.numKeys,
+			NumSlots:   slots[pn],
+		})
+		bucket.RUnlock()
+		table.pager.PutPage(bucket.page)
+	}
+	return layout, nil
+}
+
+// DepthCounts returns the table's global depth and the number of buckets with each local depth.
+func (table *HashTable) DepthCounts() (globalDepth int64, counts map[int64]int64, err error) {
+	layout, err := table.Layout()
+	if err != nil {
+		return 0, nil, err
+	}
+	counts = make(map[int64]int64)
+	for _, info := range layout {
+		if info.Role == BUCKET_PAGE {
+			counts[info.LocalDepth]++
+		}
+	}
+	table.RLock()
+	defer table.RUnlock()
+	return table.globalDepth, counts, nil
+}
@@ -0,0 +1,163 @@
+package hash
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"dinodb/pkg/pager"
+)
+
+// ErrUnrebuildableDirectory is returned when a table's bucket pages don't fit together into a directory.
+var ErrUnrebuildableDirectory = errors.New("bucket pages don't form a directory")
+
+// The deepest local depth a rebuilt directory allows, so that a corrupted depth can't overflow the directory size.
+const MAX_REBUILD_DEPTH int64 = 32
+
+// rebuiltBucket is a bucket page found while rebuilding a directory.
+type rebuiltBucket struct {
+	pn    int64 // The bucket's page number.
+	depth int64 // The bucket's local depth.
+	slot  int64 // The directory slot its entries hash to at its local depth, or -1 if it is empty.
+}
+
+// RebuildDirectory reconstructs a table's directory from its bucket pages, for when its .meta file is lost.
+// Keys are routed with the default Hasher; use RebuildDirectoryWithHasher for tables created with another one.
+func RebuildDirectory(pager *pager.Pager) (*HashTable, error) {
+	return RebuildDirectoryWithHasher(pager, Hasher)
+}
+
+// RebuildDirectoryWithHasher reconstructs a table's directory from its bucket pages, routing keys with the given hasher.
+// The global depth is the deepest local depth, and each bucket is pointed to by the slots its entries hash to.
+// Empty buckets fill the remaining slots; since they hold no entries, any slots of the right depth will do.
+// Pages freed by merging buckets aren't placed, and are reused by later splits.
+// Returns an ErrUnrebuildableDirectory if the buckets overlap or leave slots uncovered.
+func RebuildDirectoryWithHasher(pager *pager.Pager, hasher HasherFunc) (*HashTable, error) {
+	table := &HashTable{pager: pager, hasher: hasher}
+	found, err := table.scanBuckets()
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%w: the table has no bucket pages", ErrUnrebuildableDirectory)
+	}
+	found = slices.DeleteFunc(found, func(b rebuiltBucket) bool {
+		if b.depth == FREE_BUCKET_DEPTH {
+			table.freeBuckets = append(table.freeBuckets, b.pn)
+			return true
+		}
+		return false
+	})
+	// Each bucket covers 1/2^depth of the directory, so together they must cover exactly all of it.
+	for _, b := range found {
+		table.globalDepth = max(table.globalDepth, b.depth)
+	}
+	covered := int64(0)
+	for _, b := range found {
+		covered += powInt(2, table.globalDepth-b.depth)
+	}
+	if covered != powInt(2, table.globalDepth) {
+		return nil, fmt.Errorf("%w: the buckets' local depths don't add up to a directory", ErrUnrebuildableDirectory)
+	}
+	table.buckets = make([]int64, powInt(2, table.globalDepth))
+	for i := range table.buckets {
+		table.buckets[i] = -1
+	}
+	// Place the buckets with entries first, then the empty ones from shallowest to deepest,
+	// so that a shallow bucket is never left without a whole run of free slots.
+	slices.SortStableFunc(found, func(a, b rebuiltBucket) int {
+		if (a.slot < 0) != (b.slot < 0) {
+			if a.slot < 0 {
+				return 1
+			}
+			return -1
+		}
+		return int(a.depth - b.depth)
+	})
+	for _, b := range found {
+		slot := b.slot
+		if slot < 0 {
+			slot = table.freeSlot(b.depth)
+			if slot < 0 {
+				return nil, fmt.Errorf("%w: no free slots for empty bucket %d", ErrUnrebuildableDirectory, b.pn)
+			}
+		}
+		stride := powInt(2, b.depth)
+		for i := slot; i < int64(len(table.buckets)); i += stride {
+			if table.buckets[i] != -1 {
+				return nil, fmt.Errorf("%w: buckets %d and %d both hash to slot %d", ErrUnrebuildableDirectory, table.buckets[i], b.pn, i)
+			}
+			table.buckets[i] = b.pn
+		}
+	}
+	return table, nil
+}
+
+// scanBuckets reads the local depth of every bucket page in the table's file, and the slot its entries hash to.
+func (table *HashTable) scanBuckets() ([]rebuiltBucket, error) {
+	found := make([]rebuiltBucket, 0, table.pager.GetNumPages())
+	for pn := int64(0); pn < table.pager.GetNumPages(); pn++ {
+		bucket, err := table.GetAndLockBucketByPN(pn, NO_LOCK)
+		if err != nil {
+			return nil, err
+		}
+		b := rebuiltBucket{pn: pn, depth: bucket.localDepth, slot: -1}
+		entries, err := bucket.Select()
+		table.pager.PutPage(bucket.page)
+		if err != nil {
+			return nil, err
+		}
+		if (b.depth < 0 && b.depth != FREE_BUCKET_DEPTH) || b.depth > MAX_REBUILD_DEPTH {
+			return nil, fmt.Errorf("%w: bucket %d has local depth %d", ErrUnrebuildableDirectory, pn, b.depth)
+		}
+		for _, e := range entries {
+			slot := table.hasher(e.Key, b.depth)
+			if b.slot >= 0 && slot != b.slot {
+				return nil, fmt.Errorf("%w: bucket %d holds keys that hash to different slots", ErrUnrebuildableDirectory, pn)
+			}
+			b.slot = slot
+		}
+		found = append(found, b)
+	}
+	return found, nil
+}
+
+// freeSlot returns the first slot whose every slot of the given depth is free, or -1 if there isn't one.
+func (table *HashTable) freeSlot(depth int64) int64 {
+	stride := powInt(2, depth)
+	for slot := int64(0); slot < stride; slot++ {
+		free := true
+		for i := slot; i < int64(len(table.buckets)); i += stride {
+			if table.buckets[i] != -1 {
+				free = false
+				break
+			}
+		}
+		if free {
+			return slot
+		}
+	}
+	return -1
+}
+
+// RepairTable reopens the table in the given file by rebuilding its directory from its bucket pages
+// with the default Hasher, then writes the rebuilt directory to a new .meta file.
+func RepairTable(filename string) (*HashIndex, error) {
+	bucketPager, err := pager.New(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err = bucketPager.RequireFeature(pager.VERSIONED_ENTRIES_FLAG); err != nil {
+		bucketPager.Close()
+		return nil, err
+	}
+	table, err := RebuildDirectory(bucketPager)
+	if err == nil {
+		err = writeHashTableMeta(bucketPager, table)
+	}
+	if err != nil {
+		bucketPager.Close()
+		return nil, err
+	}
+	return &HashIndex{table: table, pager: bucketPager}, nil
+}
@@ -6,19 +6,20 @@ func IsHash(index *HashIndex) (bool, error) {
 	for _, pn := range buckets {
 		// Get bucket
 		bucket, err := table.GetAndLockBucketByPN(pn, NO_LOCK)
-		d := bucket.GetDepth()
 		if err != nil {
 			return false, err
 		}
+		d := bucket.GetDepth()
 		// Get all entries
 		entries, err := bucket.Select()
+		table.pager.PutPage(bucket.page)
 		if err != nil {
 			return false, err
 		}
 		// Check that all entries should hash to this bucket.
 		for _, e := range entries {
 			key := e.Key
-			hash := Hasher(key, d)
+			hash := table.hasher(key, d)
 			if pn != table.buckets[hash] {
 				return false, nil
 			}
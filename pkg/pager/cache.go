@@ -0,0 +1,98 @@
+package pager
+
+import (
+	"errors"
+	"fmt"
+
+	"dinodb/pkg/list"
+)
+
+// CachePolicy controls when a pager writes modified pages back to its file.
+type CachePolicy int
+
+const (
+	// Modified pages are written when they are evicted or flushed.
+	WRITE_BACK CachePolicy = iota
+	// Modified pages are written as soon as they are unpinned, i.e. at the end of each operation.
+	WRITE_THROUGH
+	// Like WRITE_THROUGH, but the file is also synced after each write.
+	WRITE_THROUGH_SYNC
+)
+
+// Error for when a string doesn't name a cache policy.
+var ErrUnknownCachePolicy = errors.New("unknown cache policy")
+
+// ParseCachePolicy returns the cache policy with the given name.
+func ParseCachePolicy(s string) (CachePolicy, error) {
+	for _, policy := range []CachePolicy{WRITE_BACK, WRITE_THROUGH, WRITE_THROUGH_SYNC} {
+		if policy.String() == s {
+			return policy, nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q, expected write_back, write_through, or write_through_sync", ErrUnknownCachePolicy, s)
+}
+
+// String returns the cache policy's name, as accepted by ParseCachePolicy.
+func (policy CachePolicy) String() string {
+	switch policy {
+	case WRITE_BACK:
+		return "write_back"
+	case WRITE_THROUGH:
+		return "write_through"
+	case WRITE_THROUGH_SYNC:
+		return "write_through_sync"
+	default:
+		return fmt.Sprintf("CachePolicy(%d)", int(policy))
+	}
+}
+
+// GetCachePolicy returns the pager's cache policy.
+func (pager *Pager) GetCachePolicy() CachePolicy {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.cachePolicy
+}
+
+// SetCachePolicy changes the pager's cache policy. Switching to a write-through policy
+// flushes the pages that are already dirty but unpinned, so that none are left behind.
+func (pager *Pager) SetCachePolicy(policy CachePolicy) error {
+	if _, err := ParseCachePolicy(policy.String()); err != nil {
+		return err
+	}
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.cachePolicy = policy
+	if policy == WRITE_BACK {
+		return nil
+	}
+	var err error
+	pager.unpinnedList.Map(func(link *list.Link) {
+		if err == nil {
+			err = pager.writeThrough(link.GetValue().(*Page))
+		}
+	})
+	return err
+}
+
+// writeThrough writes an unpinned page and any superblock changes to disk, syncing the file
+// if the cache policy asks for it. Expects ptMtx to be locked.
+func (pager *Pager) writeThrough(page *Page) error {
+	if !page.IsDirty() && !pager.superblockDirty {
+		return nil
+	}
+	if page.IsDirty() {
+		if _, err := pager.writeAt(page.data, pager.pageOffset(page.pagenum)); err != nil {
+			return err
+		}
+		page.SetDirty(false)
+	}
+	if pager.superblockDirty {
+		if err := pager.writeSuperblock(); err != nil {
+			return err
+		}
+	}
+	if pager.cachePolicy == WRITE_THROUGH_SYNC {
+		return pager.file.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package pager
+
+import (
+	"errors"
+	"time"
+)
+
+// Error for when a page is requested from a pager that is closing or closed
+var ErrClosing = errors.New("pager is closing")
+
+// DEFAULT_CLOSE_WAIT is how long Close waits for pinned pages to be put before giving up, unless changed with SetCloseWait.
+const DEFAULT_CLOSE_WAIT = time.Second
+
+// SetCloseWait sets how long Close waits for operations to put their pinned pages before failing.
+func (pager *Pager) SetCloseWait(wait time.Duration) {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.closeWait = wait
+}
+
+// checkClosing returns ErrClosing if the pager is closed, or if it is closing and no operation has a page pinned.
+// Pages are still handed out while others are pinned, so that operations already underway can finish.
+// Expects ptMtx to be locked.
+func (pager *Pager) checkClosing() error {
+	if pager.closed || (pager.closing && pager.numPinned == 0) {
+		return ErrClosing
+	}
+	return nil
+}
+
+// waitForUnpinned marks the pager as closing, then waits up to its close wait for every pinned page to be put.
+// Returns an error if pages are still pinned, leaving the pager open for use,
+// or ErrClosing if the pager is already closed. Expects ptMtx to be locked.
+func (pager *Pager) waitForUnpinned() error {
+	pager.closing = true
+	deadline := time.Now().Add(pager.closeWait)
+	for !pager.closed && pager.numPinned > 0 {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			pager.closing = false
+			return errors.New("pages are still pinned on close")
+		}
+		// sync.Cond can't time out, so wake the waiter once the deadline passes.
+		timer := time.AfterFunc(remaining, func() {
+			pager.ptMtx.Lock()
+			defer pager.ptMtx.Unlock()
+			pager.unpinnedCond.Broadcast()
+		})
+		pager.unpinnedCond.Wait()
+		timer.Stop()
+	}
+	// Another Close or ForceClose may have closed the file while this one waited.
+	if pager.closed {
+		return ErrClosing
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !faultinject
+
+package pager
+
+// writeFile writes data to the pager's file at the given offset.
+func (pager *Pager) writeFile(data []byte, off int64) (int, error) {
+	return pager.file.WriteAt(data, off)
+}
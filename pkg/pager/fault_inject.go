@@ -0,0 +1,90 @@
+//go:build faultinject
+
+package pager
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/ncw/directio"
+)
+
+// Error for a write that a FaultyWriter cut short.
+// Only exists when built with the faultinject tag.
+var ErrInjectedFault = errors.New("injected write fault")
+
+// FaultyWriter simulates a crash partway through a write. Writes go through untouched until one would
+// take the total number of bytes written past the limit; that write is torn at the limit, and it and
+// every write after it fail with ErrInjectedFault. Only exists when built with the faultinject tag.
+type FaultyWriter struct {
+	mtx       sync.Mutex
+	remaining int64 // How many more bytes can be written before the fault.
+	tripped   bool  // Whether a write has been torn.
+}
+
+// NewFaultyWriter returns a FaultyWriter that tears the write crossing the limit'th byte.
+func NewFaultyWriter(limit int64) *FaultyWriter {
+	return &FaultyWriter{remaining: limit}
+}
+
+// Tripped returns whether a write has been torn.
+func (w *FaultyWriter) Tripped() bool {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.tripped
+}
+
+// Cut returns how many of a write's n bytes reach the disk, and ErrInjectedFault if that isn't all of them.
+func (w *FaultyWriter) Cut(n int) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.tripped {
+		return 0, ErrInjectedFault
+	}
+	if int64(n) <= w.remaining {
+		w.remaining -= int64(n)
+		return n, nil
+	}
+	w.tripped = true
+	return int(w.remaining), ErrInjectedFault
+}
+
+// The faulty writers set on each pager.
+var faultyWriters sync.Map
+
+// SetFaultyWriter makes the pager's writes to its file go through w, or directly to the file if w is nil.
+// Only exists when built with the faultinject tag.
+func (pager *Pager) SetFaultyWriter(w *FaultyWriter) {
+	if w == nil {
+		faultyWriters.Delete(pager)
+		return
+	}
+	faultyWriters.Store(pager, w)
+}
+
+// writeFile writes data to the pager's file at the given offset, through the pager's faulty writer if it has one.
+// A torn write keeps the start of data and the end of what was on disk, like a page that was only partly written.
+func (pager *Pager) writeFile(data []byte, off int64) (int, error) {
+	w, ok := faultyWriters.Load(pager)
+	if !ok {
+		return pager.file.WriteAt(data, off)
+	}
+	keep, fault := w.(*FaultyWriter).Cut(len(data))
+	if keep == len(data) {
+		return pager.file.WriteAt(data, off)
+	}
+	if keep == 0 {
+		return 0, fault
+	}
+	// The file may be opened for direct I/O, so the torn block is written whole.
+	torn := directio.AlignedBlock(len(data))
+	if _, err := pager.file.ReadAt(torn, off); err != nil && err != io.EOF {
+		return 0, err
+	}
+	copy(torn, data[:keep])
+	if _, err := pager.file.WriteAt(torn, off); err != nil {
+		return 0, err
+	}
+	return keep, fault
+}
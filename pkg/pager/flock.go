@@ -0,0 +1,20 @@
+//go:build unix
+
+package pager
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive advisory lock on the given file without blocking,
+// returning ErrDatabaseLocked if another open file already holds it.
+// The lock is released when the file is closed.
+func lockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrDatabaseLocked
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package pager
+
+import "os"
+
+// lockFile is a no-op on platforms without flock; files are not protected from being opened twice.
+func lockFile(file *os.File) error {
+	return nil
+}
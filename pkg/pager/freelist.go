@@ -0,0 +1,64 @@
+package pager
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// A freed page stores the page number of the next page on the file's free list at its start,
+// or NoPage if it is the last one. The superblock records the head of the list.
+const (
+	freeNextOffset int64 = 0
+	freeNextSize   int64 = binary.MaxVarintLen64
+)
+
+// FreePage pushes the given page onto the file's free list, so that GetNewPage hands its pagenum out again
+// instead of growing the file. The page's data is overwritten. The caller must have the page pinned,
+// and should put it without reading or writing it again; nothing else may reach the page once it is freed.
+func (pager *Pager) FreePage(page *Page) {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	clear(page.data)
+	binary.PutVarint(page.data[freeNextOffset:freeNextOffset+freeNextSize], pager.freeListHead)
+	page.SetDirty(true)
+	pager.freeListHead = page.pagenum
+	pager.superblockDirty = true
+}
+
+// reuseFreePage pops the page at the head of the free list, returning it pinned with its data zeroed.
+// The ptMtx should be locked on entry.
+func (pager *Pager) reuseFreePage() (*Page, error) {
+	page, _, err := pager.pinPage(pager.freeListHead)
+	if err != nil {
+		return nil, err
+	}
+	next, _ := binary.Varint(page.data[freeNextOffset : freeNextOffset+freeNextSize])
+	if next < NoPage || next >= pager.numPages || next == page.pagenum {
+		pager.unpinPage(page)
+		return nil, fmt.Errorf("%w: free page %d links to page %d", ErrCorruptFile, page.pagenum, next)
+	}
+	pager.freeListHead = next
+	pager.superblockDirty = true
+	clear(page.data)
+	page.SetDirty(true)
+	return page, nil
+}
+
+// GetNumFreePages returns the number of pages on the file's free list, reading each of them.
+func (pager *Pager) GetNumFreePages() (int64, error) {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	numFree := int64(0)
+	for pn := pager.freeListHead; pn != NoPage; numFree++ {
+		if pn < 0 || pn >= pager.numPages || numFree >= pager.numPages {
+			return 0, fmt.Errorf("%w: free list is broken at page %d", ErrCorruptFile, pn)
+		}
+		page, _, err := pager.pinPage(pn)
+		if err != nil {
+			return 0, err
+		}
+		pn, _ = binary.Varint(page.data[freeNextOffset : freeNextOffset+freeNextSize])
+		pager.unpinPage(page)
+	}
+	return numFree, nil
+}
@@ -0,0 +1,18 @@
+//go:build !latchdebug
+
+package pager
+
+// [CONCURRENCY] Grab a writers lock on the page.
+func (page *Page) WLock() {
+	page.rwlock.Lock()
+}
+
+// [CONCURRENCY] Grab a readers lock on the page.
+func (page *Page) RLock() {
+	page.rwlock.RLock()
+}
+
+// [CONCURRENCY] Grab a readers lock on the page if it isn't write-locked, returning whether it was grabbed.
+func (page *Page) TryRLock() bool {
+	return page.rwlock.TryRLock()
+}
@@ -0,0 +1,65 @@
+//go:build latchdebug
+
+package pager
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// How long a page lock can be waited on before LatchTimeoutHandler is called.
+// Only exists when built with the latchdebug tag.
+var LatchTimeout = 5 * time.Second
+
+// Called with a diagnostic report (including every goroutine's stack trace)
+// when a page lock has been waited on for longer than LatchTimeout. Logs the report by default.
+var LatchTimeoutHandler = func(report string) {
+	log.Print(report)
+}
+
+// The number of page locks that have been taken, of either kind.
+// Only exists when built with the latchdebug tag.
+var LatchAcquisitions atomic.Int64
+
+// [CONCURRENCY] Grab a writers lock on the page, reporting if it takes longer than LatchTimeout.
+func (page *Page) WLock() {
+	page.timedLock("write", page.rwlock.TryLock, page.rwlock.Lock)
+	LatchAcquisitions.Add(1)
+}
+
+// [CONCURRENCY] Grab a readers lock on the page, reporting if it takes longer than LatchTimeout.
+func (page *Page) RLock() {
+	page.timedLock("read", page.rwlock.TryRLock, page.rwlock.RLock)
+	LatchAcquisitions.Add(1)
+}
+
+// [CONCURRENCY] Grab a readers lock on the page if it isn't write-locked, returning whether it was grabbed.
+func (page *Page) TryRLock() bool {
+	if !page.rwlock.TryRLock() {
+		return false
+	}
+	LatchAcquisitions.Add(1)
+	return true
+}
+
+// timedLock polls tryLock until it succeeds. If LatchTimeout passes first, it reports
+// the goroutines' stack traces and then blocks on lock, since the wait is probably a deadlock.
+func (page *Page) timedLock(kind string, tryLock func() bool, lock func()) {
+	start := time.Now()
+	backoff := time.Microsecond
+	for !tryLock() {
+		if time.Since(start) >= LatchTimeout {
+			buf := make([]byte, 1<<20)
+			buf = buf[:runtime.Stack(buf, true)]
+			LatchTimeoutHandler(fmt.Sprintf("pager: waited over %v for a %s lock on page %d of %s; goroutines:\n%s",
+				LatchTimeout, kind, page.pagenum, page.pager.GetFileName(), buf))
+			lock()
+			return
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, time.Millisecond)
+	}
+}
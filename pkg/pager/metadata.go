@@ -0,0 +1,25 @@
+package pager
+
+import "time"
+
+// TableMetadata describes a table and the file that stores it.
+type TableMetadata struct {
+	IndexType     string       // The kind of index that stores the table, e.g. "btree".
+	CreatedAt     time.Time    // When the table's file was created, or the zero time if that wasn't recorded.
+	FormatVersion int64        // The superblock format version of the table's file.
+	Features      FeatureFlags // The optional features the table's file uses.
+	NumEntries    int64        // The number of entries in the table.
+	NumPages      int64        // The number of pages in the table's file.
+}
+
+// Metadata returns the metadata recorded in the pager's superblock, with the given index type and entry count.
+func (pager *Pager) Metadata(indexType string, numEntries int64) TableMetadata {
+	return TableMetadata{
+		IndexType:     indexType,
+		CreatedAt:     pager.GetCreatedAt(),
+		FormatVersion: pager.GetFormatVersion(),
+		Features:      pager.GetFeatureFlags(),
+		NumEntries:    numEntries,
+		NumPages:      pager.GetNumPages(),
+	}
+}
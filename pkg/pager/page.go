@@ -15,7 +15,7 @@ type Page struct {
 	pinCount atomic.Int64 // The number of active references to this page
 	dirty    bool         // Flag on whether the page's data has changed and needs to be written to disk
 	rwlock   sync.RWMutex // Reader-writer lock on the page struct itself
-	data     []byte       // Serialized data (the actual 4096 bytes of the page)
+	data     []byte       // Serialized data (the actual bytes of the page, as many as the pager's page size)
 }
 
 // GetPager returns the pager this page belongs to.
@@ -36,6 +36,9 @@ func (page *Page) IsDirty() bool {
 // SetDirty changes the dirty status of a page.
 func (page *Page) SetDirty(dirty bool) {
 	page.dirty = dirty
+	if dirty {
+		page.pager.modified.Store(true)
+	}
 }
 
 // GetData returns the byte data held by the page.
@@ -56,24 +59,17 @@ func (page *Page) Put() int64 {
 // Update updates this page with `size` bytes of the the given data slice at the specified offset.
 func (page *Page) Update(data []byte, offset int64, size int64) {
 	page.dirty = true
+	page.pager.modified.Store(true)
 	copy(page.data[offset:offset+size], data)
 }
 
-// [CONCURRENCY] Grab a writers lock on the page.
-func (page *Page) WLock() {
-	page.rwlock.Lock()
-}
+// WLock and RLock are defined in latch.go, or latch_debug.go when built with the latchdebug tag.
 
 // [CONCURRENCY] Release a writers lock.
 func (page *Page) WUnlock() {
 	page.rwlock.Unlock()
 }
 
-// [CONCURRENCY] Grab a readers lock on the page.
-func (page *Page) RLock() {
-	page.rwlock.RLock()
-}
-
 // [CONCURRENCY] Release a readers lock.
 func (page *Page) RUnlock() {
 	page.rwlock.RUnlock()
@@ -2,11 +2,17 @@
 package pager
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
+	"log"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"dinodb/pkg/config"
 	"dinodb/pkg/list"
@@ -14,35 +20,149 @@ import (
 	"github.com/ncw/directio"
 )
 
-// Pagesize is the size of an individual page (ie the maximum number of bytes that the page can hold) - defaults to 4kb.
+// Pagesize is the default size of an individual page (ie the maximum number of bytes that the page can hold) - defaults to 4kb.
+// Pagers can be created with a different page size using NewWithPageSize.
 const Pagesize int64 = directio.BlockSize
 
+// The superblock occupies the first block of every pager's file and stores metadata about the file:
+// the page size that the file was created with, the file's format version, the head of its free list,
+// its feature flags, when it was created, and the id of the comparator its keys are ordered by.
+// Pages are stored after the superblock.
+const (
+	SuperblockSize          int64 = directio.BlockSize
+	superblockMagic               = "DINODBPG"
+	superblockPSOffset      int64 = int64(len(superblockMagic))
+	superblockPSSize        int64 = binary.MaxVarintLen64
+	superblockVersionOffset int64 = superblockPSOffset + superblockPSSize
+	superblockVersionSize   int64 = binary.MaxVarintLen64
+	superblockFreeOffset    int64 = superblockVersionOffset + superblockVersionSize
+	superblockFreeSize      int64 = binary.MaxVarintLen64
+	superblockFlagsOffset   int64 = superblockFreeOffset + superblockFreeSize
+	superblockFlagsSize     int64 = binary.MaxVarintLen64
+	superblockCreatedOffset int64 = superblockFlagsOffset + superblockFlagsSize
+	superblockCreatedSize   int64 = binary.MaxVarintLen64
+	superblockCmpOffset     int64 = superblockCreatedOffset + superblockCreatedSize
+	superblockCmpSize       int64 = binary.MaxVarintLen64
+)
+
+// SuperblockVersion is the format version written to the superblock of pager files.
+// Files written before the superblock recorded a version read as version 0,
+// version 1 files don't record when they were created,
+// and version 2 files don't record a comparator, so their keys are in the default order.
+const SuperblockVersion int64 = 3
+
+// FeatureFlags is a bit set recorded in the superblock, marking which optional features a file uses.
+type FeatureFlags uint64
+
+// VERSIONED_ENTRIES_FLAG marks index files whose entries are stored with a version (see entry.Entry).
+const VERSIONED_ENTRIES_FLAG FeatureFlags = 1 << 0
+
 // Error for when there are no free/unpinned pages to be used
 var ErrRanOutOfPages = errors.New("no available pages")
 
+// Error for when an operation may need to pin more pages than the buffer can hold
+var ErrPinBudgetExceeded = errors.New("operation's pin budget exceeds the buffer size")
+
+// Error for when a pager's file is already open in another pager, possibly in another process
+var ErrDatabaseLocked = errors.New("database is locked by another process")
+
+// Error for when the buffer would be resized to hold fewer pages than are currently pinned
+var ErrBufferTooSmall = errors.New("buffer cannot hold fewer pages than are pinned")
+
+// Error for when a pager's file has been corrupted, e.g. its superblock is missing or invalid
+var ErrCorruptFile = errors.New("DB file has been corrupted")
+
+// Error for when a pager's file isn't a superblock followed by whole pages, e.g. because it was
+// written by a backend that doesn't align its writes to the directio block size
+var ErrUnalignedFile = errors.New("DB file size is not aligned to its page size")
+
+// Error for when an existing file doesn't use a feature that the code opening it requires
+var ErrMissingFeature = errors.New("DB file was written without a required feature")
+
+// Error for when a page size is not a positive multiple of the directio block size
+var ErrInvalidPageSize = errors.New("page size must be a positive multiple of the directio block size")
+
 // Pager is a data structure that manages pages of data stored in a file.
 type Pager struct {
 	file         *os.File   // File descriptor for the file that backs this pager on disk.
+	pagesize     int64      // The size of each of this pager's pages, as recorded in the file's superblock.
 	numPages     int64      // The number of pages that this page has access to (both on disk and in memory).
 	freeList     *list.List // A list of pre-allocated (but unused) pages.
-	unpinnedList *list.List // The list of pages in memory that have yet to be evicted, but are not currently in use.
+	unpinnedList *list.List // The list of pages in memory that have yet to be evicted, but are not currently in use, least recently used first.
 	pinnedList   *list.List // The list of in-memory pages currently being used by the database.
 	// The page table, which maps pagenums to their corresponding pages (stored in a link belonging to the list the page is in).
-	pageTable map[int64]*list.Link
-	ptMtx     sync.Mutex // Mutex for protecting the Page table for concurrent use.
+	pageTable  map[int64]*list.Link
+	ptMtx      sync.Mutex   // Mutex for protecting the Page table for concurrent use.
+	diskReads  atomic.Int64 // The number of pages that have been read in from disk.
+	diskWrites atomic.Int64 // The number of times a page or the superblock has been written to disk.
+	hits       atomic.Int64 // The number of GetPage calls that found their page in the buffer.
+	evictions  atomic.Int64 // The number of pages evicted from the buffer.
+	modified   atomic.Bool  // Whether a page or the superblock has changed since the last TakeModified.
+	numPinned  int64        // The number of pages in the pinned list. Protected by ptMtx.
+	maxPinned  int64        // The most pages that have been in the pinned list at once. Protected by ptMtx.
+	bufSize    int64        // The number of frames in the buffer, across all three lists. Protected by ptMtx.
+	// Superblock metadata, protected by ptMtx. The superblock is rewritten on flush if it is dirty.
+	version         int64         // The format version the file was written with.
+	freeListHead    int64         // The page number at the head of the file's free list, or NoPage if it is empty.
+	flags           FeatureFlags  // The optional features the file uses.
+	createdAt       int64         // When the file was created, in Unix nanoseconds, or 0 if it wasn't recorded.
+	comparatorID    int64         // The id of the comparator the file's keys are ordered by, or 0 for the default order.
+	superblockDirty bool          // Whether the superblock metadata has changed since it was written.
+	cachePolicy     CachePolicy   // When modified pages are written to disk. Protected by ptMtx.
+	pinRetries      int           // How many times to retry getting a page when every frame is pinned. Protected by ptMtx.
+	pinBackoff      time.Duration // How long to wait before the first of those retries. Protected by ptMtx.
+
+	trace atomic.Pointer[PageTrace] // Records page accesses while tracing, or nil.
+
+	// Whether Close has been called, and whether the file has been closed. Protected by ptMtx.
+	closing, closed bool
+	closeWait       time.Duration // How long Close waits for pinned pages to be put. Protected by ptMtx.
+	unpinnedCond    *sync.Cond    // Signalled on ptMtx when the last pinned page is put while closing.
 }
 
 // New constructs a new Pager, backing it with a database file at the specified filePath.
+// New database files use the default Pagesize; existing ones use the page size they were created with.
 // See [*Pager.Open] for more details on backing the Pager with database files.
 func New(filePath string) (pager *Pager, err error) {
-	pager = &Pager{}
+	return newPager(filePath, 0)
+}
+
+// NewWithPageSize constructs a new Pager whose pages are pagesize bytes large,
+// backing it with a database file at the specified filePath.
+// The page size must be a multiple of the directio block size, and must match
+// the page size of the database file if it already exists.
+func NewWithPageSize(filePath string, pagesize int64) (pager *Pager, err error) {
+	if pagesize <= 0 || pagesize%directio.BlockSize != 0 {
+		return nil, ErrInvalidPageSize
+	}
+	return newPager(filePath, pagesize)
+}
+
+// newPager constructs a new Pager with the given page size (or 0 to
+// determine the page size from the database file), then opens it.
+func newPager(filePath string, pagesize int64) (pager *Pager, err error) {
+	pager = &Pager{pagesize: pagesize, version: SuperblockVersion, freeListHead: NoPage, closeWait: DEFAULT_CLOSE_WAIT}
+	pager.unpinnedCond = sync.NewCond(&pager.ptMtx)
 	pager.pageTable = make(map[int64]*list.Link)
 	pager.freeList = list.NewList()
 	pager.unpinnedList = list.NewList()
 	pager.pinnedList = list.NewList()
-	frames := directio.AlignedBlock(int(Pagesize * config.MaxPagesInBuffer))
-	for i := 0; i < config.MaxPagesInBuffer; i++ {
-		frame := frames[i*int(Pagesize) : (i+1)*int(Pagesize)]
+
+	err = pager.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Now that the page size is known, allocate the buffer's frames.
+	pager.allocateFrames(config.MaxPagesInBuffer)
+	return pager, nil
+}
+
+// allocateFrames adds numFrames new frames to the buffer's free list.
+func (pager *Pager) allocateFrames(numFrames int64) {
+	frames := directio.AlignedBlock(int(pager.pagesize * numFrames))
+	for i := int64(0); i < numFrames; i++ {
+		frame := frames[i*pager.pagesize : (i+1)*pager.pagesize]
 		page := Page{
 			pager:   pager,
 			pagenum: NoPage,
@@ -51,12 +171,7 @@ func New(filePath string) (pager *Pager, err error) {
 		}
 		pager.freeList.PushTail(&page)
 	}
-
-	err = pager.Open(filePath)
-	if err != nil {
-		pager = nil
-	}
-	return
+	pager.bufSize += numFrames
 }
 
 // GetFileName returns the file name/path used to open the pager's backing file.
@@ -64,22 +179,214 @@ func (pager *Pager) GetFileName() (filename string) {
 	return pager.file.Name()
 }
 
+// GetPageSize returns the size of each of this pager's pages.
+func (pager *Pager) GetPageSize() int64 {
+	return pager.pagesize
+}
+
 // GetNumPages returns the number of pages.
 func (pager *Pager) GetNumPages() (numPages int64) {
 	return pager.numPages
 }
 
-// GetFreePN returns the next available page number.
+// GetNumDiskReads returns the number of pages this pager has read in from disk.
+func (pager *Pager) GetNumDiskReads() int64 {
+	return pager.diskReads.Load()
+}
+
+// GetNumDiskWrites returns the number of times this pager has written a page or its superblock to disk.
+func (pager *Pager) GetNumDiskWrites() int64 {
+	return pager.diskWrites.Load()
+}
+
+// TakeModified reports whether any of the pager's pages or its superblock have been modified since
+// the last call, and resets it. Checkpoints use this to skip flushing tables that haven't changed.
+func (pager *Pager) TakeModified() bool {
+	return pager.modified.Swap(false)
+}
+
+// MarkModified marks the pager as modified, such as when flushing it after TakeModified failed.
+func (pager *Pager) MarkModified() {
+	pager.modified.Store(true)
+}
+
+// GetNumPinned returns the number of pages that are currently pinned.
+func (pager *Pager) GetNumPinned() int64 {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.numPinned
+}
+
+// GetPinHighWaterMark returns the most pages that have been pinned at the same time.
+func (pager *Pager) GetPinHighWaterMark() int64 {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.maxPinned
+}
+
+// CheckPinBudget returns an ErrPinBudgetExceeded if an operation that may pin up to budget pages
+// could never fit in the buffer, so that it can fail before doing any work rather than partway through.
+// Pages pinned by other operations aren't counted against the budget: they are released as those operations
+// finish, and waiting for them is left to GetPage and GetNewPage (see SetPinRetry).
+func (pager *Pager) CheckPinBudget(budget int64) error {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	if budget > pager.bufSize {
+		return fmt.Errorf("%w: may pin %d pages, but the buffer only holds %d pages; consider growing the buffer",
+			ErrPinBudgetExceeded, budget, pager.bufSize)
+	}
+	return nil
+}
+
+// GetBufferSize returns the number of pages the pager's buffer can hold.
+func (pager *Pager) GetBufferSize() int64 {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.bufSize
+}
+
+// ResizeBuffer grows or shrinks the pager's buffer to hold size pages.
+// Growing allocates new frames. Shrinking frees unused frames first, then evicts unpinned pages
+// (least recently used first, flushing them if they are dirty) and frees their frames.
+// Returns an ErrBufferTooSmall without changing the buffer if more than size pages are pinned.
+func (pager *Pager) ResizeBuffer(size int64) error {
+	if size <= 0 {
+		return errors.New("buffer size must be positive")
+	}
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	if size < pager.numPinned {
+		return fmt.Errorf("%w: %d pages are pinned", ErrBufferTooSmall, pager.numPinned)
+	}
+	if size > pager.bufSize {
+		pager.allocateFrames(size - pager.bufSize)
+		return nil
+	}
+	for pager.bufSize > size {
+		if freeLink := pager.freeList.PeekHead(); freeLink != nil {
+			freeLink.PopSelf()
+		} else {
+			unpinLink := pager.unpinnedList.PeekHead()
+			unpinLink.PopSelf()
+			page := unpinLink.GetValue().(*Page)
+			pager.FlushPage(page)
+			delete(pager.pageTable, page.pagenum)
+			pager.evictions.Add(1)
+		}
+		pager.bufSize--
+	}
+	return nil
+}
+
+// GetFormatVersion returns the format version recorded in the pager's superblock.
+func (pager *Pager) GetFormatVersion() int64 {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.version
+}
+
+// GetCreatedAt returns when the pager's file was created,
+// or the zero time if it was created before the superblock recorded it.
+func (pager *Pager) GetCreatedAt() time.Time {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	if pager.createdAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, pager.createdAt)
+}
+
+// GetFreeListHead returns the page number at the head of the file's free list, or NoPage if it is empty.
+func (pager *Pager) GetFreeListHead() int64 {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.freeListHead
+}
+
+// SetFreeListHead records the page number at the head of the file's free list in the superblock.
+func (pager *Pager) SetFreeListHead(pagenum int64) {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.freeListHead = pagenum
+	pager.superblockDirty = true
+	pager.modified.Store(true)
+}
+
+// GetFeatureFlags returns the feature flags recorded in the pager's superblock.
+func (pager *Pager) GetFeatureFlags() FeatureFlags {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.flags
+}
+
+// SetFeatureFlags records the given feature flags in the superblock, replacing the current ones.
+func (pager *Pager) SetFeatureFlags(flags FeatureFlags) {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.flags = flags
+	pager.superblockDirty = true
+	pager.modified.Store(true)
+}
+
+// GetComparatorID returns the id of the comparator recorded in the pager's superblock.
+func (pager *Pager) GetComparatorID() int64 {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.comparatorID
+}
+
+// SetComparatorID records the id of the comparator the file's keys are ordered by in the superblock.
+// The pager doesn't interpret the id; the index that owns the file maps it to a comparator.
+func (pager *Pager) SetComparatorID(id int64) {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.comparatorID = id
+	pager.superblockDirty = true
+	pager.modified.Store(true)
+}
+
+// RequireFeature marks a file with no pages as using the given feature,
+// or returns an ErrMissingFeature if an existing file doesn't use it.
+func (pager *Pager) RequireFeature(flag FeatureFlags) error {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	if pager.numPages == 0 {
+		pager.flags |= flag
+		pager.superblockDirty = true
+		pager.modified.Store(true)
+		return nil
+	}
+	if pager.flags&flag != flag {
+		return fmt.Errorf("%w: flags %b are missing %b", ErrMissingFeature, pager.flags, flag)
+	}
+	return nil
+}
+
+// pinned records that a page was moved into the pinned list. Expects ptMtx to be locked.
+func (pager *Pager) pinned() {
+	pager.numPinned++
+	pager.maxPinned = max(pager.maxPinned, pager.numPinned)
+}
+
+// GetFreePN returns the page number the next new page will get:
+// the head of the file's free list, or else the first page number beyond the end of the file.
 func (pager *Pager) GetFreePN() (nextPN int64) {
-	// Assign the first page number beyond the end of the file.
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	if pager.freeListHead != NoPage {
+		return pager.freeListHead
+	}
 	return pager.numPages
 }
 
 // Open (re-)initializes our pager with a database file at the specified filePath.
 //
-// If the database file didn't exist previously, it is created.
-// If the database file does exist but it can't be opened or
-// it's contents are not properly aligned to PAGESIZE, returns an error.
+// If the database file didn't exist previously, it is created and its superblock is written.
+// If the database file does exist but it can't be opened, it's superblock is invalid or
+// doesn't match the pager's page size, or it's contents are not properly aligned to
+// the page size, returns an error. An invalid superblock is an ErrCorruptFile,
+// and misaligned contents are an ErrUnalignedFile.
+// If the database file is already open in another pager, in this or another process, returns an ErrDatabaseLocked.
 // The Pager should not be used if an error is returned.
 func (pager *Pager) Open(filePath string) (err error) {
 	// Create the necessary prerequisite directories.
@@ -94,50 +401,174 @@ func (pager *Pager) Open(filePath string) (err error) {
 	if err != nil {
 		return err
 	}
+	// Lock the db file until it is closed, so that no other pager writes to it at the same time.
+	if err = lockFile(pager.file); err != nil {
+		pager.file.Close()
+		return fmt.Errorf("%w: %s", err, filePath)
+	}
+	// Close the file (releasing its lock) if it can't be opened.
+	defer func() {
+		if err != nil {
+			pager.file.Close()
+		}
+	}()
 	// Get info about the size of the pager.
 	var info os.FileInfo
 	var len int64
-	if info, err = pager.file.Stat(); err == nil {
-		len = info.Size()
-		if len%Pagesize != 0 {
-			return errors.New("DB file has been corrupted")
+	if info, err = pager.file.Stat(); err != nil {
+		return err
+	}
+	len = info.Size()
+	// Write the superblock of a new file, or read the superblock of an existing one.
+	if len > 0 && len < SuperblockSize {
+		return fmt.Errorf("%w: file is %d bytes, but its superblock alone is %d bytes", ErrUnalignedFile, len, SuperblockSize)
+	}
+	if len == 0 {
+		if pager.pagesize == 0 {
+			pager.pagesize = Pagesize
 		}
+		pager.createdAt = time.Now().UnixNano()
+		if err = pager.writeSuperblock(); err != nil {
+			return err
+		}
+		len = SuperblockSize
+	} else if err = pager.readSuperblock(); err != nil {
+		return err
+	}
+	if (len-SuperblockSize)%pager.pagesize != 0 {
+		return fmt.Errorf("%w: file is %d bytes, which is not a %d byte superblock followed by %d byte pages",
+			ErrUnalignedFile, len, SuperblockSize, pager.pagesize)
 	}
 	// Set the number of pages and hand off initialization to someone else.
-	pager.numPages = len / Pagesize
+	pager.numPages = (len - SuperblockSize) / pager.pagesize
+	// A closed pager can be reopened.
+	pager.closing, pager.closed = false, false
+	return nil
+}
+
+// writeSuperblock writes the pager's superblock to the start of its file, upgrading it to the current format version.
+func (pager *Pager) writeSuperblock() error {
+	pager.version = SuperblockVersion
+	block := directio.AlignedBlock(int(SuperblockSize))
+	copy(block, superblockMagic)
+	binary.PutVarint(block[superblockPSOffset:superblockPSOffset+superblockPSSize], pager.pagesize)
+	binary.PutVarint(block[superblockVersionOffset:superblockVersionOffset+superblockVersionSize], pager.version)
+	binary.PutVarint(block[superblockFreeOffset:superblockFreeOffset+superblockFreeSize], pager.freeListHead)
+	binary.PutUvarint(block[superblockFlagsOffset:superblockFlagsOffset+superblockFlagsSize], uint64(pager.flags))
+	binary.PutVarint(block[superblockCreatedOffset:superblockCreatedOffset+superblockCreatedSize], pager.createdAt)
+	binary.PutVarint(block[superblockCmpOffset:superblockCmpOffset+superblockCmpSize], pager.comparatorID)
+	if _, err := pager.writeAt(block, 0); err != nil {
+		return err
+	}
+	pager.superblockDirty = false
+	return nil
+}
+
+// readSuperblock reads the superblock at the start of the pager's file, setting the pager's
+// page size if it hasn't been set, or erroring if it doesn't match the recorded page size.
+func (pager *Pager) readSuperblock() error {
+	block := directio.AlignedBlock(int(SuperblockSize))
+	if _, err := pager.file.ReadAt(block, 0); err != nil && err != io.EOF {
+		return err
+	}
+	if !bytes.Equal(block[:superblockPSOffset], []byte(superblockMagic)) {
+		return fmt.Errorf("%w: missing superblock", ErrCorruptFile)
+	}
+	pagesize, _ := binary.Varint(block[superblockPSOffset : superblockPSOffset+superblockPSSize])
+	if pagesize <= 0 || pagesize%directio.BlockSize != 0 {
+		return fmt.Errorf("%w: invalid page size %d in superblock", ErrCorruptFile, pagesize)
+	}
+	if pager.pagesize == 0 {
+		pager.pagesize = pagesize
+	} else if pager.pagesize != pagesize {
+		return errors.New("page size does not match the page size the DB file was created with")
+	}
+	version, _ := binary.Varint(block[superblockVersionOffset : superblockVersionOffset+superblockVersionSize])
+	if version < 0 || version > SuperblockVersion {
+		return fmt.Errorf("DB file has unsupported format version %d", version)
+	}
+	pager.version = version
+	if version == 0 {
+		// Files from before the superblock was versioned have no free list, feature flags, creation time, or comparator.
+		pager.freeListHead = NoPage
+		pager.flags = 0
+		pager.createdAt = 0
+		pager.comparatorID = 0
+		return nil
+	}
+	pager.freeListHead, _ = binary.Varint(block[superblockFreeOffset : superblockFreeOffset+superblockFreeSize])
+	flags, _ := binary.Uvarint(block[superblockFlagsOffset : superblockFlagsOffset+superblockFlagsSize])
+	pager.flags = FeatureFlags(flags)
+	pager.createdAt = 0
+	if version >= 2 {
+		pager.createdAt, _ = binary.Varint(block[superblockCreatedOffset : superblockCreatedOffset+superblockCreatedSize])
+	}
+	pager.comparatorID = 0
+	if version >= 3 {
+		pager.comparatorID, _ = binary.Varint(block[superblockCmpOffset : superblockCmpOffset+superblockCmpSize])
+	}
 	return nil
 }
 
+// pageOffset returns the position of the page with the given pagenum in the pager's file.
+func (pager *Pager) pageOffset(pagenum int64) int64 {
+	return SuperblockSize + pagenum*pager.pagesize
+}
+
 // Close signals our pager to flush all dirty pages to disk
-// and close its backing file.
+// and close its backing file. Operations that already have pages pinned may keep getting pages,
+// and Close waits for them to put their pages (see SetCloseWait); new operations get ErrClosing.
+// Returns an error if pages are still pinned once the wait is up.
 func (pager *Pager) Close() error {
 	// Prevent new data from being paged in.
 	pager.ptMtx.Lock()
 	defer pager.ptMtx.Unlock()
-	// Check that no pages are in the pinned list
-	curLink := pager.pinnedList.PeekHead()
-	if curLink != nil {
-		return errors.New("pages are still pinned on close")
+	// Wait for the pinned list to empty
+	if err := pager.waitForUnpinned(); err != nil {
+		return err
 	}
 	// Cleanup.
 	pager.FlushAllPages()
+	pager.closed = true
+	return pager.file.Close()
+}
+
+// ForceClose flushes all dirty pages to disk and closes the pager's backing file, even if
+// some pages are still pinned. Each page that is still pinned is logged, since whoever pinned it
+// may not have finished with it. Intended for shutdown paths where Close has failed.
+func (pager *Pager) ForceClose() error {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.pinnedList.Map(func(link *list.Link) {
+		page := link.GetValue().(*Page)
+		log.Printf("page %d of %s is still pinned on force close\n", page.pagenum, pager.file.Name())
+	})
+	pager.FlushAllPages()
+	pager.closed = true
 	return pager.file.Close()
 }
 
 // fillPageFromDisk populate a page's data field from the data currently on disk.
 // Returns an error if there was an io problem reading from disk.
 func (pager *Pager) fillPageFromDisk(page *Page) error {
-	if _, err := pager.file.Seek(page.pagenum*Pagesize, 0); err != nil {
+	if _, err := pager.file.Seek(pager.pageOffset(page.pagenum), 0); err != nil {
 		return err
 	}
 	if _, err := pager.file.Read(page.data); err != nil && err != io.EOF {
 		return err
 	}
+	pager.diskReads.Add(1)
 	return nil
 }
 
-// newPage returns a currently unused Page from the free or unpinned list,
-// or an ErrRanOutOfPages if there are no unused pages available.
+// writeAt writes data to the pager's file at the given offset, counting the write.
+func (pager *Pager) writeAt(data []byte, off int64) (int, error) {
+	pager.diskWrites.Add(1)
+	return pager.writeFile(data, off)
+}
+
+// newPage returns a currently unused Page from the free list, or else evicts the least recently used page
+// from the unpinned list, or returns an ErrRanOutOfPages if there are no unused pages available.
 // The ptMtx should be locked on entry.
 func (pager *Pager) newPage(pagenum int64) (newPage *Page, err error) {
 	/* SOLUTION {{{ */
@@ -146,12 +577,13 @@ func (pager *Pager) newPage(pagenum int64) (newPage *Page, err error) {
 		freeLink.PopSelf()
 		newPage = freeLink.GetValue().(*Page)
 	} else if unpinLink := pager.unpinnedList.PeekHead(); unpinLink != nil {
-		// If no page was found, evict a page from the unpinned list.
+		// If no page was found, evict the least recently used page, at the head of the unpinned list.
 		// But skip this if our pager isn't backed by disk.
 		unpinLink.PopSelf()
 		newPage = unpinLink.GetValue().(*Page)
 		pager.FlushPage(newPage)
 		delete(pager.pageTable, newPage.pagenum)
+		pager.evictions.Add(1)
 	} else {
 		// If still no page is found, error.
 		return nil, ErrRanOutOfPages
@@ -163,11 +595,28 @@ func (pager *Pager) newPage(pagenum int64) (newPage *Page, err error) {
 	/* SOLUTION }}} */
 }
 
-// GetNewPage returns a new Page with the next available pagenum
+// GetNewPage returns a new Page with the next available pagenum (see [*Pager.GetFreePN]).
+// If every page in the buffer is pinned, it retries as configured by [*Pager.SetPinRetry].
 func (pager *Pager) GetNewPage() (page *Page, err error) {
+	page, err = pager.retryPinned(pager.getNewPage)
+	if err == nil {
+		pager.traceAccess(NEW_PAGE_OP, page.pagenum)
+	}
+	return page, err
+}
+
+// getNewPage makes a single attempt at GetNewPage.
+func (pager *Pager) getNewPage() (page *Page, err error) {
 	/* SOLUTION {{{ */
 	pager.ptMtx.Lock()
 	defer pager.ptMtx.Unlock()
+	if err := pager.checkClosing(); err != nil {
+		return nil, err
+	}
+	// Reuse a freed page if there is one, rather than growing the file.
+	if pager.freeListHead != NoPage {
+		return pager.reuseFreePage()
+	}
 	// Create a buffer to hold the new page in.
 	page, err = pager.newPage(pager.numPages)
 	if err != nil {
@@ -176,9 +625,11 @@ func (pager *Pager) GetNewPage() (page *Page, err error) {
 
 	// Mark dirty so new page is eventually flushed to disk.
 	page.dirty = true
+	pager.modified.Store(true)
 	// Insert new page into the pinned list and page table.
 	newLink := pager.pinnedList.PushTail(page)
 	pager.pageTable[pager.numPages] = newLink
+	pager.pinned()
 	// Increment the total number of pages.
 	pager.numPages++
 	return page, nil
@@ -186,33 +637,58 @@ func (pager *Pager) GetNewPage() (page *Page, err error) {
 }
 
 // GetPage returns an existing Page corresponding to the given pagenum.
+// If every page in the buffer is pinned, it retries as configured by [*Pager.SetPinRetry].
 func (pager *Pager) GetPage(pagenum int64) (page *Page, err error) {
+	page, err = pager.retryPinned(func() (*Page, error) { return pager.getPage(pagenum) })
+	if err == nil {
+		pager.traceAccess(GET_PAGE_OP, pagenum)
+	}
+	return page, err
+}
+
+// getPage makes a single attempt at GetPage.
+func (pager *Pager) getPage(pagenum int64) (page *Page, err error) {
 	/* SOLUTION {{{ */
 	// Try to get from page table.
-	var newLink *list.Link
 	pager.ptMtx.Lock()
 	defer pager.ptMtx.Unlock()
+	if err := pager.checkClosing(); err != nil {
+		return nil, err
+	}
 	// Input checking.
 	if pagenum < 0 || pagenum > pager.numPages-1 {
 		return nil, errors.New("invalid pagenum")
 	}
+	page, hit, err := pager.pinPage(pagenum)
+	if hit {
+		pager.hits.Add(1)
+	}
+	return page, err
+	/* SOLUTION }}} */
+}
+
+// pinPage pins the page with the given pagenum, reading it in from disk if it isn't in the buffer,
+// and reports whether it was already in the buffer. The ptMtx should be locked on entry.
+func (pager *Pager) pinPage(pagenum int64) (page *Page, hit bool, err error) {
+	var newLink *list.Link
 	link, ok := pager.pageTable[pagenum]
 	if ok {
 		page = link.GetValue().(*Page)
-		// Move the page to the pinned list if needed.
+		// Move the page to the pinned list if needed; it rejoins the unpinned list as the most recently used when put.
 		if link.GetList() == pager.unpinnedList {
 			link.PopSelf()
 			newLink = pager.pinnedList.PushTail(page)
 			pager.pageTable[pagenum] = newLink
+			pager.pinned()
 		}
 		page.Get()
-		return page, nil
+		return page, true, nil
 	}
 
 	// Else, create a buffer to hold the new page in.
 	page, err = pager.newPage(pagenum)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	// Read the page in from disk.
@@ -220,28 +696,43 @@ func (pager *Pager) GetPage(pagenum int64) (page *Page, err error) {
 	err = pager.fillPageFromDisk(page)
 	if err != nil {
 		pager.freeList.PushTail(page)
-		return nil, err
+		return nil, false, err
 	}
 
 	// Insert the page into our list of pages.
 	newLink = pager.pinnedList.PushTail(page)
 	pager.pageTable[pagenum] = newLink
-	return page, nil
-	/* SOLUTION }}} */
+	pager.pinned()
+	return page, false, nil
 }
 
 // PutPage releases a reference to a page.
 func (pager *Pager) PutPage(page *Page) (err error) {
+	pager.traceAccess(PUT_PAGE_OP, page.pagenum)
 	pager.ptMtx.Lock()
 	defer pager.ptMtx.Unlock()
+	return pager.unpinPage(page)
+}
+
+// unpinPage releases a reference to a page. The ptMtx should be locked on entry.
+func (pager *Pager) unpinPage(page *Page) error {
 	// Decrement pinCount
 	ret := page.Put()
-	// Check if we can unpin this page; if so, move from pinned to unpinned list.
+	// Check if we can unpin this page; if so, move from pinned to the most recently used end of the unpinned list.
 	if ret == 0 {
 		link := pager.pageTable[page.pagenum]
 		link.PopSelf()
 		newLink := pager.unpinnedList.PushTail(page)
 		pager.pageTable[page.pagenum] = newLink
+		pager.numPinned--
+		if pager.closing && pager.numPinned == 0 {
+			pager.unpinnedCond.Broadcast()
+		}
+		if pager.cachePolicy != WRITE_BACK {
+			if err := pager.writeThrough(page); err != nil {
+				return err
+			}
+		}
 	}
 	if ret < 0 {
 		return errors.New("pinCount for page is < 0")
@@ -252,11 +743,12 @@ func (pager *Pager) PutPage(page *Page) (err error) {
 // FlushPage flushes a particular page's data to disk if it is dirty.
 // Concurrency note: the page should at least be read-locked upon entry.
 func (pager *Pager) FlushPage(page *Page) {
+	pager.traceAccess(FLUSH_PAGE_OP, page.pagenum)
 	/* SOLUTION {{{ */
 	if page.IsDirty() {
-		pager.file.WriteAt(
+		pager.writeAt(
 			page.data,
-			page.pagenum*Pagesize,
+			pager.pageOffset(page.pagenum),
 		)
 		page.SetDirty(false)
 	}
@@ -274,6 +766,14 @@ func (pager *Pager) FlushAllPages() {
 	pager.pinnedList.Map(writer)
 	pager.unpinnedList.Map(writer)
 	/* SOLUTION }}} */
+	if pager.superblockDirty {
+		pager.writeSuperblock()
+	}
+}
+
+// Sync commits the pager's backing file to stable storage, so that flushed pages survive a power failure.
+func (pager *Pager) Sync() error {
+	return pager.file.Sync()
 }
 
 // [RECOVERY] Read locks the pager and all of the pager's pages.
@@ -57,6 +57,10 @@ func PagerRepl() (*repl.REPL, error) {
 		return "", HandlePagerFlushAll(p, payload)
 	}, "Flush all pages. usage: pager_flushall")
 
+	r.AddCommand("pager_stats", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandlePagerStats(p, payload)
+	}, "Print the buffer's hit, miss, and eviction counts. usage: pager_stats")
+
 	return r, nil
 }
 
@@ -205,6 +209,7 @@ func HandlePagerPin(p *Pager, payload string) (err error) {
 		link.PopSelf()
 		newLink := p.pinnedList.PushHead(link.GetValue())
 		p.pageTable[int64(pNum)] = newLink
+		p.pinned()
 	}
 	page := link.GetValue().(*Page)
 	page.Get()
@@ -271,3 +276,16 @@ func HandlePagerFlushAll(p *Pager, payload string) (err error) {
 	p.FlushAllPages()
 	return nil
 }
+
+// Function to print out the buffer's statistics.
+func HandlePagerStats(p *Pager, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: pager_stats
+	if numFields != 1 {
+		return "", fmt.Errorf("usage: pager_stats")
+	}
+	stats := p.Stats()
+	return fmt.Sprintf("hits: %d, misses: %d, evictions: %d, hit ratio: %.1f%%\n",
+		stats.Hits, stats.Misses, stats.Evictions, 100*stats.HitRatio()), nil
+}
@@ -0,0 +1,58 @@
+package pager
+
+import (
+	"errors"
+	"time"
+
+	"dinodb/pkg/list"
+)
+
+// SetPinRetry makes GetPage and GetNewPage wait out a buffer whose pages are all pinned, rather than
+// failing straight away with ErrRanOutOfPages. They retry up to retries times, waiting backoff before
+// the first retry and doubling the wait before each one after. Before each wait, the unpinned dirty
+// pages are flushed so that they are cheap to evict once room frees up. A retries of 0, the default,
+// disables retrying.
+func (pager *Pager) SetPinRetry(retries int, backoff time.Duration) error {
+	if retries < 0 || backoff < 0 {
+		return errors.New("pin retries and backoff must not be negative")
+	}
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.pinRetries = retries
+	pager.pinBackoff = backoff
+	return nil
+}
+
+// GetPinRetry returns the pager's pin retry settings.
+func (pager *Pager) GetPinRetry() (retries int, backoff time.Duration) {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	return pager.pinRetries, pager.pinBackoff
+}
+
+// retryPinned calls get, retrying with backoff while it fails with ErrRanOutOfPages.
+// The retries are bounded, so the last ErrRanOutOfPages is returned if pins are never released.
+// The ptMtx should not be locked on entry, so that other operations can put their pages while we wait.
+func (pager *Pager) retryPinned(get func() (*Page, error)) (*Page, error) {
+	page, err := get()
+	if !errors.Is(err, ErrRanOutOfPages) {
+		return page, err
+	}
+	retries, backoff := pager.GetPinRetry()
+	for i := 0; i < retries && errors.Is(err, ErrRanOutOfPages); i++ {
+		pager.flushUnpinned()
+		time.Sleep(backoff)
+		backoff *= 2
+		page, err = get()
+	}
+	return page, err
+}
+
+// flushUnpinned writes the unpinned dirty pages to disk.
+func (pager *Pager) flushUnpinned() {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	pager.unpinnedList.Map(func(link *list.Link) {
+		pager.FlushPage(link.GetValue().(*Page))
+	})
+}
@@ -0,0 +1,26 @@
+package pager
+
+// PagerStats counts how a pager's buffer has been used since the pager was created.
+type PagerStats struct {
+	Hits      int64 // GetPage calls that found their page in the buffer.
+	Misses    int64 // GetPage calls that had to read their page in from disk.
+	Evictions int64 // Pages evicted from the buffer, to make room for other pages or to shrink it.
+}
+
+// HitRatio returns the fraction of GetPage calls that found their page in the buffer, or 0 if there were none.
+func (stats PagerStats) HitRatio() float64 {
+	if stats.Hits+stats.Misses == 0 {
+		return 0
+	}
+	return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+}
+
+// Stats returns the pager's buffer hit, miss, and eviction counts. Every miss is a disk read, so Misses is [*Pager.GetNumDiskReads].
+// The counts are read one at a time, so calls running at the same time may be reflected in some but not others.
+func (pager *Pager) Stats() PagerStats {
+	return PagerStats{
+		Hits:      pager.hits.Load(),
+		Misses:    pager.diskReads.Load(),
+		Evictions: pager.evictions.Load(),
+	}
+}
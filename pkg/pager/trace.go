@@ -0,0 +1,66 @@
+package pager
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// PageOp is a kind of page access recorded by a PageTrace.
+type PageOp string
+
+const (
+	GET_PAGE_OP   PageOp = "get"
+	NEW_PAGE_OP   PageOp = "new"
+	PUT_PAGE_OP   PageOp = "put"
+	FLUSH_PAGE_OP PageOp = "flush"
+)
+
+// PageAccess is a single call to GetPage, GetNewPage, PutPage, or FlushPage.
+type PageAccess struct {
+	File    string // The base name of the pager's file.
+	Op      PageOp
+	PageNum int64
+}
+
+// String describes the access, e.g. "get page 3 of users".
+func (access PageAccess) String() string {
+	return fmt.Sprintf("%s page %d of %s", access.Op, access.PageNum, access.File)
+}
+
+// PageTrace records the page accesses of every pager it is set on, in the order they happen.
+type PageTrace struct {
+	mtx      sync.Mutex
+	accesses []PageAccess
+}
+
+// NewPageTrace returns an empty trace.
+func NewPageTrace() *PageTrace {
+	return &PageTrace{accesses: make([]PageAccess, 0)}
+}
+
+// Accesses returns the accesses recorded so far.
+func (trace *PageTrace) Accesses() []PageAccess {
+	trace.mtx.Lock()
+	defer trace.mtx.Unlock()
+	return append([]PageAccess(nil), trace.accesses...)
+}
+
+// record appends an access to the trace.
+func (trace *PageTrace) record(access PageAccess) {
+	trace.mtx.Lock()
+	defer trace.mtx.Unlock()
+	trace.accesses = append(trace.accesses, access)
+}
+
+// SetTrace makes the pager record each of its page accesses to trace, or stops it recording if trace is nil.
+func (pager *Pager) SetTrace(trace *PageTrace) {
+	pager.trace.Store(trace)
+}
+
+// traceAccess records an access to the pager's trace, if it has one.
+func (pager *Pager) traceAccess(op PageOp, pagenum int64) {
+	if trace := pager.trace.Load(); trace != nil {
+		trace.record(PageAccess{File: filepath.Base(pager.file.Name()), Op: op, PageNum: pagenum})
+	}
+}
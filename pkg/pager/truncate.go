@@ -0,0 +1,53 @@
+package pager
+
+import (
+	"fmt"
+
+	"dinodb/pkg/list"
+)
+
+// Truncate discards every page from numPages on, dropping them from the buffer without writing them
+// and shrinking the file to end after the remaining pages. The next new page gets pagenum numPages.
+// Returns an error without discarding anything if one of those pages is pinned.
+func (pager *Pager) Truncate(numPages int64) error {
+	pager.ptMtx.Lock()
+	defer pager.ptMtx.Unlock()
+	if err := pager.checkClosing(); err != nil {
+		return err
+	}
+	if numPages < 0 || numPages > pager.numPages {
+		return fmt.Errorf("cannot truncate %d pages to %d pages", pager.numPages, numPages)
+	}
+	for pagenum, link := range pager.pageTable {
+		if pagenum >= numPages && link.GetList() == pager.pinnedList {
+			return fmt.Errorf("cannot truncate: page %d is pinned", pagenum)
+		}
+	}
+	discarded := make([]*list.Link, 0)
+	for pagenum, link := range pager.pageTable {
+		if pagenum >= numPages {
+			discarded = append(discarded, link)
+			delete(pager.pageTable, pagenum)
+		}
+	}
+	for _, link := range discarded {
+		link.PopSelf()
+		page := link.GetValue().(*Page)
+		page.pagenum = NoPage
+		page.dirty = false
+		// Zero the data, since a new page reusing this buffer may not overwrite all of it.
+		clear(page.data)
+		pager.freeList.PushTail(page)
+	}
+	if err := pager.file.Truncate(pager.pageOffset(numPages)); err != nil {
+		return err
+	}
+	// The free list may link through discarded pages, so it is dropped; any freed pages that remain are leaked.
+	if numPages < pager.numPages && pager.freeListHead != NoPage {
+		pager.freeListHead = NoPage
+		pager.superblockDirty = true
+	}
+	pager.numPages = numPages
+	pager.modified.Store(true)
+	return nil
+}
@@ -0,0 +1,47 @@
+package recovery
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// ArchiveLog copies the write-ahead log, as it is when ArchiveLog is called, to dstPath.
+// Writers are only blocked while the log's size is recorded; logs written during the copy are
+// appended to the live log as usual and left out of the archive. The archive is written to a
+// temporary file and renamed into place, so dstPath never holds a partial copy.
+// The archive can be replayed on its own with RebuildFromLog.
+func (rm *RecoveryManager) ArchiveLog(dstPath string) (err error) {
+	// Logs are written whole while rm.mtx is held, so the size recorded under it ends on a log boundary.
+	rm.mtx.Lock()
+	fstats, err := rm.logFile.Stat()
+	rm.mtx.Unlock()
+	if err != nil {
+		return fmt.Errorf("archive error: %v", err)
+	}
+	src, err := os.Open(rm.logFile.Name())
+	if err != nil {
+		return fmt.Errorf("archive error: %v", err)
+	}
+	defer src.Close()
+	tmpPath := dstPath + ".tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("archive error: %v", err)
+	}
+	_, err = io.CopyN(dst, src, fstats.Size())
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, dstPath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("archive error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !faultinject
+
+package recovery
+
+// writeLog appends a serialized log to the log file. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) writeLog(s string) (int, error) {
+	return rm.logFile.WriteString(s)
+}
@@ -0,0 +1,37 @@
+//go:build faultinject
+
+package recovery
+
+import (
+	"sync"
+
+	"dinodb/pkg/pager"
+)
+
+// The faulty writers set on each recovery manager.
+var faultyWriters sync.Map
+
+// SetFaultyWriter makes the recovery manager's appends to the write-ahead log go through w,
+// or directly to the log file if w is nil. Only exists when built with the faultinject tag.
+func (rm *RecoveryManager) SetFaultyWriter(w *pager.FaultyWriter) {
+	if w == nil {
+		faultyWriters.Delete(rm)
+		return
+	}
+	faultyWriters.Store(rm, w)
+}
+
+// writeLog appends a serialized log to the log file, through the recovery manager's faulty writer
+// if it has one, so that a torn append leaves only the start of the log. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) writeLog(s string) (int, error) {
+	w, ok := faultyWriters.Load(rm)
+	if !ok {
+		return rm.logFile.WriteString(s)
+	}
+	keep, fault := w.(*pager.FaultyWriter).Cut(len(s))
+	n, err := rm.logFile.WriteString(s[:keep])
+	if err != nil {
+		return n, err
+	}
+	return n, fault
+}
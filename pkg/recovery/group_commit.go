@@ -0,0 +1,62 @@
+package recovery
+
+import "time"
+
+// commitBatch is a group of commit logs that are made durable by a single sync of the log file.
+type commitBatch struct {
+	deadline time.Time // When the batch is synced, even if other transactions might still commit.
+	size     int       // The number of commits in the batch.
+	synced   bool      // Whether the batch has been synced.
+	err      error     // The error from syncing the batch, if any.
+}
+
+// SetMaxCommitDelay lets commits wait up to maxDelay to share a sync of the log file with other commits,
+// trading a bounded amount of commit latency for fewer syncs under load. A commit waits only while other
+// transactions are running, so a lone commit is synced right away. A delay of 0 (the default) syncs every commit on its own.
+func (rm *RecoveryManager) SetMaxCommitDelay(maxDelay time.Duration) {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	rm.maxCommitDelay = maxDelay
+	rm.commitCond.Broadcast()
+}
+
+// syncCommit makes a commit log that was just appended durable by joining the open batch of commits,
+// or opening one if there isn't one. The commit that opens a batch waits until its deadline passes or no
+// other transactions are running, then syncs the log for the whole batch. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) syncCommit() error {
+	if batch := rm.commitBatch; batch != nil {
+		batch.size++
+		// Wake the batch's first commit in case this was the last running transaction.
+		rm.commitCond.Broadcast()
+		for !batch.synced {
+			rm.commitCond.Wait()
+		}
+		return batch.err
+	}
+	batch := &commitBatch{deadline: time.Now().Add(rm.maxCommitDelay), size: 1}
+	rm.commitBatch = batch
+	for len(rm.txStack) > 0 {
+		remaining := time.Until(batch.deadline)
+		if remaining <= 0 {
+			break
+		}
+		// sync.Cond can't time out, so wake every waiter once the deadline passes.
+		timer := time.AfterFunc(remaining, func() {
+			rm.mtx.Lock()
+			defer rm.mtx.Unlock()
+			rm.commitCond.Broadcast()
+		})
+		rm.commitCond.Wait()
+		timer.Stop()
+	}
+	rm.commitBatch = nil
+	start := time.Now()
+	batch.err = rm.logFile.Sync()
+	if batch.err == nil {
+		rm.metrics.CommitSync.record(time.Since(start))
+		rm.metrics.BatchedCommits += int64(batch.size)
+	}
+	batch.synced = true
+	rm.commitCond.Broadcast()
+	return batch.err
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 
+	"dinodb/pkg/entry"
+
 	"github.com/google/uuid"
 )
 
@@ -16,6 +18,9 @@ import (
 	 TABLE log -- create a table;
 	 < create tblType table tblName >
 
+	 CONVERT log -- convert a table to another type;
+	 < convert table tblName to tblType >
+
    EDIT log -- actions that modify database state;
    < Tx, table, INSERT|DELETE|UPDATE, key, oldval, newval >
 
@@ -25,8 +30,13 @@ import (
    COMMIT log -- end of a transaction:
    < Tx commit >
 
-   CHECKPOINT log -- lists the currently running transactions:
+   CHECKPOINT log -- lists the currently running transactions, and the tables it flushed if any:
    < Tx1, Tx2... checkpoint >
+   < Tx1, Tx2... checkpoint covering table1, table2... >
+
+   Every log written to the log file is prefixed with a sequence number
+   that increases by one with each log, so that missing logs can be detected:
+   seq < ... >
 */
 
 // Interface that all log structs share.
@@ -44,6 +54,16 @@ func (tl tableLog) toString() string {
 	return fmt.Sprintf("< create %s table %s >\n", tl.tblType, tl.tblName)
 }
 
+// Log for converting a table to another type.
+type convertLog struct {
+	tblName string // The name of the table converted
+	tblType string // The type the table was converted to, either "btree" or "hash"
+}
+
+func (cl convertLog) toString() string {
+	return fmt.Sprintf("< convert table %s to %s >\n", cl.tblName, cl.tblType)
+}
+
 // The type of edit action. Either insert, delete, or update.
 type action string
 
@@ -87,7 +107,8 @@ func (cl commitLog) toString() string {
 
 // Log for making a checkpoint.
 type checkpointLog struct {
-	ids []uuid.UUID // The currently running transactions.
+	ids    []uuid.UUID // The currently running transactions.
+	tables []string    // The tables whose pages were flushed, having changed since the last checkpoint.
 }
 
 func (cl checkpointLog) toString() string {
@@ -95,10 +116,14 @@ func (cl checkpointLog) toString() string {
 	for _, id := range cl.ids {
 		idStrings = append(idStrings, id.String())
 	}
+	covering := ""
+	if len(cl.tables) > 0 {
+		covering = " covering " + strings.Join(cl.tables, ", ")
+	}
 	if len(idStrings) == 0 {
-		return "< checkpoint >\n"
+		return fmt.Sprintf("< checkpoint%s >\n", covering)
 	}
-	return fmt.Sprintf("< %s checkpoint >\n", strings.Join(idStrings, ", "))
+	return fmt.Sprintf("< %s checkpoint%s >\n", strings.Join(idStrings, ", "), covering)
 }
 
 // Regex pattern for a uuid
@@ -106,11 +131,32 @@ const uuidPattern = "[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12
 
 var tableExp = regexp.MustCompile("< create (?P<tblType>\\w+) table (?P<tblName>\\w+) >")
 
-var editExp = regexp.MustCompile(fmt.Sprintf("< (?P<uuid>%s), (?P<table>\\w+), (?P<action>UPDATE|INSERT|DELETE), (?P<key>\\d+), (?P<oldval>\\d+), (?P<newval>\\d+) >", uuidPattern))
+var convertExp = regexp.MustCompile("< convert table (?P<tblName>\\w+) to (?P<tblType>\\w+) >")
+
+var editExp = regexp.MustCompile(fmt.Sprintf("< (?P<uuid>%s), (?P<table>\\w+), (?P<action>UPDATE|INSERT|DELETE), (?P<key>-?\\d+), (?P<oldval>-?\\d+), (?P<newval>-?\\d+) >", uuidPattern))
 var startExp = regexp.MustCompile(fmt.Sprintf("< (%s) start >", uuidPattern))
 var commitExp = regexp.MustCompile(fmt.Sprintf("< (%s) commit >", uuidPattern))
-var checkpointExp = regexp.MustCompile(fmt.Sprintf("< (%s,?\\s)*checkpoint >", uuidPattern))
+var checkpointExp = regexp.MustCompile(fmt.Sprintf("< (%s,?\\s)*checkpoint(?: covering (\\w+(?:, \\w+)*))? >", uuidPattern))
 var uuidExp = regexp.MustCompile(uuidPattern)
+var seqExp = regexp.MustCompile("^(\\d+) ")
+
+// Error for when the sequence numbers of the logs in the log file aren't contiguous.
+var ErrLogGap = errors.New("log records are missing")
+
+// Splits a line from the log file into its sequence number and log.
+// Returns an error if the line has no sequence number or could not be parsed into a log.
+func logFromLine(line string) (seq int64, l log, err error) {
+	seqStrs := seqExp.FindStringSubmatch(line)
+	if seqStrs == nil {
+		return 0, nil, errors.New("log has no sequence number")
+	}
+	seq, err = strconv.ParseInt(seqStrs[1], 10, 64)
+	if err != nil {
+		return 0, nil, err
+	}
+	l, err = logFromString(line[len(seqStrs[0]):])
+	return seq, l, err
+}
 
 // Convert the textual representation of a log to its respective struct.
 // Returns an error if the string could not be parsed into a log.
@@ -124,19 +170,30 @@ func logFromString(s string) (log, error) {
 			tblType: tblType,
 			tblName: tblName,
 		}, nil
+	case convertExp.MatchString(s):
+		expStrs := convertExp.FindStringSubmatch(s)
+		return convertLog{
+			tblName: expStrs[1],
+			tblType: expStrs[2],
+		}, nil
 	case editExp.MatchString(s):
 		expStrs := editExp.FindStringSubmatch(s)
 		uuid := uuid.MustParse(expStrs[1])
-		key, _ := strconv.Atoi(expStrs[4])
-		oldval, _ := strconv.Atoi(expStrs[5])
-		newval, _ := strconv.Atoi(expStrs[6])
+		var vals [3]int64
+		for i := range vals {
+			val, err := entry.ParseInt64(expStrs[4+i])
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = val
+		}
 		return editLog{
 			id:        uuid,
 			tablename: expStrs[2],
 			action:    action(expStrs[3]),
-			key:       int64(key),
-			oldval:    int64(oldval),
-			newval:    int64(newval),
+			key:       vals[0],
+			oldval:    vals[1],
+			newval:    vals[2],
 		}, nil
 	case startExp.MatchString(s):
 		uuid := uuid.MustParse(uuidExp.FindString(s))
@@ -150,7 +207,11 @@ func logFromString(s string) (log, error) {
 		for _, uuidStr := range uuidStrs {
 			uuids = append(uuids, uuid.MustParse(uuidStr))
 		}
-		return checkpointLog{ids: uuids}, nil
+		var tables []string
+		if covering := checkpointExp.FindStringSubmatch(s)[2]; covering != "" {
+			tables = strings.Split(covering, ", ")
+		}
+		return checkpointLog{ids: uuids, tables: tables}, nil
 	default:
 		return nil, errors.New("could not parse log")
 	}
@@ -0,0 +1,221 @@
+package recovery
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/icza/backscanner"
+)
+
+// Error for when a log would grow the write-ahead log past its maximum size.
+// Space is freed by taking a checkpoint and truncating the log.
+var ErrWALFull = errors.New("write-ahead log is full")
+
+// SetMaxLogSize limits the write-ahead log to maxSize bytes (0 for no limit). Logs that start new work
+// (table, start, and edit logs) wait up to maxWait for a truncation to make room, then fail with ErrWALFull.
+// Commits, conversions, checkpoints, and the edits that roll a transaction back are always written, so that space can be freed.
+// While a limit is set, the checkpoint scheduler truncates the log after each checkpoint.
+func (rm *RecoveryManager) SetMaxLogSize(maxSize int64, maxWait time.Duration) {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	rm.maxLogSize = maxSize
+	rm.maxLogWait = maxWait
+	rm.logSpaceCond.Broadcast()
+}
+
+// GetLogSize returns the size of the write-ahead log in bytes.
+func (rm *RecoveryManager) GetLogSize() int64 {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	return rm.logSize
+}
+
+// waitForLogSpace waits until writing the given log would keep the write-ahead log within its maximum size,
+// or returns an ErrWALFull if that takes longer than the maximum wait. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) waitForLogSpace(log log) error {
+	deadline := time.Now().Add(rm.maxLogWait)
+	for {
+		size := int64(len(fmt.Sprintf("%d %s", rm.nextSeq, log.toString())))
+		if rm.maxLogSize == 0 || rm.logSize+size <= rm.maxLogSize {
+			return nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: the log is %d bytes and limited to %d", ErrWALFull, rm.logSize, rm.maxLogSize)
+		}
+		// sync.Cond can't time out, so wake every waiter once this one's deadline passes.
+		timer := time.AfterFunc(remaining, func() {
+			rm.mtx.Lock()
+			defer rm.mtx.Unlock()
+			rm.logSpaceCond.Broadcast()
+		})
+		rm.logSpaceCond.Wait()
+		timer.Stop()
+	}
+}
+
+// TruncateLog removes the logs that recovery no longer needs from the start of the write-ahead log:
+// everything before the most recent checkpoint, except the logs of transactions that were active at it.
+// Take a checkpoint first to free the most space. Truncated logs can't be replayed by RebuildFromLog,
+// so archive the log with ArchiveLog first to keep them.
+func (rm *RecoveryManager) TruncateLog() error {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	return rm.truncateLog()
+}
+
+// truncateLog carries out a truncation. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) truncateLog() error {
+	keepFrom, err := rm.firstNeededLog()
+	if err != nil {
+		return fmt.Errorf("truncate error: %v", err)
+	}
+	if keepFrom <= 0 {
+		return nil
+	}
+	// Copy the logs to keep to a new file and rename it over the log, so a crash leaves one log or the other.
+	path := rm.logFile.Name()
+	tmpPath := path + ".tmp"
+	err = copyLogSuffix(path, tmpPath, keepFrom)
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("truncate error: %v", err)
+	}
+	logFile, err := os.OpenFile(path, os.O_APPEND|os.O_RDWR, 0666)
+	if err != nil {
+		return fmt.Errorf("truncate error: %v", err)
+	}
+	rm.logFile.Close()
+	rm.logFile = logFile
+	rm.logSize -= keepFrom
+	rm.logSpaceCond.Broadcast()
+	return nil
+}
+
+// CompactLog shrinks the write-ahead log to the least that recovery needs in one step. It takes a checkpoint,
+// syncs every table's file to disk, truncates the log to the new checkpoint log (and the logs of any transactions
+// active at it), and refreshes the recovery folder with the truncated log. The log is only truncated once every
+// table is synced, so a failed flush or sync leaves the whole log in place. Returns the log's size before and after.
+func (rm *RecoveryManager) CompactLog() (before int64, after int64, err error) {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	for rm.pausedCheckpoints > 0 {
+		rm.checkpointCond.Wait()
+	}
+	before = rm.logSize
+	err = rm.checkpointThen(func() error {
+		for name, tb := range rm.db.GetTables() {
+			if err := tb.GetPager().Sync(); err != nil {
+				return fmt.Errorf("error syncing table %s: %w", name, err)
+			}
+		}
+		return rm.truncateLog()
+	})
+	if err != nil {
+		return before, rm.logSize, fmt.Errorf("compact error: %w", err)
+	}
+	return before, rm.logSize, nil
+}
+
+// firstNeededLog returns the offset of the first log that recovery needs: the start log of the oldest
+// transaction active at the most recent checkpoint, or the checkpoint itself if none were.
+// Returns 0 if there is no checkpoint. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) firstNeededLog() (int64, error) {
+	scanner := backscanner.New(rm.logFile, int(rm.logSize))
+	checkpointTarget := []byte("checkpoint")
+	startTarget := []byte("start")
+	checkpointHit := false
+	txs := make(map[uuid.UUID]bool)
+	for {
+		line, pos, err := scanner.LineBytes()
+		if err == io.EOF {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if checkpointHit && bytes.Contains(line, startTarget) {
+			if _, log, err := logFromLine(string(line)); err == nil {
+				if sl, ok := log.(startLog); ok {
+					delete(txs, sl.id)
+				}
+			}
+		}
+		if !checkpointHit && bytes.Contains(line, checkpointTarget) {
+			_, log, err := logFromLine(string(line))
+			if err != nil {
+				return 0, err
+			}
+			if cl, ok := log.(checkpointLog); ok {
+				checkpointHit = true
+				for _, id := range cl.ids {
+					txs[id] = true
+				}
+			}
+		}
+		if checkpointHit && len(txs) == 0 {
+			return int64(pos), nil
+		}
+	}
+}
+
+// copyLogSuffix copies the log file at src, from offset onwards, to a new file at dst.
+func copyLogSuffix(src string, dst string, offset int64) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if _, err = in.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// dropTornLog truncates a log that was only partly appended, such as by a crash partway through a write,
+// from the end of the log file. Every whole log ends with a newline, so anything after the last one is torn.
+func (rm *RecoveryManager) dropTornLog() error {
+	if rm.logSize == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := rm.logFile.ReadAt(last, rm.logSize-1); err != nil {
+		return fmt.Errorf("torn log error: %v", err)
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	scanner := backscanner.New(rm.logFile, int(rm.logSize))
+	torn, pos, err := scanner.LineBytes()
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("torn log error: %v", err)
+	}
+	if err := rm.logFile.Truncate(int64(pos)); err != nil {
+		return fmt.Errorf("torn log error: %v", err)
+	}
+	if err := rm.logFile.Sync(); err != nil {
+		return fmt.Errorf("torn log error: %v", err)
+	}
+	stdlog.Printf("dropped a torn log from the end of the write-ahead log: %q\n", torn)
+	rm.logSize = int64(pos)
+	return nil
+}
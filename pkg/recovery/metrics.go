@@ -0,0 +1,58 @@
+package recovery
+
+import "time"
+
+// LatencySummary summarizes the durations of a kind of timed operation.
+type LatencySummary struct {
+	Count int64         // The number of operations recorded.
+	Total time.Duration // The sum of the recorded durations.
+	Min   time.Duration // The shortest recorded duration.
+	Max   time.Duration // The longest recorded duration.
+}
+
+// Mean returns the average recorded duration, or 0 if nothing has been recorded.
+func (s LatencySummary) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// record adds a duration to the summary.
+func (s *LatencySummary) record(d time.Duration) {
+	if s.Count == 0 || d < s.Min {
+		s.Min = d
+	}
+	s.Max = max(s.Max, d)
+	s.Total += d
+	s.Count++
+}
+
+// Metrics holds timing information about a recovery manager's writes to disk.
+type Metrics struct {
+	// How long writing and syncing a log to the write-ahead log took, for the sampled logs.
+	LogFlush LatencySummary
+	// How long each checkpoint took, including flushing pages and copying the database.
+	Checkpoint LatencySummary
+	// How long each sync shared by a batch of commits took, when commits are batched (see SetMaxCommitDelay).
+	CommitSync LatencySummary
+	// The number of commits made durable by those syncs.
+	BatchedCommits int64
+	// The number of times the checkpoint scheduler failed to take a checkpoint or truncate the log (see LastSchedulerError).
+	SchedulerErrors int64
+}
+
+// Metrics returns a snapshot of the recovery manager's timing metrics.
+func (rm *RecoveryManager) Metrics() Metrics {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	return rm.metrics
+}
+
+// SetLogFlushSampling times one in every `every` log flushes, or none if every is 0. Defaults to timing every flush.
+// Checkpoints are always timed.
+func (rm *RecoveryManager) SetLogFlushSampling(every int64) {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	rm.flushSampleEvery = every
+}
@@ -5,14 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	stdlog "log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"dinodb/pkg/concurrency"
 	"dinodb/pkg/config"
 	"dinodb/pkg/database"
+	"dinodb/pkg/hash"
 
 	"github.com/icza/backscanner"
 	"github.com/otiai10/copy"
@@ -20,6 +24,10 @@ import (
 	"github.com/google/uuid"
 )
 
+// Error for when a transaction tries to make more edits than the recovery manager allows.
+// The transaction must be committed or aborted before it can make any more edits.
+var ErrTransactionTooLarge = errors.New("transaction has reached the maximum number of edits")
+
 // RecoveryManager is the construct that manages the write-ahead log for a database.
 // It is therefore responsible for recovery from crashes and rolling back uncommitted transactions.
 type RecoveryManager struct {
@@ -29,8 +37,41 @@ type RecoveryManager struct {
 	// Keeps track of the operations of all uncommitted transactions.
 	// Maps each client/transaction id to a stack of logs.
 	txStack map[uuid.UUID][]editLog
+	// The maximum number of edits a transaction can make before committing (0 for no limit).
+	maxEdits int
+	// The transactions that are being rolled back, which may exceed maxEdits while undoing their edits.
+	rollingBack map[uuid.UUID]bool
+
+	// The number of outstanding PauseCheckpoints calls. Checkpoints only happen when this is 0.
+	pausedCheckpoints int
+	checkpointCond    *sync.Cond    // Signalled on rm.mtx when checkpoints are resumed.
+	stopScheduler     chan struct{} // Closed to stop the checkpoint scheduler, or nil if it isn't running.
+	schedulerErr      error         // The last error the checkpoint scheduler ran into, or nil if it hasn't failed.
+
+	metrics          Metrics // Timing of log flushes and checkpoints.
+	flushSampleEvery int64   // Log flushes are timed once every this many flushes, or never if 0.
+
+	// The most bytes the log file may hold before new work waits for a truncation (0 for no limit),
+	// and how long it waits before failing with ErrWALFull.
+	maxLogSize   int64
+	maxLogWait   time.Duration
+	logSpaceCond *sync.Cond // Signalled on rm.mtx when the log is truncated or its maximum size changes.
+
+	// The longest a commit waits to share its sync with other commits (0 to sync each commit on its own),
+	// and the batch of commits waiting for their sync, or nil if there isn't one.
+	maxCommitDelay time.Duration
+	commitBatch    *commitBatch
+	commitCond     *sync.Cond // Signalled on rm.mtx when a commit joins a batch, or a batch is synced.
+
+	// Whether Recover folds the edits it redoes into their net effect before applying them (see SetBatchedRedo).
+	batchedRedo bool
+
+	// The sequence number of the last checkpoint that flushed each table since this recovery manager was created.
+	tableCheckpoints map[string]int64
 
 	logFile *os.File   // The log file where the write-ahead log is stored.
+	logSize int64      // The size of the log file in bytes.
+	nextSeq int64      // The sequence number of the next log to be written.
 	mtx     sync.Mutex // A mutex used for allowing safe concurrent use of this struct.
 }
 
@@ -46,25 +87,78 @@ func NewRecoveryManager(
 	if err != nil {
 		return nil, err
 	}
-	return &RecoveryManager{
-		db:      db,
-		tm:      tm,
-		txStack: make(map[uuid.UUID][]editLog),
-		logFile: logFile,
-	}, nil
+	rm := &RecoveryManager{
+		db:               db,
+		tm:               tm,
+		txStack:          make(map[uuid.UUID][]editLog),
+		rollingBack:      make(map[uuid.UUID]bool),
+		logFile:          logFile,
+		tableCheckpoints: make(map[string]int64),
+		// Syncing dominates the cost of a flush, so timing every flush is cheap by comparison.
+		flushSampleEvery: 1,
+	}
+	rm.checkpointCond = sync.NewCond(&rm.mtx)
+	rm.logSpaceCond = sync.NewCond(&rm.mtx)
+	rm.commitCond = sync.NewCond(&rm.mtx)
+	fstats, err := logFile.Stat()
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	rm.logSize = fstats.Size()
+	if err := rm.dropTornLog(); err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	// Continue numbering logs from the last log in the log file.
+	lastSeq, err := rm.lastSeq()
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	rm.nextSeq = lastSeq + 1
+	return rm, nil
+}
+
+// SetMaxEditsPerTransaction limits the number of edits each transaction can make
+// before it must be committed or aborted. A limit of 0 means there is no limit.
+func (rm *RecoveryManager) SetMaxEditsPerTransaction(maxEdits int) {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	rm.maxEdits = maxEdits
 }
 
 // flushLog serializes the specified log and immediately appends it
-// to the end of log file on disk. Expects rm.mtx to be locked.
+// to the end of log file on disk, prefixed by its sequence number. Expects rm.mtx to be locked.
 func (rm *RecoveryManager) flushLog(log log) error {
-	_, err := rm.logFile.WriteString(log.toString())
+	sampled := rm.flushSampleEvery > 0 && rm.nextSeq%rm.flushSampleEvery == 0
+	var start time.Time
+	if sampled {
+		start = time.Now()
+	}
+	err := rm.appendLog(log)
 	if err != nil {
 		return err
 	}
 	err = rm.logFile.Sync()
+	if sampled && err == nil {
+		rm.metrics.LogFlush.record(time.Since(start))
+	}
 	return err
 }
 
+// appendLog serializes the specified log and appends it to the end of the log file, prefixed by its
+// sequence number, without syncing it to disk. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) appendLog(log log) error {
+	n, err := rm.writeLog(fmt.Sprintf("%d %s", rm.nextSeq, log.toString()))
+	rm.logSize += int64(n)
+	if err != nil {
+		return err
+	}
+	rm.nextSeq++
+	return nil
+}
+
 // Table records the creation of a table to the write-ahead log.
 func (rm *RecoveryManager) Table(tblType string, tblName string) error {
 	rm.mtx.Lock()
@@ -73,6 +167,9 @@ func (rm *RecoveryManager) Table(tblType string, tblName string) error {
 		tblType: tblType,
 		tblName: tblName,
 	}
+	if err := rm.waitForLogSpace(tl); err != nil {
+		return err
+	}
 	err := rm.flushLog(tl)
 	if err != nil {
 		return fmt.Errorf("error writing a Table log: %w", err)
@@ -80,10 +177,50 @@ func (rm *RecoveryManager) Table(tblType string, tblName string) error {
 	return nil
 }
 
+// Convert records the conversion of a table to another type to the write-ahead log.
+// The table is already converted, so like a commit, the log is written even if the log is full.
+func (rm *RecoveryManager) Convert(tblType string, tblName string) error {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	cl := convertLog{
+		tblName: tblName,
+		tblType: tblType,
+	}
+	err := rm.flushLog(cl)
+	if err != nil {
+		return fmt.Errorf("error writing a Convert log: %w", err)
+	}
+	return nil
+}
+
 // Edit records an individual entry change (insert, update, deletion) to the write-ahead log.
+// Returns ErrTransactionTooLarge instead if the transaction has reached the maximum number of edits,
+// or ErrWALFull if the write-ahead log stays full (see SetMaxLogSize).
 func (rm *RecoveryManager) Edit(clientId uuid.UUID, table database.Index, action action, key int64, oldval int64, newval int64) error {
 	rm.mtx.Lock()
 	defer rm.mtx.Unlock()
+	if !rm.rollingBack[clientId] {
+		if rm.maxEdits > 0 && len(rm.txStack[clientId]) >= rm.maxEdits {
+			return ErrTransactionTooLarge
+		}
+		if err := rm.waitForLogSpace(editLog{clientId, table.GetName(), action, key, oldval, newval}); err != nil {
+			return err
+		}
+	}
+	return rm.logEdit(clientId, table, action, key, oldval, newval)
+}
+
+// markNoOp records an edit that reverses the transaction's last edit, regardless of the
+// maximum number of edits. Used when the last edit was logged but failed to be carried out.
+func (rm *RecoveryManager) markNoOp(clientId uuid.UUID, table database.Index, action action, key int64, oldval int64, newval int64) error {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	return rm.logEdit(clientId, table, action, key, oldval, newval)
+}
+
+// logEdit pushes an edit log onto the transaction's stack and writes it to the write-ahead log.
+// Expects rm.mtx to be locked.
+func (rm *RecoveryManager) logEdit(clientId uuid.UUID, table database.Index, action action, key int64, oldval int64, newval int64) error {
 	log := editLog{clientId, table.GetName(), action, key, oldval, newval}
 	rm.txStack[clientId] = append(rm.txStack[clientId], log)
 	err := rm.flushLog(log)
@@ -98,6 +235,9 @@ func (rm *RecoveryManager) Start(clientId uuid.UUID) error {
 	rm.mtx.Lock()
 	defer rm.mtx.Unlock()
 	sl := startLog{clientId}
+	if err := rm.waitForLogSpace(sl); err != nil {
+		return err
+	}
 	rm.txStack[clientId] = make([]editLog, 0)
 	err := rm.flushLog(sl)
 	if err != nil {
@@ -112,38 +252,200 @@ func (rm *RecoveryManager) Commit(clientId uuid.UUID) error {
 	defer rm.mtx.Unlock()
 	cl := commitLog{clientId}
 	delete(rm.txStack, clientId)
-	err := rm.flushLog(cl)
+	var err error
+	if rm.maxCommitDelay > 0 {
+		err = rm.appendLog(cl)
+		if err == nil {
+			err = rm.syncCommit()
+		}
+	} else {
+		err = rm.flushLog(cl)
+	}
 	if err != nil {
 		return fmt.Errorf("error writing a Commit log: %w", err)
 	}
 	return nil
 }
 
-// Checkpoint flushes all pages to disk and creates a checkpoint to recover the database
-// from in case of a crash. Writes a checkpoint log with all the ids of active, uncommitted transactions
-// to the write-ahead log.
+// popEdits removes the last n edit logs from the client's transaction stack,
+// without writing anything to the write-ahead log.
+// Returns an error if the client's transaction stack has fewer than n edit logs.
+func (rm *RecoveryManager) popEdits(clientId uuid.UUID, n int) error {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	stack := rm.txStack[clientId]
+	if len(stack) < n {
+		return fmt.Errorf("cannot pop %d edits from a transaction stack of size %d", n, len(stack))
+	}
+	rm.txStack[clientId] = stack[:len(stack)-n]
+	return nil
+}
+
+// Checkpoint flushes the pages of every table modified since the last checkpoint to disk and creates a checkpoint
+// to recover the database from in case of a crash. Writes a checkpoint log with all the ids of active, uncommitted
+// transactions and the names of the flushed tables to the write-ahead log. If checkpoints are paused, waits until they are resumed.
 func (rm *RecoveryManager) Checkpoint() error {
 	rm.mtx.Lock()
 	defer rm.mtx.Unlock()
-	for _, tb := range rm.db.GetTables() {
+	for rm.pausedCheckpoints > 0 {
+		rm.checkpointCond.Wait()
+	}
+	return rm.checkpoint()
+}
+
+// checkpoint carries out a checkpoint. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) checkpoint() error {
+	return rm.checkpointThen(nil)
+}
+
+// checkpointThen carries out a checkpoint, calling afterLog (if not nil) once the checkpoint log is written
+// but before the recovery folder is refreshed, so that the refreshed folder reflects its changes.
+// The folder is refreshed even if afterLog fails, and afterLog's error is returned. Expects rm.mtx to be locked.
+func (rm *RecoveryManager) checkpointThen(afterLog func() error) error {
+	start := time.Now()
+	flushed := make([]string, 0)
+	for name, tb := range rm.db.GetTables() {
+		// Tables that haven't changed have nothing to flush, so their pages aren't locked.
+		if !tb.GetPager().TakeModified() {
+			continue
+		}
+		flushed = append(flushed, name)
+		// Hash tables keep their directory in memory, so it has to be written out along with their pages.
+		if hashTable, ok := tb.(*hash.HashIndex); ok {
+			if err := hashTable.Flush(); err != nil {
+				tb.GetPager().MarkModified()
+				return fmt.Errorf("error flushing table %s: %w", name, err)
+			}
+			continue
+		}
 		tb.GetPager().LockAllPages()
 		tb.GetPager().FlushAllPages()
 		tb.GetPager().UnlockAllPages()
 	}
+	sort.Strings(flushed)
+	// Copy the database before logging the checkpoint, so that a failed copy
+	// leaves neither a checkpoint log nor a half-copied recovery folder behind
+	staged, err := rm.stageDelta()
+	if err != nil {
+		return fmt.Errorf("error copying the database to the recovery folder: %w", err)
+	}
 	activeTxs := make([]uuid.UUID, 0)
 	for id := range rm.txStack {
 		activeTxs = append(activeTxs, id)
 	}
-	cl := checkpointLog{activeTxs}
-	err := rm.flushLog(cl)
+	cl := checkpointLog{activeTxs, flushed}
+	seq := rm.nextSeq
+	err = rm.flushLog(cl)
 	if err != nil {
+		os.RemoveAll(staged)
 		return fmt.Errorf("error writing a Checkpoint log: %w", err)
 	}
-	rm.delta() // Keep this line at the end that ensures checkpointing works correctly!
+	for _, name := range flushed {
+		rm.tableCheckpoints[name] = seq
+	}
+	var afterErr error
+	if afterLog != nil {
+		afterErr = afterLog()
+	}
+	// Keep this line at the end that ensures checkpointing works correctly!
+	if err := rm.delta(staged); err != nil {
+		return fmt.Errorf("error copying the database to the recovery folder: %w", err)
+	}
+	rm.metrics.Checkpoint.record(time.Since(start))
+	return afterErr
+}
+
+// PauseCheckpoints stops checkpoints from being taken until ResumeCheckpoints is called,
+// so that bulk operations are never copied into a checkpoint half-finished.
+// The scheduler skips checkpoints while paused, and Checkpoint waits until checkpoints are resumed.
+// Pauses nest; each call must be matched by a call to ResumeCheckpoints.
+func (rm *RecoveryManager) PauseCheckpoints() {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	rm.pausedCheckpoints++
+}
+
+// ResumeCheckpoints undoes a call to PauseCheckpoints, allowing checkpoints
+// to be taken again once every pause has been resumed.
+func (rm *RecoveryManager) ResumeCheckpoints() {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	if rm.pausedCheckpoints == 0 {
+		return
+	}
+	rm.pausedCheckpoints--
+	if rm.pausedCheckpoints == 0 {
+		rm.checkpointCond.Broadcast()
+	}
+}
+
+// GetNumCheckpoints returns the number of checkpoints this recovery manager has taken.
+func (rm *RecoveryManager) GetNumCheckpoints() int {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	return int(rm.metrics.Checkpoint.Count)
+}
+
+// StartCheckpointScheduler starts taking a checkpoint every interval in the background,
+// skipping any that fall while checkpoints are paused. Failed checkpoints and truncations are logged,
+// counted in the metrics, and kept for LastSchedulerError.
+// Returns an error if the scheduler is already running or the interval isn't positive.
+func (rm *RecoveryManager) StartCheckpointScheduler(interval time.Duration) error {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	if interval <= 0 {
+		return errors.New("checkpoint interval must be positive")
+	}
+	if rm.stopScheduler != nil {
+		return errors.New("checkpoint scheduler is already running")
+	}
+	stop := make(chan struct{})
+	rm.stopScheduler = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				rm.mtx.Lock()
+				if rm.pausedCheckpoints == 0 {
+					err := rm.checkpoint()
+					if err == nil && rm.maxLogSize > 0 {
+						err = rm.truncateLog()
+					}
+					if err != nil {
+						rm.schedulerErr = err
+						rm.metrics.SchedulerErrors++
+						stdlog.Printf("checkpoint scheduler: %v\n", err)
+					}
+				}
+				rm.mtx.Unlock()
+			}
+		}
+	}()
 	return nil
 }
 
-// redo carries out the given table log or edit log's action without
+// LastSchedulerError returns the last error the checkpoint scheduler ran into, or nil if it never failed.
+func (rm *RecoveryManager) LastSchedulerError() error {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	return rm.schedulerErr
+}
+
+// StopCheckpointScheduler stops the checkpoint scheduler, if it is running.
+func (rm *RecoveryManager) StopCheckpointScheduler() {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	if rm.stopScheduler != nil {
+		close(rm.stopScheduler)
+		rm.stopScheduler = nil
+	}
+}
+
+// redo carries out the given table log, convert log, or edit log's action without
 // re-writing the action to the log file. For use when recovering from a crash.
 func (rm *RecoveryManager) redo(log log) error {
 	switch log := log.(type) {
@@ -153,43 +455,56 @@ func (rm *RecoveryManager) redo(log log) error {
 		if err != nil {
 			return err
 		}
+	case convertLog:
+		return redoConvert(rm.db, log)
 	case editLog:
 		switch log.action {
 		case INSERT_ACTION:
-			payload := fmt.Sprintf("insert %v %v into %s", log.key, log.newval, log.tablename)
-			err := database.HandleInsert(rm.db, payload)
+			// The entry may or may not already exist, depending on what was flushed before the crash
+			table, err := rm.db.GetTable(log.tablename)
 			if err != nil {
-				// There is already an entry, try updating
-				payload := fmt.Sprintf("update %s %v %v", log.tablename, log.key, log.newval)
-				err = database.HandleUpdate(rm.db, payload)
-				if err != nil {
-					return err
-				}
+				return err
+			}
+			err = table.Upsert(log.key, log.newval)
+			if err != nil {
+				return err
 			}
 		case UPDATE_ACTION:
-			payload := fmt.Sprintf("update %s %v %v", log.tablename, log.key, log.newval)
+			payload := fmt.Sprintf("update %s %v %v if exists", log.tablename, log.key, log.newval)
 			err := database.HandleUpdate(rm.db, payload)
 			if err != nil {
-				// Entry may have been deleted, try inserting
-				payload := fmt.Sprintf("insert %v %v into %s", log.key, log.newval, log.tablename)
-				err := database.HandleInsert(rm.db, payload)
-				if err != nil {
-					return err
-				}
+				return err
 			}
 		case DELETE_ACTION:
-			payload := fmt.Sprintf("delete %v from %s", log.key, log.tablename)
+			payload := fmt.Sprintf("delete %v from %s if exists", log.key, log.tablename)
 			err := database.HandleDelete(rm.db, payload)
 			if err != nil {
 				return err
 			}
 		}
 	default:
-		return errors.New("can only redo edit or table logs")
+		return errors.New("can only redo edit, table, or convert logs")
 	}
 	return nil
 }
 
+// redoConvert converts the logged table to the logged type, unless it already has that type
+// because the conversion was copied into the recovery folder before the crash.
+func redoConvert(db *database.Database, l convertLog) error {
+	newType, err := database.ParseIndexType(l.tblType)
+	if err != nil {
+		return err
+	}
+	table, err := db.GetTable(l.tblName)
+	if err != nil {
+		return err
+	}
+	if oldType, err := database.GetIndexType(table); err != nil || oldType == newType {
+		return err
+	}
+	return db.ConvertTable(l.tblName, newType)
+}
+
 // undo carries out the opposite action of the given edit log's action
 // to undo it, returning an error if the undoing action failed.
 // Note: writes a log of the undoing action to the log file.
@@ -228,6 +543,12 @@ func (rm *RecoveryManager) Recover() error {
 	}
 
 	// Step 2: Replay actions from checkpoint to the end of the log
+	rm.mtx.Lock()
+	var batch *redoBatch
+	if rm.batchedRedo {
+		batch = newRedoBatch()
+	}
+	rm.mtx.Unlock()
 	activeTxs := make(map[uuid.UUID]bool)
 	for i := checkpointIndex; i < len(logs); i++ {
 		log := logs[i]
@@ -238,7 +559,18 @@ func (rm *RecoveryManager) Recover() error {
 		case commitLog:
 			delete(activeTxs, l.id)
 			rm.tm.Commit(l.id)
-		case tableLog, editLog:
+		case editLog:
+			if batch != nil {
+				batch.add(l)
+			} else if err := rm.redo(l); err != nil {
+				return fmt.Errorf("error redoing log during recovery: %w", err)
+			}
+		case tableLog, convertLog:
+			if batch != nil {
+				if err := rm.applyBatch(batch); err != nil {
+					return fmt.Errorf("error redoing logs during recovery: %w", err)
+				}
+			}
 			if err := rm.redo(l); err != nil {
 				return fmt.Errorf("error redoing log during recovery: %w", err)
 			}
@@ -249,8 +581,28 @@ func (rm *RecoveryManager) Recover() error {
 			}
 		}
 	}
+	if batch != nil {
+		if err := rm.applyBatch(batch); err != nil {
+			return fmt.Errorf("error redoing logs during recovery: %w", err)
+		}
+	}
 
 	// Step 3: Undo uncommitted transactions
+	// Undoing may take more edits than a transaction is allowed to make.
+	undoing := make([]uuid.UUID, 0, len(activeTxs))
+	rm.mtx.Lock()
+	for id := range activeTxs {
+		undoing = append(undoing, id)
+		rm.rollingBack[id] = true
+	}
+	rm.mtx.Unlock()
+	defer func() {
+		rm.mtx.Lock()
+		for _, id := range undoing {
+			delete(rm.rollingBack, id)
+		}
+		rm.mtx.Unlock()
+	}()
 	for i:=len(logs)-1; len(activeTxs) > 0; i-- {
 		log := logs[i]
 		switch l := log.(type) {
@@ -279,9 +631,42 @@ func (rm *RecoveryManager) Recover() error {
 // Rollback rolls back the current uncommitted transaction for a client.
 // This is called when you abort a transaction.
 func (rm *RecoveryManager) Rollback(clientId uuid.UUID) error {
+	return rm.rollback(clientId, rm.tm.Commit)
+}
+
+// AbortAll rolls back every uncommitted transaction, then aborts any other running transactions.
+// Each aborted client's next call will return concurrency.ErrTransactionAborted.
+func (rm *RecoveryManager) AbortAll() (err error) {
+	rm.mtx.Lock()
+	clientIds := make([]uuid.UUID, 0, len(rm.txStack))
+	for id := range rm.txStack {
+		clientIds = append(clientIds, id)
+	}
+	rm.mtx.Unlock()
+	for _, id := range clientIds {
+		if curErr := rm.rollback(id, rm.tm.Abort); err == nil {
+			err = curErr
+		}
+	}
+	if curErr := rm.tm.AbortAll(); err == nil {
+		err = curErr
+	}
+	return err
+}
 
+// rollback undoes the client's uncommitted transaction, then calls end to
+// release the transaction's locks and remove it from the transaction manager.
+func (rm *RecoveryManager) rollback(clientId uuid.UUID, end func(uuid.UUID) error) error {
 	// Check if the client has uncommitted logs
+	rm.mtx.Lock()
 	logs, exists := rm.txStack[clientId]
+	rm.rollingBack[clientId] = true
+	rm.mtx.Unlock()
+	defer func() {
+		rm.mtx.Lock()
+		delete(rm.rollingBack, clientId)
+		rm.mtx.Unlock()
+	}()
 	if !exists {
 		return errors.New("transaction not found for rollback")
 	}
@@ -295,15 +680,19 @@ func (rm *RecoveryManager) Rollback(clientId uuid.UUID) error {
 	}
 
 	// Clear the transaction from the txStack
+	rm.mtx.Lock()
 	delete(rm.txStack, clientId)
+	rm.mtx.Unlock()
 
-	// Unlock resources and remove the transaction using TransactionManager's Commit
-	if err := rm.tm.Commit(clientId); err != nil {
-		return fmt.Errorf("error committing transaction during rollback: %w", err)
+	// Unlock resources and remove the transaction using the TransactionManager
+	if err := end(clientId); err != nil {
+		return fmt.Errorf("error ending transaction during rollback: %w", err)
 	}
 
 	// Write a commit log to signify the rollback is complete
 	cl := commitLog{id: clientId}
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
 	if err := rm.flushLog(cl); err != nil {
 		return fmt.Errorf("error writing commit log during rollback: %w", err)
 	}
@@ -311,23 +700,30 @@ func (rm *RecoveryManager) Rollback(clientId uuid.UUID) error {
 	return nil
 }
 
-// Primes the database for recovery
+// Primes the database for recovery, starting it fresh, normally, or from its recovery folder (see DetectStartup).
 func Prime(folder string) (*database.Database, error) {
 	// Ensure folder is of the form */
 	base := filepath.Clean(folder)
 	recoveryFolder := base + "-recovery/"
 	dbFolder := base + "/"
 
+	// A crash while swapping in a new recovery folder can leave only the previous one behind
+	if err := restorePreviousFolder(recoveryFolder); err != nil {
+		return nil, err
+	}
+
+	mode, err := DetectStartup(base)
+	if err != nil {
+		return nil, err
+	}
+
 	// If recovery folder doesn't exist, create it and open db folder as normal
-	if _, err := os.Stat(recoveryFolder); err != nil {
-		if os.IsNotExist(err) {
-			err := os.MkdirAll(recoveryFolder, 0775)
-			if err != nil {
-				return nil, err
-			}
-			return database.Open(dbFolder)
+	if mode != RECOVERY_START {
+		if err := os.MkdirAll(recoveryFolder, 0775); err != nil {
+			return nil, fmt.Errorf("error creating recovery folder: %w", err)
 		}
-		return nil, err
+		// A fresh database also gets an empty data folder
+		return database.Open(dbFolder)
 	}
 
 	// If recovery folder exists, replace db folder with recovery folder.
@@ -335,12 +731,17 @@ func Prime(folder string) (*database.Database, error) {
 	logSrcPath := filepath.Join(base, config.LogFileName)
 	if _, err := os.Stat(logSrcPath); err == nil {
 		logDstPath := filepath.Join(recoveryFolder, config.LogFileName)
-		copy.Copy(logSrcPath, logDstPath)
+		err = copy.Copy(logSrcPath, logDstPath+".tmp")
+		if err == nil {
+			err = os.Rename(logDstPath+".tmp", logDstPath)
+		}
+		if err != nil {
+			os.Remove(logDstPath + ".tmp")
+			return nil, fmt.Errorf("error copying log file to the recovery folder: %w", err)
+		}
 	}
-	os.RemoveAll(dbFolder)
-	err := copy.Copy(recoveryFolder, dbFolder)
-	if err != nil {
-		return nil, err
+	if err := replaceFolder(recoveryFolder, dbFolder); err != nil {
+		return nil, fmt.Errorf("error restoring the database folder from the recovery folder: %w", err)
 	}
 	return database.Open(dbFolder)
 }
@@ -349,15 +750,79 @@ func Prime(folder string) (*database.Database, error) {
 ////////////////////////// Recovery Helper Functions ////////////////////////
 /////////////////////////////////////////////////////////////////////////////
 
-// delta copies the entire database to a backup recovery folder.
+// stageDelta copies the entire database to a temporary folder next to the backup recovery folder,
+// returning the temporary folder's path. Should be called before logging a checkpoint.
+func (rm *RecoveryManager) stageDelta() (string, error) {
+	folder := strings.TrimSuffix(rm.db.GetBasePath(), "/")
+	return stageFolder(folder+"/", folder+"-recovery/")
+}
+
+// delta makes the copy of the database staged by stageDelta the backup recovery folder,
+// first bringing the staged copy of the log file up to date if the log file is in the database folder.
 // Should be called at end of Checkpoint.
-func (rm *RecoveryManager) delta() error {
+func (rm *RecoveryManager) delta(staged string) error {
 	folder := strings.TrimSuffix(rm.db.GetBasePath(), "/")
-	recoveryFolder := folder + "-recovery/"
-	folder += "/"
-	os.RemoveAll(recoveryFolder)
-	err := copy.Copy(folder, recoveryFolder)
-	return err
+	rel, err := filepath.Rel(folder, rm.logFile.Name())
+	if err == nil && filepath.IsLocal(rel) {
+		err = copy.Copy(rm.logFile.Name(), filepath.Join(staged, rel))
+		if err != nil {
+			os.RemoveAll(staged)
+			return err
+		}
+	}
+	return swapFolder(staged, folder+"-recovery/")
+}
+
+// replaceFolder replaces the contents of the dst folder with a copy of the src folder,
+// so that a failed copy never replaces dst. See stageFolder and swapFolder.
+func replaceFolder(src, dst string) error {
+	staged, err := stageFolder(src, dst)
+	if err != nil {
+		return err
+	}
+	return swapFolder(staged, dst)
+}
+
+// stageFolder copies the src folder to a temporary folder next to the dst folder, returning the temporary folder's path.
+// Removes the temporary folder again if the copy fails.
+func stageFolder(src, dst string) (string, error) {
+	staged := strings.TrimSuffix(dst, "/") + ".tmp"
+	os.RemoveAll(staged)
+	if err := copy.Copy(src, staged); err != nil {
+		os.RemoveAll(staged)
+		return "", err
+	}
+	return staged, nil
+}
+
+// swapFolder renames the staged folder to dst, replacing the previous dst folder.
+// The previous dst folder is moved aside under its ".old" name until the staged folder is in place,
+// so a crash mid-swap leaves it for restorePreviousFolder to put back.
+func swapFolder(staged, dst string) error {
+	dst = strings.TrimSuffix(dst, "/")
+	old := dst + ".old"
+	os.RemoveAll(old)
+	if err := os.Rename(dst, old); err != nil && !errors.Is(err, os.ErrNotExist) {
+		os.RemoveAll(staged)
+		return err
+	}
+	if err := os.Rename(staged, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+// restorePreviousFolder puts back the previous copy of the given folder
+// if replaceFolder was interrupted after moving it aside.
+func restorePreviousFolder(folder string) error {
+	folder = strings.TrimSuffix(folder, "/")
+	if _, err := os.Stat(folder); !errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if _, err := os.Stat(folder + ".old"); err != nil {
+		return nil
+	}
+	return os.Rename(folder+".old", folder)
 }
 
 // Helper method that gets all log strings and the index of the most recent checkpoint from the log file.
@@ -391,20 +856,25 @@ func (rm *RecoveryManager) getRelevantStrings() (
 				if err != nil {
 					return nil, 0, err
 				}
-				id := log.(startLog).id
-				delete(txs, id)
+				// Table names may contain "start", so the parsed log is checked too.
+				if sl, ok := log.(startLog); ok {
+					delete(txs, sl.id)
+				}
 			}
 		}
 		if !checkpointHit && bytes.Contains(line, checkpointTarget) {
-			checkpointHit = true
 			log, err := logFromString(string(line))
 			if err != nil {
 				return nil, 0, err
 			}
-			for _, tx := range log.(checkpointLog).ids {
-				txs[tx] = true
+			// Table names may contain "checkpoint", so the parsed log is checked too.
+			if cl, ok := log.(checkpointLog); ok {
+				checkpointHit = true
+				for _, tx := range cl.ids {
+					txs[tx] = true
+				}
+				checkpointPos = 0
 			}
-			checkpointPos = 0
 		}
 		if checkpointHit && len(txs) <= 0 {
 			break
@@ -413,9 +883,75 @@ func (rm *RecoveryManager) getRelevantStrings() (
 	return relevantStrings, checkpointPos, err
 }
 
+// LastTableCheckpoint returns the sequence number of the last checkpoint that flushed the table, or found = false
+// if no checkpoint has flushed it since the recovery manager was created.
+func (rm *RecoveryManager) LastTableCheckpoint(table string) (seq int64, found bool) {
+	rm.mtx.Lock()
+	defer rm.mtx.Unlock()
+	seq, found = rm.tableCheckpoints[table]
+	return seq, found
+}
+
+// Returns the sequence number of the last log in the log file, or 0 if the log file is empty.
+func (rm *RecoveryManager) lastSeq() (int64, error) {
+	fstats, err := rm.logFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	scanner := backscanner.New(rm.logFile, int(fstats.Size()))
+	for {
+		line, _, err := scanner.LineBytes()
+		if err == io.EOF {
+			return 0, nil
+		} else if err != nil {
+			return 0, err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		seq, _, err := logFromLine(string(line))
+		return seq, err
+	}
+}
+
+// LastCheckpoint returns the sequence number of the most recent checkpoint log and the ids of the
+// transactions that were active when it was taken, or found = false if no checkpoint has been logged.
+// Only reads the log, so it can be called while the database is running.
+func (rm *RecoveryManager) LastCheckpoint() (seq int64, activeTxs []uuid.UUID, found bool, err error) {
+	// Logs are written whole while rm.mtx is held, so the size recorded under it ends on a log boundary.
+	rm.mtx.Lock()
+	fstats, err := rm.logFile.Stat()
+	rm.mtx.Unlock()
+	if err != nil {
+		return 0, nil, false, err
+	}
+	scanner := backscanner.New(rm.logFile, int(fstats.Size()))
+	checkpointTarget := []byte("checkpoint")
+	for {
+		line, _, err := scanner.LineBytes()
+		if err == io.EOF {
+			return 0, nil, false, nil
+		} else if err != nil {
+			return 0, nil, false, err
+		}
+		if !bytes.Contains(line, checkpointTarget) {
+			continue
+		}
+		seq, log, err := logFromLine(string(line))
+		if err != nil {
+			return 0, nil, false, err
+		}
+		// Table names may contain "checkpoint", so the parsed log is checked too.
+		if cl, ok := log.(checkpointLog); ok {
+			return seq, cl.ids, true, nil
+		}
+	}
+}
+
 // Returns ALL the logs written to disk and the index of the most recent checkpoint log
 // (or len(logs) if there were no checkpoint logs).
-// Alternatively returns an error if there is an IO or deserialization problem.
+// Alternatively returns an error if there is an IO or deserialization problem,
+// or an ErrLogGap naming the first gap if the logs' sequence numbers aren't contiguous.
 func (rm *RecoveryManager) readLogs() (logs []log, checkpointIndex int, err error) {
 	strings, checkpointIndex, err := rm.getRelevantStrings()
 	if err != nil {
@@ -423,11 +959,17 @@ func (rm *RecoveryManager) readLogs() (logs []log, checkpointIndex int, err erro
 	}
 	if len(strings) > 0 {
 		logs = make([]log, len(strings)-1)
+		var prevSeq int64
 		for i, s := range strings[:len(strings)-1] {
-			log, err := logFromString(s)
+			seq, log, err := logFromLine(s)
 			if err != nil {
 				return nil, 0, err
 			}
+			if i > 0 && seq != prevSeq+1 {
+				return nil, 0, fmt.Errorf("%w: expected sequence number %d after %d, but found %d",
+					ErrLogGap, prevSeq+1, prevSeq, seq)
+			}
+			prevSeq = seq
 			logs[i] = log
 		}
 	} else {
@@ -3,11 +3,12 @@ package recovery
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"sort"
 	"strings"
 
 	"dinodb/pkg/concurrency"
 	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
 	"dinodb/pkg/repl"
 
 	"github.com/google/uuid"
@@ -20,26 +21,36 @@ func RecoveryREPL(db *database.Database, tm *concurrency.TransactionManager, rm
 		return HandleCreateTable(db, rm, payload)
 	}, "Create a table. usage: create <btree|hash> table <table>")
 
-	r.AddCommand("find", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return HandleFind(db, tm, rm, payload, replConfig.GetAddr())
+	r.AddResultCommand("find", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return FindResult(db, tm, rm, database.WithDefaultTable(payload, replConfig), replConfig.GetAddr())
 	}, "Find an element. usage: find <key> from <table>")
 
 	r.AddCommand("insert", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return "", HandleInsert(db, tm, rm, payload, replConfig.GetAddr())
-	}, "Insert an element. usage: insert <key> <value> into <table>")
+		return database.HandleInsertPairs(database.WithDefaultTable(payload, replConfig), func(payload string) error {
+			return HandleInsert(db, tm, rm, payload, replConfig.GetAddr())
+		})
+	}, "Insert elements. usage: insert <key> <value> into <table> | insert (<key> <value>)... into <table>")
 
 	r.AddCommand("update", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return "", HandleUpdate(db, tm, rm, payload, replConfig.GetAddr())
 	}, "Update en element. usage: update <table> <key> <value>")
 
 	r.AddCommand("delete", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return "", HandleDelete(db, tm, rm, payload, replConfig.GetAddr())
+		return "", HandleDelete(db, tm, rm, database.WithDefaultTable(payload, replConfig), replConfig.GetAddr())
 	}, "Delete an element. usage: delete <key> from <table>")
 
-	r.AddCommand("select", func(payload string, replConfig *repl.REPLConfig) (string, error) {
-		return HandleSelect(db, tm, rm, payload, replConfig.GetAddr())
+	r.AddCommand("rekey", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleRekey(db, tm, rm, payload, replConfig.GetAddr())
+	}, "Change an element's key. usage: rekey <table> <old key> <new key>")
+
+	r.AddResultCommand("select", func(payload string, replConfig *repl.REPLConfig) (repl.Result, error) {
+		return SelectResult(db, tm, rm, database.WithDefaultTable(payload, replConfig), replConfig.GetAddr())
 	}, "Select elements from a table. usage: select from <table>")
 
+	r.AddCommand("use", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return database.HandleUse(db, payload, replConfig)
+	}, "Set the table that find, insert, delete, and select use when they aren't given one. usage: use <table>")
+
 	r.AddCommand("transaction", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return "", HandleTransaction(db, tm, rm, payload, replConfig.GetAddr())
 	}, "Handle transactions. usage: transaction <begin|commit>")
@@ -48,10 +59,22 @@ func RecoveryREPL(db *database.Database, tm *concurrency.TransactionManager, rm
 		return "", HandleLock(db, tm, payload, replConfig.GetAddr())
 	}, "Grabs a write lock on a resource. usage: lock <table> <key>")
 
+	r.AddCommand("isolation", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleIsolation(db, tm, payload, replConfig.GetAddr())
+	}, "Set the current transaction's isolation level. usage: isolation <serializable|read_committed>")
+
 	r.AddCommand("checkpoint", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return "", HandleCheckpoint(db, tm, rm, payload, replConfig.GetAddr())
 	}, "Saves a checkpoint of the current database state and running transactions. usage: checkpoint")
 
+	r.AddCommand("last_checkpoint", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleLastCheckpoint(rm, payload)
+	}, "Show the most recent checkpoint and the transactions active at it. usage: last_checkpoint")
+
+	r.AddCommand("convert", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleConvert(db, tm, rm, payload)
+	}, "Convert a table to another index type. usage: convert <table> to <btree|hash>")
+
 	r.AddCommand("abort", func(payload string, replConfig *repl.REPLConfig) (string, error) {
 		return "", HandleAbort(db, tm, rm, payload, replConfig.GetAddr())
 	}, "Simulate an abort of the current transaction. usage: abort")
@@ -64,6 +87,29 @@ func RecoveryREPL(db *database.Database, tm *concurrency.TransactionManager, rm
 		return HandlePretty(db, payload)
 	}, "Print out the internal data representation. usage: pretty")
 
+	// Roll back the transaction of a client whose command panicked, so its locks don't block other clients.
+	// Clients without logged edits have nothing to undo, so their transaction is just aborted.
+	r.AddPanicHandler(func(clientId uuid.UUID) {
+		if err := rm.rollback(clientId, tm.Abort); err != nil {
+			tm.Abort(clientId)
+		}
+	})
+
+	// Trace the pages each command accesses for clients that turn tracing on.
+	r.SetTracer(db.Trace)
+
+	return r
+}
+
+// Admin REPL, with commands that affect every client's transactions.
+func AdminREPL(rm *RecoveryManager) *repl.REPL {
+	r := repl.NewRepl()
+	r.AddCommand("killall", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return "", HandleKillAll(rm, payload)
+	}, "Roll back and abort every running transaction. usage: killall")
+	r.AddCommand("compact", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		return HandleCompact(rm, payload)
+	}, "Checkpoint, then shrink the write-ahead log to what recovery needs. usage: compact log")
 	return r
 }
 
@@ -77,11 +123,15 @@ func HandleTransaction(db *database.Database, tm *concurrency.TransactionManager
 	}
 	switch fields[1] {
 	case "begin":
-		err = rm.Start(clientId)
-		if err != nil {
+		// A resumed transaction keeps its undo stack, so it isn't logged as starting again.
+		var resumed bool
+		if _, resumed, err = tm.BeginOrResume(clientId); err != nil || resumed {
+			return err
+		}
+		if err = rm.Start(clientId); err != nil {
+			tm.Commit(clientId)
 			return err
 		}
-		err = tm.Begin(clientId)
 	case "commit":
 		err = rm.Commit(clientId)
 		if err != nil {
@@ -105,47 +155,58 @@ func HandleCreateTable(db *database.Database, rm *RecoveryManager, payload strin
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: create <type> table <table>
-	if numFields != 4 || fields[2] != "table" || (fields[1] != "btree" && fields[1] != "hash") {
+	if numFields != 4 || fields[2] != "table" {
 		return "", fmt.Errorf("usage: create <btree|hash> table <table>")
 	}
-	err = rm.Table(fields[1], fields[3])
+	tableType, err := database.ParseIndexType(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("create error: %w", err)
+	}
+	err = rm.Table(tableType.String(), fields[3])
 	if err != nil {
 		return "", err
 	}
 	return database.HandleCreateTable(db, payload)
 }
 
-// Handle find.
+// Handle find. A transaction always reads its own uncommitted writes: edits are applied to the table as they are
+// made, and the write lock taken for each edit is held until commit, so under either isolation level a find sees
+// the transaction's latest insert, update, or delete of the key.
 func HandleFind(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (output string, err error) {
 	return concurrency.HandleFind(db, tm, payload, clientId)
 }
 
+// Handle find, returning the found entry as the result's only row. Reads the transaction's own writes, as HandleFind does.
+func FindResult(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (result repl.Result, err error) {
+	return concurrency.FindResult(db, tm, payload, clientId)
+}
+
 // Handle insert.
 func HandleInsert(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: insert <key> <value> into <table>
-	var key, newval int
+	var key, newval int64
 	var table database.Index
 	if numFields != 5 || fields[3] != "into" {
 		return fmt.Errorf("usage: insert <key> <value> into <table>")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
-	if newval, err = strconv.Atoi(fields[2]); err != nil {
+	if newval, err = entry.ParseInt64(fields[2]); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
 	if table, err = db.GetTable(fields[4]); err != nil {
 		return fmt.Errorf("insert error: %v", err)
 	}
 	// First, check that the desired value doesn't exist.
-	_, err = table.Find(int64(key))
+	existing, err := table.Find(key)
 	if err == nil {
-		return errors.New("insert error: key already exists")
+		return fmt.Errorf("insert error: %w", &database.KeyExistsError{Existing: existing})
 	}
 	// Log.
-	err = rm.Edit(clientId, table, INSERT_ACTION, int64(key), 0, int64(newval))
+	err = rm.Edit(clientId, table, INSERT_ACTION, key, 0, newval)
 	if err != nil {
 		return err
 	}
@@ -153,20 +214,24 @@ func HandleInsert(db *database.Database, tm *concurrency.TransactionManager, rm
 	err = concurrency.HandleInsert(db, tm, payload, clientId)
 	if err != nil {
 		// Add a log to mark this insert as a no-op.
-		ederr := rm.Edit(clientId, table, DELETE_ACTION, int64(key), int64(newval), int64(0))
+		ederr := rm.markNoOp(clientId, table, DELETE_ACTION, key, newval, int64(0))
 		if ederr != nil {
 			return fmt.Errorf("error marking insert as no-op: %w", ederr)
 		}
 		// Then pop the last two actions from the transaction stack because
 		// these last two actions were no-ops.
-		stack := rm.txStack[clientId]
-		rm.txStack[clientId] = stack[:len(stack)-2]
+		poperr := rm.popEdits(clientId, 2)
+		if poperr != nil {
+			return fmt.Errorf("error marking insert as no-op: %w", poperr)
+		}
 		rberr := rm.Rollback(clientId)
 		if rberr != nil {
 			return rberr
 		}
+		// Don't wrap err: the whole transaction was rolled back, not just this insert.
+		return fmt.Errorf("%v, transaction rolled back", err)
 	}
-	return err
+	return nil
 }
 
 // Handle update.
@@ -174,27 +239,27 @@ func HandleUpdate(db *database.Database, tm *concurrency.TransactionManager, rm
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: update <table> <key> <value>
-	var key, newval int
+	var key, newval int64
 	var table database.Index
 	if numFields != 4 {
 		return fmt.Errorf("usage: update <table> <key> <value>")
 	}
-	if key, err = strconv.Atoi(fields[2]); err != nil {
+	if key, err = entry.ParseInt64(fields[2]); err != nil {
 		return fmt.Errorf("update error: %v", err)
 	}
-	if newval, err = strconv.Atoi(fields[3]); err != nil {
+	if newval, err = entry.ParseInt64(fields[3]); err != nil {
 		return fmt.Errorf("update error: %v", err)
 	}
 	if table, err = db.GetTable(fields[1]); err != nil {
 		return fmt.Errorf("update error: %v", err)
 	}
 	// First, check that the desired value exists.
-	oldval, err := table.Find(int64(key))
+	oldval, err := table.Find(key)
 	if err != nil {
 		return errors.New("update error: key doesn't exists")
 	}
 	// Log.
-	err = rm.Edit(clientId, table, UPDATE_ACTION, int64(key), oldval.Value, int64(newval))
+	err = rm.Edit(clientId, table, UPDATE_ACTION, key, oldval.Value, newval)
 	if err != nil {
 		return err
 	}
@@ -202,14 +267,16 @@ func HandleUpdate(db *database.Database, tm *concurrency.TransactionManager, rm
 	err = concurrency.HandleUpdate(db, tm, payload, clientId)
 	if err != nil {
 		// Add a log to mark this update as a no-op.
-		ederr := rm.Edit(clientId, table, UPDATE_ACTION, int64(key), int64(newval), oldval.Value)
+		ederr := rm.markNoOp(clientId, table, UPDATE_ACTION, key, newval, oldval.Value)
 		if ederr != nil {
 			return fmt.Errorf("error marking update as no-op: %w", ederr)
 		}
 		// Then pop the last two actions from the transaction stack because
 		// these last two actions were no-ops.
-		stack := rm.txStack[clientId]
-		rm.txStack[clientId] = stack[:len(stack)-2]
+		poperr := rm.popEdits(clientId, 2)
+		if poperr != nil {
+			return fmt.Errorf("error marking update as no-op: %w", poperr)
+		}
 		rberr := rm.Rollback(clientId)
 		if rberr != nil {
 			return rberr
@@ -223,24 +290,24 @@ func HandleDelete(db *database.Database, tm *concurrency.TransactionManager, rm
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: delete <key> from <table>
-	var key int
+	var key int64
 	var table database.Index
 	if numFields != 4 || fields[2] != "from" {
 		return fmt.Errorf("usage: delete <key> from <table>")
 	}
-	if key, err = strconv.Atoi(fields[1]); err != nil {
+	if key, err = entry.ParseInt64(fields[1]); err != nil {
 		return fmt.Errorf("delete error: %v", err)
 	}
 	if table, err = db.GetTable(fields[3]); err != nil {
 		return fmt.Errorf("delete error: %v", err)
 	}
 	// First, check that the desired value exists.
-	oldval, err := table.Find(int64(key))
+	oldval, err := table.Find(key)
 	if err != nil {
 		return errors.New("delete error: key doesn't exists")
 	}
 	// Log.
-	err = rm.Edit(clientId, table, DELETE_ACTION, int64(key), oldval.Value, 0)
+	err = rm.Edit(clientId, table, DELETE_ACTION, key, oldval.Value, 0)
 	if err != nil {
 		return err
 	}
@@ -248,14 +315,87 @@ func HandleDelete(db *database.Database, tm *concurrency.TransactionManager, rm
 	err = concurrency.HandleDelete(db, tm, payload, clientId)
 	if err != nil {
 		// Add a log to mark this delete as a no-op.
-		ederr := rm.Edit(clientId, table, INSERT_ACTION, int64(key), 0, oldval.Value)
+		ederr := rm.markNoOp(clientId, table, INSERT_ACTION, key, 0, oldval.Value)
 		if ederr != nil {
 			return fmt.Errorf("error marking delete as no-op: %w", ederr)
 		}
 		// Then pop the last two actions from the transaction stack because
 		// these last two actions were no-ops.
-		stack := rm.txStack[clientId]
-		rm.txStack[clientId] = stack[:len(stack)-2]
+		poperr := rm.popEdits(clientId, 2)
+		if poperr != nil {
+			return fmt.Errorf("error marking delete as no-op: %w", poperr)
+		}
+		rberr := rm.Rollback(clientId)
+		if rberr != nil {
+			return rberr
+		}
+	}
+	return err
+}
+
+// Handle rekey. Logged as a delete of the old key followed by an insert of the new key,
+// so the two are redone or undone together as part of the transaction.
+func HandleRekey(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: rekey <table> <old key> <new key>
+	var oldKey, newKey int64
+	var table database.Index
+	if numFields != 4 {
+		return fmt.Errorf("usage: rekey <table> <old key> <new key>")
+	}
+	if oldKey, err = entry.ParseInt64(fields[2]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	if newKey, err = entry.ParseInt64(fields[3]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	if table, err = db.GetTable(fields[1]); err != nil {
+		return fmt.Errorf("rekey error: %v", err)
+	}
+	// First, check that the old key exists and the new key doesn't.
+	oldval, err := table.Find(oldKey)
+	if err != nil {
+		return errors.New("rekey error: key doesn't exists")
+	}
+	if _, err = table.Find(newKey); err == nil {
+		return errors.New("rekey error: new key already exists")
+	}
+	// Log.
+	err = rm.Edit(clientId, table, DELETE_ACTION, oldKey, oldval.Value, 0)
+	if err != nil {
+		return err
+	}
+	err = rm.Edit(clientId, table, INSERT_ACTION, newKey, 0, oldval.Value)
+	if err != nil {
+		// Mark the logged delete as a no-op, then pop it and its reversal.
+		ederr := rm.markNoOp(clientId, table, INSERT_ACTION, oldKey, 0, oldval.Value)
+		if ederr != nil {
+			return fmt.Errorf("error marking rekey as no-op: %w", ederr)
+		}
+		poperr := rm.popEdits(clientId, 2)
+		if poperr != nil {
+			return fmt.Errorf("error marking rekey as no-op: %w", poperr)
+		}
+		return err
+	}
+	// Run transaction rekey.
+	err = concurrency.HandleRekey(db, tm, payload, clientId)
+	if err != nil {
+		// Add logs to mark both edits as no-ops, in reverse order.
+		ederr := rm.markNoOp(clientId, table, DELETE_ACTION, newKey, oldval.Value, 0)
+		if ederr == nil {
+			ederr = rm.markNoOp(clientId, table, INSERT_ACTION, oldKey, 0, oldval.Value)
+		}
+		if ederr != nil {
+			return fmt.Errorf("error marking rekey as no-op: %w", ederr)
+		}
+		// Then pop the last four actions from the transaction stack because
+		// these last four actions were no-ops.
+		poperr := rm.popEdits(clientId, 4)
+		if poperr != nil {
+			return fmt.Errorf("error marking rekey as no-op: %w", poperr)
+		}
 		rberr := rm.Rollback(clientId)
 		if rberr != nil {
 			return rberr
@@ -266,15 +406,23 @@ func HandleDelete(db *database.Database, tm *concurrency.TransactionManager, rm
 
 // Handle select.
 func HandleSelect(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (output string, err error) {
+	result, err := SelectResult(db, tm, rm, payload, clientId)
+	if err != nil {
+		return "", err
+	}
+	return result.Format(repl.HUMAN_FORMAT)
+}
+
+// Handle select, returning the selected entries as the result's rows.
+func SelectResult(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (result repl.Result, err error) {
 	fields := strings.Fields(payload)
 	numFields := len(fields)
 	// Usage: select from <table>
 	if numFields != 3 || fields[1] != "from" {
-		return "", fmt.Errorf("usage: select from <table>")
+		return result, fmt.Errorf("usage: select from <table>")
 	}
 	// NOTE: Select is unsafe; not locking anything. May provide an inconsistent view of the database.
-	output, err = database.HandleSelect(db, payload)
-	return
+	return database.SelectResult(db, payload)
 }
 
 // Handle write lock requests.
@@ -282,6 +430,11 @@ func HandleLock(db *database.Database, tm *concurrency.TransactionManager, paylo
 	return concurrency.HandleLock(db, tm, payload, clientId)
 }
 
+// Handle isolation.
+func HandleIsolation(db *database.Database, tm *concurrency.TransactionManager, payload string, clientId uuid.UUID) (err error) {
+	return concurrency.HandleIsolation(db, tm, payload, clientId)
+}
+
 // Handle checkpoint.
 func HandleCheckpoint(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (err error) {
 	fields := strings.Fields(payload)
@@ -298,6 +451,66 @@ func HandleCheckpoint(db *database.Database, tm *concurrency.TransactionManager,
 	return err
 }
 
+// Handle last checkpoint.
+func HandleLastCheckpoint(rm *RecoveryManager, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: last_checkpoint
+	if numFields != 1 {
+		return "", fmt.Errorf("usage: last_checkpoint")
+	}
+	seq, activeTxs, found, err := rm.LastCheckpoint()
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "no checkpoint has been logged", nil
+	}
+	if len(activeTxs) == 0 {
+		return fmt.Sprintf("checkpoint at log %d, no active transactions", seq), nil
+	}
+	ids := make([]string, len(activeTxs))
+	for i, id := range activeTxs {
+		ids[i] = id.String()
+	}
+	sort.Strings(ids)
+	return fmt.Sprintf("checkpoint at log %d, active transactions: %s", seq, strings.Join(ids, ", ")), nil
+}
+
+// Handle convert. Rejected if any transaction holds a lock on the table, and no transaction can lock it
+// until the conversion is logged, so that redo converts the table between the same edits.
+// Checkpoints are paused for the duration of the conversion so that no checkpoint copies a half-converted table.
+func HandleConvert(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	// Usage: convert <table> to <btree|hash>
+	if len(fields) != 4 {
+		return database.HandleConvert(db, payload)
+	}
+	table, err := db.GetTable(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("convert error: %v", err)
+	}
+	rm.PauseCheckpoints()
+	defer rm.ResumeCheckpoints()
+	err = tm.WithTableReserved(table, func() (err error) {
+		if output, err = database.HandleConvert(db, payload); err != nil {
+			return err
+		}
+		newType, err := database.ParseIndexType(fields[3])
+		if err != nil {
+			return err
+		}
+		if err = rm.Convert(newType.String(), fields[1]); err != nil {
+			return fmt.Errorf("convert error: %v", err)
+		}
+		return nil
+	})
+	if errors.Is(err, concurrency.ErrTableInUse) {
+		return "", fmt.Errorf("convert error: %w", err)
+	}
+	return output, err
+}
+
 // Handle abort.
 func HandleAbort(db *database.Database, tm *concurrency.TransactionManager, rm *RecoveryManager, payload string, clientId uuid.UUID) (err error) {
 	fields := strings.Fields(payload)
@@ -323,10 +536,37 @@ func HandleCrash(db *database.Database, tm *concurrency.TransactionManager, rm *
 	if numFields != 1 {
 		return fmt.Errorf("usage: crash")
 	}
-	panic("it's the end of the world!")
+	// Wrapping repl.ErrCrash keeps the REPL from containing the panic
+	panic(fmt.Errorf("%w: it's the end of the world!", repl.ErrCrash))
+}
+
+// Handle aborting all transactions.
+func HandleKillAll(rm *RecoveryManager, payload string) (err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: killall
+	if numFields != 1 {
+		return fmt.Errorf("usage: killall")
+	}
+	return rm.AbortAll()
 }
 
 // Handle pretty printing.
 func HandlePretty(db *database.Database, payload string) (output string, err error) {
 	return database.HandlePretty(db, payload)
 }
+
+// Handle compacting the write-ahead log.
+func HandleCompact(rm *RecoveryManager, payload string) (output string, err error) {
+	fields := strings.Fields(payload)
+	numFields := len(fields)
+	// Usage: compact log
+	if numFields != 2 || fields[1] != "log" {
+		return "", fmt.Errorf("usage: compact log")
+	}
+	before, after, err := rm.CompactLog()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("compacted the log from %d to %d bytes", before, after), nil
+}
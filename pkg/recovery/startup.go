@@ -0,0 +1,71 @@
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// StartupMode describes the state Prime finds a database's folders in.
+type StartupMode int
+
+const (
+	// Neither the database folder nor its recovery folder exists.
+	FRESH_START StartupMode = iota
+	// The database folder exists, but no recovery folder has been made for it.
+	NORMAL_START
+	// The recovery folder exists, so the database folder is rebuilt from it.
+	RECOVERY_START
+)
+
+// String returns the startup mode's name.
+func (mode StartupMode) String() string {
+	switch mode {
+	case FRESH_START:
+		return "fresh"
+	case NORMAL_START:
+		return "normal"
+	case RECOVERY_START:
+		return "recovering"
+	default:
+		return fmt.Sprintf("StartupMode(%d)", int(mode))
+	}
+}
+
+// DetectStartup returns how Prime would start the database stored in the given folder.
+// Doesn't modify either folder; a recovery folder left behind mid-swap only counts once Prime restores it.
+func DetectStartup(folder string) (StartupMode, error) {
+	base := filepath.Clean(folder)
+	recoveryExists, err := folderExists(base + "-recovery")
+	if err != nil {
+		return 0, err
+	}
+	if recoveryExists {
+		return RECOVERY_START, nil
+	}
+	dbExists, err := folderExists(base)
+	if err != nil {
+		return 0, err
+	}
+	if dbExists {
+		return NORMAL_START, nil
+	}
+	return FRESH_START, nil
+}
+
+// folderExists returns whether there is a folder at the given path,
+// or an error if the path can't be checked or isn't a folder.
+func folderExists(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error checking folder %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return false, fmt.Errorf("%s is not a folder", path)
+	}
+	return true, nil
+}
@@ -0,0 +1,63 @@
+package repl
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// ArgSpec describes the arguments a command expects, so that clients can check commands before sending them.
+type ArgSpec struct {
+	Args      []string `json:"args"`                // The arguments' names, in order, e.g. ["key", "from", "table"].
+	NumFields []int    `json:"numFields,omitempty"` // The accepted numbers of fields, counting the trigger; any if empty.
+}
+
+// CommandInfo describes a registered command in the listing written by the commands meta-command.
+type CommandInfo struct {
+	Trigger string   `json:"trigger"`
+	Help    string   `json:"help"`
+	Spec    *ArgSpec `json:"spec,omitempty"` // Nil if the command was registered without one.
+}
+
+// Add a command, along with its help string and argument spec, to the set of commands.
+// If the spec gives the accepted numbers of fields, they also validate the command's payloads for ValidateScript.
+func (r *REPL) AddCommandWithSpec(trigger string, action ReplCommand, help string, spec ArgSpec) {
+	if trigger == TriggerHelpMetacommand {
+		return
+	}
+	r.AddCommand(trigger, action, help)
+	r.specs[trigger] = spec
+	if len(spec.NumFields) > 0 {
+		r.AddValidator(trigger, NumFields(spec.NumFields...))
+	}
+}
+
+// Get the argument specs of the commands registered with one.
+func (r *REPL) GetSpecs() map[string]ArgSpec {
+	return r.specs
+}
+
+// Commands describes every registered command, ordered by trigger.
+func (r *REPL) Commands() []CommandInfo {
+	infos := make([]CommandInfo, 0, len(r.commands))
+	for trigger := range r.commands {
+		info := CommandInfo{Trigger: trigger, Help: r.help[trigger]}
+		if spec, ok := r.specs[trigger]; ok {
+			info.Spec = &spec
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b CommandInfo) int {
+		return strings.Compare(a.Trigger, b.Trigger)
+	})
+	return infos
+}
+
+// CommandsString returns the JSON listing of every registered command written by the commands meta-command.
+func (r *REPL) CommandsString() string {
+	data, err := json.Marshal(r.Commands())
+	if err != nil {
+		return ErrorPrependStr + err.Error() + "\n"
+	}
+	return string(data) + "\n"
+}
@@ -0,0 +1,45 @@
+package repl
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Error for when a command is rejected because the client sent too many commands too quickly
+var ErrRateLimited = errors.New("rate limited")
+
+// A token bucket rate limiter. The bucket holds up to `burst` tokens and refills at `rate` tokens per second;
+// each allowed command takes one token.
+type RateLimiter struct {
+	rate   float64    // Tokens added per second
+	burst  float64    // Maximum number of tokens the bucket can hold
+	tokens float64    // Tokens currently in the bucket
+	last   time.Time  // When tokens was last refilled
+	mtx    sync.Mutex // Guards tokens and last
+}
+
+// Construct a RateLimiter that allows `perSecond` commands per second on average,
+// and up to `perSecond` commands in a single burst. The bucket starts full.
+func NewRateLimiter(perSecond int) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(perSecond),
+		burst:  float64(perSecond),
+		tokens: float64(perSecond),
+		last:   time.Now(),
+	}
+}
+
+// Allow reports whether another command may run now, taking a token if so.
+func (rl *RateLimiter) Allow() bool {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	now := time.Now()
+	rl.tokens = min(rl.burst, rl.tokens+now.Sub(rl.last).Seconds()*rl.rate)
+	rl.last = now
+	if rl.tokens < 1 {
+		return false
+	}
+	rl.tokens--
+	return true
+}
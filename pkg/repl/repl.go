@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,6 +20,29 @@ const (
 	// Trigger for the help meta-command that prints out all help strings
 	TriggerHelpMetacommand = ".help"
 
+	// Trigger for the commands meta-command that writes a JSON listing of every command, with its help string and argument spec
+	TriggerCommandsMetacommand = ".commands"
+
+	// Trigger for the pipeline meta-command. Every following line up to PipelineEndSentinel
+	// is run in order, and all of their responses are written together once the batch ends.
+	TriggerPipelineMetacommand = ".pipeline"
+
+	// Line that ends a pipelined batch of commands, and the responses to it
+	PipelineEndSentinel = ".end"
+
+	// Prefix of the header line framing each response in a pipeline.
+	// The header is followed by the command's index in the batch and the length of its response in bytes.
+	PipelineFramePrefix = "RESPONSE "
+
+	// Trigger for the format meta-command, which sets the OutputFormat that ResultCommands' results are rendered in
+	TriggerFormatMetacommand = ".format"
+
+	// Trigger for the trace meta-command. While a client has tracing on, each command's trace is written after its response
+	TriggerTraceMetacommand = ".trace"
+
+	// Trigger for the timing meta-command. While a client has timing on, how long each command took is written after its response
+	TriggerTimingMetacommand = ".timing"
+
 	// String that should be prepended to any error before being sent to the output writer
 	ErrorPrependStr = "ERROR: "
 )
@@ -25,19 +51,38 @@ var (
 	// use in combine repls function
 	ErrOverlappingCommands = errors.New("found overlapping")
 
+	// Error for when a REPL being combined is nil, e.g. because its constructor failed
+	ErrNilRepl = errors.New("cannot combine a nil REPL")
+
 	// Error for when a sent trigger is not associated with any known commands
 	ErrCommandNotFound = errors.New("command not found")
+
+	// Error returned in place of a command's output when the command panics
+	ErrCommandPanicked = errors.New("command panicked")
+
+	// Commands that panic with an error wrapping ErrCrash crash the process deliberately,
+	// rather than having the panic contained
+	ErrCrash = errors.New("crash")
 )
 
 // REPL struct.
 type REPL struct {
-	commands map[string]ReplCommand
-	help     map[string]string
+	commands      map[string]ReplCommand
+	help          map[string]string
+	validators    map[string]ArgValidator    // Check commands' payloads for ValidateScript.
+	specs         map[string]ArgSpec         // The arguments of commands registered with AddCommandWithSpec.
+	panicHandlers []func(clientId uuid.UUID) // Run when one of the client's commands panics.
+	tracer        Tracer                     // Traces commands for clients with tracing on, or nil.
 }
 
 // REPL Config struct.
 type REPLConfig struct {
-	clientId uuid.UUID
+	clientId     uuid.UUID
+	outputFormat OutputFormat
+	closers      []func() // Run when the client's session ends.
+	defaultTable string   // The table used by commands that aren't given one, set by "use".
+	trace        bool     // Whether each command's trace is written after its response, set by ".trace".
+	timing       bool     // Whether how long each command took is written after its response, set by ".timing".
 }
 
 // Get address.
@@ -45,12 +90,46 @@ func (replConfig *REPLConfig) GetAddr() uuid.UUID {
 	return replConfig.clientId
 }
 
+// Get the format that results are rendered in, defaulting to HUMAN_FORMAT.
+func (replConfig *REPLConfig) GetOutputFormat() OutputFormat {
+	if replConfig == nil || replConfig.outputFormat == "" {
+		return HUMAN_FORMAT
+	}
+	return replConfig.outputFormat
+}
+
+// Get the table used by commands that aren't given one, or "" if there isn't one.
+func (replConfig *REPLConfig) GetDefaultTable() string {
+	if replConfig == nil {
+		return ""
+	}
+	return replConfig.defaultTable
+}
+
+// Set the table used by commands that aren't given one.
+func (replConfig *REPLConfig) SetDefaultTable(table string) {
+	replConfig.defaultTable = table
+}
+
+// Register a function to run when the client's session ends, e.g. to release state held for the client.
+func (replConfig *REPLConfig) OnClose(closer func()) {
+	replConfig.closers = append(replConfig.closers, closer)
+}
+
+// Run the functions registered with OnClose, most recent first.
+func (replConfig *REPLConfig) close() {
+	for i := len(replConfig.closers) - 1; i >= 0; i-- {
+		replConfig.closers[i]()
+	}
+	replConfig.closers = nil
+}
+
 // Construct an empty REPL.
 // When a new REPL is created, its commands should be empty.
 func NewRepl() *REPL {
 	/* SOLUTION {{{ */
-	return &REPL{make(map[string]ReplCommand),
-		make(map[string]string)}
+	return &REPL{commands: make(map[string]ReplCommand),
+		help: make(map[string]string), validators: make(map[string]ArgValidator), specs: make(map[string]ArgSpec)}
 	/* SOLUTION }}} */
 }
 
@@ -69,8 +148,14 @@ func contains(s []string, str string) bool {
 /*
 	- Error if the REPLs being combined have any overlapping commands (same trigger).
 	- If no REPLs are given, return a new empty REPL.
+	- Error if any of the REPLs are nil.
 */
 func CombineRepls(repls []*REPL) (*REPL, error) {
+	for i, r := range repls {
+		if r == nil {
+			return nil, fmt.Errorf("%w: REPL %d", ErrNilRepl, i)
+		}
+	}
 	/* SOLUTION {{{ */
 	if len(repls) == 0 {
 		return NewRepl(), nil
@@ -78,11 +163,21 @@ func CombineRepls(repls []*REPL) (*REPL, error) {
 		newrepl := NewRepl()
 		var listexist []string
 		for i := 0; i < len(repls); i++ {
+			newrepl.panicHandlers = append(newrepl.panicHandlers, repls[i].panicHandlers...)
+			if repls[i].tracer != nil {
+				newrepl.tracer = repls[i].tracer
+			}
 			for key, value := range repls[i].commands {
 				if contains(listexist, key) {
 					return nil, ErrOverlappingCommands
 				} else {
 					newrepl.AddCommand(key, value, repls[i].help[key])
+					if spec, ok := repls[i].specs[key]; ok {
+						newrepl.specs[key] = spec
+					}
+					if validator, ok := repls[i].validators[key]; ok {
+						newrepl.AddValidator(key, validator)
+					}
 					listexist = append(listexist, key)
 				}
 			}
@@ -92,6 +187,12 @@ func CombineRepls(repls []*REPL) (*REPL, error) {
 	/* SOLUTION }}} */
 }
 
+// Register a function to run when a command panics, with the ID of the client that sent it,
+// e.g. to release the locks the client's transaction holds so that other clients aren't blocked.
+func (r *REPL) AddPanicHandler(handler func(clientId uuid.UUID)) {
+	r.panicHandlers = append(r.panicHandlers, handler)
+}
+
 // Get commands.
 func (r *REPL) GetCommands() map[string]ReplCommand {
 	return r.commands
@@ -113,6 +214,19 @@ func (r *REPL) AddCommand(trigger string, action ReplCommand, help string) {
 	}
 	r.commands[trigger] = action
 	r.help[trigger] = help
+	delete(r.specs, trigger)
+}
+
+// Add a command that returns a structured Result, along with its help string, to the set of commands.
+// The command's result is rendered in the client's active output format.
+func (r *REPL) AddResultCommand(trigger string, action ResultCommand, help string) {
+	r.AddCommand(trigger, func(payload string, replConfig *REPLConfig) (string, error) {
+		result, err := action(payload, replConfig)
+		if err != nil {
+			return "", err
+		}
+		return result.Format(replConfig.GetOutputFormat())
+	}, help)
 }
 
 // Return all REPL commands' help strings as one string
@@ -145,6 +259,15 @@ Writes the welcome string and then runs the REPL loop.
 the equivalent of argv[0] - pass the whole string! 
 */
 func (r *REPL) Run(clientId uuid.UUID, prompt string, input io.Reader, output io.Writer) {
+	r.RunWithLimiter(clientId, prompt, input, output, nil)
+}
+
+// Runs the REPL loop like Run, but consults the given limiter before running each command.
+// Commands sent while the limiter doesn't allow them are rejected with ErrRateLimited instead of being run.
+// A nil limiter allows every command.
+// Commands sent between TriggerPipelineMetacommand and PipelineEndSentinel are run as a batch,
+// with their framed responses written all at once.
+func (r *REPL) RunWithLimiter(clientId uuid.UUID, prompt string, input io.Reader, output io.Writer, limiter *RateLimiter) {
 	// Set input and writer to stdin and stdout if left unspecified
 	if input == nil {
 		input = os.Stdin
@@ -155,6 +278,7 @@ func (r *REPL) Run(clientId uuid.UUID, prompt string, input io.Reader, output io
 
 	scanner := bufio.NewScanner(input)
 	replConfig := &REPLConfig{clientId: clientId}
+	defer replConfig.close()
 	// Make sure to write messages to `output` and not stdout! This means using functions like
 	// io.WriteString(output, ...) and fmt.Fprintln(output, ...) instead of fmt.Println(...) for your REPL
 	fmt.Fprintln(output, "Welcome to the dinodb REPL! Please type '.help' to see the list of available commands.")
@@ -169,31 +293,15 @@ func (r *REPL) Run(clientId uuid.UUID, prompt string, input io.Reader, output io
 			io.WriteString(output, prompt)
 			continue
 		}
-		trigger := fields[0]
 
-		// Check for the help meta-command.
-		if trigger == TriggerHelpMetacommand {
-			io.WriteString(output, r.HelpString())
+		// Check for the pipeline meta-command.
+		if fields[0] == TriggerPipelineMetacommand {
+			io.WriteString(output, r.runPipeline(scanner, replConfig, limiter))
 			io.WriteString(output, prompt)
 			continue
 		}
 
-		// Else, check user-specified commands.
-		if command, exists := r.commands[trigger]; exists {
-			result, err := command(payload, replConfig)
-			if err != nil {
-				fmt.Fprintf(output, "%s%s\n", ErrorPrependStr, err)
-			} else {
-				// Append newline if there is output and if it doesn't end with a newline already
-				if len(result) != 0 && !strings.HasSuffix(result, "\n") {
-					result = result + "\n"
-				}
-
-				io.WriteString(output, result)
-			}
-		} else {
-			fmt.Fprintf(output, "%s%s\n", ErrorPrependStr, ErrCommandNotFound)
-		}
+		io.WriteString(output, r.runCommand(payload, replConfig, limiter))
 		io.WriteString(output, prompt)
 		/* SOLUTION }}} */
 	}
@@ -201,6 +309,132 @@ func (r *REPL) Run(clientId uuid.UUID, prompt string, input io.Reader, output io
 	io.WriteString(output, "\n")
 }
 
+// runCommand runs a single non-empty line of input, returning everything that should be written in response.
+func (r *REPL) runCommand(payload string, replConfig *REPLConfig, limiter *RateLimiter) string {
+	trigger := strings.Fields(payload)[0]
+
+	// Reject the command if the client is sending them too quickly.
+	if limiter != nil && !limiter.Allow() {
+		return fmt.Sprintf("%s%s\n", ErrorPrependStr, ErrRateLimited)
+	}
+
+	// Check for the help meta-command.
+	if trigger == TriggerHelpMetacommand {
+		return r.HelpString()
+	}
+
+	// Check for the commands meta-command.
+	if trigger == TriggerCommandsMetacommand {
+		return r.CommandsString()
+	}
+
+	// Check for the format meta-command.
+	if trigger == TriggerFormatMetacommand {
+		return setOutputFormat(payload, replConfig)
+	}
+
+	// Check for the trace meta-command.
+	if trigger == TriggerTraceMetacommand {
+		return r.setTracing(payload, replConfig)
+	}
+
+	// Check for the timing meta-command.
+	if trigger == TriggerTimingMetacommand {
+		return setTiming(payload, replConfig)
+	}
+
+	// Else, check user-specified commands.
+	command, exists := r.commands[trigger]
+	if !exists {
+		return fmt.Sprintf("%s%s\n", ErrorPrependStr, ErrCommandNotFound)
+	}
+	start := time.Now()
+	result, trace, err := r.callTraced(command, payload, replConfig)
+	elapsed := time.Since(start)
+	if err != nil {
+		result = fmt.Sprintf("%s%s\n", ErrorPrependStr, err)
+	}
+	// Append newline if there is output and if it doesn't end with a newline already
+	if len(result) != 0 && !strings.HasSuffix(result, "\n") {
+		result = result + "\n"
+	}
+	if trace != "" && !strings.HasSuffix(trace, "\n") {
+		trace = trace + "\n"
+	}
+	if replConfig.timing {
+		return result + trace + formatTiming(elapsed)
+	}
+	return result + trace
+}
+
+// callCommand runs the command, containing any panic other than a deliberate crash (see ErrCrash).
+// A contained panic is logged, the panic handlers are run for the client, and ErrCommandPanicked is returned.
+func (r *REPL) callCommand(command ReplCommand, payload string, replConfig *REPLConfig) (output string, err error) {
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if crash, ok := p.(error); ok && errors.Is(crash, ErrCrash) {
+			panic(p)
+		}
+		log.Printf("command %q from client %s panicked: %v\n%s", payload, replConfig.clientId, p, debug.Stack())
+		for _, handler := range r.panicHandlers {
+			handler(replConfig.clientId)
+		}
+		output, err = "", fmt.Errorf("%w: %v", ErrCommandPanicked, p)
+	}()
+	return command(payload, replConfig)
+}
+
+// setOutputFormat handles the format meta-command, returning everything that should be written in response.
+func setOutputFormat(payload string, replConfig *REPLConfig) string {
+	fields := strings.Fields(payload)
+	// Usage: .format [human|csv|json]
+	if len(fields) == 1 {
+		return fmt.Sprintf("output format: %s\n", replConfig.GetOutputFormat())
+	}
+	if len(fields) != 2 {
+		return fmt.Sprintf("%susage: %s [human|csv|json]\n", ErrorPrependStr, TriggerFormatMetacommand)
+	}
+	format, err := ParseOutputFormat(fields[1])
+	if err != nil {
+		return fmt.Sprintf("%s%s\n", ErrorPrependStr, err)
+	}
+	replConfig.outputFormat = format
+	return ""
+}
+
+// runPipeline reads commands from the scanner up to PipelineEndSentinel (or EOF) and runs them in order,
+// returning all of their responses together. Each response is preceded by a header line holding
+// PipelineFramePrefix, the command's index in the batch, and the response's length in bytes,
+// and the responses are followed by PipelineEndSentinel.
+func (r *REPL) runPipeline(scanner *bufio.Scanner, replConfig *REPLConfig, limiter *RateLimiter) string {
+	var sb strings.Builder
+	i := 0
+	for scanner.Scan() {
+		payload := scanner.Text()
+		fields := strings.Fields(payload)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == PipelineEndSentinel {
+			break
+		}
+		var response string
+		if fields[0] == TriggerPipelineMetacommand {
+			response = fmt.Sprintf("%s%s\n", ErrorPrependStr, "pipelines cannot be nested")
+		} else {
+			response = r.runCommand(payload, replConfig, limiter)
+		}
+		fmt.Fprintf(&sb, "%s%d %d\n", PipelineFramePrefix, i, len(response))
+		sb.WriteString(response)
+		i++
+	}
+	sb.WriteString(PipelineEndSentinel + "\n")
+	return sb.String()
+}
+
 // Run the REPL.
 /*
 	Ignore until Concurrency
@@ -209,6 +443,7 @@ func (r *REPL) RunChan(c chan string, clientId uuid.UUID, prompt string) {
 	// Get reader and writer; stdin and stdout if no conn.
 	writer := os.Stdout
 	replConfig := &REPLConfig{clientId: clientId}
+	defer replConfig.close()
 	// Begin the repl loop!
 	io.WriteString(writer, prompt)
 	for payload := range c {
@@ -230,7 +465,7 @@ func (r *REPL) RunChan(c chan string, clientId uuid.UUID, prompt string) {
 		// Else, check user commands.
 		if command, exists := r.commands[trigger]; exists {
 			// Call a hardcoded function.
-			result, err := command(payload, replConfig)
+			result, err := r.callCommand(command, payload, replConfig)
 			if err != nil {
 				io.WriteString(writer, fmt.Sprintf("%v\n", err))
 			} else {
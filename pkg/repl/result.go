@@ -0,0 +1,114 @@
+package repl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dinodb/pkg/entry"
+)
+
+// OutputFormat is a way of rendering a command's Result.
+type OutputFormat string
+
+const (
+	HUMAN_FORMAT OutputFormat = "human"
+	CSV_FORMAT   OutputFormat = "csv"
+	JSON_FORMAT  OutputFormat = "json"
+)
+
+// ResultCommand is a command that returns a structured Result, leaving the REPL to format it.
+type ResultCommand func(string, *REPLConfig) (Result, error)
+
+// Result is the structured outcome of a command.
+type Result struct {
+	Rows     []entry.Entry // The entries the command returned, if any.
+	Columns  []string      // The names of the columns of Tuples. If set, Tuples are rendered instead of Rows.
+	Tuples   [][]int64     // Computed rows the command returned, with one value per column.
+	Affected int64         // The number of entries the command changed.
+	Message  string        // A human-readable summary. If empty, the human format lists the rows instead.
+}
+
+// jsonResult is the shape a Result is rendered in by the JSON format.
+type jsonResult struct {
+	Rows     []jsonRow `json:"rows"`
+	Columns  []string  `json:"columns,omitempty"`
+	Tuples   [][]int64 `json:"tuples,omitempty"`
+	Affected int64     `json:"affected"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// jsonRow is the shape an entry is rendered in by the JSON format.
+type jsonRow struct {
+	Key   int64 `json:"key"`
+	Value int64 `json:"value"`
+}
+
+// ParseOutputFormat returns the output format with the given name.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	switch format := OutputFormat(strings.ToLower(name)); format {
+	case HUMAN_FORMAT, CSV_FORMAT, JSON_FORMAT:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q", name)
+	}
+}
+
+// Format renders the result in the given output format.
+// The human format writes the message if there is one, and otherwise one "(key, value)" line per row.
+// The CSV format writes a "key,value" header followed by one line per row.
+// The JSON format writes a single object holding the rows, the affected count and the message.
+// Results with columns are rendered the same way, using their tuples and column names instead of their rows.
+func (result Result) Format(format OutputFormat) (string, error) {
+	var sb strings.Builder
+	columns, tuples := result.Columns, result.Tuples
+	if columns == nil {
+		columns = []string{"key", "value"}
+		for _, e := range result.Rows {
+			tuples = append(tuples, []int64{e.Key, e.Value})
+		}
+	}
+	switch format {
+	case HUMAN_FORMAT:
+		if result.Message != "" {
+			sb.WriteString(result.Message)
+			if !strings.HasSuffix(result.Message, "\n") {
+				sb.WriteString("\n")
+			}
+			break
+		}
+		for _, tuple := range tuples {
+			fmt.Fprintf(&sb, "(%s)\n", joinInts(tuple, ", "))
+		}
+	case CSV_FORMAT:
+		sb.WriteString(strings.Join(columns, ",") + "\n")
+		for _, tuple := range tuples {
+			sb.WriteString(joinInts(tuple, ",") + "\n")
+		}
+	case JSON_FORMAT:
+		out := jsonResult{Rows: make([]jsonRow, 0, len(result.Rows)), Columns: result.Columns, Tuples: result.Tuples,
+			Affected: result.Affected, Message: result.Message}
+		for _, e := range result.Rows {
+			out.Rows = append(out.Rows, jsonRow{e.Key, e.Value})
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return "", err
+		}
+		sb.Write(data)
+		sb.WriteString("\n")
+	default:
+		return "", fmt.Errorf("unknown output format %q", format)
+	}
+	return sb.String(), nil
+}
+
+// joinInts formats each value in base 10 and joins them with sep.
+func joinInts(vals []int64, sep string) string {
+	strs := make([]string, len(vals))
+	for i, val := range vals {
+		strs[i] = strconv.FormatInt(val, 10)
+	}
+	return strings.Join(strs, sep)
+}
@@ -0,0 +1,29 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// setTiming handles the timing meta-command, returning everything that should be written in response.
+func setTiming(payload string, replConfig *REPLConfig) string {
+	fields := strings.Fields(payload)
+	// Usage: .timing [on|off]
+	if len(fields) == 1 {
+		if replConfig.timing {
+			return "timing: on\n"
+		}
+		return "timing: off\n"
+	}
+	if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+		return fmt.Sprintf("%susage: %s [on|off]\n", ErrorPrependStr, TriggerTimingMetacommand)
+	}
+	replConfig.timing = fields[1] == "on"
+	return ""
+}
+
+// formatTiming formats how long a command took to run, as written after its response while timing is on.
+func formatTiming(elapsed time.Duration) string {
+	return fmt.Sprintf("(%.1fms)\n", float64(elapsed)/float64(time.Millisecond))
+}
@@ -0,0 +1,46 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tracer runs a command, returning a trace of what it did, e.g. the pages it accessed.
+type Tracer func(run func()) (trace string)
+
+// Set the tracer used to trace the commands of clients that turn tracing on.
+func (r *REPL) SetTracer(tracer Tracer) {
+	r.tracer = tracer
+}
+
+// setTracing handles the trace meta-command, returning everything that should be written in response.
+func (r *REPL) setTracing(payload string, replConfig *REPLConfig) string {
+	fields := strings.Fields(payload)
+	// Usage: .trace [on|off]
+	if len(fields) == 1 {
+		if replConfig.trace {
+			return "tracing: on\n"
+		}
+		return "tracing: off\n"
+	}
+	if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+		return fmt.Sprintf("%susage: %s [on|off]\n", ErrorPrependStr, TriggerTraceMetacommand)
+	}
+	if fields[1] == "on" && r.tracer == nil {
+		return fmt.Sprintf("%s%s\n", ErrorPrependStr, "tracing is not supported")
+	}
+	replConfig.trace = fields[1] == "on"
+	return ""
+}
+
+// callTraced runs the command as callCommand does, also returning its trace if the client has tracing on.
+func (r *REPL) callTraced(command ReplCommand, payload string, replConfig *REPLConfig) (output string, trace string, err error) {
+	if !replConfig.trace || r.tracer == nil {
+		output, err = r.callCommand(command, payload, replConfig)
+		return output, "", err
+	}
+	trace = r.tracer(func() {
+		output, err = r.callCommand(command, payload, replConfig)
+	})
+	return output, trace, err
+}
@@ -0,0 +1,122 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ArgValidator checks a command's payload without running the command, returning an error if it is malformed.
+type ArgValidator func(payload string) error
+
+// ScriptError describes a malformed line of a script.
+type ScriptError struct {
+	Line    int    // The line's number, starting at 1.
+	Payload string // The line's text.
+	Err     error  // Why the line is malformed.
+}
+
+// Error formats the script error with its line number.
+func (e ScriptError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// NumFields returns an ArgValidator accepting payloads with any of the given numbers of fields, counting the trigger.
+func NumFields(counts ...int) ArgValidator {
+	return func(payload string) error {
+		if slices.Contains(counts, len(strings.Fields(payload))) {
+			return nil
+		}
+		return fmt.Errorf("wrong number of arguments")
+	}
+}
+
+// Register a validator for the command with the given trigger, used by ValidateScript.
+// The command's help string should include its usage, which is reported alongside the validator's errors.
+func (r *REPL) AddValidator(trigger string, validator ArgValidator) {
+	r.validators[trigger] = validator
+}
+
+// ValidateScript checks every line of the script without running any of them, returning an error for each line
+// whose trigger isn't a command or meta-command, or whose payload is rejected by its command's validator.
+// Commands without a validator accept any payload. Blank lines are skipped.
+func (r *REPL) ValidateScript(input io.Reader) []ScriptError {
+	errs := make([]ScriptError, 0)
+	scanner := bufio.NewScanner(input)
+	inPipeline := false
+	for line := 1; scanner.Scan(); line++ {
+		payload := scanner.Text()
+		if err := r.validateLine(payload, &inPipeline); err != nil {
+			errs = append(errs, ScriptError{line, payload, err})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, ScriptError{0, "", fmt.Errorf("error reading script: %w", err)})
+	}
+	return errs
+}
+
+// validateLine checks a single line of a script, tracking whether the line is in a pipelined batch.
+func (r *REPL) validateLine(payload string, inPipeline *bool) error {
+	fields := strings.Fields(payload)
+	if len(fields) == 0 {
+		return nil
+	}
+	switch trigger := fields[0]; trigger {
+	case TriggerHelpMetacommand, TriggerCommandsMetacommand:
+		return nil
+	case TriggerFormatMetacommand:
+		if len(fields) > 2 {
+			return fmt.Errorf("usage: %s [human|csv|json]", TriggerFormatMetacommand)
+		}
+		if len(fields) == 2 {
+			_, err := ParseOutputFormat(fields[1])
+			return err
+		}
+		return nil
+	case TriggerTraceMetacommand, TriggerTimingMetacommand:
+		if len(fields) > 2 || (len(fields) == 2 && fields[1] != "on" && fields[1] != "off") {
+			return fmt.Errorf("usage: %s [on|off]", trigger)
+		}
+		return nil
+	case TriggerPipelineMetacommand:
+		if *inPipeline {
+			return fmt.Errorf("pipelines cannot be nested")
+		}
+		*inPipeline = true
+		return nil
+	case PipelineEndSentinel:
+		if !*inPipeline {
+			return fmt.Errorf("%s outside of a pipeline", PipelineEndSentinel)
+		}
+		*inPipeline = false
+		return nil
+	default:
+		if _, exists := r.commands[trigger]; !exists {
+			return fmt.Errorf("%w: %s", ErrCommandNotFound, trigger)
+		}
+		validator, exists := r.validators[trigger]
+		if !exists {
+			return nil
+		}
+		if err := validator(payload); err != nil {
+			// Point to the command's usage, unless the validator already has
+			if usage := r.usage(trigger); usage != "" && !strings.Contains(err.Error(), "usage: ") {
+				return fmt.Errorf("%v, %s", err, usage)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// usage returns the "usage: ..." part of the command's help string, if it has one.
+func (r *REPL) usage(trigger string) string {
+	help := r.help[trigger]
+	if i := strings.Index(help, "usage: "); i >= 0 {
+		return help[i:]
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package repl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RunWorkload runs the workload's commands through RunChan, sending them from n goroutines: goroutine i sends
+// every n-th command starting from the i-th, waiting delay() before each if delay isn't nil. Returns only once
+// every goroutine has finished sending and the last command has finished running, so that nothing is left
+// running on the workload's behalf, e.g. before the results are verified.
+func (r *REPL) RunWorkload(workload []string, n int, clientId uuid.UUID, delay func() time.Duration) {
+	c := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.RunChan(c, clientId, "")
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			for j := idx; j < len(workload); j += n {
+				if delay != nil {
+					time.Sleep(delay())
+				}
+				c <- workload[j]
+			}
+		}(i)
+	}
+	wg.Wait()
+	// Closing the channel ends RunChan once it has run the last command.
+	close(c)
+	<-done
+}
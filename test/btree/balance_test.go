@@ -0,0 +1,52 @@
+package btree_test
+
+import (
+	"testing"
+)
+
+// Inserts ascending keys until the tree grows a third level, checking that each level's node count
+// matches the number of children above it, and that the leaves hold every key
+func TestBTreeLevelStats(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	numEntries := int64(0)
+	for describe(t, index).Height < 3 {
+		// Describing the tree reads every node, so only check the height every so often.
+		for end := numEntries + 500; numEntries < end; numEntries++ {
+			if err := index.Insert(numEntries, numEntries); err != nil {
+				t.Fatal("Failed to insert:", err)
+			}
+		}
+	}
+	levels, err := index.LevelStats()
+	if err != nil {
+		t.Fatal("Failed to get level stats:", err)
+	}
+	if len(levels) != 3 {
+		t.Fatalf("Expected 3 levels, but got %d", len(levels))
+	}
+	if levels[0].NumNodes != 1 {
+		t.Errorf("Expected a single root, but got %d nodes on level 1", levels[0].NumNodes)
+	}
+	for i, level := range levels {
+		if level.Level != int64(i+1) {
+			t.Errorf("Expected level %d, but got %d", i+1, level.Level)
+		}
+		if level.FillFactor <= 0 || level.FillFactor > 1 {
+			t.Errorf("Expected level %d to be partly full, but got a fill factor of %f", level.Level, level.FillFactor)
+		}
+		if i > 0 {
+			// An internal node with n keys has n+1 children.
+			if children := levels[i-1].NumKeys + levels[i-1].NumNodes; level.NumNodes != children {
+				t.Errorf("Expected %d nodes on level %d, but got %d", children, level.Level, level.NumNodes)
+			}
+		}
+	}
+	if leaves := levels[len(levels)-1]; leaves.NumKeys != numEntries {
+		t.Errorf("Expected the leaves to hold %d keys, but got %d", numEntries, leaves.NumKeys)
+	}
+	// Ascending inserts leave every leaf but the last half full.
+	if fill := levels[len(levels)-1].FillFactor; fill < 0.45 || fill > 0.6 {
+		t.Errorf("Expected ascending inserts to leave the leaves about half full, but got %f", fill)
+	}
+}
@@ -0,0 +1,85 @@
+package btree_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+	"dinodb/test/utils"
+)
+
+func TestBTreeBulkLoad(t *testing.T) {
+	t.Run("Small", stageBulkLoad(10))
+	t.Run("TwoLevels", stageBulkLoad(btree.ENTRIES_PER_LEAF_NODE*10))
+	t.Run("ThreeLevels", stageBulkLoad(btree.ENTRIES_PER_LEAF_NODE*btree.KEYS_PER_INTERNAL_NODE*2))
+	t.Run("OutOfOrder", testBulkLoadOutOfOrder)
+	t.Run("NotEmpty", testBulkLoadNotEmpty)
+}
+
+// generateEntries returns entries with keys from 0 up to but not including numEntries, in order
+func generateEntries(numEntries int64) []entry.Entry {
+	entries := make([]entry.Entry, 0, numEntries)
+	for key := range numEntries {
+		entries = append(entries, entry.New(key, generateValue(key)))
+	}
+	return entries
+}
+
+// stageBulkLoad bulk loads numEntries entries, checking that the tree is valid and holds every entry,
+// including after reopening, and that it still takes inserts and deletes afterwards
+func stageBulkLoad(numEntries int64) func(t *testing.T) {
+	return func(t *testing.T) {
+		index := setupBTree(t)
+		if err := index.BulkLoad(generateEntries(numEntries)); err != nil {
+			t.Fatal("Failed to bulk load entries:", err)
+		}
+		checkCoalescedTree(t, index)
+		if count, err := index.Count(); err != nil || count != numEntries {
+			t.Errorf("Expected %d entries, but counted %d (err %v)", numEntries, count, err)
+		}
+		index = closeAndReopen(t, index)
+		defer index.Close()
+		for key := range numEntries {
+			utils.CheckFindEntry(t, index, key, generateValue(key))
+		}
+		for key := numEntries; key < numEntries+btree.ENTRIES_PER_LEAF_NODE; key++ {
+			utils.InsertEntry(t, index, key, generateValue(key))
+		}
+		for key := int64(0); key < numEntries; key += 2 {
+			if err := index.Delete(key); err != nil {
+				t.Fatal("Failed to delete entry:", err)
+			}
+		}
+		checkCoalescedTree(t, index)
+		utils.CheckFindEntry(t, index, numEntries-1, generateValue(numEntries-1))
+	}
+}
+
+// Bulk loads entries out of order and with a duplicate key, checking that both are rejected without changing the tree
+func testBulkLoadOutOfOrder(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	entries := generateEntries(btree.ENTRIES_PER_LEAF_NODE * 2)
+	entries[5], entries[6] = entries[6], entries[5]
+	if err := index.BulkLoad(entries); !errors.Is(err, btree.ErrBulkLoad) {
+		t.Errorf("Expected out of order entries to be rejected, but got %v", err)
+	}
+	entries[5] = entries[6]
+	if err := index.BulkLoad(entries); !errors.Is(err, btree.ErrBulkLoad) {
+		t.Errorf("Expected a duplicate key to be rejected, but got %v", err)
+	}
+	if count, err := index.Count(); err != nil || count != 0 {
+		t.Errorf("Expected a rejected bulk load to leave the tree empty, but counted %d (err %v)", count, err)
+	}
+}
+
+// Bulk loads into a tree that already has an entry, checking that it is rejected
+func testBulkLoadNotEmpty(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	utils.InsertEntry(t, index, 100, 1)
+	if err := index.BulkLoad(generateEntries(10)); !errors.Is(err, btree.ErrBulkLoad) {
+		t.Errorf("Expected bulk loading a non-empty tree to be rejected, but got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package btree_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+func TestBTreeScanInFlight(t *testing.T) {
+	t.Run("Close", testScanInFlightClose)
+	t.Run("Truncate", testScanInFlightTruncate)
+}
+
+// numScanEntries is how many entries the scans in these tests run over; enough for several levels of leaves.
+const numScanEntries = int64(5000)
+
+// scans are the B+Tree's read paths that walk its leaves, each returning the entries it saw.
+var scans = map[string]func(index *btree.BTreeIndex) ([]entry.Entry, error){
+	"Select":        (*btree.BTreeIndex).Select,
+	"SelectBatched": (*btree.BTreeIndex).SelectBatched,
+	"SelectRange": func(index *btree.BTreeIndex) ([]entry.Entry, error) {
+		return index.SelectRange(0, numScanEntries)
+	},
+	"SelectPrefix": func(index *btree.BTreeIndex) ([]entry.Entry, error) {
+		return index.SelectPrefix(0, 1)
+	},
+	"SelectChunks": func(index *btree.BTreeIndex) ([]entry.Entry, error) {
+		entries := make([]entry.Entry, 0)
+		err := index.SelectChunks(100, func(chunk []entry.Entry) error {
+			entries = append(entries, chunk...)
+			return nil
+		})
+		return entries, err
+	},
+}
+
+// runScans runs every scan over and over in its own goroutine until stop is closed, passing each result to check.
+// Returns once every scan has finished at least once, with a function that waits for the scans to stop.
+func runScans(index *btree.BTreeIndex, stop chan struct{}, check func(name string, entries []entry.Entry, err error)) (wait func()) {
+	var wg, started sync.WaitGroup
+	for name, scan := range scans {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				if i == 1 {
+					started.Done()
+				}
+				select {
+				case <-stop:
+					if i == 0 {
+						started.Done()
+					}
+					return
+				default:
+				}
+				entries, err := scan(index)
+				check(name, entries, err)
+			}
+		}()
+	}
+	started.Wait()
+	return wg.Wait
+}
+
+// Closes a B+Tree while scans are running, checking that every scan either sees every entry
+// or fails with ErrClosing, rather than ending early as if it had reached the last entry
+func testScanInFlightClose(t *testing.T) {
+	index := standardBTreeSetup(t, numScanEntries)
+	stop := make(chan struct{})
+	wait := runScans(index, stop, func(name string, entries []entry.Entry, err error) {
+		if err != nil {
+			if !errors.Is(err, pager.ErrClosing) {
+				t.Errorf("Expected %s to fail with ErrClosing, but got %v", name, err)
+			}
+			return
+		}
+		if int64(len(entries)) != numScanEntries {
+			t.Errorf("Expected %s to see all %d entries, but it saw %d", name, numScanEntries, len(entries))
+		}
+	})
+	if err := index.Close(); err != nil {
+		t.Error("Failed to close B+Tree while scans were running:", err)
+	}
+	close(stop)
+	wait()
+	if _, err := index.Select(); !errors.Is(err, pager.ErrClosing) {
+		t.Errorf("Expected a scan started after Close to fail with ErrClosing, but got %v", err)
+	}
+}
+
+// Truncates a B+Tree while scans are running, checking that the truncate succeeds and
+// that every scan sees either every entry or none, never a partly truncated tree
+func testScanInFlightTruncate(t *testing.T) {
+	index := standardBTreeSetup(t, numScanEntries)
+	defer index.Close()
+	check := func(name string, entries []entry.Entry, err error) {
+		if err != nil {
+			t.Errorf("Failed to run %s during a truncate: %v", name, err)
+			return
+		}
+		// SelectChunks lets go of the B+Tree between chunks, so a truncate can land partway through it.
+		if name == "SelectChunks" {
+			return
+		}
+		if n := int64(len(entries)); n != 0 && n != numScanEntries {
+			t.Errorf("Expected %s to see all %d entries or none, but it saw %d", name, numScanEntries, n)
+		}
+	}
+	for round := range 5 {
+		if round > 0 {
+			for i := range numScanEntries {
+				utils.InsertEntry(t, index, i, generateValue(i))
+			}
+		}
+		stop := make(chan struct{})
+		wait := runScans(index, stop, check)
+		if err := index.Truncate(); err != nil {
+			t.Error("Failed to truncate B+Tree while scans were running:", err)
+		}
+		close(stop)
+		wait()
+	}
+}
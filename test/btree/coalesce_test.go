@@ -0,0 +1,299 @@
+package btree_test
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+	"dinodb/test/utils"
+)
+
+func TestBTreeCoalesce(t *testing.T) {
+	t.Run("DeleteMost", testCoalesceDeleteMost)
+	t.Run("CollapseLevels", testCoalesceCollapseLevels)
+	t.Run("ConcurrentDeletes", func(t *testing.T) {
+		testCoalesceConcurrentDeletes(t, (*btree.BTreeIndex).Select)
+	})
+	t.Run("ConcurrentSelectBatched", func(t *testing.T) {
+		testCoalesceConcurrentDeletes(t, (*btree.BTreeIndex).SelectBatched)
+	})
+	t.Run("MergeGap", testCoalesceMergeGap)
+}
+
+// numPagesInUse counts the pages of the index that aren't on its pager's free list,
+// checking that the rest are exactly the pages reachable from the root
+func numPagesInUse(t *testing.T, index *btree.BTreeIndex) int64 {
+	numFree, err := index.GetPager().GetNumFreePages()
+	if err != nil {
+		t.Fatal("Failed to count free pages:", err)
+	}
+	layout, err := index.Layout()
+	if err != nil {
+		t.Fatal("Failed to get layout:", err)
+	}
+	numReachable := int64(0)
+	for _, info := range layout {
+		if info.Role != btree.FREE_PAGE {
+			numReachable++
+		}
+	}
+	if numInUse := index.GetPager().GetNumPages() - numFree; numInUse != numReachable {
+		t.Errorf("Expected every unreachable page to be freed, but %d pages are in use and %d are reachable", numInUse, numReachable)
+	}
+	return numReachable
+}
+
+// minNodeKeys returns the fewest keys a node other than the root should hold under the index's merge gap
+func minNodeKeys(index *btree.BTreeIndex, nodeType btree.NodeType) int64 {
+	if nodeType == btree.LEAF_NODE {
+		return max(btree.ENTRIES_PER_LEAF_NODE/2-btree.ENTRIES_PER_LEAF_NODE*index.GetMergeGap()/100, 1)
+	}
+	return max((btree.KEYS_PER_INTERNAL_NODE-1)/2-btree.KEYS_PER_INTERNAL_NODE*index.GetMergeGap()/100, 1)
+}
+
+// checkCoalescedTree errors the test if the index is unbalanced, its keys are out of order, its sibling chain
+// is broken, or a node other than the root holds fewer entries than a delete would leave it with before merging
+func checkCoalescedTree(t *testing.T, index *btree.BTreeIndex) {
+	if err := btree.VerifyChains(index); err != nil {
+		t.Fatal("Expected a valid sibling chain, but got", err)
+	}
+	desc := describe(t, index)
+	checkSubtree(t, desc.Root, desc.Height, math.MinInt64, math.MaxInt64)
+	var check func(node btree.NodeDescription, isRoot bool)
+	check = func(node btree.NodeDescription, isRoot bool) {
+		minKeys := minNodeKeys(index, node.NodeType)
+		if !isRoot && int64(len(node.Keys)) < minKeys {
+			t.Errorf("Expected node on page %d to hold at least %d keys, but it holds %d", node.PN, minKeys, len(node.Keys))
+		}
+		for _, child := range node.Children {
+			check(child, false)
+		}
+	}
+	check(desc.Root, true)
+}
+
+// Deletes most entries of a two-level tree in random order, checking that the emptied leaves are merged away
+// and their pages freed, that the remaining entries can still be found, and that reinserting the deleted
+// entries reuses the freed pages rather than growing the file
+func testCoalesceDeleteMost(t *testing.T) {
+	numEntries := int64(1000)
+	index := standardBTreeSetup(t, numEntries)
+	numPages := index.GetPager().GetNumPages()
+	if n := numPagesInUse(t, index); n != numPages {
+		t.Fatalf("Expected all %d pages of a new tree to be in use, but %d are", numPages, n)
+	}
+	keys := rand.Perm(int(numEntries))
+	numDeletes := 900
+	for _, key := range keys[:numDeletes] {
+		if err := index.Delete(int64(key)); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+	}
+	checkCoalescedTree(t, index)
+	if n := numPagesInUse(t, index); n >= numPages {
+		t.Errorf("Expected deleting most entries to shrink the tree from %d pages, but it uses %d", numPages, n)
+	}
+	for _, key := range keys[:numDeletes] {
+		if _, err := index.Find(int64(key)); err == nil {
+			t.Errorf("Expected key %d to be deleted", key)
+		}
+	}
+	for _, key := range keys[numDeletes:] {
+		utils.CheckFindEntry(t, index, int64(key), generateValue(int64(key)))
+	}
+	// The free list survives reopening, and the freed pages are handed out again as the tree regrows.
+	index = closeAndReopen(t, index)
+	defer index.Close()
+	for _, key := range keys[:numDeletes] {
+		utils.InsertEntry(t, index, int64(key), generateValue(int64(key)))
+	}
+	checkCoalescedTree(t, index)
+	numPagesInUse(t, index)
+	if n := index.GetPager().GetNumPages(); n > numPages {
+		t.Errorf("Expected reinserting the deleted entries to reuse freed pages, but the file grew from %d to %d pages", numPages, n)
+	}
+	for key := range numEntries {
+		utils.CheckFindEntry(t, index, key, generateValue(key))
+	}
+}
+
+// Grows a three-level tree, then deletes all but a few entries in random order, checking that the tree
+// stays valid as internal nodes merge, and that it collapses back into a single leaf, including after reopening
+func testCoalesceCollapseLevels(t *testing.T) {
+	index := setupBTree(t)
+	numEntries := int64(0)
+	for describe(t, index).Height < 3 {
+		// Describing the tree reads every node, so only check the height every so often.
+		for end := numEntries + 500; numEntries < end; numEntries++ {
+			utils.InsertEntry(t, index, numEntries, generateValue(numEntries))
+		}
+	}
+	keys := rand.Perm(int(numEntries))
+	numKept := 10
+	for i, key := range keys[numKept:] {
+		if err := index.Delete(int64(key)); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+		if i%5000 == 0 {
+			checkCoalescedTree(t, index)
+		}
+	}
+	checkCoalescedTree(t, index)
+	if height := describe(t, index).Height; height != 1 {
+		t.Errorf("Expected the tree to collapse into a single leaf, but it has %d levels", height)
+	}
+	index = closeAndReopen(t, index)
+	defer index.Close()
+	for _, key := range keys[:numKept] {
+		utils.CheckFindEntry(t, index, int64(key), generateValue(int64(key)))
+	}
+	// The reopened tree should still split and grow as usual.
+	for i := numEntries; i < numEntries+btree.ENTRIES_PER_LEAF_NODE; i++ {
+		utils.InsertEntry(t, index, i, generateValue(i))
+	}
+	checkCoalescedTree(t, index)
+}
+
+// Deletes most entries from several goroutines while others scan with selectAll and find the kept entries,
+// checking that merges don't hide kept entries from readers, reorder their scans, or break the tree
+func testCoalesceConcurrentDeletes(t *testing.T, selectAll func(*btree.BTreeIndex) ([]entry.Entry, error)) {
+	numWriters := int64(8)
+	numReaders := 4
+	numEntries := int64(20000)
+	index := standardBTreeSetup(t, numEntries)
+	defer index.Close()
+	// Keep every tenth key.
+	isKept := func(key int64) bool { return key%10 == 0 }
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range numReaders {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				entries, err := selectAll(index)
+				if err != nil {
+					t.Errorf("Failed to select entries: %v", err)
+					return
+				}
+				numKept := int64(0)
+				for i, e := range entries {
+					if i > 0 && e.Key <= entries[i-1].Key {
+						t.Errorf("Expected a scan to return keys in increasing order, but %d came after %d", e.Key, entries[i-1].Key)
+						return
+					}
+					if isKept(e.Key) {
+						numKept++
+					}
+				}
+				if numKept != numEntries/10 {
+					t.Errorf("Expected a scan to see all %d kept entries, but it saw %d", numEntries/10, numKept)
+					return
+				}
+			}
+		}()
+	}
+	for w := range numWriters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := w; key < numEntries; key += numWriters {
+				if !isKept(key) {
+					if err := index.Delete(key); err != nil {
+						t.Errorf("Failed to delete key %d: %v", key, err)
+						return
+					}
+				} else if _, err := index.Find(key); err != nil {
+					t.Errorf("Failed to find kept key %d: %v", key, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	checkCoalescedTree(t, index)
+	for key := range numEntries {
+		if isKept(key) {
+			utils.CheckFindEntry(t, index, key, generateValue(key))
+		} else if _, err := index.Find(key); err == nil {
+			t.Errorf("Found key %d after deleting it", key)
+		}
+	}
+}
+
+// Deletes from a two-leaf tree until its leaves merge, then alternates inserting and deleting a key,
+// counting how often the number of leaves changes. With the default merge gap the merged leaf has room
+// to absorb the churn, while with no gap it splits and merges again on every operation.
+func testCoalesceMergeGap(t *testing.T) {
+	churn := func(t *testing.T, gap int64) (numChanges int) {
+		// Filling a leaf splits it into two half full leaves.
+		numEntries := btree.ENTRIES_PER_LEAF_NODE
+		index := standardBTreeSetup(t, numEntries)
+		defer index.Close()
+		if err := index.SetMergeGap(gap); err != nil {
+			t.Fatal("Failed to set merge gap:", err)
+		}
+		numLeaves := func() int64 {
+			stats, err := index.LevelStats()
+			if err != nil {
+				t.Fatal("Failed to get level stats:", err)
+			}
+			return stats[len(stats)-1].NumNodes
+		}
+		key := numEntries
+		for numLeaves() > 1 {
+			key--
+			if err := index.Delete(key); err != nil {
+				t.Fatal("Failed to delete entry:", err)
+			}
+		}
+		leaves := numLeaves()
+		for i := 0; i < 100; i++ {
+			utils.InsertEntry(t, index, key, generateValue(key))
+			if n := numLeaves(); n != leaves {
+				numChanges, leaves = numChanges+1, n
+			}
+			if err := index.Delete(key); err != nil {
+				t.Fatal("Failed to delete entry:", err)
+			}
+			if n := numLeaves(); n != leaves {
+				numChanges, leaves = numChanges+1, n
+			}
+		}
+		checkCoalescedTree(t, index)
+		return numChanges
+	}
+	t.Run("Default", func(t *testing.T) {
+		if n := churn(t, btree.DEFAULT_MERGE_GAP); n != 0 {
+			t.Errorf("Expected churn after a merge to never split with the default merge gap, but the leaves changed %d times", n)
+		}
+	})
+	t.Run("None", func(t *testing.T) {
+		if n := churn(t, 0); n < 100 {
+			t.Errorf("Expected churn after a merge to split and merge repeatedly with no merge gap, but the leaves changed %d times", n)
+		}
+	})
+	t.Run("OutOfRange", func(t *testing.T) {
+		index := setupBTree(t)
+		defer index.Close()
+		if err := index.SetMergeGap(51); err == nil {
+			t.Error("Expected a merge gap over 50 percent to be rejected")
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package btree_test
+
+import (
+	"cmp"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+	"dinodb/test/utils"
+)
+
+// lastDigitComparator orders keys by their last decimal digit, then by the keys themselves
+func lastDigitComparator(a int64, b int64) int {
+	if c := cmp.Compare(a%10, b%10); c != 0 {
+		return c
+	}
+	return cmp.Compare(a, b)
+}
+
+const LAST_DIGIT_COMPARATOR btree.ComparatorID = 100
+
+func init() {
+	if err := btree.RegisterComparator(LAST_DIGIT_COMPARATOR, lastDigitComparator); err != nil {
+		panic(err)
+	}
+}
+
+// setupComparatorBTree creates a BTreeIndex ordered by the given comparator
+// and inserts entries with keys 0 to numInserts-1 in random order
+func setupComparatorBTree(t *testing.T, id btree.ComparatorID, numInserts int64) *btree.BTreeIndex {
+	t.Parallel()
+	index, err := btree.OpenIndexWithComparator(utils.GetTempDbFile(t), id)
+	if err != nil {
+		t.Fatal("Failed to create BTree index:", err)
+	}
+	for _, key := range rand.Perm(int(numInserts)) {
+		utils.InsertEntry(t, index, int64(key), generateValue(int64(key)))
+	}
+	if t.Failed() {
+		t.FailNow()
+	}
+	return index
+}
+
+// checkKeyOrder errors the test if the entries' keys aren't exactly the expected keys, in order
+func checkKeyOrder(t *testing.T, entries []entry.Entry, expected []int64) {
+	keys := make([]int64, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	if !slices.Equal(keys, expected) {
+		t.Errorf("Expected keys %v, but got %v", expected, keys)
+	}
+}
+
+func TestBTreeComparator(t *testing.T) {
+	t.Run("DescendingSelect", testComparatorDescendingSelect)
+	t.Run("DescendingRange", testComparatorDescendingRange)
+	t.Run("Persisted", testComparatorPersisted)
+	t.Run("Custom", testComparatorCustom)
+	t.Run("Unknown", testComparatorUnknown)
+}
+
+// Builds a multi-level descending index, checking that Select, SelectChunks, and Find follow descending order
+func testComparatorDescendingSelect(t *testing.T) {
+	numEntries := int64(2000)
+	index := setupComparatorBTree(t, btree.DESCENDING_COMPARATOR, numEntries)
+	defer index.Close()
+	expected := make([]int64, 0, numEntries)
+	for key := numEntries - 1; key >= 0; key-- {
+		expected = append(expected, key)
+	}
+	entries, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	checkKeyOrder(t, entries, expected)
+	chunked := make([]entry.Entry, 0)
+	err = index.SelectChunks(300, func(chunk []entry.Entry) error {
+		chunked = append(chunked, chunk...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Failed to select chunks:", err)
+	}
+	checkKeyOrder(t, chunked, expected)
+	for key := range numEntries {
+		utils.CheckFindEntry(t, index, key, generateValue(key))
+	}
+}
+
+// Checks that range queries, floors, and ceilings on a descending index run from larger to smaller keys
+func testComparatorDescendingRange(t *testing.T) {
+	index := setupComparatorBTree(t, btree.DESCENDING_COMPARATOR, 2000)
+	defer index.Close()
+	entries, err := index.SelectRange(1500, 1490)
+	if err != nil {
+		t.Fatal("Failed to select range:", err)
+	}
+	checkKeyOrder(t, entries, []int64{1500, 1499, 1498, 1497, 1496, 1495, 1494, 1493, 1492, 1491})
+	if _, err := index.SelectRange(1490, 1500); err == nil {
+		t.Error("Expected a range whose start sorts after its end to be rejected")
+	}
+	count, err := index.RangeCount(1999, 99)
+	if err != nil {
+		t.Fatal("Failed to count range:", err)
+	}
+	if count != 1900 {
+		t.Errorf("Expected 1900 entries in the range, but counted %d", count)
+	}
+	// Every key sorts before -5, so the last of them is its floor.
+	if floor, found, err := index.Floor(-5); err != nil || !found || floor.Key != 0 {
+		t.Errorf("Expected the descending floor of -5 to be 0, but got %v, %v, %v", floor, found, err)
+	}
+	// Every key sorts after 2500, so the first of them is its ceiling.
+	if ceiling, found, err := index.Ceiling(2500); err != nil || !found || ceiling.Key != 1999 {
+		t.Errorf("Expected the descending ceiling of 2500 to be 1999, but got %v, %v, %v", ceiling, found, err)
+	}
+	if _, found, err := index.Floor(2500); err != nil || found {
+		t.Errorf("Expected no descending floor of 2500, but got %v, %v", found, err)
+	}
+}
+
+// Reopens a descending index with OpenIndex, checking that it keeps its order,
+// and with the ascending comparator, checking that the mismatch is rejected
+func testComparatorPersisted(t *testing.T) {
+	index := setupComparatorBTree(t, btree.DESCENDING_COMPARATOR, 10)
+	filename := index.GetPager().GetFileName()
+	index = closeAndReopen(t, index)
+	entries, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	checkKeyOrder(t, entries, []int64{9, 8, 7, 6, 5, 4, 3, 2, 1, 0})
+	if err := index.Close(); err != nil {
+		t.Fatal("Failed to close index:", err)
+	}
+	if _, err := btree.OpenIndexWithComparator(filename, btree.ASCENDING_COMPARATOR); !errors.Is(err, btree.ErrComparatorMismatch) {
+		t.Errorf("Expected %q, but got %v", btree.ErrComparatorMismatch, err)
+	}
+	index, err = btree.OpenIndexWithComparator(filename, btree.DESCENDING_COMPARATOR)
+	if err != nil {
+		t.Fatal("Failed to reopen with the same comparator:", err)
+	}
+	index.Close()
+}
+
+// Orders an index with a registered custom comparator, checking that Select and SelectRange follow it
+func testComparatorCustom(t *testing.T) {
+	numEntries := int64(1000)
+	index := setupComparatorBTree(t, LAST_DIGIT_COMPARATOR, numEntries)
+	defer index.Close()
+	expected := make([]int64, numEntries)
+	for i := range expected {
+		expected[i] = int64(i)
+	}
+	slices.SortFunc(expected, lastDigitComparator)
+	entries, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	checkKeyOrder(t, entries, expected)
+	// Keys ending in 3 from 993 on sort before every key ending in 4.
+	entries, err = index.SelectRange(993, 4)
+	if err != nil {
+		t.Fatal("Failed to select range:", err)
+	}
+	checkKeyOrder(t, entries, []int64{993})
+	if err := btree.RegisterComparator(LAST_DIGIT_COMPARATOR, lastDigitComparator); err == nil {
+		t.Error("Expected registering a taken comparator id to fail")
+	}
+}
+
+// Checks that opening an index with an unregistered comparator fails
+func testComparatorUnknown(t *testing.T) {
+	t.Parallel()
+	_, err := btree.OpenIndexWithComparator(utils.GetTempDbFile(t), 12345)
+	if !errors.Is(err, btree.ErrUnknownComparator) {
+		t.Errorf("Expected %q, but got %v", btree.ErrUnknownComparator, err)
+	}
+}
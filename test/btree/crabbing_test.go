@@ -0,0 +1,182 @@
+package btree_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/test/utils"
+)
+
+func TestBTreeSharedCrabbing(t *testing.T) {
+	t.Run("FindDuringSplits", testFindDuringSplits)
+	t.Run("MixedWriters", testMixedWriters)
+}
+
+// Finds existing keys while other goroutines insert enough new keys to split leaves and internal nodes
+func testFindDuringSplits(t *testing.T) {
+	numExisting := int64(1000)
+	numWriters := int64(4)
+	insertsPerWriter := int64(2000)
+	index := standardBTreeSetup(t, numExisting)
+	defer index.Close()
+
+	var wg sync.WaitGroup
+	var writersDone atomic.Bool
+	var writers sync.WaitGroup
+	for w := range numWriters {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for i := range insertsPerWriter {
+				key := numExisting + i*numWriters + w
+				if err := index.Insert(key, generateValue(key)); err != nil {
+					t.Errorf("Failed to insert key %d: %v", key, err)
+					return
+				}
+			}
+		}()
+	}
+	for r := range int64(4) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := r; !writersDone.Load(); i = (i + 7) % numExisting {
+				e, err := index.Find(i)
+				if err != nil {
+					t.Errorf("Failed to find key %d during inserts: %v", i, err)
+					return
+				}
+				if e.Value != generateValue(i) {
+					t.Errorf("Found value %d for key %d, expected %d", e.Value, i, generateValue(i))
+					return
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	writersDone.Store(true)
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	for key := range numExisting + numWriters*insertsPerWriter {
+		utils.CheckFindEntry(t, index, key, generateValue(key))
+	}
+}
+
+// Inserts, updates, and deletes disjoint keys from several goroutines at once,
+// checking that every write lands whether or not it split a node
+func testMixedWriters(t *testing.T) {
+	numWriters := int64(8)
+	keysPerWriter := int64(1000)
+	index := setupBTree(t)
+	defer index.Close()
+
+	var wg sync.WaitGroup
+	for w := range numWriters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range keysPerWriter {
+				key := i*numWriters + w
+				if err := index.Insert(key, key); err != nil {
+					t.Errorf("Failed to insert key %d: %v", key, err)
+					return
+				}
+				if err := index.Update(key, generateValue(key)); err != nil {
+					t.Errorf("Failed to update key %d: %v", key, err)
+					return
+				}
+				// Delete every third key
+				if key%3 == 0 {
+					if err := index.Delete(key); err != nil {
+						t.Errorf("Failed to delete key %d: %v", key, err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	for key := range numWriters * keysPerWriter {
+		if key%3 == 0 {
+			if _, err := index.Find(key); err == nil {
+				t.Errorf("Found key %d after deleting it", key)
+			}
+			continue
+		}
+		utils.CheckFindEntry(t, index, key, generateValue(key))
+	}
+	entries, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select entries:", err)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i-1].Key >= entries[i].Key {
+			t.Fatalf("Select returned key %d before key %d", entries[i-1].Key, entries[i].Key)
+		}
+	}
+}
+
+// Measures Find throughput while a background goroutine keeps inserting new keys.
+// Lookups only read lock the nodes they pass through, so they aren't serialized behind the inserts.
+func BenchmarkFindDuringInserts(b *testing.B) {
+	for _, readers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("Readers%d", readers), func(b *testing.B) {
+			numExisting := int64(10000)
+			index, err := btree.OpenIndex(filepath.Join(b.TempDir(), "bench.db"))
+			if err != nil {
+				b.Fatal("Failed to create BTree index:", err)
+			}
+			defer index.Close()
+			for i := range numExisting {
+				if err := index.Insert(i, generateValue(i)); err != nil {
+					b.Fatal("Failed to insert:", err)
+				}
+			}
+
+			stop := make(chan struct{})
+			var inserter sync.WaitGroup
+			inserter.Add(1)
+			go func() {
+				defer inserter.Done()
+				for key := numExisting; ; key++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if err := index.Insert(key, generateValue(key)); err != nil {
+						b.Error("Failed to insert:", err)
+						return
+					}
+				}
+			}()
+
+			b.SetParallelism(readers)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				key := int64(0)
+				for pb.Next() {
+					if _, err := index.Find(key); err != nil {
+						b.Error("Failed to find:", err)
+						return
+					}
+					key = (key + 31) % numExisting
+				}
+			})
+			b.StopTimer()
+			close(stop)
+			inserter.Wait()
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package btree_test
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+)
+
+func TestBTreeCursorPrev(t *testing.T) {
+	t.Run("Backward", testCursorPrevBackward)
+	t.Run("EmptyTree", testCursorPrevEmptyTree)
+	t.Run("AfterMerges", testCursorPrevAfterMerges)
+	t.Run("ConcurrentInserts", testCursorPrevConcurrentInserts)
+}
+
+// walkBackward collects the entries from the end of the index to its start using CursorAtEnd and Prev
+func walkBackward(index *btree.BTreeIndex) ([]entry.Entry, error) {
+	c, err := index.CursorAtEnd()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	entries := make([]entry.Entry, 0)
+	if !c.Valid() {
+		return entries, nil
+	}
+	for {
+		e, err := c.GetEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+		if c.Prev() {
+			return entries, nil
+		}
+	}
+}
+
+// checkBackwardWalk errors the test if walking the index backward doesn't give the reverse of Select
+func checkBackwardWalk(t *testing.T, index *btree.BTreeIndex) {
+	expected, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	slices.Reverse(expected)
+	backward, err := walkBackward(index)
+	if err != nil {
+		t.Fatal("Failed to walk backward:", err)
+	}
+	if !slices.Equal(backward, expected) {
+		t.Errorf("Expected the backward walk to give the %d selected entries in reverse, but got %d entries",
+			len(expected), len(backward))
+	}
+}
+
+// Walks a 500-entry tree backward, checking that it visits every entry in reverse order
+// and that the leaves' left sibling pointers mirror their right ones
+func testCursorPrevBackward(t *testing.T) {
+	index := standardBTreeSetup(t, 500)
+	defer index.Close()
+	if err := btree.VerifyChains(index); err != nil {
+		t.Error("Expected valid sibling chains, but got", err)
+	}
+	checkBackwardWalk(t, index)
+}
+
+// Checks that a cursor at the end of an empty tree is invalid
+func testCursorPrevEmptyTree(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	c, err := index.CursorAtEnd()
+	if err != nil {
+		t.Fatal("Failed to get cursor at end:", err)
+	}
+	if c.Valid() {
+		t.Error("Expected a cursor at the end of an empty tree to be invalid")
+	}
+	if !c.Prev() {
+		t.Error("Expected Prev on an empty tree to report the start")
+	}
+	c.Close()
+}
+
+// Deletes most entries in random order so that leaves merge, checking that the backward walk
+// still matches Select and that the left sibling pointers were relinked, including after reopening
+func testCursorPrevAfterMerges(t *testing.T) {
+	numEntries := int64(2000)
+	index := standardBTreeSetup(t, numEntries)
+	for _, key := range rand.Perm(int(numEntries))[:1500] {
+		if err := index.Delete(int64(key)); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+	}
+	if err := btree.VerifyChains(index); err != nil {
+		t.Error("Expected valid sibling chains, but got", err)
+	}
+	checkBackwardWalk(t, index)
+	index = closeAndReopen(t, index)
+	defer index.Close()
+	checkBackwardWalk(t, index)
+}
+
+// Walks backward from several goroutines while others insert between the existing keys, splitting leaves,
+// checking that every walk is in descending order and sees every existing key
+func testCursorPrevConcurrentInserts(t *testing.T) {
+	numExisting := int64(5000)
+	index := setupBTree(t)
+	defer index.Close()
+	// Existing keys are even; the writers insert the odd keys between them.
+	for i := range numExisting {
+		if err := index.Insert(2*i, generateValue(2*i)); err != nil {
+			t.Fatal("Failed to insert entry:", err)
+		}
+	}
+	numWriters := int64(4)
+	var writers sync.WaitGroup
+	for w := range numWriters {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for key := 2*w + 1; key < 2*numExisting; key += 2 * numWriters {
+				if err := index.Insert(key, generateValue(key)); err != nil {
+					t.Errorf("Failed to insert key %d: %v", key, err)
+					return
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 2 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				entries, err := walkBackward(index)
+				if err != nil {
+					t.Errorf("Failed to walk backward: %v", err)
+					return
+				}
+				numExistingSeen := int64(0)
+				for i, e := range entries {
+					if i > 0 && e.Key >= entries[i-1].Key {
+						t.Errorf("Expected the backward walk to descend, but key %d came after %d", e.Key, entries[i-1].Key)
+						return
+					}
+					if e.Key%2 == 0 {
+						numExistingSeen++
+					}
+				}
+				if numExistingSeen != numExisting {
+					t.Errorf("Expected the backward walk to see all %d existing keys, but it saw %d", numExisting, numExistingSeen)
+					return
+				}
+			}
+		}()
+	}
+	writers.Wait()
+	close(done)
+	readers.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+	if err := btree.VerifyChains(index); err != nil {
+		t.Error("Expected valid sibling chains, but got", err)
+	}
+	checkBackwardWalk(t, index)
+}
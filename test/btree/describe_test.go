@@ -0,0 +1,150 @@
+package btree_test
+
+import (
+	"slices"
+	"testing"
+
+	"dinodb/pkg/btree"
+)
+
+func TestBTreeDescribe(t *testing.T) {
+	t.Run("SingleLeaf", testDescribeSingleLeaf)
+	t.Run("FirstSplit", testDescribeFirstSplit)
+	t.Run("MultiLevel", testDescribeMultiLevel)
+}
+
+// describe returns the index's description, failing the test if it can't be read
+func describe(t *testing.T, index *btree.BTreeIndex) btree.TreeDescription {
+	desc, err := index.Describe()
+	if err != nil {
+		t.Fatal("Failed to describe the tree:", err)
+	}
+	return desc
+}
+
+// keyRange returns the keys from start up to but not including end
+func keyRange(start, end int64) []int64 {
+	keys := make([]int64, 0, end-start)
+	for key := start; key < end; key++ {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Fills the root leaf one entry short of splitting, checking that the tree is a single leaf holding every key
+func testDescribeSingleLeaf(t *testing.T) {
+	numEntries := btree.ENTRIES_PER_LEAF_NODE - 1
+	index := standardBTreeSetup(t, numEntries)
+	defer index.Close()
+	desc := describe(t, index)
+	if desc.Height != 1 {
+		t.Errorf("Expected a tree of height 1, but got %d", desc.Height)
+	}
+	root := desc.Root
+	if root.PN != btree.ROOT_PN || root.NodeType != btree.LEAF_NODE {
+		t.Fatalf("Expected the root to be a leaf on page %d, but got %+v", btree.ROOT_PN, root)
+	}
+	if !slices.Equal(root.Keys, keyRange(0, numEntries)) {
+		t.Errorf("Expected the root to hold keys 0 to %d, but got %v", numEntries-1, root.Keys)
+	}
+	if root.RightSiblingPN != -1 {
+		t.Errorf("Expected the only leaf to have no sibling, but got %d", root.RightSiblingPN)
+	}
+}
+
+// Inserts the entry that splits the root leaf, checking that the new root separates two leaves
+// holding the lower and upper halves of the keys, chained left to right
+func testDescribeFirstSplit(t *testing.T) {
+	numEntries := btree.ENTRIES_PER_LEAF_NODE
+	index := standardBTreeSetup(t, numEntries)
+	defer index.Close()
+	desc := describe(t, index)
+	if desc.Height != 2 {
+		t.Errorf("Expected a tree of height 2, but got %d", desc.Height)
+	}
+	root := desc.Root
+	midpoint := numEntries / 2
+	if root.PN != btree.ROOT_PN || root.NodeType != btree.INTERNAL_NODE {
+		t.Fatalf("Expected the root to be an internal node on page %d", btree.ROOT_PN)
+	}
+	if !slices.Equal(root.Keys, []int64{midpoint}) {
+		t.Fatalf("Expected the root to hold the single key %d, but got %v", midpoint, root.Keys)
+	}
+	left, right := root.Children[0], root.Children[1]
+	if !slices.Equal(root.ChildPNs, []int64{left.PN, right.PN}) {
+		t.Errorf("Expected the root's child page numbers %v to match its children", root.ChildPNs)
+	}
+	if left.NodeType != btree.LEAF_NODE || right.NodeType != btree.LEAF_NODE {
+		t.Fatal("Expected both of the root's children to be leaves")
+	}
+	if !slices.Equal(left.Keys, keyRange(0, midpoint)) {
+		t.Errorf("Expected the left leaf to hold keys 0 to %d, but got %v", midpoint-1, left.Keys)
+	}
+	if !slices.Equal(right.Keys, keyRange(midpoint, numEntries)) {
+		t.Errorf("Expected the right leaf to hold keys %d to %d, but got %v", midpoint, numEntries-1, right.Keys)
+	}
+	if left.RightSiblingPN != right.PN || right.RightSiblingPN != -1 {
+		t.Errorf("Expected the left leaf to point to page %d and the right leaf to end the chain, but got %d and %d",
+			right.PN, left.RightSiblingPN, right.RightSiblingPN)
+	}
+}
+
+// checkSubtree checks that every key under the node is within [low, high), that every leaf is at the given depth,
+// and that internal nodes' keys separate their children
+func checkSubtree(t *testing.T, node btree.NodeDescription, depth int64, low int64, high int64) {
+	for _, key := range node.Keys {
+		if key < low || key >= high {
+			t.Errorf("Expected the keys of page %d to be in [%d, %d), but found %d", node.PN, low, high, key)
+		}
+	}
+	if node.NodeType == btree.LEAF_NODE {
+		if depth != 1 {
+			t.Errorf("Expected leaf %d to be %d levels further down", node.PN, depth-1)
+		}
+		return
+	}
+	if len(node.Children) != len(node.Keys)+1 || len(node.ChildPNs) != len(node.Children) {
+		t.Fatalf("Expected internal node %d with %d keys to have %d children", node.PN, len(node.Keys), len(node.Keys)+1)
+	}
+	for i, child := range node.Children {
+		if child.PN != node.ChildPNs[i] {
+			t.Errorf("Expected child %d of page %d to be page %d, but got %d", i, node.PN, node.ChildPNs[i], child.PN)
+		}
+		childLow, childHigh := low, high
+		if i > 0 {
+			childLow = node.Keys[i-1]
+		}
+		if i < len(node.Keys) {
+			childHigh = node.Keys[i]
+		}
+		checkSubtree(t, child, depth-1, childLow, childHigh)
+	}
+}
+
+// Inserts enough entries for a multi-level tree, checking that it is balanced, that its keys are ordered
+// across nodes, and that the leaves hold every key and are chained in order
+func testDescribeMultiLevel(t *testing.T) {
+	const numEntries = 50000
+	index := standardBTreeSetup(t, numEntries)
+	defer index.Close()
+	desc := describe(t, index)
+	if desc.Height < 3 {
+		t.Fatalf("Expected a tree with at least 3 levels, but got %d", desc.Height)
+	}
+	checkSubtree(t, desc.Root, desc.Height, 0, numEntries)
+	leaves := desc.Leaves()
+	keys := make([]int64, 0, numEntries)
+	for i, leaf := range leaves {
+		keys = append(keys, leaf.Keys...)
+		expectedSibling := int64(-1)
+		if i+1 < len(leaves) {
+			expectedSibling = leaves[i+1].PN
+		}
+		if leaf.RightSiblingPN != expectedSibling {
+			t.Errorf("Expected leaf %d to point to page %d, but got %d", leaf.PN, expectedSibling, leaf.RightSiblingPN)
+		}
+	}
+	if !slices.Equal(keys, keyRange(0, numEntries)) {
+		t.Error("Expected the leaves to hold every key in order")
+	}
+}
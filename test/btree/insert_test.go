@@ -1,10 +1,14 @@
 package btree_test
 
 import (
+	"errors"
 	"math/rand"
 	"testing"
+	"time"
 
 	"dinodb/pkg/btree"
+	"dinodb/pkg/config"
+	"dinodb/pkg/pager"
 	"dinodb/test/utils"
 )
 
@@ -189,3 +193,116 @@ func testInsertDuplicateKeys(t *testing.T) {
 	}
 	index.Close()
 }
+
+// Inserts exactly as many entries as fit in a leaf node with the default page size,
+// checking that this splits the root with 4KB pages, but doesn't with 16KB pages
+func TestBTreePageSize(t *testing.T) {
+	tests := map[string]struct {
+		pagesize    int64
+		expectSplit bool
+	}{
+		"FourKB":    {4096, true},
+		"SixteenKB": {16384, false},
+	}
+	for name, testData := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dbName := utils.GetTempDbFile(t)
+			index, err := btree.OpenIndexWithPageSize(dbName, testData.pagesize)
+			if err != nil {
+				t.Fatal("Failed to create BTree index:", err)
+			}
+			for i := range btree.ENTRIES_PER_LEAF_NODE {
+				utils.InsertEntry(t, index, i, generateValue(i))
+			}
+			didSplit := index.GetPager().GetNumPages() > 1
+			if didSplit != testData.expectSplit {
+				t.Errorf("Expected root to split: %v, but root split: %v (%d pages)",
+					testData.expectSplit, didSplit, index.GetPager().GetNumPages())
+			}
+			// Entries should all be findable, including after reopening
+			index = closeAndReopen(t, index)
+			defer index.Close()
+			if index.GetPager().GetPageSize() != testData.pagesize {
+				t.Errorf("Expected reopened index to have page size %d, but found %d",
+					testData.pagesize, index.GetPager().GetPageSize())
+			}
+			for i := range btree.ENTRIES_PER_LEAF_NODE {
+				utils.CheckFindEntry(t, index, i, generateValue(i))
+			}
+		})
+	}
+}
+
+// Shrinks a multi-level tree's buffer below what an insert may pin, checking that inserting fails up front with
+// ErrPinBudgetExceeded and without changing the tree, then succeeds once the buffer is grown again
+func TestBTreeInsertPinBudget(t *testing.T) {
+	index := standardBTreeSetup(t, 5000)
+	defer index.Close()
+	p := index.GetPager()
+	if err := p.ResizeBuffer(2); err != nil {
+		t.Fatal("Failed to shrink the buffer:", err)
+	}
+	err := index.Insert(5000, 0)
+	if !errors.Is(err, pager.ErrPinBudgetExceeded) {
+		t.Fatalf("Expected insert to fail with %q, but got %v", pager.ErrPinBudgetExceeded, err)
+	}
+	if err := p.ResizeBuffer(config.MaxPagesInBuffer); err != nil {
+		t.Fatal("Failed to grow the buffer:", err)
+	}
+	if _, err := index.Find(5000); err == nil {
+		t.Error("Expected the rejected insert to not change the tree")
+	}
+	utils.InsertEntry(t, index, 5000, 0)
+}
+
+// Pins all of a multi-level tree's buffer as other operations would, checking that an insert waits
+// for the pages to be put when pin retries are enabled instead of failing its pin budget check
+func TestBTreeInsertPinContention(t *testing.T) {
+	index := standardBTreeSetup(t, 5000)
+	defer index.Close()
+	p := index.GetPager()
+	if err := p.SetPinRetry(10, time.Millisecond); err != nil {
+		t.Fatal("Failed to set pin retries:", err)
+	}
+	pages := make([]*pager.Page, 0)
+	for p.GetNumPinned() < p.GetBufferSize() {
+		page, err := p.GetNewPage()
+		if err != nil {
+			t.Fatal("Failed to pin a page:", err)
+		}
+		pages = append(pages, page)
+	}
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		for _, page := range pages {
+			_ = p.PutPage(page)
+		}
+	}()
+	utils.InsertEntry(t, index, 5000, 0)
+	utils.CheckFindEntry(t, index, 5000, 0)
+}
+
+// Alternates inserting and deleting a key in a leaf at the split boundary, checking that the leaf
+// splits at most once. Both halves of a split leaf are left half full, so the churn neither splits nor merges them.
+func TestBTreeBoundaryChurn(t *testing.T) {
+	index := standardBTreeSetup(t, btree.ENTRIES_PER_LEAF_NODE)
+	churnKey := btree.ENTRIES_PER_LEAF_NODE
+	utils.InsertEntry(t, index, churnKey, generateValue(churnKey))
+	numPages := index.GetPager().GetNumPages()
+	if numPages == 1 {
+		t.Fatal("Expected inserting past a full leaf to split it")
+	}
+	for i := 0; i < 1000; i++ {
+		if err := index.Delete(churnKey); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+		utils.InsertEntry(t, index, churnKey, generateValue(churnKey))
+	}
+	if n := index.GetPager().GetNumPages(); n != numPages {
+		t.Errorf("Expected churning at the split boundary to keep the index at %d pages, but it grew to %d", numPages, n)
+	}
+	for i := int64(0); i <= churnKey; i++ {
+		utils.CheckFindEntry(t, index, i, generateValue(i))
+	}
+}
@@ -0,0 +1,124 @@
+//go:build btreedebug
+
+package btree_test
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/test/utils"
+)
+
+// Run with: go test -tags btreedebug ./test/btree/
+func TestBTreeRootInvariant(t *testing.T) {
+	// These tests change a package-level setting, so they can't run in parallel with each other
+	oldHandler := btree.RootInvariantHandler
+	defer func() {
+		btree.RootInvariantHandler = oldHandler
+	}()
+	var mtx sync.Mutex
+	var reports []string
+	btree.RootInvariantHandler = func(report string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		reports = append(reports, report)
+	}
+	takeReports := func() []string {
+		mtx.Lock()
+		defer mtx.Unlock()
+		taken := reports
+		reports = nil
+		return taken
+	}
+
+	t.Run("MixedWorkload", func(t *testing.T) {
+		testRootInvariantMixedWorkload(t)
+		if taken := takeReports(); len(taken) > 0 {
+			t.Errorf("Expected no root invariant violations, but got %d, starting with: %s", len(taken), taken[0])
+		}
+	})
+	t.Run("CorruptRoot", func(t *testing.T) {
+		testRootInvariantCorruptRoot(t)
+		taken := takeReports()
+		if len(taken) != 1 || !strings.Contains(taken[0], "delete(7)") {
+			t.Errorf("Expected the delete that followed the corruption to be reported, but got %q", taken)
+		}
+	})
+}
+
+// openInvariantBTree opens an empty BTreeIndex like setupBTree, but without running the test in parallel
+func openInvariantBTree(t *testing.T) *btree.BTreeIndex {
+	index, err := btree.OpenIndex(utils.GetTempDbFile(t))
+	if err != nil {
+		t.Fatal("Failed to create BTree index:", err)
+	}
+	utils.EnsureCleanup(t, func() {
+		_ = index.Close()
+	})
+	return index
+}
+
+// Runs a long random mix of every mutating operation, enough to split the root,
+// checking the tree's contents at the end
+func testRootInvariantMixedWorkload(t *testing.T) {
+	index := openInvariantBTree(t)
+	rng := rand.New(rand.NewSource(1))
+	const numOps = 20000
+	const keySpace = 5000
+	expected := make(map[int64]int64)
+	for i := 0; i < numOps; i++ {
+		key := rng.Int63n(keySpace)
+		value := rng.Int63n(btreeSalt)
+		_, exists := expected[key]
+		switch op := rng.Intn(6); {
+		case op <= 1 && !exists:
+			utils.InsertEntry(t, index, key, value)
+			expected[key] = value
+		case op == 2 && exists:
+			if err := index.Update(key, value); err != nil {
+				t.Fatalf("Failed to update key %d: %v", key, err)
+			}
+			expected[key] = value
+		case op == 3:
+			if err := index.Upsert(key, value); err != nil {
+				t.Fatalf("Failed to upsert key %d: %v", key, err)
+			}
+			expected[key] = value
+		case op == 4 && exists:
+			e, err := index.Find(key)
+			if err != nil {
+				t.Fatalf("Failed to find key %d: %v", key, err)
+			}
+			if _, err := index.CompareAndSwap(key, e.Version, value); err != nil {
+				t.Fatalf("Failed to compare and swap key %d: %v", key, err)
+			}
+			expected[key] = value
+		case op == 5 && exists:
+			if err := index.Delete(key); err != nil {
+				t.Fatalf("Failed to delete key %d: %v", key, err)
+			}
+			delete(expected, key)
+		}
+	}
+	for key, value := range expected {
+		utils.CheckFindEntry(t, index, key, value)
+	}
+}
+
+// Overwrites the root's node type, checking that the next mutating operation is reported
+func testRootInvariantCorruptRoot(t *testing.T) {
+	index := openInvariantBTree(t)
+	utils.InsertEntry(t, index, 1, 1)
+	rootPage, err := index.GetPager().GetPage(btree.ROOT_PN)
+	if err != nil {
+		t.Fatal("Failed to get the root page:", err)
+	}
+	rootPage.WLock()
+	rootPage.Update([]byte{2}, btree.NODETYPE_OFFSET, btree.NODETYPE_SIZE)
+	rootPage.WUnlock()
+	_ = index.GetPager().PutPage(rootPage)
+	_ = index.Delete(7)
+}
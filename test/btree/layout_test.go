@@ -0,0 +1,84 @@
+package btree_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/btree"
+)
+
+// Inserts enough entries for a multi-level tree, checking that the layout classifies the root,
+// internal nodes, and leaves, and that the leaves' key ranges and siblings follow the sibling chain
+func TestBTreeLayout(t *testing.T) {
+	const numEntries = 50000
+	index := standardBTreeSetup(t, numEntries)
+	defer index.Close()
+	layout, err := index.Layout()
+	if err != nil {
+		t.Fatal("Failed to get layout:", err)
+	}
+	if int64(len(layout)) != index.GetPager().GetNumPages() {
+		t.Fatalf("Expected %d pages in the layout, but got %d", index.GetPager().GetNumPages(), len(layout))
+	}
+
+	siblings := leafSiblings(t, index)
+	leaves := make(map[int64]btree.PageInfo)
+	numInternal := 0
+	for i, info := range layout {
+		if info.PN != int64(i) {
+			t.Fatalf("Expected page %d at position %d of the layout, but got page %d", i, i, info.PN)
+		}
+		switch info.Role {
+		case btree.ROOT_PAGE:
+			if info.PN != btree.ROOT_PN {
+				t.Errorf("Expected page %d to be the root, but page %d was", btree.ROOT_PN, info.PN)
+			}
+			if info.NodeType != btree.INTERNAL_NODE {
+				t.Error("Expected the root of a multi-level tree to be an internal node")
+			}
+		case btree.INTERNAL_PAGE:
+			numInternal++
+		case btree.LEAF_PAGE:
+			if _, isLeaf := siblings[info.PN]; !isLeaf {
+				t.Errorf("Page %d was classified as a leaf but isn't one", info.PN)
+			}
+			leaves[info.PN] = info
+		default:
+			t.Errorf("Unexpected role %q for page %d", info.Role, info.PN)
+		}
+	}
+	if numInternal == 0 {
+		t.Error("Expected a multi-level tree to have internal nodes below the root")
+	}
+	if len(leaves) != len(siblings) {
+		t.Fatalf("Expected %d leaves, but got %d", len(siblings), len(leaves))
+	}
+
+	// The leftmost leaf is the one that no other leaf points to
+	pointedTo := make(map[int64]bool)
+	for _, info := range leaves {
+		pointedTo[info.RightSiblingPN] = true
+	}
+	pn := int64(-1)
+	for leafPN := range leaves {
+		if !pointedTo[leafPN] {
+			pn = leafPN
+		}
+	}
+	// Following the chain, the leaves' key ranges should cover every key exactly once, in order
+	nextKey := int64(0)
+	for pn != -1 {
+		info := leaves[pn]
+		if info.RightSiblingPN != siblings[pn] {
+			t.Errorf("Expected leaf %d to have right sibling %d, but got %d", pn, siblings[pn], info.RightSiblingPN)
+		}
+		if info.MinKey != nextKey || info.MaxKey != nextKey+info.NumKeys-1 {
+			t.Fatalf("Expected leaf %d to hold keys [%d, %d], but got [%d, %d]",
+				pn, nextKey, nextKey+info.NumKeys-1, info.MinKey, info.MaxKey)
+		}
+		nextKey += info.NumKeys
+		pn = info.RightSiblingPN
+	}
+	if nextKey != numEntries {
+		t.Errorf("Expected the leaves to hold %d keys, but got %d", numEntries, nextKey)
+	}
+}
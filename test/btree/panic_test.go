@@ -0,0 +1,113 @@
+package btree_test
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dinodb/pkg/btree"
+	"dinodb/test/utils"
+)
+
+// The key poisonComparator panics on, and how many of its comparisons to let through first.
+var poisonedKey, poisonCountdown atomic.Int64
+
+// poisonComparator orders keys ascending, but panics on the poisoned key's comparison that runs its countdown out,
+// so a lookup or write can be made to panic partway down the B+Tree while pages are locked
+func poisonComparator(a int64, b int64) int {
+	if key := poisonedKey.Load(); (a == key || b == key) && poisonCountdown.Add(-1) == 0 {
+		panic("poisoned key compared")
+	}
+	return cmp.Compare(a, b)
+}
+
+const POISON_COMPARATOR btree.ComparatorID = 101
+
+func init() {
+	poisonedKey.Store(math.MinInt64)
+	if err := btree.RegisterComparator(POISON_COMPARATOR, poisonComparator); err != nil {
+		panic(err)
+	}
+}
+
+// panicOps are the B+Tree's lookups and writes, each run on a single key.
+var panicOps = map[string]func(index *btree.BTreeIndex, key int64){
+	"Find":        func(index *btree.BTreeIndex, key int64) { index.Find(key) },
+	"Insert":      func(index *btree.BTreeIndex, key int64) { index.Insert(key, generateValue(key)) },
+	"Delete":      func(index *btree.BTreeIndex, key int64) { index.Delete(key) },
+	"SelectRange": func(index *btree.BTreeIndex, key int64) { index.SelectRange(key, key+1) },
+	"ExplainFind": func(index *btree.BTreeIndex, key int64) { index.ExplainFind(key) },
+}
+
+// runPoisoned runs op on the given key, panicking on the countdown'th comparison of that key.
+// Returns whether op panicked.
+func runPoisoned(index *btree.BTreeIndex, op func(*btree.BTreeIndex, int64), key int64, countdown int64) (panicked bool) {
+	poisonCountdown.Store(countdown)
+	poisonedKey.Store(key)
+	defer func() {
+		poisonedKey.Store(math.MinInt64)
+		panicked = recover() != nil
+	}()
+	op(index, key)
+	return false
+}
+
+// Panics every kind of lookup and write at different depths of the B+Tree as it grows, including inserts and deletes
+// that lock the root and their whole path, checking that a panic leaves no page locked and the B+Tree still usable
+func TestBTreePanicUnderLatch(t *testing.T) {
+	numKeys := int64(20000)
+	index, err := btree.OpenIndexWithComparator(utils.GetTempDbFile(t), POISON_COMPARATOR)
+	if err != nil {
+		t.Fatal("Failed to create BTree index:", err)
+	}
+	names := make([]string, 0, len(panicOps))
+	for name := range panicOps {
+		names = append(names, name)
+	}
+	done := make(chan struct{})
+	// Closing waits for every operation to finish, so it is left to the operations' goroutine in case one blocks.
+	go func() {
+		defer close(done)
+		defer index.Close()
+		numPanicked := 0
+		for i := range numKeys {
+			// A key is compared a few times on each level down, so a random countdown panics at a random depth.
+			if runPoisoned(index, panicOps[names[i%int64(len(names))]], i, rand.Int63n(24)+1) {
+				numPanicked++
+			}
+			if _, err := index.Find(i); err != nil {
+				utils.InsertEntry(t, index, i, generateValue(i))
+			}
+		}
+		if numPanicked == 0 {
+			t.Error("Expected some of the poisoned operations to panic")
+		}
+		for i := range numKeys {
+			utils.CheckFindEntry(t, index, i, generateValue(i))
+		}
+		for i := int64(0); i < numKeys; i += 2 {
+			if err := index.Delete(i); err != nil {
+				t.Errorf("Failed to delete key %d: %v", i, err)
+			}
+		}
+		entries, err := index.Select()
+		if err != nil {
+			t.Error("Failed to select after panics:", err)
+			return
+		}
+		if int64(len(entries)) != numKeys/2 {
+			t.Errorf("Expected %d entries after deleting every other key, but got %d", numKeys/2, len(entries))
+		}
+		for j, e := range entries {
+			utils.CheckEntry(t, e, int64(2*j+1), generateValue(int64(2*j+1)))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatal("B+Tree operations blocked after a panic, so a page was left locked")
+	}
+}
@@ -0,0 +1,130 @@
+package btree_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+)
+
+func TestBTreeLeafPartitions(t *testing.T) {
+	t.Run("ParallelScan", testPartitionParallelScan)
+	t.Run("MorePartitionsThanLeaves", testPartitionMoreThanLeaves)
+	t.Run("EmptyTree", testPartitionEmptyTree)
+}
+
+// scanPartition returns the entries in a partition from LeafPartitions
+func scanPartition(index *btree.BTreeIndex, partition [2]int64) ([]entry.Entry, error) {
+	entries := make([]entry.Entry, 0)
+	c, err := index.CursorAtLeaves(partition[0], partition[1])
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if !c.Valid() {
+		return entries, nil
+	}
+	for {
+		e, err := c.GetEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+		if c.Next() {
+			return entries, nil
+		}
+	}
+}
+
+// Scans a large B+Tree with a run of deleted keys across 4 workers, checking that
+// the partitions' entries, put back in partition order, are exactly those of a serial select
+func testPartitionParallelScan(t *testing.T) {
+	numEntries := int64(20000)
+	index := standardBTreeSetup(t, numEntries)
+	defer index.Close()
+	for i := int64(5000); i < 5000+2*btree.ENTRIES_PER_LEAF_NODE; i++ {
+		if err := index.Delete(i); err != nil {
+			t.Fatal("Failed to delete:", err)
+		}
+	}
+	expected, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+
+	partitions, err := index.LeafPartitions(4)
+	if err != nil {
+		t.Fatal("Failed to partition leaves:", err)
+	}
+	if len(partitions) != 4 {
+		t.Fatalf("Expected 4 partitions, but got %d", len(partitions))
+	}
+	results := make([][]entry.Entry, len(partitions))
+	var wg sync.WaitGroup
+	for i, partition := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			entries, err := scanPartition(index, partition)
+			if err != nil {
+				t.Errorf("Failed to scan partition %d: %v", i, err)
+			}
+			results[i] = entries
+		}()
+	}
+	wg.Wait()
+
+	for i, entries := range results {
+		if len(entries) < len(expected)/8 {
+			t.Errorf("Expected partition %d to hold roughly a quarter of the entries, but it holds %d of %d",
+				i, len(entries), len(expected))
+		}
+	}
+	if merged := slices.Concat(results...); !slices.Equal(merged, expected) {
+		t.Errorf("Expected the partitions to hold the %d selected entries, but they hold %d entries",
+			len(expected), len(merged))
+	}
+}
+
+// Checks that a B+Tree is split into no more partitions than it has leaves, and that n must be positive
+func testPartitionMoreThanLeaves(t *testing.T) {
+	index := standardBTreeSetup(t, 2*btree.ENTRIES_PER_LEAF_NODE)
+	defer index.Close()
+	partitions, err := index.LeafPartitions(100)
+	if err != nil {
+		t.Fatal("Failed to partition leaves:", err)
+	}
+	if len(partitions) < 2 || len(partitions) > 4 {
+		t.Errorf("Expected one partition for each of the 2 to 4 leaves, but got %d", len(partitions))
+	}
+	for i, partition := range partitions {
+		if partition[0] != partition[1] {
+			t.Errorf("Expected partition %d to hold a single leaf, but it is %v", i, partition)
+		}
+	}
+	if _, err := index.LeafPartitions(0); err == nil {
+		t.Error("Expected an error for 0 partitions")
+	}
+}
+
+// Checks that an empty B+Tree is a single partition with nothing to scan
+func testPartitionEmptyTree(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	partitions, err := index.LeafPartitions(4)
+	if err != nil {
+		t.Fatal("Failed to partition leaves:", err)
+	}
+	if len(partitions) != 1 {
+		t.Fatalf("Expected 1 partition, but got %d", len(partitions))
+	}
+	entries, err := scanPartition(index, partitions[0])
+	if err != nil {
+		t.Fatal("Failed to scan partition:", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries, but got %d", len(entries))
+	}
+}
@@ -0,0 +1,83 @@
+package btree_test
+
+import (
+	"math"
+	"testing"
+
+	"dinodb/test/utils"
+)
+
+func TestBTreeSelectPrefix(t *testing.T) {
+	t.Run("SharedPrefixes", testSelectPrefixShared)
+	t.Run("Edges", testSelectPrefixEdges)
+}
+
+// Inserts keys made of a 16-bit bucket and a 48-bit id, checking that each bucket's prefix selects exactly its keys
+func testSelectPrefixShared(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	buckets := []int64{-2, -1, 0, 1, 7}
+	idsPerBucket := int64(300)
+	for _, bucket := range buckets {
+		for id := range idsPerBucket {
+			key := bucket<<48 | id*1000
+			utils.InsertEntry(t, index, key, generateValue(key))
+		}
+	}
+
+	for _, bucket := range append(buckets, 2, math.MaxInt16, math.MinInt16) {
+		entries, err := index.SelectPrefix(bucket, 16)
+		if err != nil {
+			t.Fatalf("Failed to select prefix %d: %v", bucket, err)
+		}
+		expected := int64(0)
+		for _, b := range buckets {
+			if b == bucket {
+				expected = idsPerBucket
+			}
+		}
+		if int64(len(entries)) != expected {
+			t.Errorf("Expected %d entries with prefix %d, but got %d", expected, bucket, len(entries))
+		}
+		for i, e := range entries {
+			if e.Key>>48 != bucket {
+				t.Errorf("Selected key %d for prefix %d, but its prefix is %d", e.Key, bucket, e.Key>>48)
+			}
+			if e.Key != bucket<<48|int64(i)*1000 {
+				t.Errorf("Expected entry %d with prefix %d to have key %d, but got %d", i, bucket, bucket<<48|int64(i)*1000, e.Key)
+			}
+		}
+	}
+}
+
+// Checks prefixes covering the largest and smallest keys, whole-key prefixes, and invalid prefixes
+func testSelectPrefixEdges(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	for _, key := range []int64{math.MinInt64, -1, 0, 1, math.MaxInt64 - 1, math.MaxInt64} {
+		utils.InsertEntry(t, index, key, generateValue(key))
+	}
+	for _, test := range []struct {
+		prefix, bits, expected int64
+	}{
+		{0, 1, 4},              // The non-negative keys
+		{-1, 1, 2},             // The negative keys
+		{math.MaxInt32, 32, 2}, // The largest prefix, whose range ends at the largest key
+		{math.MinInt32, 32, 1}, // The smallest prefix
+		{math.MaxInt64, 64, 1}, // A whole key
+		{-1, 64, 1},
+		{2, 64, 0},
+	} {
+		entries, err := index.SelectPrefix(test.prefix, test.bits)
+		if err != nil {
+			t.Errorf("Failed to select prefix %d of %d bits: %v", test.prefix, test.bits, err)
+		} else if int64(len(entries)) != test.expected {
+			t.Errorf("Expected %d entries with prefix %d of %d bits, but got %d", test.expected, test.prefix, test.bits, len(entries))
+		}
+	}
+	for _, test := range [][2]int64{{0, 0}, {0, 65}, {2, 2}, {-3, 2}} {
+		if _, err := index.SelectPrefix(test[0], test[1]); err == nil {
+			t.Errorf("Expected an error for prefix %d of %d bits", test[0], test[1])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build latchdebug
+
+package btree_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+	"dinodb/pkg/pager"
+)
+
+// Run with: go test -tags latchdebug -run XXX -bench SelectLocks ./test/btree/
+// Reports the page locks each select takes as locks/op.
+func BenchmarkSelectLocks(b *testing.B) {
+	index, err := btree.OpenIndex(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatal("Failed to create BTree index:", err)
+	}
+	defer index.Close()
+	for i := range int64(20000) {
+		if err := index.Insert(i, i); err != nil {
+			b.Fatal("Failed to insert:", err)
+		}
+	}
+	for _, bench := range []struct {
+		name      string
+		selectAll func() ([]entry.Entry, error)
+	}{
+		{"Cursor", index.Select},
+		{"Batched", index.SelectBatched},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			start := pager.LatchAcquisitions.Load()
+			for range b.N {
+				if _, err := bench.selectAll(); err != nil {
+					b.Fatal("Failed to select:", err)
+				}
+			}
+			b.ReportMetric(float64(pager.LatchAcquisitions.Load()-start)/float64(b.N), "locks/op")
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package btree_test
+
+import (
+	"slices"
+	"testing"
+
+	"dinodb/pkg/btree"
+)
+
+func TestBTreeSelectBatched(t *testing.T) {
+	t.Run("MatchesSelect", testSelectBatchedMatchesSelect)
+	t.Run("EmptyTree", testSelectBatchedEmptyTree)
+}
+
+// Selects from a B+Tree with several levels and an emptied leaf, checking that both selects return the same entries
+func testSelectBatchedMatchesSelect(t *testing.T) {
+	index := standardBTreeSetup(t, 20000)
+	defer index.Close()
+	for i := btree.ENTRIES_PER_LEAF_NODE; i < 3*btree.ENTRIES_PER_LEAF_NODE; i++ {
+		if err := index.Delete(i); err != nil {
+			t.Fatal("Failed to delete:", err)
+		}
+	}
+	expected, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	entries, err := index.SelectBatched()
+	if err != nil {
+		t.Fatal("Failed to select in batches:", err)
+	}
+	if !slices.Equal(entries, expected) {
+		t.Errorf("Expected the batched select to return the %d selected entries, but it returned %d entries",
+			len(expected), len(entries))
+	}
+}
+
+// Selects from an empty B+Tree, checking that no entries are returned
+func testSelectBatchedEmptyTree(t *testing.T) {
+	index := setupBTree(t)
+	defer index.Close()
+	entries, err := index.SelectBatched()
+	if err != nil {
+		t.Fatal("Failed to select in batches:", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries from an empty tree, but got %d", len(entries))
+	}
+}
@@ -10,6 +10,7 @@ import (
 func TestBTreeSelect(t *testing.T) {
 	t.Run("Increasing", testSelectIncreasing)
 	t.Run("WithEmptyNodes", testSelectWithEmptyNodes)
+	t.Run("EmptyTree", testSelectEmptyTree)
 }
 
 func TestBTreeSelectRange(t *testing.T) {
@@ -17,6 +18,8 @@ func TestBTreeSelectRange(t *testing.T) {
 	t.Run("Delete", testSelectRangeDelete)
 	t.Run("InvalidStartkey", testSelectRangeInvalidStartkey)
 	t.Run("DeletedStartKey", testSelectRangeDeletedStartKey)
+	t.Run("EmptyTree", testSelectRangeEmptyTree)
+	t.Run("PastLastKey", testSelectRangePastLastKey)
 }
 
 /*
@@ -102,6 +105,24 @@ func testSelectWithEmptyNodes(t *testing.T) {
 	index.Close()
 }
 
+/*
+Selects from a newly created BTree index, checking that no entries and no
+error are returned, and that the index can still be inserted into afterwards
+*/
+func testSelectEmptyTree(t *testing.T) {
+	index := setupBTree(t)
+	entries, err := index.Select()
+	if err != nil {
+		t.Fatal("Select on an empty tree should not error, but got:", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries from an empty tree, but got %d", len(entries))
+	}
+	utils.InsertEntry(t, index, 1, generateValue(1))
+	utils.CheckFindEntry(t, index, 1, generateValue(1))
+	index.Close()
+}
+
 /*
 Creates a BTree index, inserts 1000 entries, and then retrieves some of the
 entries through SelectRange
@@ -229,3 +250,35 @@ func testSelectRangeInvalidStartkey(t *testing.T) {
 		})
 	}
 }
+
+/*
+Calls SelectRange on a newly created BTree index, checking that no entries
+and no error are returned
+*/
+func testSelectRangeEmptyTree(t *testing.T) {
+	index := setupBTree(t)
+	entries, err := index.SelectRange(0, 100)
+	if err != nil {
+		t.Fatal("SelectRange on an empty tree should not error, but got:", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries from an empty tree, but got %d", len(entries))
+	}
+	index.Close()
+}
+
+/*
+Calls SelectRange with a range entirely after the last key in the BTree index,
+checking that no entries and no error are returned
+*/
+func testSelectRangePastLastKey(t *testing.T) {
+	index := standardBTreeSetup(t, 100)
+	entries, err := index.SelectRange(200, 300)
+	if err != nil {
+		t.Fatal("SelectRange past the last key should not error, but got:", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries past the last key, but got %d", len(entries))
+	}
+	index.Close()
+}
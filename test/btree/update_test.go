@@ -0,0 +1,87 @@
+package btree_test
+
+import (
+	"testing"
+
+	"dinodb/test/utils"
+)
+
+func TestBTreeUpdate(t *testing.T) {
+	t.Run("Existing", testUpdateExisting)
+	t.Run("Missing", testUpdateMissing)
+	t.Run("UpsertMissing", testUpsertMissing)
+	t.Run("UpsertExisting", testUpsertExisting)
+}
+
+// Updates every entry in a multi-level tree, checking the new values are found
+func testUpdateExisting(t *testing.T) {
+	numInserts := int64(1000)
+	index := standardBTreeSetup(t, numInserts)
+	defer index.Close()
+	for i := range numInserts {
+		if err := index.Update(i, i+1); err != nil {
+			t.Fatalf("Failed to update key %d: %v", i, err)
+		}
+	}
+	for i := range numInserts {
+		utils.CheckFindEntry(t, index, i, i+1)
+	}
+}
+
+// Updates keys that aren't in the tree, checking that it fails without inserting them
+func testUpdateMissing(t *testing.T) {
+	numInserts := int64(1000)
+	index := standardBTreeSetup(t, numInserts)
+	defer index.Close()
+	for i := numInserts; i < 2*numInserts; i++ {
+		if err := index.Update(i, i); err == nil {
+			t.Fatalf("Could update non-existent key %d in a B+Tree", i)
+		}
+	}
+	for i := numInserts; i < 2*numInserts; i++ {
+		if _, err := index.Find(i); err == nil {
+			t.Fatalf("Failed update inserted key %d into a B+Tree", i)
+		}
+	}
+}
+
+// Upserts keys that aren't in the tree, which should insert them (splitting as necessary)
+func testUpsertMissing(t *testing.T) {
+	numInserts := int64(1000)
+	index := setupBTree(t)
+	defer index.Close()
+	for i := range numInserts {
+		if err := index.Upsert(i, generateValue(i)); err != nil {
+			t.Fatalf("Failed to upsert key %d: %v", i, err)
+		}
+	}
+	if index.GetPager().GetNumPages() <= 1 {
+		t.Error("Expected upserting to split the root node")
+	}
+	index = closeAndReopen(t, index)
+	for i := range numInserts {
+		utils.CheckFindEntry(t, index, i, generateValue(i))
+	}
+}
+
+// Upserts keys that are already in the tree, which should update them without adding entries
+func testUpsertExisting(t *testing.T) {
+	numInserts := int64(1000)
+	index := standardBTreeSetup(t, numInserts)
+	defer index.Close()
+	for i := range numInserts {
+		if err := index.Upsert(i, i+1); err != nil {
+			t.Fatalf("Failed to upsert key %d: %v", i, err)
+		}
+	}
+	entries, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select entries:", err)
+	}
+	if int64(len(entries)) != numInserts {
+		t.Errorf("Expected %d entries after upserting existing keys, but found %d", numInserts, len(entries))
+	}
+	for i := range numInserts {
+		utils.CheckFindEntry(t, index, i, i+1)
+	}
+}
@@ -0,0 +1,120 @@
+package btree_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"dinodb/pkg/btree"
+)
+
+// leafSiblings maps the page number of every leaf in the index to its right sibling's page number
+func leafSiblings(t *testing.T, index *btree.BTreeIndex) map[int64]int64 {
+	siblings := make(map[int64]int64)
+	p := index.GetPager()
+	for pn := int64(0); pn < p.GetNumPages(); pn++ {
+		page, err := p.GetPage(pn)
+		if err != nil {
+			t.Fatal("Failed to get page:", err)
+		}
+		data := page.GetData()
+		if data[btree.NODETYPE_OFFSET] == 1 {
+			siblings[pn], _ = binary.Varint(data[btree.RIGHT_SIBLING_PN_OFFSET : btree.RIGHT_SIBLING_PN_OFFSET+btree.RIGHT_SIBLING_PN_SIZE])
+		}
+		p.PutPage(page)
+	}
+	return siblings
+}
+
+// setRightSibling overwrites the right sibling pointer stored in the given leaf's page
+func setRightSibling(t *testing.T, index *btree.BTreeIndex, pn int64, siblingPN int64) {
+	page, err := index.GetPager().GetPage(pn)
+	if err != nil {
+		t.Fatal("Failed to get page:", err)
+	}
+	defer index.GetPager().PutPage(page)
+	data := make([]byte, btree.RIGHT_SIBLING_PN_SIZE)
+	binary.PutVarint(data, siblingPN)
+	page.Update(data, btree.RIGHT_SIBLING_PN_OFFSET, btree.RIGHT_SIBLING_PN_SIZE)
+}
+
+// checkChainMismatch errors the test if verifying the index's sibling chain doesn't report a mismatch
+func checkChainMismatch(t *testing.T, index *btree.BTreeIndex) {
+	err := btree.VerifyChains(index)
+	if !errors.Is(err, btree.ErrSiblingChainMismatch) {
+		t.Errorf("Expected %q, but got %v", btree.ErrSiblingChainMismatch, err)
+	}
+}
+
+func TestBTreeVerifyChains(t *testing.T) {
+	t.Run("Valid", testVerifyChainsValid)
+	t.Run("SkippedLeaf", testVerifyChainsSkippedLeaf)
+	t.Run("EndedEarly", testVerifyChainsEndedEarly)
+	t.Run("NotEnded", testVerifyChainsNotEnded)
+}
+
+// Checks that the chain is valid for a lone root leaf, after many splits, and after reopening
+func testVerifyChainsValid(t *testing.T) {
+	for _, numInserts := range []int64{0, 10, 5000} {
+		t.Run(fmt.Sprint(numInserts), func(t *testing.T) {
+			index := standardBTreeSetup(t, numInserts)
+			if err := btree.VerifyChains(index); err != nil {
+				t.Errorf("Expected a valid chain, but got %v", err)
+			}
+			index = closeAndReopen(t, index)
+			defer index.Close()
+			if err := btree.VerifyChains(index); err != nil {
+				t.Errorf("Expected a valid chain after reopening, but got %v", err)
+			}
+		})
+	}
+}
+
+// Points a leaf past its right sibling, checking that the skipped leaf is noticed
+func testVerifyChainsSkippedLeaf(t *testing.T) {
+	index := standardBTreeSetup(t, 5000)
+	defer index.Close()
+	siblings := leafSiblings(t, index)
+	for pn, sibling := range siblings {
+		if sibling >= 0 && siblings[sibling] >= 0 {
+			setRightSibling(t, index, pn, siblings[sibling])
+			checkChainMismatch(t, index)
+			return
+		}
+	}
+	t.Fatal("Expected to find a leaf with two leaves to its right")
+}
+
+// Ends the chain at a leaf that has a right sibling, checking that the missing leaves are noticed
+func testVerifyChainsEndedEarly(t *testing.T) {
+	index := standardBTreeSetup(t, 5000)
+	defer index.Close()
+	for pn, sibling := range leafSiblings(t, index) {
+		if sibling >= 0 {
+			setRightSibling(t, index, pn, -1)
+			checkChainMismatch(t, index)
+			return
+		}
+	}
+	t.Fatal("Expected to find a leaf with a right sibling")
+}
+
+// Points the last leaf back at another leaf, checking that the chain is expected to end
+func testVerifyChainsNotEnded(t *testing.T) {
+	index := standardBTreeSetup(t, 5000)
+	defer index.Close()
+	siblings := leafSiblings(t, index)
+	for pn, sibling := range siblings {
+		if sibling < 0 {
+			for other := range siblings {
+				if other != pn {
+					setRightSibling(t, index, pn, other)
+					checkChainMismatch(t, index)
+					return
+				}
+			}
+		}
+	}
+	t.Fatal("Expected to find at least two leaves")
+}
@@ -0,0 +1,113 @@
+package btree_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/entry"
+)
+
+func TestBTreeSelectRangeWhere(t *testing.T) {
+	t.Run("Matches", testSelectRangeWhereMatches)
+	t.Run("StopsEarly", testSelectRangeWhereStopsEarly)
+	t.Run("CallbackError", testSelectRangeWhereCallbackError)
+	t.Run("InvalidRange", testSelectRangeWhereInvalidRange)
+}
+
+// isEven is a predicate accepting entries with even keys
+func isEven(e entry.Entry) bool {
+	return e.Key%2 == 0
+}
+
+// Streams the entries with even keys in a range, checking that exactly those entries arrive in order
+func testSelectRangeWhereMatches(t *testing.T) {
+	index := standardBTreeSetup(t, 1000)
+	defer index.Close()
+	matches := make([]entry.Entry, 0)
+	err := index.SelectRangeWhere(101, 301, isEven, func(e entry.Entry) error {
+		matches = append(matches, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Failed to select range:", err)
+	}
+	if len(matches) != 100 {
+		t.Fatalf("Expected 100 even keys in [101, 301), but got %d", len(matches))
+	}
+	for i, e := range matches {
+		if expected := int64(102 + 2*i); e.Key != expected || e.Value != generateValue(expected) {
+			t.Errorf("Expected match %d to be key %d, but got %v", i, expected, e)
+		}
+	}
+}
+
+// Stops a scan of half of a cold tree after a few matches, checking through the pager's disk reads
+// that only the path to the range's start and the leaves holding the matches were read
+func testSelectRangeWhereStopsEarly(t *testing.T) {
+	numEntries := int64(10000)
+	index := closeAndReopen(t, standardBTreeSetup(t, numEntries))
+	defer index.Close()
+	height := describe(t, index).Height
+	// Opening the index read the leftmost path, so start in the middle of the tree.
+	start := numEntries / 2
+	readsBefore := index.GetPager().GetNumDiskReads()
+	numMatches := 0
+	err := index.SelectRangeWhere(start, numEntries, isEven, func(e entry.Entry) error {
+		numMatches++
+		if numMatches == 10 {
+			return btree.ErrStopScan
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Expected stopping the scan to return nil, but got", err)
+	}
+	if numMatches != 10 {
+		t.Errorf("Expected the scan to stop after 10 matches, but got %d", numMatches)
+	}
+	// The 19 entries scanned fit in at most two leaves, below the internal nodes on the path to start.
+	earlyReads := index.GetPager().GetNumDiskReads() - readsBefore
+	if earlyReads > height+1 {
+		t.Errorf("Expected stopping early to read at most %d pages, but it read %d", height+1, earlyReads)
+	}
+	readsBefore = index.GetPager().GetNumDiskReads()
+	err = index.SelectRangeWhere(start, numEntries, isEven, func(e entry.Entry) error { return nil })
+	if err != nil {
+		t.Fatal("Failed to select range:", err)
+	}
+	if fullReads := index.GetPager().GetNumDiskReads() - readsBefore; fullReads <= 10*earlyReads {
+		t.Errorf("Expected the full scan to read many more pages than the %d read by stopping early, but it read %d", earlyReads, fullReads)
+	}
+}
+
+// Checks that an error from the callback stops the scan and is returned
+func testSelectRangeWhereCallbackError(t *testing.T) {
+	index := standardBTreeSetup(t, 100)
+	defer index.Close()
+	errFound := errors.New("found it")
+	numCalls := 0
+	err := index.SelectRangeWhere(0, 100, isEven, func(e entry.Entry) error {
+		numCalls++
+		if e.Key == 50 {
+			return errFound
+		}
+		return nil
+	})
+	if !errors.Is(err, errFound) {
+		t.Errorf("Expected %q, but got %v", errFound, err)
+	}
+	if numCalls != 26 {
+		t.Errorf("Expected the scan to stop at its 26th match, but it made %d calls", numCalls)
+	}
+}
+
+// Checks that a range whose start doesn't sort before its end is rejected
+func testSelectRangeWhereInvalidRange(t *testing.T) {
+	index := standardBTreeSetup(t, 10)
+	defer index.Close()
+	err := index.SelectRangeWhere(5, 5, isEven, func(e entry.Entry) error { return nil })
+	if err == nil {
+		t.Error("Expected an empty range to be rejected")
+	}
+}
@@ -0,0 +1,61 @@
+package concurrency_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/concurrency"
+	"dinodb/pkg/database"
+	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
+)
+
+// Runs a command that panics while its client's transaction holds a lock,
+// checking that the REPL keeps going and that another client can then take the lock
+func TestPanicReleasesLocks(t *testing.T) {
+	t.Parallel()
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dbName)
+	})
+	table, err := db.CreateTable("panic", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+
+	buggy := repl.NewRepl()
+	buggy.AddCommand("bug", func(payload string, replConfig *repl.REPLConfig) (string, error) {
+		panic("bug in handler")
+	}, "Panic. usage: bug")
+	r, err := repl.CombineRepls([]*repl.REPL{concurrency.TransactionREPL(db, tm), buggy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output strings.Builder
+	input := strings.NewReader("transaction begin\nlock panic 1\nbug\n.help\n")
+	r.Run(uuid.New(), "", input, &output)
+	if !strings.Contains(output.String(), repl.ErrorPrependStr+repl.ErrCommandPanicked.Error()) {
+		t.Errorf("Expected the panic to be reported as an error, got output %q", output.String())
+	}
+	if !strings.Contains(output.String(), "Panic. usage: bug") {
+		t.Errorf("Expected the REPL to keep running commands after the panic, got output %q", output.String())
+	}
+
+	tid := uuid.New()
+	if err := tm.Begin(tid); err != nil {
+		t.Fatal(err)
+	}
+	checkAcquired(t, lockAsync(tm, table, tid, 1, concurrency.W_LOCK))
+}
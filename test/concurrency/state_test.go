@@ -0,0 +1,105 @@
+package concurrency_test
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/concurrency"
+
+	"github.com/google/uuid"
+)
+
+// Runs transactions that contend for overlapping keys, unlocking some of them early and committing the rest,
+// checking that the transaction manager's state returns to where it started
+func TestStateSnapshotNoLeaks(t *testing.T) {
+	tm, index := setupTransaction(t)
+	before := tm.StateSnapshot()
+	if before != (concurrency.TMState{}) {
+		t.Fatalf("Expected a new transaction manager to be empty, but got %+v", before)
+	}
+
+	numClients, numTxs, numKeys := 8, 50, int64(16)
+	var wg sync.WaitGroup
+	errs := make(chan error, numClients)
+	for c := 0; c < numClients; c++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			clientId := uuid.New()
+			for i := 0; i < numTxs; i++ {
+				if err := tm.Begin(clientId); err != nil {
+					errs <- err
+					return
+				}
+				// Lock keys in ascending order so that transactions wait on each other without deadlocking.
+				keys := make([]int64, 0)
+				for key := int64(0); key < numKeys; key++ {
+					if rng.Intn(4) == 0 {
+						keys = append(keys, key)
+					}
+				}
+				lTypes := make(map[int64]concurrency.LockType)
+				for _, key := range keys {
+					lTypes[key] = concurrency.R_LOCK
+					if rng.Intn(2) == 0 {
+						lTypes[key] = concurrency.W_LOCK
+					}
+					if err := tm.Lock(clientId, index, key, lTypes[key]); err != nil {
+						errs <- err
+						return
+					}
+				}
+				// Unlock some keys early, leaving the rest to the commit.
+				for _, key := range slices.Backward(keys) {
+					if rng.Intn(2) == 0 {
+						continue
+					}
+					if err := tm.Unlock(clientId, index, key, lTypes[key]); err != nil {
+						errs <- err
+						return
+					}
+				}
+				if err := tm.Commit(clientId); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(int64(c))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal("Workload failed:", err)
+	}
+
+	if after := tm.StateSnapshot(); after != before {
+		t.Errorf("Expected the state to return to %+v after the workload, but got %+v", before, after)
+	}
+}
+
+// Checks that a snapshot taken mid-transaction counts its transaction and locks
+func TestStateSnapshotCounts(t *testing.T) {
+	tm, index := setupTransaction(t)
+	clientId := uuid.New()
+	if err := tm.Begin(clientId); err != nil {
+		t.Fatal(err)
+	}
+	for key := int64(0); key < 3; key++ {
+		if err := tm.Lock(clientId, index, key, concurrency.R_LOCK); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := concurrency.TMState{NumTransactions: 1, NumHeldLocks: 3, NumResources: 3}
+	if state := tm.StateSnapshot(); state != expected {
+		t.Errorf("Expected the state to be %+v, but got %+v", expected, state)
+	}
+	if err := tm.Commit(clientId); err != nil {
+		t.Fatal(err)
+	}
+	if state := tm.StateSnapshot(); state != (concurrency.TMState{}) {
+		t.Errorf("Expected committing to leave the state empty, but got %+v", state)
+	}
+}
@@ -1,8 +1,12 @@
 package concurrency_test
 
 import (
+	"bytes"
 	"dinodb/pkg/concurrency"
 	"dinodb/pkg/database"
+	"errors"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -98,6 +102,48 @@ func TestTransaction(t *testing.T) {
 	t.Run("DontDowngradeLocks", testTransactionDontDowngradeLocks)
 	t.Run("LockIdempotency", testTransactionLockIdempotency)
 	t.Run("CommitsReleaseLocks", testTransactionCommitsReleaseLocks)
+	t.Run("ReleaseLock", testTransactionReleaseLock)
+	t.Run("ReleaseAllReadLocks", testTransactionReleaseAllReadLocks)
+	t.Run("AbortAll", testTransactionAbortAll)
+	t.Run("ConvertInUse", testTransactionConvertInUse)
+	t.Run("TableReserved", testTransactionTableReserved)
+	t.Run("TruncateInUse", testTransactionTruncateInUse)
+	t.Run("IsolationSerializable", testTransactionIsolationSerializable)
+	t.Run("IsolationReadCommitted", testTransactionIsolationReadCommitted)
+	t.Run("BeginStrict", testTransactionBeginStrict)
+	t.Run("BeginResumable", testTransactionBeginResumable)
+	t.Run("OutputFormat", testTransactionOutputFormat)
+}
+
+// lockAsync tries to lock a resource in a separate goroutine,
+// returning a channel that receives the result once the lock call returns
+func lockAsync(tm *concurrency.TransactionManager, table database.Index, tid uuid.UUID, key int64, lt concurrency.LockType) chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- tm.Lock(tid, table, key, lt)
+	}()
+	return result
+}
+
+// checkAcquired errors the test if the lock call hasn't successfully returned
+func checkAcquired(t *testing.T, result chan error) {
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(10 * DELAY_TIME):
+		t.Error("expected lock to be acquired")
+	}
+}
+
+// checkBlocked errors the test if the lock call has returned
+func checkBlocked(t *testing.T, result chan error) {
+	select {
+	case err := <-result:
+		t.Errorf("expected lock to block, but it returned (err: %v)", err)
+	case <-time.After(10 * DELAY_TIME):
+	}
 }
 
 func testTransactionBasic(t *testing.T) {
@@ -291,3 +337,410 @@ func testTransactionCommitsReleaseLocks(t *testing.T) {
 	// Check for errors
 	checkWasErrors(t, errch)
 }
+
+func testTransactionReleaseLock(t *testing.T) {
+	tm, index := setupTransaction(t)
+	tid1, tid2 := uuid.New(), uuid.New()
+	tm.Begin(tid1)
+	tm.Begin(tid2)
+	defer tm.Commit(tid2)
+	defer tm.Commit(tid1)
+	// Releasing an unheld resource is an error
+	if err := tm.ReleaseLock(tid1, index, 1); err == nil {
+		t.Error("expected releasing an unheld resource to fail")
+	}
+	// Hold a write lock, which blocks the second transaction
+	if err := tm.Lock(tid1, index, 1, concurrency.W_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	result := lockAsync(tm, index, tid2, 1, concurrency.W_LOCK)
+	checkBlocked(t, result)
+	// Release it mid-transaction; the second transaction should get the lock
+	if err := tm.ReleaseLock(tid1, index, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkAcquired(t, result)
+	// The first transaction should still be running, but no longer hold the resource
+	tx, found := tm.GetTransaction(tid1)
+	if !found {
+		t.Fatal("expected transaction to still be running after releasing a lock")
+	}
+	tx.RLock()
+	defer tx.RUnlock()
+	if len(tx.GetResources()) != 0 {
+		t.Errorf("expected no locked resources, but found %v", tx.GetResources())
+	}
+}
+
+func testTransactionReleaseAllReadLocks(t *testing.T) {
+	tm, index := setupTransaction(t)
+	tid1, tid2 := uuid.New(), uuid.New()
+	tm.Begin(tid1)
+	tm.Begin(tid2)
+	defer tm.Commit(tid2)
+	defer tm.Commit(tid1)
+	// Hold two read locks and one write lock
+	for _, key := range []int64{1, 2} {
+		if err := tm.Lock(tid1, index, key, concurrency.R_LOCK); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tm.Lock(tid1, index, 3, concurrency.W_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	result := lockAsync(tm, index, tid2, 1, concurrency.W_LOCK)
+	checkBlocked(t, result)
+	// Release the read locks; the write lock on key 1 should go through
+	if err := tm.ReleaseAllReadLocks(tid1); err != nil {
+		t.Fatal(err)
+	}
+	checkAcquired(t, result)
+	checkAcquired(t, lockAsync(tm, index, tid2, 2, concurrency.W_LOCK))
+	// The write lock should still be held
+	tx, _ := tm.GetTransaction(tid1)
+	tx.RLock()
+	resources := tx.GetResources()
+	if len(resources) != 1 {
+		t.Errorf("expected only the write lock to remain, but found %v", resources)
+	}
+	tx.RUnlock()
+	result = lockAsync(tm, index, tid2, 3, concurrency.W_LOCK)
+	checkBlocked(t, result)
+	tm.Commit(tid1)
+	checkAcquired(t, result)
+}
+
+func testTransactionAbortAll(t *testing.T) {
+	tm, index := setupTransaction(t)
+	tid1, tid2, tid3 := uuid.New(), uuid.New(), uuid.New()
+	tm.Begin(tid1)
+	tm.Begin(tid2)
+	tm.Begin(tid3)
+	// Two transactions hold locks, and a third is blocked waiting on one of them
+	if err := tm.Lock(tid1, index, 1, concurrency.W_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.Lock(tid2, index, 2, concurrency.R_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	blocked := lockAsync(tm, index, tid3, 1, concurrency.W_LOCK)
+	checkBlocked(t, blocked)
+	if err := tm.AbortAll(); err != nil {
+		t.Fatal(err)
+	}
+	// The blocked transaction should be woken up and told it was aborted
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, concurrency.ErrTransactionAborted) {
+			t.Errorf("expected blocked lock to fail with %q, but got %v", concurrency.ErrTransactionAborted, err)
+		}
+	case <-time.After(10 * DELAY_TIME):
+		t.Fatal("expected blocked lock to return after aborting all transactions")
+	}
+	if n := len(tm.GetTransactions()); n != 0 {
+		t.Errorf("expected no running transactions, but found %d", n)
+	}
+	if n := tm.GetWaitsForGraph().NumEdges(); n != 0 {
+		t.Errorf("expected an empty waits-for graph, but found %d edges", n)
+	}
+	// A new transaction should be able to take every previously held lock
+	tid4 := uuid.New()
+	tm.Begin(tid4)
+	defer tm.Commit(tid4)
+	checkAcquired(t, lockAsync(tm, index, tid4, 1, concurrency.W_LOCK))
+	checkAcquired(t, lockAsync(tm, index, tid4, 2, concurrency.W_LOCK))
+	// Aborted clients are told once, then can start over
+	if err := tm.Lock(tid1, index, 3, concurrency.R_LOCK); !errors.Is(err, concurrency.ErrTransactionAborted) {
+		t.Errorf("expected lock to fail with %q, but got %v", concurrency.ErrTransactionAborted, err)
+	}
+	if err := tm.Commit(tid2); !errors.Is(err, concurrency.ErrTransactionAborted) {
+		t.Errorf("expected commit to fail with %q, but got %v", concurrency.ErrTransactionAborted, err)
+	}
+	if err := tm.Lock(tid1, index, 3, concurrency.R_LOCK); err == nil || errors.Is(err, concurrency.ErrTransactionAborted) {
+		t.Errorf("expected a generic missing transaction error, but got %v", err)
+	}
+	if err := tm.Begin(tid1); err != nil {
+		t.Fatal(err)
+	}
+	defer tm.Commit(tid1)
+	checkAcquired(t, lockAsync(tm, index, tid1, 3, concurrency.R_LOCK))
+}
+
+func testTransactionConvertInUse(t *testing.T) {
+	t.Parallel()
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbName)
+	defer db.Close()
+	table, err := db.CreateTable("convert", database.HashIndexType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+	tid := uuid.New()
+	tm.Begin(tid)
+	if err := tm.Lock(tid, table, 1, concurrency.R_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	// Converting a table that a transaction has locked should be rejected
+	_, err = concurrency.HandleConvert(db, tm, "convert convert to btree")
+	if !errors.Is(err, concurrency.ErrTableInUse) {
+		t.Errorf("expected converting a locked table to fail with %q, but got %v", concurrency.ErrTableInUse, err)
+	}
+	// Once the transaction commits, the table can be converted
+	tm.Commit(tid)
+	if _, err := concurrency.HandleConvert(db, tm, "convert convert to btree"); err != nil {
+		t.Error(err)
+	}
+}
+
+func testTransactionTableReserved(t *testing.T) {
+	t.Parallel()
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbName)
+	defer db.Close()
+	table, err := db.CreateTable("reserved", database.HashIndexType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+	tid := uuid.New()
+	tm.Begin(tid)
+	defer tm.Commit(tid)
+	// No transaction can lock the table while it is reserved, and it can't be reserved twice
+	err = tm.WithTableReserved(table, func() error {
+		if err := tm.Lock(tid, table, 1, concurrency.W_LOCK); !errors.Is(err, concurrency.ErrTableInUse) {
+			t.Errorf("expected locking a reserved table to fail with %q, but got %v", concurrency.ErrTableInUse, err)
+		}
+		if err := tm.WithTableReserved(table, func() error { return nil }); !errors.Is(err, concurrency.ErrTableInUse) {
+			t.Errorf("expected reserving a reserved table to fail with %q, but got %v", concurrency.ErrTableInUse, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Once the reservation ends, the lock can be taken
+	if err := tm.Lock(tid, table, 1, concurrency.W_LOCK); err != nil {
+		t.Error(err)
+	}
+}
+
+func testTransactionTruncateInUse(t *testing.T) {
+	t.Parallel()
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbName)
+	defer db.Close()
+	table, err := db.CreateTable("truncate", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Insert(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+	tid := uuid.New()
+	tm.Begin(tid)
+	if err := tm.Lock(tid, table, 1, concurrency.R_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	// Truncating a table that a transaction has locked should be rejected
+	_, err = concurrency.HandleTruncate(db, tm, "truncate truncate")
+	if !errors.Is(err, concurrency.ErrTableInUse) {
+		t.Errorf("expected truncating a locked table to fail with %q, but got %v", concurrency.ErrTableInUse, err)
+	}
+	if _, err := table.Find(1); err != nil {
+		t.Error("expected the rejected truncate to keep the table's entries:", err)
+	}
+	// Once the transaction commits, the table can be truncated
+	tm.Commit(tid)
+	if _, err := concurrency.HandleTruncate(db, tm, "truncate truncate"); err != nil {
+		t.Error(err)
+	}
+	if _, err := table.Find(1); err == nil {
+		t.Error("expected the truncated table to be empty")
+	}
+}
+
+// setupIsolation creates a database with a table holding key 1, and starts a transaction at the given isolation level
+func setupIsolation(t *testing.T, level concurrency.IsolationLevel) (*database.Database, *concurrency.TransactionManager, database.Index, uuid.UUID) {
+	t.Parallel()
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dbName)
+	})
+	table, err := db.CreateTable("isolation", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Insert(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+	tid := uuid.New()
+	tm.Begin(tid)
+	if err := tm.SetIsolation(tid, level); err != nil {
+		t.Fatal(err)
+	}
+	return db, tm, table, tid
+}
+
+func testTransactionIsolationSerializable(t *testing.T) {
+	db, tm, table, tid1 := setupIsolation(t, concurrency.SERIALIZABLE)
+	if _, err := concurrency.HandleFind(db, tm, "find 1 from isolation", tid1); err != nil {
+		t.Fatal(err)
+	}
+	// The read lock is held until the reader commits, so a writer blocks until then
+	tid2 := uuid.New()
+	tm.Begin(tid2)
+	defer tm.Commit(tid2)
+	result := lockAsync(tm, table, tid2, 1, concurrency.W_LOCK)
+	checkBlocked(t, result)
+	tm.Commit(tid1)
+	checkAcquired(t, result)
+}
+
+func testTransactionIsolationReadCommitted(t *testing.T) {
+	db, tm, table, tid1 := setupIsolation(t, concurrency.READ_COMMITTED)
+	defer tm.Commit(tid1)
+	if _, err := concurrency.HandleFind(db, tm, "find 1 from isolation", tid1); err != nil {
+		t.Fatal(err)
+	}
+	// The read lock is released right after the read, so a writer doesn't wait for the reader to commit
+	tid2 := uuid.New()
+	tm.Begin(tid2)
+	checkAcquired(t, lockAsync(tm, table, tid2, 1, concurrency.W_LOCK))
+	if err := concurrency.HandleUpdate(db, tm, "update isolation 1 2", tid2); err != nil {
+		t.Fatal(err)
+	}
+	tm.Commit(tid2)
+	output, err := concurrency.HandleFind(db, tm, "find 1 from isolation", tid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output != "found entry: (1, 2)\n" {
+		t.Errorf("expected the reader to see the committed update, but got %q", output)
+	}
+	// Write locks are still held until commit, even after reading the locked key
+	if err := tm.Lock(tid1, table, 1, concurrency.W_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := concurrency.HandleFind(db, tm, "find 1 from isolation", tid1); err != nil {
+		t.Fatal(err)
+	}
+	tid3 := uuid.New()
+	tm.Begin(tid3)
+	defer tm.Commit(tid3)
+	checkBlocked(t, lockAsync(tm, table, tid3, 1, concurrency.R_LOCK))
+}
+
+// Begins a transaction twice for the same client, checking that the second begin fails
+// and leaves the running transaction and its locks alone
+func testTransactionBeginStrict(t *testing.T) {
+	tm, index := setupTransaction(t)
+	tid := uuid.New()
+	tx, resumed, err := tm.BeginOrResume(tid)
+	if err != nil || resumed {
+		t.Fatalf("expected a new transaction, but got resumed %v and error %v", resumed, err)
+	}
+	defer tm.Commit(tid)
+	if err := tm.Lock(tid, index, 1, concurrency.W_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.Begin(tid); !errors.Is(err, concurrency.ErrTransactionAlreadyBegan) {
+		t.Errorf("expected begin to fail with %q, but got %v", concurrency.ErrTransactionAlreadyBegan, err)
+	}
+	if again, _, err := tm.BeginOrResume(tid); again != nil || !errors.Is(err, concurrency.ErrTransactionAlreadyBegan) {
+		t.Errorf("expected no transaction and %q, but got %v", concurrency.ErrTransactionAlreadyBegan, err)
+	}
+	if running, found := tm.GetTransaction(tid); !found || running != tx || len(running.GetResources()) != 1 {
+		t.Error("expected the failed begin to leave the running transaction and its lock alone")
+	}
+}
+
+// Begins a transaction twice for the same client on a resumable manager, checking that the second begin
+// returns the running transaction with its locks, and that a client without one still gets a new transaction
+func testTransactionBeginResumable(t *testing.T) {
+	tm, index := setupTransaction(t)
+	tm.SetResumable(true)
+	tid := uuid.New()
+	tx, resumed, err := tm.BeginOrResume(tid)
+	if err != nil || resumed {
+		t.Fatalf("expected a new transaction, but got resumed %v and error %v", resumed, err)
+	}
+	if err := tm.Lock(tid, index, 1, concurrency.W_LOCK); err != nil {
+		t.Fatal(err)
+	}
+	again, resumed, err := tm.BeginOrResume(tid)
+	if err != nil || !resumed || again != tx {
+		t.Fatalf("expected to resume the running transaction, but got resumed %v and error %v", resumed, err)
+	}
+	if len(again.GetResources()) != 1 {
+		t.Errorf("expected the resumed transaction to still hold its lock, but it holds %d", len(again.GetResources()))
+	}
+	if err := tm.Begin(tid); err != nil {
+		t.Errorf("expected begin to resume the running transaction, but got %v", err)
+	}
+	if err := tm.Commit(tid); err != nil {
+		t.Fatal(err)
+	}
+	fresh, resumed, err := tm.BeginOrResume(tid)
+	if err != nil || resumed || fresh == tx {
+		t.Errorf("expected a new transaction after committing, but got resumed %v and error %v", resumed, err)
+	}
+	tm.Commit(tid)
+}
+
+// Find and select through the transaction REPL should follow the output format set with .format
+func testTransactionOutputFormat(t *testing.T) {
+	t.Parallel()
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbName)
+	defer db.Close()
+	if _, err := db.CreateTable("format", database.BTreeIndexType); err != nil {
+		t.Fatal(err)
+	}
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+	r := concurrency.TransactionREPL(db, tm)
+	input := "transaction begin\ninsert 1 2 into format\n.format csv\nfind 1 from format\nselect from format\ntransaction commit\n"
+	output := new(bytes.Buffer)
+	r.Run(uuid.New(), "", strings.NewReader(input), output)
+	if count := strings.Count(output.String(), "key,value\n1,2\n"); count != 2 {
+		t.Errorf("expected find and select to both print csv, but got %q", output.String())
+	}
+}
@@ -0,0 +1,102 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestBufferSize(t *testing.T) {
+	t.Run("Repl", testBufferSizeRepl)
+	t.Run("NewTables", testBufferSizeNewTables)
+	t.Run("AdminOnly", testBufferSizeAdminOnly)
+}
+
+// The buffer size affects every client, so only the admin REPL should offer the command
+func testBufferSizeAdminOnly(t *testing.T) {
+	db := setupDatabase(t)
+	if _, ok := database.DatabaseRepl(db).GetCommands()["buffer_size"]; ok {
+		t.Error("Expected the database REPL to not have the buffer_size command")
+	}
+	if _, ok := database.AdminREPL(db).GetCommands()["buffer_size"]; !ok {
+		t.Error("Expected the admin REPL to have the buffer_size command")
+	}
+}
+
+// Shrinks and grows the buffer through the REPL command, checking the reported size,
+// each table's buffer size, and that every entry stays accessible
+func testBufferSizeRepl(t *testing.T) {
+	db := setupDatabase(t)
+	btreeTable, err := db.CreateTable("btree", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	hashTable, err := db.CreateTable("hash", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 2000; i++ {
+		utils.InsertEntry(t, btreeTable, i, i%utils.Salt)
+		utils.InsertEntry(t, hashTable, i, i%utils.Salt)
+	}
+
+	output, err := database.HandleBufferSize(db, "buffer_size")
+	if err != nil {
+		t.Fatal("Failed to get the buffer size:", err)
+	}
+	if expected := fmt.Sprintf("buffer size: %d pages\n", config.MaxPagesInBuffer); output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+
+	for _, size := range []int64{8, 2 * config.MaxPagesInBuffer} {
+		if _, err := database.HandleBufferSize(db, fmt.Sprintf("buffer_size %d", size)); err != nil {
+			t.Fatalf("Failed to resize the buffer to %d pages: %s", size, err)
+		}
+		output, err := database.HandleBufferSize(db, "buffer_size")
+		if err != nil {
+			t.Fatal("Failed to get the buffer size:", err)
+		}
+		if expected := fmt.Sprintf("buffer size: %d pages\n", size); output != expected {
+			t.Errorf("Expected output %q, but got %q", expected, output)
+		}
+		for _, table := range []database.Index{btreeTable, hashTable} {
+			if got := table.GetPager().GetBufferSize(); got != size {
+				t.Errorf("Expected table %s to have a buffer size of %d, but got %d", table.GetName(), size, got)
+			}
+			for i := int64(0); i < 2000; i++ {
+				utils.CheckFindEntry(t, table, i, i%utils.Salt)
+			}
+		}
+	}
+
+	if _, err := database.HandleBufferSize(db, "buffer_size 0"); err == nil {
+		t.Error("Expected resizing the buffer to 0 pages to fail")
+	}
+	if _, err := database.HandleBufferSize(db, "buffer_size many"); err == nil {
+		t.Error("Expected a malformed buffer_size command to fail")
+	}
+}
+
+// Changes the buffer size, checking that tables created afterwards use the new size
+func testBufferSizeNewTables(t *testing.T) {
+	db := setupDatabase(t)
+	if err := db.SetBufferSize(16); err != nil {
+		t.Fatal("Failed to resize the buffer:", err)
+	}
+	table, err := db.CreateTable("later", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if got := table.GetPager().GetBufferSize(); got != 16 {
+		t.Errorf("Expected the new table to have a buffer size of 16, but got %d", got)
+	}
+	for i := int64(0); i < 1000; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	for i := int64(0); i < 1000; i++ {
+		utils.CheckFindEntry(t, table, i, i%utils.Salt)
+	}
+}
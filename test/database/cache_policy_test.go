@@ -0,0 +1,97 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/database"
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+func TestCachePolicy(t *testing.T) {
+	t.Run("WriteThroughOnDisk", testCachePolicyWriteThroughOnDisk)
+	t.Run("Repl", testCachePolicyRepl)
+}
+
+// Copies a table's file while the table is still open, as a crash would leave it on disk
+func copyTableFile(t *testing.T, db *database.Database, name string) string {
+	data, err := os.ReadFile(filepath.Join(db.GetBasePath(), name))
+	if err != nil {
+		t.Fatal("Failed to read table file:", err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0666); err != nil {
+		t.Fatal("Failed to copy table file:", err)
+	}
+	return path
+}
+
+// Inserts into a write-through B+Tree without flushing or closing it, checking that every
+// entry can be found in a copy of the table's file
+func testCachePolicyWriteThroughOnDisk(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("critical", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if err := db.SetCachePolicy("critical", pager.WRITE_THROUGH); err != nil {
+		t.Fatal("Failed to set the cache policy:", err)
+	}
+	const numEntries = 1000
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	for i := int64(0); i < numEntries; i += 2 {
+		if err := table.Update(i, -i); err != nil {
+			t.Fatal("Failed to update entry:", err)
+		}
+	}
+
+	copied, err := btree.OpenIndex(copyTableFile(t, db, "critical"))
+	if err != nil {
+		t.Fatal("Failed to open the copied table:", err)
+	}
+	defer copied.Close()
+	for i := int64(0); i < numEntries; i++ {
+		if i%2 == 0 {
+			utils.CheckFindEntry(t, copied, i, -i)
+		} else {
+			utils.CheckFindEntry(t, copied, i, i%utils.Salt)
+		}
+	}
+}
+
+// Gets and sets a table's cache policy through the REPL command, checking that it survives conversion
+func testCachePolicyRepl(t *testing.T) {
+	db := setupDatabase(t)
+	if _, err := db.CreateTable("t", database.BTreeIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	checkPolicy := func(expected pager.CachePolicy) {
+		output, err := database.HandleCachePolicy(db, "cache_policy t")
+		if err != nil {
+			t.Fatal("Failed to get the cache policy:", err)
+		}
+		if output != "cache policy: "+expected.String()+"\n" {
+			t.Errorf("Expected the cache policy to be %s, but got %q", expected, output)
+		}
+	}
+	checkPolicy(pager.WRITE_BACK)
+	if _, err := database.HandleCachePolicy(db, "cache_policy t write_through_sync"); err != nil {
+		t.Fatal("Failed to set the cache policy:", err)
+	}
+	checkPolicy(pager.WRITE_THROUGH_SYNC)
+	if err := db.ConvertTable("t", database.HashIndexType); err != nil {
+		t.Fatal("Failed to convert table:", err)
+	}
+	checkPolicy(pager.WRITE_THROUGH_SYNC)
+
+	for _, payload := range []string{"cache_policy", "cache_policy t write_around", "cache_policy missing write_through"} {
+		if _, err := database.HandleCachePolicy(db, payload); err == nil {
+			t.Errorf("Expected %q to fail", payload)
+		}
+	}
+}
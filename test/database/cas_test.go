@@ -0,0 +1,187 @@
+package database_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
+	"dinodb/pkg/hash"
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+// =====================================================================
+// HELPERS
+// =====================================================================
+
+// checkVersion verifies that the entry with the given key has the given value and version
+func checkVersion(t *testing.T, index database.Index, key int64, value int64, version int64) {
+	e, err := index.Find(key)
+	if err != nil {
+		t.Fatalf("Failed to find key %d: %v", key, err)
+	}
+	if e.Value != value || e.Version != version {
+		t.Errorf("Expected key %d to have value %d at version %d, but got value %d at version %d",
+			key, value, version, e.Value, e.Version)
+	}
+}
+
+// =====================================================================
+// TESTS
+// =====================================================================
+
+func TestCompareAndSwap(t *testing.T) {
+	for name, indexType := range map[string]database.IndexType{
+		"BTree": database.BTreeIndexType,
+		"Hash":  database.HashIndexType,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("Success", func(t *testing.T) { testCASSuccess(t, indexType) })
+			t.Run("Conflict", func(t *testing.T) { testCASConflict(t, indexType) })
+			t.Run("MissingKey", func(t *testing.T) { testCASMissingKey(t, indexType) })
+			t.Run("Splits", func(t *testing.T) { testCASSplits(t, indexType) })
+		})
+	}
+	t.Run("Repl", testCASRepl)
+	t.Run("UnversionedFile", testCASUnversionedFile)
+}
+
+// Swaps an entry's value repeatedly, checking that each swap returns and stores the next version
+func testCASSuccess(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("cas", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 7, 70)
+	checkVersion(t, table, 7, 70, 0)
+	for version := int64(0); version < 5; version++ {
+		newVersion, err := table.CompareAndSwap(7, version, 100+version)
+		if err != nil {
+			t.Fatalf("Failed to swap at version %d: %v", version, err)
+		}
+		if newVersion != version+1 {
+			t.Fatalf("Expected the new version to be %d, but got %d", version+1, newVersion)
+		}
+		checkVersion(t, table, 7, 100+version, version+1)
+	}
+	// Plain updates increment the version too
+	if err := table.Update(7, 1); err != nil {
+		t.Fatal("Failed to update:", err)
+	}
+	checkVersion(t, table, 7, 1, 6)
+}
+
+// Swaps an entry with a stale version, checking that it fails with a conflict and leaves the entry alone
+func testCASConflict(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("cas", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 7, 70)
+	if _, err := table.CompareAndSwap(7, 0, 71); err != nil {
+		t.Fatal("Failed to swap:", err)
+	}
+	for _, stale := range []int64{0, 2, -1} {
+		if _, err := table.CompareAndSwap(7, stale, 99); !errors.Is(err, entry.ErrVersionConflict) {
+			t.Errorf("Expected swapping at version %d to fail with %q, but got %v", stale, entry.ErrVersionConflict, err)
+		}
+	}
+	checkVersion(t, table, 7, 71, 1)
+}
+
+// Swaps a key that isn't in the table, checking that it fails without inserting the key
+func testCASMissingKey(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("cas", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 7, 70)
+	_, err = table.CompareAndSwap(8, 0, 80)
+	if err == nil {
+		t.Fatal("Expected swapping a missing key to fail")
+	}
+	if errors.Is(err, entry.ErrVersionConflict) {
+		t.Errorf("Expected swapping a missing key not to be a version conflict, but got %v", err)
+	}
+	if _, err := table.Find(8); err == nil {
+		t.Error("Expected swapping a missing key not to insert it")
+	}
+}
+
+// Updates entries and then inserts enough others to split them around, checking that versions survive
+func testCASSplits(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("cas", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 100; i++ {
+		utils.InsertEntry(t, table, i*100, i)
+		for j := int64(0); j < i%4; j++ {
+			if err := table.Update(i*100, i); err != nil {
+				t.Fatal("Failed to update:", err)
+			}
+		}
+	}
+	for i := int64(0); i < 5000; i++ {
+		if i%100 != 0 {
+			utils.InsertEntry(t, table, i, i)
+		}
+	}
+	for i := int64(0); i < 100; i++ {
+		checkVersion(t, table, i*100, i, i%4)
+	}
+}
+
+// Runs the cas command, checking its output and errors
+func testCASRepl(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("cas", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 7, 70)
+	output, err := database.HandleCompareAndSwap(db, "cas cas 7 0 71")
+	if err != nil {
+		t.Fatal("Failed to run cas:", err)
+	}
+	if !strings.Contains(output, "version 1") {
+		t.Errorf("Expected the output to report version 1, but got %q", output)
+	}
+	if _, err := database.HandleCompareAndSwap(db, "cas cas 7 0 72"); !errors.Is(err, entry.ErrVersionConflict) {
+		t.Errorf("Expected a stale cas to fail with %q, but got %v", entry.ErrVersionConflict, err)
+	}
+	if _, err := database.HandleCompareAndSwap(db, "cas cas 7 1"); err == nil {
+		t.Error("Expected cas without a value to fail")
+	}
+}
+
+// Opens files with pages that weren't marked as storing versioned entries, checking that they are rejected
+func testCASUnversionedFile(t *testing.T) {
+	t.Parallel()
+	dbName := utils.GetTempDbFile(t)
+	p, err := pager.New(dbName)
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	page, err := p.GetNewPage()
+	if err != nil {
+		t.Fatal("Failed to get new page:", err)
+	}
+	p.PutPage(page)
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	if _, err := btree.OpenIndex(dbName); !errors.Is(err, pager.ErrMissingFeature) {
+		t.Errorf("Expected opening an unversioned B+Tree to fail with %q, but got %v", pager.ErrMissingFeature, err)
+	}
+	if _, err := hash.OpenTable(dbName); !errors.Is(err, pager.ErrMissingFeature) {
+		t.Errorf("Expected opening an unversioned hash table to fail with %q, but got %v", pager.ErrMissingFeature, err)
+	}
+}
@@ -0,0 +1,125 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
+	"dinodb/test/utils"
+)
+
+func TestSelectChunks(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(string(indexType), func(t *testing.T) {
+			t.Run("MatchesSelect", func(t *testing.T) { testSelectChunksMatchesSelect(t, indexType) })
+			t.Run("Empty", func(t *testing.T) { testSelectChunksEmpty(t, indexType) })
+			t.Run("StopsOnError", func(t *testing.T) { testSelectChunksStopsOnError(t, indexType) })
+		})
+	}
+}
+
+// Selects a table in chunks of various sizes, checking that every chunk is
+// full except the last, and that the chunks together match a full select
+func testSelectChunksMatchesSelect(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("chunks", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	entries, answerKey := utils.GenerateRandomKeyValuePairs(1000)
+	for _, e := range entries {
+		utils.InsertEntry(t, table, e.Key, e.Val)
+	}
+	expected, err := table.Select()
+	if err != nil {
+		t.Fatal("Failed to select table:", err)
+	}
+	// Chunk sizes that divide the table evenly, unevenly, and that exceed it
+	for _, chunkSize := range []int{1, 7, 250, 1000, 5000} {
+		chunks := make([][]entry.Entry, 0)
+		err := table.SelectChunks(chunkSize, func(chunk []entry.Entry) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to select chunks of size %d: %v", chunkSize, err)
+		}
+		seen := make(map[int64]bool)
+		var selected []entry.Entry
+		for i, chunk := range chunks {
+			if len(chunk) == 0 || len(chunk) > chunkSize || (i < len(chunks)-1 && len(chunk) != chunkSize) {
+				t.Errorf("Chunk %d of size %d has %d entries", i, chunkSize, len(chunk))
+			}
+			for _, e := range chunk {
+				if seen[e.Key] {
+					t.Errorf("Key %d appeared in more than one chunk of size %d", e.Key, chunkSize)
+				}
+				seen[e.Key] = true
+				utils.CheckEntry(t, e, e.Key, answerKey[e.Key])
+			}
+			selected = append(selected, chunk...)
+		}
+		if len(selected) != len(expected) {
+			t.Fatalf("Expected %d entries in chunks of size %d, but found %d", len(expected), chunkSize, len(selected))
+		}
+		// Chunks should come back in the same order that Select returns them
+		for i := range expected {
+			if selected[i] != expected[i] {
+				t.Fatalf("Entry %d differs between chunks of size %d and select: %v vs %v",
+					i, chunkSize, selected[i], expected[i])
+			}
+		}
+	}
+}
+
+// Selects an empty table in chunks, checking that the callback isn't called
+func testSelectChunksEmpty(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("chunks", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	err = table.SelectChunks(10, func(chunk []entry.Entry) error {
+		t.Errorf("Expected no chunks from an empty table, but got %v", chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Failed to select chunks:", err)
+	}
+	if err := table.SelectChunks(0, func([]entry.Entry) error { return nil }); err == nil {
+		t.Error("Expected a non-positive chunk size to fail")
+	}
+}
+
+// Returns an error from the callback, checking that it is passed back and that
+// the table is still usable afterwards (i.e. no pages were left locked)
+func testSelectChunksStopsOnError(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("chunks", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 1000; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+	stop := errors.New("stop")
+	numCalls := 0
+	err = table.SelectChunks(100, func([]entry.Entry) error {
+		numCalls++
+		if numCalls == 3 {
+			return stop
+		}
+		// Writing between chunks shouldn't block
+		return table.Update(int64(numCalls), -1)
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the callback's error, but got %v", err)
+	}
+	if numCalls != 3 {
+		t.Errorf("Expected selecting to stop after 3 chunks, but it made %d calls", numCalls)
+	}
+	utils.InsertEntry(t, table, 1000, 1000)
+	utils.CheckFindEntry(t, table, 1, -1)
+	utils.CheckFindEntry(t, table, 1000, 1000)
+}
@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+func TestCloseDuringOperations(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(indexType.String(), func(t *testing.T) { testCloseDuringOperations(t, indexType) })
+	}
+}
+
+// Closes a database while goroutines are finding and inserting, checking that every operation
+// either succeeds or fails cleanly with ErrClosing, and that the committed entries survive a reopen
+func testCloseDuringOperations(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	numEntries := int64(1000)
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+
+	numWorkers := int64(8)
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	inserted := make([]int64, 0)
+	errs := make(chan error, numWorkers)
+	started := make(chan struct{}, numWorkers)
+	for w := int64(0); w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started <- struct{}{}
+			for i := int64(0); ; i++ {
+				if _, err := table.Find(i % numEntries); err != nil {
+					errs <- err
+					return
+				}
+				key := numEntries + i*numWorkers + w
+				if err := table.Insert(key, key); err != nil {
+					errs <- err
+					return
+				}
+				mtx.Lock()
+				inserted = append(inserted, key)
+				mtx.Unlock()
+			}
+		}()
+	}
+	for w := int64(0); w < numWorkers; w++ {
+		<-started
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database during operations:", err)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if !errors.Is(err, pager.ErrClosing) {
+			t.Errorf("Expected operations to fail with ErrClosing, but got %v", err)
+		}
+	}
+
+	reopened, err := database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	defer reopened.Close()
+	table, err = reopened.GetTable("t")
+	if err != nil {
+		t.Fatal("Failed to reopen table:", err)
+	}
+	for _, key := range inserted {
+		utils.CheckFindEntry(t, table, key, key)
+	}
+	for i := int64(0); i < numEntries; i++ {
+		utils.CheckFindEntry(t, table, i, i)
+	}
+}
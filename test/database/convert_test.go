@@ -0,0 +1,203 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestConvertTable(t *testing.T) {
+	t.Run("HashToBTree", func(t *testing.T) { testConvertTable(t, database.HashIndexType, database.BTreeIndexType) })
+	t.Run("BTreeToHash", func(t *testing.T) { testConvertTable(t, database.BTreeIndexType, database.HashIndexType) })
+	t.Run("SameType", testConvertTableSameType)
+	t.Run("Repl", testConvertTableRepl)
+	t.Run("Uncommitted", testConvertTableUncommitted)
+	t.Run("Committed", testConvertTableCommitted)
+}
+
+// checkIndexType errors the test if the named table isn't of the expected type
+func checkIndexType(t *testing.T, db *database.Database, name string, expected database.IndexType) database.Index {
+	table, err := db.GetTable(name)
+	if err != nil {
+		t.Fatal("Failed to get table:", err)
+	}
+	indexType, err := database.GetIndexType(table)
+	if err != nil {
+		t.Fatal("Failed to get index type:", err)
+	}
+	if indexType != expected {
+		t.Fatalf("Expected a %s table, but found a %s table", expected, indexType)
+	}
+	return table
+}
+
+// Converts a table, checking that all of its entries can be found and changed afterwards,
+// including after reopening the database
+func testConvertTable(t *testing.T, fromType database.IndexType, toType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("convert", fromType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	entries, answerKey := utils.GenerateRandomKeyValuePairs(3000)
+	for _, e := range entries {
+		utils.InsertEntry(t, table, e.Key, e.Val)
+	}
+	if err := db.ConvertTable("convert", toType); err != nil {
+		t.Fatal("Failed to convert table:", err)
+	}
+	table = checkIndexType(t, db, "convert", toType)
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, table, k, v)
+	}
+	// The converted table should be fully usable
+	if err := table.Insert(-1, -1); err != nil {
+		t.Error("Failed to insert into converted table:", err)
+	}
+	if err := table.Delete(entries[0].Key); err != nil {
+		t.Error("Failed to delete from converted table:", err)
+	}
+	delete(answerKey, entries[0].Key)
+	answerKey[-1] = -1
+	if btreeTable, ok := table.(*btree.BTreeIndex); ok {
+		selected, err := btreeTable.SelectRange(-1, 0)
+		if err != nil {
+			t.Error("Failed to select range from converted table:", err)
+		} else if len(selected) != 1 {
+			t.Errorf("Expected 1 entry in range, but found %v", selected)
+		}
+	}
+	// The new type should persist across reopening the database
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+	db, err = database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	defer db.Close()
+	table = checkIndexType(t, db, "convert", toType)
+	count, _, err := database.Digest(table)
+	if err != nil {
+		t.Fatal("Failed to digest table:", err)
+	}
+	if count != int64(len(answerKey)) {
+		t.Errorf("Expected %d entries after reopening, but found %d", len(answerKey), count)
+	}
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, table, k, v)
+	}
+}
+
+// Converting a table to its current type should fail and leave it untouched
+func testConvertTableSameType(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("convert", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 1)
+	if err := db.ConvertTable("convert", database.BTreeIndexType); err == nil {
+		t.Error("Expected converting a table to its own type to fail")
+	}
+	utils.CheckFindEntry(t, table, 1, 1)
+	if err := db.ConvertTable("missing", database.HashIndexType); err == nil {
+		t.Error("Expected converting a missing table to fail")
+	}
+}
+
+// Converts a table through the repl, checking usage errors
+func testConvertTableRepl(t *testing.T) {
+	db := setupDatabase(t)
+	if _, err := db.CreateTable("convert", database.HashIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if _, err := database.HandleConvert(db, "convert convert to heap"); err == nil {
+		t.Error("Expected a usage error")
+	}
+	output, err := database.HandleConvert(db, "convert convert to btree")
+	if err != nil {
+		t.Fatal("Failed to convert table:", err)
+	}
+	if output != "table convert converted to btree.\n" {
+		t.Errorf("Unexpected output %q", output)
+	}
+	checkIndexType(t, db, "convert", database.BTreeIndexType)
+}
+
+// A conversion that crashed before writing its commit file should leave the table as it was,
+// and its staged file should be removed when the table is next opened
+func testConvertTableUncommitted(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("convert", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 1)
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+	staged := filepath.Join(db.GetBasePath(), "convert.convert")
+	if err := os.WriteFile(staged, []byte("half-built"), 0666); err != nil {
+		t.Fatal("Failed to stage conversion:", err)
+	}
+	db, err = database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	defer db.Close()
+	table = checkIndexType(t, db, "convert", database.BTreeIndexType)
+	utils.CheckFindEntry(t, table, 1, 1)
+	if _, err := os.Stat(staged); !os.IsNotExist(err) {
+		t.Error("Expected the staged file to be removed, but found error", err)
+	}
+}
+
+// A conversion that crashed after writing its commit file should be finished when the table is next opened
+func testConvertTableCommitted(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("convert", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 1)
+	// Build the converted table's files as another table
+	newTable, err := db.CreateTable("staged", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, newTable, 2, 2)
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+	// Stage them as a committed conversion that crashed before swapping in the new files
+	path := filepath.Join(db.GetBasePath(), "convert")
+	stagedPath := filepath.Join(db.GetBasePath(), "staged")
+	for src, dst := range map[string]string{stagedPath: path + ".convert", stagedPath + ".meta": path + ".convert.meta"} {
+		if err := os.Rename(src, dst); err != nil {
+			t.Fatal("Failed to stage conversion:", err)
+		}
+	}
+	if err := os.WriteFile(path+".convert.commit", []byte(database.HashIndexType.String()), 0666); err != nil {
+		t.Fatal("Failed to commit conversion:", err)
+	}
+	db, err = database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	defer db.Close()
+	table = checkIndexType(t, db, "convert", database.HashIndexType)
+	utils.CheckFindEntry(t, table, 2, 2)
+	if _, err := table.Find(1); err == nil {
+		t.Error("Expected the old table's entry to be gone")
+	}
+	for _, suffix := range []string{".convert", ".convert.meta", ".convert.commit"} {
+		if _, err := os.Stat(path + suffix); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, but found error %v", suffix, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package database_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestCount(t *testing.T) {
+	t.Run("Entries", testCountEntries)
+	t.Run("Repl", testCountRepl)
+}
+
+// Counts tables of both index types while empty, after inserts, and after deletes,
+// checking each count against the number of entries Select returns
+func testCountEntries(t *testing.T) {
+	const numEntries = 5000
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(string(indexType), func(t *testing.T) {
+			db := setupDatabase(t)
+			table, err := db.CreateTable("counted", indexType)
+			if err != nil {
+				t.Fatal("Failed to create table:", err)
+			}
+			checkCount := func(expected int64) {
+				count, err := table.Count()
+				if err != nil {
+					t.Fatal("Failed to count entries:", err)
+				}
+				entries, err := table.Select()
+				if err != nil {
+					t.Fatal("Failed to select entries:", err)
+				}
+				if count != expected || int64(len(entries)) != expected {
+					t.Errorf("Expected %d entries, but counted %d and selected %d", expected, count, len(entries))
+				}
+			}
+			checkCount(0)
+			for i := int64(0); i < numEntries; i++ {
+				utils.InsertEntry(t, table, i, i%utils.Salt)
+			}
+			checkCount(numEntries)
+			for i := int64(0); i < numEntries; i += 3 {
+				if err := table.Delete(i); err != nil {
+					t.Fatal("Failed to delete entry:", err)
+				}
+			}
+			checkCount(numEntries - (numEntries+2)/3)
+		})
+	}
+}
+
+// Counts a table through the REPL command, checking the printed count and the usage check
+func testCountRepl(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("counted", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 100; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	output, err := database.HandleCount(db, "count from counted")
+	if err != nil {
+		t.Fatal("Failed to count through the REPL:", err)
+	}
+	if output != "100\n" {
+		t.Errorf("Expected output %q, but got %q", "100\n", output)
+	}
+	for _, payload := range []string{"count counted", "count from missing"} {
+		if _, err := database.HandleCount(db, payload); err == nil {
+			t.Errorf("%q: expected an error", payload)
+		}
+	}
+}
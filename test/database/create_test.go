@@ -0,0 +1,145 @@
+package database_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+)
+
+func TestCreateTableNames(t *testing.T) {
+	t.Run("Reserved", testCreateTableReserved)
+	t.Run("Allowed", testCreateTableAllowed)
+}
+
+func TestCreateTableConcurrent(t *testing.T) {
+	t.Run("Create", testCreateTableConcurrentCreate)
+	t.Run("Get", testCreateTableConcurrentGet)
+}
+
+// Tries to create tables with each reserved name, in several cases, checking they are all rejected
+func testCreateTableReserved(t *testing.T) {
+	db := setupDatabase(t)
+	for _, reserved := range config.ReservedTableNames {
+		for _, name := range []string{reserved, strings.ToUpper(reserved)} {
+			for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+				_, err := db.CreateTable(name, indexType)
+				if !errors.Is(err, database.ErrReservedTableName) {
+					t.Errorf("Expected creating %s table %q to fail with %q, but got %v",
+						indexType, name, database.ErrReservedTableName, err)
+				}
+			}
+		}
+	}
+	if len(db.GetTables()) != 0 {
+		t.Errorf("Expected no tables to be created, but found %v", db.GetTables())
+	}
+	if _, err := database.HandleCreateTable(db, "create btree table catalog"); !errors.Is(err, database.ErrReservedTableName) {
+		t.Errorf("Expected the create command to fail with %q, but got %v", database.ErrReservedTableName, err)
+	}
+}
+
+// Creates tables whose names contain or resemble reserved names, checking they are allowed
+func testCreateTableAllowed(t *testing.T) {
+	db := setupDatabase(t)
+	for _, name := range []string{"users", "catalogs", "dbs", "my_catalog", "recovery2"} {
+		if _, err := db.CreateTable(name, database.BTreeIndexType); err != nil {
+			t.Errorf("Failed to create table %q: %v", name, err)
+		}
+	}
+}
+
+// Creates the same table from many goroutines at once, checking that exactly one creation succeeds
+func testCreateTableConcurrentCreate(t *testing.T) {
+	db := setupDatabase(t)
+	const numWorkers = 32
+	var wg sync.WaitGroup
+	indexes := make([]database.Index, numWorkers)
+	errs := make([]error, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			indexes[i], errs[i] = db.CreateTable("shared", database.BTreeIndexType)
+		}(i)
+	}
+	wg.Wait()
+	var created database.Index
+	for i, err := range errs {
+		if err == nil {
+			if created != nil {
+				t.Fatal("Expected the table to be created once, but it was created more than once")
+			}
+			created = indexes[i]
+		} else if err.Error() != "table already exists" {
+			t.Errorf("Expected a failed creation to report that the table exists, but got %v", err)
+		}
+	}
+	if created == nil {
+		t.Fatal("Expected the table to be created once, but it was never created")
+	}
+	if tables := db.GetTables(); len(tables) != 1 || tables["shared"] != created {
+		t.Errorf("Expected the database to hold only the created table, but found %v", tables)
+	}
+}
+
+// Opens the same on-disk table from many goroutines at once, checking that they all share one index
+func testCreateTableConcurrentGet(t *testing.T) {
+	db := setupDatabase(t)
+	if _, err := db.CreateTable("shared", database.HashIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	// Reopen the database so the table has to be opened from disk
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+	db, err := database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	defer db.Close()
+	const numWorkers = 32
+	var wg sync.WaitGroup
+	indexes := make([]database.Index, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			index, err := db.GetTable("shared")
+			if err != nil {
+				t.Errorf("Failed to get table: %v", err)
+			}
+			indexes[i] = index
+		}(i)
+	}
+	wg.Wait()
+	for _, index := range indexes {
+		if index != indexes[0] {
+			t.Fatal("Expected every caller to share the same opened index")
+		}
+	}
+}
+
+// Parses valid and invalid index type names, checking that valid ones round trip through String
+func TestParseIndexType(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		parsed, err := database.ParseIndexType(indexType.String())
+		if err != nil {
+			t.Errorf("Failed to parse index type %q: %v", indexType, err)
+		} else if parsed != indexType {
+			t.Errorf("Expected %q to parse as %q, but got %q", indexType.String(), indexType, parsed)
+		}
+	}
+	for _, name := range []string{"", "heap", "BTREE", "b+tree", "hash "} {
+		if _, err := database.ParseIndexType(name); !errors.Is(err, database.ErrUnknownIndexType) {
+			t.Errorf("Expected parsing %q to fail with %q, but got %v", name, database.ErrUnknownIndexType, err)
+		}
+	}
+	db := setupDatabase(t)
+	if _, err := database.HandleCreateTable(db, "create heap table t"); !errors.Is(err, database.ErrUnknownIndexType) {
+		t.Errorf("Expected creating a heap table to fail with %q, but got %v", database.ErrUnknownIndexType, err)
+	}
+}
@@ -0,0 +1,198 @@
+package database_test
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
+	"dinodb/test/utils"
+
+	"github.com/google/uuid"
+)
+
+// =====================================================================
+// HELPERS
+// =====================================================================
+
+// setupCursorTable creates a table of the given type holding numEntries entries, returning it and its answer key
+func setupCursorTable(t *testing.T, indexType database.IndexType, numEntries int64) (*database.Database, database.Index, map[int64]int64) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("paged", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	answerKey := make(map[int64]int64)
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i*3)
+		answerKey[i] = i * 3
+	}
+	return db, table, answerKey
+}
+
+// =====================================================================
+// TESTS
+// =====================================================================
+
+func TestCursorSessions(t *testing.T) {
+	t.Run("FetchBTree", func(t *testing.T) { testCursorFetch(t, database.BTreeIndexType) })
+	t.Run("FetchHash", func(t *testing.T) { testCursorFetch(t, database.HashIndexType) })
+	t.Run("Close", testCursorClose)
+	t.Run("Disconnect", testCursorDisconnect)
+	t.Run("WriteBetweenFetches", testCursorWriteBetweenFetches)
+}
+
+// Fetches a table through a cursor in chunks until it is exhausted,
+// checking that every entry is returned exactly once
+func testCursorFetch(t *testing.T, indexType database.IndexType) {
+	_, table, answerKey := setupCursorTable(t, indexType, 1000)
+	sessions := database.NewCursorSessions()
+	clientId := uuid.New()
+	if _, err := sessions.Open(clientId, "c", table); err != nil {
+		t.Fatal("Failed to open cursor:", err)
+	}
+	var fetched []entry.Entry
+	for {
+		entries, err := sessions.Fetch(clientId, "c", 64)
+		if err != nil {
+			t.Fatal("Failed to fetch from cursor:", err)
+		}
+		fetched = append(fetched, entries...)
+		if len(entries) < 64 {
+			break
+		}
+	}
+	if entries, err := sessions.Fetch(clientId, "c", 10); err != nil || len(entries) != 0 {
+		t.Errorf("Expected an exhausted cursor to fetch nothing, but got %v, %v", entries, err)
+	}
+	if len(fetched) != len(answerKey) {
+		t.Fatalf("Expected to fetch %d entries, but fetched %d", len(answerKey), len(fetched))
+	}
+	if indexType == database.BTreeIndexType && !sort.SliceIsSorted(fetched, func(i, j int) bool { return fetched[i].Key < fetched[j].Key }) {
+		t.Error("Expected a B+Tree cursor to fetch entries in key order")
+	}
+	seen := make(map[int64]bool)
+	for _, e := range fetched {
+		if seen[e.Key] {
+			t.Errorf("Expected key %d to be fetched once, but it was fetched again", e.Key)
+		}
+		seen[e.Key] = true
+		utils.CheckEntry(t, e, e.Key, answerKey[e.Key])
+	}
+	if err := sessions.Close(clientId, "c"); err != nil {
+		t.Error("Failed to close cursor:", err)
+	}
+}
+
+// Opens and closes cursors, checking that closing releases the cursor's page
+// and that closed or unknown cursors can't be used
+func testCursorClose(t *testing.T) {
+	_, table, _ := setupCursorTable(t, database.BTreeIndexType, 500)
+	sessions := database.NewCursorSessions()
+	clientId := uuid.New()
+	if _, err := sessions.Open(clientId, "c", table); err != nil {
+		t.Fatal("Failed to open cursor:", err)
+	}
+	if _, err := sessions.Open(clientId, "c", table); !errors.Is(err, database.ErrCursorExists) {
+		t.Errorf("Expected reopening an open cursor to fail with %q, but got %v", database.ErrCursorExists, err)
+	}
+	if _, err := sessions.Fetch(uuid.New(), "c", 1); !errors.Is(err, database.ErrCursorNotFound) {
+		t.Errorf("Expected another client's fetch to fail with %q, but got %v", database.ErrCursorNotFound, err)
+	}
+	if _, err := sessions.Fetch(clientId, "c", 100); err != nil {
+		t.Fatal("Failed to fetch from cursor:", err)
+	}
+	if err := sessions.Close(clientId, "c"); err != nil {
+		t.Fatal("Failed to close cursor:", err)
+	}
+	if pinned := table.GetPager().GetNumPinned(); pinned != 0 {
+		t.Errorf("Expected closing the cursor to unpin its page, but %d pages are pinned", pinned)
+	}
+	if _, err := sessions.Fetch(clientId, "c", 1); !errors.Is(err, database.ErrCursorNotFound) {
+		t.Errorf("Expected fetching a closed cursor to fail with %q, but got %v", database.ErrCursorNotFound, err)
+	}
+	if err := sessions.Close(clientId, "c"); !errors.Is(err, database.ErrCursorNotFound) {
+		t.Errorf("Expected closing a closed cursor to fail with %q, but got %v", database.ErrCursorNotFound, err)
+	}
+}
+
+// Opens cursors through the REPL and ends the session without closing them,
+// checking that they are cleaned up when the client disconnects
+func testCursorDisconnect(t *testing.T) {
+	db, table, _ := setupCursorTable(t, database.BTreeIndexType, 500)
+	input := strings.Join([]string{
+		"cursor open a on paged",
+		"cursor open b on paged",
+		"cursor fetch a 3",
+		"cursor fetch a 2",
+	}, "\n") + "\n"
+	output := &bytes.Buffer{}
+	database.DatabaseRepl(db).Run(uuid.New(), "", strings.NewReader(input), output)
+	expected := "cursor a opened on paged\ncursor b opened on paged\n(0, 0)\n(1, 3)\n(2, 6)\n(3, 9)\n(4, 12)\n"
+	if !strings.Contains(output.String(), expected) {
+		t.Errorf("Expected output to contain %q, but got %q", expected, output.String())
+	}
+	if pinned := table.GetPager().GetNumPinned(); pinned != 0 {
+		t.Errorf("Expected disconnecting to close the client's cursors, but %d pages are pinned", pinned)
+	}
+}
+
+// Writes to a B+Tree between fetches from a cursor on it, checking that the cursor holds no page in between,
+// and that it goes on from its last key to fetch the entries as they are then
+func testCursorWriteBetweenFetches(t *testing.T) {
+	_, table, _ := setupCursorTable(t, database.BTreeIndexType, 500)
+	sessions := database.NewCursorSessions()
+	clientId := uuid.New()
+	if _, err := sessions.Open(clientId, "c", table); err != nil {
+		t.Fatal("Failed to open cursor:", err)
+	}
+	defer sessions.CloseAll(clientId)
+	first, err := sessions.Fetch(clientId, "c", 100)
+	if err != nil {
+		t.Fatal("Failed to fetch from cursor:", err)
+	}
+	if pinned := table.GetPager().GetNumPinned(); pinned != 0 {
+		t.Errorf("Expected a cursor to hold no page between fetches, but %d pages are pinned", pinned)
+	}
+	// Delete the last key fetched and one ahead of the cursor, and insert one behind it and one ahead.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, key := range []int64{99, 150} {
+			if err := table.Delete(key); err != nil {
+				t.Errorf("Failed to delete key %d: %v", key, err)
+			}
+		}
+		utils.InsertEntry(t, table, -1, 7)
+		utils.InsertEntry(t, table, 1000, 7)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Writing to a table blocked on a cursor open on it")
+	}
+	rest, err := sessions.Fetch(clientId, "c", 1000)
+	if err != nil {
+		t.Fatal("Failed to fetch from cursor:", err)
+	}
+	expected := make([]int64, 0)
+	for i := int64(0); i < 500; i++ {
+		if i != 150 {
+			expected = append(expected, i)
+		}
+	}
+	expected = append(expected, 1000)
+	fetched := append(first, rest...)
+	if len(fetched) != len(expected) {
+		t.Fatalf("Expected to fetch %d entries, but fetched %d", len(expected), len(fetched))
+	}
+	for i, e := range fetched {
+		if e.Key != expected[i] {
+			t.Fatalf("Expected entry %d to have key %d, but it has key %d", i, expected[i], e.Key)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package database_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+func TestTableMetadata(t *testing.T) {
+	t.Run("BTree", func(t *testing.T) { testTableMetadata(t, database.BTreeIndexType) })
+	t.Run("Hash", func(t *testing.T) { testTableMetadata(t, database.HashIndexType) })
+	t.Run("Describe", testDescribe)
+}
+
+// Creates a table, checking its metadata before and after reopening it
+func testTableMetadata(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	before := time.Now()
+	table, err := db.CreateTable("metadata", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	after := time.Now()
+	numEntries := int64(500)
+	for i := range numEntries {
+		utils.InsertEntry(t, table, i, i)
+	}
+
+	metadata, err := table.Metadata()
+	if err != nil {
+		t.Fatal("Failed to get metadata:", err)
+	}
+	if metadata.IndexType != indexType.String() {
+		t.Errorf("Expected index type %s, but got %s", indexType, metadata.IndexType)
+	}
+	if metadata.CreatedAt.Before(before) || metadata.CreatedAt.After(after) {
+		t.Errorf("Expected the table to be created between %v and %v, but it was created at %v", before, after, metadata.CreatedAt)
+	}
+	if metadata.FormatVersion != pager.SuperblockVersion {
+		t.Errorf("Expected format version %d, but got %d", pager.SuperblockVersion, metadata.FormatVersion)
+	}
+	if metadata.Features&pager.VERSIONED_ENTRIES_FLAG == 0 {
+		t.Errorf("Expected the table to use versioned entries, but its features are %b", metadata.Features)
+	}
+	if metadata.NumEntries != numEntries {
+		t.Errorf("Expected %d entries, but got %d", numEntries, metadata.NumEntries)
+	}
+	if metadata.NumPages <= 1 {
+		t.Errorf("Expected %d entries to take more than one page, but got %d pages", numEntries, metadata.NumPages)
+	}
+
+	db, table = reopenTable(t, db, "metadata")
+	defer db.Close()
+	reopened, err := table.Metadata()
+	if err != nil {
+		t.Fatal("Failed to get metadata after reopening:", err)
+	}
+	if !reopened.CreatedAt.Equal(metadata.CreatedAt) {
+		t.Errorf("Expected the creation time %v to persist, but got %v", metadata.CreatedAt, reopened.CreatedAt)
+	}
+	reopened.CreatedAt = metadata.CreatedAt
+	if reopened != metadata {
+		t.Errorf("Expected metadata %+v after reopening, but got %+v", metadata, reopened)
+	}
+}
+
+// Runs describe through the database REPL handler, checking its output
+func testDescribe(t *testing.T) {
+	db := setupDatabase(t)
+	if _, err := database.HandleCreateTable(db, "create hash table described"); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	table, err := db.GetTable("described")
+	if err != nil {
+		t.Fatal("Failed to get table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 1)
+	utils.InsertEntry(t, table, 2, 2)
+	metadata, err := table.Metadata()
+	if err != nil {
+		t.Fatal("Failed to get metadata:", err)
+	}
+
+	output, err := database.HandleDescribe(db, "describe described")
+	if err != nil {
+		t.Fatal("Failed to describe table:", err)
+	}
+	for _, line := range []string{
+		"table: described",
+		"type: hash",
+		"created at: " + metadata.CreatedAt.UTC().Format(time.RFC3339),
+		fmt.Sprintf("format version: %d", pager.SuperblockVersion),
+		"entries: 2",
+	} {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected output to contain %q, but got %q", line, output)
+		}
+	}
+	if _, err := database.HandleDescribe(db, "describe missing"); err == nil {
+		t.Error("Expected an error describing a table that doesn't exist")
+	}
+}
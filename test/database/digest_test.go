@@ -0,0 +1,109 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+// getDigest wraps a call to database.Digest with error checking
+func getDigest(t *testing.T, table database.Index) (int64, uint64) {
+	count, checksum, err := database.Digest(table)
+	if err != nil {
+		t.Fatal("Failed to digest table:", err)
+	}
+	return count, checksum
+}
+
+func TestDigest(t *testing.T) {
+	t.Run("AcrossIndexTypes", testDigestAcrossIndexTypes)
+	t.Run("ChangedValue", testDigestChangedValue)
+	t.Run("Repl", testDigestRepl)
+}
+
+// Inserts the same entries into a btree and a hash table in different orders,
+// checking that both tables produce the same digest
+func testDigestAcrossIndexTypes(t *testing.T) {
+	db := setupDatabase(t)
+	btreeTable, err := db.CreateTable("btree", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	hashTable, err := db.CreateTable("hash", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	entries, _ := utils.GenerateRandomKeyValuePairs(1000)
+	for i := range entries {
+		utils.InsertEntry(t, btreeTable, entries[i].Key, entries[i].Val)
+		e := entries[len(entries)-1-i]
+		utils.InsertEntry(t, hashTable, e.Key, e.Val)
+	}
+
+	btreeCount, btreeChecksum := getDigest(t, btreeTable)
+	hashCount, hashChecksum := getDigest(t, hashTable)
+	if btreeCount != int64(len(entries)) {
+		t.Errorf("Expected a count of %d, but got %d", len(entries), btreeCount)
+	}
+	if btreeCount != hashCount || btreeChecksum != hashChecksum {
+		t.Errorf("Expected identical tables to have identical digests, but got (%d, %x) and (%d, %x)",
+			btreeCount, btreeChecksum, hashCount, hashChecksum)
+	}
+}
+
+// Checks that changing a single value changes the digest, and changing it back restores it
+func testDigestChangedValue(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(string(indexType), func(t *testing.T) {
+			db := setupDatabase(t)
+			table, err := db.CreateTable("digest", indexType)
+			if err != nil {
+				t.Fatal("Failed to create table:", err)
+			}
+			for i := int64(0); i < 1000; i++ {
+				utils.InsertEntry(t, table, i, i%utils.Salt)
+			}
+			count, checksum := getDigest(t, table)
+
+			if err = table.Update(500, 500%utils.Salt+1); err != nil {
+				t.Fatal("Failed to update entry:", err)
+			}
+			changedCount, changedChecksum := getDigest(t, table)
+			if changedCount != count {
+				t.Errorf("Expected updating an entry to keep the count at %d, but got %d", count, changedCount)
+			}
+			if changedChecksum == checksum {
+				t.Error("Expected updating an entry to change the checksum")
+			}
+
+			if err = table.Update(500, 500%utils.Salt); err != nil {
+				t.Fatal("Failed to update entry:", err)
+			}
+			if _, restoredChecksum := getDigest(t, table); restoredChecksum != checksum {
+				t.Error("Expected restoring an entry to restore the checksum")
+			}
+		})
+	}
+}
+
+// Runs digest through the database REPL handler, checking its output
+func testDigestRepl(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("digest", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 2)
+	_, checksum := getDigest(t, table)
+
+	output, err := database.HandleDigest(db, "digest digest")
+	if err != nil {
+		t.Fatal("Failed to digest table:", err)
+	}
+	expected := fmt.Sprintf("count: 1, checksum: %016x\n", checksum)
+	if output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+}
@@ -0,0 +1,102 @@
+package database_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+// =====================================================================
+// HELPERS
+// =====================================================================
+
+// setupDatabase creates and returns a Database in a unique random base directory
+func setupDatabase(t *testing.T) *database.Database {
+	t.Parallel()
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("Failed to create random database folder:", err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	utils.EnsureCleanup(t, func() {
+		_ = db.Close()
+		_ = os.RemoveAll(dbName)
+	})
+	return db
+}
+
+// =====================================================================
+// TESTS
+// =====================================================================
+
+func TestSelectDistinct(t *testing.T) {
+	t.Run("BTree", func(t *testing.T) { testSelectDistinct(t, database.BTreeIndexType) })
+	t.Run("Hash", func(t *testing.T) { testSelectDistinct(t, database.HashIndexType) })
+	t.Run("Repl", testSelectDistinctRepl)
+}
+
+// Inserts many entries sharing a small set of values, checking that each
+// value is returned once, in sorted order, with the right count
+func testSelectDistinct(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("distinct", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	numEntries := int64(2000)
+	numValues := int64(7)
+	expectedCounts := make(map[int64]int64)
+	for i := int64(0); i < numEntries; i++ {
+		// Values are negative and positive, and appear an uneven number of times
+		value := (i*i)%numValues - numValues/2
+		utils.InsertEntry(t, table, i, value)
+		expectedCounts[value]++
+	}
+
+	distinct, err := database.SelectDistinctValues(table)
+	if err != nil {
+		t.Fatal("Failed to select distinct values:", err)
+	}
+	if len(distinct) != len(expectedCounts) {
+		t.Fatalf("Expected %d distinct values, but found %d", len(expectedCounts), len(distinct))
+	}
+	for i, vc := range distinct {
+		if i > 0 && distinct[i-1].Value >= vc.Value {
+			t.Errorf("Expected distinct values to be sorted, but %d came before %d", distinct[i-1].Value, vc.Value)
+		}
+		if vc.Count != expectedCounts[vc.Value] {
+			t.Errorf("Expected value %d to occur %d times, but counted %d", vc.Value, expectedCounts[vc.Value], vc.Count)
+		}
+	}
+}
+
+// Runs select distinct through the database REPL handler, checking its output
+func testSelectDistinctRepl(t *testing.T) {
+	db := setupDatabase(t)
+	_, err := database.HandleCreateTable(db, "create btree table distinct")
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i, value := range []int64{5, -1, 5, 3, 5, -1} {
+		err = database.HandleInsert(db, fmt.Sprintf("insert %d %d into distinct", i, value))
+		if err != nil {
+			t.Fatal("Failed to insert:", err)
+		}
+	}
+
+	output, err := database.HandleSelect(db, "select distinct value from distinct")
+	if err != nil {
+		t.Fatal("Failed to select distinct values:", err)
+	}
+	expected := strings.Join([]string{"(-1, 2)", "(3, 1)", "(5, 3)", ""}, "\n")
+	if output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+}
@@ -0,0 +1,144 @@
+package database_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/database"
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+func TestExplainFind(t *testing.T) {
+	t.Run("BTree", testExplainFindBTree)
+	t.Run("Hash", testExplainFindHash)
+	t.Run("Repl", testExplainFindRepl)
+}
+
+// explainBTree explains a find for key, checking that the path starts at the root
+func explainBTree(t *testing.T, table *btree.BTreeIndex, key int64) btree.FindPlan {
+	plan, err := table.ExplainFind(key)
+	if err != nil {
+		t.Fatalf("Failed to explain finding key %d: %v", key, err)
+	}
+	if len(plan.Path) == 0 || plan.Path[0] != btree.ROOT_PN {
+		t.Fatalf("Expected the path for key %d to start at the root, but got %v", key, plan.Path)
+	}
+	return plan
+}
+
+// Grows a B+Tree one level at a time, checking that every lookup accesses one page per level
+func testExplainFindBTree(t *testing.T) {
+	db := setupDatabase(t)
+	index, err := db.CreateTable("t", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	table := index.(*btree.BTreeIndex)
+	if plan := explainBTree(t, table, 1); len(plan.Path) != 1 || plan.Found {
+		t.Errorf("Expected a missing key in an empty tree to take 1 page access, but got %+v", plan)
+	}
+
+	// One more entry than a leaf holds splits the root, adding a level.
+	numEntries := btree.ENTRIES_PER_LEAF_NODE + 1
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	for i := int64(0); i < numEntries; i++ {
+		plan := explainBTree(t, table, i)
+		if len(plan.Path) != 2 || !plan.Found {
+			t.Fatalf("Expected key %d to be found after 2 page accesses, but got %+v", i, plan)
+		}
+	}
+
+	// Grow the tree to 3 levels. Every leaf is at the same depth, so every lookup takes 3 page accesses.
+	const height = 3
+	for ; len(explainBTree(t, table, 0).Path) < height; numEntries++ {
+		utils.InsertEntry(t, table, numEntries, numEntries%utils.Salt)
+	}
+	layout, err := table.Layout()
+	if err != nil {
+		t.Fatal("Failed to get the layout:", err)
+	}
+	for i := int64(0); i < numEntries; i += 97 {
+		plan := explainBTree(t, table, i)
+		if len(plan.Path) != height || !plan.Found {
+			t.Fatalf("Expected key %d to be found after %d page accesses, but got %+v", i, height, plan)
+		}
+		if leaf := plan.Path[height-1]; layout[leaf].Role != btree.LEAF_PAGE {
+			t.Fatalf("Expected the path for key %d to end at a leaf, but page %d is %s", i, leaf, layout[leaf].Role)
+		}
+	}
+	if plan := explainBTree(t, table, -1); len(plan.Path) != height || plan.Found || plan.Slot != 0 {
+		t.Errorf("Expected a missing key to take %d page accesses and belong at slot 0, but got %+v", height, plan)
+	}
+}
+
+// Checks that every lookup in a hash table goes to the bucket the directory points to
+func testExplainFindHash(t *testing.T) {
+	db := setupDatabase(t)
+	index, err := db.CreateTable("t", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	table := index.(*hash.HashIndex)
+	for i := int64(0); i < 5000; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	buckets := table.GetTable().GetBuckets()
+	for i := int64(0); i < 5000; i += 7 {
+		plan, err := table.ExplainFind(i)
+		if err != nil {
+			t.Fatalf("Failed to explain finding key %d: %v", i, err)
+		}
+		if !plan.Found || plan.GlobalDepth != table.GetTable().GetDepth() || plan.BucketPN != buckets[plan.Hash] {
+			t.Fatalf("Expected key %d to be found in the bucket its directory slot points to, but got %+v", i, plan)
+		}
+	}
+	if plan, err := table.ExplainFind(-1); err != nil || plan.Found {
+		t.Errorf("Expected a missing key not to be found, but got %+v, %v", plan, err)
+	}
+}
+
+// Checks the explain command's output for both index types
+func testExplainFindRepl(t *testing.T) {
+	db := setupDatabase(t)
+	btreeTable, err := db.CreateTable("b", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if _, err := db.CreateTable("h", database.HashIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, btreeTable, 5, 50)
+
+	for payload, expected := range map[string]string{
+		"explain find 5 from b": "btree traversal: 1 page accesses (pages 0)\nfound at slot 0 of leaf 0\n",
+		"explain find 6 from b": "btree traversal: 1 page accesses (pages 0)\nnot found in leaf 0\n",
+	} {
+		output, err := database.HandleExplain(db, payload)
+		if err != nil {
+			t.Fatalf("Failed to run %q: %v", payload, err)
+		}
+		if output != expected {
+			t.Errorf("Expected %q to output %q, but got %q", payload, expected, output)
+		}
+	}
+	output, err := database.HandleExplain(db, "explain find 5 from h")
+	if err != nil {
+		t.Fatal("Failed to explain a hash lookup:", err)
+	}
+	if !strings.HasPrefix(output, "hash bucket lookup: 1 page access") || !strings.Contains(output, "not found") {
+		t.Errorf("Expected a hash lookup that misses, but got %q", output)
+	}
+	if strings.Contains(output, fmt.Sprint(50)) {
+		t.Errorf("Expected explain not to return the value, but got %q", output)
+	}
+	for _, payload := range []string{"explain find 5", "explain select 5 from b", "explain find x from b", "explain find 5 from missing"} {
+		if _, err := database.HandleExplain(db, payload); err == nil {
+			t.Errorf("Expected %q to fail", payload)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package database_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/repl"
+	"dinodb/test/utils"
+
+	"github.com/google/uuid"
+)
+
+// =====================================================================
+// HELPERS
+// =====================================================================
+
+// setupExportedDatabase creates a database holding a btree and a hash table of entries
+func setupExportedDatabase(t *testing.T) *database.Database {
+	db := setupDatabase(t)
+	for name, indexType := range map[string]database.IndexType{
+		"exportbtree": database.BTreeIndexType,
+		"exporthash":  database.HashIndexType,
+	} {
+		table, err := db.CreateTable(name, indexType)
+		if err != nil {
+			t.Fatal("Failed to create table:", err)
+		}
+		entries, _ := utils.GenerateRandomKeyValuePairs(500)
+		for _, e := range entries {
+			utils.InsertEntry(t, table, e.Key, e.Val)
+		}
+	}
+	return db
+}
+
+// replayScript runs an exported script against a fresh database and returns it
+func replayScript(t *testing.T, script string) *database.Database {
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("Failed to create random database folder:", err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal("Failed to open database:", err)
+	}
+	utils.EnsureCleanup(t, func() {
+		_ = db.Close()
+		_ = os.RemoveAll(dbName)
+	})
+	output := &bytes.Buffer{}
+	database.DatabaseRepl(db).Run(uuid.New(), "", strings.NewReader(script), output)
+	if strings.Contains(output.String(), repl.ErrorPrependStr) {
+		t.Fatalf("Expected the script to replay cleanly, but got output %q", output.String())
+	}
+	return db
+}
+
+// checkSameTables verifies that both databases hold the same tables, index types, and entries
+func checkSameTables(t *testing.T, expected *database.Database, actual *database.Database) {
+	expectedNames, err := expected.ListTables()
+	if err != nil {
+		t.Fatal("Failed to list tables:", err)
+	}
+	actualNames, err := actual.ListTables()
+	if err != nil {
+		t.Fatal("Failed to list tables:", err)
+	}
+	if strings.Join(expectedNames, ",") != strings.Join(actualNames, ",") {
+		t.Fatalf("Expected tables %v, but got %v", expectedNames, actualNames)
+	}
+	for _, name := range expectedNames {
+		expectedTable, err := expected.GetTable(name)
+		if err != nil {
+			t.Fatal("Failed to get table:", err)
+		}
+		actualTable, err := actual.GetTable(name)
+		if err != nil {
+			t.Fatal("Failed to get table:", err)
+		}
+		expectedType, _ := database.GetIndexType(expectedTable)
+		actualType, _ := database.GetIndexType(actualTable)
+		if expectedType != actualType {
+			t.Errorf("Expected table %s to have index type %s, but got %s", name, expectedType, actualType)
+		}
+		expectedCount, expectedChecksum, err := database.Digest(expectedTable)
+		if err != nil {
+			t.Fatal("Failed to digest table:", err)
+		}
+		actualCount, actualChecksum, err := database.Digest(actualTable)
+		if err != nil {
+			t.Fatal("Failed to digest table:", err)
+		}
+		if expectedCount != actualCount || expectedChecksum != actualChecksum {
+			t.Errorf("Expected table %s to have digest (%d, %x), but got (%d, %x)",
+				name, expectedCount, expectedChecksum, actualCount, actualChecksum)
+		}
+	}
+}
+
+// =====================================================================
+// TESTS
+// =====================================================================
+
+func TestExport(t *testing.T) {
+	t.Run("RoundTrip", testExportRoundTrip)
+	t.Run("Repl", testExportRepl)
+}
+
+// Exports a database and replays the script into an empty one, checking that the two match
+func testExportRoundTrip(t *testing.T) {
+	db := setupExportedDatabase(t)
+	var script bytes.Buffer
+	if err := db.Export(&script); err != nil {
+		t.Fatal("Failed to export database:", err)
+	}
+	checkSameTables(t, db, replayScript(t, script.String()))
+}
+
+// Exports a database to a file with the export command, checking that the file replays correctly
+func testExportRepl(t *testing.T) {
+	db := setupExportedDatabase(t)
+	path := filepath.Join(t.TempDir(), "dump.txt")
+	if err := database.HandleExport(db, "export "+path); err != nil {
+		t.Fatal("Failed to export database:", err)
+	}
+	script, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal("Failed to read export file:", err)
+	}
+	checkSameTables(t, db, replayScript(t, string(script)))
+
+	if err := database.HandleExport(db, "export"); err == nil {
+		t.Error("Expected export without a path to fail")
+	}
+}
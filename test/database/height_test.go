@@ -0,0 +1,51 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+// Runs the height command on a multi-level B+Tree and a new hash table, checking the reported height,
+// levels, and bucket depths
+func TestHeight(t *testing.T) {
+	db := setupDatabase(t)
+	btreeTable, err := db.CreateTable("tree", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 50000; i++ {
+		utils.InsertEntry(t, btreeTable, i, i)
+	}
+	if _, err := db.CreateTable("buckets", database.HashIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+
+	output, err := database.HandleHeight(db, "height tree")
+	if err != nil {
+		t.Fatal("Failed to get height:", err)
+	}
+	if output != "height: 3\n" {
+		t.Errorf("Expected a height of 3, but got %q", output)
+	}
+	output, err = database.HandleHeight(db, "height tree levels")
+	if err != nil {
+		t.Fatal("Failed to get height:", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], "level 1: 1 nodes") || !strings.HasPrefix(lines[3], "level 3:") {
+		t.Errorf("Expected the height followed by 3 levels, but got %q", output)
+	}
+	output, err = database.HandleHeight(db, "height buckets")
+	if err != nil {
+		t.Fatal("Failed to get height:", err)
+	}
+	if expected := "global depth: 2\nlocal depth 2: 4 buckets\n"; output != expected {
+		t.Errorf("Expected %q for a new hash table, but got %q", expected, output)
+	}
+	if _, err := database.HandleHeight(db, "height tree nodes"); err == nil {
+		t.Error("Expected a usage error")
+	}
+}
@@ -0,0 +1,180 @@
+package database_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestSelectHistogram(t *testing.T) {
+	t.Run("UniformBTree", func(t *testing.T) { testHistogramUniform(t, database.BTreeIndexType) })
+	t.Run("UniformHash", func(t *testing.T) { testHistogramUniform(t, database.HashIndexType) })
+	t.Run("Skewed", testHistogramSkewed)
+	t.Run("Edges", testHistogramEdges)
+	t.Run("Repl", testHistogramRepl)
+}
+
+// checkHistogram checks that the buckets are contiguous, cover [minKey, maxKey], and count total entries
+func checkHistogram(t *testing.T, buckets []database.HistogramBucket, minKey, maxKey, total int64) {
+	if len(buckets) == 0 {
+		t.Fatal("Expected a non-empty histogram")
+	}
+	if buckets[0].Low != minKey || buckets[len(buckets)-1].High != maxKey {
+		t.Errorf("Expected buckets to cover [%d, %d], but they cover [%d, %d]",
+			minKey, maxKey, buckets[0].Low, buckets[len(buckets)-1].High)
+	}
+	sum := int64(0)
+	for i, bucket := range buckets {
+		if bucket.Low > bucket.High {
+			t.Errorf("Bucket %d is empty: [%d, %d]", i, bucket.Low, bucket.High)
+		}
+		if i > 0 && buckets[i-1].High+1 != bucket.Low {
+			t.Errorf("Bucket %d starts at %d, but bucket %d ends at %d", i, bucket.Low, i-1, buckets[i-1].High)
+		}
+		sum += bucket.Count
+	}
+	if sum != total {
+		t.Errorf("Expected bucket counts to sum to %d, but they sum to %d", total, sum)
+	}
+}
+
+// Inserts evenly spaced keys, checking that the buckets' widths differ by at most one key
+// and that each bucket holds the same number of entries
+func testHistogramUniform(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("uniform", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	numEntries := int64(1000)
+	for i := range numEntries {
+		utils.InsertEntry(t, table, i*3-500, i)
+	}
+
+	buckets, err := database.SelectHistogram(table, 10)
+	if err != nil {
+		t.Fatal("Failed to select histogram:", err)
+	}
+	if len(buckets) != 10 {
+		t.Fatalf("Expected 10 buckets, but got %d", len(buckets))
+	}
+	checkHistogram(t, buckets, -500, (numEntries-1)*3-500, numEntries)
+	for i, bucket := range buckets {
+		if width := bucket.High - bucket.Low; width < 298 || width > 299 {
+			t.Errorf("Bucket %d is [%d, %d], expected a width of 299 or 300 keys", i, bucket.Low, bucket.High)
+		}
+		if bucket.Count != numEntries/10 {
+			t.Errorf("Expected bucket %d to hold %d entries, but it holds %d", i, numEntries/10, bucket.Count)
+		}
+	}
+}
+
+// Inserts most keys near the start of the range, checking that the first bucket holds them
+func testHistogramSkewed(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("skewed", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	// 900 keys in [0, 900) and 100 keys spread over [900, 100000)
+	for i := range int64(900) {
+		utils.InsertEntry(t, table, i, i)
+	}
+	for i := range int64(100) {
+		utils.InsertEntry(t, table, 900+i*991, i)
+	}
+	maxKey := int64(900 + 99*991)
+
+	buckets, err := database.SelectHistogram(table, 4)
+	if err != nil {
+		t.Fatal("Failed to select histogram:", err)
+	}
+	checkHistogram(t, buckets, 0, maxKey, 1000)
+	for i, bucket := range buckets[1:] {
+		if bucket.Count >= buckets[0].Count {
+			t.Errorf("Expected bucket %d to hold fewer entries than bucket 0, but it holds %d of %d",
+				i+1, bucket.Count, buckets[0].Count)
+		}
+	}
+	if buckets[0].Count < 900 {
+		t.Errorf("Expected bucket 0 to hold at least 900 entries, but it holds %d", buckets[0].Count)
+	}
+}
+
+// Checks empty tables, ranges narrower than the bucket count, keys spanning every int64, and bad bucket counts
+func testHistogramEdges(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("edges", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if _, err := database.SelectHistogram(table, 0); err == nil {
+		t.Error("Expected an error for a bucket count of 0")
+	}
+	buckets, err := database.SelectHistogram(table, 5)
+	if err != nil {
+		t.Fatal("Failed to select histogram:", err)
+	}
+	if len(buckets) != 0 {
+		t.Errorf("Expected no buckets for an empty table, but got %d", len(buckets))
+	}
+
+	// Three keys in a range of three can't fill five buckets
+	for i := range int64(3) {
+		utils.InsertEntry(t, table, i, i)
+	}
+	buckets, err = database.SelectHistogram(table, 5)
+	if err != nil {
+		t.Fatal("Failed to select histogram:", err)
+	}
+	if len(buckets) != 3 {
+		t.Errorf("Expected 3 buckets, but got %d", len(buckets))
+	}
+	checkHistogram(t, buckets, 0, 2, 3)
+
+	utils.InsertEntry(t, table, math.MinInt64, 0)
+	utils.InsertEntry(t, table, math.MaxInt64, 0)
+	buckets, err = database.SelectHistogram(table, 4)
+	if err != nil {
+		t.Fatal("Failed to select histogram:", err)
+	}
+	checkHistogram(t, buckets, math.MinInt64, math.MaxInt64, 5)
+	// Each bucket spans a quarter of the int64s, so the keys near 0 fall in the third
+	expectedCounts := []int64{1, 0, 3, 1}
+	for i, bucket := range buckets {
+		if bucket.Count != expectedCounts[i] {
+			t.Errorf("Expected bucket %d to hold %d entries, but it holds %d", i, expectedCounts[i], bucket.Count)
+		}
+	}
+}
+
+// Runs select histogram through the database REPL handler, checking its output
+func testHistogramRepl(t *testing.T) {
+	db := setupDatabase(t)
+	_, err := database.HandleCreateTable(db, "create btree table histogram")
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for _, key := range []int64{0, 1, 2, 3, 9, 10, 11, 19} {
+		err = database.HandleInsert(db, fmt.Sprintf("insert %d %d into histogram", key, key))
+		if err != nil {
+			t.Fatal("Failed to insert:", err)
+		}
+	}
+
+	output, err := database.HandleSelect(db, "select histogram 2 from histogram")
+	if err != nil {
+		t.Fatal("Failed to select histogram:", err)
+	}
+	expected := strings.Join([]string{"(0, 9, 5)", "(10, 19, 3)", ""}, "\n")
+	if output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+	if _, err := database.HandleSelect(db, "select histogram x from histogram"); err == nil {
+		t.Error("Expected an error for a non-numeric bucket count")
+	}
+}
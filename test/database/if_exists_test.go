@@ -0,0 +1,76 @@
+package database_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/repl"
+	"dinodb/test/utils"
+)
+
+func TestIfExists(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(fmt.Sprintf("Update/%s", indexType), func(t *testing.T) { testIfExistsUpdate(t, indexType) })
+		t.Run(fmt.Sprintf("Delete/%s", indexType), func(t *testing.T) { testIfExistsDelete(t, indexType) })
+	}
+}
+
+// setupIfExistsTable creates a table holding only the entry (1, 10)
+func setupIfExistsTable(t *testing.T, indexType database.IndexType) (*database.Database, database.Index) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 10)
+	return db, table
+}
+
+// checkAffected runs a command returning a result, checking that it succeeds and reports the given affected count
+func checkAffected(t *testing.T, command func(*database.Database, string) (repl.Result, error), db *database.Database, payload string, affected int64) {
+	result, err := command(db, payload)
+	if err != nil {
+		t.Fatalf("Expected %q to succeed, but got %v", payload, err)
+	}
+	if result.Affected != affected {
+		t.Errorf("Expected %q to affect %d entries, but it affected %d", payload, affected, result.Affected)
+	}
+}
+
+// Updates present and absent keys, checking that only strict updates of absent keys fail
+func testIfExistsUpdate(t *testing.T, indexType database.IndexType) {
+	db, table := setupIfExistsTable(t, indexType)
+	checkAffected(t, database.UpdateResult, db, "update t 1 11", 1)
+	utils.CheckFindEntry(t, table, 1, 11)
+	checkAffected(t, database.UpdateResult, db, "update t 1 12 if exists", 1)
+	utils.CheckFindEntry(t, table, 1, 12)
+
+	if _, err := database.UpdateResult(db, "update t 2 20"); !errors.Is(err, database.ErrKeyNotFound) {
+		t.Errorf("Expected a strict update of a missing key to fail with %q, but got %v", database.ErrKeyNotFound, err)
+	}
+	checkAffected(t, database.UpdateResult, db, "update t 2 20 if exists", 0)
+	if _, err := table.Find(2); err == nil {
+		t.Error("Expected an update if exists not to insert a missing key")
+	}
+	if _, err := database.UpdateResult(db, "update t 2 20 if"); err == nil {
+		t.Error("Expected an update with a partial if exists to fail")
+	}
+}
+
+// Deletes present and absent keys, checking that only strict deletes of absent keys fail
+func testIfExistsDelete(t *testing.T, indexType database.IndexType) {
+	db, table := setupIfExistsTable(t, indexType)
+	utils.InsertEntry(t, table, 2, 20)
+	checkAffected(t, database.DeleteResult, db, "delete 1 from t", 1)
+	checkAffected(t, database.DeleteResult, db, "delete 2 from t if exists", 1)
+	if _, err := table.Find(2); err == nil {
+		t.Error("Expected key 2 to be deleted")
+	}
+
+	if _, err := database.DeleteResult(db, "delete 1 from t"); !errors.Is(err, database.ErrKeyNotFound) {
+		t.Errorf("Expected a strict delete of a missing key to fail with %q, but got %v", database.ErrKeyNotFound, err)
+	}
+	checkAffected(t, database.DeleteResult, db, "delete 1 from t if exists", 0)
+}
@@ -0,0 +1,109 @@
+package database_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+// A CSV file with valid rows, a duplicate key on line 4, and a row that can't be parsed on line 5
+const importCSV = "1,10\n2, 20\n\n2,25\nx,30\n3,30\n"
+
+func TestImport(t *testing.T) {
+	t.Run("Skip", testImportSkip)
+	t.Run("Abort", testImportAbort)
+	t.Run("Repl", testImportRepl)
+}
+
+// Imports the file, skipping bad rows, checking that they're reported and that every other row is imported
+func testImportSkip(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("skip", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	summary, err := database.ImportCSV(table, strings.NewReader(importCSV), database.IMPORT_SKIP)
+	if err != nil {
+		t.Fatal("Failed to import:", err)
+	}
+	if summary.Imported != 3 || summary.Aborted {
+		t.Errorf("Expected 3 rows to be imported without aborting, but got %+v", summary)
+	}
+	if len(summary.Rejected) != 2 {
+		t.Fatalf("Expected 2 rejected rows, but got %v", summary.Rejected)
+	}
+	if summary.Rejected[0].Line != 4 || !errors.Is(summary.Rejected[0].Reason, database.ErrKeyExists) {
+		t.Errorf("Expected line 4 to be rejected as a duplicate key, but got %+v", summary.Rejected[0])
+	}
+	if summary.Rejected[1].Line != 5 || !strings.Contains(summary.Rejected[1].Reason.Error(), "bad key") {
+		t.Errorf("Expected line 5 to be rejected for a bad key, but got %+v", summary.Rejected[1])
+	}
+	utils.CheckFindEntry(t, table, 1, 10)
+	utils.CheckFindEntry(t, table, 2, 20)
+	utils.CheckFindEntry(t, table, 3, 30)
+}
+
+// Imports the file, aborting at the first bad row, checking that only the rows before it are imported
+func testImportAbort(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("abort", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	summary, err := database.ImportCSV(table, strings.NewReader(importCSV), database.IMPORT_ABORT)
+	if err != nil {
+		t.Fatal("Failed to import:", err)
+	}
+	if summary.Imported != 2 || !summary.Aborted {
+		t.Errorf("Expected 2 rows to be imported before aborting, but got %+v", summary)
+	}
+	if len(summary.Rejected) != 1 || summary.Rejected[0].Line != 4 {
+		t.Fatalf("Expected only line 4 to be rejected, but got %v", summary.Rejected)
+	}
+	utils.CheckFindEntry(t, table, 2, 20)
+	if _, err := table.Find(3); err == nil {
+		t.Error("Expected the row after the abort not to be imported")
+	}
+}
+
+// Imports the file through the database REPL handler, checking its summary in both modes
+func testImportRepl(t *testing.T) {
+	db := setupDatabase(t)
+	path := filepath.Join(t.TempDir(), "import.csv")
+	if err := os.WriteFile(path, []byte(importCSV), 0666); err != nil {
+		t.Fatal("Failed to write CSV file:", err)
+	}
+	for _, name := range []string{"skipped", "aborted"} {
+		if _, err := database.HandleCreateTable(db, "create btree table "+name); err != nil {
+			t.Fatal("Failed to create table:", err)
+		}
+	}
+
+	output, err := database.HandleImport(db, "import "+path+" into skipped on error skip")
+	if err != nil {
+		t.Fatal("Failed to import:", err)
+	}
+	expected := "imported: 3\nrejected: 2\nline 4: key already in table: 2 has value 20\nline 5: bad key: "
+	if !strings.HasPrefix(output, expected) || strings.Contains(output, "aborted") {
+		t.Errorf("Expected output starting with %q, but got %q", expected, output)
+	}
+
+	output, err = database.HandleImport(db, "import "+path+" into aborted")
+	if err != nil {
+		t.Fatal("Failed to import:", err)
+	}
+	expected = "imported: 2\nrejected: 1\nline 4: key already in table: 2 has value 20\naborted at line 4\n"
+	if output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+
+	_, err = database.HandleImport(db, "import "+path+" into skipped on error ignore")
+	if err == nil || !strings.Contains(err.Error(), database.ErrUnknownImportMode.Error()) {
+		t.Errorf("Expected an unknown import mode error, but got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestInsertPairs(t *testing.T) {
+	t.Run("Collision", testInsertPairsCollision)
+	t.Run("Single", testInsertPairsSingle)
+	t.Run("Malformed", testInsertPairsMalformed)
+	t.Run("ConflictValue", testInsertConflictValue)
+}
+
+// Inserts under a key that is already in the table, checking that the error carries the existing entry
+func testInsertConflictValue(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("conflict", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 7, 70)
+
+	err = database.HandleInsert(db, "insert 7 71 into conflict")
+	if !errors.Is(err, database.ErrKeyExists) {
+		t.Fatalf("Expected a conflicting insert to fail with %q, but got %v", database.ErrKeyExists, err)
+	}
+	var conflict *database.KeyExistsError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected the error to be a KeyExistsError, but got %v", err)
+	}
+	if conflict.Existing.Key != 7 || conflict.Existing.Value != 70 {
+		t.Errorf("Expected the existing entry (7, 70), but got (%d, %d)", conflict.Existing.Key, conflict.Existing.Value)
+	}
+	if expected := "insert error: key already in table: 7 has value 70"; err.Error() != expected {
+		t.Errorf("Expected error %q, but got %q", expected, err.Error())
+	}
+	utils.CheckFindEntry(t, table, 7, 70)
+}
+
+// Inserts several pairs where one collides with an existing key, checking
+// the reported outcome of each pair and that the other pairs were inserted
+func testInsertPairsCollision(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("pairs", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 2, 2)
+
+	output, err := database.HandleInsertPairs("insert (1 10) (2 20) (3 30) into pairs", func(payload string) error {
+		return database.HandleInsert(db, payload)
+	})
+	if err != nil {
+		t.Fatal("Failed to insert pairs:", err)
+	}
+	expected := "inserted 2 of 3 entries\nfailed (2, 20): insert error: key already in table: 2 has value 2\n"
+	if output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+	utils.CheckFindEntry(t, table, 1, 10)
+	utils.CheckFindEntry(t, table, 2, 2)
+	utils.CheckFindEntry(t, table, 3, 30)
+}
+
+// Inserts a single pair in both forms, checking that the unparenthesized form behaves like a plain insert
+func testInsertPairsSingle(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("pairs", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	insert := func(payload string) error {
+		return database.HandleInsert(db, payload)
+	}
+
+	output, err := database.HandleInsertPairs("insert 1 10 into pairs", insert)
+	if err != nil || output != "" {
+		t.Errorf("Expected a plain insert to succeed with no output, but got %q, %v", output, err)
+	}
+	if _, err = database.HandleInsertPairs("insert 1 11 into pairs", insert); err == nil {
+		t.Error("Expected a plain insert of an existing key to fail")
+	}
+	output, err = database.HandleInsertPairs("insert (-2 20) into pairs", insert)
+	if err != nil {
+		t.Fatal("Failed to insert pair:", err)
+	}
+	if expected := "inserted 1 of 1 entries\n"; output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+	utils.CheckFindEntry(t, table, 1, 10)
+	utils.CheckFindEntry(t, table, -2, 20)
+}
+
+// Checks that malformed inserts are rejected before anything is inserted
+func testInsertPairsMalformed(t *testing.T) {
+	payloads := []string{
+		"insert into pairs",
+		"insert 1 into pairs",
+		"insert (1 10) (2) into pairs",
+		"insert (1 10) (2 20 into pairs",
+		"insert (1 10) 2 20 into pairs",
+		"insert (1 ten) into pairs",
+		"insert (1 10) (2 20) pairs",
+	}
+	for _, payload := range payloads {
+		_, err := database.HandleInsertPairs(payload, func(payload string) error {
+			t.Errorf("Expected %q to be rejected, but %q was inserted", payload, payload)
+			return nil
+		})
+		if err == nil {
+			t.Errorf("Expected %q to be rejected", payload)
+		}
+	}
+}
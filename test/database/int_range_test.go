@@ -0,0 +1,71 @@
+package database_test
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
+)
+
+func TestIntRange(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(indexType.String(), func(t *testing.T) { testIntRangeRoundTrip(t, indexType) })
+	}
+	t.Run("OutOfRange", testIntRangeOutOfRange)
+}
+
+// Inserts, updates, and finds keys and values at the ends of the int64 range through the REPL's handlers
+func testIntRangeRoundTrip(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	if _, err := db.CreateTable("t", indexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	keys := []int64{math.MaxInt64, math.MaxInt64 - 1, math.MinInt64, math.MinInt64 + 1, math.MaxInt32 + 1}
+	for _, key := range keys {
+		if err := database.HandleInsert(db, fmt.Sprintf("insert %d %d into t", key, -key)); err != nil {
+			t.Fatalf("Failed to insert key %d: %v", key, err)
+		}
+	}
+	if err := database.HandleUpdate(db, fmt.Sprintf("update t %d %d", int64(math.MinInt64), int64(math.MaxInt64))); err != nil {
+		t.Fatal("Failed to update the smallest key:", err)
+	}
+	for _, key := range keys {
+		expected := -key
+		if key == math.MinInt64 {
+			expected = math.MaxInt64
+		}
+		result, err := database.FindResult(db, fmt.Sprintf("find %d from t", key))
+		if err != nil {
+			t.Fatalf("Failed to find key %d: %v", key, err)
+		}
+		if len(result.Rows) != 1 || result.Rows[0].Key != key || result.Rows[0].Value != expected {
+			t.Errorf("Expected to find (%d, %d), but got %v", key, expected, result.Rows)
+		}
+	}
+}
+
+// Checks that keys and values that don't fit in an int64 are rejected with ErrOutOfRange
+func testIntRangeOutOfRange(t *testing.T) {
+	db := setupDatabase(t)
+	if _, err := db.CreateTable("t", database.BTreeIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for _, payload := range []string{
+		"insert 9223372036854775808 1 into t",
+		"insert 1 -9223372036854775809 into t",
+	} {
+		if err := database.HandleInsert(db, payload); err == nil || !strings.Contains(err.Error(), entry.ErrOutOfRange.Error()) {
+			t.Errorf("Expected %q to be rejected as out of range, but got %v", payload, err)
+		}
+	}
+	if _, _, _, err := database.ParseInsertPairs("insert (1 2) (3 99999999999999999999) into t"); err == nil ||
+		!strings.Contains(err.Error(), entry.ErrOutOfRange.Error()) {
+		t.Errorf("Expected a multi-pair insert with an out of range value to be rejected, but got %v", err)
+	}
+	if _, err := entry.ParseInt64("12ab"); err == nil || strings.Contains(err.Error(), entry.ErrOutOfRange.Error()) {
+		t.Errorf("Expected a malformed integer to be rejected as invalid, but got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+// Runs the layout command on a btree table and a hash table, checking there is a line per page
+func TestLayoutRepl(t *testing.T) {
+	db := setupDatabase(t)
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		table, err := db.CreateTable(string(indexType), indexType)
+		if err != nil {
+			t.Fatal("Failed to create table:", err)
+		}
+		for i := int64(0); i < 2000; i++ {
+			utils.InsertEntry(t, table, i, i)
+		}
+		output, err := database.HandleLayout(db, "layout "+string(indexType))
+		if err != nil {
+			t.Fatalf("Failed to get layout of %s table: %v", indexType, err)
+		}
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+		if int64(len(lines)) != table.GetPager().GetNumPages() {
+			t.Errorf("Expected %d lines for the %s table, but got %d:\n%s", table.GetPager().GetNumPages(), indexType, len(lines), output)
+		}
+	}
+	output, err := database.HandleLayout(db, "layout btree")
+	if err != nil {
+		t.Fatal("Failed to get layout:", err)
+	}
+	if !strings.HasPrefix(output, "page 0: root (internal)") {
+		t.Errorf("Expected the first page to be an internal root, but got:\n%s", output)
+	}
+	if _, err := database.HandleLayout(db, "layout"); err == nil {
+		t.Error("Expected a usage error")
+	}
+}
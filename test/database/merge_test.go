@@ -0,0 +1,110 @@
+package database_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+)
+
+func TestMerge(t *testing.T) {
+	t.Run("Skip", func(t *testing.T) { testMerge(t, database.CONFLICT_SKIP) })
+	t.Run("Replace", func(t *testing.T) { testMerge(t, database.CONFLICT_REPLACE) })
+	t.Run("Error", testMergeError)
+	t.Run("TypeMismatch", testMergeTypeMismatch)
+	t.Run("ParseConflictPolicy", testParseConflictPolicy)
+}
+
+// mergeScript returns a script creating a table of the given type holding (key, key*factor) for each key in [start, end)
+func mergeScript(table string, indexType database.IndexType, start, end, factor int64) string {
+	return fmt.Sprintf("create %s table %s\n", indexType, table) + mergeInserts(table, start, end, factor)
+}
+
+// mergeInserts returns a script inserting (key, key*factor) into the table for each key in [start, end)
+func mergeInserts(table string, start, end, factor int64) string {
+	var sb strings.Builder
+	for key := start; key < end; key++ {
+		fmt.Fprintf(&sb, "insert %d %d into %s\n", key, key*factor, table)
+	}
+	return sb.String()
+}
+
+// setupMerge creates a destination and a source database that share a btree and a hash table, with keys 50-99
+// in both, and that each have a table of their own. Source values are 3 times their key, destination values 2 times.
+func setupMerge(t *testing.T) (dst *database.Database, src *database.Database) {
+	dst = replayScript(t, mergeScript("sharedbtree", database.BTreeIndexType, 0, 100, 2)+
+		mergeScript("sharedhash", database.HashIndexType, 0, 100, 2)+
+		mergeScript("onlydst", database.BTreeIndexType, 0, 10, 2))
+	src = replayScript(t, mergeScript("sharedbtree", database.BTreeIndexType, 50, 150, 3)+
+		mergeScript("sharedhash", database.HashIndexType, 50, 150, 3)+
+		mergeScript("onlysrc", database.HashIndexType, 0, 10, 3))
+	return dst, src
+}
+
+// Merges overlapping and disjoint tables, checking the result against a database built with the expected entries
+func testMerge(t *testing.T, policy database.ConflictPolicy) {
+	dst, src := setupMerge(t)
+	if err := database.Merge(dst, src, policy); err != nil {
+		t.Fatal("Failed to merge:", err)
+	}
+	// Shared keys keep the destination's value when skipped, and take the source's when replaced.
+	dstEnd := int64(100)
+	if policy == database.CONFLICT_REPLACE {
+		dstEnd = 50
+	}
+	var shared string
+	for table, indexType := range map[string]database.IndexType{
+		"sharedbtree": database.BTreeIndexType,
+		"sharedhash":  database.HashIndexType,
+	} {
+		shared += mergeScript(table, indexType, 0, dstEnd, 2) + mergeInserts(table, dstEnd, 150, 3)
+	}
+	expected := replayScript(t, shared+
+		mergeScript("onlydst", database.BTreeIndexType, 0, 10, 2)+
+		mergeScript("onlysrc", database.HashIndexType, 0, 10, 3))
+	checkSameTables(t, expected, dst)
+}
+
+// Merges with CONFLICT_ERROR, checking that the first shared key stops the merge
+func testMergeError(t *testing.T) {
+	dst, src := setupMerge(t)
+	err := database.Merge(dst, src, database.CONFLICT_ERROR)
+	var keyExists *database.KeyExistsError
+	if !errors.As(err, &keyExists) {
+		t.Fatalf("Expected merging shared keys to fail with a KeyExistsError, but got %v", err)
+	}
+	if keyExists.Existing.Value != keyExists.Existing.Key*2 {
+		t.Errorf("Expected the error to hold the destination's entry, but got %v", keyExists.Existing)
+	}
+	if !strings.Contains(err.Error(), "table sharedbtree") {
+		t.Errorf("Expected the error to name the conflicting table, but got %v", err)
+	}
+}
+
+// Merges a table with a different index type in each database, checking that the merge fails without changing anything
+func testMergeTypeMismatch(t *testing.T) {
+	dst := replayScript(t, mergeScript("a", database.BTreeIndexType, 0, 10, 2)+mergeScript("b", database.BTreeIndexType, 0, 10, 2))
+	src := replayScript(t, mergeScript("a", database.BTreeIndexType, 10, 20, 3)+mergeScript("b", database.HashIndexType, 0, 10, 3))
+	if err := database.Merge(dst, src, database.CONFLICT_REPLACE); !errors.Is(err, database.ErrTableTypeMismatch) {
+		t.Fatalf("Expected merging tables of different types to fail with %q, but got %v", database.ErrTableTypeMismatch, err)
+	}
+	expected := replayScript(t, mergeScript("a", database.BTreeIndexType, 0, 10, 2)+mergeScript("b", database.BTreeIndexType, 0, 10, 2))
+	checkSameTables(t, expected, dst)
+	if err := database.Merge(dst, dst, database.CONFLICT_SKIP); err == nil {
+		t.Error("Expected merging a database into itself to fail")
+	}
+}
+
+func testParseConflictPolicy(t *testing.T) {
+	for _, policy := range []database.ConflictPolicy{database.CONFLICT_ERROR, database.CONFLICT_SKIP, database.CONFLICT_REPLACE} {
+		parsed, err := database.ParseConflictPolicy(policy.String())
+		if err != nil || parsed != policy {
+			t.Errorf("Expected %q to parse as %v, but got %v, %v", policy.String(), policy, parsed, err)
+		}
+	}
+	if _, err := database.ParseConflictPolicy("merge"); !errors.Is(err, database.ErrUnknownConflictPolicy) {
+		t.Errorf("Expected an unknown policy to fail with %q, but got %v", database.ErrUnknownConflictPolicy, err)
+	}
+}
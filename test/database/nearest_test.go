@@ -0,0 +1,107 @@
+package database_test
+
+import (
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestNearest(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(indexType.String(), func(t *testing.T) { testNearest(t, indexType) })
+	}
+	t.Run("EmptiedLeaves", testNearestEmptiedLeaves)
+	t.Run("Repl", testNearestRepl)
+}
+
+// checkNearest checks a table's floor and ceiling of key against the expected keys, where found is false if there is none
+func checkNearest(t *testing.T, table database.Index, key int64, floor int64, floorFound bool, ceiling int64, ceilingFound bool) {
+	e, found, err := table.Floor(key)
+	if err != nil {
+		t.Fatalf("Failed to find the floor of %d: %v", key, err)
+	}
+	if found != floorFound || (found && e.Key != floor) {
+		t.Errorf("Expected the floor of %d to be %d (found %v), but got %d (found %v)", key, floor, floorFound, e.Key, found)
+	}
+	e, found, err = table.Ceiling(key)
+	if err != nil {
+		t.Fatalf("Failed to find the ceiling of %d: %v", key, err)
+	}
+	if found != ceilingFound || (found && e.Key != ceiling) {
+		t.Errorf("Expected the ceiling of %d to be %d (found %v), but got %d (found %v)", key, ceiling, ceilingFound, e.Key, found)
+	}
+}
+
+// Fills a table with every tenth key, checking the floor and ceiling of keys on, between, and past the ends of them
+func testNearest(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	checkNearest(t, table, 0, 0, false, 0, false)
+	numEntries := int64(1000)
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i*10, i)
+	}
+	for key := int64(-5); key < numEntries*10+5; key += 7 {
+		floor, ceiling := key-key%10, key+(10-key%10)%10
+		checkNearest(t, table, key, floor, key >= 0, ceiling, ceiling < numEntries*10)
+	}
+	checkNearest(t, table, 500, 500, true, 500, true)
+	checkNearest(t, table, 501, 500, true, 510, true)
+}
+
+// Deletes a run of keys spanning several B+Tree leaves, checking that floor and ceiling skip the deleted run
+func testNearestEmptiedLeaves(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 3000; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+	for i := int64(1000); i < 2000; i++ {
+		if err := table.Delete(i); err != nil {
+			t.Fatal("Failed to delete:", err)
+		}
+	}
+	checkNearest(t, table, 1500, 999, true, 2000, true)
+	for i := int64(0); i < 1000; i++ {
+		if err := table.Delete(i); err != nil {
+			t.Fatal("Failed to delete:", err)
+		}
+	}
+	checkNearest(t, table, 1500, 0, false, 2000, true)
+}
+
+// Runs floor and ceiling through the REPL's handler, checking the output for a nearby key and past the ends
+func testNearestRepl(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 10, 1)
+	utils.InsertEntry(t, table, 20, 2)
+	for payload, expected := range map[string]string{
+		"floor 15 from t":   "floor of 15: (10, 1)\n",
+		"ceiling 15 from t": "ceiling of 15: (20, 2)\n",
+		"floor 5 from t":    "no key at most 5\n",
+		"ceiling 25 from t": "no key at least 25\n",
+	} {
+		result, err := database.NearestResult(db, payload)
+		if err != nil {
+			t.Fatalf("Failed to run %q: %v", payload, err)
+		}
+		if result.Message != expected {
+			t.Errorf("Expected %q to output %q, but got %q", payload, expected, result.Message)
+		}
+	}
+	if _, err := database.NearestResult(db, "floor 5 in t"); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("Expected a usage error, but got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package database_test
+
+import (
+	"sort"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestSelectOrderedByValue(t *testing.T) {
+	t.Run("BTree", func(t *testing.T) { testSelectOrderedByValue(t, database.BTreeIndexType) })
+	t.Run("Hash", func(t *testing.T) { testSelectOrderedByValue(t, database.HashIndexType) })
+}
+
+// Selects the first entries by value with various limits, checking them against
+// a full select sorted by value then key. Values repeat so that ties are broken by key
+func testSelectOrderedByValue(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("ordered", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	entries, _ := utils.GenerateRandomKeyValuePairs(1000)
+	for _, e := range entries {
+		utils.InsertEntry(t, table, e.Key, e.Val%100)
+	}
+	expected, err := table.Select()
+	if err != nil {
+		t.Fatal("Failed to select from table:", err)
+	}
+	sort.Slice(expected, func(i, j int) bool {
+		if expected[i].Value != expected[j].Value {
+			return expected[i].Value < expected[j].Value
+		}
+		return expected[i].Key < expected[j].Key
+	})
+
+	for _, limit := range []int{0, 1, 10, 999, 1000, 5000} {
+		ordered, err := database.SelectOrderedByValue(table, limit)
+		if err != nil {
+			t.Fatal("Failed to select ordered by value:", err)
+		}
+		want := expected
+		if limit > 0 && limit < len(expected) {
+			want = expected[:limit]
+		}
+		if len(ordered) != len(want) {
+			t.Fatalf("Expected %d entries with limit %d, but got %d", len(want), limit, len(ordered))
+		}
+		for i := range want {
+			utils.CheckEntry(t, ordered[i], want[i].Key, want[i].Value)
+		}
+	}
+	if _, err := database.SelectOrderedByValue(table, -1); err == nil {
+		t.Error("Expected a negative limit to fail")
+	}
+}
@@ -0,0 +1,101 @@
+package database_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
+)
+
+// writeRecorder records each write made to it separately
+type writeRecorder struct {
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+// readFrames parses pipelined responses up to the end sentinel, checking that each frame is correctly numbered
+func readFrames(t *testing.T, output string) []string {
+	reader := bufio.NewReader(strings.NewReader(output))
+	frames := make([]string, 0)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Expected the responses to end with %q:\n%s", repl.PipelineEndSentinel, output)
+		}
+		if header == repl.PipelineEndSentinel+"\n" {
+			return frames
+		}
+		var index, length int
+		if _, err := fmt.Sscanf(header, repl.PipelineFramePrefix+"%d %d\n", &index, &length); err != nil {
+			t.Fatalf("Malformed frame header %q: %v", header, err)
+		}
+		if index != len(frames) {
+			t.Fatalf("Expected frame %d, but got frame %d", len(frames), index)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatalf("Frame %d was shorter than its length of %d", index, length)
+		}
+		frames = append(frames, string(body))
+	}
+}
+
+// Pipelines several inserts, a failing command, and a select, checking that the responses
+// are framed, in order, and written together
+func TestPipeline(t *testing.T) {
+	db := setupDatabase(t)
+	r := database.DatabaseRepl(db)
+	var input strings.Builder
+	input.WriteString("create btree table pipe\n")
+	input.WriteString(repl.TriggerPipelineMetacommand + "\n")
+	const numInserts = 5
+	for i := 0; i < numInserts; i++ {
+		fmt.Fprintf(&input, "insert %d %d into pipe\n", i, i*10)
+	}
+	input.WriteString("insert 0 0 into pipe\n")
+	input.WriteString("select from pipe\n")
+	input.WriteString(repl.PipelineEndSentinel + "\n")
+
+	output := &writeRecorder{}
+	r.Run(uuid.New(), "", strings.NewReader(input.String()), output)
+	var pipelined string
+	for _, w := range output.writes {
+		if strings.HasPrefix(w, repl.PipelineFramePrefix) {
+			pipelined = w
+		}
+	}
+	if pipelined == "" {
+		t.Fatalf("Expected the pipelined responses to be written together, but got writes %q", output.writes)
+	}
+
+	frames := readFrames(t, pipelined)
+	if len(frames) != numInserts+2 {
+		t.Fatalf("Expected %d responses, but got %d", numInserts+2, len(frames))
+	}
+	for i := 0; i < numInserts; i++ {
+		if frames[i] != "" {
+			t.Errorf("Expected insert %d to have an empty response, but got %q", i, frames[i])
+		}
+	}
+	if !strings.HasPrefix(frames[numInserts], repl.ErrorPrependStr) {
+		t.Errorf("Expected the duplicate insert to fail, but got %q", frames[numInserts])
+	}
+	var expected strings.Builder
+	for i := 0; i < numInserts; i++ {
+		expected.WriteString("(" + strconv.Itoa(i) + ", " + strconv.Itoa(i*10) + ")\n")
+	}
+	if frames[numInserts+1] != expected.String() {
+		t.Errorf("Expected the select to see every insert, but got %q", frames[numInserts+1])
+	}
+}
@@ -0,0 +1,37 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+
+	"dinodb/pkg/database"
+)
+
+// Runs select prefix through the database REPL handler, checking its rows and that hash tables are rejected
+func TestSelectPrefix(t *testing.T) {
+	db := setupDatabase(t)
+	for _, payload := range []string{"create btree table prefixed", "create hash table hashed"} {
+		if _, err := database.HandleCreateTable(db, payload); err != nil {
+			t.Fatal("Failed to create table:", err)
+		}
+	}
+	// Keys 0-3 have the 62-bit prefix 0 and keys 4-7 have the prefix 1
+	for key := range 8 {
+		if err := database.HandleInsert(db, fmt.Sprintf("insert %d 1 into prefixed", key)); err != nil {
+			t.Fatal("Failed to insert:", err)
+		}
+	}
+	result, err := database.SelectResult(db, "select prefix 1 62 from prefixed")
+	if err != nil {
+		t.Fatal("Failed to select prefix:", err)
+	}
+	if len(result.Rows) != 4 || result.Rows[0].Key != 4 || result.Rows[3].Key != 7 {
+		t.Errorf("Expected keys 4 through 7, but got %v", result.Rows)
+	}
+	if _, err := database.SelectResult(db, "select prefix 1 62 from hashed"); err == nil {
+		t.Error("Expected an error selecting a prefix from a hash table")
+	}
+	if _, err := database.SelectResult(db, "select prefix 1 0 from prefixed"); err == nil {
+		t.Error("Expected an error for a prefix of 0 bits")
+	}
+}
@@ -0,0 +1,110 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/repl"
+	"dinodb/test/utils"
+)
+
+// setupProjectionTable creates a btree table named "proj" holding the entries (i, 10*i) for i in [0, 5)
+func setupProjectionTable(t *testing.T) *database.Database {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("proj", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 5; i++ {
+		utils.InsertEntry(t, table, i, 10*i)
+	}
+	return db
+}
+
+func TestSelectProjection(t *testing.T) {
+	t.Run("Expressions", testProjectionExpressions)
+	t.Run("Identity", testProjectionIdentity)
+	t.Run("DivisionByZero", testProjectionDivisionByZero)
+	t.Run("Malformed", testProjectionMalformed)
+}
+
+// Selects several projections, checking the rendered rows
+func testProjectionExpressions(t *testing.T) {
+	db := setupProjectionTable(t)
+	tests := map[string]string{
+		"select key, value*2 from proj":                  "(0, 0)\n(1, 20)\n(2, 40)\n(3, 60)\n(4, 80)\n",
+		"select value from proj":                         "(0)\n(10)\n(20)\n(30)\n(40)\n",
+		"select key + value * 2 - 1 from proj":           "(-1)\n(20)\n(41)\n(62)\n(83)\n",
+		"select (key+1)*(key-1), -value/3, 7 from proj":  "(-1, 0, 7)\n(0, -3, 7)\n(3, -6, 7)\n(8, -10, 7)\n(15, -13, 7)\n",
+		"select value / (key + 1), key - -key from proj": "(0, 0)\n(5, 2)\n(6, 4)\n(7, 6)\n(8, 8)\n",
+	}
+	for payload, expected := range tests {
+		output, err := database.HandleSelect(db, payload)
+		if err != nil {
+			t.Errorf("%q: failed to select: %s", payload, err)
+			continue
+		}
+		if output != expected {
+			t.Errorf("%q: expected %q, but got %q", payload, expected, output)
+		}
+	}
+
+	result, err := database.SelectResult(db, "select key, value*2 from proj")
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	csv, err := result.Format(repl.CSV_FORMAT)
+	if err != nil {
+		t.Fatal("Failed to format result:", err)
+	}
+	if expected := "key,value*2\n0,0\n1,20\n2,40\n3,60\n4,80\n"; csv != expected {
+		t.Errorf("Expected CSV output %q, but got %q", expected, csv)
+	}
+}
+
+// Checks that projecting the key and value selects the same output as a plain select
+func testProjectionIdentity(t *testing.T) {
+	db := setupProjectionTable(t)
+	plain, err := database.HandleSelect(db, "select from proj")
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	projected, err := database.HandleSelect(db, "select key, value from proj")
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	if projected != plain {
+		t.Errorf("Expected the identity projection to output %q, but got %q", plain, projected)
+	}
+}
+
+// Divides by an expression that is zero for some entries, checking that the select fails
+func testProjectionDivisionByZero(t *testing.T) {
+	db := setupProjectionTable(t)
+	for _, payload := range []string{"select value / key from proj", "select key, 1 / (key - 3) from proj", "select key / 0 from proj"} {
+		_, err := database.HandleSelect(db, payload)
+		if !errors.Is(err, database.ErrDivisionByZero) {
+			t.Errorf("%q: expected to fail with %q, but got %v", payload, database.ErrDivisionByZero, err)
+		}
+	}
+}
+
+// Checks that malformed projections are rejected
+func testProjectionMalformed(t *testing.T) {
+	db := setupProjectionTable(t)
+	payloads := []string{
+		"select key + from proj",
+		"select keys from proj",
+		"select key, from proj",
+		"select (key from proj",
+		"select key) from proj",
+		"select key % 2 from proj",
+		"select key value from proj",
+	}
+	for _, payload := range payloads {
+		if _, err := database.HandleSelect(db, payload); err == nil {
+			t.Errorf("Expected %q to be rejected", payload)
+		}
+	}
+}
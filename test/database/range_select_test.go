@@ -0,0 +1,101 @@
+package database_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+
+	"github.com/google/uuid"
+)
+
+func TestSelectRange(t *testing.T) {
+	t.Run("Repl", testSelectRangeRepl)
+	t.Run("DefaultTable", testSelectRangeDefaultTable)
+	t.Run("DivisionByZero", testSelectRangeDivisionByZero)
+	t.Run("Invalid", testSelectRangeInvalid)
+}
+
+// setupSelectRangeTable creates a btree table named "ranged" holding the entries (i, i%7) for i in [0, 100)
+func setupSelectRangeTable(t *testing.T) *database.Database {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("ranged", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 100; i++ {
+		utils.InsertEntry(t, table, i, i%7)
+	}
+	return db
+}
+
+// Runs select range with and without where and limit clauses, checking the rendered rows
+func testSelectRangeRepl(t *testing.T) {
+	db := setupSelectRangeTable(t)
+	tests := map[string]string{
+		"select range 10 14 from ranged":                             "(10, 3)\n(11, 4)\n(12, 5)\n(13, 6)\n",
+		"select range 0 100 from ranged where value = 0 limit 3":     "(0, 0)\n(7, 0)\n(14, 0)\n",
+		"select range 20 40 from ranged where key * 2 >= value + 70": "(35, 0)\n(36, 1)\n(37, 2)\n(38, 3)\n(39, 4)\n",
+		"select range 0 100 from ranged where value!=key limit 2":    "(7, 0)\n(8, 1)\n",
+		"select range 95 200 from ranged limit 10":                   "(95, 4)\n(96, 5)\n(97, 6)\n(98, 0)\n(99, 1)\n",
+		"select range 0 100 from ranged where value > 6":             "",
+	}
+	for payload, expected := range tests {
+		output, err := database.HandleSelect(db, payload)
+		if err != nil {
+			t.Errorf("%q: failed to select: %s", payload, err)
+			continue
+		}
+		if output != expected {
+			t.Errorf("%q: expected %q, but got %q", payload, expected, output)
+		}
+	}
+}
+
+// Checks that a range select without a table reads the default table, ahead of its where and limit clauses
+func testSelectRangeDefaultTable(t *testing.T) {
+	db := setupSelectRangeTable(t)
+	r := database.DatabaseRepl(db)
+	script := "use ranged\nselect range 0 100 where value = 6 limit 1\n"
+	output := &bytes.Buffer{}
+	r.Run(uuid.New(), "", strings.NewReader(script), output)
+	if !strings.Contains(output.String(), "(6, 6)") || strings.Contains(output.String(), "(13, 6)") {
+		t.Errorf("Expected the range select to read only (6, 6) from the default table, but got:\n%s", output.String())
+	}
+}
+
+// Checks that an error evaluating the predicate stops the scan and is returned
+func testSelectRangeDivisionByZero(t *testing.T) {
+	db := setupSelectRangeTable(t)
+	_, err := database.SelectResult(db, "select range 0 100 from ranged where key / value > 1")
+	if !errors.Is(err, database.ErrDivisionByZero) {
+		t.Errorf("Expected %q, but got %v", database.ErrDivisionByZero, err)
+	}
+}
+
+// Checks that malformed range selects and range selects on hash tables are rejected
+func testSelectRangeInvalid(t *testing.T) {
+	db := setupSelectRangeTable(t)
+	if _, err := db.CreateTable("hashed", database.HashIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for _, payload := range []string{
+		"select range 0 100 from hashed",
+		"select range 5 5 from ranged",
+		"select range a 100 from ranged",
+		"select range 0 100 from ranged limit 0",
+		"select range 0 100 from ranged limit x",
+		"select range 0 100 from ranged where",
+		"select range 0 100 from ranged where value",
+		"select range 0 100 from ranged where value < < 3",
+		"select range 0 100 from ranged value = 3",
+		"select range 0 100 from missing",
+	} {
+		if _, err := database.SelectResult(db, payload); err == nil {
+			t.Errorf("%q: expected an error", payload)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package database_test
+
+import (
+	"fmt"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestRangeCount(t *testing.T) {
+	t.Run("Ranges", testRangeCountRanges)
+	t.Run("Deleted", testRangeCountDeleted)
+	t.Run("Invalid", testRangeCountInvalid)
+	t.Run("Repl", testRangeCountRepl)
+}
+
+// Inserts the even keys in [0, 2*numEntries) into tables of both index types,
+// checking the count of several ranges against the expected number of even keys in them
+func testRangeCountRanges(t *testing.T) {
+	const numEntries = 5000
+	ranges := []struct {
+		name       string
+		start, end int64
+		expected   int64
+	}{
+		{"Full", 0, 2 * numEntries, numEntries},
+		{"Wider", -100, 3 * numEntries, numEntries},
+		{"Empty", 2*numEntries + 1, 3 * numEntries, 0},
+		{"BeforeStart", -100, 0, 0},
+		{"Single", 42, 43, 1},
+		{"Gap", 43, 44, 0},
+		{"Prefix", 0, 1000, 500},
+		{"Middle", 1001, 7001, 3000},
+		{"Suffix", 9000, 2 * numEntries, 500},
+	}
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(string(indexType), func(t *testing.T) {
+			db := setupDatabase(t)
+			table, err := db.CreateTable("range", indexType)
+			if err != nil {
+				t.Fatal("Failed to create table:", err)
+			}
+			for i := int64(0); i < numEntries; i++ {
+				utils.InsertEntry(t, table, 2*i, i%utils.Salt)
+			}
+			for _, r := range ranges {
+				count, err := table.RangeCount(r.start, r.end)
+				if err != nil {
+					t.Fatalf("Failed to count range [%d, %d): %s", r.start, r.end, err)
+				}
+				if count != r.expected {
+					t.Errorf("%s: expected %d entries in [%d, %d), but counted %d", r.name, r.expected, r.start, r.end, count)
+				}
+			}
+		})
+	}
+}
+
+// Deletes entries from a table, checking that they are no longer counted
+func testRangeCountDeleted(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(string(indexType), func(t *testing.T) {
+			db := setupDatabase(t)
+			table, err := db.CreateTable("range", indexType)
+			if err != nil {
+				t.Fatal("Failed to create table:", err)
+			}
+			for i := int64(0); i < 1000; i++ {
+				utils.InsertEntry(t, table, i, i%utils.Salt)
+			}
+			for i := int64(100); i < 200; i++ {
+				if err := table.Delete(i); err != nil {
+					t.Fatal("Failed to delete entry:", err)
+				}
+			}
+			count, err := table.RangeCount(50, 250)
+			if err != nil {
+				t.Fatal("Failed to count range:", err)
+			}
+			if count != 100 {
+				t.Errorf("Expected 100 entries in [50, 250), but counted %d", count)
+			}
+			count, err = table.RangeCount(100, 200)
+			if err != nil {
+				t.Fatal("Failed to count range:", err)
+			}
+			if count != 0 {
+				t.Errorf("Expected no entries in [100, 200), but counted %d", count)
+			}
+		})
+	}
+}
+
+// Checks that ranges whose start isn't smaller than their end are rejected
+func testRangeCountInvalid(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(string(indexType), func(t *testing.T) {
+			db := setupDatabase(t)
+			table, err := db.CreateTable("range", indexType)
+			if err != nil {
+				t.Fatal("Failed to create table:", err)
+			}
+			if _, err := table.RangeCount(10, 10); err == nil {
+				t.Error("Expected counting a range with equal start and end to fail")
+			}
+			if _, err := table.RangeCount(10, 5); err == nil {
+				t.Error("Expected counting a range with its start after its end to fail")
+			}
+		})
+	}
+}
+
+// Counts a range through the REPL command, checking the printed count and the usage check
+func testRangeCountRepl(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("range", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 100; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	output, err := database.HandleRangeCount(db, "range count 10 30 from range")
+	if err != nil {
+		t.Fatal("Failed to count range through the REPL:", err)
+	}
+	if expected := fmt.Sprintf("%d\n", 20); output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+	if _, err := database.HandleRangeCount(db, "range count 10 from range"); err == nil {
+		t.Error("Expected a malformed range count command to fail")
+	}
+}
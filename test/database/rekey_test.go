@@ -0,0 +1,161 @@
+package database_test
+
+import (
+	"sync"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestRekey(t *testing.T) {
+	t.Run("BTree", func(t *testing.T) { testRekey(t, database.BTreeIndexType) })
+	t.Run("Hash", func(t *testing.T) { testRekey(t, database.HashIndexType) })
+	t.Run("Repl", testRekeyRepl)
+	t.Run("ConcurrentBTree", func(t *testing.T) { testRekeyConcurrent(t, database.BTreeIndexType) })
+	t.Run("ConcurrentHash", func(t *testing.T) { testRekeyConcurrent(t, database.HashIndexType) })
+	t.Run("ScanBTree", func(t *testing.T) { testRekeyScan(t, database.BTreeIndexType) })
+	t.Run("ScanHash", func(t *testing.T) { testRekeyScan(t, database.HashIndexType) })
+}
+
+// Moves every entry of a table to a new key, checking that the values follow their keys
+// and that rekeying a missing key or onto an existing key fails without changing the table
+func testRekey(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("rekey", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	const numEntries = 1000
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i*utils.Salt)
+	}
+	for i := int64(0); i < numEntries; i++ {
+		if err := table.Rekey(i, i+numEntries); err != nil {
+			t.Fatalf("Failed to rekey %d: %v", i, err)
+		}
+	}
+	for i := int64(0); i < numEntries; i++ {
+		utils.CheckFindEntry(t, table, i+numEntries, i*utils.Salt)
+		if _, err := table.Find(i); err == nil {
+			t.Errorf("Expected key %d to not be present after rekeying", i)
+		}
+	}
+
+	if err := table.Rekey(0, 1); err == nil {
+		t.Error("Expected rekeying a missing key to fail")
+	}
+	if err := table.Rekey(numEntries, numEntries+1); err == nil {
+		t.Error("Expected rekeying onto an existing key to fail")
+	}
+	if err := table.Rekey(numEntries, numEntries); err == nil {
+		t.Error("Expected rekeying a key onto itself to fail")
+	}
+	utils.CheckFindEntry(t, table, numEntries, 0)
+	utils.CheckFindEntry(t, table, numEntries+1, utils.Salt)
+}
+
+// Rekeys through the repl, checking malformed commands are rejected
+func testRekeyRepl(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("rekey", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 10)
+	if err := database.HandleRekey(db, "rekey rekey 1 2"); err != nil {
+		t.Fatal("Failed to rekey through the repl:", err)
+	}
+	utils.CheckFindEntry(t, table, 2, 10)
+	if _, err := table.Find(1); err == nil {
+		t.Error("Expected key 1 to not be present after rekeying")
+	}
+	for _, payload := range []string{"rekey rekey 2", "rekey rekey two 3", "rekey missing 2 3"} {
+		if err := database.HandleRekey(db, payload); err == nil {
+			t.Errorf("Expected %q to fail", payload)
+		}
+	}
+}
+
+// Races two rekeys of the same key onto different keys, checking that exactly one of them moves the entry
+// and that the table never ends up with both new keys
+func testRekeyConcurrent(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("rekey", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	const numEntries, numRounds = 1000, 200
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+	for round := int64(0); round < numRounds; round++ {
+		oldKey, newKeys := round, [2]int64{numEntries + 2*round, numEntries + 2*round + 1}
+		var errs [2]error
+		var wg sync.WaitGroup
+		for i := range newKeys {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = table.Rekey(oldKey, newKeys[i])
+			}()
+		}
+		wg.Wait()
+		if (errs[0] == nil) == (errs[1] == nil) {
+			t.Fatalf("Expected exactly one rekey of %d to succeed, but got errors %v", oldKey, errs)
+		}
+		for i, newKey := range newKeys {
+			_, err := table.Find(newKey)
+			if found := err == nil; found != (errs[i] == nil) {
+				t.Errorf("Expected key %d to be present only if its rekey succeeded, but found it: %t", newKey, found)
+			}
+		}
+		if _, err := table.Find(oldKey); err == nil {
+			t.Errorf("Expected key %d to not be present after rekeying", oldKey)
+		}
+	}
+}
+
+// Rekeys entries while another goroutine selects the whole table, checking that
+// no scan sees a moved entry under both keys or under neither
+func testRekeyScan(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("rekey", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	const numEntries = 1000
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+	done := make(chan struct{})
+	var scanner sync.WaitGroup
+	scanner.Add(1)
+	go func() {
+		defer scanner.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			entries, err := table.Select()
+			if err != nil {
+				t.Errorf("Failed to select: %v", err)
+				return
+			}
+			if len(entries) != numEntries {
+				t.Errorf("Expected a scan during rekeys to see %d entries, but it saw %d", numEntries, len(entries))
+				return
+			}
+		}
+	}()
+	for i := int64(0); i < numEntries; i++ {
+		if err := table.Rekey(i, i+numEntries); err != nil {
+			t.Errorf("Failed to rekey %d: %v", i, err)
+			break
+		}
+	}
+	close(done)
+	scanner.Wait()
+}
@@ -0,0 +1,56 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+// Deletes a hash table's .meta file while the database is closed, checking that the repair command
+// rebuilds its directory so that every entry is findable again
+func TestRepairHashTable(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 2000; i++ {
+		utils.InsertEntry(t, table, i, i*2)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+	if err := os.Remove(filepath.Join(db.GetBasePath(), "t.meta")); err != nil {
+		t.Fatal("Failed to delete the .meta file:", err)
+	}
+	db, err = database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	defer db.Close()
+
+	if _, err := database.HandleRepair(db, "repair btree t"); err == nil {
+		t.Error("Expected repairing a B+Tree to be a usage error")
+	}
+	output, err := database.HandleRepair(db, "repair hash t")
+	if err != nil {
+		t.Fatal("Failed to repair the table:", err)
+	}
+	if !strings.HasPrefix(output, "rebuilt the directory of t") {
+		t.Errorf("Unexpected repair output %q", output)
+	}
+	table, err = db.GetTable("t")
+	if err != nil {
+		t.Fatal("Failed to get the repaired table:", err)
+	}
+	if err := database.VerifyIndex(table); err != nil {
+		t.Error("Expected the repaired table to verify, but got", err)
+	}
+	for i := int64(0); i < 2000; i++ {
+		utils.CheckFindEntry(t, table, i, i*2)
+	}
+}
@@ -0,0 +1,81 @@
+package database_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
+	"dinodb/pkg/repl"
+	"dinodb/test/utils"
+)
+
+func TestResult(t *testing.T) {
+	t.Run("Find", testResultFind)
+	t.Run("Select", testResultSelect)
+}
+
+// Finds an entry, checking that the structured result holds it as its only row
+// and that the human rendering matches HandleFind's output
+func testResultFind(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("result", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 2)
+
+	result, err := database.FindResult(db, "find 1 from result")
+	if err != nil {
+		t.Fatal("Failed to find entry:", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0] != entry.New(1, 2) {
+		t.Errorf("Expected the result's rows to be [(1, 2)], but got %v", result.Rows)
+	}
+	output, err := database.HandleFind(db, "find 1 from result")
+	if err != nil {
+		t.Fatal("Failed to find entry:", err)
+	}
+	human, err := result.Format(repl.HUMAN_FORMAT)
+	if err != nil {
+		t.Fatal("Failed to format result:", err)
+	}
+	if output != "found entry: (1, 2)\n" || human != output {
+		t.Errorf("Expected both renderings to be %q, but got %q and %q", "found entry: (1, 2)\n", output, human)
+	}
+	if _, err := database.FindResult(db, "find 3 from result"); err == nil {
+		t.Error("Expected finding a missing key to fail")
+	}
+}
+
+// Selects from a table, checking that the structured result holds every entry in key order
+// and that the CSV rendering lists them
+func testResultSelect(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("result", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(2); i >= 0; i-- {
+		utils.InsertEntry(t, table, i, i*10)
+	}
+
+	result, err := database.SelectResult(db, "select from result")
+	if err != nil {
+		t.Fatal("Failed to select entries:", err)
+	}
+	if len(result.Rows) != 3 {
+		t.Fatalf("Expected 3 rows, but got %v", result.Rows)
+	}
+	for i, row := range result.Rows {
+		if row != entry.New(int64(i), int64(i)*10) {
+			t.Errorf("Expected row %d to be (%d, %d), but got (%d, %d)", i, i, i*10, row.Key, row.Value)
+		}
+	}
+	csv, err := result.Format(repl.CSV_FORMAT)
+	if err != nil {
+		t.Fatal("Failed to format result:", err)
+	}
+	if expected := "key,value\n0,0\n1,10\n2,20\n"; csv != expected {
+		t.Errorf("Expected CSV output %q, but got %q", expected, csv)
+	}
+}
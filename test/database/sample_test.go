@@ -0,0 +1,101 @@
+package database_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/entry"
+	"dinodb/test/utils"
+)
+
+func TestSelectSample(t *testing.T) {
+	t.Run("BTree", func(t *testing.T) { testSelectSample(t, database.BTreeIndexType) })
+	t.Run("Hash", func(t *testing.T) { testSelectSample(t, database.HashIndexType) })
+	t.Run("Reproducible", testSelectSampleReproducible)
+	t.Run("Repl", testSelectSampleRepl)
+}
+
+// Samples tables with various sample sizes, checking the size of each sample
+// and that every sampled entry is a distinct entry of the table
+func testSelectSample(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("sample", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	entries, answerKey := utils.GenerateRandomKeyValuePairs(1000)
+	for _, e := range entries {
+		utils.InsertEntry(t, table, e.Key, e.Val)
+	}
+	rng := rand.New(rand.NewSource(utils.Salt))
+	for _, n := range []int{0, 1, 50, 999, 1000, 5000} {
+		sample, err := database.SelectSample(table, n, rng)
+		if err != nil {
+			t.Fatal("Failed to sample table:", err)
+		}
+		if expected := min(n, len(entries)); len(sample) != expected {
+			t.Errorf("Expected a sample of %d entries, but got %d", expected, len(sample))
+		}
+		seen := make(map[int64]bool)
+		for _, e := range sample {
+			if v, ok := answerKey[e.Key]; !ok || v != e.Value {
+				t.Fatalf("Sampled entry %v is not in the table", e)
+			}
+			if seen[e.Key] {
+				t.Fatalf("Sampled key %d more than once", e.Key)
+			}
+			seen[e.Key] = true
+		}
+	}
+	if _, err := database.SelectSample(table, -1, rng); err == nil {
+		t.Error("Expected a negative sample size to fail")
+	}
+}
+
+// Samples a table twice with the same seed, checking the samples match
+func testSelectSampleReproducible(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("sample", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 1000; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+	samples := make([][]entry.Entry, 2)
+	for i := range samples {
+		samples[i], err = database.SelectSample(table, 20, rand.New(rand.NewSource(utils.Salt)))
+		if err != nil {
+			t.Fatal("Failed to sample table:", err)
+		}
+	}
+	for i := range samples[0] {
+		if samples[0][i] != samples[1][i] {
+			t.Fatalf("Expected samples with the same seed to match, but got %v and %v", samples[0], samples[1])
+		}
+	}
+}
+
+// Samples through the repl, checking the number of lines of output
+func testSelectSampleRepl(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("sample", database.HashIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 100; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+	output, err := database.HandleSelect(db, "select sample 10 from sample")
+	if err != nil {
+		t.Fatal("Failed to sample table:", err)
+	}
+	if numLines := strings.Count(output, "\n"); numLines != 10 {
+		t.Errorf("Expected 10 sampled entries, but got %d:\n%s", numLines, output)
+	}
+	if _, err := database.HandleSelect(db, "select sample ten from sample"); err == nil {
+		t.Error("Expected a non-numeric sample size to fail")
+	}
+}
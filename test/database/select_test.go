@@ -0,0 +1,43 @@
+package database_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/database"
+)
+
+func TestSelectEmpty(t *testing.T) {
+	t.Run("BTree", func(t *testing.T) { testSelectEmpty(t, database.BTreeIndexType) })
+	t.Run("Hash", func(t *testing.T) { testSelectEmpty(t, database.HashIndexType) })
+}
+
+// Selects from a newly created table, checking that no entries and no error are returned
+func testSelectEmpty(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("empty", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+
+	entries, err := table.Select()
+	if err != nil {
+		t.Fatal("Select on an empty table should not error, but got:", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries from an empty table, but got %d", len(entries))
+	}
+	output, err := database.HandleSelect(db, "select from empty")
+	if err != nil {
+		t.Fatal("Select on an empty table should not error, but got:", err)
+	}
+	if output != "" {
+		t.Errorf("Expected no output from an empty table, but got %q", output)
+	}
+	count, checksum, err := database.Digest(table)
+	if err != nil {
+		t.Fatal("Digest on an empty table should not error, but got:", err)
+	}
+	if count != 0 || checksum != 0 {
+		t.Errorf("Expected an empty table to have an empty digest, but got (%d, %x)", count, checksum)
+	}
+}
@@ -0,0 +1,106 @@
+package database_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/btree"
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+
+	"github.com/google/uuid"
+)
+
+func TestTrace(t *testing.T) {
+	t.Run("FindDescent", testTraceFindDescent)
+	t.Run("Off", testTraceOff)
+}
+
+// traceLines returns the page accesses listed in a command's output, after its "trace:" header
+func traceLines(t *testing.T, output string) []string {
+	_, trace, found := strings.Cut(output, "trace: ")
+	if !found {
+		t.Fatalf("Expected the output to hold a trace, but got:\n%s", output)
+	}
+	lines := strings.Split(strings.TrimSpace(trace), "\n")[1:]
+	accesses := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasSuffix(line, " of t") {
+			break
+		}
+		accesses = append(accesses, line)
+	}
+	return accesses
+}
+
+// Traces a find in a B+Tree with more than one level, checking that the trace gets each page
+// from the root down to the leaf, as ExplainFind describes, and puts each one back
+func testTraceFindDescent(t *testing.T) {
+	db := setupDatabase(t)
+	index, err := db.CreateTable("t", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	numEntries := 4 * btree.ENTRIES_PER_LEAF_NODE
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, index, i, i%utils.Salt)
+	}
+	key := numEntries - 1
+	plan, err := index.(*btree.BTreeIndex).ExplainFind(key)
+	if err != nil {
+		t.Fatal("Failed to explain find:", err)
+	}
+	if len(plan.Path) < 2 {
+		t.Fatalf("Expected the B+Tree to have more than one level, but the path is %v", plan.Path)
+	}
+
+	r := database.DatabaseRepl(db)
+	output := &bytes.Buffer{}
+	r.Run(uuid.New(), "", strings.NewReader(fmt.Sprintf(".trace on\nfind %d from t\n", key)), output)
+	if !strings.Contains(output.String(), fmt.Sprintf("(%d, %d)", key, key%utils.Salt)) {
+		t.Errorf("Expected the find's result before its trace, but got:\n%s", output.String())
+	}
+	gets, puts := make([]string, 0), make(map[string]int)
+	for _, access := range traceLines(t, output.String()) {
+		op, page, _ := strings.Cut(access, " ")
+		switch op {
+		case "get":
+			gets = append(gets, page)
+			puts[page]++
+		case "put":
+			puts[page]--
+		}
+	}
+	expected := make([]string, len(plan.Path))
+	for i, pn := range plan.Path {
+		expected[i] = fmt.Sprintf("page %d of t", pn)
+	}
+	if strings.Join(gets, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected the find to get %v from the root down, but it got %v", expected, gets)
+	}
+	for page, unmatched := range puts {
+		if unmatched != 0 {
+			t.Errorf("Expected every get of %s to be matched by a put", page)
+		}
+	}
+}
+
+// Checks that commands aren't traced until tracing is turned on, or after it is turned off
+func testTraceOff(t *testing.T) {
+	db := setupDatabase(t)
+	if _, err := db.CreateTable("t", database.BTreeIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	r := database.DatabaseRepl(db)
+	output := &bytes.Buffer{}
+	r.Run(uuid.New(), "", strings.NewReader("insert 1 1 into t\n.trace on\n.trace off\nfind 1 from t\n.trace\n"), output)
+	if strings.Contains(output.String(), "trace: ") {
+		t.Errorf("Expected no trace while tracing is off, but got:\n%s", output.String())
+	}
+	if !strings.Contains(output.String(), "tracing: off") {
+		t.Errorf("Expected .trace to report that tracing is off, but got:\n%s", output.String())
+	}
+}
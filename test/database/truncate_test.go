@@ -0,0 +1,112 @@
+package database_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+func TestTruncateTable(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		t.Run(indexType.String(), func(t *testing.T) { testTruncateTable(t, indexType) })
+	}
+	t.Run("OpenCursor", testTruncateOpenCursor)
+}
+
+// checkEmptyTable errors the test if the table has any entries or isn't structurally valid
+func checkEmptyTable(t *testing.T, table database.Index) {
+	entries, err := table.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the table to be empty, but found %d entries", len(entries))
+	}
+	if err := database.VerifyIndex(table); err != nil {
+		t.Error("Expected a valid index:", err)
+	}
+}
+
+// Truncates a populated table through the repl, checking that it is empty, valid, smaller on disk,
+// and reusable afterwards, including after reopening the database
+func testTruncateTable(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	numPagesEmpty := table.GetPager().GetNumPages()
+	for i := int64(0); i < 5000; i++ {
+		utils.InsertEntry(t, table, i, i)
+	}
+	if _, err := database.HandleTruncate(db, "truncate"); err == nil {
+		t.Error("Expected a usage error")
+	}
+	output, err := database.HandleTruncate(db, "truncate t")
+	if err != nil {
+		t.Fatal("Failed to truncate table:", err)
+	}
+	if output != "table t truncated.\n" {
+		t.Errorf("Unexpected output %q", output)
+	}
+	checkEmptyTable(t, table)
+	if n := table.GetPager().GetNumPages(); n != numPagesEmpty {
+		t.Errorf("Expected the truncated table to have %d pages like a new one, but it has %d", numPagesEmpty, n)
+	}
+	if _, err := table.Find(1); err == nil {
+		t.Error("Expected truncated entries to be gone")
+	}
+
+	// The same table should be reusable, and stay so after reopening.
+	for i := int64(0); i < 3000; i++ {
+		utils.InsertEntry(t, table, i, -i)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+	db, err = database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	defer db.Close()
+	table, err = db.GetTable("t")
+	if err != nil {
+		t.Fatal("Failed to reopen table:", err)
+	}
+	count, _, err := database.Digest(table)
+	if err != nil {
+		t.Fatal("Failed to digest table:", err)
+	}
+	if count != 3000 {
+		t.Errorf("Expected 3000 entries after reopening, but found %d", count)
+	}
+	for i := int64(0); i < 3000; i++ {
+		utils.CheckFindEntry(t, table, i, -i)
+	}
+	if err := database.VerifyIndex(table); err != nil {
+		t.Error("Expected a valid index after reopening:", err)
+	}
+}
+
+// Checks that truncating a table with an open cursor fails and leaves its entries in place
+func testTruncateOpenCursor(t *testing.T) {
+	db := setupDatabase(t)
+	table, err := db.CreateTable("t", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	utils.InsertEntry(t, table, 1, 1)
+	c, err := table.CursorAtStart()
+	if err != nil {
+		t.Fatal("Failed to open cursor:", err)
+	}
+	if err := table.Truncate(); err == nil {
+		t.Error("Expected truncating a table with an open cursor to fail")
+	}
+	c.Close()
+	utils.CheckFindEntry(t, table, 1, 1)
+	if err := table.Truncate(); err != nil {
+		t.Error("Failed to truncate table once its cursor was closed:", err)
+	}
+}
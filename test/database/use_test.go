@@ -0,0 +1,70 @@
+package database_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+
+	"github.com/google/uuid"
+)
+
+// Runs commands after use, checking that those without a table go to the default table
+// while those naming a table still go to it
+func TestUseDefaultTable(t *testing.T) {
+	db := setupDatabase(t)
+	r := database.DatabaseRepl(db)
+	script := strings.Join([]string{
+		"create btree table a",
+		"create btree table b",
+		"use a",
+		"insert 5 10",
+		"insert 6 12",
+		"insert 7 14 into b",
+		"delete 6",
+		"find 5",
+		"select",
+		"use missing",
+	}, "\n") + "\n"
+	output := &bytes.Buffer{}
+	r.Run(uuid.New(), "", strings.NewReader(script), output)
+
+	a, err := db.GetTable("a")
+	if err != nil {
+		t.Fatal("Failed to get table a:", err)
+	}
+	b, err := db.GetTable("b")
+	if err != nil {
+		t.Fatal("Failed to get table b:", err)
+	}
+	utils.CheckFindEntry(t, a, 5, 10)
+	if _, err := a.Find(6); err == nil {
+		t.Error("Expected 6 to be deleted from the default table")
+	}
+	if _, err := a.Find(7); err == nil {
+		t.Error("Expected 7 to be inserted into b rather than the default table")
+	}
+	utils.CheckFindEntry(t, b, 7, 14)
+	if !strings.Contains(output.String(), "(5, 10)") {
+		t.Errorf("Expected find and select to read the default table, but got:\n%s", output.String())
+	}
+	if !strings.Contains(output.String(), "use error") {
+		t.Errorf("Expected use of a missing table to fail, but got:\n%s", output.String())
+	}
+}
+
+// Checks that commands without a table still fail when the client has no default table
+func TestNoDefaultTable(t *testing.T) {
+	db := setupDatabase(t)
+	if _, err := db.CreateTable("a", database.BTreeIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	r := database.DatabaseRepl(db)
+	output := &bytes.Buffer{}
+	r.Run(uuid.New(), "", strings.NewReader("find 1\n"), output)
+	if !strings.Contains(output.String(), "usage") {
+		t.Errorf("Expected find without a table or default table to fail, but got:\n%s", output.String())
+	}
+}
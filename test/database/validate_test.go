@@ -0,0 +1,62 @@
+package database_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/repl"
+)
+
+// Validates a script with several malformed lines, checking that each is reported with its line number
+// and that nothing in the script is run
+func TestValidateScript(t *testing.T) {
+	db := setupDatabase(t)
+	r := database.DatabaseRepl(db)
+	script := strings.Join([]string{
+		"create btree table t",
+		"insert 1 10 into t",
+		"insert (2 20) (3 30) into t",
+		"",
+		"insert 4 into t",   // 5: missing value
+		"fnid 1 from t",     // 6: unknown command
+		"find 1 from",       // 7: missing table
+		".pipeline",         // 8
+		"update t 1 11",     // 9
+		".pipeline",         // 10: nested pipeline
+		".end",              // 11
+		".end",              // 12: not in a pipeline
+		".format yaml",      // 13: unknown format
+		"insert x 1 into t", // 14: key isn't a number
+		"select from t",
+		"delete 1 from t",
+	}, "\n")
+
+	errs := r.ValidateScript(strings.NewReader(script))
+	expectedLines := []int{5, 6, 7, 10, 12, 13, 14}
+	if len(errs) != len(expectedLines) {
+		t.Fatalf("Expected %d errors, but got %d: %v", len(expectedLines), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Line != expectedLines[i] {
+			t.Errorf("Expected error %d to be on line %d, but it was on line %d: %v", i, expectedLines[i], err.Line, err)
+		}
+		if !strings.HasPrefix(err.Error(), "line ") {
+			t.Errorf("Expected error %q to start with its line number", err.Error())
+		}
+	}
+	if !errors.Is(errs[1].Err, repl.ErrCommandNotFound) {
+		t.Errorf("Expected an unknown command error, got %v", errs[1].Err)
+	}
+	if !strings.Contains(errs[2].Error(), "usage: find <key> from <table>") {
+		t.Errorf("Expected the error for a malformed find to give its usage, got %v", errs[2])
+	}
+	if _, err := db.GetTable("t"); err == nil {
+		t.Error("Expected validating the script not to run it")
+	}
+
+	if errs := r.ValidateScript(strings.NewReader("create hash table t\nfind 1 from t\n")); len(errs) != 0 {
+		t.Errorf("Expected a valid script to have no errors, but got %v", errs)
+	}
+}
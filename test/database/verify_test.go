@@ -0,0 +1,78 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+// Runs the verify chains command on a btree table, a hash table, and with bad arguments
+func TestVerifyChainsRepl(t *testing.T) {
+	db := setupDatabase(t)
+	btreeTable, err := db.CreateTable("btree", database.BTreeIndexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	if _, err := db.CreateTable("hash", database.HashIndexType); err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 2000; i++ {
+		utils.InsertEntry(t, btreeTable, i, i)
+	}
+	output, err := database.HandleVerify(db, "verify chains btree")
+	if err != nil {
+		t.Errorf("Expected the btree's chain to verify, but got %v", err)
+	} else if output != "sibling chain ok\n" {
+		t.Errorf("Unexpected output %q", output)
+	}
+	if _, err := database.HandleVerify(db, "verify chains hash"); err == nil {
+		t.Error("Expected verifying a hash table's chain to fail")
+	}
+	if _, err := database.HandleVerify(db, "verify btree"); err == nil {
+		t.Error("Expected a usage error")
+	}
+}
+
+// Verifies valid btree and hash tables, then moves an entry into the wrong bucket and checks that verification fails
+func TestVerifyIndex(t *testing.T) {
+	db := setupDatabase(t)
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		table, err := db.CreateTable(indexType.String(), indexType)
+		if err != nil {
+			t.Fatal("Failed to create table:", err)
+		}
+		for i := int64(0); i < 2000; i++ {
+			utils.InsertEntry(t, table, i, i)
+		}
+		if err := database.VerifyIndex(table); err != nil {
+			t.Errorf("Expected the %s table to verify, but got %v", indexType, err)
+		}
+	}
+
+	index, err := db.GetTable(database.HashIndexType.String())
+	if err != nil {
+		t.Fatal("Failed to get table:", err)
+	}
+	table := index.(*hash.HashIndex).GetTable()
+	key := int64(5000)
+	buckets := table.GetBuckets()
+	rightPN := buckets[table.GetHasher()(key, table.GetDepth())]
+	for _, pn := range buckets {
+		if pn == rightPN {
+			continue
+		}
+		bucket, err := table.GetBucketByPN(pn)
+		if err != nil {
+			t.Fatal("Failed to get bucket:", err)
+		}
+		bucket.Insert(key, key)
+		table.GetPager().PutPage(bucket.GetPage())
+		break
+	}
+	if err := database.VerifyIndex(index); !errors.Is(err, database.ErrInvalidIndex) {
+		t.Errorf("Expected an entry in the wrong bucket to fail verification with %q, but got %v", database.ErrInvalidIndex, err)
+	}
+}
@@ -0,0 +1,105 @@
+package database_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+	"dinodb/test/utils"
+)
+
+// reopenTable closes the database and reopens the given table with an empty buffer
+func reopenTable(t *testing.T, db *database.Database, tableName string) (*database.Database, database.Index) {
+	if err := db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+	db, err := database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Failed to reopen database:", err)
+	}
+	table, err := db.GetTable(tableName)
+	if err != nil {
+		t.Fatal("Failed to reopen table:", err)
+	}
+	return db, table
+}
+
+// findAll finds every key in the answer key, returning the number of pages read from disk while doing so
+func findAll(t *testing.T, table database.Index, answerKey map[int64]int64) int64 {
+	before := table.GetPager().GetNumDiskReads()
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, table, k, v)
+	}
+	return table.GetPager().GetNumDiskReads() - before
+}
+
+func TestWarmup(t *testing.T) {
+	t.Run("BTree", func(t *testing.T) { testWarmup(t, database.BTreeIndexType) })
+	t.Run("Hash", func(t *testing.T) { testWarmup(t, database.HashIndexType) })
+	t.Run("BTreeCapacity", func(t *testing.T) { testWarmupCapacity(t, database.BTreeIndexType) })
+	t.Run("HashCapacity", func(t *testing.T) { testWarmupCapacity(t, database.HashIndexType) })
+}
+
+// Checks that finds on a warmed up table (small enough to fit in the buffer) read fewer pages from disk than finds on a cold table
+func testWarmup(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	tableName := "warmup"
+	table, err := db.CreateTable(tableName, indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	entries, answerKey := utils.GenerateRandomKeyValuePairs(2000)
+	for _, e := range entries {
+		utils.InsertEntry(t, table, e.Key, e.Val)
+	}
+
+	// Find all entries starting with a cold buffer
+	db, table = reopenTable(t, db, tableName)
+	coldReads := findAll(t, table, answerKey)
+
+	// Find all entries after warming up the buffer
+	db, table = reopenTable(t, db, tableName)
+	err = database.HandleWarmup(db, "warmup "+tableName)
+	if err != nil {
+		t.Fatal("Failed to warm up table:", err)
+	}
+	if table.GetPager().GetNumDiskReads() == 0 {
+		t.Error("Expected warming up to read pages from disk")
+	}
+	warmReads := findAll(t, table, answerKey)
+	if warmReads >= coldReads {
+		t.Errorf("Expected finds after warming up to read fewer pages than the %d read when cold, but read %d", coldReads, warmReads)
+	}
+	if err = db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+}
+
+// Checks that warming up a table larger than the buffer stops once the buffer is full,
+// and doesn't leave any pages pinned
+func testWarmupCapacity(t *testing.T, indexType database.IndexType) {
+	db := setupDatabase(t)
+	tableName := "warmup"
+	table, err := db.CreateTable(tableName, indexType)
+	if err != nil {
+		t.Fatal("Failed to create table:", err)
+	}
+	for i := int64(0); i < 10000; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	db, table = reopenTable(t, db, tableName)
+	if table.GetPager().GetNumPages() <= config.MaxPagesInBuffer {
+		t.Fatalf("Expected table to have more than %d pages, but it has %d", config.MaxPagesInBuffer, table.GetPager().GetNumPages())
+	}
+
+	if err = table.Warmup(); err != nil {
+		t.Fatal("Failed to warm up table:", err)
+	}
+	if reads := table.GetPager().GetNumDiskReads(); reads > config.MaxPagesInBuffer {
+		t.Errorf("Expected warming up to read at most %d pages, but read %d", config.MaxPagesInBuffer, reads)
+	}
+	// Closing fails if any pages are still pinned
+	if err = db.Close(); err != nil {
+		t.Fatal("Failed to close database:", err)
+	}
+}
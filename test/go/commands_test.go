@@ -0,0 +1,106 @@
+package go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
+)
+
+func TestReplCommands(t *testing.T) {
+	t.Run("Listing", testCommandsListing)
+	t.Run("MetaCommand", testCommandsMetaCommand)
+	t.Run("SpecValidates", testCommandsSpecValidates)
+	t.Run("Combine", testCommandsCombine)
+}
+
+// newSpecRepl returns a REPL with two commands registered with specs and one without
+func newSpecRepl() *repl.REPL {
+	r := repl.NewRepl()
+	r.AddCommandWithSpec("find", f1, "Find an element. usage: find <key> from <table>",
+		repl.ArgSpec{Args: []string{"key", "from", "table"}, NumFields: []int{4}})
+	r.AddCommandWithSpec("echo", echo, "prints back everything", repl.ArgSpec{Args: []string{"text..."}})
+	r.AddCommand("plain", f2, "plain help")
+	return r
+}
+
+// checkListing errors the test if the listing doesn't describe exactly the commands of newSpecRepl, in order of trigger
+func checkListing(t *testing.T, infos []repl.CommandInfo) {
+	triggers := make([]string, 0, len(infos))
+	for _, info := range infos {
+		triggers = append(triggers, info.Trigger)
+	}
+	if expected := []string{"echo", "find", "plain"}; !slices.Equal(triggers, expected) {
+		t.Fatalf("Expected the commands %v, but got %v", expected, triggers)
+	}
+	echoInfo, findInfo, plainInfo := infos[0], infos[1], infos[2]
+	if findInfo.Help != "Find an element. usage: find <key> from <table>" || findInfo.Spec == nil ||
+		!slices.Equal(findInfo.Spec.Args, []string{"key", "from", "table"}) || !slices.Equal(findInfo.Spec.NumFields, []int{4}) {
+		t.Errorf("Expected find's help and spec, but got %+v", findInfo)
+	}
+	if echoInfo.Spec == nil || !slices.Equal(echoInfo.Spec.Args, []string{"text..."}) || len(echoInfo.Spec.NumFields) != 0 {
+		t.Errorf("Expected echo's spec without field counts, but got %+v", echoInfo)
+	}
+	if plainInfo.Help != "plain help" || plainInfo.Spec != nil {
+		t.Errorf("Expected plain's help and no spec, but got %+v", plainInfo)
+	}
+}
+
+// Checks that the listing describes every registered command, with the specs of those registered with one
+func testCommandsListing(t *testing.T) {
+	checkListing(t, newSpecRepl().Commands())
+}
+
+// Runs the .commands meta-command, checking that it writes the listing as a line of JSON
+func testCommandsMetaCommand(t *testing.T) {
+	r := newSpecRepl()
+	output := &bytes.Buffer{}
+	r.Run(uuid.New(), "", strings.NewReader(repl.TriggerCommandsMetacommand+"\n"), output)
+	var line string
+	for _, l := range strings.Split(output.String(), "\n") {
+		if strings.HasPrefix(l, "[") {
+			line = l
+		}
+	}
+	var infos []repl.CommandInfo
+	if err := json.Unmarshal([]byte(line), &infos); err != nil {
+		t.Fatalf("Expected a JSON listing, but got %q: %v", output.String(), err)
+	}
+	checkListing(t, infos)
+	if strings.Contains(line, `"spec":null`) {
+		t.Error("Expected commands without a spec to leave it out of the listing")
+	}
+}
+
+// Checks that a spec's field counts validate scripts, and that re-adding a command without a spec drops it
+func testCommandsSpecValidates(t *testing.T) {
+	r := newSpecRepl()
+	script := "find 5 from t\nfind 5\n.commands\necho anything at all\n"
+	errs := r.ValidateScript(strings.NewReader(script))
+	if len(errs) != 1 || errs[0].Line != 2 || !strings.Contains(errs[0].Error(), "usage: find <key> from <table>") {
+		t.Errorf("Expected only line 2 to be rejected with find's usage, but got %v", errs)
+	}
+	r.AddCommand("echo", echo, "prints back everything")
+	if _, ok := r.GetSpecs()["echo"]; ok {
+		t.Error("Expected re-adding echo without a spec to drop its spec")
+	}
+}
+
+// Checks that combining REPLs keeps their commands' specs
+func testCommandsCombine(t *testing.T) {
+	other := repl.NewRepl()
+	other.AddCommandWithSpec("other", f3, "other help", repl.ArgSpec{Args: []string{"x"}, NumFields: []int{2}})
+	combined, err := repl.CombineRepls([]*repl.REPL{newSpecRepl(), other})
+	if err != nil {
+		t.Fatal("Failed to combine REPLs:", err)
+	}
+	specs := combined.GetSpecs()
+	if len(specs) != 3 || !slices.Equal(specs["other"].Args, []string{"x"}) || !slices.Equal(specs["find"].NumFields, []int{4}) {
+		t.Errorf("Expected the combined REPL to keep all 3 specs, but got %v", specs)
+	}
+}
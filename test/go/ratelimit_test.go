@@ -0,0 +1,66 @@
+package go_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
+)
+
+// runLimitedRepl runs a repl with a "ping" command on the given input until EOF,
+// returning how many commands succeeded and how many were rate limited
+func runLimitedRepl(limiter *repl.RateLimiter, input string) (succeeded, limited int) {
+	r := repl.NewRepl()
+	r.AddCommand("ping", func(string, *repl.REPLConfig) (string, error) { return "pong", nil }, "")
+	output := new(bytes.Buffer)
+	r.RunWithLimiter(uuid.New(), "", strings.NewReader(input), output, limiter)
+	return strings.Count(output.String(), "pong"), strings.Count(output.String(), repl.ErrRateLimited.Error())
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("Burst", testRateLimitBurst)
+	t.Run("Refill", testRateLimitRefill)
+	t.Run("NoLimiter", testRateLimitNoLimiter)
+}
+
+// Sends commands much faster than the limit, checking that the first burst
+// succeeds and the rest are rejected without being run
+func testRateLimitBurst(t *testing.T) {
+	numCommands := 50
+	succeeded, limited := runLimitedRepl(repl.NewRateLimiter(5), strings.Repeat("ping\n", numCommands))
+	if succeeded < 5 || succeeded >= numCommands {
+		t.Errorf("Expected at least the 5 command burst to succeed and some commands to fail, but %d succeeded", succeeded)
+	}
+	if succeeded+limited != numCommands {
+		t.Errorf("Expected each of %d commands to succeed or be rate limited, but %d succeeded and %d were limited",
+			numCommands, succeeded, limited)
+	}
+}
+
+// Exhausts a limiter, checking that it allows commands again once tokens refill
+func testRateLimitRefill(t *testing.T) {
+	limiter := repl.NewRateLimiter(20)
+	for limiter.Allow() {
+	}
+	if limiter.Allow() {
+		t.Fatal("Expected an exhausted limiter to reject commands")
+	}
+	time.Sleep(200 * time.Millisecond)
+	succeeded, _ := runLimitedRepl(limiter, "ping\nping\n")
+	if succeeded == 0 {
+		t.Error("Expected commands to be allowed after the limiter refilled")
+	}
+}
+
+// Checks that running without a limiter never rejects commands
+func testRateLimitNoLimiter(t *testing.T) {
+	numCommands := 100
+	succeeded, limited := runLimitedRepl(nil, strings.Repeat("ping\n", numCommands))
+	if succeeded != numCommands || limited != 0 {
+		t.Errorf("Expected all %d commands to succeed, but %d succeeded and %d were limited", numCommands, succeeded, limited)
+	}
+}
@@ -1,11 +1,16 @@
 package go_test
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
 	"strings"
 	"testing"
 
 	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
 )
 
 func f1(s string, _ *repl.REPLConfig) (string, error) { return "", nil }
@@ -19,6 +24,7 @@ func TestRepl(t *testing.T) {
 	t.Run("Add", testAdd)
 	t.Run("HelpString", testHelpString)
 	t.Run("CombineZeroRepl", testCombineZeroRepl)
+	t.Run("CombineNilRepl", testCombineNilRepl)
 }
 
 // Tests that a newly REPL doesn’t contain any commands other than the metacommands.
@@ -116,12 +122,27 @@ func testCombineZeroRepl(t *testing.T) {
 	}
 }
 
+// Tests that combining a nil REPL fails cleanly rather than panicking
+func testCombineNilRepl(t *testing.T) {
+	r := repl.NewRepl()
+	r.AddCommand("1", f1, "1 help")
+	combined, err := repl.CombineRepls([]*repl.REPL{r, nil})
+	if !errors.Is(err, repl.ErrNilRepl) {
+		t.Fatalf("Expected combining a nil REPL to fail with %q, but got %v", repl.ErrNilRepl, err)
+	}
+	if combined != nil {
+		t.Fatal("bad combine - should not return a REPL")
+	}
+}
+
 func TestReplRun(t *testing.T) {
 	t.Run("EmptyHelp", testRunEmptyHelp)
 	t.Run("InvalidCommand", testRunInvalidCommand)
 	t.Run("SingleCommand", testRunSingleCommand)
 	t.Run("CannotOverwriteHelp", testRunCannotOverwriteHelpCommand)
 	t.Run("Prompt", testRunPrompt)
+	t.Run("Trace", testRunTrace)
+	t.Run("Timing", testRunTiming)
 }
 
 func testRunEmptyHelp(t *testing.T) {
@@ -175,3 +196,95 @@ func testRunPrompt(t *testing.T) {
 		t.Fatal("Prompt was missing from output")
 	}
 }
+
+// Checks that tracing needs a tracer, and that a traced command's trace follows its response
+func testRunTrace(t *testing.T) {
+	untraced := repl.NewRepl()
+	untraced.AddCommand("1", f1, "f1 help")
+	input, output := startRepl(t, untraced)
+	fmt.Fprintln(input, ".trace on")
+	if out := getAllOutput(output); !strings.Contains(out, repl.ErrorPrependStr+"tracing is not supported") {
+		t.Fatalf("Expected tracing without a tracer to fail, but got %q", out)
+	}
+
+	r := repl.NewRepl()
+	r.AddCommand("echo", func(s string, _ *repl.REPLConfig) (string, error) { return "response", nil }, "echo help")
+	r.SetTracer(func(run func()) string {
+		run()
+		return "traced"
+	})
+	input, output = startRepl(t, r)
+	fmt.Fprintln(input, "echo")
+	fmt.Fprintln(input, ".trace on")
+	fmt.Fprintln(input, "echo")
+	if out := getAllOutput(output); strings.Count(out, "traced") != 1 || !strings.Contains(out, "response\ntraced\n") {
+		t.Fatalf("Expected only the command after .trace on to be traced, but got %q", out)
+	}
+}
+
+func TestReplPanics(t *testing.T) {
+	t.Run("Contained", testReplPanicContained)
+	t.Run("Crash", testReplPanicCrash)
+}
+
+// Checks that a panicking command is reported as an error and that the panic handlers
+// are run with the ID of the client that sent it
+func testReplPanicContained(t *testing.T) {
+	r := repl.NewRepl()
+	r.AddCommand("bug", func(s string, _ *repl.REPLConfig) (string, error) { panic("bug in handler") }, "bug help")
+	var handled []uuid.UUID
+	r.AddPanicHandler(func(clientId uuid.UUID) { handled = append(handled, clientId) })
+	combined, err := repl.CombineRepls([]*repl.REPL{r})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientId := uuid.New()
+	var output strings.Builder
+	combined.Run(clientId, "", strings.NewReader("bug\n"), &output)
+	if !strings.Contains(output.String(), repl.ErrorPrependStr+repl.ErrCommandPanicked.Error()+": bug in handler") {
+		t.Errorf("Expected the panic to be reported as an error, got output %q", output.String())
+	}
+	if len(handled) != 1 || handled[0] != clientId {
+		t.Errorf("Expected the panic handler to run once for client %s, but it ran for %v", clientId, handled)
+	}
+}
+
+// Checks that a command panicking with ErrCrash still crashes
+func testReplPanicCrash(t *testing.T) {
+	r := repl.NewRepl()
+	r.AddCommand("crash", func(s string, _ *repl.REPLConfig) (string, error) {
+		panic(fmt.Errorf("%w: on purpose", repl.ErrCrash))
+	}, "crash help")
+	handled := false
+	r.AddPanicHandler(func(uuid.UUID) { handled = true })
+
+	defer func() {
+		if p := recover(); p == nil {
+			t.Error("Expected the crash command to panic out of the REPL")
+		}
+		if handled {
+			t.Error("Expected panic handlers not to run for a deliberate crash")
+		}
+	}()
+	r.Run(uuid.New(), "", strings.NewReader("crash\n"), io.Discard)
+}
+
+// Checks that only commands run after .timing on have their duration written after their response
+func testRunTiming(t *testing.T) {
+	r := repl.NewRepl()
+	r.AddCommand("echo", func(s string, _ *repl.REPLConfig) (string, error) { return "response", nil }, "echo help")
+	input, output := startRepl(t, r)
+	fmt.Fprintln(input, "echo")
+	if out := getAllOutput(output); out != "response\n" {
+		t.Fatalf("Expected no timing before .timing on, but got %q", out)
+	}
+	fmt.Fprintln(input, ".timing on")
+	fmt.Fprintln(input, "echo")
+	out := getAllOutput(output)
+	if !regexp.MustCompile(`^response\n\(\d+\.\dms\)\n$`).MatchString(out) {
+		t.Fatalf("Expected the response to be followed by its duration, but got %q", out)
+	}
+	fmt.Fprintln(input, ".timing")
+	checkOutputExact(t, output, "timing: on\n")
+}
@@ -0,0 +1,84 @@
+package go_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/entry"
+	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
+)
+
+func TestResult(t *testing.T) {
+	t.Run("Format", testResultFormat)
+	t.Run("FormatMetacommand", testResultFormatMetacommand)
+}
+
+// Renders results in each output format, checking the rendered output
+func testResultFormat(t *testing.T) {
+	rows := repl.Result{Rows: []entry.Entry{entry.New(1, 10), entry.New(-2, 20)}}
+	message := repl.Result{Rows: []entry.Entry{entry.New(1, 10)}, Message: "found entry: (1, 10)"}
+	affected := repl.Result{Affected: 3}
+	tests := []struct {
+		name     string
+		result   repl.Result
+		format   repl.OutputFormat
+		expected string
+	}{
+		{"HumanRows", rows, repl.HUMAN_FORMAT, "(1, 10)\n(-2, 20)\n"},
+		{"HumanMessage", message, repl.HUMAN_FORMAT, "found entry: (1, 10)\n"},
+		{"HumanEmpty", affected, repl.HUMAN_FORMAT, ""},
+		{"CSVRows", rows, repl.CSV_FORMAT, "key,value\n1,10\n-2,20\n"},
+		{"CSVMessage", message, repl.CSV_FORMAT, "key,value\n1,10\n"},
+		{"JSONRows", rows, repl.JSON_FORMAT, `{"rows":[{"key":1,"value":10},{"key":-2,"value":20}],"affected":0}` + "\n"},
+		{"JSONMessage", message, repl.JSON_FORMAT, `{"rows":[{"key":1,"value":10}],"affected":0,"message":"found entry: (1, 10)"}` + "\n"},
+		{"JSONAffected", affected, repl.JSON_FORMAT, `{"rows":[],"affected":3}` + "\n"},
+	}
+	for _, test := range tests {
+		output, err := test.result.Format(test.format)
+		if err != nil {
+			t.Errorf("%s: failed to format result: %s", test.name, err)
+			continue
+		}
+		if output != test.expected {
+			t.Errorf("%s: expected %q, but got %q", test.name, test.expected, output)
+		}
+	}
+	if _, err := rows.Format("xml"); err == nil {
+		t.Error("Expected formatting in an unknown format to fail")
+	}
+}
+
+// Switches output formats with the format meta-command, checking that a result
+// command's output follows the active format and that commands returning strings don't
+func testResultFormatMetacommand(t *testing.T) {
+	r := repl.NewRepl()
+	r.AddResultCommand("rows", func(string, *repl.REPLConfig) (repl.Result, error) {
+		return repl.Result{Rows: []entry.Entry{entry.New(1, 2)}}, nil
+	}, "")
+	r.AddCommand("ping", func(string, *repl.REPLConfig) (string, error) { return "pong", nil }, "")
+	input := strings.Join([]string{
+		"rows",
+		".format csv", "rows", "ping",
+		".format json", "rows",
+		".format", ".format xml",
+		".format human", "rows",
+	}, "\n")
+	output := new(bytes.Buffer)
+	r.Run(uuid.New(), "", strings.NewReader(input), output)
+
+	expected := []string{
+		"(1, 2)",
+		"key,value", "1,2", "pong",
+		`{"rows":[{"key":1,"value":2}],"affected":0}`,
+		"output format: json",
+		repl.ErrorPrependStr + `unknown output format "xml"`,
+		"(1, 2)",
+	}
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")[1:]
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected output lines %q, but got %q", expected, lines)
+	}
+}
@@ -0,0 +1,32 @@
+package go_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dinodb/pkg/repl"
+
+	"github.com/google/uuid"
+)
+
+// Runs a workload of slow commands on several goroutines, checking that every command
+// has finished running by the time RunWorkload returns
+func TestRunWorkload(t *testing.T) {
+	var finished atomic.Int64
+	r := repl.NewRepl()
+	r.AddCommand("slow", func(s string, _ *repl.REPLConfig) (string, error) {
+		time.Sleep(5 * time.Millisecond)
+		finished.Add(1)
+		return "", nil
+	}, "slow help")
+	workload := make([]string, 20)
+	for i := range workload {
+		workload[i] = fmt.Sprintf("slow %d", i)
+	}
+	r.RunWorkload(workload, 4, uuid.New(), func() time.Duration { return time.Millisecond })
+	if n := finished.Load(); n != int64(len(workload)) {
+		t.Fatalf("Expected all %d commands to have finished once the workload returned, but %d had", len(workload), n)
+	}
+}
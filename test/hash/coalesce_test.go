@@ -0,0 +1,111 @@
+package hash_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+func TestHashCoalesce(t *testing.T) {
+	t.Run("ShrinksToInitialDepth", testCoalesceShrinks)
+	t.Run("ReusesFreedBuckets", testCoalesceReusesFreedBuckets)
+}
+
+// collidingKeys returns keys that all hash to slot 0 at the initial depth, so that they split only that bucket
+func collidingKeys(n int) []int64 {
+	keys := make([]int64, 0, n)
+	for key := int64(0); len(keys) < n; key++ {
+		if hash.Hasher(key, hash.INITIAL_DEPTH) == 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// growToDepth inserts colliding keys until the table reaches the given global depth, returning the keys inserted
+func growToDepth(t *testing.T, index *hash.HashIndex, depth int64) []int64 {
+	keys := collidingKeys(100000)
+	for i, key := range keys {
+		if index.GetTable().GetDepth() >= depth {
+			return keys[:i]
+		}
+		utils.InsertEntry(t, index, key, key%hashSalt)
+	}
+	t.Fatalf("Expected the table to reach depth %d, but it only reached %d", depth, index.GetTable().GetDepth())
+	return nil
+}
+
+// Grows a table to depth 4 with keys that collide at the initial depth, then deletes them, checking that the table
+// stays valid while its buckets merge and that it returns to the initial depth once they are all gone
+func testCoalesceShrinks(t *testing.T) {
+	index := setupHash(t)
+	keys := growToDepth(t, index, 4)
+	half := len(keys) / 2
+	for _, key := range keys[:half] {
+		if err := index.Delete(key); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+	}
+	if ok, err := hash.IsHash(index); err != nil || !ok {
+		t.Fatal("Expected a valid hash table after deleting half of the keys:", err)
+	}
+	for _, key := range keys[half:] {
+		utils.CheckFindEntry(t, index, key, key%hashSalt)
+	}
+	for _, key := range keys[half:] {
+		if err := index.Delete(key); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+	}
+	if depth := index.GetTable().GetDepth(); depth != hash.INITIAL_DEPTH {
+		t.Errorf("Expected deleting every key to shrink the table to depth %d, but it has depth %d", hash.INITIAL_DEPTH, depth)
+	}
+	if n := len(index.GetTable().GetBuckets()); n != 4 {
+		t.Errorf("Expected 4 directory slots, but got %d", n)
+	}
+	index = closeAndReopen(t, index)
+	defer index.Close()
+	if depth := index.GetTable().GetDepth(); depth != hash.INITIAL_DEPTH {
+		t.Errorf("Expected the reopened table to have depth %d, but it has depth %d", hash.INITIAL_DEPTH, depth)
+	}
+}
+
+// Shrinks a table and grows it again, checking that the splits reuse the pages freed by merging,
+// including after reopening the table or rebuilding its directory
+func testCoalesceReusesFreedBuckets(t *testing.T) {
+	index := setupHash(t)
+	keys := growToDepth(t, index, 4)
+	numPages := index.GetPager().GetNumPages()
+	for _, key := range keys {
+		if err := index.Delete(key); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+	}
+	index = closeAndReopen(t, index)
+	growToDepth(t, index, 4)
+	if n := index.GetPager().GetNumPages(); n != numPages {
+		t.Errorf("Expected regrowing the reopened table to reuse its %d pages, but it has %d", numPages, n)
+	}
+	for _, key := range keys {
+		if err := index.Delete(key); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+	}
+	filename := closeAndLoseMeta(t, index)
+	index, err := hash.RepairTable(filename)
+	if err != nil {
+		t.Fatal("Failed to repair table:", err)
+	}
+	defer index.Close()
+	if depth := index.GetTable().GetDepth(); depth != hash.INITIAL_DEPTH {
+		t.Errorf("Expected the rebuilt table to skip freed pages and have depth %d, but it has depth %d", hash.INITIAL_DEPTH, depth)
+	}
+	growToDepth(t, index, 4)
+	if n := index.GetPager().GetNumPages(); n != numPages {
+		t.Errorf("Expected regrowing the rebuilt table to reuse its %d pages, but it has %d", numPages, n)
+	}
+	if ok, err := hash.IsHash(index); err != nil || !ok {
+		t.Error("Expected a valid hash table after regrowing it:", err)
+	}
+}
@@ -0,0 +1,96 @@
+package hash_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+func TestHashConcurrentInsert(t *testing.T) {
+	t.Run("Disjoint", testConcurrentInsertDisjoint)
+	t.Run("OtherBucketNotBlocked", testConcurrentInsertOtherBucket)
+}
+
+// Inserts disjoint sets of keys from many goroutines, checking that every key
+// can be found afterwards and lives in the bucket the table routes it to
+func testConcurrentInsertDisjoint(t *testing.T) {
+	index := setupHash(t)
+	defer index.Close()
+	const numWorkers = 16
+	const insertsPerWorker = 500
+	var wg sync.WaitGroup
+	errs := make(chan error, numWorkers)
+	for w := int64(0); w < numWorkers; w++ {
+		wg.Add(1)
+		go func(w int64) {
+			defer wg.Done()
+			for i := int64(0); i < insertsPerWorker; i++ {
+				if err := index.Insert(w*insertsPerWorker+i, i); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal("Failed to insert:", err)
+	}
+	answerKey := make(map[int64]int64)
+	for w := int64(0); w < numWorkers; w++ {
+		for i := int64(0); i < insertsPerWorker; i++ {
+			answerKey[w*insertsPerWorker+i] = i
+		}
+	}
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, index, k, v)
+	}
+	checkRouting(t, index, index.GetTable().GetHasher(), answerKey)
+	entries, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select:", err)
+	}
+	if len(entries) != len(answerKey) {
+		t.Errorf("Expected %d entries, but found %d", len(answerKey), len(entries))
+	}
+}
+
+// Holds one bucket locked the way an in-progress insert does, checking that an
+// insert into a different bucket isn't blocked behind it
+func testConcurrentInsertOtherBucket(t *testing.T) {
+	index := setupHash(t)
+	defer index.Close()
+	table := index.GetTable()
+	hasher := table.GetHasher()
+
+	table.RLock()
+	heldHash := hasher(0, table.GetDepth())
+	held, err := table.GetAndLockBucket(heldHash, hash.WRITE_LOCK)
+	if err != nil {
+		table.RUnlock()
+		t.Fatal("Failed to lock bucket:", err)
+	}
+	otherKey := int64(1)
+	for table.GetBuckets()[hasher(otherKey, table.GetDepth())] == table.GetBuckets()[heldHash] {
+		otherKey++
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- index.Insert(otherKey, 1) }()
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected an insert into another bucket to finish while a bucket is locked")
+	}
+	held.WUnlock()
+	table.RUnlock()
+	index.GetPager().PutPage(held.GetPage())
+	if err != nil {
+		t.Fatal("Failed to insert:", err)
+	}
+	utils.CheckFindEntry(t, index, otherKey, 1)
+}
@@ -0,0 +1,127 @@
+package hash_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+// =====================================================================
+// HELPERS
+// =====================================================================
+
+// setupHashWithHasher creates and opens an empty HashIndex that routes keys with the given hasher
+func setupHashWithHasher(t *testing.T, hasher hash.HasherFunc) *hash.HashIndex {
+	t.Parallel()
+	dbName := utils.GetTempDbFile(t)
+	index, err := hash.OpenTableWithHasher(dbName, hasher)
+	if err != nil {
+		t.Fatal("Failed to create hash index:", err)
+	}
+
+	return index
+}
+
+// checkRouting verifies that every key in the answer key lives in the bucket the given hasher routes it to
+func checkRouting(t *testing.T, index *hash.HashIndex, hasher hash.HasherFunc, answerKey map[int64]int64) {
+	table := index.GetTable()
+	for k, v := range answerKey {
+		bucket, err := table.GetBucket(hasher(k, table.GetDepth()))
+		if err != nil {
+			t.Fatal("Failed to get bucket:", err)
+		}
+		e, found := bucket.Find(k)
+		index.GetPager().PutPage(bucket.GetPage())
+		if !found {
+			t.Errorf("Expected key %d to be in the bucket chosen by the table's hasher", k)
+			continue
+		}
+		utils.CheckEntry(t, e, k, v)
+	}
+}
+
+// =====================================================================
+// TESTS
+// =====================================================================
+
+func TestHashHasher(t *testing.T) {
+	t.Run("Routing", testHasherRouting)
+	t.Run("Reopen", testHasherReopen)
+	t.Run("Mismatch", testHasherMismatch)
+}
+
+// Inserts entries into tables using two different hashers, checking that
+// each table routes its entries according to its own hasher
+func testHasherRouting(t *testing.T) {
+	hashers := map[string]hash.HasherFunc{
+		"XxHash": hash.Hasher,
+		"Murmur": hash.MurmurDepthHasher,
+	}
+	for name, hasher := range hashers {
+		t.Run(name, func(t *testing.T) {
+			index := setupHashWithHasher(t, hasher)
+			defer index.Close()
+			entries, answerKey := utils.GenerateRandomKeyValuePairs(1000)
+			for _, e := range entries {
+				utils.InsertEntry(t, index, e.Key, e.Val)
+			}
+			if t.Failed() {
+				t.FailNow()
+			}
+			for k, v := range answerKey {
+				utils.CheckFindEntry(t, index, k, v)
+			}
+			checkRouting(t, index, hasher, answerKey)
+		})
+	}
+}
+
+// Reopens a table created with a non-default hasher, checking that
+// the stored hasher is used both when it is passed in and when it isn't
+func testHasherReopen(t *testing.T) {
+	index := setupHashWithHasher(t, hash.MurmurDepthHasher)
+	entries, answerKey := utils.GenerateRandomKeyValuePairs(500)
+	for _, e := range entries {
+		utils.InsertEntry(t, index, e.Key, e.Val)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatal("Failed to close hash index:", err)
+	}
+
+	// Reopen passing in the same hasher
+	index, err := hash.OpenTableWithHasher(index.GetPager().GetFileName(), hash.MurmurDepthHasher)
+	if err != nil {
+		t.Fatal("Failed to reopen hash index with the same hasher:", err)
+	}
+	checkRouting(t, index, hash.MurmurDepthHasher, answerKey)
+	if err := index.Close(); err != nil {
+		t.Fatal("Failed to close hash index:", err)
+	}
+
+	// Reopen without specifying a hasher
+	index, err = hash.OpenTable(index.GetPager().GetFileName())
+	if err != nil {
+		t.Fatal("Failed to reopen hash index without a hasher:", err)
+	}
+	defer index.Close()
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, index, k, v)
+	}
+	checkRouting(t, index, hash.MurmurDepthHasher, answerKey)
+}
+
+// Reopens a table with a different hasher than it was created with, checking that it is rejected
+func testHasherMismatch(t *testing.T) {
+	index := setupHashWithHasher(t, hash.MurmurDepthHasher)
+	utils.InsertEntry(t, index, 1, 1)
+	if err := index.Close(); err != nil {
+		t.Fatal("Failed to close hash index:", err)
+	}
+
+	_, err := hash.OpenTableWithHasher(index.GetPager().GetFileName(), hash.Hasher)
+	if !errors.Is(err, hash.ErrHasherMismatch) {
+		t.Errorf("Expected reopening with a mismatched hasher to fail with %q, but got %v", hash.ErrHasherMismatch, err)
+	}
+}
@@ -59,6 +59,8 @@ func TestHashInsert(t *testing.T) {
 	t.Run("Splitting", testHashSplitting)
 	t.Run("Ascending", testInsertAscending)
 	t.Run("Random", testInsertRandom)
+	t.Run("Upsert", testHashUpsert)
+	t.Run("ReopenAfterGrowing", testHashReopenAfterGrowing)
 }
 
 /*
@@ -214,3 +216,58 @@ func testInsertRandom(t *testing.T) {
 }
 
 // TODO: add test that duplicate keys are allowed
+
+// Upserts enough new keys to split buckets, then upserts them again with new values,
+// checking that missing keys are inserted and existing keys are updated
+func testHashUpsert(t *testing.T) {
+	index := setupHash(t)
+	defer index.Close()
+	entries, answerKey := utils.GenerateRandomKeyValuePairs(1000)
+	for _, e := range entries {
+		if err := index.Upsert(e.Key, e.Val); err != nil {
+			t.Fatalf("Failed to upsert key %d: %v", e.Key, err)
+		}
+	}
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, index, k, v)
+	}
+	for k, v := range answerKey {
+		if err := index.Upsert(k, v+1); err != nil {
+			t.Fatalf("Failed to upsert key %d: %v", k, err)
+		}
+	}
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, index, k, v+1)
+	}
+	selected, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select entries:", err)
+	}
+	if len(selected) != len(answerKey) {
+		t.Errorf("Expected %d entries after upserting, but found %d", len(answerKey), len(selected))
+	}
+}
+
+// Reopens a table several times, growing its directory in between, checking that
+// each reopen reads the latest directory rather than one written by an earlier close
+func testHashReopenAfterGrowing(t *testing.T) {
+	index := setupHash(t)
+	answerKey := make(map[int64]int64)
+	for round := 0; round < 3; round++ {
+		entries, roundKey := utils.GenerateRandomKeyValuePairs(1000)
+		for _, e := range entries {
+			utils.InsertEntry(t, index, e.Key, e.Val)
+		}
+		for k, v := range roundKey {
+			answerKey[k] = v
+		}
+		index = closeAndReopen(t, index)
+		for k, v := range answerKey {
+			utils.CheckFindEntry(t, index, k, v)
+		}
+		if t.Failed() {
+			t.Fatalf("Entries were lost after reopening %d times", round+1)
+		}
+	}
+	index.Close()
+}
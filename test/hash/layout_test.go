@@ -0,0 +1,60 @@
+package hash_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+// Inserts enough entries to split the table, checking that every page is classified as
+// a bucket matching the directory, and that the buckets hold every entry
+func TestHashLayout(t *testing.T) {
+	const numEntries = 5000
+	index := setupHash(t)
+	defer index.Close()
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, index, i, i)
+	}
+	table := index.GetTable()
+	if table.GetDepth() <= 2 {
+		t.Fatal("Expected the table to have split")
+	}
+	layout, err := index.Layout()
+	if err != nil {
+		t.Fatal("Failed to get layout:", err)
+	}
+	if int64(len(layout)) != index.GetPager().GetNumPages() {
+		t.Fatalf("Expected %d pages in the layout, but got %d", index.GetPager().GetNumPages(), len(layout))
+	}
+
+	slots := make(map[int64]int64)
+	for _, pn := range table.GetBuckets() {
+		slots[pn]++
+	}
+	var totalKeys, totalSlots int64
+	for i, info := range layout {
+		if info.PN != int64(i) {
+			t.Fatalf("Expected page %d at position %d of the layout, but got page %d", i, i, info.PN)
+		}
+		if info.Role != hash.BUCKET_PAGE {
+			t.Errorf("Expected page %d to be a bucket, but got %q", info.PN, info.Role)
+			continue
+		}
+		if info.NumSlots != slots[info.PN] {
+			t.Errorf("Expected %d directory slots to point to page %d, but got %d", slots[info.PN], info.PN, info.NumSlots)
+		}
+		// A bucket with local depth d is pointed to by 2^(global depth - d) slots
+		if expected := int64(1) << (table.GetDepth() - info.LocalDepth); info.NumSlots != expected {
+			t.Errorf("Expected a bucket with local depth %d to have %d slots, but got %d", info.LocalDepth, expected, info.NumSlots)
+		}
+		totalKeys += info.NumKeys
+		totalSlots += info.NumSlots
+	}
+	if totalKeys != numEntries {
+		t.Errorf("Expected the buckets to hold %d keys, but got %d", numEntries, totalKeys)
+	}
+	if totalSlots != int64(len(table.GetBuckets())) {
+		t.Errorf("Expected %d directory slots, but got %d", len(table.GetBuckets()), totalSlots)
+	}
+}
@@ -0,0 +1,106 @@
+package hash_test
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"dinodb/pkg/entry"
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+func TestHashSelectSortedPage(t *testing.T) {
+	t.Run("MatchesSelectSorted", testPageMatchesSelectSorted)
+	t.Run("InvalidLimit", testPageInvalidLimit)
+	t.Run("AcrossSplits", testPageAcrossSplits)
+}
+
+// paginate reads every page of the index with the given limit, returning the entries in the order they were read
+func paginate(t *testing.T, index *hash.HashIndex, limit int) []entry.Entry {
+	var all []entry.Entry
+	var afterKey int64
+	hasAfter := false
+	for {
+		page, more, err := index.SelectSortedPage(afterKey, hasAfter, limit)
+		if err != nil {
+			t.Errorf("Failed to select page: %v", err)
+			return all
+		}
+		if len(page) > limit || (more && len(page) != limit) {
+			t.Errorf("Expected a page of at most %d entries, full if there are more, but got %d (more: %v)", limit, len(page), more)
+			return all
+		}
+		all = append(all, page...)
+		if !more {
+			return all
+		}
+		afterKey, hasAfter = page[len(page)-1].Key, true
+	}
+}
+
+// Paginates a static table with several limits, checking that the pages together equal SelectSorted
+func testPageMatchesSelectSorted(t *testing.T) {
+	entries, _ := utils.GenerateRandomKeyValuePairs(1000)
+	index := buildHash(t, entries)
+	expected, err := index.SelectSorted()
+	if err != nil {
+		t.Fatal("Failed to select entries:", err)
+	}
+	for _, limit := range []int{1, 7, 100, 1000, 5000} {
+		if got := paginate(t, index, limit); !slices.Equal(got, expected) {
+			t.Errorf("Limit %d: expected the pages to hold the %d sorted entries, but got %d entries", limit, len(expected), len(got))
+		}
+	}
+}
+
+// Checks that a page must have a positive limit
+func testPageInvalidLimit(t *testing.T) {
+	index := setupHash(t)
+	defer index.Close()
+	for _, limit := range []int{0, -1} {
+		if _, _, err := index.SelectSortedPage(0, false, limit); err == nil {
+			t.Errorf("Expected a limit of %d to fail", limit)
+		}
+	}
+}
+
+// Paginates the even keys while other goroutines insert the odd keys, splitting buckets between pages,
+// checking that the pass sees every even key exactly once and in increasing order
+func testPageAcrossSplits(t *testing.T) {
+	index := setupHash(t)
+	defer index.Close()
+	const numKeys, numWriters = 4000, 4
+	for key := int64(0); key < numKeys; key += 2 {
+		utils.InsertEntry(t, index, key, key)
+	}
+	var wg sync.WaitGroup
+	for w := int64(0); w < numWriters; w++ {
+		wg.Add(1)
+		go func(w int64) {
+			defer wg.Done()
+			for key := 2*w + 1; key < numKeys; key += 2 * numWriters {
+				if err := index.Insert(key, key); err != nil {
+					t.Errorf("Failed to insert: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	seen := paginate(t, index, 50)
+	wg.Wait()
+	for i := 1; i < len(seen); i++ {
+		if seen[i].Key <= seen[i-1].Key {
+			t.Fatalf("Expected strictly increasing keys, but key %d followed key %d", seen[i].Key, seen[i-1].Key)
+		}
+	}
+	numEven := 0
+	for _, e := range seen {
+		if e.Key%2 == 0 {
+			numEven++
+		}
+	}
+	if numEven != numKeys/2 {
+		t.Errorf("Expected the pass to see all %d even keys, but it saw %d", numKeys/2, numEven)
+	}
+}
@@ -0,0 +1,118 @@
+package hash_test
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"dinodb/pkg/hash"
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+func TestHashRebuildDirectory(t *testing.T) {
+	t.Run("LostMeta", testRebuildLostMeta)
+	t.Run("Hasher", testRebuildHasher)
+}
+
+// closeAndLoseMeta closes the index and deletes its .meta file, returning the table's file name
+func closeAndLoseMeta(t *testing.T, index *hash.HashIndex) string {
+	filename := index.GetPager().GetFileName()
+	if err := index.Close(); err != nil {
+		t.Fatal("Failed to close hash index:", err)
+	}
+	if err := os.Remove(filename + ".meta"); err != nil {
+		t.Fatal("Failed to delete the .meta file:", err)
+	}
+	return filename
+}
+
+// Splits a table and empties one of its buckets, then deletes its .meta file, checking that repairing it
+// rebuilds the same directory, that every entry is findable, and that the repaired table reopens normally
+func testRebuildLostMeta(t *testing.T) {
+	index := setupHash(t)
+	answerKey := make(map[int64]int64)
+	for i := int64(0); i < 5000; i++ {
+		utils.InsertEntry(t, index, i, i%hashSalt)
+		answerKey[i] = i % hashSalt
+	}
+	table := index.GetTable()
+	bucket, err := table.GetBucket(0)
+	if err != nil {
+		t.Fatal("Failed to get bucket:", err)
+	}
+	entries, err := bucket.Select()
+	index.GetPager().PutPage(bucket.GetPage())
+	if err != nil {
+		t.Fatal("Failed to select from bucket:", err)
+	}
+	for _, e := range entries {
+		if err := index.Delete(e.Key); err != nil {
+			t.Fatal("Failed to delete:", err)
+		}
+		delete(answerKey, e.Key)
+	}
+	depth, buckets := table.GetDepth(), slices.Clone(table.GetBuckets())
+
+	index, err = hash.RepairTable(closeAndLoseMeta(t, index))
+	if err != nil {
+		t.Fatal("Failed to repair the table:", err)
+	}
+	table = index.GetTable()
+	if table.GetDepth() != depth {
+		t.Errorf("Expected a rebuilt global depth of %d, but got %d", depth, table.GetDepth())
+	}
+	if !slices.Equal(table.GetBuckets(), buckets) {
+		t.Error("Expected the rebuilt directory to match the lost one")
+	}
+	if ok, err := hash.IsHash(index); err != nil || !ok {
+		t.Errorf("Expected the rebuilt table to pass IsHash, but got %v, %v", ok, err)
+	}
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, index, k, v)
+	}
+	index = closeAndReopen(t, index)
+	defer index.Close()
+	for k, v := range answerKey {
+		utils.CheckFindEntry(t, index, k, v)
+	}
+}
+
+// Rebuilds a table created with a non-default hasher, checking that rebuilding it with the default hasher
+// fails, and that rebuilding it with its own hasher routes every entry correctly
+func testRebuildHasher(t *testing.T) {
+	index := setupHashWithHasher(t, hash.MurmurDepthHasher)
+	answerKey := make(map[int64]int64)
+	for i := int64(0); i < 2000; i++ {
+		utils.InsertEntry(t, index, i, i)
+		answerKey[i] = i
+	}
+	filename := closeAndLoseMeta(t, index)
+
+	bucketPager, err := pager.New(filename)
+	if err != nil {
+		t.Fatal("Failed to open pager:", err)
+	}
+	defer bucketPager.Close()
+	if _, err := hash.RebuildDirectory(bucketPager); !errors.Is(err, hash.ErrUnrebuildableDirectory) {
+		t.Errorf("Expected ErrUnrebuildableDirectory with the wrong hasher, but got %v", err)
+	}
+	table, err := hash.RebuildDirectoryWithHasher(bucketPager, hash.MurmurDepthHasher)
+	if err != nil {
+		t.Fatal("Failed to rebuild the directory:", err)
+	}
+	for k, v := range answerKey {
+		bucket, err := table.GetBucket(hash.MurmurDepthHasher(k, table.GetDepth()))
+		if err != nil {
+			t.Fatal("Failed to get bucket:", err)
+		}
+		e, found := bucket.Find(k)
+		bucketPager.PutPage(bucket.GetPage())
+		if !found {
+			t.Errorf("Expected key %d to be in the bucket its hash points to", k)
+			continue
+		}
+		utils.CheckEntry(t, e, k, v)
+	}
+}
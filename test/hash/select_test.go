@@ -0,0 +1,104 @@
+package hash_test
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"dinodb/pkg/entry"
+	"dinodb/pkg/hash"
+	"dinodb/test/utils"
+)
+
+// buildHash creates a HashIndex holding the given entries, inserted in order
+func buildHash(t *testing.T, entries []utils.KeyValuePair) *hash.HashIndex {
+	index, err := hash.OpenTable(utils.GetTempDbFile(t))
+	if err != nil {
+		t.Fatal("Failed to create hash index:", err)
+	}
+	t.Cleanup(func() { _ = index.Close() })
+	for _, e := range entries {
+		utils.InsertEntry(t, index, e.Key, e.Val)
+	}
+	return index
+}
+
+func TestHashSelect(t *testing.T) {
+	t.Run("SetEquality", testHashSelectSetEquality)
+	t.Run("SortedInsertionOrder", testHashSelectSortedInsertionOrder)
+}
+
+// Checks that Select returns each entry exactly once, and that the table can still be written to afterwards
+func testHashSelectSetEquality(t *testing.T) {
+	t.Parallel()
+	entries, answerKey := utils.GenerateRandomKeyValuePairs(2000)
+	index := buildHash(t, entries)
+	selected, err := index.Select()
+	if err != nil {
+		t.Fatal("Failed to select entries:", err)
+	}
+	if len(selected) != len(answerKey) {
+		t.Errorf("Expected %d entries, but selected %d", len(answerKey), len(selected))
+	}
+	seen := make(map[int64]bool)
+	for _, e := range selected {
+		if seen[e.Key] {
+			t.Errorf("Expected key %d to be selected once, but it was selected again", e.Key)
+		}
+		seen[e.Key] = true
+		if val, ok := answerKey[e.Key]; !ok || val != e.Value {
+			t.Errorf("Selected unexpected entry (%d, %d)", e.Key, e.Value)
+		}
+	}
+	utils.InsertEntry(t, index, -1, 1)
+}
+
+// Builds the same data set in tables with different insertion orders and split histories,
+// checking that SelectSorted returns the same key-sorted sequence for each of them
+func testHashSelectSortedInsertionOrder(t *testing.T) {
+	t.Parallel()
+	entries, _ := utils.GenerateRandomKeyValuePairs(2000)
+	ascending := slices.Clone(entries)
+	slices.SortFunc(ascending, func(a, b utils.KeyValuePair) int { return cmp.Compare(a.Key, b.Key) })
+	descending := slices.Clone(ascending)
+	slices.Reverse(descending)
+	shuffled := slices.Clone(entries)
+	rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+	// A table that also held, and lost, extra entries has split differently.
+	// Generated keys are never negative, so the extra keys can't collide with them
+	extra := make([]utils.KeyValuePair, 0, 2000)
+	for i := int64(0); i < 2000; i++ {
+		extra = append(extra, utils.KeyValuePair{Key: -i - 1, Val: i})
+	}
+	churned := buildHash(t, append(slices.Clone(extra), shuffled...))
+	for _, e := range extra {
+		if err := churned.Delete(e.Key); err != nil {
+			t.Fatal("Failed to delete entry:", err)
+		}
+	}
+
+	var expected []entry.Entry
+	for i, index := range []*hash.HashIndex{buildHash(t, entries), buildHash(t, ascending), buildHash(t, descending), churned} {
+		sorted, err := index.SelectSorted()
+		if err != nil {
+			t.Fatal("Failed to select sorted entries:", err)
+		}
+		if i == 0 {
+			expected = sorted
+			if len(expected) != len(entries) {
+				t.Fatalf("Expected %d entries, but selected %d", len(entries), len(expected))
+			}
+			for j := 1; j < len(expected); j++ {
+				if expected[j-1].Key >= expected[j].Key {
+					t.Fatalf("Expected entries sorted by key, but %d came before %d", expected[j-1].Key, expected[j].Key)
+				}
+			}
+			continue
+		}
+		if !slices.Equal(sorted, expected) {
+			t.Errorf("Expected table %d to select the same sorted entries as table 0", i)
+		}
+	}
+}
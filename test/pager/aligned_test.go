@@ -0,0 +1,100 @@
+package pager_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+// =====================================================================
+// HELPERS
+// =====================================================================
+
+// setupFileWithPages creates a pager file holding numPages pages, returning its name
+func setupFileWithPages(t *testing.T, numPages int) string {
+	t.Parallel()
+	dbname := utils.GetTempDbFile(t)
+	p, err := pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	for i := 0; i < numPages; i++ {
+		page, err := p.GetNewPage()
+		if err != nil {
+			t.Fatal("Failed to get new page:", err)
+		}
+		page.SetDirty(true)
+		p.PutPage(page)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	return dbname
+}
+
+// =====================================================================
+// TESTS
+// =====================================================================
+
+func TestPagerAlignment(t *testing.T) {
+	t.Run("Unaligned", testAlignmentUnaligned)
+	t.Run("Truncated", testAlignmentTruncated)
+	t.Run("Corrupt", testAlignmentCorrupt)
+}
+
+// Appends a partial page to a file, checking that opening it fails with ErrUnalignedFile
+// and that the failed open doesn't leave the file locked
+func testAlignmentUnaligned(t *testing.T) {
+	dbname := setupFileWithPages(t, 2)
+	f, err := os.OpenFile(dbname, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatal("Failed to open database file:", err)
+	}
+	if _, err = f.Write(make([]byte, 100)); err != nil {
+		t.Fatal("Failed to append to database file:", err)
+	}
+	f.Close()
+
+	_, err = pager.New(dbname)
+	if !errors.Is(err, pager.ErrUnalignedFile) {
+		t.Fatalf("Expected opening an unaligned file to fail with %q, but got %v", pager.ErrUnalignedFile, err)
+	}
+	if errors.Is(err, pager.ErrCorruptFile) {
+		t.Errorf("Expected an unaligned file not to be reported as corrupt, but got %v", err)
+	}
+	_, err = pager.New(dbname)
+	if errors.Is(err, pager.ErrDatabaseLocked) {
+		t.Errorf("Expected a failed open to release the file's lock, but got %v", err)
+	}
+}
+
+// Truncates a file to less than its superblock, checking that opening it fails with ErrUnalignedFile
+func testAlignmentTruncated(t *testing.T) {
+	dbname := setupFileWithPages(t, 1)
+	if err := os.Truncate(dbname, pager.SuperblockSize/2); err != nil {
+		t.Fatal("Failed to truncate database file:", err)
+	}
+	if _, err := pager.New(dbname); !errors.Is(err, pager.ErrUnalignedFile) {
+		t.Errorf("Expected opening a truncated file to fail with %q, but got %v", pager.ErrUnalignedFile, err)
+	}
+}
+
+// Overwrites a file's superblock, checking that opening it fails with ErrCorruptFile
+func testAlignmentCorrupt(t *testing.T) {
+	dbname := setupFileWithPages(t, 1)
+	f, err := os.OpenFile(dbname, os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatal("Failed to open database file:", err)
+	}
+	if _, err = f.WriteAt([]byte("NOTMAGIC"), 0); err != nil {
+		t.Fatal("Failed to overwrite superblock:", err)
+	}
+	f.Close()
+	_, err = pager.New(dbname)
+	if !errors.Is(err, pager.ErrCorruptFile) {
+		t.Errorf("Expected opening a file without a superblock to fail with %q, but got %v", pager.ErrCorruptFile, err)
+	}
+}
@@ -0,0 +1,116 @@
+package pager_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"dinodb/pkg/pager"
+)
+
+func TestCachePolicy(t *testing.T) {
+	t.Run("WriteBack", testCachePolicyWriteBack)
+	t.Run("WriteThrough", stageCachePolicyWriteThrough(pager.WRITE_THROUGH))
+	t.Run("WriteThroughSync", stageCachePolicyWriteThrough(pager.WRITE_THROUGH_SYNC))
+	t.Run("SwitchFlushesUnpinned", testCachePolicySwitchFlushesUnpinned)
+	t.Run("Parse", testParseCachePolicy)
+}
+
+// readPageFromDisk reads a page's bytes straight from the pager's file, bypassing its buffer
+func readPageFromDisk(t *testing.T, p *pager.Pager, pagenum int64) []byte {
+	data, err := os.ReadFile(p.GetFileName())
+	if err != nil {
+		t.Fatal("Failed to read the pager's file:", err)
+	}
+	start := pager.SuperblockSize + pagenum*p.GetPageSize()
+	if int64(len(data)) < start+p.GetPageSize() {
+		return nil
+	}
+	return data[start : start+p.GetPageSize()]
+}
+
+// writePage writes data to a new page and unpins it
+func writePage(t *testing.T, p *pager.Pager, data []byte) *pager.Page {
+	page := getNewPage(t, p, false)
+	page.Update(data, 0, int64(len(data)))
+	if err := p.PutPage(page); err != nil {
+		t.Fatal("Failed to put page:", err)
+	}
+	return page
+}
+
+// Checks that the default policy leaves an unpinned page's changes in the buffer
+func testCachePolicyWriteBack(t *testing.T) {
+	p := setupPager(t)
+	if policy := p.GetCachePolicy(); policy != pager.WRITE_BACK {
+		t.Errorf("Expected the default cache policy to be %s, but got %s", pager.WRITE_BACK, policy)
+	}
+	data := []byte("write back")
+	page := writePage(t, p, data)
+	if !page.IsDirty() {
+		t.Error("Expected the unpinned page to still be dirty")
+	}
+	if onDisk := readPageFromDisk(t, p, page.GetPageNum()); bytes.HasPrefix(onDisk, data) {
+		t.Error("Expected the page not to be written before it is flushed")
+	}
+}
+
+// Checks that a write-through policy writes a page's changes as soon as it is unpinned
+func stageCachePolicyWriteThrough(policy pager.CachePolicy) func(t *testing.T) {
+	return func(t *testing.T) {
+		p := setupPager(t)
+		if err := p.SetCachePolicy(policy); err != nil {
+			t.Fatal("Failed to set the cache policy:", err)
+		}
+		data := []byte("write through")
+		page := writePage(t, p, data)
+		if page.IsDirty() {
+			t.Error("Expected the unpinned page to be clean")
+		}
+		if onDisk := readPageFromDisk(t, p, page.GetPageNum()); !bytes.HasPrefix(onDisk, data) {
+			t.Error("Expected the page to be written as soon as it was unpinned")
+		}
+
+		// Pages are only written once every pin is released
+		page = getPage(t, p, page.GetPageNum(), false)
+		getPage(t, p, page.GetPageNum(), false)
+		page.Update([]byte("pinned twice"), 0, 12)
+		_ = p.PutPage(page)
+		if !page.IsDirty() {
+			t.Error("Expected the still pinned page to be dirty")
+		}
+		_ = p.PutPage(page)
+		if onDisk := readPageFromDisk(t, p, page.GetPageNum()); !bytes.HasPrefix(onDisk, []byte("pinned twice")) {
+			t.Error("Expected the page to be written once its last pin was released")
+		}
+	}
+}
+
+// Checks that switching to write-through writes the pages that were left dirty under write-back
+func testCachePolicySwitchFlushesUnpinned(t *testing.T) {
+	p := setupPager(t)
+	data := []byte("left dirty")
+	page := writePage(t, p, data)
+	if err := p.SetCachePolicy(pager.WRITE_THROUGH); err != nil {
+		t.Fatal("Failed to set the cache policy:", err)
+	}
+	if onDisk := readPageFromDisk(t, p, page.GetPageNum()); !bytes.HasPrefix(onDisk, data) {
+		t.Error("Expected switching to write-through to write the dirty page")
+	}
+	if err := p.SetCachePolicy(pager.CachePolicy(-1)); !errors.Is(err, pager.ErrUnknownCachePolicy) {
+		t.Errorf("Expected setting an invalid cache policy to fail with %q, but got %v", pager.ErrUnknownCachePolicy, err)
+	}
+}
+
+func testParseCachePolicy(t *testing.T) {
+	for _, policy := range []pager.CachePolicy{pager.WRITE_BACK, pager.WRITE_THROUGH, pager.WRITE_THROUGH_SYNC} {
+		parsed, err := pager.ParseCachePolicy(policy.String())
+		if err != nil || parsed != policy {
+			t.Errorf("Expected %q to parse as %s, but got %s, %v", policy.String(), policy, parsed, err)
+		}
+	}
+	if _, err := pager.ParseCachePolicy("write_around"); !errors.Is(err, pager.ErrUnknownCachePolicy) {
+		t.Errorf("Expected parsing an unknown cache policy to fail with %q, but got %v", pager.ErrUnknownCachePolicy, err)
+	}
+}
@@ -0,0 +1,72 @@
+package pager_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dinodb/pkg/pager"
+)
+
+func TestPagerClosing(t *testing.T) {
+	t.Run("RejectsAfterClose", testClosingRejectsAfterClose)
+	t.Run("WaitsForPinnedPage", testClosingWaitsForPinnedPage)
+	t.Run("TimesOut", testClosingTimesOut)
+}
+
+// Closes a pager, checking that getting pages afterwards fails with ErrClosing
+func testClosingRejectsAfterClose(t *testing.T) {
+	p := setupPager(t)
+	page := getNewPage(t, p, false)
+	if err := p.PutPage(page); err != nil {
+		t.Fatal("Failed to put page:", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	if _, err := p.GetPage(0); !errors.Is(err, pager.ErrClosing) {
+		t.Errorf("Expected ErrClosing getting a page after close, but got %v", err)
+	}
+	if _, err := p.GetNewPage(); !errors.Is(err, pager.ErrClosing) {
+		t.Errorf("Expected ErrClosing getting a new page after close, but got %v", err)
+	}
+}
+
+// Closes a pager while a page is pinned, checking that Close waits for the page to be put,
+// and that the operation holding it can still get pages in the meantime
+func testClosingWaitsForPinnedPage(t *testing.T) {
+	p := setupPager(t)
+	page := getNewPage(t, p, false)
+	closed := make(chan error)
+	go func() { closed <- p.Close() }()
+	select {
+	case err := <-closed:
+		t.Fatal("Expected close to wait for the pinned page, but it returned", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// The operation holding the pinned page can finish its work.
+	other, err := p.GetNewPage()
+	if err != nil {
+		t.Fatal("Failed to get a new page while another is pinned during close:", err)
+	}
+	_ = p.PutPage(other)
+	_ = p.PutPage(page)
+	if err := <-closed; err != nil {
+		t.Fatal("Failed to close pager once its pages were put:", err)
+	}
+}
+
+// Closes a pager whose page is never put, checking that Close gives up and leaves the pager usable
+func testClosingTimesOut(t *testing.T) {
+	p := setupPager(t)
+	p.SetCloseWait(10 * time.Millisecond)
+	page := getNewPage(t, p, false)
+	if err := p.Close(); err == nil {
+		t.Fatal("Expected an error closing a pager with a pinned page")
+	}
+	if err := p.PutPage(page); err != nil {
+		t.Fatal("Failed to put page after a failed close:", err)
+	}
+	page = getPage(t, p, 0, false)
+	_ = p.PutPage(page)
+}
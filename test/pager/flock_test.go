@@ -0,0 +1,51 @@
+//go:build unix
+
+package pager_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/pager"
+)
+
+func TestPagerFileLock(t *testing.T) {
+	t.Run("SecondPager", testFileLockSecondPager)
+	t.Run("ReleasedOnClose", testFileLockReleasedOnClose)
+}
+
+// Opens a second pager on a file that a pager already has open, checking that it is rejected
+func testFileLockSecondPager(t *testing.T) {
+	p := setupPager(t)
+	_, err := pager.New(p.GetFileName())
+	if !errors.Is(err, pager.ErrDatabaseLocked) {
+		t.Fatalf("Expected opening a locked file to fail with %q, but got %v", pager.ErrDatabaseLocked, err)
+	}
+	// The first pager keeps working
+	page := getNewPage(t, p, false)
+	if err = p.PutPage(page); err != nil {
+		t.Error("Failed to put page:", err)
+	}
+}
+
+// Closes a pager, checking that its file can then be opened by another pager
+func testFileLockReleasedOnClose(t *testing.T) {
+	p := setupPager(t)
+	if err := p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	second, err := pager.New(p.GetFileName())
+	if err != nil {
+		t.Fatal("Expected the file to be unlocked once its pager closed, but got:", err)
+	}
+	if err = second.Close(); err != nil {
+		t.Error("Failed to close second pager:", err)
+	}
+	// Reopening the first pager locks the file again
+	if err = p.Open(p.GetFileName()); err != nil {
+		t.Fatal("Failed to reopen pager:", err)
+	}
+	if _, err = pager.New(p.GetFileName()); !errors.Is(err, pager.ErrDatabaseLocked) {
+		t.Errorf("Expected opening a relocked file to fail with %q, but got %v", pager.ErrDatabaseLocked, err)
+	}
+}
@@ -0,0 +1,48 @@
+package pager_test
+
+import (
+	"bytes"
+	"testing"
+
+	"dinodb/pkg/pager"
+)
+
+// Frees pages, checking that new pages reuse them most recently freed first, zeroed,
+// without growing the file, including after reopening
+func TestPagerFreeList(t *testing.T) {
+	p := setupPager(t)
+	for i := 0; i < 5; i++ {
+		page := getNewPage(t, p, false)
+		page.Update([]byte("data"), 0, 4)
+		p.PutPage(page)
+	}
+	for _, pn := range []int64{1, 3} {
+		page := getPage(t, p, pn, false)
+		p.FreePage(page)
+		p.PutPage(page)
+	}
+	if numFree, err := p.GetNumFreePages(); err != nil || numFree != 2 {
+		t.Errorf("Expected 2 free pages, but got %d (err %v)", numFree, err)
+	}
+	closeAndReopen(t, p)
+	if pn := p.GetFreePN(); pn != 3 {
+		t.Errorf("Expected the next new page to be page 3, but got %d", pn)
+	}
+	for _, expected := range []int64{3, 1, 5} {
+		page := getNewPage(t, p, false)
+		if page.GetPageNum() != expected {
+			t.Errorf("Expected new page %d, but got %d", expected, page.GetPageNum())
+		}
+		if !bytes.Equal(page.GetData(), make([]byte, p.GetPageSize())) {
+			t.Errorf("Expected reused page %d to be zeroed", page.GetPageNum())
+		}
+		p.PutPage(page)
+	}
+	if head := p.GetFreeListHead(); head != pager.NoPage {
+		t.Errorf("Expected an empty free list, but its head is page %d", head)
+	}
+	if n := p.GetNumPages(); n != 6 {
+		t.Errorf("Expected the file to grow by one page once the free list was empty, but it has %d pages", n)
+	}
+	p.Close()
+}
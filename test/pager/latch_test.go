@@ -0,0 +1,89 @@
+//go:build latchdebug
+
+package pager_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+// setupLatchPager creates a new pager like setupPager, but without running the test in parallel
+func setupLatchPager(t *testing.T) *pager.Pager {
+	p, err := pager.New(utils.GetTempDbFile(t))
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	utils.EnsureCleanup(t, func() {
+		_ = p.Close()
+	})
+	return p
+}
+
+// Run with: go test -tags latchdebug ./test/pager/
+func TestLatchTimeout(t *testing.T) {
+	// These tests change package-level settings, so they can't run in parallel with each other
+	oldTimeout, oldHandler := pager.LatchTimeout, pager.LatchTimeoutHandler
+	defer func() {
+		pager.LatchTimeout, pager.LatchTimeoutHandler = oldTimeout, oldHandler
+	}()
+	pager.LatchTimeout = 50 * time.Millisecond
+	reports := make(chan string, 10)
+	pager.LatchTimeoutHandler = func(report string) {
+		reports <- report
+	}
+	t.Run("Reported", func(t *testing.T) { testLatchTimeoutReported(t, reports) })
+	t.Run("NotReported", func(t *testing.T) { testLatchTimeoutNotReported(t, reports) })
+}
+
+// Holds a write lock on a page while another goroutine tries to lock it,
+// checking that a report is made, and that the lock is still acquired once released
+func testLatchTimeoutReported(t *testing.T, reports chan string) {
+	p := setupLatchPager(t)
+	page := getNewPage(t, p, true)
+	page.WLock()
+	acquired := make(chan bool)
+	go func() {
+		page.RLock()
+		page.RUnlock()
+		close(acquired)
+	}()
+	select {
+	case report := <-reports:
+		if !strings.Contains(report, "read lock on page 0") {
+			t.Errorf("Expected the report to name the page and lock type, but got:\n%s", report)
+		}
+		if !strings.Contains(report, "goroutine") {
+			t.Errorf("Expected the report to include stack traces, but got:\n%s", report)
+		}
+	case <-time.After(20 * pager.LatchTimeout):
+		t.Fatal("Expected a report for a lock held past the timeout")
+	}
+	page.WUnlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lock to be acquired after being released")
+	}
+}
+
+// Contends for a page lock that is released before the timeout, checking that nothing is reported
+func testLatchTimeoutNotReported(t *testing.T, reports chan string) {
+	p := setupLatchPager(t)
+	page := getNewPage(t, p, true)
+	page.WLock()
+	go func() {
+		time.Sleep(pager.LatchTimeout / 5)
+		page.WUnlock()
+	}()
+	page.WLock()
+	page.WUnlock()
+	select {
+	case report := <-reports:
+		t.Errorf("Expected no report, but got:\n%s", report)
+	case <-time.After(2 * pager.LatchTimeout):
+	}
+}
@@ -0,0 +1,75 @@
+package pager_test
+
+import (
+	"testing"
+)
+
+func TestPagerLRU(t *testing.T) {
+	t.Run("HotPagesSurvive", testLRUHotPagesSurvive)
+	t.Run("EvictsLeastRecentlyUsed", testLRUEvictsLeastRecentlyUsed)
+}
+
+// Interleaves accesses to a small hot set of pages with a scan over many cold pages through a small buffer,
+// checking through the pager's disk reads that the hot pages are never evicted while the cold pages are
+func testLRUHotPagesSurvive(t *testing.T) {
+	p := setupPager(t)
+	const bufSize, numHot, numPages = 8, 3, 40
+	if err := p.ResizeBuffer(bufSize); err != nil {
+		t.Fatal("Failed to resize buffer:", err)
+	}
+	for range numPages {
+		p.PutPage(getNewPage(t, p, false))
+	}
+	// Touch the hot pages so that they are resident before counting reads.
+	for pn := range int64(numHot) {
+		p.PutPage(getPage(t, p, pn, false))
+	}
+	for round := range 3 {
+		for cold := int64(numHot); cold < numPages; cold++ {
+			readsBefore := p.GetNumDiskReads()
+			for pn := range int64(numHot) {
+				p.PutPage(getPage(t, p, pn, false))
+			}
+			if reads := p.GetNumDiskReads() - readsBefore; reads != 0 {
+				t.Fatalf("Round %d: expected the hot pages to stay in the buffer, but reading them took %d disk reads", round, reads)
+			}
+			p.PutPage(getPage(t, p, cold, false))
+		}
+	}
+	// The cold pages cycle through the buffer's remaining frames, so reading them all again must go to disk.
+	readsBefore := p.GetNumDiskReads()
+	for cold := int64(numHot); cold < numPages; cold++ {
+		p.PutPage(getPage(t, p, cold, false))
+	}
+	if reads := p.GetNumDiskReads() - readsBefore; reads < numPages-bufSize {
+		t.Errorf("Expected rescanning the cold pages to read at least %d from disk, but it read %d", numPages-bufSize, reads)
+	}
+}
+
+// Fills the buffer, re-reads its oldest page, then reads a page from disk,
+// checking that the evicted page is the least recently used one rather than the oldest one read in
+func testLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	p := setupPager(t)
+	const bufSize = 4
+	if err := p.ResizeBuffer(bufSize); err != nil {
+		t.Fatal("Failed to resize buffer:", err)
+	}
+	for range bufSize + 1 {
+		p.PutPage(getNewPage(t, p, false))
+	}
+	// Page 0 was evicted for page 4; read it back in, evicting page 1, then use page 2.
+	p.PutPage(getPage(t, p, 0, false))
+	p.PutPage(getPage(t, p, 2, false))
+	// Reading page 1 evicts page 3, the least recently used, leaving pages 0 and 2 in the buffer.
+	p.PutPage(getPage(t, p, 1, false))
+	readsBefore := p.GetNumDiskReads()
+	p.PutPage(getPage(t, p, 0, false))
+	p.PutPage(getPage(t, p, 2, false))
+	if reads := p.GetNumDiskReads() - readsBefore; reads != 0 {
+		t.Errorf("Expected recently used pages 0 and 2 to be in the buffer, but reading them took %d disk reads", reads)
+	}
+	p.PutPage(getPage(t, p, 3, false))
+	if reads := p.GetNumDiskReads() - readsBefore; reads != 1 {
+		t.Errorf("Expected the least recently used page 3 to have been evicted, but reading it took %d disk reads", reads)
+	}
+}
@@ -2,6 +2,8 @@ package pager_test
 
 import (
 	"bytes"
+	"errors"
+	"os"
 	"testing"
 
 	"dinodb/pkg/config"
@@ -85,6 +87,7 @@ func TestPager(t *testing.T) {
 	t.Run("FlushOnePage", testFlushOnePage)
 	t.Run("TooManyPuts", testTooManyPuts)
 	t.Run("PincountsOnClose", testPincountsOnClose)
+	t.Run("ForceCloseWithPinnedPage", testForceCloseWithPinnedPage)
 	t.Run("GetExistingChangedPage", testGetExistingChangedPage)
 	t.Run("GetNewPagesStress", testGetNewPagesStress)
 }
@@ -227,6 +230,38 @@ func testPincountsOnClose(t *testing.T) {
 	}
 }
 
+/*
+Tests that force closing a pager with a page still pinned flushes
+the page's data to disk and closes the pager's file.
+*/
+func testForceCloseWithPinnedPage(t *testing.T) {
+	p := setupPager(t)
+	page := getNewPage(t, p, false)
+	data := []byte("pinned data")
+	page.Update(data, 0, int64(len(data)))
+	// Force close without unpinning the page
+	err := p.ForceClose()
+	if err != nil {
+		t.Fatal("Failed to force close pager:", err)
+	}
+	err = p.ForceClose()
+	if !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected the pager's file to be closed after force closing, but got %v", err)
+	}
+
+	// Reopen the database file with a new pager to check the data was flushed
+	reopened, err := pager.New(p.GetFileName())
+	if err != nil {
+		t.Fatal("Failed to reopen pager:", err)
+	}
+	defer reopened.Close()
+	page = getPage(t, reopened, 0, false)
+	defer reopened.PutPage(page)
+	if !bytes.Equal(page.GetData()[:len(data)], data) {
+		t.Error("Data not flushed on force close")
+	}
+}
+
 /*
 Writes data to a newly created page without flushing.
 Then makes sure that GetPage returns the same page with the new data
@@ -263,4 +298,90 @@ func testGetNewPagesStress(t *testing.T) {
 		}
 		_ = p.PutPage(page)
 	}
-}
\ No newline at end of file
+}
+func TestPagerPageSize(t *testing.T) {
+	t.Run("FourKB", stagePageSize(4096))
+	t.Run("SixteenKB", stagePageSize(16384))
+	t.Run("Invalid", testInvalidPageSize)
+	t.Run("Mismatch", testMismatchedPageSize)
+}
+
+/*
+Creates a pager with the given page size, writes to the last bytes of
+two pages, and reopens the pager without specifying a page size, checking
+that the page size is read from the superblock and the data is intact.
+*/
+func stagePageSize(pagesize int64) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+		dbname := utils.GetTempDbFile(t)
+		p, err := pager.NewWithPageSize(dbname, pagesize)
+		if err != nil {
+			t.Fatal("Failed to create a new pager:", err)
+		}
+		if p.GetPageSize() != pagesize {
+			t.Fatalf("Expected page size %d, but found %d", pagesize, p.GetPageSize())
+		}
+		data := []byte("tail")
+		for range 2 {
+			page := getNewPage(t, p, false)
+			if int64(len(page.GetData())) != pagesize {
+				t.Fatalf("Expected page to hold %d bytes, but it holds %d", pagesize, len(page.GetData()))
+			}
+			page.Update(data, pagesize-int64(len(data)), int64(len(data)))
+			_ = p.PutPage(page)
+		}
+		if err = p.Close(); err != nil {
+			t.Fatal("Failed to close pager:", err)
+		}
+
+		p, err = pager.New(dbname)
+		if err != nil {
+			t.Fatal("Failed to reopen pager:", err)
+		}
+		defer p.Close()
+		if p.GetPageSize() != pagesize {
+			t.Fatalf("Expected reopened pager to have page size %d, but found %d", pagesize, p.GetPageSize())
+		}
+		if p.GetNumPages() != 2 {
+			t.Fatalf("Expected reopened pager to have 2 pages, but found %d", p.GetNumPages())
+		}
+		for pagenum := range int64(2) {
+			page := getPage(t, p, pagenum, true)
+			if !bytes.Equal(page.GetData()[pagesize-int64(len(data)):], data) {
+				t.Errorf("Data at the end of page %d not flushed properly", pagenum)
+			}
+		}
+	}
+}
+
+/*
+Checks that page sizes that aren't a multiple of the block size are rejected.
+*/
+func testInvalidPageSize(t *testing.T) {
+	t.Parallel()
+	dbname := utils.GetTempDbFile(t)
+	for _, pagesize := range []int64{0, -4096, 1000} {
+		if _, err := pager.NewWithPageSize(dbname, pagesize); err == nil {
+			t.Errorf("Expected page size %d to be rejected", pagesize)
+		}
+	}
+}
+
+/*
+Checks that reopening a database file with a different page size than it was created with fails.
+*/
+func testMismatchedPageSize(t *testing.T) {
+	t.Parallel()
+	dbname := utils.GetTempDbFile(t)
+	p, err := pager.NewWithPageSize(dbname, 16384)
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	if _, err = pager.NewWithPageSize(dbname, 4096); err == nil {
+		t.Error("Expected reopening with a mismatched page size to fail")
+	}
+}
@@ -0,0 +1,70 @@
+package pager_test
+
+import (
+	"errors"
+	"testing"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/pager"
+)
+
+func TestPinBudget(t *testing.T) {
+	t.Run("HighWaterMark", testPinHighWaterMark)
+	t.Run("BudgetExceeded", testPinBudgetExceeded)
+}
+
+// Pins and unpins pages, checking that the number of pinned pages is tracked
+// and that the high-water mark stays at the most pages pinned at once
+func testPinHighWaterMark(t *testing.T) {
+	p := setupPager(t)
+	pages := make([]*pager.Page, 0)
+	for i := 0; i < 10; i++ {
+		pages = append(pages, getNewPage(t, p, false))
+	}
+	// Pinning an already pinned page again doesn't pin another page
+	_ = getPage(t, p, 0, true)
+	if n := p.GetNumPinned(); n != 10 {
+		t.Errorf("Expected 10 pinned pages, but got %d", n)
+	}
+	for _, page := range pages[:5] {
+		_ = p.PutPage(page)
+	}
+	if n := p.GetNumPinned(); n != 6 {
+		t.Errorf("Expected 6 pinned pages after putting 5, but got %d", n)
+	}
+	if hwm := p.GetPinHighWaterMark(); hwm != 10 {
+		t.Errorf("Expected a high-water mark of 10, but got %d", hwm)
+	}
+	// Repinning the unpinned pages raises the high-water mark again
+	for pn := int64(1); pn < 5; pn++ {
+		_ = getPage(t, p, pn, true)
+	}
+	_ = getNewPage(t, p, true)
+	if hwm := p.GetPinHighWaterMark(); hwm != 11 {
+		t.Errorf("Expected a high-water mark of 11, but got %d", hwm)
+	}
+	for _, page := range pages[5:] {
+		_ = p.PutPage(page)
+	}
+}
+
+// Pins most of the buffer, checking that only budgets larger than the whole buffer are rejected with a clear error,
+// since pages pinned by other operations are released as they finish
+func testPinBudgetExceeded(t *testing.T) {
+	p := setupPager(t)
+	numPinned := config.MaxPagesInBuffer - 4
+	for i := 0; i < numPinned; i++ {
+		_ = getNewPage(t, p, true)
+	}
+	if err := p.CheckPinBudget(config.MaxPagesInBuffer); err != nil {
+		t.Errorf("Expected a budget of the whole buffer to fit, but got %v", err)
+	}
+	err := p.CheckPinBudget(config.MaxPagesInBuffer + 1)
+	if !errors.Is(err, pager.ErrPinBudgetExceeded) {
+		t.Fatalf("Expected a budget larger than the buffer to fail with %q, but got %v", pager.ErrPinBudgetExceeded, err)
+	}
+	t.Log(err)
+	if hwm := p.GetPinHighWaterMark(); hwm != int64(numPinned) {
+		t.Errorf("Expected a high-water mark of %d, but got %d", numPinned, hwm)
+	}
+}
@@ -0,0 +1,100 @@
+package pager_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/pager"
+)
+
+func TestResizeBuffer(t *testing.T) {
+	t.Run("Grow", testResizeBufferGrow)
+	t.Run("Shrink", testResizeBufferShrink)
+	t.Run("TooSmall", testResizeBufferTooSmall)
+}
+
+// Fills the buffer with pinned pages, checking that growing it makes room for more
+func testResizeBufferGrow(t *testing.T) {
+	p := setupPager(t)
+	if size := p.GetBufferSize(); size != config.MaxPagesInBuffer {
+		t.Errorf("Expected a default buffer size of %d, but got %d", config.MaxPagesInBuffer, size)
+	}
+	for i := 0; i < config.MaxPagesInBuffer; i++ {
+		_ = getNewPage(t, p, true)
+	}
+	if _, err := p.GetNewPage(); !errors.Is(err, pager.ErrRanOutOfPages) {
+		t.Fatalf("Expected getting a page from a full buffer to fail with %q, but got %v", pager.ErrRanOutOfPages, err)
+	}
+
+	if err := p.ResizeBuffer(config.MaxPagesInBuffer + 8); err != nil {
+		t.Fatal("Failed to grow the buffer:", err)
+	}
+	if size := p.GetBufferSize(); size != config.MaxPagesInBuffer+8 {
+		t.Errorf("Expected a buffer size of %d, but got %d", config.MaxPagesInBuffer+8, size)
+	}
+	for i := 0; i < 8; i++ {
+		_ = getNewPage(t, p, true)
+	}
+	if _, err := p.GetNewPage(); !errors.Is(err, pager.ErrRanOutOfPages) {
+		t.Errorf("Expected getting a page from the full, grown buffer to fail with %q, but got %v", pager.ErrRanOutOfPages, err)
+	}
+}
+
+// Writes to more pages than the shrunk buffer will hold, checking that shrinking the buffer
+// limits how many pages can be pinned and that every page's data survives being evicted
+func testResizeBufferShrink(t *testing.T) {
+	p := setupPager(t)
+	const numPages = 20
+	for i := 0; i < numPages; i++ {
+		page := getNewPage(t, p, false)
+		page.Update([]byte{byte(i + 1)}, 0, 1)
+		_ = p.PutPage(page)
+	}
+
+	if err := p.ResizeBuffer(4); err != nil {
+		t.Fatal("Failed to shrink the buffer:", err)
+	}
+	if size := p.GetBufferSize(); size != 4 {
+		t.Errorf("Expected a buffer size of 4, but got %d", size)
+	}
+	pages := make([]*pager.Page, 0)
+	for pn := int64(0); pn < 4; pn++ {
+		pages = append(pages, getPage(t, p, pn, false))
+	}
+	if _, err := p.GetPage(4); !errors.Is(err, pager.ErrRanOutOfPages) {
+		t.Errorf("Expected pinning a fifth page in the shrunk buffer to fail with %q, but got %v", pager.ErrRanOutOfPages, err)
+	}
+	for _, page := range pages {
+		_ = p.PutPage(page)
+	}
+
+	for pn := int64(0); pn < numPages; pn++ {
+		page := getPage(t, p, pn, false)
+		if !bytes.Equal(page.GetData()[:1], []byte{byte(pn + 1)}) {
+			t.Errorf("Expected page %d to still hold %d, but it holds %d", pn, pn+1, page.GetData()[0])
+		}
+		_ = p.PutPage(page)
+	}
+}
+
+// Pins pages, checking that the buffer can't be shrunk below the number of pinned pages
+func testResizeBufferTooSmall(t *testing.T) {
+	p := setupPager(t)
+	for i := 0; i < 10; i++ {
+		_ = getNewPage(t, p, true)
+	}
+	if err := p.ResizeBuffer(9); !errors.Is(err, pager.ErrBufferTooSmall) {
+		t.Errorf("Expected shrinking below the pinned pages to fail with %q, but got %v", pager.ErrBufferTooSmall, err)
+	}
+	if size := p.GetBufferSize(); size != config.MaxPagesInBuffer {
+		t.Errorf("Expected a failed resize to keep the buffer size at %d, but got %d", config.MaxPagesInBuffer, size)
+	}
+	if err := p.ResizeBuffer(0); err == nil {
+		t.Error("Expected resizing the buffer to 0 pages to fail")
+	}
+	if err := p.ResizeBuffer(10); err != nil {
+		t.Errorf("Expected shrinking to exactly the pinned pages to succeed, but got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package pager_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"dinodb/pkg/pager"
+)
+
+func TestPinRetry(t *testing.T) {
+	t.Run("SucceedsAfterRelease", testPinRetrySucceedsAfterRelease)
+	t.Run("Bounded", testPinRetryBounded)
+	t.Run("Disabled", testPinRetryDisabled)
+}
+
+// fillBuffer pins every page in the buffer, returning the pinned pages
+func fillBuffer(t *testing.T, p *pager.Pager) []*pager.Page {
+	pages := make([]*pager.Page, 0, p.GetBufferSize())
+	for i := int64(0); i < p.GetBufferSize(); i++ {
+		pages = append(pages, getNewPage(t, p, false))
+	}
+	return pages
+}
+
+// Saturates the buffer with pins, then releases some of them while other goroutines are waiting
+// for new pages or pages on disk, checking that the waiting goroutines get their pages instead of failing
+func testPinRetrySucceedsAfterRelease(t *testing.T) {
+	p := setupPager(t)
+	if err := p.SetPinRetry(10, time.Millisecond); err != nil {
+		t.Fatal("Failed to set pin retry:", err)
+	}
+	const numWaiters = 4
+	// These pages are evicted to disk when the buffer is filled
+	onDisk := make([]int64, numWaiters)
+	for i := range onDisk {
+		page := getNewPage(t, p, false)
+		onDisk[i] = page.GetPageNum()
+		_ = p.PutPage(page)
+	}
+	pages := fillBuffer(t, p)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, numWaiters)
+	for i := 0; i < numWaiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var page *pager.Page
+			var err error
+			if i%2 == 0 {
+				page, err = p.GetNewPage()
+			} else {
+				page, err = p.GetPage(onDisk[i])
+			}
+			if err == nil {
+				err = p.PutPage(page)
+			}
+			errs <- err
+		}(i)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	for _, page := range pages[numWaiters:] {
+		if err := p.PutPage(page); err != nil {
+			t.Fatal("Failed to put page:", err)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected getting a page to succeed once pins were released, but got %v", err)
+		}
+	}
+}
+
+// Keeps the buffer saturated, checking that retrying gives up with ErrRanOutOfPages
+func testPinRetryBounded(t *testing.T) {
+	p := setupPager(t)
+	if err := p.SetPinRetry(3, time.Millisecond); err != nil {
+		t.Fatal("Failed to set pin retry:", err)
+	}
+	_ = fillBuffer(t, p)
+	start := time.Now()
+	if _, err := p.GetNewPage(); !errors.Is(err, pager.ErrRanOutOfPages) {
+		t.Fatalf("Expected retrying to give up with %q, but got %v", pager.ErrRanOutOfPages, err)
+	}
+	// Waits of 1, 2, and 4 milliseconds
+	if elapsed := time.Since(start); elapsed < 7*time.Millisecond {
+		t.Errorf("Expected retrying to back off for at least 7ms, but it gave up after %v", elapsed)
+	}
+	if err := p.SetPinRetry(-1, time.Millisecond); err == nil {
+		t.Error("Expected an error for a negative number of retries")
+	}
+}
+
+// Checks that pagers don't retry by default
+func testPinRetryDisabled(t *testing.T) {
+	p := setupPager(t)
+	if retries, _ := p.GetPinRetry(); retries != 0 {
+		t.Errorf("Expected pin retry to be disabled by default, but got %d retries", retries)
+	}
+	_ = fillBuffer(t, p)
+	if _, err := p.GetNewPage(); !errors.Is(err, pager.ErrRanOutOfPages) {
+		t.Errorf("Expected getting a page from a full buffer to fail with %q, but got %v", pager.ErrRanOutOfPages, err)
+	}
+}
@@ -0,0 +1,109 @@
+package pager_test
+
+import (
+	"sync"
+	"testing"
+
+	"dinodb/pkg/pager"
+)
+
+func TestPagerStats(t *testing.T) {
+	t.Run("Counts", testStatsCounts)
+	t.Run("Concurrent", testStatsConcurrent)
+	t.Run("Repl", testStatsRepl)
+}
+
+// checkStats errors the test if the pager's stats aren't the expected ones
+func checkStats(t *testing.T, p *pager.Pager, expected pager.PagerStats) {
+	if stats := p.Stats(); stats != expected {
+		t.Errorf("Expected stats %+v, but got %+v", expected, stats)
+	}
+}
+
+// Reads pages through a small buffer, checking the hits, misses, and evictions after each step
+func testStatsCounts(t *testing.T) {
+	p := setupPager(t)
+	if err := p.ResizeBuffer(4); err != nil {
+		t.Fatal("Failed to resize buffer:", err)
+	}
+	if ratio := p.Stats().HitRatio(); ratio != 0 {
+		t.Errorf("Expected a hit ratio of 0 before any reads, but got %v", ratio)
+	}
+	// Allocating pages is neither a hit nor a miss, but the fifth page evicts the first.
+	for range 5 {
+		p.PutPage(getNewPage(t, p, false))
+	}
+	checkStats(t, p, pager.PagerStats{Evictions: 1})
+	for pn := int64(1); pn < 5; pn++ {
+		p.PutPage(getPage(t, p, pn, false))
+	}
+	checkStats(t, p, pager.PagerStats{Hits: 4, Evictions: 1})
+	// Reading page 0 back in evicts page 1.
+	p.PutPage(getPage(t, p, 0, false))
+	checkStats(t, p, pager.PagerStats{Hits: 4, Misses: 1, Evictions: 2})
+	if ratio := p.Stats().HitRatio(); ratio != 0.8 {
+		t.Errorf("Expected a hit ratio of 0.8, but got %v", ratio)
+	}
+	// Shrinking the buffer evicts unpinned pages too.
+	if err := p.ResizeBuffer(2); err != nil {
+		t.Fatal("Failed to resize buffer:", err)
+	}
+	checkStats(t, p, pager.PagerStats{Hits: 4, Misses: 1, Evictions: 4})
+}
+
+// Reads pages from several goroutines at once, checking that every read is counted as exactly one hit or miss
+func testStatsConcurrent(t *testing.T) {
+	p := setupPager(t)
+	const numPages, numReaders, numReads = 8, 4, 500
+	// Each reader pins at most one page at a time, so a buffer with a frame per reader never runs out.
+	if err := p.ResizeBuffer(numReaders); err != nil {
+		t.Fatal("Failed to resize buffer:", err)
+	}
+	for range numPages {
+		p.PutPage(getNewPage(t, p, false))
+	}
+	before := p.Stats()
+	var wg sync.WaitGroup
+	for r := range numReaders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range numReads {
+				page, err := p.GetPage(int64((r + i) % numPages))
+				if err != nil {
+					t.Errorf("Failed to get page: %v", err)
+					return
+				}
+				p.PutPage(page)
+			}
+		}()
+	}
+	wg.Wait()
+	after := p.Stats()
+	if reads := (after.Hits - before.Hits) + (after.Misses - before.Misses); reads != numReaders*numReads {
+		t.Errorf("Expected %d reads to be counted, but counted %d", numReaders*numReads, reads)
+	}
+	if after.Misses-before.Misses == 0 || after.Evictions == before.Evictions {
+		t.Errorf("Expected reading twice the buffer's pages to miss and evict, but got %+v", after)
+	}
+}
+
+// Prints the stats through the REPL command, checking its output and usage check
+func testStatsRepl(t *testing.T) {
+	p := setupPager(t)
+	page := getNewPage(t, p, false)
+	p.PutPage(page)
+	for range 3 {
+		p.PutPage(getPage(t, p, page.GetPageNum(), false))
+	}
+	output, err := pager.HandlePagerStats(p, "pager_stats")
+	if err != nil {
+		t.Fatal("Failed to print stats:", err)
+	}
+	if expected := "hits: 3, misses: 0, evictions: 0, hit ratio: 100.0%\n"; output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+	if _, err := pager.HandlePagerStats(p, "pager_stats now"); err == nil {
+		t.Error("Expected a malformed pager_stats command to fail")
+	}
+}
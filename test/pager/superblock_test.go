@@ -0,0 +1,229 @@
+package pager_test
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"dinodb/pkg/pager"
+	"dinodb/test/utils"
+)
+
+// The offset of the format version in the superblock, after the magic string and the page size
+const superblockVersionOffset = int64(len("DINODBPG") + binary.MaxVarintLen64)
+
+// writeSuperblockVersion overwrites the format version in the superblock of the given file
+func writeSuperblockVersion(t *testing.T, dbname string, version int64) {
+	f, err := os.OpenFile(dbname, os.O_RDWR, 0666)
+	if err != nil {
+		t.Fatal("Failed to open database file:", err)
+	}
+	defer f.Close()
+	data := make([]byte, binary.MaxVarintLen64)
+	binary.PutVarint(data, version)
+	if _, err := f.WriteAt(data, superblockVersionOffset); err != nil {
+		t.Fatal("Failed to write superblock version:", err)
+	}
+}
+
+func TestPagerSuperblock(t *testing.T) {
+	t.Run("Defaults", testSuperblockDefaults)
+	t.Run("RoundTrip", testSuperblockRoundTrip)
+	t.Run("FlushedWithPages", testSuperblockFlushedWithPages)
+	t.Run("LegacyVersion", testSuperblockLegacyVersion)
+	t.Run("UnrecordedCreationTime", testSuperblockUnrecordedCreationTime)
+	t.Run("ComparatorID", testSuperblockComparatorID)
+	t.Run("UnsupportedVersion", testSuperblockUnsupportedVersion)
+}
+
+// Checks the superblock metadata of a new file
+func testSuperblockDefaults(t *testing.T) {
+	p := setupPager(t)
+	closeAndReopen(t, p)
+	if v := p.GetFormatVersion(); v != pager.SuperblockVersion {
+		t.Errorf("Expected format version %d, but got %d", pager.SuperblockVersion, v)
+	}
+	if head := p.GetFreeListHead(); head != pager.NoPage {
+		t.Errorf("Expected an empty free list, but its head is page %d", head)
+	}
+	if flags := p.GetFeatureFlags(); flags != 0 {
+		t.Errorf("Expected no feature flags, but got %b", flags)
+	}
+}
+
+// Sets various feature flags and free list heads, checking that each survives reopening the pager
+func testSuperblockRoundTrip(t *testing.T) {
+	tests := map[string]struct {
+		flags pager.FeatureFlags
+		head  int64
+	}{
+		"None":    {0, pager.NoPage},
+		"One":     {1, 0},
+		"Several": {1<<0 | 1<<3 | 1<<17, 42},
+		"All":     {^pager.FeatureFlags(0), 1 << 40},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := setupPager(t)
+			// Pages shouldn't be affected by the superblock
+			page := getNewPage(t, p, false)
+			page.Update([]byte("data"), 0, 4)
+			_ = p.PutPage(page)
+			p.SetFeatureFlags(test.flags)
+			p.SetFreeListHead(test.head)
+			closeAndReopen(t, p)
+			if flags := p.GetFeatureFlags(); flags != test.flags {
+				t.Errorf("Expected feature flags %b, but got %b", test.flags, flags)
+			}
+			if head := p.GetFreeListHead(); head != test.head {
+				t.Errorf("Expected free list head %d, but got %d", test.head, head)
+			}
+			if p.GetNumPages() != 1 {
+				t.Fatalf("Expected 1 page, but got %d", p.GetNumPages())
+			}
+			page = getPage(t, p, 0, true)
+			if string(page.GetData()[:4]) != "data" {
+				t.Error("Expected the page's data to be intact")
+			}
+		})
+	}
+}
+
+// Checks that flushing all pages (as checkpoints do) also writes the superblock
+func testSuperblockFlushedWithPages(t *testing.T) {
+	p := setupPager(t)
+	p.SetFeatureFlags(5)
+	p.FlushAllPages()
+	// Open a copy of the file, since the file itself is locked while p has it open
+	data, err := os.ReadFile(p.GetFileName())
+	if err != nil {
+		t.Fatal("Failed to read the pager's file:", err)
+	}
+	copyName := utils.GetTempDbFile(t)
+	if err = os.WriteFile(copyName, data, 0666); err != nil {
+		t.Fatal("Failed to copy the pager's file:", err)
+	}
+	reopened, err := pager.New(copyName)
+	if err != nil {
+		t.Fatal("Failed to open a copy of the file:", err)
+	}
+	defer reopened.Close()
+	if flags := reopened.GetFeatureFlags(); flags != 5 {
+		t.Errorf("Expected flushed feature flags %b, but got %b", 5, flags)
+	}
+}
+
+// Checks that files from before the superblock was versioned open with no free list or feature flags
+func testSuperblockLegacyVersion(t *testing.T) {
+	t.Parallel()
+	dbname := utils.GetTempDbFile(t)
+	p, err := pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	p.SetFreeListHead(3)
+	p.SetFeatureFlags(1)
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	writeSuperblockVersion(t, dbname, 0)
+
+	p, err = pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to open legacy file:", err)
+	}
+	if v := p.GetFormatVersion(); v != 0 {
+		t.Errorf("Expected format version 0, but got %d", v)
+	}
+	if head := p.GetFreeListHead(); head != pager.NoPage {
+		t.Errorf("Expected a legacy file to have an empty free list, but its head is page %d", head)
+	}
+	if flags := p.GetFeatureFlags(); flags != 0 {
+		t.Errorf("Expected a legacy file to have no feature flags, but got %b", flags)
+	}
+	// Changing the metadata upgrades the file to the current version
+	p.SetFeatureFlags(2)
+	closeAndReopen(t, p)
+	defer p.Close()
+	if v := p.GetFormatVersion(); v != pager.SuperblockVersion {
+		t.Errorf("Expected the file to be upgraded to format version %d, but got %d", pager.SuperblockVersion, v)
+	}
+}
+
+// Checks that version 1 files, which don't record when they were created, report the zero time
+func testSuperblockUnrecordedCreationTime(t *testing.T) {
+	t.Parallel()
+	dbname := utils.GetTempDbFile(t)
+	p, err := pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	if p.GetCreatedAt().IsZero() {
+		t.Error("Expected a new file to record when it was created")
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	writeSuperblockVersion(t, dbname, 1)
+
+	p, err = pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to open version 1 file:", err)
+	}
+	defer p.Close()
+	if createdAt := p.GetCreatedAt(); !createdAt.IsZero() {
+		t.Errorf("Expected a version 1 file to have no creation time, but got %v", createdAt)
+	}
+}
+
+// Records a comparator id, checking that it survives reopening the file,
+// and that version 2 files, which don't record one, read as the default id 0
+func testSuperblockComparatorID(t *testing.T) {
+	t.Parallel()
+	dbname := utils.GetTempDbFile(t)
+	p, err := pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	p.SetComparatorID(7)
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	p, err = pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to reopen pager:", err)
+	}
+	if id := p.GetComparatorID(); id != 7 {
+		t.Errorf("Expected comparator id 7 after reopening, but got %d", id)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	writeSuperblockVersion(t, dbname, 2)
+
+	p, err = pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to open version 2 file:", err)
+	}
+	defer p.Close()
+	if id := p.GetComparatorID(); id != 0 {
+		t.Errorf("Expected a version 2 file to have the default comparator id 0, but got %d", id)
+	}
+}
+
+// Checks that files with a newer format version than the pager supports are rejected
+func testSuperblockUnsupportedVersion(t *testing.T) {
+	t.Parallel()
+	dbname := utils.GetTempDbFile(t)
+	p, err := pager.New(dbname)
+	if err != nil {
+		t.Fatal("Failed to create a new pager:", err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal("Failed to close pager:", err)
+	}
+	writeSuperblockVersion(t, dbname, pager.SuperblockVersion+1)
+	if _, err = pager.New(dbname); err == nil {
+		t.Error("Expected opening a file with an unsupported format version to fail")
+	}
+}
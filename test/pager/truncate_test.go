@@ -0,0 +1,58 @@
+package pager_test
+
+import (
+	"testing"
+)
+
+func TestPagerTruncate(t *testing.T) {
+	t.Run("DropsPages", testTruncateDropsPages)
+	t.Run("PinnedPage", testTruncatePinnedPage)
+}
+
+// Truncates a pager with cached and flushed pages, checking that the dropped pages are gone
+// and that new pages reuse their page numbers, including after reopening
+func testTruncateDropsPages(t *testing.T) {
+	p := setupPager(t)
+	for i := 0; i < 10; i++ {
+		p.PutPage(getNewPage(t, p, false))
+	}
+	closeAndReopen(t, p)
+	if err := p.Truncate(3); err != nil {
+		t.Fatal("Failed to truncate pager:", err)
+	}
+	if n := p.GetNumPages(); n != 3 {
+		t.Errorf("Expected 3 pages after truncating, but found %d", n)
+	}
+	if _, err := p.GetPage(3); err == nil {
+		t.Error("Expected getting a truncated page to fail")
+	}
+	page := getNewPage(t, p, false)
+	if page.GetPageNum() != 3 {
+		t.Errorf("Expected the next new page to be page 3, but got %d", page.GetPageNum())
+	}
+	p.PutPage(page)
+	closeAndReopen(t, p)
+	if n := p.GetNumPages(); n != 4 {
+		t.Errorf("Expected 4 pages after reopening, but found %d", n)
+	}
+	p.Close()
+}
+
+// Checks that truncating away a pinned page fails without dropping any pages
+func testTruncatePinnedPage(t *testing.T) {
+	p := setupPager(t)
+	for i := 0; i < 3; i++ {
+		p.PutPage(getNewPage(t, p, false))
+	}
+	page := getPage(t, p, 2, false)
+	if err := p.Truncate(1); err == nil {
+		t.Error("Expected truncating a pinned page to fail")
+	}
+	if n := p.GetNumPages(); n != 3 {
+		t.Errorf("Expected the failed truncate to keep 3 pages, but found %d", n)
+	}
+	p.PutPage(page)
+	if err := p.Truncate(1); err != nil {
+		t.Error("Failed to truncate pager once its page was put:", err)
+	}
+}
@@ -0,0 +1,125 @@
+package recovery_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+)
+
+// readLog returns the contents of the given database's live log
+func readLog(t *testing.T, db *database.Database) []byte {
+	live, err := os.ReadFile(filepath.Join(db.GetBasePath(), config.LogFileName))
+	if err != nil {
+		t.Fatal("Failed to read log:", err)
+	}
+	return live
+}
+
+// readArchiveAndLog reads the archive at archivePath and the live log of the given database,
+// checking that the archive is a prefix of the log that ends on a log boundary
+func readArchiveAndLog(t *testing.T, db *database.Database, archivePath string) (archive []byte, live []byte) {
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatal("Failed to read archive:", err)
+	}
+	live = readLog(t, db)
+	if !bytes.HasPrefix(live, archive) {
+		t.Fatal("Expected the archive to be a prefix of the live log")
+	}
+	if len(archive) > 0 && archive[len(archive)-1] != '\n' {
+		t.Error("Expected the archive to end on a log boundary")
+	}
+	return archive, live
+}
+
+func TestRecoveryArchive(t *testing.T) {
+	t.Run("Prefix", testArchivePrefix)
+	t.Run("ConcurrentWriters", testArchiveConcurrentWriters)
+}
+
+// Archives the log between two committed transactions, checking that the archive holds exactly
+// the log up to that point and rebuilds only the first transaction's edits
+func testArchivePrefix(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 10; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+
+	archivePath := filepath.Join(t.TempDir(), "archive.log")
+	if err := rm.ArchiveLog(archivePath); err != nil {
+		t.Fatal("Failed to archive log:", err)
+	}
+	sizeAtArchive := len(readLog(t, db))
+
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(10); i < 20; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i)
+	}
+	updateTableEntry(t, db, tm, rm, clientId, tableName, 0, 100)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	archive, live := readArchiveAndLog(t, db, archivePath)
+	if len(archive) != sizeAtArchive {
+		t.Errorf("Expected the archive to hold the %d bytes logged before archiving, but it holds %d", sizeAtArchive, len(archive))
+	}
+	if len(live) <= len(archive) {
+		t.Error("Expected the live log to keep growing after archiving")
+	}
+
+	rebuilt := setupRebuild(t)
+	if err := recovery.RebuildFromLog(rebuilt, archivePath); err != nil {
+		t.Fatal("Error rebuilding from archive:", err)
+	}
+	expected := make(map[int64]int64)
+	for i := int64(0); i < 10; i++ {
+		expected[i] = i
+	}
+	checkRebuiltTable(t, rebuilt, tableName, expected, []int64{10, 19})
+}
+
+// Archives the log repeatedly while other clients write to it, checking that every archive
+// is a prefix of the log that ends on a log boundary and can be rebuilt
+func testArchiveConcurrentWriters(t *testing.T) {
+	db, tm, rm, _ := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.HashIndexType)
+	var wg sync.WaitGroup
+	for w := int64(0); w < 4; w++ {
+		wg.Add(1)
+		go func(w int64) {
+			defer wg.Done()
+			for i := int64(0); i < 10; i++ {
+				clientId := uuid.New()
+				startTransaction(t, db, tm, rm, clientId)
+				insertIntoTable(t, db, tm, rm, clientId, tableName, w*100+i, i)
+				commitTransaction(t, db, tm, rm, clientId)
+			}
+		}(w)
+	}
+	archiveDir := t.TempDir()
+	var archives []string
+	for i := 0; i < 10; i++ {
+		archivePath := filepath.Join(archiveDir, uuid.NewString())
+		if err := rm.ArchiveLog(archivePath); err != nil {
+			t.Fatal("Failed to archive log:", err)
+		}
+		archives = append(archives, archivePath)
+	}
+	wg.Wait()
+	for _, archivePath := range archives {
+		readArchiveAndLog(t, db, archivePath)
+		if err := recovery.RebuildFromLog(setupRebuild(t), archivePath); err != nil {
+			t.Error("Error rebuilding from archive:", err)
+		}
+	}
+}
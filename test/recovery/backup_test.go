@@ -0,0 +1,121 @@
+package recovery_test
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dinodb/pkg/database"
+)
+
+// blockCopy puts a unix socket into the given folder, which can't be copied, and
+// returns a function that removes it again. The socket's name sorts after every
+// other file in the folder, so copying the folder fails partway through
+func blockCopy(t *testing.T, folder string) func() {
+	listener, err := net.Listen("unix", filepath.Join(folder, "zzz.sock"))
+	if err != nil {
+		t.Fatal("Failed to create unix socket:", err)
+	}
+	return func() {
+		listener.Close()
+	}
+}
+
+// readFolder returns the contents of every file in the given folder, keyed by file name
+func readFolder(t *testing.T, folder string) map[string][]byte {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal("Failed to read folder:", err)
+	}
+	contents := make(map[string][]byte)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(folder, entry.Name()))
+		if err != nil {
+			t.Fatal("Failed to read file:", err)
+		}
+		contents[entry.Name()] = data
+	}
+	return contents
+}
+
+func TestRecoveryBackup(t *testing.T) {
+	t.Run("CheckpointCopyFails", testBackupCheckpointCopyFails)
+	t.Run("InterruptedSwap", testBackupInterruptedSwap)
+}
+
+// Makes copying the database fail during a checkpoint, checking that the checkpoint fails,
+// the previous recovery backup is left untouched, and committed data still survives a crash
+func testBackupCheckpointCopyFails(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 10; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+
+	recoveryFolder := filepath.Clean(db.GetBasePath()) + "-recovery"
+	backup := readFolder(t, recoveryFolder)
+	numCheckpoints := rm.GetNumCheckpoints()
+
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(10); i < 20; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	unblock := blockCopy(t, db.GetBasePath())
+	if err := rm.Checkpoint(); err == nil {
+		t.Fatal("Expected the checkpoint to fail when the database can't be copied")
+	}
+	unblock()
+
+	if n := rm.GetNumCheckpoints(); n != numCheckpoints {
+		t.Errorf("Expected the failed checkpoint to not be counted, but %d checkpoints were taken", n)
+	}
+	after := readFolder(t, recoveryFolder)
+	if len(after) != len(backup) {
+		t.Errorf("Expected the recovery backup to still hold %d files, but it holds %d", len(backup), len(after))
+	}
+	for name, data := range backup {
+		if !bytes.Equal(after[name], data) {
+			t.Errorf("Expected %q in the recovery backup to be left untouched", name)
+		}
+	}
+	if _, err := os.Stat(recoveryFolder + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Expected the partial copy to be removed")
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 20; i++ {
+		checkFind(t, db, tm, clientId, tableName, i, i)
+	}
+}
+
+// Leaves only the previous recovery backup behind, as a crash partway through
+// swapping in a new backup would, checking that it is used to recover
+func testBackupInterruptedSwap(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 10; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+
+	recoveryFolder := filepath.Clean(db.GetBasePath()) + "-recovery"
+	if err := os.Rename(recoveryFolder, recoveryFolder+".old"); err != nil {
+		t.Fatal("Failed to move the recovery backup aside:", err)
+	}
+	defer os.RemoveAll(recoveryFolder + ".old")
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 10; i++ {
+		checkFind(t, db, tm, clientId, tableName, i, i)
+	}
+}
@@ -0,0 +1,202 @@
+package recovery_test
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"dinodb/pkg/concurrency"
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+
+	"github.com/google/uuid"
+)
+
+// runRedoWorkload runs transactions of random inserts, updates, and deletes over numKeys keys of the tables in committed,
+// committing them all unless leaveRunning is set, in which case the last one is left running.
+// Updates committed to hold the committed entries of each table.
+func runRedoWorkload(tb testing.TB, db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager,
+	committed map[string]map[int64]int64, numTxs int, editsPerTx int, numKeys int64, seed int64, leaveRunning bool) {
+	rng := rand.New(rand.NewSource(seed))
+	tables := slices.Sorted(maps.Keys(committed))
+	for tx := 0; tx < numTxs; tx++ {
+		clientId := uuid.New()
+		if err := recovery.HandleTransaction(db, tm, rm, "transaction begin", clientId); err != nil {
+			tb.Fatal("Error starting a transaction:", err)
+		}
+		running := make(map[string]map[int64]int64)
+		for _, table := range tables {
+			running[table] = maps.Clone(committed[table])
+		}
+		for edit := 0; edit < editsPerTx; edit++ {
+			table := tables[rng.Intn(len(tables))]
+			key, value := rng.Int63n(numKeys), rng.Int63()
+			var err error
+			_, exists := running[table][key]
+			switch {
+			case !exists:
+				err = recovery.HandleInsert(db, tm, rm, fmt.Sprintf("insert %d %d into %s", key, value, table), clientId)
+				running[table][key] = value
+			case rng.Intn(3) == 0:
+				err = recovery.HandleDelete(db, tm, rm, fmt.Sprintf("delete %d from %s", key, table), clientId)
+				delete(running[table], key)
+			default:
+				err = recovery.HandleUpdate(db, tm, rm, fmt.Sprintf("update %s %d %d", table, key, value), clientId)
+				running[table][key] = value
+			}
+			if err != nil {
+				tb.Fatal("Error editing table:", err)
+			}
+		}
+		if leaveRunning && tx == numTxs-1 {
+			return
+		}
+		if err := recovery.HandleTransaction(db, tm, rm, "transaction commit", clientId); err != nil {
+			tb.Fatal("Error committing a transaction:", err)
+		}
+		for _, table := range tables {
+			committed[table] = running[table]
+		}
+	}
+}
+
+// copyCrashedDatabase copies a database's folder and its recovery folder as they are, as if it had crashed,
+// returning the copy's folder name
+func copyCrashedDatabase(tb testing.TB, dbName string) string {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		tb.Fatal("Failed to create a folder for the copy:", err)
+	}
+	tb.Cleanup(func() { os.RemoveAll(dir) })
+	copyName := filepath.Join(dir, "copy")
+	dbName = filepath.Clean(dbName)
+	for _, suffix := range []string{"", "-recovery"} {
+		if err := os.CopyFS(copyName+suffix, os.DirFS(dbName+suffix)); err != nil {
+			tb.Fatal("Failed to copy the database:", err)
+		}
+	}
+	return copyName
+}
+
+// recoverCopy opens a copied database and recovers it, with or without batching the redo
+func recoverCopy(tb testing.TB, dbName string, batched bool) *database.Database {
+	db, err := recovery.Prime(dbName)
+	if err != nil {
+		tb.Fatal("Error priming database:", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+	rm, err := recovery.NewRecoveryManager(db, tm, filepath.Join(dbName, config.LogFileName))
+	if err != nil {
+		tb.Fatal("Error constructing recovery manager:", err)
+	}
+	rm.SetBatchedRedo(batched)
+	if err := rm.Recover(); err != nil {
+		tb.Fatal("Error recovering:", err)
+	}
+	return db
+}
+
+// Crashes a database after a checkpoint and a long mix of edits, then recovers copies of it with and
+// without batching the redo, checking that both end up with the committed entries
+func TestBatchedRedo(t *testing.T) {
+	db, tm, rm, _ := setupRecovery(t, "")
+	tables := []string{createTable(t, db, rm, database.BTreeIndexType), createTable(t, db, rm, database.HashIndexType)}
+	committed := map[string]map[int64]int64{tables[0]: {}, tables[1]: {}}
+	runRedoWorkload(t, db, tm, rm, committed, 5, 20, 50, 1, false)
+	checkpoint(t, rm)
+	// The edits after the checkpoint are redone, then the last, running transaction's edits are undone.
+	runRedoWorkload(t, db, tm, rm, committed, 30, 40, 50, 2, true)
+	dbName := db.GetBasePath()
+	naive := recoverCopy(t, copyCrashedDatabase(t, dbName), false)
+	batched := recoverCopy(t, copyCrashedDatabase(t, dbName), true)
+
+	for _, tableName := range tables {
+		naiveTable, err := naive.GetTable(tableName)
+		if err != nil {
+			t.Fatal("Failed to get table:", err)
+		}
+		batchedTable, err := batched.GetTable(tableName)
+		if err != nil {
+			t.Fatal("Failed to get table:", err)
+		}
+		naiveCount, naiveChecksum, err := database.Digest(naiveTable)
+		if err != nil {
+			t.Fatal("Failed to digest table:", err)
+		}
+		batchedCount, batchedChecksum, err := database.Digest(batchedTable)
+		if err != nil {
+			t.Fatal("Failed to digest table:", err)
+		}
+		if naiveCount != batchedCount || naiveChecksum != batchedChecksum {
+			t.Errorf("Expected table %s to hold the same %d entries after a batched redo, but got %d with a different checksum",
+				tableName, naiveCount, batchedCount)
+		}
+		if batchedCount != int64(len(committed[tableName])) {
+			t.Errorf("Expected table %s to hold %d entries, but got %d", tableName, len(committed[tableName]), batchedCount)
+		}
+		for key, value := range committed[tableName] {
+			found, err := batchedTable.Find(key)
+			if err != nil || found.Value != value {
+				t.Errorf("Expected committed entry (%d, %d) in table %s, but got %v, %v", key, value, tableName, found, err)
+			}
+		}
+	}
+}
+
+// Run with: go test -run XXX -bench Redo ./test/recovery/
+// Recovers a log that repeatedly edits a small set of keys, with and without batching the redo.
+func BenchmarkRedo(b *testing.B) {
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		b.Fatal("Failed to create random database folder:", err)
+	}
+	b.Cleanup(func() {
+		os.RemoveAll(dbName)
+		os.RemoveAll(dbName + "-recovery")
+	})
+	db, err := recovery.Prime(dbName)
+	if err != nil {
+		b.Fatal("Error priming database:", err)
+	}
+	logFileName := filepath.Join(dbName, config.LogFileName)
+	if err := db.CreateLogFile(logFileName); err != nil {
+		b.Fatal("Error creating log file:", err)
+	}
+	tm := concurrency.NewTransactionManager(concurrency.NewResourceLockManager())
+	rm, err := recovery.NewRecoveryManager(db, tm, logFileName)
+	if err != nil {
+		b.Fatal("Error constructing recovery manager:", err)
+	}
+	committed := make(map[string]map[int64]int64)
+	for i, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		tableName := fmt.Sprintf("bench%d", i)
+		if _, err := db.CreateTable(tableName, indexType); err != nil {
+			b.Fatal("Error creating table:", err)
+		}
+		if err := rm.Table(string(indexType), tableName); err != nil {
+			b.Fatal("Error creating table:", err)
+		}
+		committed[tableName] = make(map[int64]int64)
+	}
+	if err := rm.Checkpoint(); err != nil {
+		b.Fatal("Error creating a checkpoint:", err)
+	}
+	runRedoWorkload(b, db, tm, rm, committed, 100, 100, 500, 1, false)
+
+	for _, batched := range []bool{false, true} {
+		b.Run(map[bool]string{false: "Naive", true: "Batched"}[batched], func(b *testing.B) {
+			for range b.N {
+				b.StopTimer()
+				copyName := copyCrashedDatabase(b, dbName)
+				b.StartTimer()
+				recoverCopy(b, copyName, batched)
+			}
+		})
+	}
+}
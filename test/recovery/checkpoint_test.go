@@ -0,0 +1,235 @@
+package recovery_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+	"dinodb/test/utils"
+
+	"github.com/google/uuid"
+)
+
+// The interval the checkpoint scheduler runs at in these tests
+const checkpointInterval = 10 * time.Millisecond
+
+// waitForCheckpoint waits until the recovery manager has taken more than numCheckpoints checkpoints
+func waitForCheckpoint(t *testing.T, getNumCheckpoints func() int, numCheckpoints int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for getNumCheckpoints() <= numCheckpoints {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for a checkpoint")
+		}
+		time.Sleep(checkpointInterval)
+	}
+}
+
+func TestPauseCheckpoints(t *testing.T) {
+	t.Run("Scheduler", testPauseCheckpointsScheduler)
+	t.Run("Manual", testPauseCheckpointsManual)
+}
+
+// Bulk loads a table while the checkpoint scheduler is paused, checking that no checkpoint
+// happens during the load and that the first checkpoint after resuming survives a crash
+func testPauseCheckpointsScheduler(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	table, err := db.GetTable(tableName)
+	if err != nil {
+		t.Fatal("Failed to get table:", err)
+	}
+	if err := rm.StartCheckpointScheduler(checkpointInterval); err != nil {
+		t.Fatal("Failed to start checkpoint scheduler:", err)
+	}
+	defer rm.StopCheckpointScheduler()
+	if err := rm.StartCheckpointScheduler(checkpointInterval); err == nil {
+		t.Error("Expected starting the checkpoint scheduler twice to fail")
+	}
+	waitForCheckpoint(t, rm.GetNumCheckpoints, 0)
+
+	rm.PauseCheckpoints()
+	numCheckpoints := rm.GetNumCheckpoints()
+	// Bulk load straight into the table, bypassing the write-ahead log
+	const numEntries = 1000
+	for i := int64(0); i < numEntries; i++ {
+		utils.InsertEntry(t, table, i, i%utils.Salt)
+	}
+	time.Sleep(10 * checkpointInterval)
+	if n := rm.GetNumCheckpoints(); n != numCheckpoints {
+		t.Fatalf("Expected no checkpoints while paused, but %d were taken", n-numCheckpoints)
+	}
+	rm.ResumeCheckpoints()
+	waitForCheckpoint(t, rm.GetNumCheckpoints, numCheckpoints)
+	rm.StopCheckpointScheduler()
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < numEntries; i++ {
+		checkFind(t, db, tm, clientId, tableName, i, i%utils.Salt)
+	}
+}
+
+// Takes a checkpoint manually while checkpoints are paused, checking that it waits until they are resumed
+func testPauseCheckpointsManual(t *testing.T) {
+	_, _, rm, _ := setupRecovery(t, "")
+	rm.PauseCheckpoints()
+	rm.PauseCheckpoints()
+	done := make(chan error, 1)
+	go func() {
+		done <- rm.Checkpoint()
+	}()
+
+	rm.ResumeCheckpoints()
+	select {
+	case <-done:
+		t.Fatal("Expected checkpoint to wait until every pause was resumed")
+	case <-time.After(10 * checkpointInterval):
+	}
+	rm.ResumeCheckpoints()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal("Failed to checkpoint:", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for checkpoint after resuming")
+	}
+	if n := rm.GetNumCheckpoints(); n != 1 {
+		t.Errorf("Expected 1 checkpoint, but got %d", n)
+	}
+}
+
+// Checkpoints with known active transactions and checks that last_checkpoint reports them
+func TestLastCheckpoint(t *testing.T) {
+	db, tm, rm, client1 := setupRecovery(t, "")
+	output, err := recovery.HandleLastCheckpoint(rm, "last_checkpoint")
+	if err != nil {
+		t.Fatal("Failed to show the last checkpoint:", err)
+	}
+	if output != "no checkpoint has been logged" {
+		t.Errorf("Expected no checkpoint to be reported, but got %q", output)
+	}
+
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	client2, client3 := uuid.New(), uuid.New()
+	startTransaction(t, db, tm, rm, client1)
+	startTransaction(t, db, tm, rm, client2)
+	startTransaction(t, db, tm, rm, client3)
+	insertIntoTable(t, db, tm, rm, client1, tableName, 1, 1)
+	commitTransaction(t, db, tm, rm, client3)
+	checkpoint(t, rm)
+	seq, activeTxs, found, err := rm.LastCheckpoint()
+	if err != nil {
+		t.Fatal("Failed to find the last checkpoint:", err)
+	}
+	if !found {
+		t.Fatal("Expected a checkpoint to be found")
+	}
+	if len(activeTxs) != 2 {
+		t.Errorf("Expected 2 active transactions, but got %v", activeTxs)
+	}
+	output, err = recovery.HandleLastCheckpoint(rm, "last_checkpoint")
+	if err != nil {
+		t.Fatal("Failed to show the last checkpoint:", err)
+	}
+	if !strings.Contains(output, client1.String()) || !strings.Contains(output, client2.String()) {
+		t.Errorf("Expected %q to report %s and %s", output, client1, client2)
+	}
+	if strings.Contains(output, client3.String()) {
+		t.Errorf("Expected %q not to report committed transaction %s", output, client3)
+	}
+
+	// A later checkpoint with nothing running replaces the earlier one
+	commitTransaction(t, db, tm, rm, client1)
+	commitTransaction(t, db, tm, rm, client2)
+	checkpoint(t, rm)
+	nextSeq, activeTxs, _, err := rm.LastCheckpoint()
+	if err != nil {
+		t.Fatal("Failed to find the last checkpoint:", err)
+	}
+	if nextSeq <= seq {
+		t.Errorf("Expected the last checkpoint to be after log %d, but got log %d", seq, nextSeq)
+	}
+	if len(activeTxs) != 0 {
+		t.Errorf("Expected no active transactions, but got %v", activeTxs)
+	}
+	if _, err := recovery.HandleLastCheckpoint(rm, "last_checkpoint now"); err == nil {
+		t.Error("Expected last_checkpoint with arguments to fail")
+	}
+}
+
+// Modifies one of several tables between checkpoints, checking that the second checkpoint
+// writes only that table's pages and records that it covered only that table
+func TestCheckpointModifiedTables(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	changed := createTable(t, db, rm, database.BTreeIndexType)
+	unchangedBTree := createTable(t, db, rm, database.BTreeIndexType)
+	unchangedHash := createTable(t, db, rm, database.HashIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for _, tableName := range []string{changed, unchangedBTree, unchangedHash} {
+		for i := int64(0); i < 500; i++ {
+			insertIntoTable(t, db, tm, rm, clientId, tableName, i, i%utils.Salt)
+		}
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+	firstSeq, _, _, err := rm.LastCheckpoint()
+	if err != nil {
+		t.Fatal("Failed to find the last checkpoint:", err)
+	}
+
+	writes := make(map[string]int64)
+	for _, tableName := range []string{changed, unchangedBTree, unchangedHash} {
+		table, err := db.GetTable(tableName)
+		if err != nil {
+			t.Fatal("Failed to get table:", err)
+		}
+		writes[tableName] = table.GetPager().GetNumDiskWrites()
+	}
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, changed, 1000, 1000)
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+	for tableName, before := range writes {
+		table, _ := db.GetTable(tableName)
+		after := table.GetPager().GetNumDiskWrites()
+		if tableName == changed && after == before {
+			t.Errorf("Expected the checkpoint to write the modified table's pages")
+		} else if tableName != changed && after != before {
+			t.Errorf("Expected the checkpoint not to write unmodified table %s, but it wrote %d pages", tableName, after-before)
+		}
+	}
+
+	secondSeq, _, _, err := rm.LastCheckpoint()
+	if err != nil {
+		t.Fatal("Failed to find the last checkpoint:", err)
+	}
+	if seq, found := rm.LastTableCheckpoint(changed); !found || seq != secondSeq {
+		t.Errorf("Expected the modified table to have been checkpointed at log %d, but got %d (found: %v)", secondSeq, seq, found)
+	}
+	if seq, found := rm.LastTableCheckpoint(unchangedHash); !found || seq != firstSeq {
+		t.Errorf("Expected the unmodified table to have been checkpointed at log %d, but got %d (found: %v)", firstSeq, seq, found)
+	}
+	contents, err := os.ReadFile(filepath.Join(db.GetBasePath(), config.LogFileName))
+	if err != nil {
+		t.Fatal("Error reading log file:", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if expected := "checkpoint covering " + changed + " >"; !strings.HasSuffix(lines[len(lines)-1], expected) {
+		t.Errorf("Expected the checkpoint log to end with %q, but got %q", expected, lines[len(lines)-1])
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	checkFind(t, db, tm, clientId, changed, 1000, 1000)
+	for _, tableName := range []string{changed, unchangedBTree, unchangedHash} {
+		for i := int64(0); i < 500; i += 50 {
+			checkFind(t, db, tm, clientId, tableName, i, i%utils.Salt)
+		}
+	}
+}
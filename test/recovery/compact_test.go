@@ -0,0 +1,101 @@
+package recovery_test
+
+import (
+	"strings"
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+
+	"github.com/google/uuid"
+)
+
+func TestCompactLog(t *testing.T) {
+	t.Run("Committed", testCompactLogCommitted)
+	t.Run("ActiveTransaction", testCompactLogActiveTransaction)
+	t.Run("Command", testCompactLogCommand)
+}
+
+// compactLog compacts the log, checking that it shrinks
+func compactLog(t *testing.T, rm *recovery.RecoveryManager) int64 {
+	before, after, err := rm.CompactLog()
+	if err != nil {
+		t.Fatal("Failed to compact the log:", err)
+	}
+	if after >= before {
+		t.Errorf("Expected compaction to shrink the log from %d bytes, but it is %d bytes", before, after)
+	}
+	if size := rm.GetLogSize(); size != after {
+		t.Errorf("Expected the log to be the reported %d bytes, but it is %d bytes", after, size)
+	}
+	return after
+}
+
+// Compacts the log after a large committed workload, checking that only the checkpoint log is left
+// and that recovery still finds every entry
+func testCompactLogCommitted(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	numEntries := int64(500)
+	for i := int64(0); i < numEntries; i += 50 {
+		startTransaction(t, db, tm, rm, clientId)
+		for key := i; key < i+50; key++ {
+			insertIntoTable(t, db, tm, rm, clientId, tableName, key, key*2)
+		}
+		commitTransaction(t, db, tm, rm, clientId)
+	}
+	after := compactLog(t, rm)
+	if after > 200 {
+		t.Errorf("Expected only the checkpoint log to be left, but the log is %d bytes", after)
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for key := int64(0); key < numEntries; key++ {
+		checkFind(t, db, tm, clientId, tableName, key, key*2)
+	}
+}
+
+// Compacts the log while a transaction is running, checking that its logs are kept so that recovery can undo it
+func testCompactLogActiveTransaction(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for key := int64(0); key < 100; key++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, key, key)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	active := uuid.New()
+	startTransaction(t, db, tm, rm, active)
+	insertIntoTable(t, db, tm, rm, active, tableName, 100, 100)
+	compactLog(t, rm)
+	if _, activeTxs, found, err := rm.LastCheckpoint(); err != nil || !found || len(activeTxs) != 1 || activeTxs[0] != active {
+		t.Errorf("Expected the compacted log to end with a checkpoint of transaction %s, but got %v (found %v, err %v)", active, activeTxs, found, err)
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for key := int64(0); key < 100; key++ {
+		checkFind(t, db, tm, clientId, tableName, key, key)
+	}
+	checkFindFails(t, db, tm, clientId, tableName, 100)
+}
+
+// Runs compact through the admin REPL's handler, checking its usage and output
+func testCompactLogCommand(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 1)
+	commitTransaction(t, db, tm, rm, clientId)
+	if _, err := recovery.HandleCompact(rm, "compact"); err == nil {
+		t.Error("Expected compact without log to fail")
+	}
+	output, err := recovery.HandleCompact(rm, "compact log")
+	if err != nil {
+		t.Fatal("Failed to compact the log:", err)
+	}
+	if !strings.HasPrefix(output, "compacted the log from ") {
+		t.Errorf("Unexpected compact output %q", output)
+	}
+}
@@ -0,0 +1,135 @@
+//go:build faultinject
+
+package recovery_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+	"dinodb/pkg/pager"
+	"dinodb/pkg/recovery"
+	"dinodb/test/utils"
+)
+
+func TestInjectedFaults(t *testing.T) {
+	t.Run("TornLogAppend", testTornLogAppend)
+	t.Run("PartialPageWrite", testPartialPageWrite)
+	t.Run("SchedulerError", testSchedulerError)
+}
+
+// Fails every log append while the checkpoint scheduler runs, checking that its failed checkpoints
+// are counted and that the last error is kept
+func testSchedulerError(t *testing.T) {
+	db, _, rm, _ := setupRecovery(t, "")
+	createTable(t, db, rm, database.BTreeIndexType)
+	rm.SetFaultyWriter(pager.NewFaultyWriter(0))
+	if err := rm.StartCheckpointScheduler(time.Millisecond); err != nil {
+		t.Fatal("Error starting the checkpoint scheduler:", err)
+	}
+	defer rm.StopCheckpointScheduler()
+	deadline := time.Now().Add(time.Second)
+	for rm.LastSchedulerError() == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	rm.StopCheckpointScheduler()
+	if err := rm.LastSchedulerError(); !errors.Is(err, pager.ErrInjectedFault) {
+		t.Fatalf("Expected the scheduler's checkpoints to fail with %q, but got %v", pager.ErrInjectedFault, err)
+	}
+	if n := rm.Metrics().SchedulerErrors; n == 0 {
+		t.Error("Expected the scheduler's failed checkpoints to be counted")
+	}
+}
+
+// Tears an edit log partway through its append, checking that the torn record is dropped
+// on restart so that recovery keeps the committed edits and new logs start on a fresh line
+func testTornLogAppend(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 10)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	startTransaction(t, db, tm, rm, clientId)
+	w := pager.NewFaultyWriter(5)
+	rm.SetFaultyWriter(w)
+	err := recovery.HandleInsert(db, tm, rm, fmt.Sprintf("insert 2 20 into %s", tableName), clientId)
+	if !errors.Is(err, pager.ErrInjectedFault) {
+		t.Fatalf("Expected the insert to fail with %q, but got %v", pager.ErrInjectedFault, err)
+	}
+	if !w.Tripped() {
+		t.Fatal("Expected the log append to be torn")
+	}
+	logFileName := filepath.Join(db.GetBasePath(), config.LogFileName)
+	contents, err := os.ReadFile(logFileName)
+	if err != nil {
+		t.Fatal("Error reading log file:", err)
+	}
+	if strings.HasSuffix(string(contents), "\n") {
+		t.Fatal("Expected the log file to end with a torn record")
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	checkFind(t, db, tm, clientId, tableName, 1, 10)
+	checkFindFails(t, db, tm, clientId, tableName, 2)
+	contents, err = os.ReadFile(logFileName)
+	if err != nil {
+		t.Fatal("Error reading log file:", err)
+	}
+	if !strings.HasSuffix(string(contents), "\n") {
+		t.Errorf("Expected the torn record to be dropped from the log, but it ends with %q",
+			contents[strings.LastIndex(string(contents), "\n")+1:])
+	}
+
+	// The log is usable again after recovery
+	_, _, rm = crashAndRecover(t, db.GetBasePath())
+	if _, _, _, err := rm.LastCheckpoint(); err != nil {
+		t.Error("Error reading the log after recovering twice:", err)
+	}
+}
+
+// Tears a page halfway through being flushed after a checkpoint, checking that recovery
+// restores the table from the checkpoint and redoes the logged edits over it
+func testPartialPageWrite(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	numEntries := int64(200)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < numEntries/2; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i%utils.Salt)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := numEntries / 2; i < numEntries; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i%utils.Salt)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+
+	table, err := db.GetTable(tableName)
+	if err != nil {
+		t.Fatal("Failed to get table:", err)
+	}
+	w := pager.NewFaultyWriter(pager.Pagesize / 2)
+	p := table.GetPager()
+	p.SetFaultyWriter(w)
+	p.LockAllPages()
+	p.FlushAllPages()
+	p.UnlockAllPages()
+	if !w.Tripped() {
+		t.Fatal("Expected a page write to be torn")
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < numEntries; i++ {
+		checkFind(t, db, tm, clientId, tableName, i, i%utils.Salt)
+	}
+}
@@ -0,0 +1,98 @@
+package recovery_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+
+	"github.com/google/uuid"
+)
+
+func TestGroupCommit(t *testing.T) {
+	t.Run("LoneCommit", testGroupCommitLone)
+	t.Run("LatencyBound", testGroupCommitLatencyBound)
+	t.Run("SharedSync", testGroupCommitSharedSync)
+}
+
+// Commits the only running transaction, checking that it is synced without waiting out the delay
+func testGroupCommitLone(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	const maxDelay = 2 * time.Second
+	rm.SetMaxCommitDelay(maxDelay)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 0, 0)
+	start := time.Now()
+	commitTransaction(t, db, tm, rm, clientId)
+	if elapsed := time.Since(start); elapsed >= maxDelay {
+		t.Errorf("Expected a lone commit to be synced right away, but it took %v", elapsed)
+	}
+	if n := rm.Metrics().CommitSync.Count; n != 1 {
+		t.Errorf("Expected 1 commit sync, but got %d", n)
+	}
+}
+
+// Commits while another transaction stays open, checking that the commit waits no longer than the delay
+func testGroupCommitLatencyBound(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	const maxDelay = 100 * time.Millisecond
+	rm.SetMaxCommitDelay(maxDelay)
+	startTransaction(t, db, tm, rm, uuid.New())
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 0, 0)
+	start := time.Now()
+	commitTransaction(t, db, tm, rm, clientId)
+	// Allow the sync itself some time on top of the delay.
+	if elapsed := time.Since(start); elapsed > maxDelay+time.Second {
+		t.Errorf("Expected the commit to be synced within %v, but it took %v", maxDelay, elapsed)
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	checkFind(t, db, tm, clientId, tableName, 0, 0)
+}
+
+// Commits many transactions at once, checking that they share fewer syncs than there are commits
+// and that every commit survives a crash
+func testGroupCommitSharedSync(t *testing.T) {
+	db, tm, rm, _ := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	rm.SetMaxCommitDelay(time.Second)
+	const numClients = 20
+	clientIds := make([]uuid.UUID, numClients)
+	for i := range clientIds {
+		clientIds[i] = uuid.New()
+		startTransaction(t, db, tm, rm, clientIds[i])
+		insertIntoTable(t, db, tm, rm, clientIds[i], tableName, int64(i), int64(i))
+	}
+	var wg sync.WaitGroup
+	for _, clientId := range clientIds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := recovery.HandleTransaction(db, tm, rm, "transaction commit", clientId); err != nil {
+				t.Error("Error committing a transaction:", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	metrics := rm.Metrics()
+	if metrics.BatchedCommits != numClients {
+		t.Errorf("Expected %d batched commits, but got %d", numClients, metrics.BatchedCommits)
+	}
+	if metrics.CommitSync.Count >= numClients {
+		t.Errorf("Expected %d commits to share syncs, but they took %d", numClients, metrics.CommitSync.Count)
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	clientId := uuid.New()
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < numClients; i++ {
+		checkFind(t, db, tm, clientId, tableName, i, i)
+	}
+}
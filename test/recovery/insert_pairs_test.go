@@ -0,0 +1,39 @@
+package recovery_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+)
+
+// Inserts several pairs in one transaction where one collides with a committed key, checking the
+// reported outcome of each pair and that aborting the transaction undoes every inserted pair
+func TestRecoveryInsertPairsAbort(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 2, 2)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	startTransaction(t, db, tm, rm, clientId)
+	output, err := database.HandleInsertPairs("insert (1 10) (2 20) (3 30) into "+tableName, func(payload string) error {
+		return recovery.HandleInsert(db, tm, rm, payload, clientId)
+	})
+	if err != nil {
+		t.Fatal("Failed to insert pairs:", err)
+	}
+	expected := "inserted 2 of 3 entries\nfailed (2, 20): insert error: key already in table: 2 has value 2\n"
+	if output != expected {
+		t.Errorf("Expected output %q, but got %q", expected, output)
+	}
+	checkFind(t, db, tm, clientId, tableName, 1, 10)
+	checkFind(t, db, tm, clientId, tableName, 3, 30)
+	abortTransaction(t, tm, rm, clientId)
+
+	startTransaction(t, db, tm, rm, clientId)
+	checkFindFails(t, db, tm, clientId, tableName, 1)
+	checkFind(t, db, tm, clientId, tableName, 2, 2)
+	checkFindFails(t, db, tm, clientId, tableName, 3)
+	commitTransaction(t, db, tm, rm, clientId)
+}
@@ -0,0 +1,26 @@
+package recovery_test
+
+import (
+	"math"
+	"testing"
+
+	"dinodb/pkg/database"
+)
+
+// Commits keys and values at the ends of the int64 range, checking that they survive being logged and redone
+func TestIntRangeRecovery(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	keys := []int64{math.MaxInt64, math.MinInt64, math.MinInt64 + 1, -1}
+	startTransaction(t, db, tm, rm, clientId)
+	for _, key := range keys {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, key, -(key + 1))
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for _, key := range keys {
+		checkFind(t, db, tm, clientId, tableName, key, -(key + 1))
+	}
+}
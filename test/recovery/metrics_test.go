@@ -0,0 +1,70 @@
+package recovery_test
+
+import (
+	"testing"
+
+	"dinodb/pkg/database"
+)
+
+func TestRecoveryMetrics(t *testing.T) {
+	t.Run("Recorded", testMetricsRecorded)
+	t.Run("Sampling", testMetricsSampling)
+}
+
+// Commits writes and takes checkpoints, checking that flushes and checkpoints are timed and counted
+func testMetricsRecorded(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	const numCheckpoints = 3
+	for i := int64(0); i < numCheckpoints; i++ {
+		startTransaction(t, db, tm, rm, clientId)
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i)
+		commitTransaction(t, db, tm, rm, clientId)
+		checkpoint(t, rm)
+	}
+
+	metrics := rm.Metrics()
+	// A table log, then a start, insert, commit, and checkpoint log per iteration
+	if expected := int64(1 + 4*numCheckpoints); metrics.LogFlush.Count != expected {
+		t.Errorf("Expected %d log flushes to be timed, but got %d", expected, metrics.LogFlush.Count)
+	}
+	if metrics.Checkpoint.Count != numCheckpoints {
+		t.Errorf("Expected %d checkpoints to be timed, but got %d", numCheckpoints, metrics.Checkpoint.Count)
+	}
+	for name, summary := range map[string]struct{ min, mean, max int64 }{
+		"log flush":  {int64(metrics.LogFlush.Min), int64(metrics.LogFlush.Mean()), int64(metrics.LogFlush.Max)},
+		"checkpoint": {int64(metrics.Checkpoint.Min), int64(metrics.Checkpoint.Mean()), int64(metrics.Checkpoint.Max)},
+	} {
+		if summary.min <= 0 {
+			t.Errorf("Expected a non-zero minimum %s latency", name)
+		}
+		if summary.min > summary.mean || summary.mean > summary.max {
+			t.Errorf("Expected %s latencies to satisfy min <= mean <= max, but got %d, %d, %d", name, summary.min, summary.mean, summary.max)
+		}
+	}
+}
+
+// Samples a fraction of log flushes, then disables sampling, checking how many flushes are timed
+func testMetricsSampling(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	rm.SetLogFlushSampling(4)
+	before := rm.Metrics().LogFlush.Count
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 40; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i)
+	}
+	if timed := rm.Metrics().LogFlush.Count - before; timed != 10 {
+		t.Errorf("Expected 10 of 40 flushes to be timed, but got %d", timed)
+	}
+	rm.SetLogFlushSampling(0)
+	before = rm.Metrics().LogFlush.Count
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+	if timed := rm.Metrics().LogFlush.Count - before; timed != 0 {
+		t.Errorf("Expected no flushes to be timed with sampling disabled, but got %d", timed)
+	}
+	if n := rm.Metrics().Checkpoint.Count; n != 1 {
+		t.Errorf("Expected checkpoints to be timed with sampling disabled, but got %d", n)
+	}
+}
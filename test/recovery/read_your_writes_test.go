@@ -0,0 +1,77 @@
+package recovery_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"dinodb/pkg/concurrency"
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+
+	"github.com/google/uuid"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	for _, indexType := range []database.IndexType{database.BTreeIndexType, database.HashIndexType} {
+		for _, isolation := range []string{"serializable", "read_committed"} {
+			t.Run(fmt.Sprintf("%s/%s", indexType, isolation), func(t *testing.T) {
+				testReadYourWrites(t, indexType, isolation)
+			})
+		}
+	}
+}
+
+// findInTransaction runs a find through the recovery REPL's handler, returning its output
+func findInTransaction(t *testing.T, db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager, clientId uuid.UUID, tableName string, key int64) (string, error) {
+	return recovery.HandleFind(db, tm, rm, fmt.Sprintf("find %d from %s", key, tableName), clientId)
+}
+
+// checkReadsOwnWrite checks that a find in the client's transaction sees the given entry
+func checkReadsOwnWrite(t *testing.T, db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager, clientId uuid.UUID, tableName string, key, value int64) {
+	output, err := findInTransaction(t, db, tm, rm, clientId, tableName, key)
+	if err != nil {
+		t.Fatalf("Expected to find key %d written by the same transaction, but got %v", key, err)
+	}
+	if !strings.Contains(output, fmt.Sprintf("(%d, %d)", key, value)) {
+		t.Errorf("Expected to find (%d, %d) written by the same transaction, but got %q", key, value, output)
+	}
+}
+
+// Inserts, updates, and deletes keys in one transaction, checking that each find before commit
+// sees the transaction's own writes, and that they are undone by a rollback
+func testReadYourWrites(t *testing.T, indexType database.IndexType, isolation string) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, indexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 10)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 2, 20)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	startTransaction(t, db, tm, rm, clientId)
+	if err := recovery.HandleIsolation(db, tm, "isolation "+isolation, clientId); err != nil {
+		t.Fatal("Failed to set the isolation level:", err)
+	}
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 3, 30)
+	checkReadsOwnWrite(t, db, tm, rm, clientId, tableName, 3, 30)
+	updateTableEntry(t, db, tm, rm, clientId, tableName, 1, 11)
+	checkReadsOwnWrite(t, db, tm, rm, clientId, tableName, 1, 11)
+	deleteFromTable(t, db, tm, rm, clientId, tableName, 2)
+	if _, err := findInTransaction(t, db, tm, rm, clientId, tableName, 2); err == nil {
+		t.Error("Expected key 2 to be absent after the same transaction deleted it")
+	}
+	// Reading again, or after a later write to the same key, still sees the latest write
+	updateTableEntry(t, db, tm, rm, clientId, tableName, 3, 31)
+	checkReadsOwnWrite(t, db, tm, rm, clientId, tableName, 3, 31)
+	checkReadsOwnWrite(t, db, tm, rm, clientId, tableName, 3, 31)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 2, 22)
+	checkReadsOwnWrite(t, db, tm, rm, clientId, tableName, 2, 22)
+
+	if err := rm.Rollback(clientId); err != nil {
+		t.Fatal("Failed to roll back:", err)
+	}
+	startTransaction(t, db, tm, rm, clientId)
+	checkFind(t, db, tm, clientId, tableName, 1, 10)
+	checkFind(t, db, tm, clientId, tableName, 2, 20)
+	checkFindFails(t, db, tm, clientId, tableName, 3)
+}
@@ -0,0 +1,215 @@
+package recovery_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+	"dinodb/test/utils"
+)
+
+// setupRebuild opens an empty Database in a unique random base directory to rebuild tables into
+func setupRebuild(t *testing.T) *database.Database {
+	dbName, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal("Failed to create random database folder:", err)
+	}
+	db, err := database.Open(dbName)
+	if err != nil {
+		t.Fatal("Error opening database:", err)
+	}
+	utils.EnsureCleanup(t, func() {
+		_ = db.Close()
+		_ = os.RemoveAll(dbName)
+	})
+	return db
+}
+
+// Asserts that the rebuilt table holds exactly the given entries
+func checkRebuiltTable(t *testing.T, db *database.Database, tableName string, expected map[int64]int64, absent []int64) {
+	table, err := db.GetTable(tableName)
+	if err != nil {
+		t.Fatalf("Failed to get rebuilt table %q: %s", tableName, err)
+	}
+	for k, v := range expected {
+		entry, err := table.Find(k)
+		if err != nil {
+			t.Errorf("Expected key %d to be present in rebuilt table %q", k, tableName)
+			continue
+		}
+		if entry.Value != v {
+			t.Errorf("Expected to find value %d under key %d in rebuilt table %q, but instead found %d", v, k, tableName, entry.Value)
+		}
+	}
+	for _, k := range absent {
+		if _, err := table.Find(k); err == nil {
+			t.Errorf("Expected key %d to not be present in rebuilt table %q", k, tableName)
+		}
+	}
+}
+
+func TestRecoveryRebuild(t *testing.T) {
+	t.Run("CommittedOnly", testRebuildCommittedOnly)
+	t.Run("IgnoresCheckpoints", testRebuildIgnoresCheckpoints)
+	t.Run("StaleDataFiles", testRebuildStaleDataFiles)
+	t.Run("TableOpen", testRebuildTableOpen)
+	t.Run("LogGap", testRebuildLogGap)
+}
+
+// Rebuilds tables of both index types from a log with committed, aborted and
+// uncommitted transactions, checking that only committed edits are kept
+func testRebuildCommittedOnly(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	btreeName := createTable(t, db, rm, database.BTreeIndexType)
+	hashName := createTable(t, db, rm, database.HashIndexType)
+
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 10; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, btreeName, i, i)
+		insertIntoTable(t, db, tm, rm, clientId, hashName, i, i)
+	}
+	updateTableEntry(t, db, tm, rm, clientId, btreeName, 3, 30)
+	deleteFromTable(t, db, tm, rm, clientId, hashName, 4)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	abortedId := uuid.New()
+	startTransaction(t, db, tm, rm, abortedId)
+	insertIntoTable(t, db, tm, rm, abortedId, btreeName, 100, 100)
+	updateTableEntry(t, db, tm, rm, abortedId, hashName, 5, 50)
+	abortTransaction(t, tm, rm, abortedId)
+
+	uncommittedId := uuid.New()
+	startTransaction(t, db, tm, rm, uncommittedId)
+	insertIntoTable(t, db, tm, rm, uncommittedId, hashName, 200, 200)
+	deleteFromTable(t, db, tm, rm, uncommittedId, btreeName, 6)
+
+	rebuilt := setupRebuild(t)
+	err := recovery.RebuildFromLog(rebuilt, filepath.Join(db.GetBasePath(), config.LogFileName))
+	if err != nil {
+		t.Fatal("Error rebuilding from log:", err)
+	}
+	checkRebuiltTable(t, rebuilt, btreeName,
+		map[int64]int64{0: 0, 3: 30, 5: 5, 6: 6, 9: 9}, []int64{100})
+	checkRebuiltTable(t, rebuilt, hashName,
+		map[int64]int64{0: 0, 3: 3, 5: 5, 9: 9}, []int64{4, 200})
+}
+
+// Removes the data files after committing edits on both sides of a checkpoint, checking
+// that rebuilding from the log alone replays the edits from before the checkpoint too
+func testRebuildIgnoresCheckpoints(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 1)
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 2, 2)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	// Simulate losing the data files, keeping only the log
+	if err := db.Close(); err != nil {
+		t.Fatal("Error closing database:", err)
+	}
+	if err := os.Remove(filepath.Join(db.GetBasePath(), tableName)); err != nil {
+		t.Fatal("Error removing table file:", err)
+	}
+
+	rebuilt, err := database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Error reopening database:", err)
+	}
+	defer rebuilt.Close()
+	err = recovery.RebuildFromLog(rebuilt, filepath.Join(db.GetBasePath(), config.LogFileName))
+	if err != nil {
+		t.Fatal("Error rebuilding from log:", err)
+	}
+	checkRebuiltTable(t, rebuilt, tableName, map[int64]int64{1: 1, 2: 2}, nil)
+}
+
+// Rebuilds over data files left on disk that hold an edit the log doesn't, checking
+// that the old files are replaced rather than opened or kept
+func testRebuildStaleDataFiles(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	btreeName := createTable(t, db, rm, database.BTreeIndexType)
+	hashName := createTable(t, db, rm, database.HashIndexType)
+
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, btreeName, 1, 1)
+	insertIntoTable(t, db, tm, rm, clientId, hashName, 1, 1)
+	commitTransaction(t, db, tm, rm, clientId)
+	// Write an entry straight to the tables, bypassing the log
+	for _, name := range []string{btreeName, hashName} {
+		table, err := db.GetTable(name)
+		if err != nil {
+			t.Fatal("Error getting table:", err)
+		}
+		if err := table.Insert(99, 99); err != nil {
+			t.Fatal("Error inserting unlogged entry:", err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal("Error closing database:", err)
+	}
+
+	rebuilt, err := database.Open(db.GetBasePath())
+	if err != nil {
+		t.Fatal("Error reopening database:", err)
+	}
+	defer rebuilt.Close()
+	err = recovery.RebuildFromLog(rebuilt, filepath.Join(db.GetBasePath(), config.LogFileName))
+	if err != nil {
+		t.Fatal("Error rebuilding over old data files:", err)
+	}
+	checkRebuiltTable(t, rebuilt, btreeName, map[int64]int64{1: 1}, []int64{99})
+	checkRebuiltTable(t, rebuilt, hashName, map[int64]int64{1: 1}, []int64{99})
+}
+
+// Rebuilds into the database the log was written for, checking that
+// it refuses to replace a table that is still open
+func testRebuildTableOpen(t *testing.T) {
+	db, _, rm, _ := setupRecovery(t, "")
+	createTable(t, db, rm, database.BTreeIndexType)
+
+	err := recovery.RebuildFromLog(db, filepath.Join(db.GetBasePath(), config.LogFileName))
+	if err == nil {
+		t.Error("Expected rebuilding over an open table to fail")
+	}
+}
+
+// Rebuilds from a log with a missing record, checking that the gap is reported
+func testRebuildLogGap(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 1)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	// Simulate a lost write by deleting a record from the middle of the log
+	logPath := filepath.Join(db.GetBasePath(), config.LogFileName)
+	contents, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal("Error reading log file:", err)
+	}
+	lines := strings.SplitAfter(string(contents), "\n")
+	lostLine := len(lines) / 2
+	lines = append(lines[:lostLine], lines[lostLine+1:]...)
+	err = os.WriteFile(logPath, []byte(strings.Join(lines, "")), 0666)
+	if err != nil {
+		t.Fatal("Error writing log file:", err)
+	}
+
+	rebuilt := setupRebuild(t)
+	err = recovery.RebuildFromLog(rebuilt, logPath)
+	if !errors.Is(err, recovery.ErrLogGap) {
+		t.Errorf("Expected rebuilding from a log with a gap to fail with %q, but got %v", recovery.ErrLogGap, err)
+	}
+}
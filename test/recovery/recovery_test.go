@@ -2,6 +2,7 @@ package recovery_test
 
 import (
 	"dinodb/test/utils"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -158,6 +159,14 @@ func updateTableEntry(t *testing.T, db *database.Database, tm *concurrency.Trans
 	}
 }
 
+func rekeyTableEntry(t *testing.T, db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager, clientId uuid.UUID, tableName string, oldKey int64, newKey int64) {
+	payload := fmt.Sprintf("rekey %s %d %d", tableName, oldKey, newKey)
+	err := recovery.HandleRekey(db, tm, rm, payload, clientId)
+	if err != nil {
+		t.Fatalf("Error rekeying %d to %d in table %q: %s", oldKey, newKey, tableName, err)
+	}
+}
+
 func deleteFromTable(t *testing.T, db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager, clientId uuid.UUID, tableName string, key int64) {
 	payload := fmt.Sprintf("delete %d from %s", key, tableName)
 	err := recovery.HandleDelete(db, tm, rm, payload, clientId)
@@ -217,9 +226,20 @@ func TestRecovery(t *testing.T) {
 	t.Run("InsertDeleteCommit", testInsertDeleteCommit)
 	t.Run("InsertCommitUpdate", testInsertCommitUpdate)
 	t.Run("InsertCheckpointCommitUpdate", testInsertCheckpointCommitUpdate)
+	t.Run("RekeyAbort", testRekeyAbort)
+	t.Run("RekeyCommit", testRekeyCommit)
+	t.Run("RekeyUncommitted", testRekeyUncommitted)
 	t.Run("MultipleTablesOneClient", testMultipleTablesOneClient)
 	t.Run("MultiInsertCheckpointing", testMultiInsertCheckpointing)
 	t.Run("MultiInsertCommitDeleteCheckpointing", testMultiInsertCommitDeleteCheckpointing)
+	t.Run("InterleavedMultiTable", testInterleavedMultiTable)
+	t.Run("LogGap", testLogGap)
+	t.Run("ConcurrentClients", testConcurrentClients)
+	t.Run("ConcurrentNoOps", testConcurrentNoOps)
+	t.Run("MaxEditsExceeded", testMaxEditsExceeded)
+	t.Run("MaxEditsUnderLimit", testMaxEditsUnderLimit)
+	t.Run("Convert", testConvert)
+	t.Run("BeginResumed", testBeginResumed)
 }
 
 func testBasic(t *testing.T) {
@@ -356,6 +376,60 @@ func testInsertCheckpointCommitUpdate(t *testing.T) {
 	checkFind(t, db, tm, clientId, tableName, 0, 0)
 }
 
+func testRekeyAbort(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.HashIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 0, 5)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 2, 2)
+	commitTransaction(t, db, tm, rm, clientId)
+	startTransaction(t, db, tm, rm, clientId)
+	rekeyTableEntry(t, db, tm, rm, clientId, tableName, 0, 1)
+	if err := recovery.HandleRekey(db, tm, rm, fmt.Sprintf("rekey %s 1 2", tableName), clientId); err == nil {
+		t.Error("Expected rekeying onto an existing key to fail")
+	}
+
+	abortTransaction(t, tm, rm, clientId)
+
+	startTransaction(t, db, tm, rm, clientId)
+	checkFind(t, db, tm, clientId, tableName, 0, 5)
+	checkFind(t, db, tm, clientId, tableName, 2, 2)
+	checkFindFails(t, db, tm, clientId, tableName, 1)
+}
+
+func testRekeyCommit(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	// Before crash
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 0, 5)
+	rekeyTableEntry(t, db, tm, rm, clientId, tableName, 0, 1)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	// After crash
+	startTransaction(t, db, tm, rm, clientId)
+	checkFind(t, db, tm, clientId, tableName, 1, 5)
+	checkFindFails(t, db, tm, clientId, tableName, 0)
+}
+
+func testRekeyUncommitted(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	// Before crash
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 0, 5)
+	commitTransaction(t, db, tm, rm, clientId)
+	startTransaction(t, db, tm, rm, clientId)
+	rekeyTableEntry(t, db, tm, rm, clientId, tableName, 0, 1)
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	// After crash
+	startTransaction(t, db, tm, rm, clientId)
+	checkFind(t, db, tm, clientId, tableName, 0, 5)
+	checkFindFails(t, db, tm, clientId, tableName, 1)
+}
+
 func testMultipleTablesOneClient(t *testing.T) {
 	db, tm, rm, clientId := setupRecovery(t, "")
 	// Before crash
@@ -427,3 +501,314 @@ func testMultiInsertCommitDeleteCheckpointing(t *testing.T) {
 		checkFind(t, db, tm, clientId, tableName, i, i%utils.Salt)
 	}
 }
+
+// Interleaves two clients' transactions across a B+Tree and a hash table, checkpointing while both
+// are active and again after one aborts, then crashes after the other commits and a third starts,
+// checking that exactly the committed edits survive in both tables
+func testInterleavedMultiTable(t *testing.T) {
+	db, tm, rm, client1 := setupRecovery(t, "")
+	client2 := uuid.New()
+	client3 := uuid.New()
+	// Before crash
+	btreeName := createTable(t, db, rm, database.BTreeIndexType)
+	hashName := createTable(t, db, rm, database.HashIndexType)
+	startTransaction(t, db, tm, rm, client1)
+	for i := int64(1); i <= 5; i++ {
+		insertIntoTable(t, db, tm, rm, client1, btreeName, i, i)
+		insertIntoTable(t, db, tm, rm, client1, hashName, i, i)
+	}
+	commitTransaction(t, db, tm, rm, client1)
+
+	startTransaction(t, db, tm, rm, client1)
+	startTransaction(t, db, tm, rm, client2)
+	updateTableEntry(t, db, tm, rm, client1, btreeName, 1, 100)
+	insertIntoTable(t, db, tm, rm, client2, btreeName, 20, 20)
+	insertIntoTable(t, db, tm, rm, client1, hashName, 10, 10)
+	deleteFromTable(t, db, tm, rm, client2, hashName, 2)
+	checkpoint(t, rm)
+	updateTableEntry(t, db, tm, rm, client2, btreeName, 3, 300)
+	deleteFromTable(t, db, tm, rm, client1, btreeName, 4)
+	updateTableEntry(t, db, tm, rm, client2, hashName, 5, 500)
+	updateTableEntry(t, db, tm, rm, client1, hashName, 1, 100)
+	abortTransaction(t, tm, rm, client1)
+	checkpoint(t, rm)
+	insertIntoTable(t, db, tm, rm, client2, hashName, 30, 30)
+	commitTransaction(t, db, tm, rm, client2)
+	startTransaction(t, db, tm, rm, client3)
+	insertIntoTable(t, db, tm, rm, client3, btreeName, 40, 40)
+	deleteFromTable(t, db, tm, rm, client3, hashName, 3)
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	// After crash
+	startTransaction(t, db, tm, rm, client1)
+	for key, val := range map[int64]int64{1: 1, 2: 2, 3: 300, 4: 4, 5: 5, 20: 20} {
+		checkFind(t, db, tm, client1, btreeName, key, val)
+	}
+	checkFindFails(t, db, tm, client1, btreeName, 40)
+	for key, val := range map[int64]int64{1: 1, 3: 3, 4: 4, 5: 500, 30: 30} {
+		checkFind(t, db, tm, client1, hashName, key, val)
+	}
+	checkFindFails(t, db, tm, client1, hashName, 2)
+	checkFindFails(t, db, tm, client1, hashName, 10)
+}
+
+func testLogGap(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	numEntries := int64(10)
+	// Before crash
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < numEntries; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i%utils.Salt)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+
+	// Simulate a lost write by deleting a record from the middle of the log
+	logFileName := filepath.Join(db.GetBasePath(), config.LogFileName)
+	contents, err := os.ReadFile(logFileName)
+	if err != nil {
+		t.Fatal("Error reading log file:", err)
+	}
+	lines := strings.SplitAfter(string(contents), "\n")
+	lostLine := len(lines) / 2
+	lines = append(lines[:lostLine], lines[lostLine+1:]...)
+	err = os.WriteFile(logFileName, []byte(strings.Join(lines, "")), 0666)
+	if err != nil {
+		t.Fatal("Error writing log file:", err)
+	}
+
+	func() {
+		defer revive(t)
+		panic("simulating database crash")
+	}()
+	_, _, rm, _ = setupRecovery(t, db.GetBasePath())
+	err = rm.Recover()
+	if !errors.Is(err, recovery.ErrLogGap) {
+		t.Fatalf("Expected recovery to fail with %q, but got %v", recovery.ErrLogGap, err)
+	}
+	expectedGap := fmt.Sprintf("expected sequence number %d after %d", lostLine+1, lostLine)
+	if !strings.Contains(err.Error(), expectedGap) {
+		t.Errorf("Expected error to name the gap (%s), but got %q", expectedGap, err)
+	}
+}
+
+// runClient inserts numEntries keys starting at base in its own transaction,
+// then commits the transaction if commit is set and aborts it otherwise
+func runClient(db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager,
+	tableName string, base int64, numEntries int64, commit bool, doneCh chan<- bool, errCh chan<- error) {
+	clientId := uuid.New()
+	err := recovery.HandleTransaction(db, tm, rm, "transaction begin", clientId)
+	if err != nil {
+		errCh <- fmt.Errorf("Failed to start a transaction: %s", err)
+		return
+	}
+	for i := base; i < base+numEntries; i++ {
+		payload := fmt.Sprintf("insert %v %v into %s", i, i%utils.Salt, tableName)
+		err = recovery.HandleInsert(db, tm, rm, payload, clientId)
+		if err != nil {
+			errCh <- fmt.Errorf("Failed to concurrently insert (%d, %d): %s", i, i%utils.Salt, err)
+			return
+		}
+	}
+	if commit {
+		err = recovery.HandleTransaction(db, tm, rm, "transaction commit", clientId)
+	} else {
+		err = rm.Rollback(clientId)
+	}
+	if err != nil {
+		errCh <- fmt.Errorf("Failed to end a transaction: %s", err)
+		return
+	}
+	doneCh <- true
+}
+
+func testConcurrentClients(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	numClients := 8
+	numEntries := int64(100)
+	// Before crash, every other client commits and the rest abort
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	checkpoint(t, rm)
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+	for i := 0; i < numClients; i++ {
+		go runClient(db, tm, rm, tableName, int64(i)*numEntries, numEntries, i%2 == 0, doneCh, errCh)
+	}
+	for i := 0; i < numClients; i++ {
+		select {
+		case <-doneCh:
+			continue
+		case err := <-errCh:
+			t.Fatal(err)
+		}
+	}
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	// After crash, only the committed clients' entries should exist
+	startTransaction(t, db, tm, rm, clientId)
+	for i := 0; i < numClients; i++ {
+		for key := int64(i) * numEntries; key < int64(i+1)*numEntries; key++ {
+			if i%2 == 0 {
+				checkFind(t, db, tm, clientId, tableName, key, key%utils.Salt)
+			} else {
+				checkFindFails(t, db, tm, clientId, tableName, key)
+			}
+		}
+	}
+}
+
+// runNoOpClient runs an insert, update, and delete against key without having begun a transaction,
+// so that each edit fails after being logged and must be marked as a no-op
+func runNoOpClient(db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager,
+	tableName string, key int64, doneCh chan<- bool, errCh chan<- error) {
+	clientId := uuid.New()
+	payloads := map[string]func() error{
+		"insert": func() error {
+			return recovery.HandleInsert(db, tm, rm, fmt.Sprintf("insert %v %v into %s", key+1, key, tableName), clientId)
+		},
+		"update": func() error {
+			return recovery.HandleUpdate(db, tm, rm, fmt.Sprintf("update %s %v %v", tableName, key, key+1), clientId)
+		},
+		"delete": func() error {
+			return recovery.HandleDelete(db, tm, rm, fmt.Sprintf("delete %v from %s", key, tableName), clientId)
+		},
+	}
+	for name, handle := range payloads {
+		if err := handle(); err == nil {
+			errCh <- fmt.Errorf("Expected %s without a transaction to fail", name)
+			return
+		}
+	}
+	doneCh <- true
+}
+
+func testConcurrentNoOps(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	numClients := 8
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := 0; i < numClients; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, int64(i)*2, int64(i))
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+	for i := 0; i < numClients; i++ {
+		go runNoOpClient(db, tm, rm, tableName, int64(i)*2, doneCh, errCh)
+	}
+	for i := 0; i < numClients; i++ {
+		select {
+		case <-doneCh:
+			continue
+		case err := <-errCh:
+			t.Fatal(err)
+		}
+	}
+
+	// None of the failed edits should have taken effect
+	startTransaction(t, db, tm, rm, clientId)
+	for i := 0; i < numClients; i++ {
+		checkFind(t, db, tm, clientId, tableName, int64(i)*2, int64(i))
+		checkFindFails(t, db, tm, clientId, tableName, int64(i)*2+1)
+	}
+}
+
+func testMaxEditsExceeded(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	maxEdits := 10
+	rm.SetMaxEditsPerTransaction(maxEdits)
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < int64(maxEdits); i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i%utils.Salt)
+	}
+	// The next edit goes over the limit
+	payload := fmt.Sprintf("insert %v %v into %s", maxEdits, 0, tableName)
+	err := recovery.HandleInsert(db, tm, rm, payload, clientId)
+	if !errors.Is(err, recovery.ErrTransactionTooLarge) {
+		t.Fatalf("Expected edit over the limit to fail with %q, but got %v", recovery.ErrTransactionTooLarge, err)
+	}
+	checkFindFails(t, db, tm, clientId, tableName, int64(maxEdits))
+
+	// Aborting should still undo every edit, even though undoing takes more edits
+	abortTransaction(t, tm, rm, clientId)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < int64(maxEdits); i++ {
+		checkFindFails(t, db, tm, clientId, tableName, i)
+	}
+}
+
+func testMaxEditsUnderLimit(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	maxEdits := 10
+	rm.SetMaxEditsPerTransaction(maxEdits)
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < int64(maxEdits)-1; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, i, i%utils.Salt)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+
+	// A new transaction gets to make edits again
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, int64(maxEdits), 0)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < int64(maxEdits)-1; i++ {
+		checkFind(t, db, tm, clientId, tableName, i, i%utils.Salt)
+	}
+	checkFind(t, db, tm, clientId, tableName, int64(maxEdits), 0)
+}
+
+// Converting a table is logged, so that redo converts it again between the same edits.
+func testConvert(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.HashIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 0, 0)
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 1)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	if _, err := recovery.HandleConvert(db, tm, rm, fmt.Sprintf("convert %s to btree", tableName)); err != nil {
+		t.Fatal("Error converting table:", err)
+	}
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 2, 2)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	table, err := db.GetTable(tableName)
+	if err != nil {
+		t.Fatal("Error getting table:", err)
+	}
+	if indexType, _ := database.GetIndexType(table); indexType != database.BTreeIndexType {
+		t.Errorf("Expected a btree table after recovery, but found a %s table", indexType)
+	}
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 3; i++ {
+		checkFind(t, db, tm, clientId, tableName, i, i)
+	}
+}
+
+// Beginning a running transaction again with a resumable transaction manager keeps its edits,
+// so that aborting it still undoes the edits made before it was resumed.
+func testBeginResumed(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tm.SetResumable(true)
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 0, 0)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 1)
+	abortTransaction(t, tm, rm, clientId)
+
+	startTransaction(t, db, tm, rm, clientId)
+	checkFindFails(t, db, tm, clientId, tableName, 0)
+	checkFindFails(t, db, tm, clientId, tableName, 1)
+}
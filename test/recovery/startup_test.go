@@ -0,0 +1,106 @@
+package recovery_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dinodb/pkg/config"
+	"dinodb/pkg/recovery"
+)
+
+func TestPrimeStartup(t *testing.T) {
+	t.Run("Fresh", testPrimeFresh)
+	t.Run("Normal", testPrimeNormal)
+	t.Run("Recovering", testPrimeRecovering)
+	t.Run("NotAFolder", testPrimeNotAFolder)
+}
+
+// primeWithMode checks that DetectStartup reports the expected mode for the database in base, then primes it
+func primeWithMode(t *testing.T, base string, expected recovery.StartupMode) {
+	mode, err := recovery.DetectStartup(base)
+	if err != nil {
+		t.Fatal("Error detecting startup mode:", err)
+	}
+	if mode != expected {
+		t.Fatalf("Expected a %s start, got a %s start", expected, mode)
+	}
+	d, err := recovery.Prime(base)
+	if err != nil {
+		t.Fatal("Error priming database:", err)
+	}
+	d.Close()
+	for _, folder := range []string{base, base + "-recovery"} {
+		if info, err := os.Stat(folder); err != nil || !info.IsDir() {
+			t.Errorf("Expected folder %s to exist after priming", folder)
+		}
+	}
+}
+
+// writeFile writes the contents to the named file, failing the test on error
+func writeFile(t *testing.T, name string, contents string) {
+	if err := os.WriteFile(name, []byte(contents), 0666); err != nil {
+		t.Fatal("Error writing file:", err)
+	}
+}
+
+// checkFile checks that the named file exists with the given contents
+func checkFile(t *testing.T, name string, contents string) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Errorf("Error reading %s: %v", name, err)
+		return
+	}
+	if string(data) != contents {
+		t.Errorf("Expected %s to hold %q, found %q", name, contents, string(data))
+	}
+}
+
+// Priming a database with neither folder creates both
+func testPrimeFresh(t *testing.T) {
+	t.Parallel()
+	base := filepath.Join(t.TempDir(), "db")
+	primeWithMode(t, base, recovery.FRESH_START)
+}
+
+// Priming a database without a recovery folder creates one, leaving the data folder alone
+func testPrimeNormal(t *testing.T) {
+	t.Parallel()
+	base := filepath.Join(t.TempDir(), "db")
+	if err := os.Mkdir(base, 0775); err != nil {
+		t.Fatal("Error creating database folder:", err)
+	}
+	writeFile(t, filepath.Join(base, "data"), "current")
+	primeWithMode(t, base, recovery.NORMAL_START)
+	checkFile(t, filepath.Join(base, "data"), "current")
+}
+
+// Priming a database with a recovery folder replaces the data folder with it, keeping the log file
+func testPrimeRecovering(t *testing.T) {
+	t.Parallel()
+	base := filepath.Join(t.TempDir(), "db")
+	for _, folder := range []string{base, base + "-recovery"} {
+		if err := os.Mkdir(folder, 0775); err != nil {
+			t.Fatal("Error creating folder:", err)
+		}
+	}
+	writeFile(t, filepath.Join(base, "data"), "current")
+	writeFile(t, filepath.Join(base+"-recovery", "data"), "backup")
+	writeFile(t, filepath.Join(base, config.LogFileName), "log contents")
+	primeWithMode(t, base, recovery.RECOVERY_START)
+	checkFile(t, filepath.Join(base, "data"), "backup")
+	checkFile(t, filepath.Join(base, config.LogFileName), "log contents")
+}
+
+// Priming fails, rather than starting fresh, when the database's path isn't a folder
+func testPrimeNotAFolder(t *testing.T) {
+	t.Parallel()
+	base := filepath.Join(t.TempDir(), "db")
+	writeFile(t, base, "not a folder")
+	if _, err := recovery.DetectStartup(base); err == nil {
+		t.Error("Expected an error detecting the startup mode of a file")
+	}
+	if _, err := recovery.Prime(base); err == nil {
+		t.Error("Expected an error priming a database whose path is a file")
+	}
+}
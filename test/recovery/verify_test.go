@@ -0,0 +1,110 @@
+package recovery_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+)
+
+func TestVerifyAgainstLog(t *testing.T) {
+	t.Run("AfterRecover", testVerifyAfterRecover)
+	t.Run("DetectsLostRedo", testVerifyDetectsLostRedo)
+	t.Run("DetectsWrongValue", testVerifyDetectsWrongValue)
+	t.Run("TruncatedLog", testVerifyTruncatedLog)
+}
+
+// setupVerify logs committed edits to tables of both index types on both sides of a checkpoint,
+// leaves a transaction uncommitted, and crashes and recovers, returning the recovered database and table names
+func setupVerify(t *testing.T) (*database.Database, *recovery.RecoveryManager, string, string) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	btreeName := createTable(t, db, rm, database.BTreeIndexType)
+	hashName := createTable(t, db, rm, database.HashIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for i := int64(0); i < 100; i++ {
+		insertIntoTable(t, db, tm, rm, clientId, btreeName, i, i)
+		insertIntoTable(t, db, tm, rm, clientId, hashName, i, i)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	checkpoint(t, rm)
+	startTransaction(t, db, tm, rm, clientId)
+	updateTableEntry(t, db, tm, rm, clientId, btreeName, 3, 30)
+	deleteFromTable(t, db, tm, rm, clientId, hashName, 4)
+	commitTransaction(t, db, tm, rm, clientId)
+	uncommittedId := uuid.New()
+	startTransaction(t, db, tm, rm, uncommittedId)
+	insertIntoTable(t, db, tm, rm, uncommittedId, hashName, 200, 200)
+	deleteFromTable(t, db, tm, rm, uncommittedId, btreeName, 6)
+	db, _, rm = crashAndRecover(t, db.GetBasePath())
+	return db, rm, btreeName, hashName
+}
+
+// checkDivergence errors the test unless err is an ErrLogDivergence naming exactly the given table
+func checkDivergence(t *testing.T, err error, tableName string, otherName string) {
+	if !errors.Is(err, recovery.ErrLogDivergence) {
+		t.Fatalf("Expected %q, but got %v", recovery.ErrLogDivergence, err)
+	}
+	if !strings.Contains(err.Error(), tableName) || strings.Contains(err.Error(), otherName) {
+		t.Errorf("Expected the divergence to name only table %s, but got %v", tableName, err)
+	}
+}
+
+// Checks that a database recovered from committed, checkpointed, and uncommitted transactions matches its log
+func testVerifyAfterRecover(t *testing.T) {
+	_, rm, _, _ := setupVerify(t)
+	if err := rm.VerifyAgainstLog(); err != nil {
+		t.Error("Expected the recovered database to match its log, but got", err)
+	}
+}
+
+// Deletes a committed entry behind the log's back, as a redo that skipped an insert would leave it,
+// checking that verification reports the table
+func testVerifyDetectsLostRedo(t *testing.T) {
+	db, rm, btreeName, hashName := setupVerify(t)
+	table, err := db.GetTable(hashName)
+	if err != nil {
+		t.Fatal("Failed to get table:", err)
+	}
+	if err := table.Delete(50); err != nil {
+		t.Fatal("Failed to delete entry:", err)
+	}
+	checkDivergence(t, rm.VerifyAgainstLog(), hashName, btreeName)
+}
+
+// Restores an entry's value from before a committed update behind the log's back, as a redo that applied
+// the update's old value would leave it, checking that verification reports the table though its size is right
+func testVerifyDetectsWrongValue(t *testing.T) {
+	db, rm, btreeName, hashName := setupVerify(t)
+	table, err := db.GetTable(btreeName)
+	if err != nil {
+		t.Fatal("Failed to get table:", err)
+	}
+	if err := table.Update(3, 3); err != nil {
+		t.Fatal("Failed to update entry:", err)
+	}
+	checkDivergence(t, rm.VerifyAgainstLog(), btreeName, hashName)
+}
+
+// Compacts the log, dropping the tables' create logs, checking that verification refuses to compare
+// rather than reporting a divergence
+func testVerifyTruncatedLog(t *testing.T) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 1, 1)
+	commitTransaction(t, db, tm, rm, clientId)
+	if _, _, err := rm.CompactLog(); err != nil {
+		t.Fatal("Failed to compact log:", err)
+	}
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, 2, 2)
+	commitTransaction(t, db, tm, rm, clientId)
+	err := rm.VerifyAgainstLog()
+	if err == nil || errors.Is(err, recovery.ErrLogDivergence) {
+		t.Errorf("Expected verifying a truncated log to fail without reporting a divergence, but got %v", err)
+	}
+}
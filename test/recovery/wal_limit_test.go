@@ -0,0 +1,124 @@
+package recovery_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"dinodb/pkg/concurrency"
+	"dinodb/pkg/database"
+	"dinodb/pkg/recovery"
+
+	"github.com/google/uuid"
+)
+
+func TestMaxLogSize(t *testing.T) {
+	t.Run("Full", testMaxLogSizeFull)
+	t.Run("Backpressure", testMaxLogSizeBackpressure)
+}
+
+// fillLog inserts keys from start until the write-ahead log is full, returning the first key that didn't fit
+func fillLog(t *testing.T, db *database.Database, tm *concurrency.TransactionManager, rm *recovery.RecoveryManager, clientId uuid.UUID, tableName string, start int64) int64 {
+	for key := start; key < start+1000; key++ {
+		err := recovery.HandleInsert(db, tm, rm, fmt.Sprintf("insert %d %d into %s", key, key, tableName), clientId)
+		if errors.Is(err, recovery.ErrWALFull) {
+			return key
+		} else if err != nil {
+			t.Fatalf("Error inserting key %d: %v", key, err)
+		}
+	}
+	t.Fatal("Expected the write-ahead log to fill up")
+	return 0
+}
+
+// Commits some inserts, then limits the log to a little more than its current size
+func setupFullLog(t *testing.T, maxWait time.Duration) (
+	*database.Database, *concurrency.TransactionManager, *recovery.RecoveryManager, uuid.UUID, string, int64) {
+	db, tm, rm, clientId := setupRecovery(t, "")
+	tableName := createTable(t, db, rm, database.BTreeIndexType)
+	startTransaction(t, db, tm, rm, clientId)
+	for key := int64(0); key < 20; key++ {
+		insertIntoTable(t, db, tm, rm, clientId, tableName, key, key)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	maxSize := rm.GetLogSize() + 1000
+	rm.SetMaxLogSize(maxSize, maxWait)
+	return db, tm, rm, clientId, tableName, maxSize
+}
+
+// truncateLog takes a checkpoint and truncates the log, checking that the log shrinks
+func truncateLog(t *testing.T, rm *recovery.RecoveryManager) {
+	sizeBefore := rm.GetLogSize()
+	checkpoint(t, rm)
+	if err := rm.TruncateLog(); err != nil {
+		t.Fatal("Failed to truncate the log:", err)
+	}
+	if size := rm.GetLogSize(); size >= sizeBefore {
+		t.Errorf("Expected truncation to shrink the log from %d bytes, but it is %d bytes", sizeBefore, size)
+	}
+}
+
+// Fills a tiny log, checking that edits fail with ErrWALFull, that the transaction can still commit,
+// and that edits resume after a checkpoint and truncation without breaking recovery
+func testMaxLogSizeFull(t *testing.T) {
+	db, tm, rm, clientId, tableName, maxSize := setupFullLog(t, 0)
+	startTransaction(t, db, tm, rm, clientId)
+	full := fillLog(t, db, tm, rm, clientId, tableName, 20)
+	if size := rm.GetLogSize(); size > maxSize {
+		t.Errorf("Expected the log to stay within %d bytes, but it is %d bytes", maxSize, size)
+	}
+	commitTransaction(t, db, tm, rm, clientId)
+	if err := recovery.HandleTransaction(db, tm, rm, "transaction begin", clientId); !errors.Is(err, recovery.ErrWALFull) {
+		t.Errorf("Expected starting a transaction in a full log to fail with %q, but got %v", recovery.ErrWALFull, err)
+	}
+
+	truncateLog(t, rm)
+	startTransaction(t, db, tm, rm, clientId)
+	insertIntoTable(t, db, tm, rm, clientId, tableName, full, full)
+	commitTransaction(t, db, tm, rm, clientId)
+
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for key := int64(0); key <= full; key++ {
+		checkFind(t, db, tm, clientId, tableName, key, key)
+	}
+}
+
+// Fills a tiny log, checking that the next edit waits until a checkpoint and truncation make room,
+// and that the truncated log still recovers the transaction that was active across the truncation
+func testMaxLogSizeBackpressure(t *testing.T) {
+	db, tm, rm, clientId, tableName, maxSize := setupFullLog(t, 0)
+	startTransaction(t, db, tm, rm, clientId)
+	full := fillLog(t, db, tm, rm, clientId, tableName, 20)
+
+	rm.SetMaxLogSize(maxSize, 10*time.Second)
+	done := make(chan error, 1)
+	go func() {
+		done <- recovery.HandleInsert(db, tm, rm, fmt.Sprintf("insert %d %d into %s", full, full, tableName), clientId)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the insert to wait for room in the log, but it returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	truncateLog(t, rm)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal("Expected the insert to succeed once the log was truncated, but got", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the insert to resume")
+	}
+
+	// The transaction was active at the checkpoint, so its logs survive the truncation.
+	db, tm, rm = crashAndRecover(t, db.GetBasePath())
+	startTransaction(t, db, tm, rm, clientId)
+	for key := int64(0); key < 20; key++ {
+		checkFind(t, db, tm, clientId, tableName, key, key)
+	}
+	for key := int64(20); key <= full; key++ {
+		checkFindFails(t, db, tm, clientId, tableName, key)
+	}
+}